@@ -0,0 +1,129 @@
+package crypto
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/NebulousLabs/fastrand"
+	"github.com/btcsuite/btcd/btcec"
+)
+
+const (
+	// Secp256k1PublicKeySize defines the size of (compressed) secp256k1 public keys in bytes.
+	Secp256k1PublicKeySize = btcec.PubKeyBytesLenCompressed
+
+	// Secp256k1SecretKeySize defines the size of secp256k1 secret keys in bytes.
+	Secp256k1SecretKeySize = btcec.PrivKeyBytesLen
+
+	// Secp256k1SignatureSize defines the size of secp256k1 signatures in bytes,
+	// the concatenation of the 32-byte R and S values.
+	Secp256k1SignatureSize = 64
+)
+
+var (
+	// ErrInvalidSecp256k1PublicKey is returned if a secp256k1 public key
+	// is used which is not a valid (compressed) point on the curve.
+	ErrInvalidSecp256k1PublicKey = errors.New("invalid secp256k1 public key")
+)
+
+type (
+	// Secp256k1PublicKey is a compressed secp256k1 public key,
+	// which can be used to verify secp256k1 signatures.
+	Secp256k1PublicKey [Secp256k1PublicKeySize]byte
+
+	// Secp256k1SecretKey can be used to sign data for the corresponding secp256k1 public key.
+	Secp256k1SecretKey [Secp256k1SecretKeySize]byte
+
+	// Secp256k1Signature proves that data was signed by the owner of a particular
+	// secp256k1 public key's corresponding secret key.
+	Secp256k1Signature [Secp256k1SignatureSize]byte
+)
+
+var (
+	// nilSecp256k1SecretKey defines a pure nil secp256k1 secret key
+	nilSecp256k1SecretKey = Secp256k1SecretKey{}
+	// nilSecp256k1PublicKey defines a pure nil secp256k1 public key
+	nilSecp256k1PublicKey = Secp256k1PublicKey{}
+
+	// secp256k1HalfOrder is used to ensure signatures use the lower S value,
+	// as to prevent signature malleability
+	secp256k1HalfOrder = new(big.Int).Rsh(btcec.S256().N, 1)
+)
+
+// PublicKey returns the (compressed) public key that corresponds to a secp256k1 secret key.
+func (sk Secp256k1SecretKey) PublicKey() (pk Secp256k1PublicKey) {
+	_, bpk := btcec.PrivKeyFromBytes(btcec.S256(), sk[:])
+	copy(pk[:], bpk.SerializeCompressed())
+	return
+}
+
+// IsNil returns true if this secp256k1 secret key equals to a pure zero array.
+func (sk Secp256k1SecretKey) IsNil() bool {
+	return sk == nilSecp256k1SecretKey
+}
+
+// IsNil returns true if this secp256k1 public key equals to a pure zero array.
+func (pk Secp256k1PublicKey) IsNil() bool {
+	return pk == nilSecp256k1PublicKey
+}
+
+// GenerateSecp256k1KeyPair creates a secp256k1 public-secret keypair that can be used
+// to sign and verify messages.
+func GenerateSecp256k1KeyPair() (sk Secp256k1SecretKey, pk Secp256k1PublicKey) {
+	var entropy [EntropySize]byte
+	fastrand.Read(entropy[:])
+	return GenerateSecp256k1KeyPairDeterministic(entropy)
+}
+
+// GenerateSecp256k1KeyPairDeterministic generates secp256k1 keys deterministically
+// using the input entropy. In the unlikely event that the entropy is not
+// a valid secret key, it is hashed until it is.
+func GenerateSecp256k1KeyPairDeterministic(entropy [EntropySize]byte) (sk Secp256k1SecretKey, pk Secp256k1PublicKey) {
+	d := new(big.Int).SetBytes(entropy[:])
+	for d.Sign() == 0 || d.Cmp(btcec.S256().N) >= 0 {
+		entropy = HashBytes(entropy[:])
+		d.SetBytes(entropy[:])
+	}
+	copy(sk[:], entropy[:])
+	pk = sk.PublicKey()
+	return
+}
+
+// SignHashSecp256k1 signs a message using a secp256k1 secret key.
+// The signature is created deterministically (RFC6979),
+// and always uses the lower S value.
+func SignHashSecp256k1(data Hash, sk Secp256k1SecretKey) (sig Secp256k1Signature, err error) {
+	if sk.IsNil() {
+		return sig, ErrSecretNilKey
+	}
+	bsk, _ := btcec.PrivKeyFromBytes(btcec.S256(), sk[:])
+	bsig, err := bsk.Sign(data[:])
+	if err != nil {
+		return sig, err
+	}
+	rb, sb := bsig.R.Bytes(), bsig.S.Bytes()
+	copy(sig[32-len(rb):32], rb)
+	copy(sig[64-len(sb):], sb)
+	return sig, nil
+}
+
+// VerifyHashSecp256k1 uses a secp256k1 public key and input data to verify a signature.
+// Signatures using the higher S value are considered invalid.
+func VerifyHashSecp256k1(data Hash, pk Secp256k1PublicKey, sig Secp256k1Signature) error {
+	if pk.IsNil() {
+		return ErrPublicNilKey
+	}
+	bpk, err := btcec.ParsePubKey(pk[:], btcec.S256())
+	if err != nil {
+		return ErrInvalidSecp256k1PublicKey
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Sign() == 0 || s.Sign() == 0 || r.Cmp(btcec.S256().N) >= 0 || s.Cmp(secp256k1HalfOrder) > 0 {
+		return ErrInvalidSignature
+	}
+	if !(&btcec.Signature{R: r, S: s}).Verify(data[:], bpk) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
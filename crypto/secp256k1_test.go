@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/NebulousLabs/fastrand"
+	"github.com/btcsuite/btcd/btcec"
+)
+
+// TestSecp256k1PublicKey ensures the public key is derived as expected,
+// using the generator point as the public key of the secret key 1.
+func TestSecp256k1PublicKey(t *testing.T) {
+	var sk Secp256k1SecretKey
+	sk[Secp256k1SecretKeySize-1] = 1
+	pk := sk.PublicKey()
+	const expected = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	if str := hex.EncodeToString(pk[:]); str != expected {
+		t.Fatalf("unexpected public key: %s != %s", str, expected)
+	}
+}
+
+// TestSecp256k1KeyPairDeterministic ensures the same entropy results in the same key pair,
+// and invalid secret keys are never generated.
+func TestSecp256k1KeyPairDeterministic(t *testing.T) {
+	var entropy [EntropySize]byte
+	fastrand.Read(entropy[:])
+	sk1, pk1 := GenerateSecp256k1KeyPairDeterministic(entropy)
+	sk2, pk2 := GenerateSecp256k1KeyPairDeterministic(entropy)
+	if sk1 != sk2 || pk1 != pk2 {
+		t.Fatal("expected the same key pair for the same entropy")
+	}
+	if sk1.PublicKey() != pk1 {
+		t.Fatal("public key does not match secret key")
+	}
+
+	// a zero or out of range entropy is not a valid secret key
+	for _, entropy := range [][EntropySize]byte{
+		{},
+		func() (e [EntropySize]byte) {
+			for i := range e {
+				e[i] = 0xff
+			}
+			return
+		}(),
+	} {
+		sk, pk := GenerateSecp256k1KeyPairDeterministic(entropy)
+		if sk.IsNil() || pk.IsNil() {
+			t.Fatal("expected a valid key pair")
+		}
+		if sk == entropy {
+			t.Fatal("expected an invalid secret key to be rehashed")
+		}
+	}
+}
+
+// TestSecp256k1Signing signs random data with random keys,
+// and verifies the signatures.
+func TestSecp256k1Signing(t *testing.T) {
+	for i := 0; i < 10; i++ {
+		sk, pk := GenerateSecp256k1KeyPair()
+		var data Hash
+		fastrand.Read(data[:])
+		sig, err := SignHashSecp256k1(data, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = VerifyHashSecp256k1(data, pk, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// signatures are deterministic
+		sig2, err := SignHashSecp256k1(data, sk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig != sig2 {
+			t.Fatal("expected signatures to be deterministic")
+		}
+
+		// the signature should not verify with other data
+		data[0]++
+		err = VerifyHashSecp256k1(data, pk, sig)
+		if err != ErrInvalidSignature {
+			t.Fatalf("expected %v, got %v", ErrInvalidSignature, err)
+		}
+		data[0]--
+
+		// the higher S value is not accepted
+		s := new(big.Int).SetBytes(sig[32:])
+		s.Sub(btcec.S256().N, s)
+		highSig := sig
+		for j := 32; j < Secp256k1SignatureSize; j++ {
+			highSig[j] = 0
+		}
+		sb := s.Bytes()
+		copy(highSig[Secp256k1SignatureSize-len(sb):], sb)
+		err = VerifyHashSecp256k1(data, pk, highSig)
+		if err != ErrInvalidSignature {
+			t.Fatalf("expected %v for a high S signature, got %v", ErrInvalidSignature, err)
+		}
+	}
+
+	// nil keys cannot be used
+	var data Hash
+	if _, err := SignHashSecp256k1(data, Secp256k1SecretKey{}); err != ErrSecretNilKey {
+		t.Fatalf("expected %v, got %v", ErrSecretNilKey, err)
+	}
+	if err := VerifyHashSecp256k1(data, Secp256k1PublicKey{}, Secp256k1Signature{}); err != ErrPublicNilKey {
+		t.Fatalf("expected %v, got %v", ErrPublicNilKey, err)
+	}
+	if err := VerifyHashSecp256k1(data, Secp256k1PublicKey{4, 2}, Secp256k1Signature{}); err != ErrInvalidSecp256k1PublicKey {
+		t.Fatalf("expected %v, got %v", ErrInvalidSecp256k1PublicKey, err)
+	}
+}
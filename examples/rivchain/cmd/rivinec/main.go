@@ -10,6 +10,7 @@ import (
 	"github.com/threefoldtech/rivine/examples/rivchain/pkg/config"
 
 	"github.com/threefoldtech/rivine/examples/rivchain/pkg/types"
+	assetscli "github.com/threefoldtech/rivine/extensions/assets/client"
 	authcointxcli "github.com/threefoldtech/rivine/extensions/authcointx/client"
 	mintingcli "github.com/threefoldtech/rivine/extensions/minting/client"
 
@@ -54,6 +55,17 @@ func main() {
 		},
 	)
 
+	// register assets specific commands
+	err = assetscli.CreateConsensusCmd(cliClient.CommandLineClient)
+	exitIfError(err)
+	err = assetscli.CreateExploreCmd(cliClient.CommandLineClient)
+	exitIfError(err)
+	err = assetscli.CreateWalletCmds(
+		cliClient.CommandLineClient,
+		types.TransactionVersionAssetTransaction,
+	)
+	exitIfError(err)
+
 	// define preRun function
 	cliClient.PreRunE = func(cfg *client.Config) (*client.Config, error) {
 		if cfg == nil {
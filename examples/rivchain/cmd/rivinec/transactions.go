@@ -2,6 +2,8 @@ package main
 
 import (
 	rivchaintypes "github.com/threefoldtech/rivine/examples/rivchain/pkg/types"
+	"github.com/threefoldtech/rivine/extensions/assets"
+	assetscli "github.com/threefoldtech/rivine/extensions/assets/client"
 	"github.com/threefoldtech/rivine/extensions/authcointx"
 	authcointxcli "github.com/threefoldtech/rivine/extensions/authcointx/client"
 	"github.com/threefoldtech/rivine/extensions/minting"
@@ -50,4 +52,12 @@ func registerTransactions(bc client.BaseClient) {
 		AuthInfoGetter:     authCoinTxCLI,
 		TransactionVersion: rivchaintypes.TransactionVersionAuthAddressUpdate,
 	})
+
+	// create assets plugin client...
+	assetsCLI := assetscli.NewPluginConsensusClient(bc)
+	// ...and register asset types
+	types.RegisterTransactionVersion(rivchaintypes.TransactionVersionAssetTransaction, assets.AssetTransactionController{
+		AssetGetter:        assetsCLI,
+		TransactionVersion: rivchaintypes.TransactionVersionAssetTransaction,
+	})
 }
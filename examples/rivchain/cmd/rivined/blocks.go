@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/modules/consensus"
+	"github.com/threefoldtech/rivine/modules/gateway"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/pkg/cli"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// blocksCommands contains the flags of the block archive commands.
+type blocksCommands struct {
+	exportFrom uint64
+	exportTo   uint64
+	exportOut  string
+}
+
+func (cmds *commands) exportBlocksCommand(cmd *cobra.Command, _ []string) {
+	cs, closeConsensusSet, err := openConsensusSet(cmds.cfg, false)
+	if err != nil {
+		cli.DieWithError("failed to open consensus set", err)
+	}
+	defer closeConsensusSet()
+
+	from := types.BlockHeight(cmds.blocks.exportFrom)
+	to := cs.Height()
+	if cmd.Flags().Changed("to") {
+		to = types.BlockHeight(cmds.blocks.exportTo)
+	}
+
+	file, err := os.Create(cmds.blocks.exportOut)
+	if err != nil {
+		closeConsensusSet()
+		cli.DieWithError("failed to create block archive", err)
+	}
+	fmt.Printf("Exporting blocks %d-%d to %s...\n", from, to, cmds.blocks.exportOut)
+	count, err := cs.ExportBlocks(file, from, to)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(cmds.blocks.exportOut)
+		closeConsensusSet()
+		cli.DieWithError("failed to export blocks", err)
+	}
+	fmt.Printf("Exported %d blocks to %s\n", count, cmds.blocks.exportOut)
+}
+
+func (cmds *commands) importBlocksCommand(_ *cobra.Command, args []string) {
+	file, err := os.Open(args[0])
+	if err != nil {
+		cli.DieWithError("failed to open block archive", err)
+	}
+	defer file.Close()
+
+	cs, closeConsensusSet, err := openConsensusSet(cmds.cfg, true)
+	if err != nil {
+		cli.DieWithError("failed to open consensus set", err)
+	}
+	defer closeConsensusSet()
+
+	// interrupt the import (in between two blocks) when a stop signal is caught
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	defer signal.Stop(sigChan)
+	go func() {
+		select {
+		case <-sigChan:
+			fmt.Println("\rCaught stop signal, stopping the import...")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Printf("Importing blocks from %s...\n", args[0])
+	var lastReport time.Time
+	status, err := cs.ImportBlocks(ctx, file, func(progress consensus.BlockArchiveProgress) {
+		if time.Since(lastReport) < time.Second && progress.Processed != progress.Total {
+			return
+		}
+		lastReport = time.Now()
+		fmt.Printf("\rProcessed %d/%d blocks (height %d, %d accepted)",
+			progress.Processed, progress.Total, progress.Height, progress.Accepted)
+	})
+	fmt.Println()
+	if err != nil {
+		closeConsensusSet()
+		cli.DieWithError("failed to import blocks", err)
+	}
+	fmt.Printf("Imported block archive: %d of %d blocks accepted, consensus set is at height %d\n",
+		status.Accepted, status.Total, cs.Height())
+}
+
+// openConsensusSet opens the consensus set of the daemon, configured the same
+// way as the daemon does, without connecting it to the network. The returned
+// function closes the consensus set and the resources it depends upon,
+// and can be called multiple times.
+func openConsensusSet(cfg ExtendedDaemonConfig, withPlugins bool) (*consensus.ConsensusSet, func(), error) {
+	// use the same (network-specific) storage directory as the daemon
+	cfg.RootPersistentDir = filepath.Join(cfg.RootPersistentDir, cfg.BlockchainInfo.NetworkName)
+
+	setupNetworkCfg, err := setupNetwork(cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create network config: %v", err)
+	}
+	networkCfg := setupNetworkCfg.NetworkConfig
+	err = networkCfg.Constants.Validate()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to validate network config: %v", err)
+	}
+	dbBackend, err := kv.ParseBackend(cfg.DatabaseBackend)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the consensus set requires a gateway, even though no peers are connected,
+	// a temporary gateway is used, as to leave the state of the daemon's gateway untouched
+	gatewayDir, err := ioutil.TempDir("", "rivined-gateway")
+	if err != nil {
+		return nil, nil, err
+	}
+	g, err := gateway.New("localhost:0", false, maxConcurrentRPC, gatewayDir,
+		cfg.BlockchainInfo, networkCfg.Constants, nil, cfg.VerboseLogging)
+	if err != nil {
+		os.RemoveAll(gatewayDir)
+		return nil, nil, err
+	}
+	closeGateway := func() {
+		err := g.Close()
+		if err != nil {
+			fmt.Println("Error during gateway shutdown:", err)
+		}
+		os.RemoveAll(gatewayDir)
+	}
+
+	cs, err := consensus.NewWithBackend(g, false,
+		filepath.Join(cfg.RootPersistentDir, modules.ConsensusDir),
+		cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging, "", dbBackend)
+	if err != nil {
+		closeGateway()
+		return nil, nil, err
+	}
+	closed := false
+	closeAll := func() {
+		if closed {
+			return
+		}
+		closed = true
+		err := cs.Close()
+		if err != nil {
+			fmt.Println("Error during consensus set shutdown:", err)
+		}
+		closeGateway()
+	}
+
+	err = configureConsensusSet(cs, cfg, networkCfg)
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	// the extension plugins are required to validate the transactions they define,
+	// and have to apply all blocks accepted by the consensus set
+	if withPlugins {
+		_, err = registerConsensusPlugins(context.Background(), cs, setupNetworkCfg, cfg.IndexSpentOutputs)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+	}
+	return cs, closeAll, nil
+}
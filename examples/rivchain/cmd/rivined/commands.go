@@ -19,6 +19,7 @@ import (
 type commands struct {
 	cfg           ExtendedDaemonConfig
 	moduleSetFlag daemon.ModuleSetFlag
+	blocks        blocksCommands
 }
 
 func (cmds *commands) rootCommand(*cobra.Command, []string) {
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -20,7 +21,13 @@ import (
 	"github.com/threefoldtech/rivine/extensions/authcointx"
 	authcointxapi "github.com/threefoldtech/rivine/extensions/authcointx/api"
 
+	"github.com/threefoldtech/rivine/extensions/assets"
+	assetsapi "github.com/threefoldtech/rivine/extensions/assets/api"
+	"github.com/threefoldtech/rivine/extensions/spentoutputs"
+	spentoutputsapi "github.com/threefoldtech/rivine/extensions/spentoutputs/api"
+
 	"github.com/julienschmidt/httprouter"
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/modules/blockcreator"
 	"github.com/threefoldtech/rivine/modules/consensus"
@@ -28,6 +35,7 @@ import (
 	"github.com/threefoldtech/rivine/modules/gateway"
 	"github.com/threefoldtech/rivine/modules/transactionpool"
 	"github.com/threefoldtech/rivine/modules/wallet"
+	"github.com/threefoldtech/rivine/persist/kv"
 	rivineapi "github.com/threefoldtech/rivine/pkg/api"
 	"github.com/threefoldtech/rivine/pkg/daemon"
 )
@@ -87,6 +95,16 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 			cancel()
 			return
 		}
+		// print and parse unlock hashes (addresses) using the format defined by the network config
+		err = types.SetUnlockHashEncoding(networkCfg.Constants.UnlockHashEncoding, cfg.BlockchainInfo.AddressPrefix)
+		if err != nil {
+			servErrs <- fmt.Errorf("failed to set unlock hash encoding: %v", err)
+			cancel()
+			return
+		}
+		// register the chain ID, valid-until and signature hash flags transaction versions,
+		// committing to the ID of the chain defined by the network config
+		networkCfg.RegisterChainTransactionVersions(cfg.BlockchainInfo)
 
 		fmt.Println("Setting up root HTTP API handler...")
 
@@ -128,6 +146,13 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 			cancel()
 		})
 
+		dbBackend, err := kv.ParseBackend(cfg.DatabaseBackend)
+		if err != nil {
+			servErrs <- err
+			cancel()
+			return
+		}
+
 		// Initialize the Rivine modules
 		var g modules.Gateway
 		if moduleIdentifiers.Contains(daemon.GatewayModule.Identifier()) {
@@ -152,18 +177,40 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 
 		var mintingPlugin *minting.Plugin
 		var authCoinTxPlugin *authcointx.Plugin
+		var assetsPlugin *assets.Plugin
 
 		if moduleIdentifiers.Contains(daemon.ConsensusSetModule.Identifier()) {
 			printModuleIsLoading("consensus set")
-			cs, err = consensus.New(g, !cfg.NoBootstrap,
+			consensusSet, err := consensus.NewWithBackend(g, !cfg.NoBootstrap,
 				filepath.Join(cfg.RootPersistentDir, modules.ConsensusDir),
-				cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging, cfg.DebugConsensusDB)
+				cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging, cfg.DebugConsensusDB, dbBackend)
+			if err != nil {
+				servErrs <- err
+				cancel()
+				return
+			}
+			cs = consensusSet
+			if cfg.BootstrapSnapshot != "" {
+				err = importConsensusSnapshot(consensusSet, cfg.BootstrapSnapshot, networkCfg.TrustedSnapshots)
+				if err != nil {
+					servErrs <- err
+					cancel()
+					return
+				}
+			}
+			if cfg.PruneDepth > 0 && moduleIdentifiers.Contains(daemon.ExplorerModule.Identifier()) {
+				servErrs <- errors.New("the explorer module requires all blocks, and cannot be used in pruned mode")
+				cancel()
+				return
+			}
+			err = configureConsensusSet(consensusSet, cfg, networkCfg)
 			if err != nil {
 				servErrs <- err
 				cancel()
 				return
 			}
 			rivineapi.RegisterConsensusHTTPHandlers(router, cs)
+			rivineapi.RegisterConsensusSnapshotHTTPHandlers(router, cs, cfg.APIPassword)
 			defer func() {
 				fmt.Println("Closing consensus set...")
 				err := cs.Close()
@@ -176,9 +223,9 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 		var tpool modules.TransactionPool
 		if moduleIdentifiers.Contains(daemon.TransactionPoolModule.Identifier()) {
 			printModuleIsLoading("transaction pool")
-			tpool, err = transactionpool.New(cs, g,
+			tpool, err = transactionpool.NewWithBackend(cs, g,
 				filepath.Join(cfg.RootPersistentDir, modules.TransactionPoolDir),
-				cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging)
+				cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging, dbBackend)
 			if err != nil {
 				servErrs <- err
 				cancel()
@@ -195,26 +242,18 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 		}
 
 		if cs != nil {
-			// create the minting extension plugin
-			mintingPlugin = minting.NewMintingPlugin(
-				setupNetworkCfg.GenesisMintCondition,
-				rivchaintypes.TransactionVersionMinterDefinition,
-				rivchaintypes.TransactionVersionCoinCreation,
-				&minting.PluginOptions{
-					CoinDestructionTransactionVersion: rivchaintypes.TransactionVersionCoinDestruction,
-				},
-			)
-			// add the HTTP handlers for the auth coin tx extension as well
-			mintingapi.RegisterConsensusMintingHTTPHandlers(router, mintingPlugin)
+			plugins, err := registerConsensusPlugins(ctx, cs, setupNetworkCfg, cfg.IndexSpentOutputs)
+			if err != nil {
+				servErrs <- err
+				cancel()
+				return
+			}
+			mintingPlugin, authCoinTxPlugin, assetsPlugin = plugins.Minting, plugins.AuthCoinTx, plugins.Assets
 
-			// create the auth coin tx plugin
-			// > NOTE: this also overwrites the standard tx controllers!!!!
-			authCoinTxPlugin = authcointx.NewPlugin(
-				setupNetworkCfg.GenesisAuthCondition,
-				rivchaintypes.TransactionVersionAuthAddressUpdate,
-				rivchaintypes.TransactionVersionAuthConditionUpdate,
-				nil, // no custom opts
-			)
+			// add the HTTP handlers for the minting extension
+			mintingapi.RegisterConsensusMintingHTTPHandlers(router, mintingPlugin)
+			// add the HTTP handlers for the assets extension
+			assetsapi.RegisterConsensusAssetsHTTPHandlers(router, assetsPlugin)
 			// add the HTTP handlers for the auth coin tx extension as well
 			if tpool != nil {
 				authcointxapi.RegisterConsensusAuthCoinHTTPHandlers(
@@ -227,38 +266,23 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 					nil, rivchaintypes.TransactionVersionAuthConditionUpdate,
 					rivchaintypes.TransactionVersionAuthAddressUpdate)
 			}
-
-			// register the minting extension plugin
-			err = cs.RegisterPlugin(ctx, "minting", mintingPlugin)
-			if err != nil {
-				servErrs <- fmt.Errorf("failed to register the minting extension: %v", err)
-				err = mintingPlugin.Close() //make sure any resources are released
-				if err != nil {
-					fmt.Println("Error during closing of the mintingPlugin :", err)
-				}
-				cancel()
-				return
-			}
-
-			// register the AuthCoin extension plugin
-			err = cs.RegisterPlugin(ctx, "authcointx", authCoinTxPlugin)
-			if err != nil {
-				servErrs <- fmt.Errorf("failed to register the auth coin tx extension: %v", err)
-				err = authCoinTxPlugin.Close() //make sure any resources are released
-				if err != nil {
-					fmt.Println("Error during closing of the authCoinTxPlugin :", err)
-				}
-				cancel()
-				return
+			// add the HTTP handlers for the (optional) spent outputs extension
+			if plugins.SpentOutputs != nil {
+				spentoutputsapi.RegisterConsensusSpentOutputsHTTPHandlers(router, plugins.SpentOutputs)
 			}
 		}
 
 		var w modules.Wallet
 		if moduleIdentifiers.Contains(daemon.WalletModule.Identifier()) {
 			printModuleIsLoading("wallet")
-			w, err = wallet.New(cs, tpool,
+			// the assets plugin tracks the assets of the coin outputs of a bootstrap snapshot
+			var assetGetter modules.CoinOutputAssetGetter
+			if assetsPlugin != nil {
+				assetGetter = assetsPlugin
+			}
+			w, err = wallet.NewWithAssets(cs, tpool,
 				filepath.Join(cfg.RootPersistentDir, modules.WalletDir),
-				cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging)
+				cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging, assetGetter)
 			if err != nil {
 				servErrs <- err
 				cancel()
@@ -297,9 +321,9 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 		var e modules.Explorer
 		if moduleIdentifiers.Contains(daemon.ExplorerModule.Identifier()) {
 			printModuleIsLoading("explorer")
-			e, err = explorer.New(cs,
+			e, err = explorer.NewWithBackend(cs,
 				filepath.Join(cfg.RootPersistentDir, modules.ExplorerDir),
-				cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging)
+				cfg.BlockchainInfo, networkCfg.Constants, cfg.VerboseLogging, dbBackend)
 			if err != nil {
 				servErrs <- err
 				cancel()
@@ -315,6 +339,7 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 			}()
 
 			mintingapi.RegisterExplorerMintingHTTPHandlers(router, mintingPlugin)
+			assetsapi.RegisterExplorerAssetsHTTPHandlers(router, assetsPlugin)
 			if tpool != nil {
 				authcointxapi.RegisterExplorerAuthCoinHTTPHandlers(
 					router, authCoinTxPlugin,
@@ -359,6 +384,129 @@ func runDaemon(cfg ExtendedDaemonConfig, moduleIdentifiers daemon.ModuleIdentifi
 	return <-servErrs
 }
 
+// importConsensusSnapshot bootstraps a fresh consensus set from the snapshot found at the given path,
+// the import is skipped if the consensus set already contains blocks other than the genesis block.
+func importConsensusSnapshot(cs *consensus.ConsensusSet, path string, trustedSnapshots map[types.BlockHeight]crypto.Hash) error {
+	if cs.Height() > 0 {
+		fmt.Println("Consensus set already contains blocks, skipping the import of snapshot", path)
+		return nil
+	}
+	fmt.Println("Importing consensus snapshot", path, "...")
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open consensus snapshot: %v", err)
+	}
+	defer file.Close()
+	snapshot, err := cs.ImportSnapshot(file, trustedSnapshots)
+	if err != nil {
+		return fmt.Errorf("failed to import consensus snapshot: %v", err)
+	}
+	fmt.Printf("Imported consensus snapshot at height %d (block %s)\n", snapshot.BlockHeight, snapshot.BlockID.String())
+	return nil
+}
+
+// configureConsensusSet applies the consensus related configuration
+// to a newly created consensus set, prior to it being started.
+func configureConsensusSet(cs *consensus.ConsensusSet, cfg ExtendedDaemonConfig, networkCfg daemon.NetworkConfig) error {
+	err := cs.SetCheckpoints(networkCfg.Checkpoints)
+	if err != nil {
+		return err
+	}
+	if cfg.MaxReorgDepth > 0 {
+		cs.SetMaxReorgDepth(types.BlockHeight(cfg.MaxReorgDepth))
+	}
+	if cfg.PruneDepth > 0 {
+		return cs.EnablePruning(types.BlockHeight(cfg.PruneDepth))
+	}
+	return nil
+}
+
+// consensusPlugins are the extension plugins registered to the consensus set.
+type consensusPlugins struct {
+	Minting    *minting.Plugin
+	AuthCoinTx *authcointx.Plugin
+	Assets     *assets.Plugin
+	// SpentOutputs is nil in case the spent outputs aren't indexed
+	SpentOutputs *spentoutputs.Plugin
+}
+
+// registerConsensusPlugins creates the extension plugins and registers them to the given consensus set.
+func registerConsensusPlugins(ctx context.Context, cs modules.ConsensusSet, setupNetworkCfg setupNetworkConfig, indexSpentOutputs bool) (consensusPlugins, error) {
+	var plugins consensusPlugins
+
+	// create the minting extension plugin
+	plugins.Minting = minting.NewMintingPlugin(
+		setupNetworkCfg.GenesisMintCondition,
+		rivchaintypes.TransactionVersionMinterDefinition,
+		rivchaintypes.TransactionVersionCoinCreation,
+		&minting.PluginOptions{
+			CoinDestructionTransactionVersion: rivchaintypes.TransactionVersionCoinDestruction,
+		},
+	)
+
+	// create the auth coin tx plugin
+	// > NOTE: this also overwrites the standard tx controllers!!!!
+	plugins.AuthCoinTx = authcointx.NewPlugin(
+		setupNetworkCfg.GenesisAuthCondition,
+		rivchaintypes.TransactionVersionAuthAddressUpdate,
+		rivchaintypes.TransactionVersionAuthConditionUpdate,
+		nil, // no custom opts
+	)
+
+	// create the assets extension plugin,
+	// its transactions are only accepted from the block height scheduled in the network config
+	plugins.Assets = assets.NewPlugin(rivchaintypes.TransactionVersionAssetTransaction, nil)
+
+	// register the minting extension plugin
+	err := cs.RegisterPlugin(ctx, "minting", plugins.Minting)
+	if err != nil {
+		err = fmt.Errorf("failed to register the minting extension: %v", err)
+		closeErr := plugins.Minting.Close() //make sure any resources are released
+		if closeErr != nil {
+			fmt.Println("Error during closing of the mintingPlugin :", closeErr)
+		}
+		return consensusPlugins{}, err
+	}
+
+	// register the AuthCoin extension plugin
+	err = cs.RegisterPlugin(ctx, "authcointx", plugins.AuthCoinTx)
+	if err != nil {
+		err = fmt.Errorf("failed to register the auth coin tx extension: %v", err)
+		closeErr := plugins.AuthCoinTx.Close() //make sure any resources are released
+		if closeErr != nil {
+			fmt.Println("Error during closing of the authCoinTxPlugin :", closeErr)
+		}
+		return consensusPlugins{}, err
+	}
+
+	// register the assets extension plugin
+	err = cs.RegisterPlugin(ctx, "assets", plugins.Assets)
+	if err != nil {
+		err = fmt.Errorf("failed to register the assets extension: %v", err)
+		closeErr := plugins.Assets.Close() //make sure any resources are released
+		if closeErr != nil {
+			fmt.Println("Error during closing of the assetsPlugin :", closeErr)
+		}
+		return consensusPlugins{}, err
+	}
+
+	// register the (optional) spent outputs extension plugin
+	if indexSpentOutputs {
+		plugins.SpentOutputs = spentoutputs.NewPlugin()
+		err = cs.RegisterPlugin(ctx, "spentoutputs", plugins.SpentOutputs)
+		if err != nil {
+			err = fmt.Errorf("failed to register the spent outputs extension: %v", err)
+			closeErr := plugins.SpentOutputs.Close() //make sure any resources are released
+			if closeErr != nil {
+				fmt.Println("Error during closing of the spentOutputsPlugin :", closeErr)
+			}
+			return consensusPlugins{}, err
+		}
+	}
+
+	return plugins, nil
+}
+
 type setupNetworkConfig struct {
 	NetworkConfig        daemon.NetworkConfig
 	GenesisMintCondition types.UnlockConditionProxy
@@ -383,8 +531,10 @@ func setupNetwork(cfg ExtendedDaemonConfig) (setupNetworkConfig, error) {
 		// return the genesis block and bootstrap peers
 		return setupNetworkConfig{
 			NetworkConfig: daemon.NetworkConfig{
-				Constants:      constants,
-				BootstrapPeers: bootstrapPeers,
+				Constants:        constants,
+				BootstrapPeers:   bootstrapPeers,
+				TrustedSnapshots: config.GetDevnetTrustedSnapshots(),
+				Checkpoints:      config.GetDevnetCheckpoints(),
 			},
 			GenesisMintCondition: config.GetDevnetGenesisMintCondition(),
 			GenesisAuthCondition: config.GetDevnetGenesisAuthCoinCondition(),
@@ -399,8 +549,10 @@ func setupNetwork(cfg ExtendedDaemonConfig) (setupNetworkConfig, error) {
 		// return the genesis block and bootstrap peers
 		return setupNetworkConfig{
 			NetworkConfig: daemon.NetworkConfig{
-				Constants:      constants,
-				BootstrapPeers: bootstrapPeers,
+				Constants:        constants,
+				BootstrapPeers:   bootstrapPeers,
+				TrustedSnapshots: config.GetStandardTrustedSnapshots(),
+				Checkpoints:      config.GetStandardCheckpoints(),
 			},
 			GenesisMintCondition: config.GetStandardGenesisMintCondition(),
 			GenesisAuthCondition: config.GetStandardGenesisAuthCoinCondition(),
@@ -415,8 +567,10 @@ func setupNetwork(cfg ExtendedDaemonConfig) (setupNetworkConfig, error) {
 		// return the genesis block and bootstrap peers
 		return setupNetworkConfig{
 			NetworkConfig: daemon.NetworkConfig{
-				Constants:      constants,
-				BootstrapPeers: bootstrapPeers,
+				Constants:        constants,
+				BootstrapPeers:   bootstrapPeers,
+				TrustedSnapshots: config.GetTestnetTrustedSnapshots(),
+				Checkpoints:      config.GetTestnetCheckpoints(),
 			},
 			GenesisMintCondition: config.GetTestnetGenesisMintCondition(),
 			GenesisAuthCondition: config.GetTestnetGenesisAuthCoinCondition(),
@@ -49,6 +49,35 @@ func main() {
 		Run:   cmds.modulesCommand,
 	})
 
+	exportBlocksCommand := &cobra.Command{
+		Use:   "export-blocks",
+		Short: "Export blocks to a block archive file",
+		Long: "Export a range of blocks of the local consensus set to a block archive file,\n" +
+			"which can be used to seed other nodes or as an offline backup.\n" +
+			"The daemon cannot be running while exporting blocks.",
+		Args: cobra.NoArgs,
+		Run:  cmds.exportBlocksCommand,
+	}
+	cmds.cfg.RegisterAsFlags(exportBlocksCommand.Flags())
+	exportBlocksCommand.Flags().Uint64Var(&cmds.blocks.exportFrom, "from", 0, "height of the first block to export")
+	exportBlocksCommand.Flags().Uint64Var(&cmds.blocks.exportTo, "to", 0, "height of the last block to export (defaults to the current height)")
+	exportBlocksCommand.Flags().StringVarP(&cmds.blocks.exportOut, "out", "o", "", "file path of the block archive to create")
+	exportBlocksCommand.MarkFlagRequired("out")
+	rootCommand.AddCommand(exportBlocksCommand)
+
+	importBlocksCommand := &cobra.Command{
+		Use:   "import-blocks <file>",
+		Short: "Import blocks from a block archive file",
+		Long: "Import the blocks of a block archive file into the local consensus set,\n" +
+			"validating each block as if it was received from the network.\n" +
+			"Blocks which are already known are skipped.\n" +
+			"The daemon cannot be running while importing blocks.",
+		Args: cobra.ExactArgs(1),
+		Run:  cmds.importBlocksCommand,
+	}
+	cmds.cfg.RegisterAsFlags(importBlocksCommand.Flags())
+	rootCommand.AddCommand(importBlocksCommand)
+
 	// Parse cmdline flags, overwriting both the default values and the config
 	// file values.
 	if err := rootCommand.Execute(); err != nil {
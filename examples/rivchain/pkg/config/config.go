@@ -4,6 +4,8 @@ import (
 	"math/big"
 
 	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
+	rivchaintypes "github.com/threefoldtech/rivine/examples/rivchain/pkg/types"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -17,11 +19,20 @@ var (
 	Version build.ProtocolVersion
 )
 
+// Block heights from which the asset transactions are accepted on the live networks,
+// as these were added after their launch. On the devnet they are active from the genesis block onwards.
+const (
+	standardAssetTransactionActivationHeight types.BlockHeight = 150000
+	testnetAssetTransactionActivationHeight  types.BlockHeight = 150000
+)
+
 const (
 	// TokenUnit defines the unit of one Token.
 	TokenUnit = "ROC"
 	// TokenChainName defines the name of the chain.
 	TokenChainName = "rivchain"
+	// TokenAddressPrefix defines the human-readable prefix of bech32-encoded addresses.
+	TokenAddressPrefix = "riv"
 )
 
 // chain network names
@@ -45,6 +56,7 @@ func GetBlockchainInfo() types.BlockchainInfo {
 		CoinUnit:        TokenUnit,
 		ChainVersion:    Version,       // use our own blockChain/build version
 		ProtocolVersion: build.Version, // use latest available rivine protocol version
+		AddressPrefix:   TokenAddressPrefix,
 	}
 }
 
@@ -121,6 +133,18 @@ func GetDevnetBootstrapPeers() []modules.NetAddress {
 	}
 }
 
+func GetDevnetTrustedSnapshots() map[types.BlockHeight]crypto.Hash {
+	// No consensus snapshots are published (yet) for this network,
+	// meaning --bootstrap-snapshot refuses any snapshot until the operator of
+	// this chain adds the checksum (and height) of a snapshot it exported and trusts.
+	return nil
+}
+
+func GetDevnetCheckpoints() map[types.BlockHeight]types.BlockID {
+	// no checkpoints are hard-coded (yet) for this network
+	return nil
+}
+
 func GetDevnetGenesisMintCondition() types.UnlockConditionProxy {
 	return types.NewCondition(types.NewUnlockHashCondition(unlockHashFromHex("015a080a9259b9d4aaa550e2156f49b1a79a64c7ea463d810d4493e8242e6791584fbdac553e6f")))
 }
@@ -193,6 +217,13 @@ func GetStandardGenesis() types.ChainConstants {
 		},
 	}
 
+	// schedule the activation of the asset transactions
+	cfg.ActivationSchedule = types.ActivationSchedule{
+		TransactionVersions: map[types.TransactionVersion]types.BlockHeight{
+			rivchaintypes.TransactionVersionAssetTransaction: standardAssetTransactionActivationHeight,
+		},
+	}
+
 	return cfg
 }
 
@@ -204,6 +235,18 @@ func GetStandardBootstrapPeers() []modules.NetAddress {
 	}
 }
 
+func GetStandardTrustedSnapshots() map[types.BlockHeight]crypto.Hash {
+	// No consensus snapshots are published (yet) for this network,
+	// meaning --bootstrap-snapshot refuses any snapshot until the operator of
+	// this chain adds the checksum (and height) of a snapshot it exported and trusts.
+	return nil
+}
+
+func GetStandardCheckpoints() map[types.BlockHeight]types.BlockID {
+	// no checkpoints are hard-coded (yet) for this network
+	return nil
+}
+
 func GetStandardGenesisMintCondition() types.UnlockConditionProxy {
 	return types.NewCondition(types.NewUnlockHashCondition(unlockHashFromHex("01b5e42056ef394f2ad9b511a61cec874d25bebe2095682dd37455cbafed4bec154e382a23f90e")))
 }
@@ -280,6 +323,13 @@ func GetTestnetGenesis() types.ChainConstants {
 		},
 	}
 
+	// schedule the activation of the asset transactions
+	cfg.ActivationSchedule = types.ActivationSchedule{
+		TransactionVersions: map[types.TransactionVersion]types.BlockHeight{
+			rivchaintypes.TransactionVersionAssetTransaction: testnetAssetTransactionActivationHeight,
+		},
+	}
+
 	return cfg
 }
 
@@ -291,6 +341,18 @@ func GetTestnetBootstrapPeers() []modules.NetAddress {
 	}
 }
 
+func GetTestnetTrustedSnapshots() map[types.BlockHeight]crypto.Hash {
+	// No consensus snapshots are published (yet) for this network,
+	// meaning --bootstrap-snapshot refuses any snapshot until the operator of
+	// this chain adds the checksum (and height) of a snapshot it exported and trusts.
+	return nil
+}
+
+func GetTestnetCheckpoints() map[types.BlockHeight]types.BlockID {
+	// no checkpoints are hard-coded (yet) for this network
+	return nil
+}
+
 func GetTestnetGenesisMintCondition() types.UnlockConditionProxy {
 	return types.NewCondition(types.NewUnlockHashCondition(unlockHashFromHex("01434535fd01243c02c277cd58d71423163767a575a8ae44e15807bf545e4a8456a5c4afabad51")))
 }
@@ -16,3 +16,9 @@ const (
 	TransactionVersionAuthAddressUpdate   types.TransactionVersion = 177
 	TransactionVersionAuthConditionUpdate types.TransactionVersion = 176
 )
+
+// Assets Extension Transaction Versions
+const (
+	//TransactionVersionAssetTransaction is the transaction version for the asset transaction
+	TransactionVersionAssetTransaction types.TransactionVersion = 144
+)
@@ -0,0 +1,11 @@
+package api
+
+import (
+	"github.com/threefoldtech/rivine/extensions/assets"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+)
+
+// RegisterConsensusAssetsHTTPHandlers registers the default Rivine handlers for all default Rivine consensus asset HTTP endpoints.
+func RegisterConsensusAssetsHTTPHandlers(router rapi.Router, plugin *assets.Plugin) {
+	router.GET("/consensus/assets/:id", NewTransactionDBGetAssetHandler(plugin))
+}
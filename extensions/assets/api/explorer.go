@@ -0,0 +1,11 @@
+package api
+
+import (
+	"github.com/threefoldtech/rivine/extensions/assets"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+)
+
+// RegisterExplorerAssetsHTTPHandlers registers the default Rivine handlers for all default Rivine explorer asset endpoints.
+func RegisterExplorerAssetsHTTPHandlers(router rapi.Router, plugin *assets.Plugin) {
+	router.GET("/explorer/assets/:id", NewTransactionDBGetAssetHandler(plugin))
+}
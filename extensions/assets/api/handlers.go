@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/threefoldtech/rivine/extensions/assets"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TransactionDBGetAsset contains a requested asset.
+type TransactionDBGetAsset struct {
+	Asset assets.Asset `json:"asset"`
+}
+
+// NewTransactionDBGetAssetHandler creates a handler to handle the API calls to /transactiondb/assets/:id.
+func NewTransactionDBGetAssetHandler(plugin *assets.Plugin) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		var assetID types.AssetID
+		err := assetID.LoadString(ps.ByName("id"))
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: fmt.Sprintf("invalid asset ID given: %v", err)}, http.StatusBadRequest)
+			return
+		}
+		asset, err := plugin.GetAsset(assetID)
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusNotFound)
+			return
+		}
+		rapi.WriteJSON(w, TransactionDBGetAsset{
+			Asset: asset,
+		})
+	}
+}
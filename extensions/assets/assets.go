@@ -0,0 +1,537 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/threefoldtech/rivine/persist/kv"
+)
+
+const (
+	pluginDBVersion = "1.0.0.0"
+	pluginDBHeader  = "assetsPlugin"
+)
+
+var (
+	// maps asset IDs to their (consensus) asset state
+	bucketAssets = []byte("assets")
+	// maps coin output IDs to the asset they hold,
+	// coin outputs which hold the native coin are not stored
+	bucketAssetOutputs = []byte("assetoutputs")
+)
+
+type (
+	// Plugin is a struct defines the assets plugin
+	Plugin struct {
+		assetTransactionVersion types.TransactionVersion
+		storage                 modules.PluginViewStorage
+		unregisterCallback      modules.PluginUnregisterCallback
+
+		binMarshal   func(v interface{}) ([]byte, error)
+		binUnmarshal func(b []byte, v interface{}) error
+	}
+
+	// PluginOptions allows optional parameters to be defined for the assets plugin.
+	PluginOptions struct {
+		UseLegacySiaEncoding bool
+	}
+)
+
+// NewPlugin creates a new assets Plugin,
+// registering the asset transaction controller for the given transaction version.
+func NewPlugin(assetTransactionVersion types.TransactionVersion, opts *PluginOptions) *Plugin {
+	p := &Plugin{
+		assetTransactionVersion: assetTransactionVersion,
+	}
+	legacyEncoding := opts != nil && opts.UseLegacySiaEncoding
+	types.RegisterTransactionVersion(assetTransactionVersion, AssetTransactionController{
+		UseLegacySiaEncoding: legacyEncoding,
+		AssetGetter:          p,
+		TransactionVersion:   assetTransactionVersion,
+	})
+	if legacyEncoding {
+		p.binMarshal = siabin.Marshal
+		p.binUnmarshal = siabin.Unmarshal
+	} else {
+		p.binMarshal = rivbin.Marshal
+		p.binUnmarshal = rivbin.Unmarshal
+	}
+	return p
+}
+
+// InitPlugin initializes the Buckets for the first time
+func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket kv.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
+	p.storage = storage
+	p.unregisterCallback = unregisterCallback
+	if metadata == nil {
+		for _, name := range [][]byte{bucketAssets, bucketAssetOutputs} {
+			_, err := bucket.CreateBucketIfNotExists(name)
+			if err != nil {
+				return persist.Metadata{}, fmt.Errorf("failed to create %s bucket: %v", string(name), err)
+			}
+		}
+		metadata = &persist.Metadata{
+			Version: pluginDBVersion,
+			Header:  pluginDBHeader,
+		}
+	} else if metadata.Version != pluginDBVersion {
+		return persist.Metadata{}, errors.New("There is only 1 version of this plugin, version mismatch")
+	}
+	return *metadata, nil
+}
+
+// ApplyBlock applies a block's asset transactions to the asset buckets.
+func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
+	if bucket == nil {
+		return errors.New("assets bucket does not exist")
+	}
+	var err error
+	for idx, txn := range block.Transactions {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            txn,
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err = p.ApplyTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyBlockHeader applies nothing and has no effect on this plugin.
+func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBucket) error {
+	return nil
+}
+
+// ApplyTransaction applies an asset transaction to the asset buckets.
+func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
+	if bucket == nil {
+		return errors.New("assets bucket does not exist")
+	}
+	if txn.Version != p.assetTransactionVersion {
+		return nil // only asset transactions are of interest
+	}
+	atx, err := AssetTransactionFromTransaction(txn.Transaction, p.assetTransactionVersion)
+	if err != nil {
+		return fmt.Errorf("unexpected error while unpacking the asset tx type: %v", err)
+	}
+	assetsBucket, assetOutputsBucket, err := getAssetBuckets(bucket)
+	if err != nil {
+		return err
+	}
+
+	// store all newly defined assets
+	for _, definition := range atx.Definitions {
+		err = p.putAsset(assetsBucket, definition.AssetID(), Asset{
+			Issuer:   definition.Issuer,
+			Mintable: definition.Mintable,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	// increase the supply of all defined and minted assets
+	deltas, err := p.assetSupplyDeltas(atx, txn.SpentCoinOutputs, assetOutputsBucket)
+	if err != nil {
+		return err
+	}
+	for assetID, delta := range deltas {
+		asset, err := p.getAsset(assetsBucket, assetID)
+		if err != nil {
+			return err
+		}
+		asset.Supply = asset.Supply.Add(delta)
+		err = p.putAsset(assetsBucket, assetID, asset)
+		if err != nil {
+			return err
+		}
+	}
+	// tag all coin outputs which hold an asset
+	for idx, assetID := range atx.CoinOutputAssets {
+		if assetID.IsNative() {
+			continue
+		}
+		outputID := txn.CoinOutputID(uint64(idx))
+		err = assetOutputsBucket.Put(outputID[:], assetID[:])
+		if err != nil {
+			return fmt.Errorf("failed to put asset %s for coin output %s: %v", assetID.String(), outputID.String(), err)
+		}
+	}
+	return nil
+}
+
+// RevertBlock reverts a block's asset transactions from the asset buckets.
+func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
+	if bucket == nil {
+		return errors.New("assets bucket does not exist")
+	}
+	// revert in reverse order, as a transaction can depend on an earlier transaction of the same block
+	var err error
+	for idx := len(block.Transactions) - 1; idx >= 0; idx-- {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            block.Transactions[idx],
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err = p.RevertTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertBlockHeader reverts nothing and has no effect on this plugin.
+func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBucket) error {
+	return nil
+}
+
+// RevertTransaction reverts an asset transaction from the asset buckets.
+func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
+	if bucket == nil {
+		return errors.New("assets bucket does not exist")
+	}
+	if txn.Version != p.assetTransactionVersion {
+		return nil // only asset transactions are of interest
+	}
+	atx, err := AssetTransactionFromTransaction(txn.Transaction, p.assetTransactionVersion)
+	if err != nil {
+		return fmt.Errorf("unexpected error while unpacking the asset tx type: %v", err)
+	}
+	assetsBucket, assetOutputsBucket, err := getAssetBuckets(bucket)
+	if err != nil {
+		return err
+	}
+
+	// untag all coin outputs which hold an asset
+	for idx, assetID := range atx.CoinOutputAssets {
+		if assetID.IsNative() {
+			continue
+		}
+		outputID := txn.CoinOutputID(uint64(idx))
+		err = assetOutputsBucket.Delete(outputID[:])
+		if err != nil {
+			return fmt.Errorf("failed to delete asset %s for coin output %s: %v", assetID.String(), outputID.String(), err)
+		}
+	}
+	// decrease the supply of all defined and minted assets,
+	// the spent coin outputs are never untagged, so the deltas can be computed once again
+	deltas, err := p.assetSupplyDeltas(atx, txn.SpentCoinOutputs, assetOutputsBucket)
+	if err != nil {
+		return err
+	}
+	for assetID, delta := range deltas {
+		asset, err := p.getAsset(assetsBucket, assetID)
+		if err != nil {
+			return err
+		}
+		asset.Supply = asset.Supply.Sub(delta)
+		err = p.putAsset(assetsBucket, assetID, asset)
+		if err != nil {
+			return err
+		}
+	}
+	// delete all assets defined by this transaction
+	for _, definition := range atx.Definitions {
+		assetID := definition.AssetID()
+		err = assetsBucket.Delete(assetID[:])
+		if err != nil {
+			return fmt.Errorf("failed to delete asset %s: %v", assetID.String(), err)
+		}
+	}
+	return nil
+}
+
+// GetAsset implements AssetGetter.GetAsset
+func (p *Plugin) GetAsset(id types.AssetID) (Asset, error) {
+	var asset Asset
+	err := p.storage.View(func(bucket kv.Bucket) error {
+		assetsBucket := bucket.Bucket(bucketAssets)
+		if assetsBucket == nil {
+			return errors.New("no assets bucket found")
+		}
+		var err error
+		asset, err = p.getAsset(assetsBucket, id)
+		return err
+	})
+	return asset, err
+}
+
+// GetCoinOutputAsset returns the ID of the asset held by the coin output associated with the given ID,
+// the zero ID in case it holds the native coin (or isn't known to the plugin).
+func (p *Plugin) GetCoinOutputAsset(id types.CoinOutputID) (types.AssetID, error) {
+	var assetID types.AssetID
+	err := p.storage.View(func(bucket kv.Bucket) error {
+		assetOutputsBucket := bucket.Bucket(bucketAssetOutputs)
+		if assetOutputsBucket == nil {
+			return errors.New("no asset outputs bucket found")
+		}
+		assetID = getCoinOutputAsset(assetOutputsBucket, id)
+		return nil
+	})
+	return assetID, err
+}
+
+func getAssetBuckets(bucket *persist.LazyBucket) (assetsBucket, assetOutputsBucket kv.Bucket, err error) {
+	assetsBucket, err = bucket.Bucket(bucketAssets)
+	if err != nil {
+		return nil, nil, errors.New("assets bucket does not exist")
+	}
+	assetOutputsBucket, err = bucket.Bucket(bucketAssetOutputs)
+	if err != nil {
+		return nil, nil, errors.New("asset outputs bucket does not exist")
+	}
+	return assetsBucket, assetOutputsBucket, nil
+}
+
+func (p *Plugin) getAsset(assetsBucket kv.Bucket, id types.AssetID) (Asset, error) {
+	b := assetsBucket.Get(id[:])
+	if len(b) == 0 {
+		return Asset{}, fmt.Errorf("asset %s is not defined", id.String())
+	}
+	var asset Asset
+	err := p.binUnmarshal(b, &asset)
+	if err != nil {
+		return Asset{}, fmt.Errorf("failed to decode asset %s: %v", id.String(), err)
+	}
+	return asset, nil
+}
+
+func (p *Plugin) putAsset(assetsBucket kv.Bucket, id types.AssetID, asset Asset) error {
+	b, err := p.binMarshal(asset)
+	if err != nil {
+		return fmt.Errorf("failed to marshal asset %s: %v", id.String(), err)
+	}
+	err = assetsBucket.Put(id[:], b)
+	if err != nil {
+		return fmt.Errorf("failed to put asset %s: %v", id.String(), err)
+	}
+	return nil
+}
+
+func getCoinOutputAsset(assetOutputsBucket kv.Bucket, id types.CoinOutputID) (assetID types.AssetID) {
+	copy(assetID[:], assetOutputsBucket.Get(id[:]))
+	return
+}
+
+// assetSupplyDeltas returns the amount of units each defined or minted asset
+// is increased with by the given asset transaction.
+func (p *Plugin) assetSupplyDeltas(atx AssetTransaction, spentCoinOutputs map[types.CoinOutputID]types.CoinOutput, assetOutputsBucket kv.Bucket) (map[types.AssetID]types.Currency, error) {
+	deltas := map[types.AssetID]types.Currency{}
+	for _, definition := range atx.Definitions {
+		deltas[definition.AssetID()] = types.Currency{}
+	}
+	for _, mint := range atx.Mints {
+		deltas[mint.AssetID] = types.Currency{}
+	}
+	if len(deltas) == 0 {
+		return nil, nil
+	}
+	inputs, err := coinInputAssetSums(atx.CoinInputs, spentCoinOutputs, assetOutputsBucket)
+	if err != nil {
+		return nil, err
+	}
+	outputs := coinOutputAssetSums(atx)
+	for assetID := range deltas {
+		deltas[assetID] = outputs[assetID].Sub(inputs[assetID])
+	}
+	return deltas, nil
+}
+
+// coinInputAssetSums returns the sum of the values spent by the given coin inputs, per asset.
+func coinInputAssetSums(coinInputs []types.CoinInput, spentCoinOutputs map[types.CoinOutputID]types.CoinOutput, assetOutputsBucket kv.Bucket) (map[types.AssetID]types.Currency, error) {
+	sums := map[types.AssetID]types.Currency{}
+	for _, ci := range coinInputs {
+		co, ok := spentCoinOutputs[ci.ParentID]
+		if !ok {
+			return nil, fmt.Errorf("unable to find parent ID %s as an unspent coin output", ci.ParentID.String())
+		}
+		assetID := getCoinOutputAsset(assetOutputsBucket, ci.ParentID)
+		sums[assetID] = sums[assetID].Add(co.Value)
+	}
+	return sums, nil
+}
+
+// coinOutputAssetSums returns the sum of the values of the coin outputs, per asset.
+func coinOutputAssetSums(atx AssetTransaction) map[types.AssetID]types.Currency {
+	sums := map[types.AssetID]types.Currency{}
+	for idx, co := range atx.CoinOutputs {
+		assetID := atx.CoinOutputAssets[idx]
+		sums[assetID] = sums[assetID].Add(co.Value)
+	}
+	return sums
+}
+
+// TransactionValidatorVersionFunctionMapping returns all tx validators linked to this plugin
+func (p *Plugin) TransactionValidatorVersionFunctionMapping() map[types.TransactionVersion][]modules.PluginTransactionValidationFunction {
+	return map[types.TransactionVersion][]modules.PluginTransactionValidationFunction{
+		p.assetTransactionVersion: []modules.PluginTransactionValidationFunction{
+			p.validateAssetTx,
+		},
+	}
+}
+
+// TransactionValidators returns all tx validators linked to this plugin
+func (p *Plugin) TransactionValidators() []modules.PluginTransactionValidationFunction {
+	return []modules.PluginTransactionValidationFunction{
+		p.validateAssetCoinOutputsAreNotSpentForAllTxs,
+	}
+}
+
+// validateAssetCoinOutputsAreNotSpentForAllTxs ensures that coin outputs which hold an asset,
+// can only be spent by asset transactions, as all other transactions treat them as native coins.
+func (p *Plugin) validateAssetCoinOutputsAreNotSpentForAllTxs(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error {
+	if tx.Version == p.assetTransactionVersion || len(tx.CoinInputs) == 0 {
+		return nil // nothing to do
+	}
+	assetOutputsBucket, err := bucket.Bucket(bucketAssetOutputs)
+	if err != nil {
+		return err
+	}
+	for _, ci := range tx.CoinInputs {
+		if assetID := getCoinOutputAsset(assetOutputsBucket, ci.ParentID); !assetID.IsNative() {
+			return fmt.Errorf(
+				"coin output %s holds asset %s and can only be spent by an asset transaction (v%d)",
+				ci.ParentID.String(), assetID.String(), p.assetTransactionVersion)
+		}
+	}
+	return nil
+}
+
+func (p *Plugin) validateAssetTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error {
+	atx, err := AssetTransactionFromTransaction(tx.Transaction, p.assetTransactionVersion)
+	if err != nil {
+		return fmt.Errorf("failed to use tx as an asset tx: %v", err)
+	}
+	assetsBucket, assetOutputsBucket, err := getAssetBuckets(bucket)
+	if err != nil {
+		return err
+	}
+	fulfillCtx := types.FulfillContext{
+		BlockHeight: ctx.BlockHeight,
+		BlockTime:   ctx.BlockTime,
+		Transaction: tx.Transaction,
+	}
+
+	// validate all asset definitions
+	unrestrictedAssets := map[types.AssetID]struct{}{}
+	for idx, definition := range atx.Definitions {
+		// ensure the Nonce is not Nil
+		if definition.Nonce == (types.TransactionNonce{}) {
+			return fmt.Errorf("nil nonce is not allowed for asset definition #%d", idx)
+		}
+		assetID := definition.AssetID()
+		if _, ok := unrestrictedAssets[assetID]; ok {
+			return fmt.Errorf("asset %s is defined multiple times", assetID.String())
+		}
+		if len(assetsBucket.Get(assetID[:])) != 0 {
+			return fmt.Errorf("asset %s is already defined", assetID.String())
+		}
+		// check if the issuer condition is valid
+		if definition.Issuer.ConditionType() == types.ConditionTypeNil {
+			return fmt.Errorf("issuer of asset %s cannot be the nil condition", assetID.String())
+		}
+		err = definition.Issuer.IsStandardCondition(ctx.ValidationContext)
+		if err != nil {
+			return fmt.Errorf("issuer of asset %s is not standard within the given blockchain context: %v", assetID.String(), err)
+		}
+		err = definition.Issuer.Fulfill(definition.IssuerFulfillment, fulfillCtx)
+		if err != nil {
+			return fmt.Errorf("failed to fulfill issuer condition of asset definition %s: %v", assetID.String(), err)
+		}
+		unrestrictedAssets[assetID] = struct{}{}
+	}
+
+	// validate all asset mints
+	for _, mint := range atx.Mints {
+		if _, ok := unrestrictedAssets[mint.AssetID]; ok {
+			return fmt.Errorf("asset %s is defined or minted multiple times", mint.AssetID.String())
+		}
+		asset, err := p.getAsset(assetsBucket, mint.AssetID)
+		if err != nil {
+			return err
+		}
+		if !asset.Mintable {
+			return fmt.Errorf("asset %s has a fixed supply and cannot be minted", mint.AssetID.String())
+		}
+		err = asset.Issuer.Fulfill(mint.IssuerFulfillment, fulfillCtx)
+		if err != nil {
+			return fmt.Errorf("failed to fulfill issuer condition of asset mint %s: %v", mint.AssetID.String(), err)
+		}
+		unrestrictedAssets[mint.AssetID] = struct{}{}
+	}
+
+	// collect the input and output sums per asset
+	inputs, err := coinInputAssetSums(atx.CoinInputs, tx.SpentCoinOutputs, assetOutputsBucket)
+	if err != nil {
+		return err
+	}
+	outputs := coinOutputAssetSums(atx)
+
+	// ensure all assets are balanced
+	for assetID, outputSum := range outputs {
+		if assetID.IsNative() {
+			continue
+		}
+		inputSum := inputs[assetID]
+		if _, ok := unrestrictedAssets[assetID]; ok {
+			// the supply of defined and minted assets can only increase
+			if outputSum.Cmp(inputSum) < 0 {
+				return fmt.Errorf(
+					"unbalanced asset %s: the sum of coin inputs (%s) is greater than its sum of coin outputs (%s)",
+					assetID.String(), inputSum.String(), outputSum.String())
+			}
+			continue
+		}
+		if len(assetsBucket.Get(assetID[:])) == 0 {
+			return fmt.Errorf("coin output holds unknown asset %s", assetID.String())
+		}
+		if !inputSum.Equals(outputSum) {
+			return fmt.Errorf(
+				"unbalanced asset %s: the sum of coin inputs (%s) does not equal its sum of coin outputs (%s)",
+				assetID.String(), inputSum.String(), outputSum.String())
+		}
+	}
+	for assetID, inputSum := range inputs {
+		if _, ok := outputs[assetID]; !ok && !assetID.IsNative() {
+			return fmt.Errorf(
+				"unbalanced asset %s: the sum of coin inputs (%s) is spent without any coin outputs",
+				assetID.String(), inputSum.String())
+		}
+	}
+
+	// ensure the native coin is balanced, taking the miner fees into account
+	nativeOutputSum := outputs[types.AssetID{}]
+	for _, fee := range atx.MinerFees {
+		nativeOutputSum = nativeOutputSum.Add(fee)
+	}
+	if nativeInputSum := inputs[types.AssetID{}]; !nativeInputSum.Equals(nativeOutputSum) {
+		return fmt.Errorf(
+			"unbalanced coin outputs: the sum of coin inputs (%s) for tx %s does not equal its sum of coin outputs (%s)",
+			nativeInputSum.String(), tx.ID().String(), nativeOutputSum.String())
+	}
+
+	return nil // valid what this validator concerns
+}
+
+// Close unregisters the plugin from the consensus
+func (p *Plugin) Close() error {
+	if p.storage == nil {
+		return nil
+	}
+	return p.storage.Close()
+}
@@ -0,0 +1,266 @@
+package assets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
+)
+
+var pluginBucketName = []byte("assets")
+
+// testViewStorage implements modules.PluginViewStorage on top of a key-value database.
+type testViewStorage struct {
+	db kv.DB
+}
+
+func (s testViewStorage) View(callback func(bucket kv.Bucket) error) error {
+	return s.db.View(func(tx kv.Tx) error {
+		return callback(tx.Bucket(pluginBucketName))
+	})
+}
+
+func (s testViewStorage) Close() error { return nil }
+
+func TestAssetsPlugin(t *testing.T) {
+	dir := build.TempDir("assets", t.Name())
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := persist.OpenDatabase(persist.Metadata{Header: "test", Version: "1.0"}, filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	storage := testViewStorage{db: db.DB}
+
+	plugin := NewPlugin(testAssetTxVersion, nil)
+	defer types.RegisterTransactionVersion(testAssetTxVersion, nil)
+	err = db.Update(func(tx kv.Tx) error {
+		bucket, err := tx.CreateBucket(pluginBucketName)
+		if err != nil {
+			return err
+		}
+		_, err = plugin.InitPlugin(nil, bucket, storage, nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sk, rpk := crypto.GenerateKeyPair()
+	uh, err := types.NewPubKeyUnlockHash(types.Ed25519PublicKey(rpk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := types.NewCondition(types.NewUnlockHashCondition(uh))
+	newFulfillment := func() types.UnlockFulfillmentProxy {
+		return types.NewFulfillment(types.NewSingleSignatureFulfillment(types.Ed25519PublicKey(rpk)))
+	}
+	sign := func(atx AssetTransaction) types.Transaction {
+		tx := atx.Transaction(testAssetTxVersion)
+		err := tx.SignExtension(func(fulfillment *types.UnlockFulfillmentProxy, condition types.UnlockConditionProxy, extraObjects ...interface{}) error {
+			return fulfillment.Sign(types.FulfillmentSignContext{
+				ExtraObjects: extraObjects,
+				Transaction:  tx,
+				Key:          sk,
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+
+	spentCoinOutputs := map[types.CoinOutputID]types.CoinOutput{
+		{1}: {Value: types.NewCurrency64(10), Condition: issuer},
+	}
+	update := func(tx types.Transaction, fn func(modules.ConsensusTransaction, *persist.LazyBucket) error) {
+		err := db.Update(func(btx kv.Tx) error {
+			return fn(modules.ConsensusTransaction{
+				Transaction:      tx,
+				SpentCoinOutputs: spentCoinOutputs,
+			}, persist.NewLazyBucket(func() (kv.Bucket, error) {
+				return btx.Bucket(pluginBucketName), nil
+			}))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	validate := func(tx types.Transaction) error {
+		return db.View(func(btx kv.Tx) error {
+			bucket := persist.NewLazyBucket(func() (kv.Bucket, error) {
+				return btx.Bucket(pluginBucketName), nil
+			})
+			ctx := types.TransactionValidationContext{
+				ValidationContext: types.ValidationContext{
+					BlockTime: types.CurrentTimestamp(),
+				},
+			}
+			cTxn := modules.ConsensusTransaction{
+				Transaction:      tx,
+				SpentCoinOutputs: spentCoinOutputs,
+			}
+			for _, validator := range plugin.TransactionValidatorVersionFunctionMapping()[tx.Version] {
+				if err := validator(cTxn, ctx, bucket); err != nil {
+					return err
+				}
+			}
+			for _, validator := range plugin.TransactionValidators() {
+				if err := validator(cTxn, ctx, bucket); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	// define a mintable asset, with an initial supply of 100 units
+	definition := AssetDefinition{
+		Nonce:             types.RandomTransactionNonce(),
+		Issuer:            issuer,
+		Mintable:          true,
+		IssuerFulfillment: newFulfillment(),
+	}
+	assetID := definition.AssetID()
+	defineTx := sign(AssetTransaction{
+		CoinInputs: []types.CoinInput{{ParentID: types.CoinOutputID{1}}},
+		CoinOutputs: []types.CoinOutput{
+			{Value: types.NewCurrency64(100), Condition: issuer},
+			{Value: types.NewCurrency64(9), Condition: issuer},
+		},
+		CoinOutputAssets: []types.AssetID{assetID, {}},
+		Definitions:      []AssetDefinition{definition},
+		MinerFees:        []types.Currency{types.NewCurrency64(1)},
+	})
+	if err = validate(defineTx); err != nil {
+		t.Fatalf("expected asset definition to be valid: %v", err)
+	}
+	if _, err = plugin.GetAsset(assetID); err == nil {
+		t.Fatal("expected asset to not be defined yet")
+	}
+	update(defineTx, plugin.ApplyTransaction)
+	asset, err := plugin.GetAsset(assetID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !asset.Mintable || !asset.Supply.Equals64(100) || asset.Issuer.UnlockHash() != uh {
+		t.Fatalf("unexpected asset: %v", asset)
+	}
+	if err = validate(defineTx); err == nil {
+		t.Fatal("expected an asset to not be definable twice")
+	}
+
+	// the coin outputs of the asset can only be spent by asset transactions
+	assetOutputID := defineTx.CoinOutputID(0)
+	coinOutputID := defineTx.CoinOutputID(1)
+	if id, err := plugin.GetCoinOutputAsset(assetOutputID); err != nil || id != assetID {
+		t.Fatalf("unexpected coin output asset: %s (%v)", id.String(), err)
+	}
+	if id, err := plugin.GetCoinOutputAsset(coinOutputID); err != nil || !id.IsNative() {
+		t.Fatalf("unexpected coin output asset: %s (%v)", id.String(), err)
+	}
+	spentCoinOutputs[assetOutputID] = defineTx.CoinOutputs[0]
+	spentCoinOutputs[coinOutputID] = defineTx.CoinOutputs[1]
+	err = validate(types.Transaction{
+		Version:     types.TransactionVersionOne,
+		CoinInputs:  []types.CoinInput{{ParentID: assetOutputID}},
+		CoinOutputs: []types.CoinOutput{{Value: types.NewCurrency64(99), Condition: issuer}},
+		MinerFees:   []types.Currency{types.NewCurrency64(1)},
+	})
+	if err == nil {
+		t.Fatal("expected asset coin output to not be spendable by a regular transaction")
+	}
+
+	// each asset is balanced separately
+	transfer := func(assetValue, coinValue uint64, mints []AssetMint) types.Transaction {
+		return sign(AssetTransaction{
+			CoinInputs: []types.CoinInput{{ParentID: assetOutputID}, {ParentID: coinOutputID}},
+			CoinOutputs: []types.CoinOutput{
+				{Value: types.NewCurrency64(assetValue), Condition: issuer},
+				{Value: types.NewCurrency64(coinValue), Condition: issuer},
+			},
+			CoinOutputAssets: []types.AssetID{assetID, {}},
+			Mints:            mints,
+			MinerFees:        []types.Currency{types.NewCurrency64(1)},
+		})
+	}
+	if err = validate(transfer(100, 8, nil)); err != nil {
+		t.Fatalf("expected balanced asset transfer to be valid: %v", err)
+	}
+	if err = validate(transfer(101, 7, nil)); err == nil {
+		t.Fatal("expected coins to not be convertible into asset units")
+	}
+	if err = validate(transfer(99, 9, nil)); err == nil {
+		t.Fatal("expected asset units to not be convertible into coins")
+	}
+	if err = validate(transfer(99, 8, nil)); err == nil {
+		t.Fatal("expected asset units to not be destroyable")
+	}
+
+	// the issuer can mint new units of a mintable asset
+	mintTx := transfer(150, 8, []AssetMint{{AssetID: assetID, IssuerFulfillment: newFulfillment()}})
+	if err = validate(mintTx); err != nil {
+		t.Fatalf("expected asset mint to be valid: %v", err)
+	}
+	update(mintTx, plugin.ApplyTransaction)
+	if asset, err = plugin.GetAsset(assetID); err != nil || !asset.Supply.Equals64(150) {
+		t.Fatalf("unexpected asset supply: %s (%v)", asset.Supply.String(), err)
+	}
+	// but only with a valid issuer fulfillment
+	invalidMintTx := transfer(150, 8, []AssetMint{{AssetID: assetID, IssuerFulfillment: newFulfillment()}})
+	invalidMintTx.Extension.(*AssetTransactionExtension).Mints[0].IssuerFulfillment = newFulfillment()
+	if err = validate(invalidMintTx); err == nil {
+		t.Fatal("expected asset mint without issuer signature to be invalid")
+	}
+
+	// units of a fixed asset cannot be minted
+	fixedDefinition := AssetDefinition{
+		Nonce:             types.RandomTransactionNonce(),
+		Issuer:            issuer,
+		IssuerFulfillment: newFulfillment(),
+	}
+	fixedDefineTx := sign(AssetTransaction{
+		CoinInputs:       []types.CoinInput{{ParentID: types.CoinOutputID{1}}},
+		CoinOutputs:      []types.CoinOutput{{Value: types.NewCurrency64(9), Condition: issuer}},
+		CoinOutputAssets: []types.AssetID{{}},
+		Definitions:      []AssetDefinition{fixedDefinition},
+		MinerFees:        []types.Currency{types.NewCurrency64(1)},
+	})
+	update(fixedDefineTx, plugin.ApplyTransaction)
+	fixedMintTx := sign(AssetTransaction{
+		CoinInputs:       []types.CoinInput{{ParentID: coinOutputID}},
+		CoinOutputs:      []types.CoinOutput{{Value: types.NewCurrency64(1), Condition: issuer}, {Value: types.NewCurrency64(8), Condition: issuer}},
+		CoinOutputAssets: []types.AssetID{fixedDefinition.AssetID(), {}},
+		Mints:            []AssetMint{{AssetID: fixedDefinition.AssetID(), IssuerFulfillment: newFulfillment()}},
+		MinerFees:        []types.Currency{types.NewCurrency64(1)},
+	})
+	if err = validate(fixedMintTx); err == nil {
+		t.Fatal("expected a fixed asset to not be mintable")
+	}
+
+	// reverting restores the previous state
+	update(fixedDefineTx, plugin.RevertTransaction)
+	if _, err = plugin.GetAsset(fixedDefinition.AssetID()); err == nil {
+		t.Fatal("expected reverted asset to no longer be defined")
+	}
+	update(mintTx, plugin.RevertTransaction)
+	if asset, err = plugin.GetAsset(assetID); err != nil || !asset.Supply.Equals64(100) {
+		t.Fatalf("unexpected asset supply: %s (%v)", asset.Supply.String(), err)
+	}
+	update(defineTx, plugin.RevertTransaction)
+	if _, err = plugin.GetAsset(assetID); err == nil {
+		t.Fatal("expected reverted asset to no longer be defined")
+	}
+	if id, err := plugin.GetCoinOutputAsset(assetOutputID); err != nil || !id.IsNative() {
+		t.Fatalf("expected reverted coin output to no longer hold an asset: %s (%v)", id.String(), err)
+	}
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"fmt"
+
+	assets "github.com/threefoldtech/rivine/extensions/assets"
+	"github.com/threefoldtech/rivine/extensions/assets/api"
+	client "github.com/threefoldtech/rivine/pkg/client"
+	types "github.com/threefoldtech/rivine/types"
+)
+
+// PluginClient is used to be able to get the consensus state of a defined asset,
+// such that the CLI can sign the issuer fulfillment of an asset mint,
+// without requiring access to the consensus-extended transactiondb.
+type PluginClient struct {
+	client       client.BaseClient
+	rootEndpoint string
+}
+
+// NewPluginConsensusClient creates a new PluginClient,
+// that can be used for easy interaction with the TransactionDB API exposed via the Consensus endpoints
+func NewPluginConsensusClient(cli client.BaseClient) *PluginClient {
+	if cli == nil {
+		panic("no BaseClient given")
+	}
+	return &PluginClient{
+		client:       cli,
+		rootEndpoint: "/consensus",
+	}
+}
+
+// NewPluginExplorerClient creates a new PluginClient,
+// that can be used for easy interaction with the TransactionDB API exposed via the Explorer endpoints
+func NewPluginExplorerClient(cli client.BaseClient) *PluginClient {
+	if cli == nil {
+		panic("no BaseClient given")
+	}
+	return &PluginClient{
+		client:       cli,
+		rootEndpoint: "/explorer",
+	}
+}
+
+var (
+	// ensure PluginClient implements the AssetGetter interface
+	_ assets.AssetGetter = (*PluginClient)(nil)
+)
+
+// GetAsset implements assets.AssetGetter.GetAsset
+func (cli *PluginClient) GetAsset(id types.AssetID) (assets.Asset, error) {
+	var result api.TransactionDBGetAsset
+	err := cli.client.HTTP().GetWithResponse(cli.rootEndpoint+"/assets/"+id.String(), &result)
+	if err != nil {
+		return assets.Asset{}, fmt.Errorf(
+			"failed to get asset %s from daemon: %v", id.String(), err)
+	}
+	return result.Asset, nil
+}
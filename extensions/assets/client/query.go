@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/threefoldtech/rivine/pkg/cli"
+	client "github.com/threefoldtech/rivine/pkg/client"
+	types "github.com/threefoldtech/rivine/types"
+)
+
+// CreateExploreCmd adds the explorer cli subcommands for the assets plugin
+func CreateExploreCmd(cli *client.CommandLineClient) error {
+	bc, err := client.NewLazyBaseClientFromCommandLineClient(cli)
+	if err != nil {
+		return err
+	}
+	createCmd(cli.ExploreCmd, NewPluginExplorerClient(bc))
+	return nil
+}
+
+// CreateConsensusCmd adds the consensus cli subcommands for the assets plugin
+func CreateConsensusCmd(cli *client.CommandLineClient) error {
+	bc, err := client.NewLazyBaseClientFromCommandLineClient(cli)
+	if err != nil {
+		return err
+	}
+	createCmd(cli.ConsensusCmd, NewPluginConsensusClient(bc))
+	return nil
+}
+
+func createCmd(rootCmd *cobra.Command, pluginClient *PluginClient) {
+	subCmds := &subCmd{
+		pluginClient: pluginClient,
+	}
+
+	// create root explore command and all subs
+	var (
+		getAssetCmd = &cobra.Command{
+			Use:   "asset <assetID>",
+			Short: "Get a defined asset",
+			Long: `Get the issuer condition, the mintable flag
+and the current supply of a defined asset.
+`,
+			Args: cobra.ExactArgs(1),
+			Run:  subCmds.getAsset,
+		}
+	)
+
+	getAssetCmd.Flags().Var(
+		cli.NewEncodingTypeFlag(cli.EncodingTypeHuman, &subCmds.getAssetCfg.EncodingType, cli.EncodingTypeJSON|cli.EncodingTypeHuman), "encoding",
+		cli.EncodingTypeFlagDescription(cli.EncodingTypeJSON|cli.EncodingTypeHuman))
+
+	// Add getAssetCmd to the root command
+	rootCmd.AddCommand(getAssetCmd)
+}
+
+type subCmd struct {
+	pluginClient *PluginClient
+	getAssetCfg  struct {
+		EncodingType cli.EncodingType
+	}
+}
+
+func (subCmds *subCmd) getAsset(cmd *cobra.Command, args []string) {
+	var assetID types.AssetID
+	err := assetID.LoadString(args[0])
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.DieWithError("invalid asset ID given", err)
+	}
+	asset, err := subCmds.pluginClient.GetAsset(assetID)
+	if err != nil {
+		cli.DieWithError("failed to get the asset", err)
+	}
+
+	// encode depending on the encoding flag
+	var encode func(interface{}) error
+	switch subCmds.getAssetCfg.EncodingType {
+	case cli.EncodingTypeHuman:
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		encode = e.Encode
+	case cli.EncodingTypeJSON:
+		encode = json.NewEncoder(os.Stdout).Encode
+	}
+	err = encode(asset)
+	if err != nil {
+		cli.DieWithError("failed to encode asset", err)
+	}
+}
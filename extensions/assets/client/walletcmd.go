@@ -0,0 +1,353 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	assets "github.com/threefoldtech/rivine/extensions/assets"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/threefoldtech/rivine/pkg/cli"
+	client "github.com/threefoldtech/rivine/pkg/client"
+)
+
+// CreateWalletCmds adds the wallet cli subcommands for the assets plugin
+func CreateWalletCmds(ccli *client.CommandLineClient, assetTxVersion types.TransactionVersion) error {
+	bc, err := client.NewLazyBaseClientFromCommandLineClient(ccli)
+	if err != nil {
+		return err
+	}
+	walletCmd := &walletCmd{
+		cli:            ccli,
+		walletClient:   client.NewWalletClient(bc),
+		txPoolClient:   client.NewTransactionPoolClient(bc),
+		assetTxVersion: assetTxVersion,
+	}
+
+	// create all wallet sub commands
+	var (
+		sendAssetCmd = &cobra.Command{
+			Use:   "asset <assetID> <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...",
+			Short: "Send units of an asset to one or multiple addresses",
+			Long: `Send units of an asset to one or multiple addresses.
+The outputs can be given as a pair of value and a raw output condition (or
+address, which resolves to a singlesignature condition).
+
+Amounts have to be given as a number of (indivisible) asset units.
+
+The asset units are funded using the wallet's outputs which hold the asset,
+while the Minimum Miner Fee is funded using the wallet's coins.
+`,
+			Run: walletCmd.sendAssetCmd,
+		}
+		createAssetTxCmd = &cobra.Command{
+			Use:   "assettransaction <issuer>|<rawCondition> <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...",
+			Short: "Create a new asset transaction, defining a new asset",
+			Long: `Create a new asset transaction, defining a new asset using the given issuer condition.
+The initial supply of the asset is created using the given outputs,
+which can be given as a pair of value and a raw output condition (or
+address, which resolves to a singlesignature condition).
+
+Amounts have to be given as a number of (indivisible) asset units.
+Unless the --mintable flag is given, the asset has a fixed supply.
+
+The Minimum Miner Fee is funded using the wallet's coins.
+The ID of the new asset is printed to STDERR.
+
+The returned (raw) AssetTransaction still has to be signed, prior to sending.
+`,
+			Run: walletCmd.createAssetTxCmd,
+		}
+		createAssetMintTxCmd = &cobra.Command{
+			Use:   "assetminttransaction <assetID> <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...",
+			Short: "Create a new asset transaction, minting units of a mintable asset",
+			Long: `Create a new asset transaction, minting units of a mintable asset into the given outputs.
+The outputs can be given as a pair of value and a raw output condition (or
+address, which resolves to a singlesignature condition).
+
+Amounts have to be given as a number of (indivisible) asset units.
+
+The Minimum Miner Fee is funded using the wallet's coins.
+
+The returned (raw) AssetTransaction still has to be signed, prior to sending.
+`,
+			Run: walletCmd.createAssetMintTxCmd,
+		}
+	)
+
+	// add commands as wallet sub commands
+	ccli.WalletCmd.RootCmdSend.AddCommand(sendAssetCmd)
+	ccli.WalletCmd.RootCmdCreate.AddCommand(
+		createAssetTxCmd,
+		createAssetMintTxCmd,
+	)
+
+	// set the flags
+	cli.ArbitraryDataFlagVar(sendAssetCmd.Flags(), &walletCmd.sendAssetCfg.Data,
+		"data", "optional arbitrary data (or description) to attach to transaction")
+	sendAssetCmd.Flags().StringVar(
+		&walletCmd.sendAssetCfg.RefundAddress,
+		"refund-address", "", "define a custom refund address")
+	sendAssetCmd.Flags().BoolVar(
+		&walletCmd.sendAssetCfg.RefundAddressNew,
+		"refund-address-new", false, "generate a new refund address if a refund needs to happen")
+	cli.ArbitraryDataFlagVar(createAssetTxCmd.Flags(), &walletCmd.createAssetTxCfg.Description,
+		"description", "optionally add a description to describe the asset, added as arbitrary data")
+	createAssetTxCmd.Flags().BoolVar(
+		&walletCmd.createAssetTxCfg.Mintable,
+		"mintable", false, "allow the issuer to mint new units of the asset in future transactions")
+	cli.ArbitraryDataFlagVar(createAssetMintTxCmd.Flags(), &walletCmd.createAssetMintTxCfg.Description,
+		"description", "optionally add a description to describe the origins of the minted units, added as arbitrary data")
+
+	return nil
+}
+
+type walletCmd struct {
+	cli          *client.CommandLineClient
+	walletClient *client.WalletClient
+	txPoolClient *client.TransactionPoolClient
+
+	assetTxVersion types.TransactionVersion
+
+	sendAssetCfg struct {
+		Data             []byte
+		RefundAddress    string
+		RefundAddressNew bool
+	}
+	createAssetTxCfg struct {
+		Description []byte
+		Mintable    bool
+	}
+	createAssetMintTxCfg struct {
+		Description []byte
+	}
+}
+
+func (walletCmd *walletCmd) sendAssetCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		cmd.UsageFunc()(cmd)
+		cli.Die("Invalid arguments. Arguments must be of the form <assetID> <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...")
+	}
+	var assetID types.AssetID
+	err := assetID.LoadString(args[0])
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.DieWithError("invalid asset ID given", err)
+	}
+	if assetID.IsNative() {
+		cmd.UsageFunc()(cmd)
+		cli.Die("the native coin cannot be sent as an asset, use `wallet send coins` instead")
+	}
+	pairs, err := parsePairedOutputs(args[1:])
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die(err)
+	}
+
+	// define the optional user-defined refund address
+	var refundAddress *types.UnlockHash
+	if walletCmd.sendAssetCfg.RefundAddress != "" {
+		refundAddress = new(types.UnlockHash)
+		err = refundAddress.LoadString(walletCmd.sendAssetCfg.RefundAddress)
+		if err != nil {
+			cmd.UsageFunc()(cmd)
+			cli.DieWithError("invalid refund address specified", err)
+		}
+	}
+
+	// fund the asset units
+	var amount types.Currency
+	for _, pair := range pairs {
+		amount = amount.Add(pair.Value)
+	}
+	coinInputs, refundCoinOutput, err := walletCmd.walletClient.FundAsset(assetID, amount, refundAddress, walletCmd.sendAssetCfg.RefundAddressNew)
+	if err != nil {
+		cli.DieWithError("failed to fund asset transaction", err)
+	}
+
+	// assemble the transaction
+	atx := assets.AssetTransaction{
+		CoinInputs: coinInputs,
+		MinerFees:  []types.Currency{walletCmd.cli.Config.MinimumTransactionFee},
+	}
+	for _, pair := range pairs {
+		atx.CoinOutputs = append(atx.CoinOutputs, types.CoinOutput{
+			Value:     pair.Value,
+			Condition: pair.Condition,
+		})
+		atx.CoinOutputAssets = append(atx.CoinOutputAssets, assetID)
+	}
+	if refundCoinOutput != nil {
+		atx.CoinOutputs = append(atx.CoinOutputs, *refundCoinOutput)
+		atx.CoinOutputAssets = append(atx.CoinOutputAssets, assetID)
+	}
+	walletCmd.fundMinerFees(&atx, refundAddress, walletCmd.sendAssetCfg.RefundAddressNew)
+	if n := len(walletCmd.sendAssetCfg.Data); n > 0 {
+		atx.ArbitraryData = make([]byte, n)
+		copy(atx.ArbitraryData[:], walletCmd.sendAssetCfg.Data[:])
+	}
+
+	// sign the transaction
+	tx := atx.Transaction(walletCmd.assetTxVersion)
+	err = walletCmd.walletClient.GreedySignTx(&tx)
+	if err != nil {
+		cli.DieWithError("failed to sign asset transaction", err)
+	}
+
+	// send the transaction
+	txID, err := walletCmd.txPoolClient.AddTransactiom(tx)
+	if err != nil {
+		cli.DieWithError("failed to send asset transaction", err)
+	}
+	fmt.Println("Succesfully sent asset as transaction " + txID.String())
+	for _, pair := range pairs {
+		fmt.Printf("Sent %s units of asset %s to %s (using ConditionType %d)\n",
+			pair.Value.String(), assetID.String(), pair.Condition.UnlockHash(),
+			pair.Condition.ConditionType())
+	}
+}
+
+func (walletCmd *walletCmd) createAssetTxCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		cmd.UsageFunc()(cmd)
+		cli.Die("Invalid arguments. Arguments must be of the form <issuer>|<rawCondition> <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...")
+	}
+	issuer, err := client.ParseConditionString(args[0])
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die(err)
+	}
+	pairs, err := parsePairedOutputs(args[1:])
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die(err)
+	}
+
+	// define the asset with a random nonce
+	definition := assets.AssetDefinition{
+		Nonce:    types.RandomTransactionNonce(),
+		Issuer:   issuer,
+		Mintable: walletCmd.createAssetTxCfg.Mintable,
+	}
+	assetID := definition.AssetID()
+	atx := assets.AssetTransaction{
+		Definitions: []assets.AssetDefinition{definition},
+		MinerFees:   []types.Currency{walletCmd.cli.Config.MinimumTransactionFee},
+	}
+	walletCmd.createAssetOutputs(&atx, assetID, pairs, walletCmd.createAssetTxCfg.Description)
+
+	fmt.Fprintln(os.Stderr, "asset ID: "+assetID.String())
+	err = json.NewEncoder(os.Stdout).Encode(atx.Transaction(walletCmd.assetTxVersion))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (walletCmd *walletCmd) createAssetMintTxCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 3 || len(args)%2 != 1 {
+		cmd.UsageFunc()(cmd)
+		cli.Die("Invalid arguments. Arguments must be of the form <assetID> <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...")
+	}
+	var assetID types.AssetID
+	err := assetID.LoadString(args[0])
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.DieWithError("invalid asset ID given", err)
+	}
+	pairs, err := parsePairedOutputs(args[1:])
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die(err)
+	}
+
+	atx := assets.AssetTransaction{
+		Mints:     []assets.AssetMint{{AssetID: assetID}},
+		MinerFees: []types.Currency{walletCmd.cli.Config.MinimumTransactionFee},
+	}
+	walletCmd.createAssetOutputs(&atx, assetID, pairs, walletCmd.createAssetMintTxCfg.Description)
+
+	err = json.NewEncoder(os.Stdout).Encode(atx.Transaction(walletCmd.assetTxVersion))
+	if err != nil {
+		panic(err)
+	}
+}
+
+// createAssetOutputs adds the given pairs as outputs holding the given asset,
+// funds the miner fees and adds the optional description as arbitrary data.
+func (walletCmd *walletCmd) createAssetOutputs(atx *assets.AssetTransaction, assetID types.AssetID, pairs []outputPair, description []byte) {
+	for _, pair := range pairs {
+		atx.CoinOutputs = append(atx.CoinOutputs, types.CoinOutput{
+			Value:     pair.Value,
+			Condition: pair.Condition,
+		})
+		atx.CoinOutputAssets = append(atx.CoinOutputAssets, assetID)
+	}
+	walletCmd.fundMinerFees(atx, nil, false)
+	if n := len(description); n > 0 {
+		atx.ArbitraryData = make([]byte, n)
+		copy(atx.ArbitraryData[:], description[:])
+	}
+}
+
+// fundMinerFees funds the miner fees of the given asset transaction using the wallet's coins.
+func (walletCmd *walletCmd) fundMinerFees(atx *assets.AssetTransaction, refundAddress *types.UnlockHash, newRefundAddress bool) {
+	var fee types.Currency
+	for _, minerFee := range atx.MinerFees {
+		fee = fee.Add(minerFee)
+	}
+	coinInputs, refundCoinOutput, err := walletCmd.walletClient.FundCoins(fee, refundAddress, newRefundAddress)
+	if err != nil {
+		cli.DieWithError("failed to fund the miner fees of the asset transaction", err)
+	}
+	atx.CoinInputs = append(atx.CoinInputs, coinInputs...)
+	if refundCoinOutput != nil {
+		atx.CoinOutputs = append(atx.CoinOutputs, *refundCoinOutput)
+		atx.CoinOutputAssets = append(atx.CoinOutputAssets, types.AssetID{})
+	}
+}
+
+type outputPair struct {
+	Condition types.UnlockConditionProxy
+	Value     types.Currency
+}
+
+// parsePairedOutputs parses the given arguments as pairs of conditions and asset unit amounts
+func parsePairedOutputs(args []string) (pairs []outputPair, err error) {
+	argn := len(args)
+	if argn < 2 {
+		err = errors.New("not enough arguments, at least 2 required")
+		return
+	}
+	if argn%2 != 0 {
+		err = errors.New("arguments have to be given in pairs of '<dest>|<rawCondition>'+'<value>'")
+		return
+	}
+
+	for i := 0; i < argn; i += 2 {
+		// parse value first, as it's the one without any possibility of ambiguity
+		var pair outputPair
+		err = pair.Value.LoadString(args[i+1])
+		if err != nil {
+			err = fmt.Errorf("failed to parse amount/value for output #%d: %v", i/2, err)
+			return
+		}
+		if pair.Value.IsZero() {
+			err = fmt.Errorf("amount/value for output #%d cannot be zero", i/2)
+			return
+		}
+
+		// parse condition second
+		pair.Condition, err = client.ParseConditionString(args[i])
+		if err != nil {
+			err = fmt.Errorf("failed to parse condition for output #%d: %v", i/2, err)
+			return
+		}
+
+		// append succesfully parsed pair
+		pairs = append(pairs, pair)
+	}
+	return
+}
@@ -0,0 +1,416 @@
+package assets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	types "github.com/threefoldtech/rivine/types"
+)
+
+// These Specifiers are used internally when calculating a Transaction's ID,
+// as well as when calculating the ID of a newly defined asset.
+// See Rivine's Specifier for more details.
+var (
+	SpecifierAssetTransaction = types.Specifier{'a', 's', 's', 'e', 't', ' ', 't', 'x'}
+	SpecifierAssetDefinition  = types.Specifier{'a', 's', 's', 'e', 't', ' ', 'd', 'e', 'f', 'i', 'n', 'i', 't', 'i', 'o', 'n'}
+)
+
+type (
+	// Asset defines the consensus state of a defined asset.
+	Asset struct {
+		// Issuer defines the condition which has to be fulfilled
+		// in order to mint new units of the asset, should it be mintable.
+		Issuer types.UnlockConditionProxy `json:"issuer"`
+		// Mintable defines if new units of the asset can be minted,
+		// after the transaction which defined the asset.
+		Mintable bool `json:"mintable"`
+		// Supply defines the total amount of units of the asset in existence.
+		Supply types.Currency `json:"supply"`
+	}
+
+	// AssetGetter allows you to get the consensus state of a defined asset.
+	//
+	// For the daemon this interface could be implemented directly by the DB object
+	// that keeps track of the asset state, while for a client this could
+	// come via the REST API from a rivine daemon in a more indirect way.
+	AssetGetter interface {
+		// GetAsset returns the asset for the given ID,
+		// returning an error in case no asset is defined for that ID.
+		GetAsset(id types.AssetID) (Asset, error)
+	}
+)
+
+type (
+	// AssetTransactionController defines a rivine-specific transaction controller,
+	// for an Asset Transaction. It allows for the definition of new assets,
+	// the minting of (mintable) assets and the transfer of assets and/or coins.
+	AssetTransactionController struct {
+		UseLegacySiaEncoding bool
+
+		// AssetGetter is used to get the issuer condition of an asset minted
+		// in an asset transaction, such that its issuer fulfillment can be signed.
+		AssetGetter AssetGetter
+
+		// TransactionVersion is used to validate/set the transaction version
+		// of an asset transaction.
+		TransactionVersion types.TransactionVersion
+	}
+)
+
+// ensure at compile time that AssetTransactionController
+// implements the desired interfaces
+var (
+	_ types.TransactionController                = AssetTransactionController{}
+	_ types.TransactionExtensionSigner           = AssetTransactionController{}
+	_ types.TransactionSignatureHasher           = AssetTransactionController{}
+	_ types.TransactionIDEncoder                 = AssetTransactionController{}
+	_ types.TransactionCommonExtensionDataGetter = AssetTransactionController{}
+)
+
+type binaryEncoder interface {
+	Encode(interface{}) error
+	EncodeAll(...interface{}) error
+}
+
+type binaryDecoder interface {
+	Decode(interface{}) error
+	DecodeAll(...interface{}) error
+}
+
+func (atc AssetTransactionController) newBencoder(w io.Writer) binaryEncoder {
+	if atc.UseLegacySiaEncoding {
+		return siabin.NewEncoder(w)
+	}
+	return rivbin.NewEncoder(w)
+}
+func (atc AssetTransactionController) newBdecoder(r io.Reader) binaryDecoder {
+	if atc.UseLegacySiaEncoding {
+		return siabin.NewDecoder(r)
+	}
+	return rivbin.NewDecoder(r)
+}
+
+// EncodeTransactionData implements TransactionController.EncodeTransactionData
+func (atc AssetTransactionController) EncodeTransactionData(w io.Writer, txData types.TransactionData) error {
+	atx, err := AssetTransactionFromTransactionData(txData)
+	if err != nil {
+		return fmt.Errorf("failed to convert txData to an AssetTx: %v", err)
+	}
+	return atc.newBencoder(w).Encode(atx)
+}
+
+// DecodeTransactionData implements TransactionController.DecodeTransactionData
+func (atc AssetTransactionController) DecodeTransactionData(r io.Reader) (types.TransactionData, error) {
+	var atx AssetTransaction
+	err := atc.newBdecoder(r).Decode(&atx)
+	if err != nil {
+		return types.TransactionData{}, fmt.Errorf(
+			"failed to binary-decode tx as an AssetTx: %v", err)
+	}
+	// return asset tx as regular rivine tx data
+	return atx.TransactionData(), nil
+}
+
+// JSONEncodeTransactionData implements TransactionController.JSONEncodeTransactionData
+func (atc AssetTransactionController) JSONEncodeTransactionData(txData types.TransactionData) ([]byte, error) {
+	atx, err := AssetTransactionFromTransactionData(txData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert txData to an AssetTx: %v", err)
+	}
+	return json.Marshal(atx)
+}
+
+// JSONDecodeTransactionData implements TransactionController.JSONDecodeTransactionData
+func (atc AssetTransactionController) JSONDecodeTransactionData(data []byte) (types.TransactionData, error) {
+	var atx AssetTransaction
+	err := json.Unmarshal(data, &atx)
+	if err != nil {
+		return types.TransactionData{}, fmt.Errorf(
+			"failed to json-decode tx as an AssetTx: %v", err)
+	}
+	// return asset tx as regular rivine tx data
+	return atx.TransactionData(), nil
+}
+
+// SignExtension implements TransactionExtensionSigner.SignExtension
+func (atc AssetTransactionController) SignExtension(extension interface{}, sign func(*types.UnlockFulfillmentProxy, types.UnlockConditionProxy, ...interface{}) error) (interface{}, error) {
+	// (tx) extension (data) is expected to be a pointer to a valid AssetTransactionExtension,
+	// which contains the issuer fulfillments of the defined and minted assets
+	atxExtension, ok := extension.(*AssetTransactionExtension)
+	if !ok {
+		return nil, errors.New("invalid extension data for an AssetTx")
+	}
+	var err error
+	for idx := range atxExtension.Definitions {
+		definition := &atxExtension.Definitions[idx]
+		err = sign(&definition.IssuerFulfillment, definition.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign issuer fulfillment of asset definition #%d: %v", idx, err)
+		}
+	}
+	for idx := range atxExtension.Mints {
+		mint := &atxExtension.Mints[idx]
+		// get the issuer of the minted asset and use it to sign
+		if atc.AssetGetter == nil {
+			return nil, errors.New("failed to sign issuer fulfillment of asset mint: no asset getter configured")
+		}
+		asset, err := atc.AssetGetter.GetAsset(mint.AssetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset %s: %v", mint.AssetID.String(), err)
+		}
+		err = sign(&mint.IssuerFulfillment, asset.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign issuer fulfillment of asset mint #%d: %v", idx, err)
+		}
+	}
+	return atxExtension, nil
+}
+
+// SignatureHash implements TransactionSignatureHasher.SignatureHash
+func (atc AssetTransactionController) SignatureHash(t types.Transaction, extraObjects ...interface{}) (crypto.Hash, error) {
+	atx, err := AssetTransactionFromTransaction(t, atc.TransactionVersion)
+	if err != nil {
+		return crypto.Hash{}, fmt.Errorf("failed to use tx as an AssetTx: %v", err)
+	}
+
+	h := crypto.NewHash()
+	enc := atc.newBencoder(h)
+
+	enc.EncodeAll(
+		t.Version,
+		SpecifierAssetTransaction,
+	)
+
+	if len(extraObjects) > 0 {
+		enc.EncodeAll(extraObjects...)
+	}
+
+	parentIDSlice := make([]types.CoinOutputID, 0, len(atx.CoinInputs))
+	for _, ci := range atx.CoinInputs {
+		parentIDSlice = append(parentIDSlice, ci.ParentID)
+	}
+	// all issuer fulfillments are excluded, just as the coin input fulfillments are
+	type definitionSigData struct {
+		Nonce    types.TransactionNonce
+		Issuer   types.UnlockConditionProxy
+		Mintable bool
+	}
+	definitionSlice := make([]definitionSigData, 0, len(atx.Definitions))
+	for _, definition := range atx.Definitions {
+		definitionSlice = append(definitionSlice, definitionSigData{
+			Nonce:    definition.Nonce,
+			Issuer:   definition.Issuer,
+			Mintable: definition.Mintable,
+		})
+	}
+	mintAssetIDSlice := make([]types.AssetID, 0, len(atx.Mints))
+	for _, mint := range atx.Mints {
+		mintAssetIDSlice = append(mintAssetIDSlice, mint.AssetID)
+	}
+
+	enc.EncodeAll(
+		parentIDSlice,
+		definitionSlice,
+		mintAssetIDSlice,
+		atx.CoinOutputs,
+		atx.CoinOutputAssets,
+		atx.MinerFees,
+		atx.ArbitraryData,
+	)
+
+	var hash crypto.Hash
+	h.Sum(hash[:0])
+	return hash, nil
+}
+
+// EncodeTransactionIDInput implements TransactionIDEncoder.EncodeTransactionIDInput
+func (atc AssetTransactionController) EncodeTransactionIDInput(w io.Writer, txData types.TransactionData) error {
+	atx, err := AssetTransactionFromTransactionData(txData)
+	if err != nil {
+		return fmt.Errorf("failed to convert txData to an AssetTx: %v", err)
+	}
+	return atc.newBencoder(w).EncodeAll(SpecifierAssetTransaction, atx)
+}
+
+// GetCommonExtensionData implements TransactionCommonExtensionDataGetter.GetCommonExtensionData
+func (atc AssetTransactionController) GetCommonExtensionData(extension interface{}) (types.CommonTransactionExtensionData, error) {
+	atxExtension, ok := extension.(*AssetTransactionExtension)
+	if !ok {
+		return types.CommonTransactionExtensionData{}, errors.New("invalid extension data for an AssetTx")
+	}
+	data := types.CommonTransactionExtensionData{
+		CoinOutputAssets: atxExtension.CoinOutputAssets,
+	}
+	for _, definition := range atxExtension.Definitions {
+		data.UnlockConditions = append(data.UnlockConditions, definition.Issuer)
+	}
+	return data, nil
+}
+
+type (
+	// AssetTransaction is to be used by anyone in order to transfer assets
+	// (and coins), and by issuers in order to define new assets or mint new units
+	// of the assets they issued as mintable.
+	//
+	// Coin inputs holding an asset can only be spent by an AssetTransaction.
+	AssetTransaction struct {
+		// CoinInputs defines the coin outputs that are being spent,
+		// which can hold the native coin as well as any asset.
+		CoinInputs []types.CoinInput `json:"coininputs,omitempty"`
+		// CoinOutputs defines the coin outputs created by this transaction.
+		CoinOutputs []types.CoinOutput `json:"coinoutputs"`
+		// CoinOutputAssets defines for each coin output the ID of the asset it holds,
+		// the zero ID in case the coin output holds the native coin.
+		CoinOutputAssets []types.AssetID `json:"coinoutputassets"`
+		// Definitions defines the new assets defined by this transaction.
+		Definitions []AssetDefinition `json:"definitions,omitempty"`
+		// Mints defines the (mintable) assets for which new units are minted by this transaction.
+		Mints []AssetMint `json:"mints,omitempty"`
+		// Minerfees, a fee paid for this asset transaction.
+		MinerFees []types.Currency `json:"minerfees"`
+		// ArbitraryData can be used for any purpose.
+		ArbitraryData []byte `json:"arbitrarydata,omitempty"`
+	}
+
+	// AssetDefinition defines a new asset. All coin outputs holding the asset,
+	// created by the transaction which defines it, make up its initial supply.
+	AssetDefinition struct {
+		// Nonce used to ensure the uniqueness of the asset ID.
+		Nonce types.TransactionNonce `json:"nonce"`
+		// Issuer defines the condition that has to be fulfilled
+		// in order to define the asset and mint new units of it.
+		Issuer types.UnlockConditionProxy `json:"issuer"`
+		// Mintable defines if new units of the asset can be minted in future transactions,
+		// if false the asset has a fixed supply.
+		Mintable bool `json:"mintable"`
+		// IssuerFulfillment defines the fulfillment which is used in order to
+		// fulfill the issuer condition.
+		IssuerFulfillment types.UnlockFulfillmentProxy `json:"issuerfulfillment"`
+	}
+
+	// AssetMint allows the issuer of a mintable asset to increase the supply of the asset,
+	// by creating more units in the coin outputs of the transaction than it spends.
+	AssetMint struct {
+		// AssetID defines the ID of the mintable asset.
+		AssetID types.AssetID `json:"assetid"`
+		// IssuerFulfillment defines the fulfillment which is used in order to
+		// fulfill the issuer condition of the asset.
+		IssuerFulfillment types.UnlockFulfillmentProxy `json:"issuerfulfillment"`
+	}
+
+	// AssetTransactionExtension defines the AssetTx Extension Data
+	AssetTransactionExtension struct {
+		CoinOutputAssets []types.AssetID
+		Definitions      []AssetDefinition
+		Mints            []AssetMint
+	}
+)
+
+// AssetID returns the ID of the asset defined by this definition.
+func (ad *AssetDefinition) AssetID() types.AssetID {
+	h := crypto.NewHash()
+	rivbin.NewEncoder(h).EncodeAll(
+		SpecifierAssetDefinition,
+		ad.Nonce,
+		ad.Issuer,
+	)
+	var id types.AssetID
+	h.Sum(id[:0])
+	return id
+}
+
+// AssetTransactionFromTransaction creates an AssetTransaction,
+// using a regular in-memory rivine transaction.
+//
+// Past the (tx) Version validation it piggy-backs onto the
+// `AssetTransactionFromTransactionData` constructor.
+func AssetTransactionFromTransaction(tx types.Transaction, expectedVersion types.TransactionVersion) (AssetTransaction, error) {
+	if tx.Version != expectedVersion {
+		return AssetTransaction{}, fmt.Errorf(
+			"an asset transaction requires tx version %d",
+			expectedVersion)
+	}
+	return AssetTransactionFromTransactionData(types.TransactionData{
+		CoinInputs:        tx.CoinInputs,
+		CoinOutputs:       tx.CoinOutputs,
+		BlockStakeInputs:  tx.BlockStakeInputs,
+		BlockStakeOutputs: tx.BlockStakeOutputs,
+		MinerFees:         tx.MinerFees,
+		ArbitraryData:     tx.ArbitraryData,
+		Extension:         tx.Extension,
+	})
+}
+
+// AssetTransactionFromTransactionData creates an AssetTransaction,
+// using the TransactionData from a regular in-memory rivine transaction.
+func AssetTransactionFromTransactionData(txData types.TransactionData) (AssetTransaction, error) {
+	// (tx) extension (data) is expected to be a pointer to a valid AssetTransactionExtension,
+	// which contains the asset IDs of the coin outputs, as well as the asset definitions and mints
+	extensionData, ok := txData.Extension.(*AssetTransactionExtension)
+	if !ok {
+		return AssetTransaction{}, errors.New("invalid extension data for an AssetTransaction")
+	}
+	// at least one miner fee is required
+	if len(txData.MinerFees) == 0 {
+		return AssetTransaction{}, errors.New("at least one miner fee is required for an AssetTransaction")
+	}
+	// each coin output has to define the asset it holds
+	if len(extensionData.CoinOutputAssets) != len(txData.CoinOutputs) {
+		return AssetTransaction{}, fmt.Errorf(
+			"an AssetTransaction requires one asset ID per coin output: %d asset IDs defined for %d coin outputs",
+			len(extensionData.CoinOutputAssets), len(txData.CoinOutputs))
+	}
+	// no block stake inputs or block stake outputs are allowed
+	if len(txData.BlockStakeInputs) != 0 || len(txData.BlockStakeOutputs) != 0 {
+		return AssetTransaction{}, errors.New("no block stake inputs/outputs are allowed in an AssetTransaction")
+	}
+	// return the AssetTransaction, with the data extracted from the TransactionData
+	return AssetTransaction{
+		CoinInputs:       txData.CoinInputs,
+		CoinOutputs:      txData.CoinOutputs,
+		CoinOutputAssets: extensionData.CoinOutputAssets,
+		Definitions:      extensionData.Definitions,
+		Mints:            extensionData.Mints,
+		MinerFees:        txData.MinerFees,
+		// ArbitraryData is optional
+		ArbitraryData: txData.ArbitraryData,
+	}, nil
+}
+
+// TransactionData returns this AssetTransaction
+// as regular rivine transaction data.
+func (atx *AssetTransaction) TransactionData() types.TransactionData {
+	return types.TransactionData{
+		CoinInputs:    atx.CoinInputs,
+		CoinOutputs:   atx.CoinOutputs,
+		MinerFees:     atx.MinerFees,
+		ArbitraryData: atx.ArbitraryData,
+		Extension: &AssetTransactionExtension{
+			CoinOutputAssets: atx.CoinOutputAssets,
+			Definitions:      atx.Definitions,
+			Mints:            atx.Mints,
+		},
+	}
+}
+
+// Transaction returns this AssetTransaction
+// as regular rivine transaction, using the given version as the type.
+func (atx *AssetTransaction) Transaction(version types.TransactionVersion) types.Transaction {
+	return types.Transaction{
+		Version:       version,
+		CoinInputs:    atx.CoinInputs,
+		CoinOutputs:   atx.CoinOutputs,
+		MinerFees:     atx.MinerFees,
+		ArbitraryData: atx.ArbitraryData,
+		Extension: &AssetTransactionExtension{
+			CoinOutputAssets: atx.CoinOutputAssets,
+			Definitions:      atx.Definitions,
+			Mints:            atx.Mints,
+		},
+	}
+}
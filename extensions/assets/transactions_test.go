@@ -0,0 +1,233 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/types"
+)
+
+const testAssetTxVersion types.TransactionVersion = 144
+
+type testAssetGetter map[types.AssetID]Asset
+
+func (getter testAssetGetter) GetAsset(id types.AssetID) (Asset, error) {
+	asset, ok := getter[id]
+	if !ok {
+		return Asset{}, errors.New("asset not found")
+	}
+	return asset, nil
+}
+
+func newTestAssetTransaction(t *testing.T) (AssetTransaction, crypto.SecretKey, types.UnlockConditionProxy) {
+	sk, rpk := crypto.GenerateKeyPair()
+	uh, err := types.NewPubKeyUnlockHash(types.Ed25519PublicKey(rpk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := types.NewCondition(types.NewUnlockHashCondition(uh))
+	definition := AssetDefinition{
+		Nonce:             types.TransactionNonce{1, 2, 3, 4, 5, 6, 7, 8},
+		Issuer:            issuer,
+		Mintable:          true,
+		IssuerFulfillment: types.NewFulfillment(types.NewSingleSignatureFulfillment(types.Ed25519PublicKey(rpk))),
+	}
+	return AssetTransaction{
+		CoinInputs: []types.CoinInput{{
+			ParentID:    types.CoinOutputID{4, 2},
+			Fulfillment: types.NewFulfillment(types.NewSingleSignatureFulfillment(types.Ed25519PublicKey(rpk))),
+		}},
+		CoinOutputs: []types.CoinOutput{
+			{Value: types.NewCurrency64(1000), Condition: issuer},
+			{Value: types.NewCurrency64(9), Condition: issuer},
+		},
+		CoinOutputAssets: []types.AssetID{definition.AssetID(), {}},
+		Definitions:      []AssetDefinition{definition},
+		Mints: []AssetMint{{
+			AssetID:           types.AssetID{4, 2},
+			IssuerFulfillment: types.NewFulfillment(types.NewSingleSignatureFulfillment(types.Ed25519PublicKey(rpk))),
+		}},
+		MinerFees:     []types.Currency{types.NewCurrency64(1)},
+		ArbitraryData: []byte("assets"),
+	}, sk, issuer
+}
+
+func TestAssetTransactionEncoding(t *testing.T) {
+	types.RegisterTransactionVersion(testAssetTxVersion, AssetTransactionController{TransactionVersion: testAssetTxVersion})
+	defer types.RegisterTransactionVersion(testAssetTxVersion, nil)
+
+	atx, _, _ := newTestAssetTransaction(t)
+	tx := atx.Transaction(testAssetTxVersion)
+
+	// binary round trip
+	buf := bytes.NewBuffer(nil)
+	err := tx.MarshalRivine(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decodedTx types.Transaction
+	err = decodedTx.UnmarshalRivine(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodedTx.ID() != tx.ID() {
+		t.Fatalf("unexpected binary-decoded tx ID: %s != %s", decodedTx.ID().String(), tx.ID().String())
+	}
+
+	// JSON round trip
+	b, err := json.Marshal(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decodedTx = types.Transaction{}
+	err = json.Unmarshal(b, &decodedTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodedTx.ID() != tx.ID() {
+		t.Fatalf("unexpected JSON-decoded tx ID: %s != %s", decodedTx.ID().String(), tx.ID().String())
+	}
+	decodedAtx, err := AssetTransactionFromTransaction(decodedTx, testAssetTxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decodedAtx.CoinOutputAssets, atx.CoinOutputAssets) {
+		t.Fatalf("unexpected coin output assets: %v != %v", decodedAtx.CoinOutputAssets, atx.CoinOutputAssets)
+	}
+
+	// the coin output assets are exposed as common extension data
+	data, err := tx.CommonExtensionData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data.CoinOutputAssets, atx.CoinOutputAssets) {
+		t.Fatalf("unexpected common coin output assets: %v != %v", data.CoinOutputAssets, atx.CoinOutputAssets)
+	}
+	if len(data.UnlockConditions) != 1 || data.UnlockConditions[0].UnlockHash() != atx.Definitions[0].Issuer.UnlockHash() {
+		t.Fatalf("unexpected common unlock conditions: %v", data.UnlockConditions)
+	}
+}
+
+func TestAssetTransactionFromTransactionData(t *testing.T) {
+	atx, _, _ := newTestAssetTransaction(t)
+
+	txData := atx.TransactionData()
+	txData.Extension.(*AssetTransactionExtension).CoinOutputAssets = nil
+	if _, err := AssetTransactionFromTransactionData(txData); err == nil {
+		t.Error("expected an error for coin outputs without asset IDs")
+	}
+	txData = atx.TransactionData()
+	txData.MinerFees = nil
+	if _, err := AssetTransactionFromTransactionData(txData); err == nil {
+		t.Error("expected an error for a transaction without miner fees")
+	}
+	txData = atx.TransactionData()
+	txData.BlockStakeOutputs = []types.BlockStakeOutput{{Value: types.NewCurrency64(1)}}
+	if _, err := AssetTransactionFromTransactionData(txData); err == nil {
+		t.Error("expected an error for a transaction with block stake outputs")
+	}
+	if _, err := AssetTransactionFromTransaction(atx.Transaction(testAssetTxVersion+1), testAssetTxVersion); err == nil {
+		t.Error("expected an error for an unexpected transaction version")
+	}
+}
+
+func TestAssetDefinitionAssetID(t *testing.T) {
+	atx, _, _ := newTestAssetTransaction(t)
+	definition := atx.Definitions[0]
+	assetID := definition.AssetID()
+	if assetID.IsNative() {
+		t.Fatal("expected a non-native asset ID")
+	}
+	// the asset ID does not depend on the fulfillment or mintable flag
+	definition.IssuerFulfillment = types.UnlockFulfillmentProxy{}
+	definition.Mintable = false
+	if id := definition.AssetID(); id != assetID {
+		t.Fatalf("unexpected asset ID: %s != %s", id.String(), assetID.String())
+	}
+	// but it does depend on the nonce
+	definition.Nonce = types.TransactionNonce{8, 7, 6, 5, 4, 3, 2, 1}
+	if id := definition.AssetID(); id == assetID {
+		t.Fatal("expected a different asset ID for a different nonce")
+	}
+}
+
+func TestAssetTransactionSignature(t *testing.T) {
+	atx, sk, issuer := newTestAssetTransaction(t)
+	getter := testAssetGetter{
+		atx.Mints[0].AssetID: Asset{Issuer: issuer, Mintable: true},
+	}
+	types.RegisterTransactionVersion(testAssetTxVersion, AssetTransactionController{
+		AssetGetter:        getter,
+		TransactionVersion: testAssetTxVersion,
+	})
+	defer types.RegisterTransactionVersion(testAssetTxVersion, nil)
+
+	tx := atx.Transaction(testAssetTxVersion)
+	hash, err := tx.SignatureHash(uint64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the signature hash excludes all fulfillments
+	otherAtx := atx
+	otherAtx.CoinInputs = []types.CoinInput{{ParentID: atx.CoinInputs[0].ParentID}}
+	otherAtx.Definitions = []AssetDefinition{atx.Definitions[0]}
+	otherAtx.Definitions[0].IssuerFulfillment = types.UnlockFulfillmentProxy{}
+	otherAtx.Mints = []AssetMint{{AssetID: atx.Mints[0].AssetID}}
+	otherTx := otherAtx.Transaction(testAssetTxVersion)
+	if otherHash, err := otherTx.SignatureHash(uint64(0)); err != nil {
+		t.Fatal(err)
+	} else if otherHash != hash {
+		t.Fatal("expected the signature hash to exclude the fulfillments")
+	}
+	// but it does include the asset IDs of the coin outputs
+	atx.CoinOutputAssets[1] = types.AssetID{1}
+	if otherHash, err := atx.Transaction(testAssetTxVersion).SignatureHash(uint64(0)); err != nil {
+		t.Fatal(err)
+	} else if otherHash == hash {
+		t.Fatal("expected the signature hash to include the coin output assets")
+	}
+	atx.CoinOutputAssets[1] = types.AssetID{}
+
+	// sign all issuer fulfillments
+	err = tx.SignExtension(func(fulfillment *types.UnlockFulfillmentProxy, condition types.UnlockConditionProxy, extraObjects ...interface{}) error {
+		if condition.UnlockHash() != issuer.UnlockHash() {
+			t.Fatalf("unexpected condition to sign: %s", condition.UnlockHash().String())
+		}
+		return fulfillment.Sign(types.FulfillmentSignContext{
+			ExtraObjects: extraObjects,
+			Transaction:  tx,
+			Key:          sk,
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedAtx, err := AssetTransactionFromTransaction(tx, testAssetTxVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fulfillCtx := types.FulfillContext{
+		BlockTime:   types.CurrentTimestamp(),
+		Transaction: tx,
+	}
+	if err = issuer.Fulfill(signedAtx.Definitions[0].IssuerFulfillment, fulfillCtx); err != nil {
+		t.Errorf("expected a valid issuer fulfillment for the asset definition: %v", err)
+	}
+	if err = issuer.Fulfill(signedAtx.Mints[0].IssuerFulfillment, fulfillCtx); err != nil {
+		t.Errorf("expected a valid issuer fulfillment for the asset mint: %v", err)
+	}
+
+	// an unknown minted asset cannot be signed
+	delete(getter, atx.Mints[0].AssetID)
+	tx = atx.Transaction(testAssetTxVersion)
+	err = tx.SignExtension(func(*types.UnlockFulfillmentProxy, types.UnlockConditionProxy, ...interface{}) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("expected an error when signing the mint of an unknown asset")
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
 	"github.com/threefoldtech/rivine/types"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 const (
@@ -65,7 +65,7 @@ type (
 // DefaultUnauthorizedCoinTransactionExceptionCallback is the default callback that is used in ase the auth coin plugin
 // does not define a custom callback.
 func DefaultUnauthorizedCoinTransactionExceptionCallback(tx modules.ConsensusTransaction, dedupAddresses []types.UnlockHash, ctx types.TransactionValidationContext) (bool, error) {
-	if tx.Version != types.TransactionVersionZero && tx.Version != types.TransactionVersionOne {
+	if tx.Version != types.TransactionVersionZero && tx.Version != types.TransactionVersionOne && tx.Version != types.TransactionVersionTwo && tx.Version != types.TransactionVersionThree && tx.Version != types.TransactionVersionFour {
 		return false, nil
 	}
 	return (len(dedupAddresses) == 1 && len(tx.CoinOutputs) <= 1), nil
@@ -113,7 +113,7 @@ func NewPlugin(genesisAuthCondition types.UnlockConditionProxy, authAddressUpdat
 }
 
 // InitPlugin initializes the Bucket for the first time
-func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket *bolt.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
+func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket kv.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
 	p.storage = storage
 	p.unregisterCallback = unregisterCallback
 	if metadata == nil {
@@ -156,7 +156,7 @@ func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket *bolt.Bucket, sto
 }
 
 // ApplyBlock applies a block's minting transactions to the minting bucket.
-func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
 	if bucket == nil {
 		return errors.New("plugin bucket does not exist")
 	}
@@ -179,12 +179,12 @@ func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBo
 }
 
 // ApplyBlockHeader applies nothing and has no effect on this plugin.
-func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBucket) error {
 	return nil
 }
 
 // ApplyTransaction applies a minting transactions to the minting bucket.
-func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
 	if bucket == nil {
 		return errors.New("plugin bucket does not exist")
 	}
@@ -268,7 +268,7 @@ func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *pers
 }
 
 // RevertBlock reverts a block's minting transaction from the minting bucket
-func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
 	if bucket == nil {
 		return errors.New("plugin bucket does not exist")
 	}
@@ -291,12 +291,12 @@ func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyB
 }
 
 // RevertBlockHeader reverts nothing and has no effect on this plugin.
-func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBucket) error {
 	return nil
 }
 
 // RevertTransaction reverts a  minting transaction from the minting bucket
-func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
 	if bucket == nil {
 		return errors.New("plugin bucket does not exist")
 	}
@@ -364,7 +364,7 @@ func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *per
 // GetActiveAuthCondition implements types.AuthInfoGetter.GetActiveAuthCondition
 func (p *Plugin) GetActiveAuthCondition() (types.UnlockConditionProxy, error) {
 	var authCondition types.UnlockConditionProxy
-	err := p.storage.View(func(bucket *bolt.Bucket) (err error) {
+	err := p.storage.View(func(bucket kv.Bucket) (err error) {
 		authBucket := bucket.Bucket([]byte(bucketAuthConditions))
 		if authBucket == nil {
 			return errors.New("auth condition bucket could not be found")
@@ -381,7 +381,7 @@ func (p *Plugin) GetActiveAuthCondition() (types.UnlockConditionProxy, error) {
 // GetAuthConditionAt implements types.AuthInfoGetter.GetAuthConditionAt
 func (p *Plugin) GetAuthConditionAt(height types.BlockHeight) (types.UnlockConditionProxy, error) {
 	var authCondition types.UnlockConditionProxy
-	err := p.storage.View(func(bucket *bolt.Bucket) (err error) {
+	err := p.storage.View(func(bucket kv.Bucket) (err error) {
 		authBucket := bucket.Bucket([]byte(bucketAuthConditions))
 		if authBucket == nil {
 			return errors.New("auth condition bucket could not be found")
@@ -404,7 +404,7 @@ func (p *Plugin) GetAddressesAuthStateNow(addresses []types.UnlockHash, exitEarl
 		return nil, errors.New("no addresses given to check the current auth state for")
 	}
 	stateSlice := make([]bool, l)
-	err := p.storage.View(func(bucket *bolt.Bucket) error {
+	err := p.storage.View(func(bucket kv.Bucket) error {
 		authBucket := bucket.Bucket([]byte(bucketAuthAddresses))
 		var err error
 		for index, address := range addresses {
@@ -434,7 +434,7 @@ func (p *Plugin) GetAddressesAuthStateAt(height types.BlockHeight, addresses []t
 		return nil, errors.New("no addresses given to check the current auth state for")
 	}
 	stateSlice := make([]bool, l)
-	err := p.storage.View(func(bucket *bolt.Bucket) error {
+	err := p.storage.View(func(bucket kv.Bucket) error {
 		authBucket := bucket.Bucket([]byte(bucketAuthAddresses))
 		var err error
 		for index, address := range addresses {
@@ -455,7 +455,7 @@ func (p *Plugin) GetAddressesAuthStateAt(height types.BlockHeight, addresses []t
 	return stateSlice, err
 }
 
-func (p *Plugin) getAuthConditionFromBucketAt(authConditionBucket *bolt.Bucket, height types.BlockHeight) (types.UnlockConditionProxy, error) {
+func (p *Plugin) getAuthConditionFromBucketAt(authConditionBucket kv.Bucket, height types.BlockHeight) (types.UnlockConditionProxy, error) {
 	var b []byte
 	err := func() error {
 		cursor := authConditionBucket.Cursor()
@@ -490,7 +490,7 @@ func (p *Plugin) getAuthConditionFromBucketAt(authConditionBucket *bolt.Bucket,
 	return authCondition, nil
 }
 
-func (p *Plugin) getAuthConditionFromBucket(authConditionBucket *bolt.Bucket) (types.UnlockConditionProxy, error) {
+func (p *Plugin) getAuthConditionFromBucket(authConditionBucket kv.Bucket) (types.UnlockConditionProxy, error) {
 	cursor := authConditionBucket.Cursor()
 	k, b := cursor.Last()
 	if len(k) == 0 {
@@ -504,7 +504,7 @@ func (p *Plugin) getAuthConditionFromBucket(authConditionBucket *bolt.Bucket) (t
 	return authCondition, nil
 }
 
-func (p *Plugin) getAuthConditionFromBucketWithContextInfo(authConditionBucket *bolt.Bucket, confirmed bool, blockHeight types.BlockHeight) (types.UnlockConditionProxy, error) {
+func (p *Plugin) getAuthConditionFromBucketWithContextInfo(authConditionBucket kv.Bucket, confirmed bool, blockHeight types.BlockHeight) (types.UnlockConditionProxy, error) {
 	if confirmed || blockHeight > 0 {
 		mintCondition, err := p.getAuthConditionFromBucketAt(authConditionBucket, blockHeight)
 		if err != nil {
@@ -519,7 +519,7 @@ func (p *Plugin) getAuthConditionFromBucketWithContextInfo(authConditionBucket *
 	return mintCondition, nil
 }
 
-func (p *Plugin) getAuthAddressStateFromBucketAt(authAddressBucket *bolt.Bucket, uh types.UnlockHash, height types.BlockHeight) (bool, error) {
+func (p *Plugin) getAuthAddressStateFromBucketAt(authAddressBucket kv.Bucket, uh types.UnlockHash, height types.BlockHeight) (bool, error) {
 	var b []byte
 	err := func() error {
 		uhBytes, err := rivbin.Marshal(uh)
@@ -566,7 +566,7 @@ func (p *Plugin) getAuthAddressStateFromBucketAt(authAddressBucket *bolt.Bucket,
 	return state, nil
 }
 
-func (p *Plugin) getAuthAddressStateFromBucket(authAddressBucket *bolt.Bucket, uh types.UnlockHash) (bool, error) {
+func (p *Plugin) getAuthAddressStateFromBucket(authAddressBucket kv.Bucket, uh types.UnlockHash) (bool, error) {
 	var b []byte
 	err := func() error {
 		uhBytes, err := rivbin.Marshal(uh)
@@ -602,7 +602,7 @@ func (p *Plugin) getAuthAddressStateFromBucket(authAddressBucket *bolt.Bucket, u
 	return state, nil
 }
 
-func (p *Plugin) getAuthAddressStateFromBucketWithContextInfo(authAddressBucket *bolt.Bucket, uh types.UnlockHash, confirmed bool, blockHeight types.BlockHeight) (bool, error) {
+func (p *Plugin) getAuthAddressStateFromBucketWithContextInfo(authAddressBucket kv.Bucket, uh types.UnlockHash, confirmed bool, blockHeight types.BlockHeight) (bool, error) {
 	if confirmed || blockHeight > 0 {
 		state, err := p.getAuthAddressStateFromBucketAt(authAddressBucket, uh, blockHeight)
 		if err != nil {
@@ -636,7 +636,7 @@ func (p *Plugin) TransactionValidators() []modules.PluginTransactionValidationFu
 	}
 }
 
-func (p *Plugin) validateAuthorizedCoinFlowForAllTxs(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) validateAuthorizedCoinFlowForAllTxs(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error {
 	// collect all dedupAddresses
 	dedupAddresses := map[types.UnlockHash]struct{}{}
 	for _, co := range tx.CoinOutputs {
@@ -689,7 +689,7 @@ func (p *Plugin) validateAuthorizedCoinFlowForAllTxs(tx modules.ConsensusTransac
 	return nil
 }
 
-func (p *Plugin) validateAuthAddressUpdateTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) validateAuthAddressUpdateTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error {
 	// get AuthAddressUpdateTx
 	autx, err := AuthAddressUpdateTransactionFromTransaction(tx.Transaction, p.authAddressUpdateTransactionVersion, p.RequireMinerFees)
 	if err != nil {
@@ -771,7 +771,7 @@ func (p *Plugin) validateAuthAddressUpdateTx(tx modules.ConsensusTransaction, ct
 	return nil // tx is valid according to this tx validator
 }
 
-func (p *Plugin) validateAuthConditionUpdateTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) validateAuthConditionUpdateTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error {
 	// get AuthConditionUpdateTx
 	cutx, err := AuthConditionUpdateTransactionFromTransaction(tx.Transaction, p.authConditionUpdateTransactionVersion, p.RequireMinerFees)
 	if err != nil {
@@ -800,7 +800,7 @@ func (p *Plugin) validateAuthConditionUpdateTx(tx modules.ConsensusTransaction,
 	}
 	// ensure the defined condition maps to an acceptable uh
 	uh := cutx.AuthCondition.UnlockHash()
-	if uh.Type != types.UnlockTypePubKey && uh.Type != types.UnlockTypeMultiSig {
+	if !uh.Type.IsPubKey() && uh.Type != types.UnlockTypeMultiSig {
 		return fmt.Errorf("defined condition maps to an invalid unlock hash type %d", uh.Type)
 	}
 
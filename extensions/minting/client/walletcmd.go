@@ -167,7 +167,7 @@ func (walletCmd *walletCmd) createMinterDefinitionTxCmd(cmd *cobra.Command, args
 
 	// parse the given mint condition
 	var err error
-	tx.MintCondition, err = parseConditionString(args[0])
+	tx.MintCondition, err = client.ParseConditionString(args[0])
 	if err != nil {
 		cmd.UsageFunc()(cmd)
 		cli.Die(err)
@@ -273,27 +273,6 @@ func (walletCmd *walletCmd) burnCoinsCmd(cmd *cobra.Command, args []string) {
 	fmt.Println(txID.String())
 }
 
-// try to parse the string first as an unlock hash,
-// if that fails parse it as a
-func parseConditionString(str string) (condition types.UnlockConditionProxy, err error) {
-	// try to parse it as an unlock hash
-	var uh types.UnlockHash
-	err = uh.LoadString(str)
-	if err == nil {
-		// parsing as an unlock hash was succesfull, store the pair and continue to the next pair
-		condition = types.NewCondition(types.NewUnlockHashCondition(uh))
-		return
-	}
-
-	// try to parse it as a JSON-encoded unlock condition
-	err = condition.UnmarshalJSON([]byte(str))
-	if err != nil {
-		return types.UnlockConditionProxy{}, fmt.Errorf(
-			"condition has to be UnlockHash or JSON-encoded UnlockCondition, output %q is neither", str)
-	}
-	return
-}
-
 type (
 	// parseCurrencyString takes the string representation of a currency value
 	parseCurrencyString func(string) (types.Currency, error)
@@ -325,7 +304,7 @@ func parsePairedOutputs(args []string, parseCurrency parseCurrencyString) (pairs
 		}
 
 		// parse condition second
-		pair.Condition, err = parseConditionString(args[i])
+		pair.Condition, err = client.ParseConditionString(args[i])
 		if err != nil {
 			err = fmt.Errorf("failed to parse condition for output #%d: %v", i/2, err)
 			return
@@ -12,7 +12,7 @@ import (
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 const (
@@ -102,7 +102,7 @@ func NewMintingPlugin(genesisMintCondition types.UnlockConditionProxy, minterDef
 }
 
 // InitPlugin initializes the Bucket for the first time
-func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket *bolt.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
+func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket kv.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
 	p.storage = storage
 	p.unregisterCallback = unregisterCallback
 	if metadata == nil {
@@ -134,7 +134,7 @@ func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket *bolt.Bucket, sto
 }
 
 // ApplyBlock applies a block's minting transactions to the minting bucket.
-func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
 	if bucket == nil {
 		return errors.New("minting bucket does not exist")
 	}
@@ -157,17 +157,17 @@ func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBo
 }
 
 // ApplyBlockHeader applies nothing and has no effect on this plugin.
-func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBucket) error {
 	return nil
 }
 
 // ApplyTransaction applies a minting transactions to the minting bucket.
-func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
 	if bucket == nil {
 		return errors.New("minting bucket does not exist")
 	}
 	var (
-		mintingBucket *bolt.Bucket
+		mintingBucket kv.Bucket
 	)
 	// check the version and handle the ones we care about
 	switch txn.Version {
@@ -197,7 +197,7 @@ func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *pers
 }
 
 // RevertBlock reverts a block's minting transaction from the minting bucket
-func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
 	if bucket == nil {
 		return errors.New("mint conditions bucket does not exist")
 	}
@@ -221,18 +221,18 @@ func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyB
 }
 
 // RevertBlockHeader reverts nothing and has no effect on this plugin.
-func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBoltBucket) error {
+func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBucket) error {
 	return nil
 }
 
 // RevertTransaction reverts a minting transactions to the minting bucket.
-func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
 	if bucket == nil {
 		return errors.New("minting bucket does not exist")
 	}
 	var (
 		err           error
-		mintingBucket *bolt.Bucket
+		mintingBucket kv.Bucket
 	)
 	// check the version and handle the ones we care about
 	switch txn.Version {
@@ -256,7 +256,7 @@ func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *per
 // GetActiveMintCondition implements types.MintConditionGetter.GetActiveMintCondition
 func (p *Plugin) GetActiveMintCondition() (types.UnlockConditionProxy, error) {
 	var mintCondition types.UnlockConditionProxy
-	err := p.storage.View(func(bucket *bolt.Bucket) error {
+	err := p.storage.View(func(bucket kv.Bucket) error {
 		mintingBucket := bucket.Bucket([]byte(bucketMintConditions))
 		if mintingBucket == nil {
 			return errors.New("no minting condition bucket found")
@@ -272,7 +272,7 @@ func (p *Plugin) GetActiveMintCondition() (types.UnlockConditionProxy, error) {
 // GetMintConditionAt implements types.MintConditionGetter.GetMintConditionAt
 func (p *Plugin) GetMintConditionAt(height types.BlockHeight) (types.UnlockConditionProxy, error) {
 	var mintCondition types.UnlockConditionProxy
-	err := p.storage.View(func(bucket *bolt.Bucket) error {
+	err := p.storage.View(func(bucket kv.Bucket) error {
 		mintingBucket := bucket.Bucket([]byte(bucketMintConditions))
 		if mintingBucket == nil {
 			return errors.New("no minting condition bucket found")
@@ -285,7 +285,7 @@ func (p *Plugin) GetMintConditionAt(height types.BlockHeight) (types.UnlockCondi
 	return mintCondition, err
 }
 
-func (p *Plugin) getMintConditionFromBucketAt(mintConditionBucket *bolt.Bucket, height types.BlockHeight) (types.UnlockConditionProxy, error) {
+func (p *Plugin) getMintConditionFromBucketAt(mintConditionBucket kv.Bucket, height types.BlockHeight) (types.UnlockConditionProxy, error) {
 	var b []byte
 	err := func() error {
 		cursor := mintConditionBucket.Cursor()
@@ -320,7 +320,7 @@ func (p *Plugin) getMintConditionFromBucketAt(mintConditionBucket *bolt.Bucket,
 	return mintCondition, nil
 }
 
-func (p *Plugin) getMintConditionFromBucket(mintConditionBucket *bolt.Bucket) (types.UnlockConditionProxy, error) {
+func (p *Plugin) getMintConditionFromBucket(mintConditionBucket kv.Bucket) (types.UnlockConditionProxy, error) {
 	cursor := mintConditionBucket.Cursor()
 	k, b := cursor.Last()
 	if len(k) == 0 {
@@ -334,7 +334,7 @@ func (p *Plugin) getMintConditionFromBucket(mintConditionBucket *bolt.Bucket) (t
 	return mintCondition, nil
 }
 
-func (p *Plugin) getMintConditionFromBucketWithContextInfo(mintConditionBucket *bolt.Bucket, confirmed bool, blockHeight types.BlockHeight) (types.UnlockConditionProxy, error) {
+func (p *Plugin) getMintConditionFromBucketWithContextInfo(mintConditionBucket kv.Bucket, confirmed bool, blockHeight types.BlockHeight) (types.UnlockConditionProxy, error) {
 	if confirmed || blockHeight > 0 {
 		mintCondition, err := p.getMintConditionFromBucketAt(mintConditionBucket, blockHeight)
 		if err != nil {
@@ -372,7 +372,7 @@ func (p *Plugin) TransactionValidators() []modules.PluginTransactionValidationFu
 	return nil
 }
 
-func (p *Plugin) validateMinterDefinitionTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) validateMinterDefinitionTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error {
 	mdtx, err := MinterDefinitionTransactionFromTransaction(tx.Transaction, p.minterDefinitionTransactionVersion, p.requireMinerFees)
 	if err != nil {
 		return fmt.Errorf("failed to use tx as a minter definition tx: %v", err)
@@ -420,7 +420,7 @@ func (p *Plugin) validateMinterDefinitionTx(tx modules.ConsensusTransaction, ctx
 	return nil // valid what this validator concerns
 }
 
-func (p *Plugin) validateCoinCreationTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) validateCoinCreationTx(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error {
 	cctx, err := CoinCreationTransactionFromTransaction(tx.Transaction, p.coinCreationTransactionVersion, p.requireMinerFees)
 	if err != nil {
 		return fmt.Errorf("failed to use tx as a coin creation tx: %v", err)
@@ -461,8 +461,8 @@ func validateMintCondition(condition types.UnlockCondition) error {
 		return nil
 
 	case types.ConditionTypeUnlockHash:
-		// only valid for unlock hash type 1 (PubKey)
-		if condition.UnlockHash().Type == types.UnlockTypePubKey {
+		// only valid for public key unlock hash types
+		if condition.UnlockHash().Type.IsPubKey() {
 			return nil
 		}
 		return errors.New("unlockHash conditions can be used as mint conditions, if the unlock hash type is PubKey")
@@ -489,7 +489,7 @@ func validateMintCondition(condition types.UnlockCondition) error {
 	}
 }
 
-func (p *Plugin) validateCoinDestructionTxCreation(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error {
+func (p *Plugin) validateCoinDestructionTxCreation(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error {
 	// collect the coin input sum
 	var coinInputSum types.Currency
 	for _, ci := range tx.CoinInputs {
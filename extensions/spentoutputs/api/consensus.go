@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+	"github.com/threefoldtech/rivine/extensions/spentoutputs"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// GetSpentOutputResponse contains the transaction which spent the requested output.
+type GetSpentOutputResponse struct {
+	spentoutputs.SpentOutput
+}
+
+// RegisterConsensusSpentOutputsHTTPHandlers registers the spent outputs handlers as consensus HTTP endpoints.
+func RegisterConsensusSpentOutputsHTTPHandlers(router rapi.Router, plugin *spentoutputs.Plugin) {
+	router.GET("/consensus/spent/coinoutputs/:id", NewGetSpentCoinOutputHandler(plugin))
+	router.GET("/consensus/spent/blockstakeoutputs/:id", NewGetSpentBlockStakeOutputHandler(plugin))
+}
+
+// NewGetSpentCoinOutputHandler creates a handler to handle the API calls to /consensus/spent/coinoutputs/:id.
+func NewGetSpentCoinOutputHandler(plugin *spentoutputs.Plugin) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		var id types.CoinOutputID
+		err := id.LoadString(ps.ByName("id"))
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: fmt.Sprintf("invalid coin output ID given: %v", err)}, http.StatusBadRequest)
+			return
+		}
+		spentOutput, err := plugin.GetSpentCoinOutput(id)
+		writeSpentOutput(w, spentOutput, err)
+	}
+}
+
+// NewGetSpentBlockStakeOutputHandler creates a handler to handle the API calls to /consensus/spent/blockstakeoutputs/:id.
+func NewGetSpentBlockStakeOutputHandler(plugin *spentoutputs.Plugin) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		var id types.BlockStakeOutputID
+		err := id.LoadString(ps.ByName("id"))
+		if err != nil {
+			rapi.WriteError(w, rapi.Error{Message: fmt.Sprintf("invalid blockstake output ID given: %v", err)}, http.StatusBadRequest)
+			return
+		}
+		spentOutput, err := plugin.GetSpentBlockStakeOutput(id)
+		writeSpentOutput(w, spentOutput, err)
+	}
+}
+
+func writeSpentOutput(w http.ResponseWriter, spentOutput spentoutputs.SpentOutput, err error) {
+	if err == spentoutputs.ErrOutputNotSpent {
+		rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		rapi.WriteError(w, rapi.Error{Message: err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	rapi.WriteJSON(w, GetSpentOutputResponse{SpentOutput: spentOutput})
+}
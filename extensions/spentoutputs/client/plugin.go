@@ -0,0 +1,53 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/threefoldtech/rivine/extensions/spentoutputs"
+	"github.com/threefoldtech/rivine/extensions/spentoutputs/api"
+	rapi "github.com/threefoldtech/rivine/pkg/api"
+	client "github.com/threefoldtech/rivine/pkg/client"
+	types "github.com/threefoldtech/rivine/types"
+)
+
+// PluginClient is used to look up the transactions which spent
+// coin- and blockstake outputs, using the spent outputs index
+// exposed via the Consensus endpoints of a daemon.
+type PluginClient struct {
+	client client.BaseClient
+}
+
+// NewPluginConsensusClient creates a new PluginClient,
+// that can be used for easy interaction with the spent outputs API exposed via the Consensus endpoints
+func NewPluginConsensusClient(cli client.BaseClient) *PluginClient {
+	if cli == nil {
+		panic("no BaseClient given")
+	}
+	return &PluginClient{
+		client: cli,
+	}
+}
+
+// GetSpentCoinOutput returns the transaction which spent the coin output with the given ID,
+// spentoutputs.ErrOutputNotSpent is returned in case the coin output is not spent.
+func (cli *PluginClient) GetSpentCoinOutput(id types.CoinOutputID) (spentoutputs.SpentOutput, error) {
+	return cli.getSpentOutput("/consensus/spent/coinoutputs/" + id.String())
+}
+
+// GetSpentBlockStakeOutput returns the transaction which spent the blockstake output with the given ID,
+// spentoutputs.ErrOutputNotSpent is returned in case the blockstake output is not spent.
+func (cli *PluginClient) GetSpentBlockStakeOutput(id types.BlockStakeOutputID) (spentoutputs.SpentOutput, error) {
+	return cli.getSpentOutput("/consensus/spent/blockstakeoutputs/" + id.String())
+}
+
+func (cli *PluginClient) getSpentOutput(call string) (spentoutputs.SpentOutput, error) {
+	var result api.GetSpentOutputResponse
+	err := cli.client.HTTP().GetWithResponse(call, &result)
+	if err == rapi.ErrStatusNotFound {
+		return spentoutputs.SpentOutput{}, spentoutputs.ErrOutputNotSpent
+	}
+	if err != nil {
+		return spentoutputs.SpentOutput{}, fmt.Errorf("failed to get spent output from daemon: %v", err)
+	}
+	return result.SpentOutput, nil
+}
@@ -0,0 +1,256 @@
+package spentoutputs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+
+	"github.com/threefoldtech/rivine/persist/kv"
+)
+
+const (
+	pluginDBVersion = "1.0.0.0"
+	pluginDBHeader  = "spentOutputsPlugin"
+)
+
+var (
+	bucketSpentCoinOutputs       = []byte("spentcoinoutputs")
+	bucketSpentBlockStakeOutputs = []byte("spentblockstakeoutputs")
+)
+
+var (
+	// ErrOutputNotSpent is returned in case an output is not (yet) spent,
+	// or is not known at all.
+	ErrOutputNotSpent = errors.New("output is not spent or does not exist")
+)
+
+type (
+	// Plugin is a struct defines the spent outputs plugin,
+	// indexing for each spent coin- and blockstake output
+	// the transaction that spent it.
+	Plugin struct {
+		storage            modules.PluginViewStorage
+		unregisterCallback modules.PluginUnregisterCallback
+	}
+
+	// SpentOutput identifies the transaction which spent an output.
+	SpentOutput struct {
+		TransactionID types.TransactionID `json:"transactionid"`
+		BlockHeight   types.BlockHeight   `json:"blockheight"`
+	}
+)
+
+// NewPlugin creates a new spent outputs Plugin.
+func NewPlugin() *Plugin {
+	return new(Plugin)
+}
+
+// InitPlugin initializes the Bucket for the first time
+func (p *Plugin) InitPlugin(metadata *persist.Metadata, bucket kv.Bucket, storage modules.PluginViewStorage, unregisterCallback modules.PluginUnregisterCallback) (persist.Metadata, error) {
+	p.storage = storage
+	p.unregisterCallback = unregisterCallback
+	if metadata == nil {
+		for _, name := range [][]byte{bucketSpentCoinOutputs, bucketSpentBlockStakeOutputs} {
+			_, err := bucket.CreateBucketIfNotExists(name)
+			if err != nil {
+				return persist.Metadata{}, fmt.Errorf("failed to create %s bucket: %v", name, err)
+			}
+		}
+		metadata = &persist.Metadata{
+			Version: pluginDBVersion,
+			Header:  pluginDBHeader,
+		}
+	} else if metadata.Version != pluginDBVersion {
+		return persist.Metadata{}, errors.New("There is only 1 version of this plugin, version mismatch")
+	} else if metadata.Header != pluginDBHeader {
+		return persist.Metadata{}, errors.New("There is only 1 header of this plugin, header mismatch")
+	}
+	return *metadata, nil
+}
+
+// ApplyBlock indexes the outputs spent by the transactions of the block.
+func (p *Plugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
+	if bucket == nil {
+		return errors.New("plugin bucket does not exist")
+	}
+	var err error
+	for idx, txn := range block.Transactions {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            txn,
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err = p.ApplyTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyBlockHeader applies nothing and has no effect on this plugin.
+func (p *Plugin) ApplyBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBucket) error {
+	return nil
+}
+
+// ApplyTransaction indexes the outputs spent by the transaction.
+func (p *Plugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
+	if bucket == nil {
+		return errors.New("plugin bucket does not exist")
+	}
+	spentOutputBytes, err := rivbin.Marshal(SpentOutput{
+		TransactionID: txn.ID(),
+		BlockHeight:   txn.BlockHeight,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to (rivbin) marshal spent output: %v", err)
+	}
+	if len(txn.CoinInputs) > 0 {
+		coinOutputsBucket, err := bucket.Bucket(bucketSpentCoinOutputs)
+		if err != nil {
+			return errors.New("spent coin outputs bucket does not exist")
+		}
+		for _, ci := range txn.CoinInputs {
+			err = coinOutputsBucket.Put(ci.ParentID[:], spentOutputBytes)
+			if err != nil {
+				return fmt.Errorf("failed to put spent coin output %s: %v", ci.ParentID.String(), err)
+			}
+		}
+	}
+	if len(txn.BlockStakeInputs) > 0 {
+		blockStakeOutputsBucket, err := bucket.Bucket(bucketSpentBlockStakeOutputs)
+		if err != nil {
+			return errors.New("spent blockstake outputs bucket does not exist")
+		}
+		for _, bsi := range txn.BlockStakeInputs {
+			err = blockStakeOutputsBucket.Put(bsi.ParentID[:], spentOutputBytes)
+			if err != nil {
+				return fmt.Errorf("failed to put spent blockstake output %s: %v", bsi.ParentID.String(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// RevertBlock removes the outputs spent by the transactions of the block from the index.
+func (p *Plugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
+	if bucket == nil {
+		return errors.New("plugin bucket does not exist")
+	}
+	var err error
+	for idx, txn := range block.Transactions {
+		cTxn := modules.ConsensusTransaction{
+			Transaction:            txn,
+			BlockHeight:            block.Height,
+			BlockTime:              block.Timestamp,
+			SequenceID:             uint16(idx),
+			SpentCoinOutputs:       block.SpentCoinOutputs,
+			SpentBlockStakeOutputs: block.SpentBlockStakeOutputs,
+		}
+		err = p.RevertTransaction(cTxn, bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RevertBlockHeader reverts nothing and has no effect on this plugin.
+func (p *Plugin) RevertBlockHeader(modules.ConsensusBlockHeader, *persist.LazyBucket) error {
+	return nil
+}
+
+// RevertTransaction removes the outputs spent by the transaction from the index,
+// as they are unspent again.
+func (p *Plugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
+	if bucket == nil {
+		return errors.New("plugin bucket does not exist")
+	}
+	if len(txn.CoinInputs) > 0 {
+		coinOutputsBucket, err := bucket.Bucket(bucketSpentCoinOutputs)
+		if err != nil {
+			return errors.New("spent coin outputs bucket does not exist")
+		}
+		for _, ci := range txn.CoinInputs {
+			err = coinOutputsBucket.Delete(ci.ParentID[:])
+			if err != nil {
+				return fmt.Errorf("failed to delete spent coin output %s: %v", ci.ParentID.String(), err)
+			}
+		}
+	}
+	if len(txn.BlockStakeInputs) > 0 {
+		blockStakeOutputsBucket, err := bucket.Bucket(bucketSpentBlockStakeOutputs)
+		if err != nil {
+			return errors.New("spent blockstake outputs bucket does not exist")
+		}
+		for _, bsi := range txn.BlockStakeInputs {
+			err = blockStakeOutputsBucket.Delete(bsi.ParentID[:])
+			if err != nil {
+				return fmt.Errorf("failed to delete spent blockstake output %s: %v", bsi.ParentID.String(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetSpentCoinOutput returns the transaction which spent the coin output with the given ID,
+// ErrOutputNotSpent is returned in case the coin output is not spent.
+func (p *Plugin) GetSpentCoinOutput(id types.CoinOutputID) (SpentOutput, error) {
+	return p.getSpentOutput(bucketSpentCoinOutputs, id[:])
+}
+
+// GetSpentBlockStakeOutput returns the transaction which spent the blockstake output with the given ID,
+// ErrOutputNotSpent is returned in case the blockstake output is not spent.
+func (p *Plugin) GetSpentBlockStakeOutput(id types.BlockStakeOutputID) (SpentOutput, error) {
+	return p.getSpentOutput(bucketSpentBlockStakeOutputs, id[:])
+}
+
+func (p *Plugin) getSpentOutput(bucketName, id []byte) (SpentOutput, error) {
+	var spentOutput SpentOutput
+	err := p.storage.View(func(bucket kv.Bucket) error {
+		spentOutputsBucket := bucket.Bucket(bucketName)
+		if spentOutputsBucket == nil {
+			return fmt.Errorf("%s bucket could not be found", bucketName)
+		}
+		b := spentOutputsBucket.Get(id)
+		if len(b) == 0 {
+			return ErrOutputNotSpent
+		}
+		err := rivbin.Unmarshal(b, &spentOutput)
+		if err != nil {
+			return fmt.Errorf("corrupt plugin DB: failed to decode spent output: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return SpentOutput{}, err
+	}
+	return spentOutput, nil
+}
+
+// TransactionValidatorVersionFunctionMapping returns all tx validators linked to this plugin,
+// which is none for this plugin.
+func (p *Plugin) TransactionValidatorVersionFunctionMapping() map[types.TransactionVersion][]modules.PluginTransactionValidationFunction {
+	return nil
+}
+
+// TransactionValidators returns all tx validators linked to this plugin,
+// which is none for this plugin.
+func (p *Plugin) TransactionValidators() []modules.PluginTransactionValidationFunction {
+	return nil
+}
+
+// Close unregisters the plugin from the consensus
+func (p *Plugin) Close() error {
+	if p.storage == nil {
+		return nil
+	}
+	return p.storage.Close()
+}
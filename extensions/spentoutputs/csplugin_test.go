@@ -0,0 +1,108 @@
+package spentoutputs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
+)
+
+var pluginBucketName = []byte("spentoutputs")
+
+// testViewStorage implements modules.PluginViewStorage on top of a key-value database.
+type testViewStorage struct {
+	db kv.DB
+}
+
+func (s testViewStorage) View(callback func(bucket kv.Bucket) error) error {
+	return s.db.View(func(tx kv.Tx) error {
+		return callback(tx.Bucket(pluginBucketName))
+	})
+}
+
+func (s testViewStorage) Close() error { return nil }
+
+func TestSpentOutputsApplyRevert(t *testing.T) {
+	dir := build.TempDir("spentoutputs", t.Name())
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := persist.OpenDatabase(persist.Metadata{Header: "test", Version: "1.0"}, filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	storage := testViewStorage{db: db.DB}
+
+	plugin := NewPlugin()
+	err = db.Update(func(tx kv.Tx) error {
+		bucket, err := tx.CreateBucket(pluginBucketName)
+		if err != nil {
+			return err
+		}
+		_, err = plugin.InitPlugin(nil, bucket, storage, nil)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var coinOutputID types.CoinOutputID
+	coinOutputID[0] = 1
+	var blockStakeOutputID types.BlockStakeOutputID
+	blockStakeOutputID[0] = 2
+	txn := modules.ConsensusTransaction{
+		Transaction: types.Transaction{
+			CoinInputs:       []types.CoinInput{{ParentID: coinOutputID}},
+			BlockStakeInputs: []types.BlockStakeInput{{ParentID: blockStakeOutputID}},
+		},
+		BlockHeight: 42,
+	}
+	update := func(fn func(modules.ConsensusTransaction, *persist.LazyBucket) error) {
+		err := db.Update(func(tx kv.Tx) error {
+			return fn(txn, persist.NewLazyBucket(func() (kv.Bucket, error) {
+				return tx.Bucket(pluginBucketName), nil
+			}))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// outputs are not spent until the transaction is applied
+	if _, err = plugin.GetSpentCoinOutput(coinOutputID); err != ErrOutputNotSpent {
+		t.Fatalf("expected %v, got: %v", ErrOutputNotSpent, err)
+	}
+
+	update(plugin.ApplyTransaction)
+	expected := SpentOutput{TransactionID: txn.ID(), BlockHeight: txn.BlockHeight}
+	spentOutput, err := plugin.GetSpentCoinOutput(coinOutputID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spentOutput != expected {
+		t.Errorf("unexpected spent coin output: %v != %v", spentOutput, expected)
+	}
+	spentOutput, err = plugin.GetSpentBlockStakeOutput(blockStakeOutputID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spentOutput != expected {
+		t.Errorf("unexpected spent blockstake output: %v != %v", spentOutput, expected)
+	}
+
+	// reverting the transaction makes the outputs unspent again
+	update(plugin.RevertTransaction)
+	if _, err = plugin.GetSpentCoinOutput(coinOutputID); err != ErrOutputNotSpent {
+		t.Errorf("expected %v, got: %v", ErrOutputNotSpent, err)
+	}
+	if _, err = plugin.GetSpentBlockStakeOutput(blockStakeOutputID); err != ErrOutputNotSpent {
+		t.Errorf("expected %v, got: %v", ErrOutputNotSpent, err)
+	}
+}
@@ -65,22 +65,58 @@ func (b *BlockCreator) ReceiveUpdatedUnconfirmedTransactions(unconfirmedTransact
 
 	// Add transactions to the block until the block size limit is reached.
 	// Transactions are assumed to be in a sensible order.
-	var i int
-	remainingSize := int(b.chainCts.BlockSizeLimit - 5e3) //check this 5k for the first extra
+	txns, err := selectTransactions(unconfirmedTransactions, int(b.chainCts.BlockSizeLimit-5e3)) //check this 5k for the first extra
+	if err != nil {
+		return err
+	}
+	b.unsolvedBlock.Transactions = txns
+	return nil
+}
+
+// selectTransactions selects, in order, the transactions that fit within the given size.
+// A transaction that doesn't fit is skipped, as well as all transactions depending on it,
+// such that a child transaction is never added to the block without its parent.
+// Smaller transactions ordered after a skipped transaction can still be selected.
+// The transaction pool ranks a parent and child transaction as a single package,
+// allowing the fee of the child transaction to pay for the parent transaction.
+func selectTransactions(txns []types.Transaction, remainingSize int) ([]types.Transaction, error) {
 	var (
-		err     error
-		txBytes []byte
+		selected                 []types.Transaction
+		skippedCoinOutputs       = make(map[types.CoinOutputID]struct{})
+		skippedBlockStakeOutputs = make(map[types.BlockStakeOutputID]struct{})
 	)
-	for i = range unconfirmedTransactions {
-		txBytes, err = siabin.Marshal(unconfirmedTransactions[i])
-		if err != nil {
-			return fmt.Errorf("failed to (siabin) marshal tx %d: %v", i, err)
+	for i, txn := range txns {
+		skip := false
+		for _, ci := range txn.CoinInputs {
+			if _, ok := skippedCoinOutputs[ci.ParentID]; ok {
+				skip = true
+				break
+			}
 		}
-		remainingSize -= len(txBytes)
-		if remainingSize < 0 {
-			break
+		for _, bsi := range txn.BlockStakeInputs {
+			if _, ok := skippedBlockStakeOutputs[bsi.ParentID]; ok {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			txBytes, err := siabin.Marshal(txn)
+			if err != nil {
+				return nil, fmt.Errorf("failed to (siabin) marshal tx %d: %v", i, err)
+			}
+			if len(txBytes) <= remainingSize {
+				remainingSize -= len(txBytes)
+				selected = append(selected, txn)
+				continue
+			}
+		}
+		// skip the transaction, as well as all transactions spending its outputs
+		for j := range txn.CoinOutputs {
+			skippedCoinOutputs[txn.CoinOutputID(uint64(j))] = struct{}{}
+		}
+		for j := range txn.BlockStakeOutputs {
+			skippedBlockStakeOutputs[txn.BlockStakeOutputID(uint64(j))] = struct{}{}
 		}
 	}
-	b.unsolvedBlock.Transactions = unconfirmedTransactions[:i+1]
-	return nil
+	return selected, nil
 }
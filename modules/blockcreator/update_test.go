@@ -1 +1,67 @@
 package blockcreator
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+func TestSelectTransactions(t *testing.T) {
+	newTxn := func(data string, parents ...types.Transaction) types.Transaction {
+		txn := types.Transaction{
+			Version:       types.TransactionVersionOne,
+			CoinOutputs:   []types.CoinOutput{{Value: types.NewCurrency64(1)}},
+			ArbitraryData: []byte(data),
+		}
+		for _, parent := range parents {
+			txn.CoinInputs = append(txn.CoinInputs, types.CoinInput{ParentID: parent.CoinOutputID(0)})
+		}
+		return txn
+	}
+	size := func(txns ...types.Transaction) (n int) {
+		for _, txn := range txns {
+			b, err := siabin.Marshal(txn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n += len(b)
+		}
+		return
+	}
+
+	parent := newTxn("parent")
+	child := newTxn("child", parent)
+	large := newTxn(string(make([]byte, 512)))
+	largeChild := newTxn("large child", large)
+	small := newTxn("small")
+
+	testCases := []struct {
+		Transactions []types.Transaction
+		Size         int
+		Expected     []types.Transaction
+	}{
+		{nil, 1024, nil},
+		{[]types.Transaction{parent, child, small}, size(parent, child, small), []types.Transaction{parent, child, small}},
+		{[]types.Transaction{parent, child, small}, size(parent, child, small) - 1, []types.Transaction{parent, child}},
+		{[]types.Transaction{parent, child, small}, size(parent, small), []types.Transaction{parent, small}},
+		{[]types.Transaction{large, largeChild, parent, child}, size(largeChild, parent, child), []types.Transaction{parent, child}},
+		{[]types.Transaction{large, largeChild, small}, size(large, small), []types.Transaction{large, small}},
+	}
+	for idx, testCase := range testCases {
+		txns, err := selectTransactions(testCase.Transactions, testCase.Size)
+		if err != nil {
+			t.Errorf("test case #%d: %v", idx, err)
+			continue
+		}
+		if len(txns) != len(testCase.Expected) {
+			t.Errorf("test case #%d: unexpected selection of %d transactions: %v", idx, len(txns), txns)
+			continue
+		}
+		for i := range txns {
+			if txns[i].ID() != testCase.Expected[i].ID() {
+				t.Errorf("test case #%d: unexpected transaction #%d: %v", idx, i, txns[i])
+			}
+		}
+	}
+}
@@ -3,13 +3,14 @@ package modules
 import (
 	"context"
 	"errors"
+	"io"
 	"math/big"
 
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/persist"
 	"github.com/threefoldtech/rivine/types"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 const (
@@ -40,6 +41,11 @@ var (
 	// database.
 	ErrBlockKnown = errors.New("block already present in database")
 
+	// ErrBlockPruned indicates that the (full) data of a block is not available,
+	// as it has been pruned by a consensus set running in pruned mode,
+	// or because the consensus set was bootstrapped from a snapshot taken after that block.
+	ErrBlockPruned = errors.New("block data has been pruned")
+
 	// ErrBlockUnsolved indicates that a block did not meet the required POS
 	// target.
 	ErrBlockUnsolved = errors.New("block does not meet target")
@@ -84,28 +90,31 @@ type (
 		// An error should be returned in case something went wrong.
 		// metadata is nil in case the plugin wasn't registered prior to this attempt.
 		// This method will be called while registering the plugin.
-		InitPlugin(metadata *persist.Metadata, bucket *bolt.Bucket, ps PluginViewStorage, cb PluginUnregisterCallback) (persist.Metadata, error)
+		InitPlugin(metadata *persist.Metadata, bucket kv.Bucket, ps PluginViewStorage, cb PluginUnregisterCallback) (persist.Metadata, error)
 
 		// Apply the block to the plugin.
 		// An error should be returned in case something went wrong.
-		ApplyBlock(block ConsensusBlock, bucket *persist.LazyBoltBucket) error
+		ApplyBlock(block ConsensusBlock, bucket *persist.LazyBucket) error
 		// Revert the block from the plugin.
 		// An error should be returned in case something went wrong.
-		RevertBlock(block ConsensusBlock, bucket *persist.LazyBoltBucket) error
+		RevertBlock(block ConsensusBlock, bucket *persist.LazyBucket) error
 
 		// Apply the block header to the plugin.
 		// An error should be returned in case something went wrong.
-		ApplyBlockHeader(block ConsensusBlockHeader, bucket *persist.LazyBoltBucket) error
+		// It is also used to validate the block headers received during the headers-first
+		// initial blockchain download, prior to their blocks being known, in which case the
+		// header has no miner payouts and all changes made to the bucket are rolled back.
+		ApplyBlockHeader(block ConsensusBlockHeader, bucket *persist.LazyBucket) error
 		// Revert the block header from the plugin.
 		// An error should be returned in case something went wrong.
-		RevertBlockHeader(block ConsensusBlockHeader, bucket *persist.LazyBoltBucket) error
+		RevertBlockHeader(block ConsensusBlockHeader, bucket *persist.LazyBucket) error
 
 		// Apply the transaction to the plugin.
 		// An error should be returned in case something went wrong.
-		ApplyTransaction(txn ConsensusTransaction, bucket *persist.LazyBoltBucket) error
+		ApplyTransaction(txn ConsensusTransaction, bucket *persist.LazyBucket) error
 		// Revert the transaction from the plugin.
 		// An error should be returned in case something went wrong.
-		RevertTransaction(txn ConsensusTransaction, bucket *persist.LazyBoltBucket) error
+		RevertTransaction(txn ConsensusTransaction, bucket *persist.LazyBucket) error
 
 		// TransactionValidatorFunctions allows the plugin to provide validation rules for all transaction versions it mapped to
 		TransactionValidatorVersionFunctionMapping() map[types.TransactionVersion][]PluginTransactionValidationFunction
@@ -122,13 +131,13 @@ type (
 
 	// A PluginStorage
 	PluginViewStorage interface {
-		View(callback func(bucket *bolt.Bucket) error) error
+		View(callback func(bucket kv.Bucket) error) error
 		Close() error
 	}
 
 	// PluginTransactionValidationFunction is the signature of a validator function that
 	// can be used to provide plugin-driven transaction validation, provided by (and linked to) a plugin.
-	PluginTransactionValidationFunction func(tx ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBoltBucket) error
+	PluginTransactionValidationFunction func(tx ConsensusTransaction, ctx types.TransactionValidationContext, bucket *persist.LazyBucket) error
 
 	// TransactionValidationFunction is the signature of a validator function that
 	// can be used to provide validation rules for transactions.
@@ -170,6 +179,12 @@ type (
 
 		SpentCoinOutputs       map[types.CoinOutputID]types.CoinOutput
 		SpentBlockStakeOutputs map[types.BlockStakeOutputID]types.BlockStakeOutput
+
+		// SpentCoinOutputHeights and SpentBlockStakeOutputHeights contain the heights
+		// of the blocks in which the spent outputs were confirmed. They are only
+		// defined for transactions which are validated by the consensus set.
+		SpentCoinOutputHeights       map[types.CoinOutputID]types.BlockHeight
+		SpentBlockStakeOutputHeights map[types.BlockStakeOutputID]types.BlockHeight
 	}
 
 	// A ConsensusChange enumerates a set of changes that occurred to the consensus set.
@@ -248,6 +263,45 @@ type (
 		ShortID   types.TransactionShortID
 	}
 
+	// ConsensusSyncProgress describes the progress of the
+	// initial blockchain download of the consensus set.
+	ConsensusSyncProgress struct {
+		// BlockHeight is the height of the last block applied to the consensus set.
+		BlockHeight types.BlockHeight `json:"blockheight"`
+		// PeerHeight is the highest block height reported by the peers
+		// the block headers are downloaded from.
+		PeerHeight types.BlockHeight `json:"peerheight"`
+		// DownloadPeers is the amount of peers block bodies are currently downloaded from.
+		DownloadPeers int `json:"downloadpeers"`
+	}
+
+	// ConsensusSnapshot describes a snapshot of the consensus state,
+	// as exported by the consensus set.
+	ConsensusSnapshot struct {
+		// BlockHeight is the height of the block at which the snapshot was taken.
+		BlockHeight types.BlockHeight `json:"blockheight"`
+		// BlockID is the ID of the block at which the snapshot was taken.
+		BlockID types.BlockID `json:"blockid"`
+		// Checksum is the (blake2b) checksum of the entire snapshot file,
+		// which has to be known (hard-coded) by nodes in order to bootstrap from it.
+		Checksum crypto.Hash `json:"checksum"`
+	}
+
+	// RefusedFork describes a fork the consensus set refused to switch to,
+	// as it conflicts with a checkpoint, or would revert too many blocks.
+	RefusedFork struct {
+		// BlockID is the ID of the block which would have caused the switch.
+		BlockID types.BlockID `json:"blockid"`
+		// BlockHeight is the height of that block.
+		BlockHeight types.BlockHeight `json:"blockheight"`
+		// ForkHeight is the height of the common parent of the fork and the current path.
+		ForkHeight types.BlockHeight `json:"forkheight"`
+		// Reason explains why the fork was refused.
+		Reason string `json:"reason"`
+		// Timestamp is the time at which the fork was refused.
+		Timestamp types.Timestamp `json:"timestamp"`
+	}
+
 	// A ConsensusSet accepts blocks and builds an understanding of network
 	// consensus.
 	ConsensusSet interface {
@@ -264,9 +318,15 @@ type (
 		AcceptBlock(types.Block) error
 
 		// BlockAtHeight returns the block found at the input height, with a
-		// bool to indicate whether that block exists.
+		// bool to indicate whether that block exists. A block which has been pruned
+		// is reported as non-existing, use PrunedHeight to tell both cases apart.
 		BlockAtHeight(types.BlockHeight) (types.Block, bool)
 
+		// PrunedHeight returns the height from which onwards all blocks of the
+		// current path are available. Blocks below this height might have been
+		// pruned, it is 0 for a consensus set which has all blocks available.
+		PrunedHeight() types.BlockHeight
+
 		// BlockHeightOfBlock returns the blockheight of a given block, with a
 		// bool to indicate whether that block exists.
 		BlockHeightOfBlock(types.Block) (types.BlockHeight, bool)
@@ -314,6 +374,18 @@ type (
 		// Synced returns true if the consensus set is synced with the network.
 		Synced() bool
 
+		// SyncProgress returns the progress of the initial blockchain download.
+		SyncProgress() ConsensusSyncProgress
+
+		// RefusedForks returns the most recent forks the consensus set refused to switch to,
+		// as they conflict with a checkpoint or would revert more blocks than allowed.
+		RefusedForks() []RefusedFork
+
+		// ExportSnapshot writes a snapshot of the consensus state at the current block height
+		// to the given writer. The checksum of the snapshot depends on the registered plugins,
+		// as their buckets are part of the snapshot.
+		ExportSnapshot(w io.Writer) (ConsensusSnapshot, error)
+
 		// InCurrentPath returns true if the block id presented is found in the
 		// current path, false otherwise.
 		InCurrentPath(types.BlockID) bool
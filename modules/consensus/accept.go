@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"time"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -57,6 +57,13 @@ func (cs *ConsensusSet) validateHeaderAndBlock(tx dbTx, b types.Block) error {
 	if err != nil {
 		return err
 	}
+	// Check that the block does not conflict with the checkpoints,
+	// refusing (and remembering) the fork it is part of otherwise.
+	err = cs.validateCheckpoints(blockMap, parent.Height+1, id)
+	if err != nil {
+		cs.refuseFork(id, parent.Height+1, parent.Height, err)
+		return err
+	}
 	// Check that the timestamp is not too far in the past to be acceptable.
 	minTimestamp := cs.blockRuleHelper.minimumValidChildTimestamp(blockMap, &parent)
 
@@ -96,6 +103,12 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 		return err
 	}
 
+	// Check that the header does not conflict with the checkpoints.
+	err = cs.validateCheckpoints(blockMap, parent.Height+1, id)
+	if err != nil {
+		return err
+	}
+
 	// TODO: check if the block is a non extending block once headers-first
 	// downloads are implemented.
 
@@ -134,7 +147,7 @@ func (cs *ConsensusSet) validateHeader(tx dbTx, h types.BlockHeader) error {
 // unneeded.
 func (cs *ConsensusSet) addBlockToTree(b types.Block) (ce changeEntry, err error) {
 	var nonExtending bool
-	err = cs.db.Update(func(tx *bolt.Tx) error {
+	err = cs.db.Update(func(tx kv.Tx) error {
 		pb, err := getBlockMap(tx, b.ParentID)
 		if err != nil {
 			build.Critical(err)
@@ -242,7 +255,7 @@ func (cs *ConsensusSet) managedAcceptBlock(b types.Block) error {
 	cs.mu.Lock()
 
 	// Start verification inside of a bolt View tx.
-	err := cs.db.View(func(tx *bolt.Tx) error {
+	err := cs.db.View(func(tx kv.Tx) error {
 		// Do not accept a block if the database is inconsistent.
 		if inconsistencyDetected(tx) {
 			return errInconsistentSet
@@ -250,7 +263,7 @@ func (cs *ConsensusSet) managedAcceptBlock(b types.Block) error {
 		// Do some relatively inexpensive checks to validate the header and block.
 		// Validation generally occurs in the order of least expensive validation
 		// first.
-		err := cs.validateHeaderAndBlock(boltTxWrapper{tx}, b)
+		err := cs.validateHeaderAndBlock(kvTxWrapper{tx}, b)
 		if err != nil {
 			// If the block is in the near future, but too far to be acceptable, then
 			// save the block and add it to the consensus set after it is no longer
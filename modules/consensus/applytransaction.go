@@ -6,15 +6,15 @@ package consensus
 import (
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/types"
 )
 
 // applyCoinInputs takes all of the coin inputs in a transaction and
 // applies them to the state, updating the diffs in the processed block.
-func applyCoinInputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyCoinInputs(tx kv.Tx, pb *processedBlock, t types.Transaction) {
 	// Remove all coin inputs from the unspent siacoin outputs list.
 	for _, sci := range t.CoinInputs {
 		sco, err := getCoinOutput(tx, sci.ParentID)
@@ -27,13 +27,13 @@ func applyCoinInputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
 			CoinOutput: sco,
 		}
 		pb.CoinOutputDiffs = append(pb.CoinOutputDiffs, scod)
-		commitCoinOutputDiff(tx, scod, modules.DiffApply)
+		commitCoinOutputDiff(tx, scod, modules.DiffApply, pb.Height)
 	}
 }
 
 // applyCoinOutputs takes all of the coin outputs in a transaction and
 // applies them to the state, updating the diffs in the processed block.
-func applyCoinOutputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyCoinOutputs(tx kv.Tx, pb *processedBlock, t types.Transaction) {
 	// Add all siacoin outputs to the unspent siacoin outputs list.
 	for i, sco := range t.CoinOutputs {
 		scoid := t.CoinOutputID(uint64(i))
@@ -43,13 +43,13 @@ func applyCoinOutputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
 			CoinOutput: sco,
 		}
 		pb.CoinOutputDiffs = append(pb.CoinOutputDiffs, scod)
-		commitCoinOutputDiff(tx, scod, modules.DiffApply)
+		commitCoinOutputDiff(tx, scod, modules.DiffApply, pb.Height)
 	}
 }
 
 // applyBlockStakeInputs takes all of the siafund inputs in a transaction and
 // applies them to the state, updating the diffs in the processed block.
-func applyBlockStakeInputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyBlockStakeInputs(tx kv.Tx, pb *processedBlock, t types.Transaction) {
 	for _, sfi := range t.BlockStakeInputs {
 		// Calculate the volume of siacoins to put in the claim output.
 		sfo, err := getBlockStakeOutput(tx, sfi.ParentID)
@@ -65,12 +65,12 @@ func applyBlockStakeInputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction)
 			BlockStakeOutput: sfo,
 		}
 		pb.BlockStakeOutputDiffs = append(pb.BlockStakeOutputDiffs, sfod)
-		commitBlockStakeOutputDiff(tx, sfod, modules.DiffApply)
+		commitBlockStakeOutputDiff(tx, sfod, modules.DiffApply, pb.Height)
 	}
 }
 
 // applyBlockStakeOutput applies a siafund output to the consensus set.
-func applyBlockStakeOutputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyBlockStakeOutputs(tx kv.Tx, pb *processedBlock, t types.Transaction) {
 	for i, sfo := range t.BlockStakeOutputs {
 		sfoid := t.BlockStakeOutputID(uint64(i))
 		sfod := modules.BlockStakeOutputDiff{
@@ -79,12 +79,12 @@ func applyBlockStakeOutputs(tx *bolt.Tx, pb *processedBlock, t types.Transaction
 			BlockStakeOutput: sfo,
 		}
 		pb.BlockStakeOutputDiffs = append(pb.BlockStakeOutputDiffs, sfod)
-		commitBlockStakeOutputDiff(tx, sfod, modules.DiffApply)
+		commitBlockStakeOutputDiff(tx, sfod, modules.DiffApply, pb.Height)
 	}
 }
 
 // applyTransactionIDMapping applies a transaction id mapping to the consensus set
-func applyTransactionIDMapping(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyTransactionIDMapping(tx kv.Tx, pb *processedBlock, t types.Transaction) {
 	tidmod := modules.TransactionIDDiff{
 		Direction: modules.DiffApply,
 		LongID:    t.ID(),
@@ -97,7 +97,7 @@ func applyTransactionIDMapping(tx *bolt.Tx, pb *processedBlock, t types.Transact
 // applyTransaction applies the contents of a transaction to the ConsensusSet.
 // This produces a set of diffs, which are stored in the blockNode containing
 // the transaction. No verification is done by this function.
-func applyTransaction(tx *bolt.Tx, pb *processedBlock, t types.Transaction) {
+func applyTransaction(tx kv.Tx, pb *processedBlock, t types.Transaction) {
 	applyCoinInputs(tx, pb, t)
 	applyCoinOutputs(tx, pb, t)
 	applyBlockStakeInputs(tx, pb, t)
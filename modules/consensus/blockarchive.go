@@ -0,0 +1,183 @@
+package consensus
+
+// blockarchive.go implements block archives: a range of blocks of the current
+// path, exported as a single portable file. Nodes can be seeded from such an
+// archive (or use it as an offline backup), rather than downloading the blocks
+// from the network. Unlike a snapshot, an archive contains the blocks only,
+// which are fully validated when imported, as if received from a peer.
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+var (
+	errBlockArchiveVersion      = errors.New("unsupported block archive version")
+	errBlockArchiveInvalid      = errors.New("invalid block archive")
+	errBlockArchiveRange        = errors.New("invalid block archive range")
+	errBlockArchiveBlockMissing = errors.New("block required for the archive is not available")
+)
+
+const (
+	// blockArchiveVersion is the version of the block archive format.
+	blockArchiveVersion uint8 = 1
+)
+
+type (
+	// blockArchiveHeader is the first object encoded in a block archive,
+	// identifying the chain and network the blocks belong to,
+	// as well as the range of blocks that follow the header.
+	blockArchiveHeader struct {
+		Version     uint8
+		ChainName   string
+		NetworkName string
+		GenesisID   types.BlockID
+		StartHeight types.BlockHeight
+		BlockCount  uint64
+	}
+
+	// BlockArchiveProgress reports the progress of a block archive import.
+	BlockArchiveProgress struct {
+		// Height of the last block read from the archive.
+		Height types.BlockHeight
+		// Amount of blocks read from the archive so far,
+		// and the total amount of blocks contained by the archive.
+		Processed uint64
+		Total     uint64
+		// Amount of blocks accepted by the consensus set so far,
+		// blocks that are already known are skipped.
+		Accepted uint64
+	}
+)
+
+// ExportBlocks writes the blocks of the current path, starting at height from
+// up to and including height to, as a block archive to the given writer.
+// The amount of blocks exported is returned. Blocks which are no longer
+// available, e.g. because they are pruned, cannot be exported.
+func (cs *ConsensusSet) ExportBlocks(w io.Writer, from, to types.BlockHeight) (uint64, error) {
+	err := cs.tg.Add()
+	if err != nil {
+		return 0, err
+	}
+	defer cs.tg.Done()
+
+	if from > to {
+		return 0, fmt.Errorf("%v: start height %d is greater than end height %d", errBlockArchiveRange, from, to)
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := rivbin.NewEncoder(bw)
+
+	count := uint64(to-from) + 1
+	// a single (read-only) database transaction ensures all blocks belong to the same path,
+	// even if blocks are applied to the consensus set in the meantime
+	err = cs.db.View(func(tx kv.Tx) error {
+		if height := blockHeight(tx); to > height {
+			return fmt.Errorf("%v: cannot export up to height %d at height %d", errBlockArchiveRange, to, height)
+		}
+		err := enc.Encode(blockArchiveHeader{
+			Version:     blockArchiveVersion,
+			ChainName:   cs.bcInfo.Name,
+			NetworkName: cs.bcInfo.NetworkName,
+			GenesisID:   cs.blockRoot.Block.ID(),
+			StartHeight: from,
+			BlockCount:  count,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to (rivbin) encode block archive header: %v", err)
+		}
+		for height := from; height <= to; height++ {
+			id, err := getPath(tx, height)
+			if err != nil {
+				return fmt.Errorf("failed to get block ID at height %d: %v", height, err)
+			}
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				return fmt.Errorf("%v: block %v at height %d: %v", errBlockArchiveBlockMissing, id, height, err)
+			}
+			err = enc.Encode(pb.Block)
+			if err != nil {
+				return fmt.Errorf("failed to (rivbin) encode block %v at height %d: %v", id, height, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return 0, err
+	}
+	cs.log.Printf("Exported %d blocks (heights %d-%d) to a block archive", count, from, to)
+	return count, nil
+}
+
+// ImportBlocks reads a block archive from the given reader, and feeds all its blocks
+// to the consensus set using AcceptBlock, such that each block is fully validated.
+// Blocks already known by the consensus set are skipped. The given (optional)
+// progress callback is called for each block read from the archive.
+// The import can be interrupted by cancelling the given context.
+func (cs *ConsensusSet) ImportBlocks(ctx context.Context, r io.Reader, progress func(BlockArchiveProgress)) (BlockArchiveProgress, error) {
+	dec := rivbin.NewDecoder(bufio.NewReader(r))
+
+	var header blockArchiveHeader
+	err := dec.Decode(&header)
+	if err != nil {
+		return BlockArchiveProgress{}, fmt.Errorf("failed to (rivbin) decode block archive header: %v", err)
+	}
+	if header.Version != blockArchiveVersion {
+		return BlockArchiveProgress{}, fmt.Errorf("%v: %d", errBlockArchiveVersion, header.Version)
+	}
+	if header.ChainName != cs.bcInfo.Name || header.NetworkName != cs.bcInfo.NetworkName {
+		return BlockArchiveProgress{}, fmt.Errorf(
+			"%v: blocks of %s (%s) cannot be used for %s (%s)", errBlockArchiveInvalid,
+			header.ChainName, header.NetworkName, cs.bcInfo.Name, cs.bcInfo.NetworkName)
+	}
+	if header.GenesisID != cs.blockRoot.Block.ID() {
+		return BlockArchiveProgress{}, fmt.Errorf("%v: archive has a different genesis block", errBlockArchiveInvalid)
+	}
+
+	status := BlockArchiveProgress{
+		Total: header.BlockCount,
+	}
+	for status.Processed < header.BlockCount {
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		default:
+		}
+
+		var block types.Block
+		err = dec.Decode(&block)
+		if err != nil {
+			return status, fmt.Errorf("failed to (rivbin) decode block #%d of block archive: %v", status.Processed, err)
+		}
+		status.Height = header.StartHeight + types.BlockHeight(status.Processed)
+		status.Processed++
+
+		err = cs.AcceptBlock(block)
+		switch err {
+		case nil:
+			status.Accepted++
+		case modules.ErrBlockKnown:
+			// skip blocks that are already known
+		default:
+			return status, fmt.Errorf("failed to accept block %v at height %d: %v", block.ID(), status.Height, err)
+		}
+		if progress != nil {
+			progress(status)
+		}
+	}
+	cs.log.Printf("Imported block archive: accepted %d out of %d blocks (heights %d-%d)",
+		status.Accepted, status.Total, header.StartHeight, status.Height)
+	return status, nil
+}
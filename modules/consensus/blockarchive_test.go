@@ -0,0 +1,123 @@
+package consensus
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestBlockArchiveExportImport probes the export of a block archive,
+// and the import of it into another consensus set.
+func TestBlockArchiveExportImport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	// only blocks of the current path can be exported
+	_, err = cst.cs.ExportBlocks(new(bytes.Buffer), 0, cst.cs.Height()+1)
+	if !isErr(err, errBlockArchiveRange) {
+		t.Fatalf("expected %v, got: %v", errBlockArchiveRange, err)
+	}
+	_, err = cst.cs.ExportBlocks(new(bytes.Buffer), 1, 0)
+	if !isErr(err, errBlockArchiveRange) {
+		t.Fatalf("expected %v, got: %v", errBlockArchiveRange, err)
+	}
+
+	var buf bytes.Buffer
+	count, err := cst.cs.ExportBlocks(&buf, 0, cst.cs.Height())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != uint64(cst.cs.Height())+1 {
+		t.Fatalf("expected %d exported blocks, got %d", cst.cs.Height()+1, count)
+	}
+	data := buf.Bytes()
+
+	importTester, err := blankConsensusSetTester(t.Name() + "Import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		importTester.cs.Close()
+		importTester.gateway.Close()
+	}()
+
+	var progressed []BlockArchiveProgress
+	status, err := importTester.cs.ImportBlocks(context.Background(), bytes.NewReader(data), func(p BlockArchiveProgress) {
+		progressed = append(progressed, p)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the genesis block is already known, and thus skipped
+	expected := BlockArchiveProgress{Height: 0, Processed: 1, Total: 1, Accepted: 0}
+	if status != expected {
+		t.Fatalf("expected import status %v, got %v", expected, status)
+	}
+	if len(progressed) != 1 || progressed[0] != expected {
+		t.Fatalf("unexpected progress reports: %v", progressed)
+	}
+
+	// an archive of a different network cannot be imported
+	var header blockArchiveHeader
+	err = rivbin.NewDecoder(bytes.NewReader(data)).Decode(&header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.NetworkName = "othernet"
+	_, err = importTester.cs.ImportBlocks(context.Background(), bytes.NewReader(encodeBlockArchive(t, header)), nil)
+	if !isErr(err, errBlockArchiveInvalid) {
+		t.Fatalf("expected %v, got: %v", errBlockArchiveInvalid, err)
+	}
+	header.NetworkName = importTester.cs.bcInfo.NetworkName
+	header.GenesisID = types.BlockID{1}
+	_, err = importTester.cs.ImportBlocks(context.Background(), bytes.NewReader(encodeBlockArchive(t, header)), nil)
+	if !isErr(err, errBlockArchiveInvalid) {
+		t.Fatalf("expected %v, got: %v", errBlockArchiveInvalid, err)
+	}
+	header.GenesisID = importTester.cs.blockRoot.Block.ID()
+	header.Version++
+	_, err = importTester.cs.ImportBlocks(context.Background(), bytes.NewReader(encodeBlockArchive(t, header)), nil)
+	if !isErr(err, errBlockArchiveVersion) {
+		t.Fatalf("expected %v, got: %v", errBlockArchiveVersion, err)
+	}
+
+	// an archive missing the announced blocks cannot be imported
+	header.Version = blockArchiveVersion
+	header.BlockCount++
+	_, err = importTester.cs.ImportBlocks(context.Background(), bytes.NewReader(encodeBlockArchive(t, header)), nil)
+	if err == nil {
+		t.Fatal("expected an error when importing a truncated block archive")
+	}
+
+	// a cancelled import stops before reading any block
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	status, err = importTester.cs.ImportBlocks(ctx, bytes.NewReader(data), nil)
+	if err != context.Canceled {
+		t.Fatalf("expected %v, got: %v", context.Canceled, err)
+	}
+	if status.Processed != 0 {
+		t.Fatalf("expected no blocks to be processed, got %d", status.Processed)
+	}
+}
+
+// encodeBlockArchive encodes a block archive containing only the given header.
+func encodeBlockArchive(t *testing.T, header blockArchiveHeader) []byte {
+	b, err := rivbin.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
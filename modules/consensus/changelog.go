@@ -19,10 +19,10 @@ package consensus
 import (
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -54,7 +54,7 @@ type (
 )
 
 // appendChangeLog adds a new change entry to the change log.
-func appendChangeLog(tx *bolt.Tx, ce changeEntry) error {
+func appendChangeLog(tx kv.Tx, ce changeEntry) error {
 	// Insert the change entry.
 	cl := tx.Bucket(ChangeLog)
 	ceid := ce.ID()
@@ -103,7 +103,7 @@ func appendChangeLog(tx *bolt.Tx, ce changeEntry) error {
 
 // getEntry returns the change entry with a given id, using a bool to indicate
 // existence.
-func getEntry(tx *bolt.Tx, id modules.ConsensusChangeID) (ce changeEntry, exists bool) {
+func getEntry(tx kv.Tx, id modules.ConsensusChangeID) (ce changeEntry, exists bool) {
 	var cn changeNode
 	cl := tx.Bucket(ChangeLog)
 	changeNodeBytes := cl.Get(id[:])
@@ -127,7 +127,7 @@ func (ce *changeEntry) ID() modules.ConsensusChangeID {
 }
 
 // NextEntry returns the entry after the current entry.
-func (ce *changeEntry) NextEntry(tx *bolt.Tx) (nextEntry changeEntry, exists bool) {
+func (ce *changeEntry) NextEntry(tx kv.Tx) (nextEntry changeEntry, exists bool) {
 	// Get the change node associated with the provided change entry.
 	ceid := ce.ID()
 	var cn changeNode
@@ -141,16 +141,16 @@ func (ce *changeEntry) NextEntry(tx *bolt.Tx) (nextEntry changeEntry, exists boo
 	return getEntry(tx, cn.Next)
 }
 
-// createChangeLog assumes that no change log exists and creates a new one.
-func (cs *ConsensusSet) createChangeLog(tx *bolt.Tx) error {
+// createChangeLog assumes that no change log exists and creates a new one,
+// with the given entry as its first entry.
+func createChangeLog(tx kv.Tx, ge changeEntry) error {
 	// Create the changelog bucket.
 	cl, err := tx.CreateBucket(ChangeLog)
 	if err != nil {
 		return err
 	}
 
-	// Add the genesis block as the first entry of the change log.
-	ge := cs.genesisEntry()
+	// Add the first entry of the change log.
 	geid := ge.ID()
 	cn := changeNode{
 		Entry: ge,
@@ -177,3 +177,15 @@ func (cs *ConsensusSet) genesisEntry() changeEntry {
 		AppliedBlocks: []types.BlockID{cs.blockRoot.Block.ID()},
 	}
 }
+
+// initialEntry returns the first entry of the change log, which is the genesis
+// block log entry, unless the consensus set was bootstrapped from a snapshot,
+// in which case it is the snapshot block log entry.
+func (cs *ConsensusSet) initialEntry() changeEntry {
+	if cs.snapshotBlockID != (types.BlockID{}) {
+		return changeEntry{
+			AppliedBlocks: []types.BlockID{cs.snapshotBlockID},
+		}
+	}
+	return cs.genesisEntry()
+}
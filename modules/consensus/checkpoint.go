@@ -0,0 +1,121 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// maxRefusedForks is the maximum amount of refused forks remembered by the consensus set.
+const maxRefusedForks = 16
+
+var (
+	errCheckpointMismatch = errors.New("block conflicts with a checkpoint")
+	errCheckpointFork     = errors.New("fork would revert a checkpointed block")
+	errMaxReorgDepth      = errors.New("fork would revert more blocks than the maximum reorg depth")
+)
+
+// SetCheckpoints sets the hard-coded checkpoints of the network, mapping block heights
+// to the ID of the block which is required at that height. Blocks and forks which
+// conflict with these checkpoints are refused. An error is returned in case
+// the current path of the consensus set already conflicts with one of the checkpoints.
+func (cs *ConsensusSet) SetCheckpoints(checkpoints map[types.BlockHeight]types.BlockID) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	err := cs.db.View(func(tx kv.Tx) error {
+		height := blockHeight(tx)
+		for checkpointHeight, checkpointID := range checkpoints {
+			if checkpointHeight > height {
+				continue
+			}
+			id, err := getPath(tx, checkpointHeight)
+			if err != nil {
+				// the block path is not available prior to the snapshot
+				// the consensus set was bootstrapped from
+				continue
+			}
+			if id != checkpointID {
+				return fmt.Errorf("current block %v at height %d: %v %v",
+					id, checkpointHeight, errCheckpointMismatch, checkpointID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	cs.checkpoints = make(map[types.BlockHeight]types.BlockID, len(checkpoints))
+	for height, id := range checkpoints {
+		cs.checkpoints[height] = id
+	}
+	return nil
+}
+
+// SetMaxReorgDepth sets the maximum amount of blocks which can be reverted by a
+// single reorg. Forks which would revert more blocks are refused.
+// A depth of 0 allows reorgs of any depth.
+func (cs *ConsensusSet) SetMaxReorgDepth(depth types.BlockHeight) {
+	cs.mu.Lock()
+	cs.maxReorgDepth = depth
+	cs.mu.Unlock()
+}
+
+// RefusedForks returns the most recent forks the consensus set refused to switch to,
+// ordered from oldest to newest.
+func (cs *ConsensusSet) RefusedForks() []modules.RefusedFork {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return append([]modules.RefusedFork(nil), cs.refusedForks...)
+}
+
+// validateCheckpoints validates that a block with the given ID at the given height
+// does not conflict with any of the checkpoints. A block conflicts if a different block
+// is required at its height, or if it would fork off below a checkpointed block already
+// known to the consensus set, as such a block can never be an ancestor of that checkpoint.
+func (cs *ConsensusSet) validateCheckpoints(blockMap dbBucket, height types.BlockHeight, id types.BlockID) error {
+	for checkpointHeight, checkpointID := range cs.checkpoints {
+		if checkpointHeight == height && checkpointID != id {
+			return errCheckpointMismatch
+		}
+		if checkpointHeight >= height && blockMap.Get(checkpointID[:]) != nil {
+			return errCheckpointFork
+		}
+	}
+	return nil
+}
+
+// validateFork validates that the consensus set can switch to a fork with the given
+// common parent, which is refused if that would revert a checkpointed block,
+// or more blocks than the maximum reorg depth allows.
+func (cs *ConsensusSet) validateFork(tx kv.Tx, commonParent *processedBlock) error {
+	height := blockHeight(tx)
+	for checkpointHeight := range cs.checkpoints {
+		if checkpointHeight > commonParent.Height && checkpointHeight <= height {
+			return errCheckpointFork
+		}
+	}
+	if cs.maxReorgDepth != 0 && height-commonParent.Height > cs.maxReorgDepth {
+		return fmt.Errorf("%v: reverting %d blocks, while at most %d blocks can be reverted",
+			errMaxReorgDepth, height-commonParent.Height, cs.maxReorgDepth)
+	}
+	return nil
+}
+
+// refuseFork logs and remembers a fork the consensus set refused to switch to,
+// such that it can be surfaced to the user.
+func (cs *ConsensusSet) refuseFork(id types.BlockID, height, forkHeight types.BlockHeight, reason error) {
+	cs.log.Printf("WARN: refused fork of block %v at height %d, forking off at height %d: %v", id, height, forkHeight, reason)
+	cs.refusedForks = append(cs.refusedForks, modules.RefusedFork{
+		BlockID:     id,
+		BlockHeight: height,
+		ForkHeight:  forkHeight,
+		Reason:      reason.Error(),
+		Timestamp:   types.CurrentTimestamp(),
+	})
+	if len(cs.refusedForks) > maxRefusedForks {
+		cs.refusedForks = cs.refusedForks[len(cs.refusedForks)-maxRefusedForks:]
+	}
+}
@@ -0,0 +1,134 @@
+package consensus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestSetCheckpoints probes that checkpoints which conflict
+// with the current path of the consensus set are refused.
+func TestSetCheckpoints(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	var otherID types.BlockID
+	otherID[0] = 1
+	err = cst.cs.SetCheckpoints(map[types.BlockHeight]types.BlockID{0: otherID})
+	if !isErr(err, errCheckpointMismatch) {
+		t.Fatalf("expected %v, got: %v", errCheckpointMismatch, err)
+	}
+	err = cst.cs.SetCheckpoints(map[types.BlockHeight]types.BlockID{
+		0: cst.cs.blockRoot.Block.ID(),
+		// checkpoints beyond the current height can't conflict yet
+		1: otherID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestValidateCheckpoints probes the validation of blocks against the checkpoints.
+func TestValidateCheckpoints(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	// store two blocks on top of the genesis block, the second one being checkpointed
+	parentID := cst.cs.blockRoot.Block.ID()
+	var ids []types.BlockID
+	err = cst.cs.db.Update(func(tx kv.Tx) error {
+		for height := types.BlockHeight(1); height <= 2; height++ {
+			pb := &processedBlock{
+				Block: types.Block{
+					ParentID:  parentID,
+					Timestamp: cst.cs.blockRoot.Block.Timestamp + types.Timestamp(height),
+				},
+				Height: height,
+			}
+			addBlockMap(tx, pb)
+			parentID = pb.Block.ID()
+			ids = append(ids, parentID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cst.cs.checkpoints = map[types.BlockHeight]types.BlockID{2: ids[1]}
+
+	var otherID types.BlockID
+	otherID[0] = 1
+	testCases := []struct {
+		Height types.BlockHeight
+		Error  error
+	}{
+		{1, errCheckpointFork},
+		{2, errCheckpointMismatch},
+		{3, nil},
+	}
+	err = cst.cs.db.View(func(tx kv.Tx) error {
+		for idx, testCase := range testCases {
+			err := cst.cs.validateCheckpoints(tx.Bucket(BlockMap), testCase.Height, otherID)
+			if err != testCase.Error {
+				t.Errorf("test case #%d: unexpected error: %v != %v", idx, err, testCase.Error)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRefusedForks probes that only the most recent refused forks are remembered.
+func TestRefusedForks(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	if forks := cst.cs.RefusedForks(); len(forks) != 0 {
+		t.Fatalf("unexpected refused forks: %v", forks)
+	}
+	reason := errors.New("test fork")
+	for height := types.BlockHeight(1); height <= maxRefusedForks+1; height++ {
+		cst.cs.refuseFork(types.BlockID{}, height, 0, reason)
+	}
+	forks := cst.cs.RefusedForks()
+	if len(forks) != maxRefusedForks {
+		t.Fatalf("unexpected amount of refused forks: %d", len(forks))
+	}
+	if forks[0].BlockHeight != 2 || forks[len(forks)-1].BlockHeight != maxRefusedForks+1 {
+		t.Errorf("unexpected refused forks: %v", forks)
+	}
+	if forks[0].Reason != reason.Error() {
+		t.Errorf("unexpected reason: %q", forks[0].Reason)
+	}
+}
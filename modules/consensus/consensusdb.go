@@ -7,11 +7,12 @@ package consensus
 // ignored otherwise, which is suboptimal.
 
 import (
+	"errors"
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -59,10 +60,45 @@ var (
 
 	// BucketPlugins is a database buckets that contains all plugins and their metadata.
 	BucketPlugins = []byte("Plugins")
+
+	// BlockHeaders is a database bucket containing the headers of blocks in the
+	// current path for which no processed block is available in the BlockMap,
+	// together with their height, keyed by their id. It only exists for consensus sets
+	// bootstrapped from a snapshot or which pruned blocks, and allows to walk back
+	// the chain beyond the available processed blocks.
+	BlockHeaders = []byte("BlockHeaders")
+
+	// CoinOutputHeights is a database bucket that contains the height of the
+	// block in which a coin output was added to the unspent coin outputs,
+	// keyed by the coin output ID. It contains an entry for each unspent coin output.
+	CoinOutputHeights = []byte("CoinOutputHeights")
+
+	// BlockStakeOutputHeights is a database bucket that contains the height of the
+	// block in which a blockstake output was added to the unspent blockstake outputs,
+	// keyed by the blockstake output ID. It contains an entry for each unspent blockstake output.
+	BlockStakeOutputHeights = []byte("BlockStakeOutputHeights")
+
+	// SpentCoinOutputHeights is a database bucket that contains a nested bucket
+	// for each block height, containing the (CoinOutputHeights) entries of the coin outputs
+	// spent by the block at that height. These are moved back when the block is reverted,
+	// and deleted once the block is pruned.
+	SpentCoinOutputHeights = []byte("SpentCoinOutputHeights")
+
+	// SpentBlockStakeOutputHeights is a database bucket that contains a nested bucket
+	// for each block height, containing the (BlockStakeOutputHeights) entries of the blockstake outputs
+	// spent by the block at that height. These are moved back when the block is reverted,
+	// and deleted once the block is pruned.
+	SpentBlockStakeOutputHeights = []byte("SpentBlockStakeOutputHeights")
+)
+
+var (
+	errMissingOutputHeight = errors.New("confirmation height of output is missing from the consensus database")
+	errOutputHeightsResync = errors.New("consensus database predates the output confirmation heights, " +
+		"which can't be restored as not all blocks are available, a resync of the consensus set is required")
 )
 
 // createConsensusObjects initialzes the consensus portions of the database.
-func (cs *ConsensusSet) createConsensusDB(tx *bolt.Tx) error {
+func (cs *ConsensusSet) createConsensusDB(tx kv.Tx) error {
 	// Enumerate and create the database buckets.
 	buckets := [][]byte{
 		BlockHeight,
@@ -80,6 +116,10 @@ func (cs *ConsensusSet) createConsensusDB(tx *bolt.Tx) error {
 			return err
 		}
 	}
+	err := createOutputHeightBuckets(tx)
+	if err != nil {
+		return err
+	}
 
 	// Set the block height to -1, so the genesis block is at height 0.
 	blockHeight := tx.Bucket(BlockHeight)
@@ -97,10 +137,10 @@ func (cs *ConsensusSet) createConsensusDB(tx *bolt.Tx) error {
 	// needs to happen between the database being opened/initilized and the
 	// consensus set hash being calculated
 	for _, cod := range cs.blockRoot.CoinOutputDiffs {
-		commitCoinOutputDiff(tx, cod, modules.DiffApply)
+		commitCoinOutputDiff(tx, cod, modules.DiffApply, 0)
 	}
 	for _, sfod := range cs.blockRoot.BlockStakeOutputDiffs {
-		commitBlockStakeOutputDiff(tx, sfod, modules.DiffApply)
+		commitBlockStakeOutputDiff(tx, sfod, modules.DiffApply, 0)
 	}
 
 	// Add the genesis block to the block structures - checksum must be taken
@@ -114,7 +154,7 @@ func (cs *ConsensusSet) createConsensusDB(tx *bolt.Tx) error {
 }
 
 // blockHeight returns the height of the blockchain.
-func blockHeight(tx *bolt.Tx) (height types.BlockHeight) {
+func blockHeight(tx kv.Tx) (height types.BlockHeight) {
 	bh := tx.Bucket(BlockHeight)
 	err := siabin.Unmarshal(bh.Get(BlockHeight), &height)
 	if err != nil {
@@ -124,7 +164,7 @@ func blockHeight(tx *bolt.Tx) (height types.BlockHeight) {
 }
 
 // blockTimeStamp returns the timestamp of the block on the given height.
-func blockTimeStamp(tx *bolt.Tx, height types.BlockHeight) (types.Timestamp, error) {
+func blockTimeStamp(tx kv.Tx, height types.BlockHeight) (types.Timestamp, error) {
 	id, err := getPath(tx, height)
 	if err != nil {
 		return 0, err
@@ -137,7 +177,7 @@ func blockTimeStamp(tx *bolt.Tx, height types.BlockHeight) (types.Timestamp, err
 }
 
 // currentBlockID returns the id of the most recent block in the consensus set.
-func currentBlockID(tx *bolt.Tx) types.BlockID {
+func currentBlockID(tx kv.Tx) types.BlockID {
 	id, err := getPath(tx, blockHeight(tx))
 	if err != nil {
 		build.Severe(err)
@@ -146,7 +186,7 @@ func currentBlockID(tx *bolt.Tx) types.BlockID {
 }
 
 // currentProcessedBlock returns the most recent block in the consensus set.
-func currentProcessedBlock(tx *bolt.Tx) *processedBlock {
+func currentProcessedBlock(tx kv.Tx) *processedBlock {
 	pb, err := getBlockMap(tx, currentBlockID(tx))
 	if err != nil {
 		build.Severe(err)
@@ -155,7 +195,7 @@ func currentProcessedBlock(tx *bolt.Tx) *processedBlock {
 }
 
 // getBlockMap returns a processed block with the input id.
-func getBlockMap(tx *bolt.Tx, id types.BlockID) (*processedBlock, error) {
+func getBlockMap(tx kv.Tx, id types.BlockID) (*processedBlock, error) {
 	// Look up the encoded block.
 	pbBytes := tx.Bucket(BlockMap).Get(id[:])
 	if pbBytes == nil {
@@ -172,7 +212,7 @@ func getBlockMap(tx *bolt.Tx, id types.BlockID) (*processedBlock, error) {
 }
 
 // addBlockMap adds a processed block to the block map.
-func addBlockMap(tx *bolt.Tx, pb *processedBlock) {
+func addBlockMap(tx kv.Tx, pb *processedBlock) {
 	id := pb.Block.ID()
 	pbBytes, err := siabin.Marshal(*pb)
 	if err != nil {
@@ -184,8 +224,58 @@ func addBlockMap(tx *bolt.Tx, pb *processedBlock) {
 	}
 }
 
+// blockHeaderEntry is the value of a block in the BlockHeaders bucket.
+type blockHeaderEntry struct {
+	Header types.BlockHeader
+	Height types.BlockHeight
+}
+
+// getBlockHeader returns the header of the block with the input id,
+// looking first in the block map and then in the block headers.
+func getBlockHeader(tx kv.Tx, id types.BlockID) (types.BlockHeader, error) {
+	header, _, err := getBlockHeaderHeight(tx, id)
+	return header, err
+}
+
+// getBlockHeaderHeight returns the header and height of the block with the input id,
+// looking first in the block map and then in the block headers.
+func getBlockHeaderHeight(tx kv.Tx, id types.BlockID) (types.BlockHeader, types.BlockHeight, error) {
+	pb, err := getBlockMap(tx, id)
+	if err == nil {
+		return pb.Block.Header(), pb.Height, nil
+	}
+	bucket := tx.Bucket(BlockHeaders)
+	if bucket == nil {
+		return types.BlockHeader{}, 0, errNilItem
+	}
+	entryBytes := bucket.Get(id[:])
+	if entryBytes == nil {
+		return types.BlockHeader{}, 0, errNilItem
+	}
+	var entry blockHeaderEntry
+	err = siabin.Unmarshal(entryBytes, &entry)
+	if err != nil {
+		build.Severe(err)
+	}
+	return entry.Header, entry.Height, nil
+}
+
+// addBlockHeader adds the header of a block, of which no processed block is kept, to the block headers.
+func addBlockHeader(tx kv.Tx, header types.BlockHeader, height types.BlockHeight) error {
+	bucket, err := tx.CreateBucketIfNotExists(BlockHeaders)
+	if err != nil {
+		return err
+	}
+	entryBytes, err := siabin.Marshal(blockHeaderEntry{Header: header, Height: height})
+	if err != nil {
+		return fmt.Errorf("failed to (siabin) marshal block header: %v", err)
+	}
+	id := header.ID()
+	return bucket.Put(id[:], entryBytes)
+}
+
 // getPath returns the block id at 'height' in the block path.
-func getPath(tx *bolt.Tx, height types.BlockHeight) (id types.BlockID, err error) {
+func getPath(tx kv.Tx, height types.BlockHeight) (id types.BlockID, err error) {
 	heightBytes, err := siabin.Marshal(height)
 	if err != nil {
 		return types.BlockID{}, fmt.Errorf("failed to (siabin) Marshal block height: %v", err)
@@ -203,7 +293,7 @@ func getPath(tx *bolt.Tx, height types.BlockHeight) (id types.BlockID, err error
 }
 
 // pushPath adds a block to the BlockPath at current height + 1.
-func pushPath(tx *bolt.Tx, bid types.BlockID) {
+func pushPath(tx kv.Tx, bid types.BlockID) {
 	// Fetch and update the block height.
 	bh := tx.Bucket(BlockHeight)
 	heightBytes := bh.Get(BlockHeight)
@@ -231,7 +321,7 @@ func pushPath(tx *bolt.Tx, bid types.BlockID) {
 
 // popPath removes a block from the "end" of the chain, i.e. the block
 // with the largest height.
-func popPath(tx *bolt.Tx) {
+func popPath(tx kv.Tx) {
 	// Fetch and update the block height.
 	bh := tx.Bucket(BlockHeight)
 	oldHeightBytes := bh.Get(BlockHeight)
@@ -260,7 +350,7 @@ func popPath(tx *bolt.Tx) {
 
 // isCoinOutput returns true if there is a coin output of that id in the
 // database.
-func isCoinOutput(tx *bolt.Tx, id types.CoinOutputID) bool {
+func isCoinOutput(tx kv.Tx, id types.CoinOutputID) bool {
 	bucket := tx.Bucket(CoinOutputs)
 	sco := bucket.Get(id[:])
 	return sco != nil
@@ -268,7 +358,7 @@ func isCoinOutput(tx *bolt.Tx, id types.CoinOutputID) bool {
 
 // getCoinOutput fetches a coin output from the database. An error is
 // returned if the siacoin output does not exist.
-func getCoinOutput(tx *bolt.Tx, id types.CoinOutputID) (types.CoinOutput, error) {
+func getCoinOutput(tx kv.Tx, id types.CoinOutputID) (types.CoinOutput, error) {
 	scoBytes := tx.Bucket(CoinOutputs).Get(id[:])
 	if scoBytes == nil {
 		return types.CoinOutput{}, errNilItem
@@ -283,7 +373,7 @@ func getCoinOutput(tx *bolt.Tx, id types.CoinOutputID) (types.CoinOutput, error)
 
 // addCoinOutput adds a coin output to the database. An error is returned
 // if the coin output is already in the database.
-func addCoinOutput(tx *bolt.Tx, id types.CoinOutputID, sco types.CoinOutput) {
+func addCoinOutput(tx kv.Tx, id types.CoinOutputID, sco types.CoinOutput) {
 	// While this is not supposed to be allowed, there's a bug in the consensus
 	// code which means that earlier versions have accetped 0-value outputs
 	// onto the blockchain. A hardfork to remove 0-value outputs will fix this,
@@ -310,7 +400,7 @@ func addCoinOutput(tx *bolt.Tx, id types.CoinOutputID, sco types.CoinOutput) {
 
 // removeCoinOutput removes a coin output from the database. An error is
 // returned if the coin output is not in the database prior to removal.
-func removeCoinOutput(tx *bolt.Tx, id types.CoinOutputID) {
+func removeCoinOutput(tx kv.Tx, id types.CoinOutputID) {
 	scoBucket := tx.Bucket(CoinOutputs)
 	// Sanity check - should not be removing an item that is not in the db.
 	if scoBucket.Get(id[:]) == nil {
@@ -324,7 +414,7 @@ func removeCoinOutput(tx *bolt.Tx, id types.CoinOutputID) {
 
 // getBlockStakeOutput fetches a blockstake output from the database. An error is
 // returned if the blockstake output does not exist.
-func getBlockStakeOutput(tx *bolt.Tx, id types.BlockStakeOutputID) (types.BlockStakeOutput, error) {
+func getBlockStakeOutput(tx kv.Tx, id types.BlockStakeOutputID) (types.BlockStakeOutput, error) {
 	sfoBytes := tx.Bucket(BlockStakeOutputs).Get(id[:])
 	if sfoBytes == nil {
 		return types.BlockStakeOutput{}, errNilItem
@@ -339,7 +429,7 @@ func getBlockStakeOutput(tx *bolt.Tx, id types.BlockStakeOutputID) (types.BlockS
 
 // addBlockStakeOutput adds a blockstake output to the database. An error is returned
 // if the blockstake output is already in the database.
-func addBlockStakeOutput(tx *bolt.Tx, id types.BlockStakeOutputID, sfo types.BlockStakeOutput) {
+func addBlockStakeOutput(tx kv.Tx, id types.BlockStakeOutputID, sfo types.BlockStakeOutput) {
 	blockstakeOutputs := tx.Bucket(BlockStakeOutputs)
 	// Sanity check - should not be adding a blockstake output with a value of
 	// zero.
@@ -362,7 +452,7 @@ func addBlockStakeOutput(tx *bolt.Tx, id types.BlockStakeOutputID, sfo types.Blo
 
 // removeBlockStakeOutput removes a blockstake output from the database. An error is
 // returned if the blockstake output is not in the database prior to removal.
-func removeBlockStakeOutput(tx *bolt.Tx, id types.BlockStakeOutputID) {
+func removeBlockStakeOutput(tx kv.Tx, id types.BlockStakeOutputID) {
 	sfoBucket := tx.Bucket(BlockStakeOutputs)
 	if sfoBucket.Get(id[:]) == nil {
 		build.Severe("nil blockstake output")
@@ -373,8 +463,160 @@ func removeBlockStakeOutput(tx *bolt.Tx, id types.BlockStakeOutputID) {
 	}
 }
 
+// outputHeightBuckets are the buckets which store the heights at which the outputs were confirmed.
+var outputHeightBuckets = [][]byte{
+	CoinOutputHeights,
+	BlockStakeOutputHeights,
+	SpentCoinOutputHeights,
+	SpentBlockStakeOutputHeights,
+}
+
+// createOutputHeightBuckets creates the buckets which store the heights at which
+// the outputs were confirmed.
+func createOutputHeightBuckets(tx kv.Tx) error {
+	for _, bucket := range outputHeightBuckets {
+		_, err := tx.CreateBucket(bucket)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreOutputHeights creates and fills the buckets which store the heights at which the outputs
+// were confirmed, for a database created prior to (some of) these buckets. The heights are restored
+// by applying the output diffs of all blocks in the current path, which is only possible
+// if none of these blocks have been pruned.
+func restoreOutputHeights(tx kv.Tx) error {
+	if tx.Bucket(SpentCoinOutputHeights) != nil {
+		return nil
+	}
+	// remove any incomplete output height buckets
+	for _, bucket := range outputHeightBuckets {
+		if tx.Bucket(bucket) == nil {
+			continue
+		}
+		err := tx.DeleteBucket(bucket)
+		if err != nil {
+			return err
+		}
+	}
+	err := createOutputHeightBuckets(tx)
+	if err != nil {
+		return err
+	}
+	height := blockHeight(tx)
+	for h := types.BlockHeight(0); h <= height; h++ {
+		pb, err := getPathBlock(tx, h)
+		if err == errNilItem {
+			return errOutputHeightsResync
+		}
+		if err != nil {
+			return err
+		}
+		for _, scod := range pb.CoinOutputDiffs {
+			commitOutputHeightDiff(tx, CoinOutputHeights, SpentCoinOutputHeights, scod.ID[:], scod.Direction, modules.DiffApply, h)
+		}
+		for _, sfod := range pb.BlockStakeOutputDiffs {
+			commitOutputHeightDiff(tx, BlockStakeOutputHeights, SpentBlockStakeOutputHeights, sfod.ID[:], sfod.Direction, modules.DiffApply, h)
+		}
+	}
+	return nil
+}
+
+// getOutputHeight returns the height at which the unspent output, identified by the given ID,
+// was confirmed, using the given (output height) bucket.
+func getOutputHeight(tx kv.Tx, bucket []byte, id []byte) (types.BlockHeight, error) {
+	heightBytes := tx.Bucket(bucket).Get(id)
+	if heightBytes == nil {
+		return 0, errMissingOutputHeight
+	}
+	var height types.BlockHeight
+	err := siabin.Unmarshal(heightBytes, &height)
+	if err != nil {
+		return 0, fmt.Errorf("failed to (siabin) unmarshal output height: %v", err)
+	}
+	return height, nil
+}
+
+// commitOutputHeightDiff applies or reverts the effect of an output diff of the block at the given height,
+// on the given (output height) bucket and (spent output height) bucket.
+// Creating an output stores the height at which it was confirmed, while spending an output
+// moves its entry to the nested bucket of the spending block, such that it can be moved back
+// when that block is reverted.
+func commitOutputHeightDiff(tx kv.Tx, bucket, spentBucket []byte, id []byte, diffDir, dir modules.DiffDirection, height types.BlockHeight) {
+	heightKey, err := siabin.Marshal(height)
+	if err != nil {
+		build.Severe(err)
+	}
+	heights := tx.Bucket(bucket)
+	if diffDir == modules.DiffApply {
+		// the output is created by the block
+		if dir == modules.DiffApply {
+			err = heights.Put(id, heightKey)
+		} else {
+			err = heights.Delete(id)
+		}
+		if err != nil {
+			build.Severe(err)
+		}
+		return
+	}
+
+	// the output is spent by the block
+	spent, err := tx.Bucket(spentBucket).CreateBucketIfNotExists(heightKey)
+	if err != nil {
+		build.Severe(err)
+	}
+	from, to := heights, spent
+	if dir == modules.DiffRevert {
+		from, to = spent, heights
+	}
+	heightBytes := from.Get(id)
+	if heightBytes == nil {
+		build.Severe(errMissingOutputHeight)
+		return
+	}
+	err = to.Put(id, append([]byte(nil), heightBytes...))
+	if err != nil {
+		build.Severe(err)
+	}
+	err = from.Delete(id)
+	if err != nil {
+		build.Severe(err)
+	}
+}
+
+// pruneSpentOutputHeights deletes the heights of the outputs spent by the block at the given height,
+// as these are no longer required once that block can no longer be reverted.
+func pruneSpentOutputHeights(tx kv.Tx, height types.BlockHeight) error {
+	heightKey, err := siabin.Marshal(height)
+	if err != nil {
+		return fmt.Errorf("failed to (siabin) marshal block height: %v", err)
+	}
+	for _, bucket := range [][]byte{SpentCoinOutputHeights, SpentBlockStakeOutputHeights} {
+		err = tx.Bucket(bucket).DeleteBucket(heightKey)
+		if err != nil && err != kv.ErrBucketNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+// getCoinOutputHeight returns the height of the block
+// in which the unspent coin output was added to the unspent coin outputs.
+func getCoinOutputHeight(tx kv.Tx, id types.CoinOutputID) (types.BlockHeight, error) {
+	return getOutputHeight(tx, CoinOutputHeights, id[:])
+}
+
+// getBlockStakeOutputHeight returns the height of the block
+// in which the unspent blockstake output was added to the unspent blockstake outputs.
+func getBlockStakeOutputHeight(tx kv.Tx, id types.BlockStakeOutputID) (types.BlockHeight, error) {
+	return getOutputHeight(tx, BlockStakeOutputHeights, id[:])
+}
+
 // addTxnIDMapping adds a transaction ID mapping to the database.
-func addTxnIDMapping(tx *bolt.Tx, longID types.TransactionID, shortID types.TransactionShortID) {
+func addTxnIDMapping(tx kv.Tx, longID types.TransactionID, shortID types.TransactionShortID) {
 	txIDMapBucket := tx.Bucket(TransactionIDMap)
 	// Sanity check - should not be adding an item already in the db.
 	if txIDMapBucket.Get(longID[:]) != nil {
@@ -391,7 +633,7 @@ func addTxnIDMapping(tx *bolt.Tx, longID types.TransactionID, shortID types.Tran
 }
 
 // removeTxnIDMappng removes a transaction ID mapping from the database.
-func removeTxnIDMapping(tx *bolt.Tx, longID types.TransactionID) {
+func removeTxnIDMapping(tx kv.Tx, longID types.TransactionID) {
 	txIDMapBucket := tx.Bucket(TransactionIDMap)
 	if txIDMapBucket.Get(longID[:]) == nil {
 		build.Severe("nil txID mapping")
@@ -404,7 +646,7 @@ func removeTxnIDMapping(tx *bolt.Tx, longID types.TransactionID) {
 
 // getTransactionShortID returns a transaction short ID from
 // a regular transaction ID
-func getTransactionShortID(tx *bolt.Tx, id types.TransactionID) (types.TransactionShortID, error) {
+func getTransactionShortID(tx kv.Tx, id types.TransactionID) (types.TransactionShortID, error) {
 	shortIDBytes := tx.Bucket(TransactionIDMap).Get(id[:])
 	if shortIDBytes == nil {
 		return types.TransactionShortID(0), errNilItem
@@ -415,7 +657,7 @@ func getTransactionShortID(tx *bolt.Tx, id types.TransactionID) (types.Transacti
 }
 
 // addDCO adds a delayed coin output to the consensus set.
-func addDCO(tx *bolt.Tx, bh types.BlockHeight, id types.CoinOutputID, sco types.CoinOutput) {
+func addDCO(tx kv.Tx, bh types.BlockHeight, id types.CoinOutputID, sco types.CoinOutput) {
 	// Sanity check - dco should never have a value of zero.
 	if sco.Value.IsZero() {
 		build.Severe("zero-value dco being added")
@@ -441,7 +683,7 @@ func addDCO(tx *bolt.Tx, bh types.BlockHeight, id types.CoinOutputID, sco types.
 }
 
 // removeDCO removes a delayed siacoin output from the consensus set.
-func removeDCO(tx *bolt.Tx, bh types.BlockHeight, id types.CoinOutputID) {
+func removeDCO(tx kv.Tx, bh types.BlockHeight, id types.CoinOutputID) {
 	bhb, err := siabin.Marshal(bh)
 	if err != nil {
 		build.Severe(err)
@@ -460,7 +702,7 @@ func removeDCO(tx *bolt.Tx, bh types.BlockHeight, id types.CoinOutputID) {
 
 // createDCOBucket creates a bucket for the delayed coin outputs at the
 // input height.
-func createDCOBucket(tx *bolt.Tx, bh types.BlockHeight) {
+func createDCOBucket(tx kv.Tx, bh types.BlockHeight) {
 	bhb, err := siabin.Marshal(bh)
 	if err != nil {
 		build.Severe(err)
@@ -473,7 +715,7 @@ func createDCOBucket(tx *bolt.Tx, bh types.BlockHeight) {
 }
 
 // deleteDCOBucket deletes the bucket that held a set of delayed coin outputs.
-func deleteDCOBucket(tx *bolt.Tx, bh types.BlockHeight) {
+func deleteDCOBucket(tx kv.Tx, bh types.BlockHeight) {
 	// Delete the bucket.
 	bhb, err := siabin.Marshal(bh)
 	if err != nil {
@@ -0,0 +1,153 @@
+package consensus
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestCommitOutputHeightDiff probes the confirmation height of an output,
+// which is removed when the output is spent and restored when that spend is reverted.
+func TestCommitOutputHeightDiff(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	create := modules.CoinOutputDiff{
+		Direction:  modules.DiffApply,
+		ID:         types.CoinOutputID{1},
+		CoinOutput: types.CoinOutput{Value: types.NewCurrency64(1)},
+	}
+	spend := create
+	spend.Direction = modules.DiffRevert
+
+	err = cst.cs.db.Update(func(tx kv.Tx) error {
+		commitCoinOutputDiff(tx, create, modules.DiffApply, 5)
+		if height, err := getCoinOutputHeight(tx, create.ID); err != nil || height != 5 {
+			t.Errorf("unexpected height of created output: %d (%v)", height, err)
+		}
+
+		// spending the output moves its height
+		commitCoinOutputDiff(tx, spend, modules.DiffApply, 7)
+		if _, err := getCoinOutputHeight(tx, create.ID); err != errMissingOutputHeight {
+			t.Errorf("expected %v for spent output, got: %v", errMissingOutputHeight, err)
+		}
+
+		// reverting the spend restores its height
+		commitCoinOutputDiff(tx, spend, modules.DiffRevert, 7)
+		if height, err := getCoinOutputHeight(tx, create.ID); err != nil || height != 5 {
+			t.Errorf("unexpected height of unspent output: %d (%v)", height, err)
+		}
+
+		// pruning the spending block deletes its spent heights
+		commitCoinOutputDiff(tx, spend, modules.DiffApply, 7)
+		err := pruneSpentOutputHeights(tx, 7)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(SpentCoinOutputHeights).ForEach(func(k, _ []byte) error {
+			return errors.New("unexpected spent output heights after pruning")
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRestoreOutputHeights probes the restoration of the output heights
+// of a consensus database created prior to the output height buckets.
+func TestRestoreOutputHeights(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	collect := func(tx kv.Tx) map[string][]byte {
+		heights := make(map[string][]byte)
+		for _, bucket := range [][]byte{CoinOutputHeights, BlockStakeOutputHeights} {
+			tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+				heights[string(bucket)+string(k)] = append([]byte(nil), v...)
+				return nil
+			})
+		}
+		return heights
+	}
+	deleteBuckets := func(tx kv.Tx) error {
+		for _, bucket := range outputHeightBuckets {
+			err := tx.DeleteBucket(bucket)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err = cst.cs.db.Update(func(tx kv.Tx) error {
+		expected := collect(tx)
+		if len(expected) == 0 {
+			t.Fatal("expected the genesis outputs to have a height")
+		}
+		err := deleteBuckets(tx)
+		if err != nil {
+			return err
+		}
+		err = restoreOutputHeights(tx)
+		if err != nil {
+			return err
+		}
+		heights := collect(tx)
+		if len(heights) != len(expected) {
+			t.Errorf("expected %d restored heights, got %d", len(expected), len(heights))
+		}
+		for k, v := range expected {
+			if !bytes.Equal(heights[k], v) {
+				t.Errorf("unexpected restored height for %x: %x != %x", k, heights[k], v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the heights can't be restored if a block is unavailable
+	errRollback := errors.New("rollback")
+	err = cst.cs.db.Update(func(tx kv.Tx) error {
+		err := deleteBuckets(tx)
+		if err != nil {
+			return err
+		}
+		id := cst.cs.blockRoot.Block.ID()
+		err = tx.Bucket(BlockMap).Delete(id[:])
+		if err != nil {
+			return err
+		}
+		err = restoreOutputHeights(tx)
+		if err != errOutputHeightsResync {
+			t.Errorf("expected %v, got: %v", errOutputHeightsResync, err)
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatal(err)
+	}
+}
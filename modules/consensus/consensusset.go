@@ -14,18 +14,20 @@ import (
 	gosync "sync"
 	"time"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/sync"
 	"github.com/threefoldtech/rivine/types"
 
 	"github.com/NebulousLabs/demotemutex"
+	bolt "github.com/rivine/bbolt"
 )
 
 var (
-	errNilGateway = errors.New("cannot have a nil gateway as input")
+	errNilGateway           = errors.New("cannot have a nil gateway as input")
+	errDatabaseStatsBackend = errors.New("database stats can only be collected when using the bolt storage backend")
 )
 
 // marshaler marshals objects into byte slices and unmarshals byte
@@ -103,17 +105,43 @@ type ConsensusSet struct {
 	// bootstrap is a bool indicating wether we should do an IBD
 	bootstrap bool
 
+	// snapshotHeight and snapshotBlockID identify the block of the snapshot
+	// the consensus set was bootstrapped from, they are zero otherwise.
+	// Blocks prior to the snapshot block are not available,
+	// and the snapshot block itself can never be reverted.
+	snapshotHeight  types.BlockHeight
+	snapshotBlockID types.BlockID
+
+	// pruneDepth is the amount of most recent blocks for which the processed
+	// block is kept, it is zero unless the pruned mode is enabled.
+	// Blocks of the current path below prunedHeight might have been pruned.
+	pruneDepth   types.BlockHeight
+	prunedHeight types.BlockHeight
+
+	// checkpoints map block heights to the blocks required at those heights,
+	// and maxReorgDepth is the maximum amount of blocks a reorg can revert (0 if unlimited).
+	// Forks which conflict with either are refused, and remembered in refusedForks.
+	checkpoints   map[types.BlockHeight]types.BlockID
+	maxReorgDepth types.BlockHeight
+	refusedForks  []modules.RefusedFork
+
 	// synced is true if initial blockchain download has finished. It indicates
 	// whether the consensus set is synced with the network.
 	synced bool
 
+	// syncProgress keeps track of the progress of the headers-first initial blockchain download,
+	// it is protected by its own mutex, as to not block on the consensus set lock.
+	syncProgress   modules.ConsensusSyncProgress
+	syncProgressMu gosync.RWMutex
+
 	// Interfaces to abstract the dependencies of the ConsensusSet.
 	marshaler       marshaler
 	blockRuleHelper blockRuleHelper
 	blockValidator  blockValidator
 
 	// Utilities
-	db         *persist.BoltDatabase
+	db         *persist.Database
+	dbBackend  kv.Backend
 	log        *persist.Logger
 	mu         demotemutex.DemoteMutex
 	persistDir string
@@ -128,12 +156,21 @@ type ConsensusSet struct {
 
 // New returns a new ConsensusSet, containing at least the genesis block. If
 // there is an existing block database present in the persist directory, it
-// will be loaded.
+// will be loaded. The database is stored using the default (bolt) storage backend.
 func New(gateway modules.Gateway, bootstrap bool, persistDir string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, verboseLogging bool, dbDebugFile string) (*ConsensusSet, error) {
+	return NewWithBackend(gateway, bootstrap, persistDir, bcInfo, chainCts, verboseLogging, dbDebugFile, kv.BackendBolt)
+}
+
+// NewWithBackend returns a new ConsensusSet, the same way as New does,
+// storing its database using the given storage backend.
+func NewWithBackend(gateway modules.Gateway, bootstrap bool, persistDir string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, verboseLogging bool, dbDebugFile string, dbBackend kv.Backend) (*ConsensusSet, error) {
 	// Check for nil dependencies.
 	if gateway == nil {
 		return nil, errNilGateway
 	}
+	if dbDebugFile != "" && dbBackend != kv.BackendBolt {
+		return nil, errDatabaseStatsBackend
+	}
 
 	genesisBlock := chainCts.GenesisBlock()
 	// Create the ConsensusSet object.
@@ -161,6 +198,7 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string, bcInfo type
 		blockRuleHelper: newStdBlockRuleHelper(chainCts),
 
 		persistDir: persistDir,
+		dbBackend:  dbBackend,
 
 		bcInfo:                 bcInfo,
 		chainCts:               chainCts,
@@ -208,6 +246,7 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string, bcInfo type
 
 		go func() {
 			var previousStats, currentStats bolt.Stats
+			boltDB := kv.Bolt(cs.db.DB)
 
 			if err := cs.tg.Add(); err != nil {
 				// tg already closed
@@ -224,7 +263,7 @@ func New(gateway modules.Gateway, bootstrap bool, persistDir string, bcInfo type
 					}
 					return
 				case <-time.NewTicker(time.Second).C:
-					currentStats = cs.db.Stats()
+					currentStats = boltDB.Stats()
 					if err := enc.Encode(currentStats.Sub(&previousStats)); err != nil {
 						cs.log.Println("[WARN] Failed to collect database stats: ", err)
 					}
@@ -268,11 +307,17 @@ func (cs *ConsensusSet) Start() {
 		cs.gateway.RegisterRPC("SendBlocks", cs.rpcSendBlocks)
 		cs.gateway.RegisterRPC("RelayHeader", cs.threadedRPCRelayHeader)
 		cs.gateway.RegisterRPC("SendBlk", cs.rpcSendBlk)
+		cs.gateway.RegisterRPC("SendHeaders", cs.rpcSendHeaders)
+		cs.gateway.RegisterRPC("SendBlocksByID", cs.rpcSendBlocksByID)
+		cs.gateway.RegisterRPC("SendBlockRange", cs.rpcSendBlockRange)
 		cs.gateway.RegisterConnectCall("SendBlocks", cs.threadedReceiveBlocks)
 		cs.tg.OnStop(func() {
 			cs.gateway.UnregisterRPC("SendBlocks")
 			cs.gateway.UnregisterRPC("RelayHeader")
 			cs.gateway.UnregisterRPC("SendBlk")
+			cs.gateway.UnregisterRPC("SendHeaders")
+			cs.gateway.UnregisterRPC("SendBlocksByID")
+			cs.gateway.UnregisterRPC("SendBlockRange")
 			cs.gateway.UnregisterConnectCall("SendBlocks")
 		})
 
@@ -280,13 +325,20 @@ func (cs *ConsensusSet) Start() {
 		cs.mu.Lock()
 		cs.log.Debug("Marked CS as Synced")
 		cs.synced = true
+		pruned := cs.pruneDepth != 0
 		cs.mu.Unlock()
+
+		// Prune old blocks in the background, if the pruned mode is enabled.
+		if pruned {
+			go cs.threadedPruneBlocks()
+		}
 	}()
 }
 
 // BlockAtHeight returns the block at a given height.
+// A block which has been pruned is reported as non-existing.
 func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Block, exists bool) {
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		id, err := getPath(tx, height)
 		if err != nil {
 			return err
@@ -304,7 +356,7 @@ func (cs *ConsensusSet) BlockAtHeight(height types.BlockHeight) (block types.Blo
 
 // BlockHeightOfBlock returns the blockheight given a block.
 func (cs *ConsensusSet) BlockHeightOfBlock(block types.Block) (height types.BlockHeight, exists bool) {
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		pb, err := getBlockMap(tx, block.ID())
 		if err != nil {
 			return err
@@ -331,7 +383,7 @@ func (cs *ConsensusSet) FindParentBlock(b types.Block, depth types.BlockHeight)
 		return
 	}
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		parent, err = getBlockMap(tx, ph)
 		if err != nil {
 			return err
@@ -354,7 +406,7 @@ func (cs *ConsensusSet) FindParentBlock(b types.Block, depth types.BlockHeight)
 // will fail. Specifically, when this function is used for validation, the parent ID
 // of the block being validated should be used, and depth adjusted accordingly
 func (cs *ConsensusSet) FindParentHash(h types.BlockID, depth types.BlockHeight) (id types.BlockID, exists bool) {
-	var parent *processedBlock
+	var header types.BlockHeader
 	var err error
 
 	// Keep track of the current block ID
@@ -363,7 +415,7 @@ func (cs *ConsensusSet) FindParentHash(h types.BlockID, depth types.BlockHeight)
 	// This variable will fix itself in the first loop iteration
 	pID := h
 
-	err = cs.db.View(func(tx *bolt.Tx) error {
+	err = cs.db.View(func(tx kv.Tx) error {
 
 		// Count back to the right block, add 1 loop iteration to fix the parent
 		// ID not being present yet.
@@ -379,13 +431,13 @@ func (cs *ConsensusSet) FindParentHash(h types.BlockID, depth types.BlockHeight)
 				// Not found in cache, load from disk
 				// we previously updated cbID to point to the parent, so we can use it
 				// here instead of the now invalid pID
-				parent, err = getBlockMap(tx, cbID)
+				header, err = getBlockHeader(tx, cbID)
 				if err != nil {
 					return err
 				}
 
 				// save parentID for later use
-				pID = parent.Block.Header().ParentID
+				pID = header.ParentID
 				cs.knownParentIDs[cbID] = pID
 
 			}
@@ -428,7 +480,7 @@ func (cs *ConsensusSet) TransactionAtShortID(shortID types.TransactionShortID) (
 func (cs *ConsensusSet) TransactionAtID(id types.TransactionID) (types.Transaction, types.TransactionShortID, bool) {
 	var txnShortID types.TransactionShortID
 	var exists bool
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		shortID, err := getTransactionShortID(tx, id)
 		if err != nil {
 			return err
@@ -450,7 +502,7 @@ func (cs *ConsensusSet) ChildTarget(id types.BlockID) (target types.Target, exis
 	}
 	defer cs.tg.Done()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			return err
@@ -476,7 +528,7 @@ func (cs *ConsensusSet) managedCurrentBlock() (block types.Block) {
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		pb := currentProcessedBlock(tx)
 		block = pb.Block
 		return nil
@@ -494,7 +546,7 @@ func (cs *ConsensusSet) CurrentBlock() (block types.Block) {
 	defer cs.tg.Done()
 	cs.mu.RLock()
 	defer cs.mu.RUnlock()
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		pb := currentProcessedBlock(tx)
 		block = pb.Block
 		return nil
@@ -517,7 +569,7 @@ func (cs *ConsensusSet) Height() (height types.BlockHeight) {
 	}
 	defer cs.tg.Done()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		height = blockHeight(tx)
 		return nil
 	})
@@ -534,7 +586,7 @@ func (cs *ConsensusSet) InCurrentPath(id types.BlockID) (inPath bool) {
 	}
 	defer cs.tg.Done()
 
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			inPath = false
@@ -562,7 +614,7 @@ func (cs *ConsensusSet) MinimumValidChildTimestamp(id types.BlockID) (timestamp
 	defer cs.tg.Done()
 
 	// Error is not checked because it does not matter.
-	_ = cs.db.View(func(tx *bolt.Tx) error {
+	_ = cs.db.View(func(tx kv.Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
 			return err
@@ -4,15 +4,15 @@ import (
 	"bytes"
 	"errors"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
 
 // manageErr handles an error detected by the consistency checks.
-func manageErr(tx *bolt.Tx, err error) {
+func manageErr(tx kv.Tx, err error) {
 	markInconsistency(tx)
 	build.Severe(err)
 }
@@ -21,13 +21,13 @@ func manageErr(tx *bolt.Tx, err error) {
 // the elements in sorted order into a merkle tree and taking the root. All
 // consensus sets with the same current block should have identical consensus
 // checksums.
-func consensusChecksum(tx *bolt.Tx) crypto.Hash {
+func consensusChecksum(tx kv.Tx) crypto.Hash {
 	// Create a checksum tree.
 	tree := crypto.NewTree()
 
 	// For all of the constant buckets, push every key and every value. Buckets
 	// are sorted in byte-order, therefore this operation is deterministic.
-	consensusSetBuckets := []*bolt.Bucket{
+	consensusSetBuckets := []kv.Bucket{
 		tx.Bucket(BlockPath),
 		tx.Bucket(CoinOutputs),
 		tx.Bucket(BlockStakeOutputs),
@@ -46,7 +46,7 @@ func consensusChecksum(tx *bolt.Tx) crypto.Hash {
 	// Iterate through all the buckets looking for buckets prefixed with
 	// prefixDCO. Buckets are presented in byte-sorted order by
 	// name.
-	err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+	err := tx.ForEach(func(name []byte, b kv.Bucket) error {
 		if !bytes.HasPrefix(name, prefixDCO) {
 			return nil
 		}
@@ -66,7 +66,7 @@ func consensusChecksum(tx *bolt.Tx) crypto.Hash {
 
 // checkBlockStakeCount checks that the number of siafunds countable within the
 // consensus set equal the expected number of BlockStakeOutputs for the block height.
-func (cs *ConsensusSet) checkBlockStakeCount(tx *bolt.Tx) {
+func (cs *ConsensusSet) checkBlockStakeCount(tx kv.Tx) {
 	var total types.Currency
 	err := tx.Bucket(BlockStakeOutputs).ForEach(func(_, siafundOutputBytes []byte) error {
 		var sfo types.BlockStakeOutput
@@ -89,10 +89,11 @@ func (cs *ConsensusSet) checkBlockStakeCount(tx *bolt.Tx) {
 // consensus set hash matches the hash obtained for the previous block. Then it
 // applies the block again and checks that the consensus set hash matches the
 // original consensus set hash.
-func (cs *ConsensusSet) checkRevertApply(tx *bolt.Tx) {
+func (cs *ConsensusSet) checkRevertApply(tx kv.Tx) {
 	current := currentProcessedBlock(tx)
-	// Don't perform the check if this block is the genesis block.
-	if current.Block.ID() == cs.blockRoot.Block.ID() {
+	// Don't perform the check if this block is the genesis block,
+	// or the snapshot block the consensus set was bootstrapped from.
+	if current.Block.ID() == cs.blockRoot.Block.ID() || current.Height <= cs.snapshotHeight {
 		return
 	}
 
@@ -121,7 +122,7 @@ func (cs *ConsensusSet) checkRevertApply(tx *bolt.Tx) {
 
 // checkConsistency runs a series of checks to make sure that the consensus set
 // is consistent with some rules that should always be true.
-func (cs *ConsensusSet) checkConsistency(tx *bolt.Tx) {
+func (cs *ConsensusSet) checkConsistency(tx kv.Tx) {
 	if cs.checkingConsistency {
 		return
 	}
@@ -137,7 +138,7 @@ func (cs *ConsensusSet) checkConsistency(tx *bolt.Tx) {
 // Useful for detecting database corruption in production without needing to go
 // through the extremely slow process of running a consistency check every
 // block.
-func (cs *ConsensusSet) maybeCheckConsistency(tx *bolt.Tx) {
+func (cs *ConsensusSet) maybeCheckConsistency(tx kv.Tx) {
 	n, err := crypto.RandIntn(1000)
 	if err != nil {
 		manageErr(tx, err)
@@ -8,9 +8,9 @@ import (
 	"fmt"
 	"os"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 )
 
@@ -37,16 +37,16 @@ type (
 		Bucket(name []byte) dbBucket
 	}
 
-	// boltTxWrapper wraps a bolt.Tx so that it matches the dbTx interface. The
-	// wrap is necessary because bolt.Tx.Bucket() returns a fixed type
-	// (bolt.Bucket), but we want it to return an interface (dbBucket).
-	boltTxWrapper struct {
-		tx *bolt.Tx
+	// kvTxWrapper wraps a kv.Tx so that it matches the dbTx interface. The
+	// wrap is necessary because kv.Tx.Bucket() returns a fixed type
+	// (kv.Bucket), but we want it to return an interface (dbBucket).
+	kvTxWrapper struct {
+		tx kv.Tx
 	}
 )
 
 // Bucket returns the dbBucket associated with the given bucket name.
-func (b boltTxWrapper) Bucket(name []byte) dbBucket {
+func (b kvTxWrapper) Bucket(name []byte) dbBucket {
 	return b.tx.Bucket(name)
 }
 
@@ -61,7 +61,7 @@ func (cs *ConsensusSet) replaceDatabase(filename string) error {
 
 	// Try again to create a new database, this time without checking for an
 	// outdated database error.
-	cs.db, err = persist.OpenDatabase(dbMetadata, filename)
+	cs.db, err = persist.OpenDatabaseWithBackend(dbMetadata, filename, cs.dbBackend)
 	if err != nil {
 		return errors.New("error opening consensus database: " + err.Error())
 	}
@@ -70,7 +70,7 @@ func (cs *ConsensusSet) replaceDatabase(filename string) error {
 
 // openDB loads the set database and populates it with the necessary buckets
 func (cs *ConsensusSet) openDB(filename string) (err error) {
-	cs.db, err = persist.OpenDatabase(dbMetadata, filename)
+	cs.db, err = persist.OpenDatabaseWithBackend(dbMetadata, filename, cs.dbBackend)
 	if err == persist.ErrBadVersion {
 		cs.db, err = convertLegacyDatabase(filename, cs.log)
 		if err == persist.ErrBadVersion {
@@ -87,19 +87,19 @@ func (cs *ConsensusSet) openDB(filename string) (err error) {
 // if not. Checking for the existence of the siafund pool bucket is typically
 // sufficient to determine whether the database has gone through the
 // initialization process.
-func dbInitialized(tx *bolt.Tx) bool {
+func dbInitialized(tx kv.Tx) bool {
 	return tx.Bucket(BlockStakeOutputs) != nil
 }
 
 // initDB is run if there is no existing consensus database, creating a
 // database with all the required buckets and sane initial values.
-func (cs *ConsensusSet) initDB(tx *bolt.Tx) error {
+func (cs *ConsensusSet) initDB(tx kv.Tx) error {
 	// Create the compononents of the database.
 	err := cs.createConsensusDB(tx)
 	if err != nil {
 		return err
 	}
-	err = cs.createChangeLog(tx)
+	err = createChangeLog(tx, cs.genesisEntry())
 	if err != nil {
 		return err
 	}
@@ -119,7 +119,7 @@ func (cs *ConsensusSet) initDB(tx *bolt.Tx) error {
 
 // inconsistencyDetected indicates whether inconsistency has been detected
 // within the database.
-func inconsistencyDetected(tx *bolt.Tx) (detected bool) {
+func inconsistencyDetected(tx kv.Tx) (detected bool) {
 	inconsistencyBytes := tx.Bucket(Consistency).Get(Consistency)
 	err := siabin.Unmarshal(inconsistencyBytes, &detected)
 	if err != nil {
@@ -130,7 +130,7 @@ func inconsistencyDetected(tx *bolt.Tx) (detected bool) {
 
 // markInconsistency flags the database to indicate that inconsistency has been
 // detected.
-func markInconsistency(tx *bolt.Tx) {
+func markInconsistency(tx kv.Tx) {
 	// Place a 'true' in the consistency bucket to indicate that
 	// inconsistencies have been found.
 	consBytes, err := siabin.Marshal(true)
@@ -4,9 +4,9 @@ import (
 	"errors"
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -20,7 +20,7 @@ var (
 
 // commitDiffSetSanity performs a series of sanity checks before committing a
 // diff set.
-func commitDiffSetSanity(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+func commitDiffSetSanity(tx kv.Tx, pb *processedBlock, dir modules.DiffDirection) {
 	// This function is purely sanity checks.
 	if !build.DEBUG {
 		return
@@ -49,25 +49,29 @@ func commitDiffSetSanity(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirect
 }
 
 // commitCoinOutputDiff applies or reverts a SiacoinOutputDiff.
-func commitCoinOutputDiff(tx *bolt.Tx, scod modules.CoinOutputDiff, dir modules.DiffDirection) {
+// The height is the height of the block the diff belongs to.
+func commitCoinOutputDiff(tx kv.Tx, scod modules.CoinOutputDiff, dir modules.DiffDirection, height types.BlockHeight) {
 	if scod.Direction == dir {
 		addCoinOutput(tx, scod.ID, scod.CoinOutput)
 	} else {
 		removeCoinOutput(tx, scod.ID)
 	}
+	commitOutputHeightDiff(tx, CoinOutputHeights, SpentCoinOutputHeights, scod.ID[:], scod.Direction, dir, height)
 }
 
 // commitBlockStakeOutputDiff applies or reverts a Siafund output diff.
-func commitBlockStakeOutputDiff(tx *bolt.Tx, sfod modules.BlockStakeOutputDiff, dir modules.DiffDirection) {
+// The height is the height of the block the diff belongs to.
+func commitBlockStakeOutputDiff(tx kv.Tx, sfod modules.BlockStakeOutputDiff, dir modules.DiffDirection, height types.BlockHeight) {
 	if sfod.Direction == dir {
 		addBlockStakeOutput(tx, sfod.ID, sfod.BlockStakeOutput)
 	} else {
 		removeBlockStakeOutput(tx, sfod.ID)
 	}
+	commitOutputHeightDiff(tx, BlockStakeOutputHeights, SpentBlockStakeOutputHeights, sfod.ID[:], sfod.Direction, dir, height)
 }
 
 // commitTxIDMapDiff applies or reverts a transaction ID mapping diff
-func commitTxIDMapDiff(tx *bolt.Tx, tidmod modules.TransactionIDDiff, dir modules.DiffDirection) {
+func commitTxIDMapDiff(tx kv.Tx, tidmod modules.TransactionIDDiff, dir modules.DiffDirection) {
 	if tidmod.Direction == dir {
 		addTxnIDMapping(tx, tidmod.LongID, tidmod.ShortID)
 	} else {
@@ -76,7 +80,7 @@ func commitTxIDMapDiff(tx *bolt.Tx, tidmod modules.TransactionIDDiff, dir module
 }
 
 // commitDelayedCoinOutputDiff applies or reverts a delayedCoinOutputDiff.
-func commitDelayedCoinOutputDiff(tx *bolt.Tx, dscod modules.DelayedCoinOutputDiff, dir modules.DiffDirection) {
+func commitDelayedCoinOutputDiff(tx kv.Tx, dscod modules.DelayedCoinOutputDiff, dir modules.DiffDirection) {
 	if dscod.Direction == dir {
 		addDCO(tx, dscod.MaturityHeight, dscod.ID, dscod.CoinOutput)
 	} else {
@@ -85,13 +89,13 @@ func commitDelayedCoinOutputDiff(tx *bolt.Tx, dscod modules.DelayedCoinOutputDif
 }
 
 // commitNodeDiffs commits all of the diffs in a block node.
-func commitNodeDiffs(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+func commitNodeDiffs(tx kv.Tx, pb *processedBlock, dir modules.DiffDirection) {
 	if dir == modules.DiffApply {
 		for _, scod := range pb.CoinOutputDiffs {
-			commitCoinOutputDiff(tx, scod, dir)
+			commitCoinOutputDiff(tx, scod, dir, pb.Height)
 		}
 		for _, sfod := range pb.BlockStakeOutputDiffs {
-			commitBlockStakeOutputDiff(tx, sfod, dir)
+			commitBlockStakeOutputDiff(tx, sfod, dir, pb.Height)
 		}
 		for _, dscod := range pb.DelayedCoinOutputDiffs {
 			commitDelayedCoinOutputDiff(tx, dscod, dir)
@@ -101,10 +105,10 @@ func commitNodeDiffs(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection)
 		}
 	} else {
 		for i := len(pb.CoinOutputDiffs) - 1; i >= 0; i-- {
-			commitCoinOutputDiff(tx, pb.CoinOutputDiffs[i], dir)
+			commitCoinOutputDiff(tx, pb.CoinOutputDiffs[i], dir, pb.Height)
 		}
 		for i := len(pb.BlockStakeOutputDiffs) - 1; i >= 0; i-- {
-			commitBlockStakeOutputDiff(tx, pb.BlockStakeOutputDiffs[i], dir)
+			commitBlockStakeOutputDiff(tx, pb.BlockStakeOutputDiffs[i], dir, pb.Height)
 		}
 		for i := len(pb.DelayedCoinOutputDiffs) - 1; i >= 0; i-- {
 			commitDelayedCoinOutputDiff(tx, pb.DelayedCoinOutputDiffs[i], dir)
@@ -116,7 +120,7 @@ func commitNodeDiffs(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection)
 }
 
 // updateCurrentPath updates the current path after applying a diff set.
-func updateCurrentPath(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+func updateCurrentPath(tx kv.Tx, pb *processedBlock, dir modules.DiffDirection) {
 	// Update the current path.
 	if dir == modules.DiffApply {
 		pushPath(tx, pb.Block.ID())
@@ -126,7 +130,7 @@ func updateCurrentPath(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirectio
 }
 
 // commitDiffSet applies or reverts the diffs in a blockNode.
-func commitDiffSet(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
+func commitDiffSet(tx kv.Tx, pb *processedBlock, dir modules.DiffDirection) {
 	// Sanity checks - there are a few so they were moved to another function.
 	if build.DEBUG {
 		commitDiffSetSanity(tx, pb, dir)
@@ -141,7 +145,7 @@ func commitDiffSet(tx *bolt.Tx, pb *processedBlock, dir modules.DiffDirection) {
 // transactions are allowed to depend on each other. We can't be sure that a
 // transaction is valid unless we have applied all of the previous transactions
 // in the block, which means we need to apply while we verify.
-func (cs *ConsensusSet) generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) error {
+func (cs *ConsensusSet) generateAndApplyDiff(tx kv.Tx, pb *processedBlock) error {
 	// Sanity check - the block being applied should have the current block as
 	// a parent.
 	if pb.Block.ParentID != currentBlockID(tx) {
@@ -164,6 +168,9 @@ func (cs *ConsensusSet) generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) er
 			SequenceID:             uint16(idx),
 			SpentCoinOutputs:       make(map[types.CoinOutputID]types.CoinOutput),
 			SpentBlockStakeOutputs: make(map[types.BlockStakeOutputID]types.BlockStakeOutput),
+
+			SpentCoinOutputHeights:       make(map[types.CoinOutputID]types.BlockHeight),
+			SpentBlockStakeOutputHeights: make(map[types.BlockStakeOutputID]types.BlockHeight),
 		}
 		var err error
 		for _, ci := range txn.CoinInputs {
@@ -171,14 +178,29 @@ func (cs *ConsensusSet) generateAndApplyDiff(tx *bolt.Tx, pb *processedBlock) er
 			if err != nil {
 				return fmt.Errorf("failed to find coin input %s as unspent coin output in current consensus state: %v", ci.ParentID.String(), err)
 			}
+			cTxn.SpentCoinOutputHeights[ci.ParentID], err = getCoinOutputHeight(tx, ci.ParentID)
+			if err != nil {
+				return fmt.Errorf("failed to find the confirmation height of coin input %s: %v", ci.ParentID.String(), err)
+			}
 		}
 		for _, bsi := range txn.BlockStakeInputs {
 			cTxn.SpentBlockStakeOutputs[bsi.ParentID], err = getBlockStakeOutput(tx, bsi.ParentID)
 			if err != nil {
 				return fmt.Errorf("failed to find block stake input %s as unspent block stake output in current consensus state: %v", bsi.ParentID.String(), err)
 			}
+			cTxn.SpentBlockStakeOutputHeights[bsi.ParentID], err = getBlockStakeOutputHeight(tx, bsi.ParentID)
+			if err != nil {
+				return fmt.Errorf("failed to find the confirmation height of block stake input %s: %v", bsi.ParentID.String(), err)
+			}
 		}
 
+		// ensure the transaction version and all of its unlock condition and fulfillment types are active
+		err = cs.chainCts.ActivationSchedule.ValidateTransaction(txn, pb.Height)
+		if err != nil {
+			cs.log.Printf("WARN: block %v cannot be applied: tx %v is not yet activated: %v",
+				pb.Block.ID(), txn.ID(), err)
+			return err
+		}
 		err = cs.validTransaction(tx, cTxn, types.TransactionValidationConstants{
 			BlockSizeLimit:         cs.chainCts.BlockSizeLimit,
 			ArbitraryDataSizeLimit: cs.chainCts.ArbitraryDataSizeLimit,
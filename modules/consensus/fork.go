@@ -3,9 +3,9 @@ package consensus
 import (
 	"errors"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/types"
 )
 
@@ -16,8 +16,9 @@ var (
 // backtrackToCurrentPath traces backwards from 'pb' until it reaches a block
 // in the ConsensusSet's current path (the "common parent"). It returns the
 // (inclusive) set of blocks between the common parent and 'pb', starting from
-// the former.
-func backtrackToCurrentPath(tx *bolt.Tx, pb *processedBlock) []*processedBlock {
+// the former. An error is returned if the common parent can not be reached,
+// as the processed blocks leading to it have been pruned.
+func backtrackToCurrentPath(tx kv.Tx, pb *processedBlock) ([]*processedBlock, error) {
 	path := []*processedBlock{pb}
 	for {
 		// Error is not checked in production code - an error can only indicate
@@ -34,19 +35,23 @@ func backtrackToCurrentPath(tx *bolt.Tx, pb *processedBlock) []*processedBlock {
 
 		// Prepend the next block to the list of blocks leading from the
 		// current path to the input block.
-		pb, err = getBlockMap(tx, pb.Block.ParentID)
+		parentID := pb.Block.ParentID
+		pb, err = getBlockMap(tx, parentID)
 		if err != nil {
+			if isBlockPruned(tx, parentID) {
+				return nil, errPrunedFork
+			}
 			build.Severe(err)
 		}
 		path = append([]*processedBlock{pb}, path...)
 	}
-	return path
+	return path, nil
 }
 
 // revertToBlock will revert blocks from the ConsensusSet's current path until
 // 'pb' is the current block. Blocks are returned in the order that they were
 // reverted.  'pb' is not reverted.
-func (cs *ConsensusSet) revertToBlock(tx *bolt.Tx, pb *processedBlock) (revertedBlocks []*processedBlock) {
+func (cs *ConsensusSet) revertToBlock(tx kv.Tx, pb *processedBlock) (revertedBlocks []*processedBlock) {
 	// Sanity check - make sure that pb is in the current path.
 	currentPathID, err := getPath(tx, pb.Height)
 	if err != nil || currentPathID != pb.Block.ID() {
@@ -75,9 +80,12 @@ func (cs *ConsensusSet) revertToBlock(tx *bolt.Tx, pb *processedBlock) (reverted
 
 // applyUntilBlock will successively apply the blocks between the consensus
 // set's current path and 'pb'.
-func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (appliedBlocks []*processedBlock, err error) {
+func (cs *ConsensusSet) applyUntilBlock(tx kv.Tx, pb *processedBlock) (appliedBlocks []*processedBlock, err error) {
 	// Backtrack to the common parent of 'bn' and current path and then apply the new blocks.
-	newPath := backtrackToCurrentPath(tx, pb)
+	newPath, err := backtrackToCurrentPath(tx, pb)
+	if err != nil {
+		return nil, err
+	}
 	for _, block := range newPath[1:] {
 		// If the diffs for this block have already been generated, apply diffs
 		// directly instead of generating them. This is much faster.
@@ -108,7 +116,7 @@ func (cs *ConsensusSet) applyUntilBlock(tx *bolt.Tx, pb *processedBlock) (applie
 }
 
 // rewindBlock rewinds a single block from the consensus set. This method assumes that pb is the current top op the chain, i.e. the active fork
-func (cs *ConsensusSet) rewindBlock(tx *bolt.Tx, pb *processedBlock) error {
+func (cs *ConsensusSet) rewindBlock(tx kv.Tx, pb *processedBlock) error {
 	cs.log.Debugf("[CS] rewinding block %d\n", pb.Height)
 	createDCOBucket(tx, pb.Height)
 	commitDiffSet(tx, pb, modules.DiffRevert)
@@ -116,7 +124,7 @@ func (cs *ConsensusSet) rewindBlock(tx *bolt.Tx, pb *processedBlock) error {
 	return cs.rewindBlockForPlugins(tx, pb)
 }
 
-func (cs *ConsensusSet) rewindBlockForPlugins(tx *bolt.Tx, pb *processedBlock) error {
+func (cs *ConsensusSet) rewindBlockForPlugins(tx kv.Tx, pb *processedBlock) error {
 	cBlock := modules.ConsensusBlock{
 		Block:                  pb.Block,
 		Height:                 pb.Height,
@@ -144,7 +152,7 @@ func (cs *ConsensusSet) rewindBlockForPlugins(tx *bolt.Tx, pb *processedBlock) e
 }
 
 // forwardBlock adds a single block to the chain. It assumes that pb is the block at "currentHeight + 1"
-func (cs *ConsensusSet) forwardBlock(tx *bolt.Tx, pb *processedBlock) error {
+func (cs *ConsensusSet) forwardBlock(tx kv.Tx, pb *processedBlock) error {
 	cs.log.Debugf("[CS] reapplying block %d\n", pb.Height)
 	createDCOBucket(tx, pb.Height+cs.chainCts.MaturityDelay)
 	commitDiffSet(tx, pb, modules.DiffApply)
@@ -152,7 +160,7 @@ func (cs *ConsensusSet) forwardBlock(tx *bolt.Tx, pb *processedBlock) error {
 	return cs.forwardBlockForPlugins(tx, pb)
 }
 
-func (cs *ConsensusSet) forwardBlockForPlugins(tx *bolt.Tx, pb *processedBlock) error {
+func (cs *ConsensusSet) forwardBlockForPlugins(tx kv.Tx, pb *processedBlock) error {
 	cBlock := modules.ConsensusBlock{
 		Block:                  pb.Block,
 		Height:                 pb.Height,
@@ -183,8 +191,24 @@ func (cs *ConsensusSet) forwardBlockForPlugins(tx *bolt.Tx, pb *processedBlock)
 // error will be returned if any of the blocks applied in the transition are
 // found to be invalid. forkBlockchain is atomic; the ConsensusSet is only
 // updated if the function returns nil.
-func (cs *ConsensusSet) forkBlockchain(tx *bolt.Tx, newBlock *processedBlock) (revertedBlocks, appliedBlocks []*processedBlock, err error) {
-	commonParent := backtrackToCurrentPath(tx, newBlock)[0]
+func (cs *ConsensusSet) forkBlockchain(tx kv.Tx, newBlock *processedBlock) (revertedBlocks, appliedBlocks []*processedBlock, err error) {
+	path, err := backtrackToCurrentPath(tx, newBlock)
+	if err != nil {
+		return nil, nil, err
+	}
+	commonParent := path[0]
+	if commonParent.Height < cs.snapshotHeight {
+		return nil, nil, errSnapshotFork
+	}
+	if commonParent.Height < cs.prunedHeight {
+		cs.refuseFork(newBlock.Block.ID(), newBlock.Height, commonParent.Height, errPrunedFork)
+		return nil, nil, errPrunedFork
+	}
+	err = cs.validateFork(tx, commonParent)
+	if err != nil {
+		cs.refuseFork(newBlock.Block.ID(), newBlock.Height, commonParent.Height, err)
+		return nil, nil, err
+	}
 	revertedBlocks = cs.revertToBlock(tx, commonParent)
 	appliedBlocks, err = cs.applyUntilBlock(tx, newBlock)
 	if err != nil {
@@ -0,0 +1,628 @@
+package consensus
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// headers-first initial blockchain download (IBD):
+//
+// Rather than pulling full blocks serially from a single peer (the SendBlocks RPC),
+// the IBD first fetches a batch of block headers from a single peer (the SendHeaders RPC),
+// which are validated as a linked header chain on top of our current chain.
+// Once validated, the block bodies of that header chain are downloaded
+// in parallel from several outbound peers (the SendBlocksByID RPC),
+// and applied in order, using the regular block acceptance logic.
+// Prior to downloading the bodies, the header chain is validated on linkage,
+// checkpoints, DoS-listed blocks and timestamps, as well as by the (plugin) block header hooks,
+// such that an invalid header chain is refused before its bodies are downloaded.
+// Slow or misbehaving peers are dropped, and their work is redistributed
+// to the remaining peers. Peers that do not support the headers-first RPCs
+// are simply not used for it, the legacy SendBlocks IBD remains in use as a fallback.
+
+var (
+	// MaxCatchUpHeaders is the maximum number of block headers that can be
+	// requested in a single SendHeaders RPC call during the headers-first
+	// initial blockchain download.
+	MaxCatchUpHeaders = func() types.BlockHeight {
+		switch build.Release {
+		case "dev":
+			return 500
+		case "testing":
+			return 10
+		default:
+			if build.Release != "standard" {
+				build.Severe("unrecognized build.Release")
+			}
+			return 2000
+		}
+	}()
+	// maxIBDDownloadPeers is the maximum number of peers that
+	// block bodies are downloaded from in parallel during the
+	// headers-first initial blockchain download.
+	maxIBDDownloadPeers = func() int {
+		switch build.Release {
+		case "dev":
+			return 4
+		case "testing":
+			return 2
+		default:
+			if build.Release != "standard" {
+				build.Severe("unrecognized build.Release")
+			}
+			return 8
+		}
+	}()
+	// sendBlocksByIDTimeout is the timeout for a single SendBlocksByID RPC,
+	// peers which are not able to send a batch of blocks in time are dropped.
+	sendBlocksByIDTimeout = func() time.Duration {
+		switch build.Release {
+		case "dev":
+			return 20 * time.Second
+		case "testing":
+			return 3 * time.Second
+		default:
+			if build.Release != "standard" {
+				build.Severe("unrecognized build.Release")
+			}
+			return 1 * time.Minute
+		}
+	}()
+	// maxHeadersFirstFailures is the maximum number of consecutive failed
+	// headers-first rounds, prior to falling back to the legacy SendBlocks IBD.
+	maxHeadersFirstFailures = 3
+
+	errHeaderChainBroken   = errors.New("received block headers do not form a linked chain")
+	errNoDownloadPeers     = errors.New("no peers available to download block bodies from")
+	errUnexpectedBlock     = errors.New("peer sent a block that was not requested")
+	errInvalidHeaderChain  = errors.New("header chain contains an invalid block")
+	errTooManyRequestedIDs = errors.New("too many block IDs requested")
+
+	// errHeaderChainRollback is used to roll back the plugin state
+	// updated while validating a header chain
+	errHeaderChainRollback = errors.New("rollback of validated header chain")
+)
+
+type (
+	// headerChain is a validated chain of headers, which has to be extended
+	// with the block bodies, in order to be applied to the consensus set.
+	headerChain struct {
+		// IDs of the blocks of this chain, in order
+		ids []types.BlockID
+		// height of the block identified by the first ID
+		startHeight types.BlockHeight
+	}
+
+	// ibdBatchResult is the result of a single SendBlocksByID RPC
+	// as executed by a body download worker. An index of -1 indicates
+	// that the worker stopped.
+	ibdBatchResult struct {
+		index  int
+		blocks []types.Block
+		peer   modules.NetAddress
+	}
+)
+
+// SyncProgress returns the progress of the initial blockchain download.
+func (cs *ConsensusSet) SyncProgress() modules.ConsensusSyncProgress {
+	cs.syncProgressMu.RLock()
+	progress := cs.syncProgress
+	cs.syncProgressMu.RUnlock()
+	progress.BlockHeight = cs.Height()
+	if progress.PeerHeight < progress.BlockHeight {
+		progress.PeerHeight = progress.BlockHeight
+	}
+	return progress
+}
+
+// updateSyncProgress updates the progress of the initial blockchain download.
+func (cs *ConsensusSet) updateSyncProgress(update func(*modules.ConsensusSyncProgress)) {
+	cs.syncProgressMu.Lock()
+	update(&cs.syncProgress)
+	cs.syncProgressMu.Unlock()
+}
+
+// rpcSendHeaders is the receiving end of the SendHeaders RPC. It returns a
+// sequential set of block headers based on the 32 input block IDs. The most recent
+// known ID is used as the starting point, and up to 'MaxCatchUpHeaders' from
+// that BlockHeight onwards are returned, followed by the current height of the consensus set.
+func (cs *ConsensusSet) rpcSendHeaders(conn modules.PeerConn) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	// Read a list of blocks known to the requester.
+	var knownBlocks [32]types.BlockID
+	err = siabin.ReadObject(conn, &knownBlocks, 32*crypto.HashSize)
+	if err != nil {
+		return err
+	}
+
+	// Collect the headers following the most recent common block.
+	var (
+		headers  []types.BlockHeader
+		csHeight types.BlockHeight
+	)
+	cs.mu.RLock()
+	err = cs.db.View(func(tx kv.Tx) error {
+		csHeight = blockHeight(tx)
+		start, found := findCommonBlockHeight(tx, knownBlocks)
+		if !found {
+			return nil
+		}
+		for height := start; height <= csHeight && height < start+MaxCatchUpHeaders; height++ {
+			id, err := getPath(tx, height)
+			if err != nil {
+				return err
+			}
+			// headers are available for pruned blocks as well
+			header, err := getBlockHeader(tx, id)
+			if err != nil {
+				return err
+			}
+			headers = append(headers, header)
+		}
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err = siabin.WriteObject(conn, headers); err != nil {
+		return err
+	}
+	return siabin.WriteObject(conn, csHeight)
+}
+
+// rpcSendBlocksByID is the receiving end of the SendBlocksByID RPC.
+// It returns the blocks identified by the requested IDs, in the requested order.
+// Up to 'MaxCatchUpBlocks' blocks can be requested in a single call.
+func (cs *ConsensusSet) rpcSendBlocksByID(conn modules.PeerConn) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	var ids []types.BlockID
+	err = siabin.ReadObject(conn, &ids, uint64(MaxCatchUpBlocks)*crypto.HashSize+8)
+	if err != nil {
+		return err
+	}
+	if types.BlockHeight(len(ids)) > MaxCatchUpBlocks {
+		return errTooManyRequestedIDs
+	}
+
+	blocks := make([]types.Block, 0, len(ids))
+	cs.mu.RLock()
+	err = cs.db.View(func(tx kv.Tx) error {
+		for _, id := range ids {
+			pb, err := getBlockMap(tx, id)
+			if err != nil {
+				if isBlockPruned(tx, id) {
+					return fmt.Errorf("cannot send block %v: %v", id, modules.ErrBlockPruned)
+				}
+				return err
+			}
+			blocks = append(blocks, pb.Block)
+		}
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return siabin.WriteObject(conn, blocks)
+}
+
+// managedReceiveHeaders returns the calling end of the SendHeaders RPC,
+// storing the validated header chain in the given chain.
+func (cs *ConsensusSet) managedReceiveHeaders(chain *headerChain, peerHeight *types.BlockHeight) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := setConnDeadline(conn, sendBlocksTimeout)
+		if err != nil {
+			return err
+		}
+
+		// Send the block history, so the peer can find our most recent common block.
+		var history [32]types.BlockID
+		cs.mu.RLock()
+		err = cs.db.View(func(tx kv.Tx) error {
+			history = blockHistory(tx)
+			return nil
+		})
+		cs.mu.RUnlock()
+		if err != nil {
+			return err
+		}
+		if err = siabin.WriteObject(conn, history); err != nil {
+			return err
+		}
+
+		// Read the headers, followed by the height of the peer.
+		var headers []types.BlockHeader
+		err = siabin.ReadObject(conn, &headers, uint64(MaxCatchUpHeaders)*types.BlockHeaderSize+8)
+		if err != nil {
+			return err
+		}
+		if types.BlockHeight(len(headers)) > MaxCatchUpHeaders {
+			return fmt.Errorf("peer sent %d headers while at most %d were requested", len(headers), MaxCatchUpHeaders)
+		}
+		if err = siabin.ReadObject(conn, peerHeight, 8); err != nil {
+			return err
+		}
+
+		// Validate the headers as a linked header chain on top of our current chain.
+		// The plugin state updated by the block header hooks is rolled back,
+		// as the headers are only applied once the blocks are accepted.
+		cs.mu.RLock()
+		err = cs.db.Update(func(tx kv.Tx) error {
+			err := cs.validateHeaderChain(tx, headers, chain)
+			if err != nil {
+				return err
+			}
+			return errHeaderChainRollback
+		})
+		cs.mu.RUnlock()
+		if err == errHeaderChainRollback {
+			err = nil
+		}
+		return err
+	}
+}
+
+// validateHeaderChain validates that the given headers form a linked header chain,
+// of which the first header extends a block known to the consensus set.
+// Headers of blocks already known to the consensus set are skipped.
+//
+// Besides the linkage, checkpoints, DoS-listed blocks and timestamps, the headers
+// are validated by the (plugin) block header hooks, applied in order on top of the current
+// plugin state. It is up to the caller to roll back the given transaction, such that
+// the headers are only applied to the plugins once their blocks are accepted.
+func (cs *ConsensusSet) validateHeaderChain(tx kv.Tx, headers []types.BlockHeader, chain *headerChain) error {
+	chain.ids = chain.ids[:0]
+	var parentID types.BlockID
+	for _, header := range headers {
+		id := header.ID()
+		if len(chain.ids) == 0 {
+			// the first unknown header is validated against its parent,
+			// which has to be known to the consensus set
+			err := cs.validateHeader(kvTxWrapper{tx}, header)
+			if err == modules.ErrBlockKnown {
+				parentID = id
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			_, parentHeight, err := getBlockHeaderHeight(tx, header.ParentID)
+			if err != nil {
+				return err
+			}
+			chain.startHeight = parentHeight + 1
+		}
+		height := chain.startHeight + types.BlockHeight(len(chain.ids))
+		if len(chain.ids) > 0 {
+			// all other headers are validated against their parent header
+			if header.ParentID != parentID {
+				return errHeaderChainBroken
+			}
+			if checkpointID, ok := cs.checkpoints[height]; ok && checkpointID != id {
+				return errCheckpointMismatch
+			}
+			if _, exists := cs.dosBlocks[id]; exists {
+				return errDoSBlock
+			}
+			if header.Timestamp > types.CurrentTimestamp()+cs.chainCts.ExtremeFutureThreshold {
+				return errExtremeFutureTimestamp
+			}
+		}
+		err := cs.applyPluginBlockHeader(tx, header, height)
+		if err != nil {
+			return err
+		}
+		chain.ids = append(chain.ids, id)
+		parentID = id
+	}
+	return nil
+}
+
+// applyPluginBlockHeader applies the given header, of a block which is not yet known,
+// to the (plugin) block header hooks. The miner payouts of the block are not known,
+// and are thus not part of the header applied to the plugins.
+func (cs *ConsensusSet) applyPluginBlockHeader(tx kv.Tx, header types.BlockHeader, height types.BlockHeight) error {
+	if len(cs.plugins) == 0 {
+		return nil
+	}
+	ch := modules.ConsensusBlockHeader{
+		ID:         header.ID(),
+		ParentID:   header.ParentID,
+		POBSOutput: header.POBSOutput,
+		Timestamp:  header.Timestamp,
+		Height:     height,
+	}
+	for name, plugin := range cs.plugins {
+		err := plugin.ApplyBlockHeader(ch, cs.bucketForPlugin(tx, name))
+		if err != nil {
+			cs.log.Printf("WARN: header %v at height %d is refused by plugin %s: %v", ch.ID, height, name, err)
+			return errInvalidHeaderChain
+		}
+	}
+	return nil
+}
+
+// managedReceiveBlocksByID returns the calling end of the SendBlocksByID RPC,
+// storing the received blocks in the given slice, after validating
+// that they are the requested blocks.
+func (cs *ConsensusSet) managedReceiveBlocksByID(ids []types.BlockID, blocks *[]types.Block) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := setConnDeadline(conn, sendBlocksByIDTimeout)
+		if err != nil {
+			return err
+		}
+		if err = siabin.WriteObject(conn, ids); err != nil {
+			return err
+		}
+		err = siabin.ReadObject(conn, blocks, uint64(len(ids))*cs.chainCts.BlockSizeLimit+8)
+		if err != nil {
+			return err
+		}
+		if len(*blocks) != len(ids) {
+			return errUnexpectedBlock
+		}
+		for idx, block := range *blocks {
+			// the block ID commits to the merkle root of the block content,
+			// and thus ensures the body belongs to the validated header
+			if block.ID() != ids[idx] {
+				return errUnexpectedBlock
+			}
+		}
+		return nil
+	}
+}
+
+// managedHeadersFirstDownload downloads the blockchain from the given peers using
+// the headers-first strategy, until the peer used to fetch the headers from reports no more headers.
+// An error is returned in case the download failed too many times, in which case
+// the caller is expected to fall back to the legacy SendBlocks IBD.
+// Block bodies are only downloaded from the peers which, according to the
+// given block range cache, did not prune them.
+func (cs *ConsensusSet) managedHeadersFirstDownload(peers []modules.Peer, ranges *peerBlockRanges) error {
+	if len(peers) == 0 {
+		return errNoDownloadPeers
+	}
+	defer cs.updateSyncProgress(func(progress *modules.ConsensusSyncProgress) {
+		progress.DownloadPeers = 0
+	})
+
+	var (
+		failures   int
+		headerPeer int
+		chain      headerChain
+	)
+	for failures < maxHeadersFirstFailures {
+		select {
+		case <-cs.tg.StopChan():
+			return nil
+		default:
+		}
+
+		// fetch and validate the next batch of headers
+		peer := peers[headerPeer%len(peers)]
+		var peerHeight types.BlockHeight
+		err := cs.gateway.RPC(peer.NetAddress, "SendHeaders", cs.managedReceiveHeaders(&chain, &peerHeight))
+		if err != nil {
+			cs.log.Printf("WARN: failed to receive headers from peer %v: %v", peer.NetAddress, err)
+			cs.managedDropIBDPeer(peer.NetAddress, err)
+			failures++
+			headerPeer++
+			continue
+		}
+		cs.updateSyncProgress(func(progress *modules.ConsensusSyncProgress) {
+			if peerHeight > progress.PeerHeight {
+				progress.PeerHeight = peerHeight
+			}
+		})
+		if len(chain.ids) == 0 {
+			// no more headers available from this peer
+			return nil
+		}
+		cs.log.Debugf("received %d valid headers from peer %v, starting at height %d",
+			len(chain.ids), peer.NetAddress, chain.startHeight)
+
+		// download the bodies from the peers which did not prune them,
+		// and apply them to the consensus set
+		var bodyPeers []modules.Peer
+		for _, p := range peers {
+			if ranges.managedServesHeight(p.NetAddress, chain.startHeight) {
+				bodyPeers = append(bodyPeers, p)
+			}
+		}
+		if len(bodyPeers) == 0 {
+			cs.log.Printf("WARN: none of the peers can send the blocks starting at height %d", chain.startHeight)
+			return errNoDownloadPeers
+		}
+		err = cs.managedDownloadBlockBodies(chain, bodyPeers)
+		if err != nil {
+			cs.log.Printf("WARN: failed to download block bodies for headers starting at height %d: %v", chain.startHeight, err)
+			if err == errInvalidHeaderChain {
+				// the bodies match the headers, so it is the peer that sent us the headers which misbehaved
+				cs.managedDropIBDPeer(peer.NetAddress, err)
+			}
+			failures++
+			headerPeer++
+			continue
+		}
+		failures = 0
+	}
+	return fmt.Errorf("headers-first download failed %d times in a row", failures)
+}
+
+// managedDownloadBlockBodies downloads the block bodies for the given header chain,
+// in parallel from the given peers, applying them in order to the consensus set.
+func (cs *ConsensusSet) managedDownloadBlockBodies(chain headerChain, peers []modules.Peer) error {
+	// split the header chain in batches
+	var batches [][]types.BlockID
+	for start := 0; start < len(chain.ids); start += int(MaxCatchUpBlocks) {
+		end := start + int(MaxCatchUpBlocks)
+		if end > len(chain.ids) {
+			end = len(chain.ids)
+		}
+		batches = append(batches, chain.ids[start:end])
+	}
+
+	numWorkers := len(peers)
+	if numWorkers > maxIBDDownloadPeers {
+		numWorkers = maxIBDDownloadPeers
+	}
+	if numWorkers > len(batches) {
+		numWorkers = len(batches)
+	}
+	cs.updateSyncProgress(func(progress *modules.ConsensusSyncProgress) {
+		progress.DownloadPeers = numWorkers
+	})
+
+	// the tasks channel can hold all batches, such that a failed batch
+	// can always be rescheduled without blocking, the same goes for the results channel
+	tasks := make(chan int, len(batches))
+	for index := range batches {
+		tasks <- index
+	}
+	results := make(chan ibdBatchResult, len(batches)+numWorkers)
+	done := make(chan struct{})
+	defer close(done)
+
+	for _, peer := range peers[:numWorkers] {
+		go cs.threadedDownloadBlockBodies(peer.NetAddress, batches, tasks, results, done)
+	}
+
+	// apply the batches in order, as they become available
+	pending := make(map[int]ibdBatchResult)
+	activeWorkers := numWorkers
+	for next := 0; next < len(batches); {
+		var result ibdBatchResult
+		select {
+		case <-cs.tg.StopChan():
+			return nil
+		case result = <-results:
+		}
+		if result.index < 0 {
+			activeWorkers--
+			if activeWorkers == 0 {
+				return errNoDownloadPeers
+			}
+			cs.updateSyncProgress(func(progress *modules.ConsensusSyncProgress) {
+				progress.DownloadPeers = activeWorkers
+			})
+			continue
+		}
+		pending[result.index] = result
+
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			for _, block := range result.blocks {
+				err := cs.managedAcceptBlock(block)
+				// blocks might have been received already via other means,
+				// and are thus not an error
+				if err == modules.ErrNonExtendingBlock || err == modules.ErrBlockKnown {
+					err = nil
+				}
+				if err != nil {
+					cs.log.Printf("WARN: failed to accept block %v received from peer %v: %v", block.ID(), result.peer, err)
+					return errInvalidHeaderChain
+				}
+			}
+			next++
+		}
+	}
+	return nil
+}
+
+// threadedDownloadBlockBodies downloads batches of block bodies from a single peer,
+// until no more batches are available, the download is done or the peer misbehaves.
+// Batches which could not be downloaded are rescheduled for the other peers.
+func (cs *ConsensusSet) threadedDownloadBlockBodies(peer modules.NetAddress, batches [][]types.BlockID, tasks chan int, results chan<- ibdBatchResult, done <-chan struct{}) {
+	err := cs.tg.Add()
+	if err != nil {
+		results <- ibdBatchResult{index: -1, peer: peer}
+		return
+	}
+	defer cs.tg.Done()
+
+	for {
+		var index int
+		select {
+		case <-done:
+			return
+		case <-cs.tg.StopChan():
+			return
+		case index = <-tasks:
+		}
+
+		var blocks []types.Block
+		err := cs.gateway.RPC(peer, "SendBlocksByID", cs.managedReceiveBlocksByID(batches[index], &blocks))
+		if err != nil {
+			cs.log.Printf("WARN: failed to download block bodies from peer %v: %v", peer, err)
+			// reschedule the batch for another peer and stop using this peer
+			tasks <- index
+			cs.managedDropIBDPeer(peer, err)
+			results <- ibdBatchResult{index: -1, peer: peer}
+			return
+		}
+		results <- ibdBatchResult{index: index, blocks: blocks, peer: peer}
+	}
+}
+
+// isIBDMisbehaviorErr returns true if the error returned by a headers-first RPC
+// indicates that the peer is either too slow or misbehaving, in which case it should be dropped.
+// Other errors, such as a peer not supporting the headers-first RPCs, are not considered misbehavior.
+func isIBDMisbehaviorErr(err error) bool {
+	switch err {
+	case errSendBlocksStalled, errUnexpectedBlock, errHeaderChainBroken, errInvalidHeaderChain,
+		errDoSBlock, errEarlyTimestamp, errExtremeFutureTimestamp, errCheckpointMismatch, errCheckpointFork:
+		return true
+	default:
+		return isTimeoutErr(err)
+	}
+}
+
+// managedDropIBDPeer disconnects from a peer which timed out or misbehaved during the IBD.
+func (cs *ConsensusSet) managedDropIBDPeer(peer modules.NetAddress, reason error) {
+	if !isIBDMisbehaviorErr(reason) {
+		return
+	}
+	cs.log.Printf("WARN: disconnecting from peer %v because headers-first IBD failed: %v", peer, reason)
+	err := cs.gateway.Disconnect(peer)
+	if err != nil {
+		cs.log.Printf("WARN: disconnecting from peer %v failed: %v", peer, err)
+	}
+}
+
+// setConnDeadline sets a deadline on the connection,
+// ignoring the error returned by pipes in testing.
+func setConnDeadline(conn modules.PeerConn, timeout time.Duration) error {
+	err := conn.SetDeadline(time.Now().Add(timeout))
+	// Ignore errors returned by SetDeadline if the conn is a pipe in testing.
+	// Pipes do not support Set{,Read,Write}Deadline and should only be used in
+	// testing.
+	if opErr, ok := err.(*net.OpError); ok && opErr.Op == "set" && opErr.Net == "pipe" && build.Release == "testing" {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,264 @@
+package consensus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestValidateHeaderChain probes the validation of header chains
+// as received during the headers-first initial blockchain download.
+func TestValidateHeaderChain(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	genesisID := cst.cs.blockRoot.Block.ID()
+	genesisTimestamp := cst.cs.blockRoot.Block.Timestamp
+
+	// create a linked chain of headers on top of the genesis block
+	headers := make([]types.BlockHeader, 5)
+	parentID := genesisID
+	for i := range headers {
+		headers[i] = types.BlockHeader{
+			ParentID:  parentID,
+			Timestamp: genesisTimestamp + types.Timestamp(i+1),
+		}
+		headers[i].MerkleRoot[0] = byte(i)
+		parentID = headers[i].ID()
+	}
+
+	testCases := []struct {
+		Headers     []types.BlockHeader
+		Error       error
+		ChainLength int
+	}{
+		{nil, nil, 0},
+		{headers, nil, len(headers)},
+		{headers[:1], nil, 1},
+		// the genesis header is known, and thus skipped
+		{append([]types.BlockHeader{cst.cs.blockRoot.Block.Header()}, headers...), nil, len(headers)},
+		// the first header has to extend a known block
+		{headers[1:], errOrphan, 0},
+		// all headers have to be linked
+		{[]types.BlockHeader{headers[0], headers[2]}, errHeaderChainBroken, 0},
+	}
+	for idx, testCase := range testCases {
+		var chain headerChain
+		err = cst.cs.db.View(func(tx kv.Tx) error {
+			return cst.cs.validateHeaderChain(tx, testCase.Headers, &chain)
+		})
+		if err != testCase.Error {
+			t.Errorf("test case #%d: unexpected error: %v != %v", idx, err, testCase.Error)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(chain.ids) != testCase.ChainLength {
+			t.Errorf("test case #%d: unexpected chain length: %d != %d", idx, len(chain.ids), testCase.ChainLength)
+			continue
+		}
+		if len(chain.ids) > 0 {
+			if chain.startHeight != 1 {
+				t.Errorf("test case #%d: unexpected start height: %d", idx, chain.startHeight)
+			}
+			if chain.ids[0] != headers[0].ID() {
+				t.Errorf("test case #%d: unexpected first ID: %v", idx, chain.ids[0])
+			}
+		}
+	}
+}
+
+// headerPlugin is a plugin which counts the block headers applied to it,
+// refusing the header of the given block.
+type headerPlugin struct {
+	testPlugin
+	refused types.BlockID
+}
+
+var (
+	headerPluginCountKey   = []byte("count")
+	errHeaderPluginRefused = errors.New("header refused by plugin")
+)
+
+// ApplyBlockHeader implements modules.ConsensusSetPlugin.ApplyBlockHeader
+func (plugin *headerPlugin) ApplyBlockHeader(header modules.ConsensusBlockHeader, bucket *persist.LazyBucket) error {
+	if header.ID == plugin.refused {
+		return errHeaderPluginRefused
+	}
+	count, err := headerPluginCount(bucket)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(headerPluginCountKey, []byte{byte(count + 1)})
+}
+
+// headerPluginCount returns the amount of headers applied to the plugin.
+func headerPluginCount(bucket *persist.LazyBucket) (int, error) {
+	v, err := bucket.Get(headerPluginCountKey)
+	if err != nil || len(v) == 0 {
+		return 0, err
+	}
+	return int(v[0]), nil
+}
+
+// TestValidateHeaderChainPlugins probes that the headers of a header chain
+// are validated by the block header hooks of the plugins,
+// without the plugin state being updated.
+func TestValidateHeaderChainPlugins(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	headers := make([]types.BlockHeader, 3)
+	parentID := cst.cs.blockRoot.Block.ID()
+	for i := range headers {
+		headers[i] = types.BlockHeader{
+			ParentID:  parentID,
+			Timestamp: cst.cs.blockRoot.Block.Timestamp + types.Timestamp(i+1),
+		}
+		parentID = headers[i].ID()
+	}
+	plugin := &headerPlugin{refused: headers[2].ID()}
+	err = cst.cs.RegisterPlugin(context.Background(), "headerplugin", plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validate := func(headers []types.BlockHeader) (headerChain, int, error) {
+		var (
+			chain headerChain
+			count int
+		)
+		err := cst.cs.db.Update(func(tx kv.Tx) error {
+			err := cst.cs.validateHeaderChain(tx, headers, &chain)
+			if err != nil {
+				return err
+			}
+			count, err = headerPluginCount(cst.cs.bucketForPlugin(tx, "headerplugin"))
+			if err != nil {
+				return err
+			}
+			return errHeaderChainRollback
+		})
+		if err == errHeaderChainRollback {
+			err = nil
+		}
+		return chain, count, err
+	}
+
+	// the plugin is applied to all headers of a valid chain
+	chain, count, err := validate(headers[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain.ids) != 2 || count != 2 {
+		t.Fatalf("unexpected chain length %d and amount of headers applied to the plugin %d", len(chain.ids), count)
+	}
+	// the plugin refuses the last header
+	_, _, err = validate(headers)
+	if err != errInvalidHeaderChain {
+		t.Fatalf("expected %v, got: %v", errInvalidHeaderChain, err)
+	}
+	// the plugin state is rolled back
+	_, count, err = validate(nil)
+	if err != nil || count != 0 {
+		t.Fatalf("unexpected amount of headers applied to the plugin %d (%v)", count, err)
+	}
+}
+
+// TestFindCommonBlockHeight probes the findCommonBlockHeight function,
+// used by both the SendBlocks and SendHeaders RPCs.
+func TestFindCommonBlockHeight(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	err = cst.cs.db.View(func(tx kv.Tx) error {
+		// a caller which knows all our blocks doesn't need any blocks
+		_, found := findCommonBlockHeight(tx, blockHistory(tx))
+		if found {
+			t.Error("no common block expected for a caller with all known blocks")
+		}
+		// a caller which knows none of our blocks doesn't have a common block
+		var unknownBlocks [32]types.BlockID
+		unknownBlocks[0][0] = 1
+		_, found = findCommonBlockHeight(tx, unknownBlocks)
+		if found {
+			t.Error("no common block expected for unknown blocks")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a pruned block is found as the common block as well
+	errRollback := errors.New("rollback")
+	err = cst.cs.db.Update(func(tx kv.Tx) error {
+		parentID := cst.cs.blockRoot.Block.ID()
+		var pbs []*processedBlock
+		for height := types.BlockHeight(1); height <= 2; height++ {
+			pb := &processedBlock{
+				Block: types.Block{
+					ParentID:  parentID,
+					Timestamp: cst.cs.blockRoot.Block.Timestamp + types.Timestamp(height),
+				},
+				Height: height,
+			}
+			addBlockMap(tx, pb)
+			pushPath(tx, pb.Block.ID())
+			parentID = pb.Block.ID()
+			pbs = append(pbs, pb)
+		}
+		err := pruneBlock(tx, pbs[0])
+		if err != nil {
+			return err
+		}
+		var knownBlocks [32]types.BlockID
+		knownBlocks[0] = pbs[0].Block.ID()
+		start, found := findCommonBlockHeight(tx, knownBlocks)
+		if !found || start != 2 {
+			t.Errorf("expected the pruned block to be found as common block: %d (%v)", start, found)
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatal(err)
+	}
+
+	progress := cst.cs.SyncProgress()
+	if progress != (modules.ConsensusSyncProgress{}) {
+		t.Errorf("unexpected sync progress: %v", progress)
+	}
+}
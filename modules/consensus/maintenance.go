@@ -3,9 +3,9 @@ package consensus
 import (
 	"errors"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -16,7 +16,7 @@ var (
 
 // applyMinerPayouts adds a block's miner payouts to the consensus set as
 // delayed coin outputs.
-func (cs *ConsensusSet) applyMinerPayouts(tx *bolt.Tx, pb *processedBlock) {
+func (cs *ConsensusSet) applyMinerPayouts(tx kv.Tx, pb *processedBlock) {
 	for i := range pb.Block.MinerPayouts {
 		mpid := pb.Block.MinerPayoutID(uint64(i))
 		dscod := modules.DelayedCoinOutputDiff{
@@ -38,7 +38,7 @@ func (cs *ConsensusSet) applyMinerPayouts(tx *bolt.Tx, pb *processedBlock) {
 // applyMaturedCoinOutputs goes through the list of coin outputs that
 // have matured and adds them to the consensus set. This also updates the block
 // node diff set.
-func applyMaturedCoinOutputs(tx *bolt.Tx, pb *processedBlock) {
+func applyMaturedCoinOutputs(tx kv.Tx, pb *processedBlock) {
 	// Iterate through the list of delayed coin outputs. Sometimes boltdb
 	// has trouble if you delete elements in a bucket while iterating through
 	// the bucket (and sometimes not - nondeterministic), so all of the
@@ -96,7 +96,7 @@ func applyMaturedCoinOutputs(tx *bolt.Tx, pb *processedBlock) {
 	}
 	for _, scod := range scods {
 		pb.CoinOutputDiffs = append(pb.CoinOutputDiffs, scod)
-		commitCoinOutputDiff(tx, scod, modules.DiffApply)
+		commitCoinOutputDiff(tx, scod, modules.DiffApply, pb.Height)
 	}
 	for _, dscod := range dscods {
 		pb.DelayedCoinOutputDiffs = append(pb.DelayedCoinOutputDiffs, dscod)
@@ -108,7 +108,7 @@ func applyMaturedCoinOutputs(tx *bolt.Tx, pb *processedBlock) {
 // applyMaintenance applies block-level alterations to the consensus set.
 // Maintenance is applied after all of the transactions for the block have been
 // applied.
-func (cs *ConsensusSet) applyMaintenance(tx *bolt.Tx, pb *processedBlock) {
+func (cs *ConsensusSet) applyMaintenance(tx kv.Tx, pb *processedBlock) {
 	cs.applyMinerPayouts(tx, pb)
 	applyMaturedCoinOutputs(tx, pb)
 }
@@ -6,10 +6,10 @@ import (
 	"os"
 	"path/filepath"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 const (
@@ -22,14 +22,14 @@ const (
 // loadDB pulls all the blocks that have been saved to disk into memory, using
 // them to fill out the ConsensusSet.
 func (cs *ConsensusSet) loadDB() error {
-	// Open the database - a new bolt database will be created if none exists.
+	// Open the database - a new database will be created if none exists.
 	err := cs.openDB(filepath.Join(cs.persistDir, DatabaseFilename))
 	if err != nil {
 		return err
 	}
 
 	// Walk through initialization for Sia.
-	return cs.db.Update(func(tx *bolt.Tx) error {
+	return cs.db.Update(func(tx kv.Tx) error {
 		// Check if the database has been initialized.
 		if !dbInitialized(tx) {
 			return cs.initDB(tx)
@@ -49,7 +49,15 @@ func (cs *ConsensusSet) loadDB() error {
 		if genesisID != cs.blockRoot.Block.ID() {
 			return errors.New("blockchain has wrong genesis block, exiting")
 		}
-		return nil
+		err = restoreOutputHeights(tx)
+		if err != nil {
+			return err
+		}
+		err = cs.loadSnapshotMetadata(tx)
+		if err != nil {
+			return err
+		}
+		return cs.loadPrunedHeight(tx)
 	})
 }
 
@@ -5,23 +5,23 @@ import (
 	"encoding/hex"
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
 
-func convertLegacyDatabase(filepath string, log *persist.Logger) (*persist.BoltDatabase, error) {
+func convertLegacyDatabase(filepath string, log *persist.Logger) (*persist.Database, error) {
 	return convertLegacyOneZeroFiveDatabase(filepath, log)
 }
 
 // convertLegacyOneZeroFiveDatabase converts a 1.0.5 consensus database,
 // to a database of the current version as defined by dbMetadata.
 // It keeps the database open and returns it for further usage.
-func convertLegacyOneZeroFiveDatabase(filepath string, log *persist.Logger) (db *persist.BoltDatabase, err error) {
+func convertLegacyOneZeroFiveDatabase(filepath string, log *persist.Logger) (db *persist.Database, err error) {
 	var legacyDBMetadata = persist.Metadata{
 		Header:  "Consensus Set Database",
 		Version: "1.0.5",
@@ -38,7 +38,7 @@ func convertLegacyOneZeroFiveDatabase(filepath string, log *persist.Logger) (db
 		}
 	}
 
-	err = db.Update(func(tx *bolt.Tx) error {
+	err = db.Update(func(tx kv.Tx) error {
 		_, err := tx.CreateBucket(BucketPlugins)
 		return err
 	})
@@ -60,7 +60,7 @@ func convertLegacyOneZeroFiveDatabase(filepath string, log *persist.Logger) (db
 // convertLegacyDatabase converts a 0.5.0 consensus database,
 // to a database of the current version as defined by dbMetadata.
 // It keeps the database open and returns it for further usage.
-func convertLegacyZeroFiveZeroDatabase(filePath string, log *persist.Logger, desiredMetadata persist.Metadata) (db *persist.BoltDatabase, err error) {
+func convertLegacyZeroFiveZeroDatabase(filePath string, log *persist.Logger, desiredMetadata persist.Metadata) (db *persist.Database, err error) {
 	var legacyDBMetadata = persist.Metadata{
 		Header:  "Consensus Set Database",
 		Version: "0.5.0",
@@ -69,7 +69,7 @@ func convertLegacyZeroFiveZeroDatabase(filePath string, log *persist.Logger, des
 	if err != nil {
 		return nil, err
 	}
-	err = db.Update(func(tx *bolt.Tx) error {
+	err = db.Update(func(tx kv.Tx) error {
 		if bucket := tx.Bucket(BlockMap); bucket != nil {
 			log.Printf("upgrading bucket %s format from 0.5.0 to %v...\n", string(BlockMap), dbMetadata.Version)
 			if err := updateLegacyBlockMapBucket(bucket, log); err != nil {
@@ -88,7 +88,7 @@ func convertLegacyZeroFiveZeroDatabase(filePath string, log *persist.Logger, des
 				return err
 			}
 		}
-		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+		return tx.ForEach(func(name []byte, bucket kv.Bucket) error {
 			if !bytes.HasPrefix(name, prefixDCO) {
 				return nil
 			}
@@ -111,7 +111,7 @@ func convertLegacyZeroFiveZeroDatabase(filePath string, log *persist.Logger, des
 	return
 }
 
-func updateLegacyBlockMapBucket(bucket *bolt.Bucket, log *persist.Logger) error {
+func updateLegacyBlockMapBucket(bucket kv.Bucket, log *persist.Logger) error {
 	var (
 		err    error
 		cursor = bucket.Cursor()
@@ -139,7 +139,7 @@ func updateLegacyBlockMapBucket(bucket *bolt.Bucket, log *persist.Logger) error
 	return nil
 }
 
-func updateLegacyCoinOutputBucket(bucket *bolt.Bucket, log *persist.Logger) error {
+func updateLegacyCoinOutputBucket(bucket kv.Bucket, log *persist.Logger) error {
 	var (
 		err    error
 		cursor = bucket.Cursor()
@@ -176,7 +176,7 @@ func updateLegacyCoinOutputBucket(bucket *bolt.Bucket, log *persist.Logger) erro
 	return nil
 }
 
-func updateLegacyBlockstakeOutputBucket(bucket *bolt.Bucket, log *persist.Logger) error {
+func updateLegacyBlockstakeOutputBucket(bucket kv.Bucket, log *persist.Logger) error {
 	var (
 		err    error
 		cursor = bucket.Cursor()
@@ -259,7 +259,7 @@ type (
 	}
 )
 
-func (lpb *legacyProcessedBlock) storeAsNewFormat(bucket *bolt.Bucket, key []byte) error {
+func (lpb *legacyProcessedBlock) storeAsNewFormat(bucket kv.Bucket, key []byte) error {
 	block := processedBlock{
 		Block: types.Block{
 			ParentID:     lpb.Block.ParentID,
@@ -10,7 +10,7 @@ import (
 	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
 	"github.com/threefoldtech/rivine/types"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 // plugin user errors
@@ -77,7 +77,7 @@ func (cs *ConsensusSet) RegisterPlugin(ctx context.Context, name string, plugin
 
 	// init the plugin
 	var consensusChangeID modules.ConsensusChangeID
-	err = cs.db.Update(func(tx *bolt.Tx) (err error) {
+	err = cs.db.Update(func(tx kv.Tx) (err error) {
 		consensusChangeID, err = cs.initConsensusSetPlugin(tx, name, plugin)
 		return err
 	})
@@ -94,7 +94,7 @@ func (cs *ConsensusSet) RegisterPlugin(ctx context.Context, name string, plugin
 	}
 
 	if newConsensusChangeID != consensusChangeID {
-		err = cs.db.Update(func(tx *bolt.Tx) error {
+		err = cs.db.Update(func(tx kv.Tx) error {
 			rootbucket := tx.Bucket(BucketPlugins)
 			// get the metadata bucket from the rootbucket
 			metadataBucket := rootbucket.Bucket(bucketPluginsMetadata)
@@ -140,7 +140,7 @@ func (cs *ConsensusSet) RegisterPlugin(ctx context.Context, name string, plugin
 // This initial sync is  be cancelled if the passes context is closed.
 func (cs *ConsensusSet) initPluginSync(ctx context.Context, name string, plugin modules.ConsensusSetPlugin, start modules.ConsensusChangeID) (modules.ConsensusChangeID, error) {
 	newChangeID := start
-	err := cs.db.Update(func(tx *bolt.Tx) error {
+	err := cs.db.Update(func(tx kv.Tx) error {
 		// 'exists' and 'entry' are going to be pointed to the first entry that
 		// has not yet been seen by subscriber.
 		var exists bool
@@ -148,10 +148,10 @@ func (cs *ConsensusSet) initPluginSync(ctx context.Context, name string, plugin
 
 		if start == modules.ConsensusChangeBeginning {
 			// Special case: for ConsensusChangeBeginning, create an
-			// initial node pointing to the genesis block. The subscriber will
-			// receive the diffs for all blocks in the consensus set, including
-			// the genesis block.
-			entry = cs.genesisEntry()
+			// initial node pointing to the genesis block (or snapshot block).
+			// The subscriber will receive the diffs for all blocks in the
+			// consensus set, including the genesis block (or snapshot block).
+			entry = cs.initialEntry()
 			exists = true
 		} else if start == modules.ConsensusChangeRecent {
 			// Special case: for ConsensusChangeRecent, set up the
@@ -176,7 +176,7 @@ func (cs *ConsensusSet) initPluginSync(ctx context.Context, name string, plugin
 			entry, exists = entry.NextEntry(tx)
 		}
 
-		bucket := persist.NewLazyBoltBucket(func() (*bolt.Bucket, error) {
+		bucket := persist.NewLazyBucket(func() (kv.Bucket, error) {
 			rootbucket := tx.Bucket(BucketPlugins)
 			// get the metadata bucket from the rootbucket
 			mdBucket := rootbucket.Bucket(bucketPluginsMetadata)
@@ -242,6 +242,11 @@ func (cs *ConsensusSet) initPluginSync(ctx context.Context, name string, plugin
 					}
 				}
 				for _, block := range cc.AppliedBlocks {
+					if block.ID() == cs.snapshotBlockID {
+						// the state of a plugin prior to the snapshot block is unknown,
+						// plugins which weren't part of the snapshot start from the block that follows it
+						continue
+					}
 					blockHeight, exists := cs.BlockHeightOfBlock(block)
 					if exists {
 						cBlock := modules.ConsensusBlock{
@@ -280,7 +285,7 @@ func (cs *ConsensusSet) initPluginSync(ctx context.Context, name string, plugin
 	return newChangeID, err
 }
 
-func (cs *ConsensusSet) initConsensusSetPlugin(tx *bolt.Tx, name string, plugin modules.ConsensusSetPlugin) (modules.ConsensusChangeID, error) {
+func (cs *ConsensusSet) initConsensusSetPlugin(tx kv.Tx, name string, plugin modules.ConsensusSetPlugin) (modules.ConsensusChangeID, error) {
 	// get the root plugins bucket
 	rootbucket := tx.Bucket([]byte(BucketPlugins))
 	if rootbucket == nil {
@@ -360,7 +365,7 @@ func (cs *ConsensusSet) initConsensusSetPlugin(tx *bolt.Tx, name string, plugin
 	return pluginMetadata.ConsensusChangeID, nil
 }
 
-func (cs *ConsensusSet) validateTransactionUsingPlugins(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, btx *bolt.Tx) error {
+func (cs *ConsensusSet) validateTransactionUsingPlugins(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext, btx kv.Tx) error {
 	if len(cs.plugins) == 0 {
 		return nil // if there are no errors, there is nothing to validate using plugins
 	}
@@ -393,9 +398,9 @@ func (cs *ConsensusSet) validateTransactionUsingPlugins(tx modules.ConsensusTran
 	return nil
 }
 
-// return the root bucket for a plugin using name in the form of a LazyBoltBucket
-func (cs *ConsensusSet) bucketForPlugin(tx *bolt.Tx, name string) *persist.LazyBoltBucket {
-	return persist.NewLazyBoltBucket(func() (*bolt.Bucket, error) {
+// return the root bucket for a plugin using name in the form of a LazyBucket
+func (cs *ConsensusSet) bucketForPlugin(tx kv.Tx, name string) *persist.LazyBucket {
+	return persist.NewLazyBucket(func() (kv.Bucket, error) {
 		mdBucket := tx.Bucket(BucketPlugins)
 		if mdBucket == nil {
 			return nil, errors.New("metadata plugins bucket is missing, while it should exist at this point")
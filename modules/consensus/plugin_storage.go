@@ -6,19 +6,19 @@ import (
 
 	"github.com/threefoldtech/rivine/persist"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 // A PluginViewStorage struct is a definition for the storage tool for a plugin bucket
 type PluginViewStorage struct {
-	db   *persist.BoltDatabase
+	db   *persist.Database
 	name string
 	wg   *sync.WaitGroup
 }
 
 // NewPluginStorage creates a new plugin storage for a given plugin bucket.
 // PluginViewStorage abstracts the way the plugin bucket manages its data.
-func NewPluginStorage(db *persist.BoltDatabase, name string, wg *sync.WaitGroup) *PluginViewStorage {
+func NewPluginStorage(db *persist.Database, name string, wg *sync.WaitGroup) *PluginViewStorage {
 	wg.Add(1)
 	return &PluginViewStorage{
 		db:   db,
@@ -28,8 +28,8 @@ func NewPluginStorage(db *persist.BoltDatabase, name string, wg *sync.WaitGroup)
 }
 
 // View takes in a callback to a bucket and takes care of managing the database knowledge
-func (ps *PluginViewStorage) View(callback func(bucket *bolt.Bucket) error) error {
-	return ps.db.View(func(tx *bolt.Tx) error {
+func (ps *PluginViewStorage) View(callback func(bucket kv.Bucket) error) error {
+	return ps.db.View(func(tx kv.Tx) error {
 		rootbucket := tx.Bucket([]byte(BucketPlugins))
 		if rootbucket == nil {
 			return errors.New("Plugins bucket does not exist")
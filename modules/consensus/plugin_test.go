@@ -4,9 +4,9 @@ import (
 	"context"
 	"testing"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/types"
 )
 
@@ -21,36 +21,36 @@ func (plugin *testPlugin) Close() error {
 	plugin.closeCalled = true
 	return nil
 }
-func (plugin *testPlugin) InitPlugin(_ *persist.Metadata, _ *bolt.Bucket, ps modules.PluginViewStorage, _ modules.PluginUnregisterCallback) (persist.Metadata, error) {
+func (plugin *testPlugin) InitPlugin(_ *persist.Metadata, _ kv.Bucket, ps modules.PluginViewStorage, _ modules.PluginUnregisterCallback) (persist.Metadata, error) {
 	plugin.storage = ps
 	return persist.Metadata{
 		Version: "1.0.0.0",
 		Header:  "testPlugin",
 	}, nil
 }
-func (plugin *testPlugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+func (plugin *testPlugin) ApplyBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
 	return nil
 }
-func (plugin *testPlugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBoltBucket) error {
+func (plugin *testPlugin) RevertBlock(block modules.ConsensusBlock, bucket *persist.LazyBucket) error {
 	return nil
 }
 
-func (plugin *testPlugin) ApplyBlockHeader(block modules.ConsensusBlockHeader, bucket *persist.LazyBoltBucket) error {
+func (plugin *testPlugin) ApplyBlockHeader(block modules.ConsensusBlockHeader, bucket *persist.LazyBucket) error {
 	return nil
 }
-func (plugin *testPlugin) RevertBlockHeader(block modules.ConsensusBlockHeader, bucket *persist.LazyBoltBucket) error {
+func (plugin *testPlugin) RevertBlockHeader(block modules.ConsensusBlockHeader, bucket *persist.LazyBucket) error {
 	return nil
 }
 
 // Apply the transaction to the plugin.
 // An error should be returned in case something went wrong.
-func (plugin *testPlugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+func (plugin *testPlugin) ApplyTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
 	return nil
 }
 
 // Revert the transaction from the plugin.
 // An error should be returned in case something went wrong.
-func (plugin *testPlugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBoltBucket) error {
+func (plugin *testPlugin) RevertTransaction(txn modules.ConsensusTransaction, bucket *persist.LazyBucket) error {
 	return nil
 }
 
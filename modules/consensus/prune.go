@@ -0,0 +1,496 @@
+package consensus
+
+// prune.go implements the pruned mode of the consensus set. In pruned mode
+// only the processed blocks (and thus their diffs) of the most recent blocks
+// are kept, the processed blocks of older blocks of the current path are
+// deleted on a background schedule, keeping only their headers, such that the
+// chain can still be walked back and validated.
+//
+// The processed blocks of old blocks which created blockstake outputs that are
+// unspent, or which were spent within the prune depth, are retained, as these
+// are looked up in order to validate the proof of blockstake of future blocks.
+// Such retained blocks are pruned as soon as it is no longer required.
+//
+// A pruned consensus set cannot revert blocks beyond the prune depth, and can
+// only serve the blocks from its pruned height onwards to its peers, which
+// can request that range using the SendBlockRange RPC. Subscribers which start
+// from the beginning receive the current state as a single consensus change,
+// applying the current block and all unspent outputs.
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+var (
+	// pruneInterval is the time waited in between
+	// two pruning runs of a consensus set in pruned mode.
+	pruneInterval = func() time.Duration {
+		switch build.Release {
+		case "dev":
+			return 1 * time.Minute
+		case "testing":
+			return 100 * time.Millisecond
+		default:
+			if build.Release != "standard" {
+				build.Severe("unrecognized build.Release")
+			}
+			return 10 * time.Minute
+		}
+	}()
+	// pruneBatchSize is the maximum amount of blocks pruned in a single
+	// database transaction, as to not block the consensus set for too long.
+	pruneBatchSize = func() types.BlockHeight {
+		switch build.Release {
+		case "dev":
+			return 100
+		case "testing":
+			return 5
+		default:
+			if build.Release != "standard" {
+				build.Severe("unrecognized build.Release")
+			}
+			return 500
+		}
+	}()
+)
+
+var (
+	// BucketPruned is a database bucket that only exists for consensus sets
+	// which have pruned blocks. It contains the height up to which the blocks
+	// of the current path have been pruned, as well as the heights of the blocks
+	// below that height which are (still) retained.
+	BucketPruned = []byte("Pruned")
+
+	prunedHeightKey      = []byte("Height")
+	prunedRetainedBucket = []byte("Retained")
+)
+
+var (
+	errPruneDepth     = errors.New("prune depth is too small to validate new blocks")
+	errPruningEnabled = errors.New("pruned mode is already enabled")
+	errPrunedFork     = errors.New("cannot revert blocks which have been pruned")
+)
+
+type (
+	// pruneRun keeps track of the state of a single pruning run,
+	// which prunes the blocks in batches.
+	pruneRun struct {
+		// recheckRetained indicates whether the retained blocks
+		// should be pruned if they are no longer required
+		recheckRetained bool
+		// recentlySpent contains the blockstake outputs spent by the blocks
+		// within the prune depth, up to and including spentHeight,
+		// it can contain outputs spent by reverted blocks as well
+		recentlySpent map[types.BlockStakeOutputID]struct{}
+		spentHeight   types.BlockHeight
+		// pruned and retained count the blocks pruned and retained during this run
+		pruned, retained int
+	}
+
+	// blockRange is the range of block heights
+	// for which a consensus set can send blocks to its peers,
+	// as sent by the SendBlockRange RPC.
+	blockRange struct {
+		Lowest  types.BlockHeight
+		Highest types.BlockHeight
+	}
+)
+
+// EnablePruning enables the pruned mode of the consensus set, such that only
+// the processed blocks of the given amount of most recent blocks are kept.
+// This depth is also the maximum depth of a reorg the consensus set can handle.
+// Blocks are pruned on a background schedule, once the consensus set is started.
+func (cs *ConsensusSet) EnablePruning(depth types.BlockHeight) error {
+	if minDepth := cs.snapshotBlockWindow(); depth < minDepth {
+		return fmt.Errorf("%v: depth %d is less than the minimum depth of %d", errPruneDepth, depth, minDepth)
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.pruneDepth != 0 {
+		return errPruningEnabled
+	}
+	cs.pruneDepth = depth
+	cs.log.Printf("Pruned mode enabled, keeping the processed blocks of the last %d blocks", depth)
+	return nil
+}
+
+// PrunedHeight returns the height from which onwards all blocks of the current
+// path are available. Blocks below this height might have been pruned, or were
+// never downloaded, in case the consensus set was bootstrapped from a snapshot.
+func (cs *ConsensusSet) PrunedHeight() types.BlockHeight {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.availableHeight()
+}
+
+// availableHeight returns the height from which onwards
+// all blocks of the current path are available.
+func (cs *ConsensusSet) availableHeight() types.BlockHeight {
+	if cs.snapshotHeight > cs.prunedHeight {
+		return cs.snapshotHeight
+	}
+	return cs.prunedHeight
+}
+
+// threadedPruneBlocks prunes the blocks which are older than the prune depth,
+// on a fixed interval, until the consensus set is stopped.
+func (cs *ConsensusSet) threadedPruneBlocks() {
+	err := cs.tg.Add()
+	if err != nil {
+		return
+	}
+	defer cs.tg.Done()
+
+	for {
+		err = cs.managedPruneBlocks()
+		if err != nil {
+			cs.log.Printf("[WARN] failed to prune blocks: %v", err)
+		}
+		select {
+		case <-cs.tg.StopChan():
+			return
+		case <-time.After(pruneInterval):
+		}
+	}
+}
+
+// managedPruneBlocks prunes all blocks which are older than the prune depth,
+// in batches, such that the consensus set isn't locked for too long.
+func (cs *ConsensusSet) managedPruneBlocks() error {
+	run := pruneRun{recheckRetained: true}
+	for done := false; !done; {
+		select {
+		case <-cs.tg.StopChan():
+			return nil
+		default:
+		}
+		var err error
+		done, err = cs.managedPruneBlockBatch(&run)
+		if err != nil {
+			return err
+		}
+		run.recheckRetained = false
+	}
+	if run.pruned > 0 || run.retained > 0 {
+		cs.log.Printf("Pruned %d blocks, retained %d blocks for proof of blockstake validation, blocks prior to height %d are pruned",
+			run.pruned, run.retained, cs.PrunedHeight())
+	}
+	return nil
+}
+
+// managedPruneBlockBatch prunes a single batch of blocks within a single database transaction,
+// returning true if all blocks older than the prune depth have been pruned.
+func (cs *ConsensusSet) managedPruneBlockBatch(run *pruneRun) (done bool, err error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	err = cs.db.Update(func(tx kv.Tx) error {
+		height := blockHeight(tx)
+		if height <= cs.pruneDepth {
+			done = true
+			return nil
+		}
+		// all blocks prior to this height can be pruned
+		target := height - cs.pruneDepth
+		start := cs.prunedHeight
+		if start == 0 {
+			// the genesis block is never pruned
+			start = 1
+		}
+		if !run.recheckRetained && start >= target {
+			done = true
+			return nil
+		}
+
+		prunedBucket, err := tx.CreateBucketIfNotExists(BucketPruned)
+		if err != nil {
+			return err
+		}
+		retainedBucket, err := prunedBucket.CreateBucketIfNotExists(prunedRetainedBucket)
+		if err != nil {
+			return err
+		}
+
+		// collect the blockstake outputs spent within the prune depth
+		from := target
+		if run.recentlySpent == nil {
+			run.recentlySpent = make(map[types.BlockStakeOutputID]struct{})
+		} else if run.spentHeight >= from {
+			from = run.spentHeight + 1
+		}
+		err = collectSpentBlockStakeOutputs(tx, from, height, run.recentlySpent)
+		if err != nil {
+			return err
+		}
+		run.spentHeight = height
+
+		// prune the retained blocks which are no longer required
+		if run.recheckRetained {
+			var prunable [][]byte
+			err = retainedBucket.ForEach(func(k, _ []byte) error {
+				var h types.BlockHeight
+				err := siabin.Unmarshal(k, &h)
+				if err != nil {
+					return err
+				}
+				pb, err := getPathBlock(tx, h)
+				if err != nil {
+					return err
+				}
+				if !retainBlock(tx, pb, run.recentlySpent) {
+					prunable = append(prunable, append([]byte(nil), k...))
+					return pruneBlock(tx, pb)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, k := range prunable {
+				err = retainedBucket.Delete(k)
+				if err != nil {
+					return err
+				}
+			}
+			run.pruned += len(prunable)
+		}
+
+		// prune the next batch of blocks
+		h := start
+		for ; h < target && h < start+pruneBatchSize; h++ {
+			// the block can no longer be reverted,
+			// so the heights of the outputs it spent are no longer required
+			err = pruneSpentOutputHeights(tx, h)
+			if err != nil {
+				return err
+			}
+			pb, err := getPathBlock(tx, h)
+			if err == errNilItem {
+				// the block was never available, which is the case for blocks
+				// prior to the snapshot the consensus set was bootstrapped from
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if retainBlock(tx, pb, run.recentlySpent) {
+				k, err := siabin.Marshal(h)
+				if err != nil {
+					return fmt.Errorf("failed to (siabin) marshal block height: %v", err)
+				}
+				err = retainedBucket.Put(k, []byte{})
+				if err != nil {
+					return err
+				}
+				run.retained++
+				continue
+			}
+			err = pruneBlock(tx, pb)
+			if err != nil {
+				return err
+			}
+			run.pruned++
+		}
+		done = h >= target
+		if h == start {
+			return nil
+		}
+		heightBytes, err := siabin.Marshal(h)
+		if err != nil {
+			return fmt.Errorf("failed to (siabin) marshal block height: %v", err)
+		}
+		err = prunedBucket.Put(prunedHeightKey, heightBytes)
+		if err != nil {
+			return err
+		}
+		cs.prunedHeight = h
+		return nil
+	})
+	return
+}
+
+// getPathBlock returns the processed block at the given height of the current path,
+// errNilItem is returned if the processed block is not available.
+func getPathBlock(tx kv.Tx, height types.BlockHeight) (*processedBlock, error) {
+	id, err := getPath(tx, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block ID at height %d: %v", height, err)
+	}
+	return getBlockMap(tx, id)
+}
+
+// collectSpentBlockStakeOutputs collects the IDs of the blockstake outputs spent
+// by the blocks of the current path, in between the given heights (both inclusive).
+func collectSpentBlockStakeOutputs(tx kv.Tx, from, to types.BlockHeight, spent map[types.BlockStakeOutputID]struct{}) error {
+	for h := from; h <= to; h++ {
+		pb, err := getPathBlock(tx, h)
+		if err != nil {
+			return fmt.Errorf("failed to get processed block at height %d: %v", h, err)
+		}
+		for _, diff := range pb.BlockStakeOutputDiffs {
+			if diff.Direction == modules.DiffRevert {
+				spent[diff.ID] = struct{}{}
+			}
+		}
+	}
+	return nil
+}
+
+// retainBlock returns true if the given processed block created a blockstake output
+// which is unspent, or which was spent recently, in which case the block might still
+// be looked up to validate the proof of blockstake of a future block.
+func retainBlock(tx kv.Tx, pb *processedBlock, recentlySpent map[types.BlockStakeOutputID]struct{}) bool {
+	bsoBucket := tx.Bucket(BlockStakeOutputs)
+	for _, txn := range pb.Block.Transactions {
+		for i := range txn.BlockStakeOutputs {
+			id := txn.BlockStakeOutputID(uint64(i))
+			if bsoBucket.Get(id[:]) != nil {
+				return true
+			}
+			if _, ok := recentlySpent[id]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pruneBlock deletes the given processed block from the block map,
+// storing only its header, such that the chain can still be walked back.
+func pruneBlock(tx kv.Tx, pb *processedBlock) error {
+	err := addBlockHeader(tx, pb.Block.Header(), pb.Height)
+	if err != nil {
+		return err
+	}
+	id := pb.Block.ID()
+	return tx.Bucket(BlockMap).Delete(id[:])
+}
+
+// isBlockPruned returns true if only the header of the block with the given ID is available.
+func isBlockPruned(tx kv.Tx, id types.BlockID) bool {
+	if tx.Bucket(BlockMap).Get(id[:]) != nil {
+		return false
+	}
+	headers := tx.Bucket(BlockHeaders)
+	return headers != nil && headers.Get(id[:]) != nil
+}
+
+// loadPrunedHeight loads the height up to which the blocks have been pruned, if any.
+func (cs *ConsensusSet) loadPrunedHeight(tx kv.Tx) error {
+	prunedBucket := tx.Bucket(BucketPruned)
+	if prunedBucket == nil {
+		return nil
+	}
+	heightBytes := prunedBucket.Get(prunedHeightKey)
+	if heightBytes == nil {
+		return nil
+	}
+	err := siabin.Unmarshal(heightBytes, &cs.prunedHeight)
+	if err != nil {
+		return fmt.Errorf("failed to (siabin) unmarshal pruned height: %v", err)
+	}
+	return nil
+}
+
+// currentStateChange returns a consensus change which applies the current block
+// and all unspent outputs, identified by the most recent change log entry.
+// It is sent to subscribers which start from the beginning, in case the blocks
+// required to compute all consensus changes are no longer available.
+func (cs *ConsensusSet) currentStateChange(tx kv.Tx) (modules.ConsensusChange, error) {
+	pb := currentProcessedBlock(tx)
+	var tailID modules.ConsensusChangeID
+	copy(tailID[:], tx.Bucket(ChangeLog).Get(ChangeLogTailID))
+	cc := modules.ConsensusChange{
+		ID:                         tailID,
+		AppliedBlocks:              []types.Block{pb.Block},
+		ChildTarget:                pb.ChildTarget,
+		MinimumValidChildTimestamp: cs.blockRuleHelper.minimumValidChildTimestamp(tx.Bucket(BlockMap), pb),
+		Synced:                     cs.synced,
+	}
+	var err error
+	cc.CoinOutputDiffs, cc.BlockStakeOutputDiffs, err = outputDiffs(tx.Bucket(CoinOutputs), tx.Bucket(BlockStakeOutputs))
+	if err != nil {
+		return modules.ConsensusChange{}, err
+	}
+	return cc, nil
+}
+
+// rpcSendBlockRange is the receiving end of the SendBlockRange RPC,
+// it sends the range of block heights this consensus set can send blocks for.
+func (cs *ConsensusSet) rpcSendBlockRange(conn modules.PeerConn) error {
+	err := cs.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer cs.tg.Done()
+
+	cs.mu.RLock()
+	br := blockRange{Lowest: cs.availableHeight()}
+	err = cs.db.View(func(tx kv.Tx) error {
+		br.Highest = blockHeight(tx)
+		return nil
+	})
+	cs.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return siabin.WriteObject(conn, br)
+}
+
+// managedReceiveBlockRange returns the calling end of the SendBlockRange RPC,
+// storing the block range of the peer in the given range.
+func managedReceiveBlockRange(br *blockRange) modules.RPCFunc {
+	return func(conn modules.PeerConn) error {
+		err := setConnDeadline(conn, sendBlocksTimeout)
+		if err != nil {
+			return err
+		}
+		return siabin.ReadObject(conn, br, 16)
+	}
+}
+
+// peerBlockRanges caches the block ranges of the peers for the duration of
+// the initial blockchain download, such that the SendBlockRange RPC is called
+// only once per peer, rather than for every batch of blocks to download.
+// It is only used by the IBD goroutine, and is not safe for concurrent use.
+type peerBlockRanges struct {
+	cs     *ConsensusSet
+	ranges map[modules.NetAddress]blockRange
+}
+
+// newPeerBlockRanges creates an empty block range cache.
+func newPeerBlockRanges(cs *ConsensusSet) *peerBlockRanges {
+	return &peerBlockRanges{
+		cs:     cs,
+		ranges: make(map[modules.NetAddress]blockRange),
+	}
+}
+
+// managedServesHeight returns true if the given peer can send the block at
+// the given height. The block range of the peer is only requested the first
+// time, peers that do not support the SendBlockRange RPC (or for which it
+// fails) are assumed to have all blocks available, rather than being excluded.
+func (pbr *peerBlockRanges) managedServesHeight(peer modules.NetAddress, height types.BlockHeight) bool {
+	br, ok := pbr.ranges[peer]
+	if !ok {
+		err := pbr.cs.gateway.RPC(peer, "SendBlockRange", managedReceiveBlockRange(&br))
+		if err != nil {
+			pbr.cs.log.Printf("WARN: failed to receive the block range of peer %v, assuming all blocks are available: %v", peer, err)
+			br = blockRange{}
+		}
+		pbr.ranges[peer] = br
+	}
+	if br.Lowest > height {
+		pbr.cs.log.Debugf("peer %v cannot send the block at height %d, as it only has blocks from height %d onwards", peer, height, br.Lowest)
+		return false
+	}
+	return true
+}
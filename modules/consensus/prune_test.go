@@ -0,0 +1,241 @@
+package consensus
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestEnablePruning probes the validation of the prune depth,
+// and the pruning of a consensus set which has no blocks to prune.
+func TestEnablePruning(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	minDepth := cst.cs.snapshotBlockWindow()
+	err = cst.cs.EnablePruning(minDepth - 1)
+	if !isErr(err, errPruneDepth) {
+		t.Fatalf("expected %v, got: %v", errPruneDepth, err)
+	}
+	err = cst.cs.EnablePruning(minDepth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst.cs.EnablePruning(minDepth)
+	if err != errPruningEnabled {
+		t.Fatalf("expected %v, got: %v", errPruningEnabled, err)
+	}
+
+	err = cst.cs.managedPruneBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height := cst.cs.PrunedHeight(); height != 0 {
+		t.Fatalf("unexpected pruned height: %d", height)
+	}
+}
+
+// TestPruneBlock probes the pruning of a single processed block,
+// of which only the header remains available.
+func TestPruneBlock(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	pb := &processedBlock{
+		Block: types.Block{
+			ParentID:  cst.cs.blockRoot.Block.ID(),
+			Timestamp: cst.cs.blockRoot.Block.Timestamp + 1,
+		},
+		Height: 1,
+	}
+	id := pb.Block.ID()
+	err = cst.cs.db.Update(func(tx kv.Tx) error {
+		addBlockMap(tx, pb)
+		if isBlockPruned(tx, id) {
+			t.Error("block reported as pruned before it was pruned")
+		}
+		return pruneBlock(tx, pb)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cst.cs.db.View(func(tx kv.Tx) error {
+		if !isBlockPruned(tx, id) {
+			t.Error("block not reported as pruned")
+		}
+		if _, err := getBlockMap(tx, id); err == nil {
+			t.Error("processed block still available after being pruned")
+		}
+		header, err := getBlockHeader(tx, id)
+		if err != nil {
+			return err
+		}
+		if header.ID() != id {
+			t.Errorf("unexpected header of pruned block: %v", header)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// mockGatewayBlockRange implements modules.Gateway to mock the
+// SendBlockRange RPC, counting the calls made for each peer.
+type mockGatewayBlockRange struct {
+	modules.Gateway
+	ranges map[modules.NetAddress]blockRange
+	calls  map[modules.NetAddress]int
+}
+
+// mockBlockRangeConn implements modules.PeerConn over a net.Pipe.
+type mockBlockRangeConn struct {
+	net.Conn
+}
+
+// RPCAddr implements this method of the modules.PeerConn interface.
+func (mockBlockRangeConn) RPCAddr() modules.NetAddress {
+	return "mockBlockRangeConn dialback addr"
+}
+
+// RPC is a mock implementation of modules.Gateway.RPC, which sends the
+// block range of the peer, or fails for peers without a block range.
+func (g *mockGatewayBlockRange) RPC(addr modules.NetAddress, name string, fn modules.RPCFunc) error {
+	g.calls[addr]++
+	br, ok := g.ranges[addr]
+	if !ok {
+		return errors.New("unsupported RPC")
+	}
+	p1, p2 := net.Pipe()
+	defer p1.Close()
+	go func() {
+		siabin.WriteObject(p2, br)
+		p2.Close()
+	}()
+	return fn(mockBlockRangeConn{p1})
+}
+
+// TestPeerBlockRanges probes the block range cache used during the IBD,
+// which should request the block range of each peer only once,
+// and should not exclude peers for which the request fails.
+func TestPeerBlockRanges(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	g := &mockGatewayBlockRange{
+		Gateway: cst.cs.gateway,
+		ranges: map[modules.NetAddress]blockRange{
+			"full":   {Lowest: 0, Highest: 100},
+			"pruned": {Lowest: 50, Highest: 100},
+		},
+		calls: make(map[modules.NetAddress]int),
+	}
+	cst.cs.gateway = g
+	defer func() {
+		cst.cs.gateway = g.Gateway
+	}()
+
+	ranges := newPeerBlockRanges(cst.cs)
+	tests := []struct {
+		peer   modules.NetAddress
+		height types.BlockHeight
+		serves bool
+	}{
+		{"full", 10, true},
+		{"pruned", 10, false},
+		{"pruned", 50, true},
+		{"failing", 10, true},
+		{"full", 60, true},
+		{"pruned", 49, false},
+		{"failing", 0, true},
+	}
+	for _, test := range tests {
+		if serves := ranges.managedServesHeight(test.peer, test.height); serves != test.serves {
+			t.Errorf("peer %v serves height %d: expected %v, got %v", test.peer, test.height, test.serves, serves)
+		}
+	}
+	for _, peer := range []modules.NetAddress{"full", "pruned", "failing"} {
+		if calls := g.calls[peer]; calls != 1 {
+			t.Errorf("expected the block range of peer %v to be requested once, got %d calls", peer, calls)
+		}
+	}
+}
+
+// TestCurrentStateChange probes the consensus change sent to subscribers
+// which start from the beginning, once blocks have been pruned.
+func TestCurrentStateChange(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	// the current state of a blank consensus set equals the state
+	// applied by the consensus change of its genesis block
+	var cc, genesisCC modules.ConsensusChange
+	err = cst.cs.db.View(func(tx kv.Tx) error {
+		var err error
+		cc, err = cst.cs.currentStateChange(tx)
+		if err != nil {
+			return err
+		}
+		genesisCC, err = cst.cs.computeConsensusChange(tx, cst.cs.initialEntry())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cc.ID != genesisCC.ID {
+		t.Errorf("unexpected consensus change ID: %v != %v", cc.ID, genesisCC.ID)
+	}
+	if len(cc.AppliedBlocks) != 1 || cc.AppliedBlocks[0].ID() != cst.cs.blockRoot.Block.ID() {
+		t.Errorf("unexpected applied blocks: %v", cc.AppliedBlocks)
+	}
+	if len(cc.CoinOutputDiffs) != len(genesisCC.CoinOutputDiffs) {
+		t.Errorf("unexpected amount of coin output diffs: %d != %d", len(cc.CoinOutputDiffs), len(genesisCC.CoinOutputDiffs))
+	}
+	if len(cc.BlockStakeOutputDiffs) != len(genesisCC.BlockStakeOutputDiffs) {
+		t.Errorf("unexpected amount of block stake output diffs: %d != %d", len(cc.BlockStakeOutputDiffs), len(genesisCC.BlockStakeOutputDiffs))
+	}
+	if cc.ChildTarget != genesisCC.ChildTarget {
+		t.Errorf("unexpected child target: %v != %v", cc.ChildTarget, genesisCC.ChildTarget)
+	}
+}
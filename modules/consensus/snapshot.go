@@ -0,0 +1,755 @@
+package consensus
+
+// snapshot.go implements consensus snapshots: the consensus state at a block
+// height, exported as a single file. A fresh consensus set can be bootstrapped
+// from such a snapshot, given that the checksum of the file is trusted, after
+// which it synchronizes with the network from the snapshot height onwards,
+// rather than having to download and apply the entire blockchain.
+//
+// A snapshot contains the raw key-value pairs of the consensus database
+// buckets required to continue from the snapshot height: the entire block
+// path, the unspent (delayed) coin and blockstake outputs, the plugin buckets,
+// the processed blocks of the most recent blocks and of the blocks which
+// created the unspent blockstake outputs, and the headers of the blocks in
+// between, as these are required to validate future (proof of blockstake)
+// blocks.
+//
+// A bootstrapped consensus set does not have the blocks prior to the snapshot
+// height available, and refuses to revert the snapshot block. Subscribers
+// (and plugins) that start from the beginning receive the snapshot block as
+// the first applied block, together with all outputs unspent at the snapshot
+// height as applied diffs.
+//
+// The plugin buckets are exported as-is, meaning that the checksum of a
+// snapshot depends on the plugins registered by the exporting node, as well as
+// on the consensus state itself. Trusted snapshots are therefore only
+// reproducible by nodes which run the same set of plugins, and should be
+// exported by the chain's operators using the plugins registered by the
+// chain's daemon. Plugins which are not part of the snapshot start from the
+// beginning when registered on a bootstrapped consensus set, receiving the
+// snapshot block as their first applied block.
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+var (
+	// SnapshotBlockWindow is the minimum amount of most recent blocks for
+	// which the processed block is included in a snapshot. Bootstrapped nodes
+	// (and their modules, such as the explorer) need these blocks to validate
+	// and process the blocks following the snapshot.
+	SnapshotBlockWindow = func() types.BlockHeight {
+		switch build.Release {
+		case "dev":
+			return 200
+		case "testing":
+			return 10
+		default:
+			if build.Release != "standard" {
+				build.Severe("unrecognized build.Release")
+			}
+			return 1000
+		}
+	}()
+)
+
+var (
+	// BucketSnapshot is a database bucket that only exists for consensus sets
+	// bootstrapped from a snapshot. It contains the metadata of the imported
+	// snapshot, as well as the outputs that were unspent at the snapshot
+	// height, which are sent to subscribers starting from the beginning.
+	BucketSnapshot = []byte("Snapshot")
+
+	snapshotMetadataKey             = []byte("Metadata")
+	snapshotCoinOutputsBucket       = []byte("CoinOutputs")
+	snapshotBlockStakeOutputsBucket = []byte("BlockStakeOutputs")
+
+	// snapshotRootBuckets are the consensus database (root) buckets
+	// which can be written to by a snapshot, besides the delayed coin output buckets.
+	snapshotRootBuckets = [][]byte{
+		BlockPath,
+		BlockMap,
+		BlockHeaders,
+		CoinOutputs,
+		BlockStakeOutputs,
+		CoinOutputHeights,
+		BlockStakeOutputHeights,
+		BucketPlugins,
+	}
+)
+
+var (
+	// ErrUntrustedSnapshot is returned in case a snapshot is imported,
+	// for which no trusted checksum is known, or for which the checksum does not match.
+	ErrUntrustedSnapshot = errors.New("snapshot checksum is not trusted")
+
+	errSnapshotNotFresh     = errors.New("snapshots can only be imported into an unused consensus set, which only contains the genesis block")
+	errSnapshotVersion      = errors.New("unsupported snapshot version")
+	errSnapshotInvalid      = errors.New("invalid snapshot")
+	errSnapshotBucket       = errors.New("snapshot contains a record for an unexpected bucket")
+	errSnapshotBlockMissing = errors.New("processed block required for the snapshot is not available")
+	errSnapshotFork         = errors.New("cannot revert the block the consensus set was bootstrapped from")
+)
+
+const (
+	// snapshotVersion is the version of the snapshot format.
+	snapshotVersion uint8 = 1
+
+	// stakeModifierBlockCount is the amount of blocks,
+	// prior to the stake modifier delay, used to calculate the stake modifier,
+	// as defined by the CalculateStakeModifier method.
+	stakeModifierBlockCount = 256
+)
+
+// the kinds of records of which a snapshot is composed
+const (
+	snapshotRecordEnd uint8 = iota
+	snapshotRecordBucket
+	snapshotRecordValue
+)
+
+type (
+	// snapshotHeader is the first object encoded in a snapshot,
+	// identifying the chain, network and block it was taken at.
+	snapshotHeader struct {
+		Version     uint8
+		ChainName   string
+		NetworkName string
+		GenesisID   types.BlockID
+		BlockHeight types.BlockHeight
+		BlockID     types.BlockID
+	}
+
+	// snapshotRecord is a single key-value pair of a database bucket,
+	// identified by its path starting from the root bucket.
+	// Bucket records (with no key or value) create the bucket,
+	// such that empty (nested) buckets are exported as well.
+	snapshotRecord struct {
+		Kind   uint8
+		Bucket [][]byte
+		Key    []byte
+		Value  []byte
+	}
+
+	// snapshotMetadata is stored in the snapshot bucket
+	// of a consensus set bootstrapped from a snapshot.
+	snapshotMetadata struct {
+		BlockHeight types.BlockHeight
+		BlockID     types.BlockID
+	}
+)
+
+// ExportSnapshot writes a snapshot of the consensus state at the current block
+// height to the given writer. Earlier block heights cannot be exported, as the
+// plugin buckets cannot be reverted to an earlier block height.
+func (cs *ConsensusSet) ExportSnapshot(w io.Writer) (modules.ConsensusSnapshot, error) {
+	err := cs.tg.Add()
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	defer cs.tg.Done()
+
+	hasher := crypto.NewHash()
+	bw := bufio.NewWriter(io.MultiWriter(w, hasher))
+	enc := rivbin.NewEncoder(bw)
+
+	var snapshot modules.ConsensusSnapshot
+	// a single (read-only) database transaction ensures the exported state is consistent,
+	// even if blocks are applied to the consensus set in the meantime
+	err = cs.db.View(func(tx kv.Tx) error {
+		snapshot.BlockHeight = blockHeight(tx)
+		snapshot.BlockID = currentBlockID(tx)
+		err := enc.Encode(snapshotHeader{
+			Version:     snapshotVersion,
+			ChainName:   cs.bcInfo.Name,
+			NetworkName: cs.bcInfo.NetworkName,
+			GenesisID:   cs.blockRoot.Block.ID(),
+			BlockHeight: snapshot.BlockHeight,
+			BlockID:     snapshot.BlockID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to (rivbin) encode snapshot header: %v", err)
+		}
+		return cs.exportSnapshotRecords(tx, enc, snapshot.BlockHeight)
+	})
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	copy(snapshot.Checksum[:], hasher.Sum(nil))
+	cs.log.Printf("Exported consensus snapshot at height %d (block %v) with checksum %v",
+		snapshot.BlockHeight, snapshot.BlockID, snapshot.Checksum)
+	return snapshot, nil
+}
+
+// exportSnapshotRecords encodes all records of a snapshot at the given (current) height.
+func (cs *ConsensusSet) exportSnapshotRecords(tx kv.Tx, enc *rivbin.Encoder, height types.BlockHeight) error {
+	// export the block path, the unspent outputs and the plugin buckets as-is,
+	// the latter containing the buckets of all plugins registered on this node
+	for _, name := range [][]byte{BlockPath, CoinOutputs, BlockStakeOutputs, BucketPlugins} {
+		err := exportSnapshotBucket(enc, tx.Bucket(name), [][]byte{name})
+		if err != nil {
+			return err
+		}
+	}
+	// export the confirmation heights of the unspent outputs,
+	// the heights of outputs that are already spent are not required
+	for _, names := range [][2][]byte{
+		{CoinOutputs, CoinOutputHeights},
+		{BlockStakeOutputs, BlockStakeOutputHeights},
+	} {
+		err := exportSnapshotOutputHeights(tx, enc, names[0], names[1])
+		if err != nil {
+			return err
+		}
+	}
+	// export all delayed coin output buckets, including the empty ones
+	err := tx.ForEach(func(name []byte, b kv.Bucket) error {
+		if !bytes.HasPrefix(name, prefixDCO) {
+			return nil
+		}
+		return exportSnapshotBucket(enc, b, [][]byte{name})
+	})
+	if err != nil {
+		return err
+	}
+
+	// export the processed blocks and headers required to validate future blocks,
+	// the genesis block is never exported, as it is known by all consensus sets
+	blockHeights, lowestHeight := cs.snapshotBlockHeights(tx, height)
+	if lowestHeight == 0 {
+		lowestHeight = 1
+	}
+	for h := lowestHeight; h <= height; h++ {
+		id, err := getPath(tx, h)
+		if err != nil {
+			return err
+		}
+		if _, ok := blockHeights[h]; ok {
+			pbBytes := tx.Bucket(BlockMap).Get(id[:])
+			if pbBytes == nil {
+				return fmt.Errorf("%v: block %v at height %d", errSnapshotBlockMissing, id, h)
+			}
+			err = enc.Encode(snapshotRecord{
+				Kind:   snapshotRecordValue,
+				Bucket: [][]byte{BlockMap},
+				Key:    id[:],
+				Value:  pbBytes,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to (rivbin) encode processed block snapshot record: %v", err)
+			}
+			continue
+		}
+		header, err := getBlockHeader(tx, id)
+		if err != nil {
+			return fmt.Errorf("failed to get header of block %v at height %d: %v", id, h, err)
+		}
+		entryBytes, err := siabin.Marshal(blockHeaderEntry{Header: header, Height: h})
+		if err != nil {
+			return fmt.Errorf("failed to (siabin) marshal block header: %v", err)
+		}
+		err = enc.Encode(snapshotRecord{
+			Kind:   snapshotRecordValue,
+			Bucket: [][]byte{BlockHeaders},
+			Key:    id[:],
+			Value:  entryBytes,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to (rivbin) encode block header snapshot record: %v", err)
+		}
+	}
+
+	return enc.Encode(snapshotRecord{Kind: snapshotRecordEnd})
+}
+
+// exportSnapshotOutputHeights encodes the (output height) bucket as records of a snapshot,
+// only containing the heights of the outputs found in the given (outputs) bucket.
+func exportSnapshotOutputHeights(tx kv.Tx, enc *rivbin.Encoder, outputs, heights []byte) error {
+	err := enc.Encode(snapshotRecord{
+		Kind:   snapshotRecordBucket,
+		Bucket: [][]byte{heights},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to (rivbin) encode bucket snapshot record: %v", err)
+	}
+	heightsBucket := tx.Bucket(heights)
+	return tx.Bucket(outputs).ForEach(func(k, _ []byte) error {
+		v := heightsBucket.Get(k)
+		if v == nil {
+			return fmt.Errorf("failed to export height of output %x: %v", k, errMissingOutputHeight)
+		}
+		err := enc.Encode(snapshotRecord{
+			Kind:   snapshotRecordValue,
+			Bucket: [][]byte{heights},
+			Key:    k,
+			Value:  v,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to (rivbin) encode value snapshot record: %v", err)
+		}
+		return nil
+	})
+}
+
+// exportSnapshotBucket encodes the given bucket, and all its key-value pairs
+// and nested buckets, as records of a snapshot.
+func exportSnapshotBucket(enc *rivbin.Encoder, b kv.Bucket, path [][]byte) error {
+	err := enc.Encode(snapshotRecord{
+		Kind:   snapshotRecordBucket,
+		Bucket: path,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to (rivbin) encode bucket snapshot record: %v", err)
+	}
+	return b.ForEach(func(k, v []byte) error {
+		if v == nil {
+			// nested bucket
+			nestedPath := make([][]byte, len(path)+1)
+			copy(nestedPath, path)
+			nestedPath[len(path)] = k
+			return exportSnapshotBucket(enc, b.Bucket(k), nestedPath)
+		}
+		err := enc.Encode(snapshotRecord{
+			Kind:   snapshotRecordValue,
+			Bucket: path,
+			Key:    k,
+			Value:  v,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to (rivbin) encode value snapshot record: %v", err)
+		}
+		return nil
+	})
+}
+
+// snapshotBlockHeights returns the heights of the blocks for which the
+// processed block has to be included in a snapshot at the given height,
+// as well as the lowest height from which block headers have to be included.
+//
+// Processed blocks are required for the most recent blocks, and for the blocks
+// which created the unspent blockstake outputs, as these are looked up in
+// order to validate the proof of blockstake of future blocks. The headers of
+// the blocks in between are required to walk back the chain from those future
+// blocks, which is also done in order to compute the stake modifier.
+func (cs *ConsensusSet) snapshotBlockHeights(tx kv.Tx, height types.BlockHeight) (map[types.BlockHeight]struct{}, types.BlockHeight) {
+	window := cs.snapshotBlockWindow()
+	var lowestHeight types.BlockHeight
+	if height > window {
+		lowestHeight = height - window
+	}
+	blockHeights := make(map[types.BlockHeight]struct{})
+	for h := lowestHeight; h <= height; h++ {
+		blockHeights[h] = struct{}{}
+	}
+	if stakeModifierWindow := cs.chainCts.StakeModifierDelay + stakeModifierBlockCount; height > stakeModifierWindow {
+		if h := height - stakeModifierWindow; h < lowestHeight {
+			lowestHeight = h
+		}
+	} else {
+		lowestHeight = 0
+	}
+
+	// collect the unspent blockstake outputs, other than those of the genesis block
+	unspentOutputs := make(map[types.BlockStakeOutputID]struct{})
+	err := tx.Bucket(BlockStakeOutputs).ForEach(func(k, _ []byte) error {
+		var id types.BlockStakeOutputID
+		copy(id[:], k)
+		unspentOutputs[id] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		build.Severe(err)
+	}
+	for _, txn := range cs.blockRoot.Block.Transactions {
+		for i := range txn.BlockStakeOutputs {
+			delete(unspentOutputs, txn.BlockStakeOutputID(uint64(i)))
+		}
+	}
+	// walk back the current path until all blocks which created them are found
+	for h := height; h > 0 && len(unspentOutputs) > 0; h-- {
+		id, err := getPath(tx, h)
+		if err != nil {
+			build.Severe(err)
+		}
+		pb, err := getBlockMap(tx, id)
+		if err != nil {
+			// the block is not available, which can only be the case for
+			// a block prior to the snapshot this consensus set was bootstrapped from,
+			// in which case it cannot have created any unspent blockstake output
+			continue
+		}
+		for _, txn := range pb.Block.Transactions {
+			for i := range txn.BlockStakeOutputs {
+				id := txn.BlockStakeOutputID(uint64(i))
+				if _, ok := unspentOutputs[id]; !ok {
+					continue
+				}
+				delete(unspentOutputs, id)
+				blockHeights[h] = struct{}{}
+				if h < lowestHeight {
+					lowestHeight = h
+				}
+			}
+		}
+	}
+	return blockHeights, lowestHeight
+}
+
+// snapshotBlockWindow returns the amount of most recent blocks,
+// for which the processed block has to be included in a snapshot.
+func (cs *ConsensusSet) snapshotBlockWindow() types.BlockHeight {
+	window := SnapshotBlockWindow
+	for _, w := range []types.BlockHeight{
+		cs.chainCts.TargetWindow,
+		types.BlockHeight(cs.chainCts.MedianTimestampWindow),
+		cs.chainCts.MaturityDelay,
+	} {
+		if w > window {
+			window = w
+		}
+	}
+	return window
+}
+
+// ImportSnapshot bootstraps the consensus set from the snapshot read from the
+// given reader. The checksum of the snapshot has to be listed in the given
+// trusted checksums, for the block height the snapshot was taken at.
+//
+// A snapshot can only be imported into a consensus set which only contains the
+// genesis block, prior to registering any plugins or subscribers, and prior to
+// starting the consensus set. The import is atomic: in case of an error
+// (including an untrusted checksum) the consensus set remains unchanged.
+func (cs *ConsensusSet) ImportSnapshot(r io.Reader, trustedChecksums map[types.BlockHeight]crypto.Hash) (modules.ConsensusSnapshot, error) {
+	err := cs.tg.Add()
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	defer cs.tg.Done()
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if len(cs.plugins) != 0 || len(cs.subscribers) != 0 || cs.snapshotBlockID != (types.BlockID{}) {
+		return modules.ConsensusSnapshot{}, errSnapshotNotFresh
+	}
+
+	// all bytes read are hashed, such that the checksum covers the entire snapshot
+	hasher := crypto.NewHash()
+	br := bufio.NewReader(io.TeeReader(r, hasher))
+	dec := rivbin.NewDecoder(br)
+
+	var header snapshotHeader
+	err = dec.Decode(&header)
+	if err != nil {
+		return modules.ConsensusSnapshot{}, fmt.Errorf("failed to (rivbin) decode snapshot header: %v", err)
+	}
+	if header.Version != snapshotVersion {
+		return modules.ConsensusSnapshot{}, fmt.Errorf("%v: %d", errSnapshotVersion, header.Version)
+	}
+	if header.ChainName != cs.bcInfo.Name || header.NetworkName != cs.bcInfo.NetworkName {
+		return modules.ConsensusSnapshot{}, fmt.Errorf(
+			"%v: snapshot of %s (%s) cannot be used for %s (%s)", errSnapshotInvalid,
+			header.ChainName, header.NetworkName, cs.bcInfo.Name, cs.bcInfo.NetworkName)
+	}
+	if header.GenesisID != cs.blockRoot.Block.ID() {
+		return modules.ConsensusSnapshot{}, fmt.Errorf("%v: snapshot has a different genesis block", errSnapshotInvalid)
+	}
+	trustedChecksum, ok := trustedChecksums[header.BlockHeight]
+	if !ok {
+		return modules.ConsensusSnapshot{}, fmt.Errorf("%v: no checksum known for height %d", ErrUntrustedSnapshot, header.BlockHeight)
+	}
+
+	snapshot := modules.ConsensusSnapshot{
+		BlockHeight: header.BlockHeight,
+		BlockID:     header.BlockID,
+	}
+	err = cs.db.Update(func(tx kv.Tx) error {
+		if blockHeight(tx) != 0 {
+			return errSnapshotNotFresh
+		}
+		err := clearSnapshotBuckets(tx)
+		if err != nil {
+			return err
+		}
+		err = importSnapshotRecords(tx, dec)
+		if err != nil {
+			return err
+		}
+		// consume any remaining bytes, as to verify the checksum of the entire snapshot
+		_, err = io.Copy(ioutil.Discard, br)
+		if err != nil {
+			return err
+		}
+		copy(snapshot.Checksum[:], hasher.Sum(nil))
+		if snapshot.Checksum != trustedChecksum {
+			return fmt.Errorf("%v: checksum %v does not match trusted checksum %v",
+				ErrUntrustedSnapshot, snapshot.Checksum, trustedChecksum)
+		}
+		return cs.finalizeSnapshotImport(tx, header)
+	})
+	if err != nil {
+		return modules.ConsensusSnapshot{}, err
+	}
+	cs.snapshotHeight = header.BlockHeight
+	cs.snapshotBlockID = header.BlockID
+	cs.log.Printf("Bootstrapped consensus set from snapshot at height %d (block %v) with checksum %v",
+		snapshot.BlockHeight, snapshot.BlockID, snapshot.Checksum)
+	return snapshot, nil
+}
+
+// clearSnapshotBuckets clears the consensus database buckets
+// which are (re)populated by a snapshot import.
+func clearSnapshotBuckets(tx kv.Tx) error {
+	var dcoBuckets [][]byte
+	err := tx.ForEach(func(name []byte, _ kv.Bucket) error {
+		if bytes.HasPrefix(name, prefixDCO) {
+			dcoBuckets = append(dcoBuckets, append([]byte(nil), name...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range dcoBuckets {
+		err = tx.DeleteBucket(name)
+		if err != nil {
+			return err
+		}
+	}
+	for _, name := range [][]byte{BlockPath, CoinOutputs, BlockStakeOutputs, CoinOutputHeights, BlockStakeOutputHeights,
+		SpentCoinOutputHeights, SpentBlockStakeOutputHeights, BucketPlugins} {
+		err = tx.DeleteBucket(name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket(name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importSnapshotRecords decodes all records of a snapshot,
+// storing them in the consensus database.
+func importSnapshotRecords(tx kv.Tx, dec *rivbin.Decoder) error {
+	for {
+		var record snapshotRecord
+		err := dec.Decode(&record)
+		if err != nil {
+			return fmt.Errorf("failed to (rivbin) decode snapshot record: %v", err)
+		}
+		if record.Kind == snapshotRecordEnd {
+			return nil
+		}
+		if len(record.Bucket) == 0 || !isSnapshotRootBucket(record.Bucket[0]) {
+			return errSnapshotBucket
+		}
+		bucket, err := tx.CreateBucketIfNotExists(record.Bucket[0])
+		if err != nil {
+			return err
+		}
+		for _, name := range record.Bucket[1:] {
+			bucket, err = bucket.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		switch record.Kind {
+		case snapshotRecordBucket:
+		case snapshotRecordValue:
+			err = bucket.Put(record.Key, record.Value)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%v: unknown record kind %d", errSnapshotInvalid, record.Kind)
+		}
+	}
+}
+
+// isSnapshotRootBucket returns true if the given root bucket can be written to by a snapshot.
+func isSnapshotRootBucket(name []byte) bool {
+	if bytes.HasPrefix(name, prefixDCO) {
+		return true
+	}
+	for _, rootBucket := range snapshotRootBuckets {
+		if bytes.Equal(name, rootBucket) {
+			return true
+		}
+	}
+	return false
+}
+
+// finalizeSnapshotImport validates the imported snapshot state, and updates the
+// block height, the change log and the plugin metadata to start at the snapshot block.
+func (cs *ConsensusSet) finalizeSnapshotImport(tx kv.Tx, header snapshotHeader) error {
+	heightBytes, err := siabin.Marshal(header.BlockHeight)
+	if err != nil {
+		return fmt.Errorf("failed to (siabin) marshal block height: %v", err)
+	}
+	err = tx.Bucket(BlockHeight).Put(BlockHeight, heightBytes)
+	if err != nil {
+		return err
+	}
+	genesisID, err := getPath(tx, 0)
+	if err != nil || genesisID != cs.blockRoot.Block.ID() {
+		return fmt.Errorf("%v: snapshot path does not start at the genesis block", errSnapshotInvalid)
+	}
+	blockID, err := getPath(tx, header.BlockHeight)
+	if err != nil || blockID != header.BlockID {
+		return fmt.Errorf("%v: snapshot path does not end at the snapshot block", errSnapshotInvalid)
+	}
+	pb, err := getBlockMap(tx, header.BlockID)
+	if err != nil || pb.Height != header.BlockHeight {
+		return fmt.Errorf("%v: snapshot block is missing", errSnapshotInvalid)
+	}
+
+	// store the snapshot metadata and the unspent outputs at the snapshot height
+	snapshotBucket, err := tx.CreateBucket(BucketSnapshot)
+	if err != nil {
+		return err
+	}
+	metadataBytes, err := siabin.Marshal(snapshotMetadata{
+		BlockHeight: header.BlockHeight,
+		BlockID:     header.BlockID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to (siabin) marshal snapshot metadata: %v", err)
+	}
+	err = snapshotBucket.Put(snapshotMetadataKey, metadataBytes)
+	if err != nil {
+		return err
+	}
+	for _, names := range [][2][]byte{
+		{CoinOutputs, snapshotCoinOutputsBucket},
+		{BlockStakeOutputs, snapshotBlockStakeOutputsBucket},
+	} {
+		outputsBucket, err := snapshotBucket.CreateBucket(names[1])
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket(names[0]).ForEach(func(k, v []byte) error {
+			return outputsBucket.Put(k, v)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// the snapshot block becomes the first entry of the change log
+	entry := changeEntry{AppliedBlocks: []types.BlockID{header.BlockID}}
+	err = tx.DeleteBucket(ChangeLog)
+	if err != nil {
+		return err
+	}
+	err = createChangeLog(tx, entry)
+	if err != nil {
+		return err
+	}
+
+	// plugins exported as part of the snapshot continue from the snapshot block
+	metadataBucket := tx.Bucket(BucketPlugins).Bucket(bucketPluginsMetadata)
+	if metadataBucket == nil {
+		return nil
+	}
+	var names [][]byte
+	err = metadataBucket.ForEach(func(k, _ []byte) error {
+		names = append(names, append([]byte(nil), k...))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		var metadata pluginMetadata
+		err = rivbin.Unmarshal(metadataBucket.Get(name), &metadata)
+		if err != nil {
+			return fmt.Errorf("failed to (rivbin) unmarshal metadata of plugin %s: %v", string(name), err)
+		}
+		metadata.ConsensusChangeID = entry.ID()
+		metadataBytes, err := rivbin.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("failed to (rivbin) marshal metadata of plugin %s: %v", string(name), err)
+		}
+		err = metadataBucket.Put(name, metadataBytes)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSnapshotMetadata loads the metadata of the snapshot the consensus set was
+// bootstrapped from, if any.
+func (cs *ConsensusSet) loadSnapshotMetadata(tx kv.Tx) error {
+	snapshotBucket := tx.Bucket(BucketSnapshot)
+	if snapshotBucket == nil {
+		return nil
+	}
+	var metadata snapshotMetadata
+	err := siabin.Unmarshal(snapshotBucket.Get(snapshotMetadataKey), &metadata)
+	if err != nil {
+		return fmt.Errorf("failed to (siabin) unmarshal snapshot metadata: %v", err)
+	}
+	cs.snapshotHeight = metadata.BlockHeight
+	cs.snapshotBlockID = metadata.BlockID
+	return nil
+}
+
+// snapshotOutputDiffs returns the outputs which were unspent at the height of
+// the snapshot the consensus set was bootstrapped from, as applied diffs.
+func snapshotOutputDiffs(tx kv.Tx) (coinOutputDiffs []modules.CoinOutputDiff, blockStakeOutputDiffs []modules.BlockStakeOutputDiff, err error) {
+	snapshotBucket := tx.Bucket(BucketSnapshot)
+	if snapshotBucket == nil {
+		return nil, nil, errNilBucket
+	}
+	return outputDiffs(snapshotBucket.Bucket(snapshotCoinOutputsBucket), snapshotBucket.Bucket(snapshotBlockStakeOutputsBucket))
+}
+
+// outputDiffs returns all outputs stored in the given buckets as applied diffs.
+func outputDiffs(coinOutputs, blockStakeOutputs kv.Bucket) (coinOutputDiffs []modules.CoinOutputDiff, blockStakeOutputDiffs []modules.BlockStakeOutputDiff, err error) {
+	err = coinOutputs.ForEach(func(k, v []byte) error {
+		diff := modules.CoinOutputDiff{Direction: modules.DiffApply}
+		copy(diff.ID[:], k)
+		err := siabin.Unmarshal(v, &diff.CoinOutput)
+		if err != nil {
+			return err
+		}
+		coinOutputDiffs = append(coinOutputDiffs, diff)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	err = blockStakeOutputs.ForEach(func(k, v []byte) error {
+		diff := modules.BlockStakeOutputDiff{Direction: modules.DiffApply}
+		copy(diff.ID[:], k)
+		err := siabin.Unmarshal(v, &diff.BlockStakeOutput)
+		if err != nil {
+			return err
+		}
+		blockStakeOutputDiffs = append(blockStakeOutputDiffs, diff)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return coinOutputDiffs, blockStakeOutputDiffs, nil
+}
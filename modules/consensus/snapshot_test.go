@@ -0,0 +1,110 @@
+package consensus
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestSnapshotExportImport probes the export of a consensus snapshot,
+// and the import of it into a fresh consensus set.
+func TestSnapshotExportImport(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	var buf bytes.Buffer
+	snapshot, err := cst.cs.ExportSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.BlockHeight != cst.cs.Height() || snapshot.BlockID != cst.cs.CurrentBlock().ID() {
+		t.Fatalf("unexpected snapshot: %v", snapshot)
+	}
+	if checksum := crypto.HashBytes(buf.Bytes()); checksum != snapshot.Checksum {
+		t.Fatalf("snapshot checksum %v does not match the checksum of the exported bytes %v", snapshot.Checksum, checksum)
+	}
+	data := buf.Bytes()
+
+	importTester, err := blankConsensusSetTester(t.Name() + "Import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		importTester.cs.Close()
+		importTester.gateway.Close()
+	}()
+
+	// an untrusted snapshot cannot be imported
+	_, err = importTester.cs.ImportSnapshot(bytes.NewReader(data), nil)
+	if !isErr(err, ErrUntrustedSnapshot) {
+		t.Fatalf("expected untrusted snapshot error, got: %v", err)
+	}
+	trustedChecksums := map[types.BlockHeight]crypto.Hash{
+		snapshot.BlockHeight: snapshot.Checksum,
+	}
+	// a modified snapshot doesn't match the trusted checksum
+	tampered := make([]byte, len(data))
+	copy(tampered, data)
+	tampered[len(tampered)-1]++
+	_, err = importTester.cs.ImportSnapshot(bytes.NewReader(tampered), trustedChecksums)
+	if err == nil {
+		t.Fatal("expected an error when importing a modified snapshot")
+	}
+
+	imported, err := importTester.cs.ImportSnapshot(bytes.NewReader(data), trustedChecksums)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported != snapshot {
+		t.Fatalf("imported snapshot %v does not match exported snapshot %v", imported, snapshot)
+	}
+	if importTester.cs.CurrentBlock().ID() != snapshot.BlockID {
+		t.Fatal("consensus set is not at the block of the imported snapshot")
+	}
+	for _, bucket := range [][]byte{CoinOutputs, BlockStakeOutputs, CoinOutputHeights, BlockStakeOutputHeights} {
+		if a, b := bucketChecksum(t, cst.cs, bucket), bucketChecksum(t, importTester.cs, bucket); a != b {
+			t.Fatalf("%s bucket of the imported set does not match the original set", bucket)
+		}
+	}
+
+	// a snapshot can only be imported once
+	_, err = importTester.cs.ImportSnapshot(bytes.NewReader(data), trustedChecksums)
+	if err != errSnapshotNotFresh {
+		t.Fatalf("expected %v, got: %v", errSnapshotNotFresh, err)
+	}
+}
+
+// bucketChecksum computes a checksum of all key-value pairs of the given root bucket.
+func bucketChecksum(t *testing.T, cs *ConsensusSet, name []byte) crypto.Hash {
+	h := crypto.NewHash()
+	err := cs.db.View(func(tx kv.Tx) error {
+		return tx.Bucket(name).ForEach(func(k, v []byte) error {
+			h.Write(k)
+			h.Write(v)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checksum crypto.Hash
+	copy(checksum[:], h.Sum(nil))
+	return checksum
+}
+
+func isErr(err, target error) bool {
+	return err != nil && (err == target || strings.Contains(err.Error(), target.Error()))
+}
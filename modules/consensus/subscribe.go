@@ -3,19 +3,23 @@ package consensus
 import (
 	"errors"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
 )
 
 // computeConsensusChange computes the consensus change from the change entry
 // at index 'i' in the change log. If i is out of bounds, an error is returned.
-func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (modules.ConsensusChange, error) {
+func (cs *ConsensusSet) computeConsensusChange(tx kv.Tx, ce changeEntry) (modules.ConsensusChange, error) {
 	cc := modules.ConsensusChange{
 		ID: ce.ID(),
 	}
 	for _, revertedBlockID := range ce.RevertedBlocks {
 		revertedBlock, err := getBlockMap(tx, revertedBlockID)
 		if err != nil {
+			if isBlockPruned(tx, revertedBlockID) {
+				return modules.ConsensusChange{}, modules.ErrBlockPruned
+			}
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
 			return modules.ConsensusChange{}, err
 		}
@@ -37,11 +41,26 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 	for _, appliedBlockID := range ce.AppliedBlocks {
 		appliedBlock, err := getBlockMap(tx, appliedBlockID)
 		if err != nil {
+			if isBlockPruned(tx, appliedBlockID) {
+				return modules.ConsensusChange{}, modules.ErrBlockPruned
+			}
 			cs.log.Critical("getBlockMap failed in computeConsensusChange:", err)
 			return modules.ConsensusChange{}, err
 		}
 
 		cc.AppliedBlocks = append(cc.AppliedBlocks, appliedBlock.Block)
+		if appliedBlockID == cs.snapshotBlockID {
+			// Special case: the snapshot block applies all outputs that were
+			// unspent at the snapshot height, as the blocks prior to it
+			// are not available.
+			coinOutputDiffs, blockStakeOutputDiffs, err := snapshotOutputDiffs(tx)
+			if err != nil {
+				return modules.ConsensusChange{}, err
+			}
+			cc.CoinOutputDiffs = append(cc.CoinOutputDiffs, coinOutputDiffs...)
+			cc.BlockStakeOutputDiffs = append(cc.BlockStakeOutputDiffs, blockStakeOutputDiffs...)
+			continue
+		}
 		cc.CoinOutputDiffs = append(cc.CoinOutputDiffs, appliedBlock.CoinOutputDiffs...)
 		cc.BlockStakeOutputDiffs = append(cc.BlockStakeOutputDiffs, appliedBlock.BlockStakeOutputDiffs...)
 	}
@@ -68,7 +87,7 @@ func (cs *ConsensusSet) computeConsensusChange(tx *bolt.Tx, ce changeEntry) (mod
 func (cs *ConsensusSet) readlockUpdateSubscribers(ce changeEntry) {
 	// Get the consensus change and send it to all subscribers.
 	var cc modules.ConsensusChange
-	err := cs.db.View(func(tx *bolt.Tx) error {
+	err := cs.db.View(func(tx kv.Tx) error {
 		// Compute the consensus change so it can be sent to subscribers.
 		var err error
 		cc, err = cs.computeConsensusChange(tx, ce)
@@ -89,18 +108,28 @@ func (cs *ConsensusSet) readlockUpdateSubscribers(ce changeEntry) {
 // As a special case, using an empty id as the start will have all the changes
 // sent to the modules starting with the genesis block.
 func (cs *ConsensusSet) initializeSubscribe(subscriber modules.ConsensusSetSubscriber, start modules.ConsensusChangeID, cancel <-chan struct{}) error {
-	return cs.db.View(func(tx *bolt.Tx) error {
+	return cs.db.View(func(tx kv.Tx) error {
 		// 'exists' and 'entry' are going to be pointed to the first entry that
 		// has not yet been seen by subscriber.
 		var exists bool
 		var entry changeEntry
 
-		if start == modules.ConsensusChangeBeginning {
+		if start == modules.ConsensusChangeBeginning && cs.prunedHeight > cs.snapshotHeight {
+			// Special case: the blocks required to compute all consensus
+			// changes have been pruned, the subscriber receives the
+			// current state as a single consensus change instead.
+			cc, err := cs.currentStateChange(tx)
+			if err != nil {
+				return err
+			}
+			subscriber.ProcessConsensusChange(cc)
+			return nil
+		} else if start == modules.ConsensusChangeBeginning {
 			// Special case: for modules.ConsensusChangeBeginning, create an
-			// initial node pointing to the genesis block. The subscriber will
-			// receive the diffs for all blocks in the consensus set, including
-			// the genesis block.
-			entry = cs.genesisEntry()
+			// initial node pointing to the genesis block (or snapshot block).
+			// The subscriber will receive the diffs for all blocks in the
+			// consensus set, including the genesis block (or snapshot block).
+			entry = cs.initialEntry()
 			exists = true
 		} else if start == modules.ConsensusChangeRecent {
 			// Special case: for modules.ConsensusChangeRecent, set up the
@@ -123,6 +152,12 @@ func (cs *ConsensusSet) initializeSubscribe(subscriber modules.ConsensusSetSubsc
 				return modules.ErrInvalidConsensusChangeID
 			}
 			entry, exists = entry.NextEntry(tx)
+			if exists && cs.prunedHeight > 0 && !entryAvailable(tx, entry) {
+				// The consensus changes since the provided change can not
+				// be computed, as some of its blocks have been pruned,
+				// requiring the subscriber to perform a rescan instead.
+				return modules.ErrInvalidConsensusChangeID
+			}
 		}
 
 		// Send all remaining consensus changes to the subscriber.
@@ -190,3 +225,18 @@ func (cs *ConsensusSet) Unsubscribe(subscriber modules.ConsensusSetSubscriber) {
 		}
 	}
 }
+
+// entryAvailable returns true if the processed blocks of the given change entry,
+// and of all entries that follow it, are available.
+func entryAvailable(tx kv.Tx, entry changeEntry) bool {
+	for exists := true; exists; entry, exists = entry.NextEntry(tx) {
+		for _, ids := range [][]types.BlockID{entry.RevertedBlocks, entry.AppliedBlocks} {
+			for _, id := range ids {
+				if isBlockPruned(tx, id) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
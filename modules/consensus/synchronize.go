@@ -2,14 +2,15 @@ package consensus
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"time"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -90,7 +91,7 @@ func isTimeoutErr(err error) bool {
 // to find a common parent that is reasonably recent, usually the most recent
 // common parent is found, but always a common parent within a factor of 2 is
 // found.
-func blockHistory(tx *bolt.Tx) (blockIDs [32]types.BlockID) {
+func blockHistory(tx kv.Tx) (blockIDs [32]types.BlockID) {
 	height := blockHeight(tx)
 	step := types.BlockHeight(1)
 	// The final step is to include the genesis block, which is why the final
@@ -132,13 +133,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 	// Set a deadline after which SendBlocks will timeout. During IBD, esepcially,
 	// SendBlocks will timeout. This is by design so that IBD switches peers to
 	// prevent any one peer from stalling IBD.
-	err := conn.SetDeadline(time.Now().Add(sendBlocksTimeout))
-	// Ignore errors returned by SetDeadline if the conn is a pipe in testing.
-	// Pipes do not support Set{,Read,Write}Deadline and should only be used in
-	// testing.
-	if opErr, ok := err.(*net.OpError); ok && opErr.Op == "set" && opErr.Net == "pipe" && build.Release == "testing" {
-		err = nil
-	}
+	err := setConnDeadline(conn, sendBlocksTimeout)
 	if err != nil {
 		return err
 	}
@@ -152,7 +147,7 @@ func (cs *ConsensusSet) managedReceiveBlocks(conn modules.PeerConn) (returnErr e
 	// Get blockIDs to send.
 	var history [32]types.BlockID
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
+	err = cs.db.View(func(tx kv.Tx) error {
 		history = blockHistory(tx)
 		return nil
 	})
@@ -258,38 +253,26 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 	}
 
 	// Find the most recent block from knownBlocks in the current path.
-	found := false
-	var start types.BlockHeight
-	var csHeight types.BlockHeight
+	var (
+		found     bool
+		start     types.BlockHeight
+		available types.BlockHeight
+	)
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
-		csHeight = blockHeight(tx)
-		for _, id := range knownBlocks {
-			pb, err := getBlockMap(tx, id)
-			if err != nil {
-				continue
-			}
-			pathID, err := getPath(tx, pb.Height)
-			if err != nil {
-				continue
-			}
-			if pathID != pb.Block.ID() {
-				continue
-			}
-			if pb.Height == csHeight {
-				break
-			}
-			found = true
-			// Start from the child of the common block.
-			start = pb.Height + 1
-			break
-		}
+	available = cs.availableHeight()
+	err = cs.db.View(func(tx kv.Tx) error {
+		start, found = findCommonBlockHeight(tx, knownBlocks)
 		return nil
 	})
 	cs.mu.RUnlock()
 	if err != nil {
 		return err
 	}
+	// The caller can not be served if it requires blocks which have been pruned.
+	if found && start < available {
+		return fmt.Errorf("cannot send blocks from height %d, only blocks from height %d are available: %v",
+			start, available, modules.ErrBlockPruned)
+	}
 
 	// If no matching blocks are found, or if the caller has all known blocks,
 	// don't send any blocks.
@@ -309,7 +292,7 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 		// Get the set of blocks to send.
 		var blocks []types.Block
 		cs.mu.RLock()
-		err = cs.db.View(func(tx *bolt.Tx) error {
+		err = cs.db.View(func(tx kv.Tx) error {
 			height := blockHeight(tx)
 			for i := start; i <= height && i < start+MaxCatchUpBlocks; i++ {
 				id, err := getPath(tx, i)
@@ -318,6 +301,10 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 				}
 				pb, err := getBlockMap(tx, id)
 				if err != nil {
+					if isBlockPruned(tx, id) {
+						// the block got pruned since the start height was checked
+						return fmt.Errorf("cannot send block at height %d: %v", i, modules.ErrBlockPruned)
+					}
 					build.Severe(err)
 				}
 				blocks = append(blocks, pb.Block)
@@ -344,6 +331,33 @@ func (cs *ConsensusSet) rpcSendBlocks(conn modules.PeerConn) error {
 	return nil
 }
 
+// findCommonBlockHeight finds the most recent block from the given known blocks
+// which is part of the current path. The height of the child of that block is returned,
+// and false is returned in case no such block is found, or the caller has all known blocks.
+// Pruned blocks are found as well, as only their header is required to find their height.
+func findCommonBlockHeight(tx kv.Tx, knownBlocks [32]types.BlockID) (start types.BlockHeight, found bool) {
+	csHeight := blockHeight(tx)
+	for _, id := range knownBlocks {
+		_, height, err := getBlockHeaderHeight(tx, id)
+		if err != nil {
+			continue
+		}
+		pathID, err := getPath(tx, height)
+		if err != nil {
+			continue
+		}
+		if pathID != id {
+			continue
+		}
+		if height == csHeight {
+			break
+		}
+		// Start from the child of the common block.
+		return height + 1, true
+	}
+	return 0, false
+}
+
 // threadedRPCRelayHeader is an RPC that accepts a block header from a peer.
 func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 	err := cs.tg.Add()
@@ -367,9 +381,9 @@ func (cs *ConsensusSet) threadedRPCRelayHeader(conn modules.PeerConn) error {
 
 	// Start verification inside of a bolt View tx.
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
+	err = cs.db.View(func(tx kv.Tx) error {
 		// Do some relatively inexpensive checks to validate the header
-		return cs.validateHeader(boltTxWrapper{tx}, h)
+		return cs.validateHeader(kvTxWrapper{tx}, h)
 	})
 	cs.mu.RUnlock()
 	if err == errOrphan {
@@ -434,9 +448,12 @@ func (cs *ConsensusSet) rpcSendBlk(conn modules.PeerConn) error {
 	// Lookup the corresponding block.
 	var b types.Block
 	cs.mu.RLock()
-	err = cs.db.View(func(tx *bolt.Tx) error {
+	err = cs.db.View(func(tx kv.Tx) error {
 		pb, err := getBlockMap(tx, id)
 		if err != nil {
+			if isBlockPruned(tx, id) {
+				return fmt.Errorf("cannot send block %v: %v", id, modules.ErrBlockPruned)
+			}
 			return err
 		}
 		b = pb.Block
@@ -477,7 +494,8 @@ func (cs *ConsensusSet) managedReceiveBlock(id types.BlockID) modules.RPCFunc {
 }
 
 // threadedInitialBlockchainDownload performs the IBD on outbound peers. Blocks
-// are downloaded from one peer at a time in 5 minute intervals, so as to
+// are downloaded headers-first, in parallel from several peers, see headersfirst.go.
+// Remaining blocks are downloaded from one peer at a time in 5 minute intervals, so as to
 // prevent any one peer from significantly slowing down IBD.
 //
 // NOTE: IBD will succeed right now when each peer has a different blockchain.
@@ -497,7 +515,7 @@ func (cs *ConsensusSet) threadedInitialBlockchainDownload() error {
 
 	// keep track of our initial block height
 	getHeight := func() (height types.BlockHeight) {
-		_ = cs.db.View(func(tx *bolt.Tx) error {
+		_ = cs.db.View(func(tx kv.Tx) error {
 			height = blockHeight(tx)
 			return nil
 		})
@@ -506,7 +524,39 @@ func (cs *ConsensusSet) threadedInitialBlockchainDownload() error {
 	height := getHeight()
 	lastReceiveTime := time.Now()
 
+	// headers-first IBD is used as long as it doesn't fail too often,
+	// in which case we fall back to the legacy IBD only
+	headersFirst := true
+	// the block ranges of the peers are requested only once during the IBD
+	ranges := newPeerBlockRanges(cs)
+
 	for {
+		// First try to download the blockchain headers-first, downloading block bodies
+		// in parallel from several outbound peers. The SendBlocks RPC is still used afterwards,
+		// to download any remaining blocks and to determine whether or not we are synced.
+		if headersFirst {
+			var outboundPeers []modules.Peer
+			for _, p := range cs.gateway.Peers() {
+				if !p.Inbound {
+					outboundPeers = append(outboundPeers, p)
+				}
+			}
+			if len(outboundPeers) > 0 {
+				err := func() error {
+					err := cs.tg.Add()
+					if err != nil {
+						return err
+					}
+					defer cs.tg.Done()
+					return cs.managedHeadersFirstDownload(outboundPeers, ranges)
+				}()
+				if err != nil {
+					cs.log.Printf("WARN: headers-first IBD failed, falling back to legacy IBD: %v", err)
+					headersFirst = false
+				}
+			}
+		}
+
 		numOutboundSynced = 0
 		numOutboundNotSynced = 0
 		for _, p := range cs.gateway.Peers() {
@@ -525,6 +575,11 @@ func (cs *ConsensusSet) threadedInitialBlockchainDownload() error {
 				}
 				defer cs.tg.Done()
 
+				// Skip peers which have pruned the blocks we still require.
+				if !ranges.managedServesHeight(p.NetAddress, getHeight()+1) {
+					return nil
+				}
+
 				// Request blocks from the peer. The error returned will only be
 				// 'nil' if there are no more blocks to receive.
 				err = cs.gateway.RPC(p.NetAddress, "SendBlocks", cs.managedReceiveBlocks)
@@ -46,6 +46,22 @@ func StandardTransactionVersionMappedValidators() map[types.TransactionVersion][
 			ValidateBlockStakeOutputsAreBalanced,
 			ValidateMinerFeeIsPresent,
 		},
+		types.TransactionVersionTwo: []modules.TransactionValidationFunction{
+			ValidateCoinOutputsAreBalanced,
+			ValidateBlockStakeOutputsAreBalanced,
+			ValidateMinerFeeIsPresent,
+		},
+		types.TransactionVersionThree: []modules.TransactionValidationFunction{
+			ValidateCoinOutputsAreBalanced,
+			ValidateBlockStakeOutputsAreBalanced,
+			ValidateMinerFeeIsPresent,
+			ValidateTransactionIsNotExpired,
+		},
+		types.TransactionVersionFour: []modules.TransactionValidationFunction{
+			ValidateCoinOutputsAreBalanced,
+			ValidateBlockStakeOutputsAreBalanced,
+			ValidateMinerFeeIsPresent,
+		},
 	}
 }
 
@@ -160,6 +176,12 @@ func ValidateMinerFeeIsPresent(tx modules.ConsensusTransaction, ctx types.Transa
 	return nil
 }
 
+// ValidateTransactionIsNotExpired is a validator function that checks
+// that the transaction has not yet expired at the height it is validated at.
+func ValidateTransactionIsNotExpired(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext) error {
+	return tx.ValidateValidUntil(ctx.BlockHeight)
+}
+
 // ValidateMinerFeesAreValid is a validator function that checks if all miner fees are valid,
 // meaning their (coin) value is individually greater than zero.
 func ValidateMinerFeesAreValid(tx modules.ConsensusTransaction, ctx types.TransactionValidationContext) error {
@@ -215,10 +237,12 @@ func ValidateCoinInputsAreFulfilled(tx modules.ConsensusTransaction, ctx types.T
 		}
 		// check if the referenced output's condition has been fulfilled
 		err := co.Condition.Fulfill(ci.Fulfillment, types.FulfillContext{
-			ExtraObjects: []interface{}{uint64(index)},
-			BlockHeight:  ctx.BlockHeight,
-			BlockTime:    ctx.BlockTime,
-			Transaction:  tx.Transaction,
+			ExtraObjects:       []interface{}{uint64(index)},
+			BlockHeight:        ctx.BlockHeight,
+			BlockTime:          ctx.BlockTime,
+			ConfirmationHeight: tx.SpentCoinOutputHeights[ci.ParentID],
+			Transaction:        tx.Transaction,
+			ParentID:           types.OutputID(ci.ParentID),
 		})
 		if err != nil {
 			return err
@@ -276,10 +300,12 @@ func ValidateBlockStakeInputsAreFulfilled(tx modules.ConsensusTransaction, ctx t
 		}
 		// check if the referenced output's condition has been fulfilled
 		err = bso.Condition.Fulfill(bsi.Fulfillment, types.FulfillContext{
-			ExtraObjects: []interface{}{uint64(index)},
-			BlockHeight:  ctx.BlockHeight,
-			BlockTime:    ctx.BlockTime,
-			Transaction:  tx.Transaction,
+			ExtraObjects:       []interface{}{uint64(index)},
+			BlockHeight:        ctx.BlockHeight,
+			BlockTime:          ctx.BlockTime,
+			ConfirmationHeight: tx.SpentBlockStakeOutputHeights[bsi.ParentID],
+			Transaction:        tx.Transaction,
+			ParentID:           types.OutputID(bsi.ParentID),
 		})
 		if err != nil {
 			return err
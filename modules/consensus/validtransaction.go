@@ -4,14 +4,14 @@ import (
 	"errors"
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/types"
 )
 
 // validTransaction checks that all fields are valid within the current
 // consensus state. If not an error is returned.
-func (cs *ConsensusSet) validTransaction(tx *bolt.Tx, t modules.ConsensusTransaction, constants types.TransactionValidationConstants, blockHeight types.BlockHeight, blockTimestamp types.Timestamp, isBlockCreatingTx bool) error {
+func (cs *ConsensusSet) validTransaction(tx kv.Tx, t modules.ConsensusTransaction, constants types.TransactionValidationConstants, blockHeight types.BlockHeight, blockTimestamp types.Timestamp, isBlockCreatingTx bool) error {
 	ctx := types.TransactionValidationContext{
 		ValidationContext: types.ValidationContext{
 			Confirmed:         true,
@@ -25,9 +25,8 @@ func (cs *ConsensusSet) validTransaction(tx *bolt.Tx, t modules.ConsensusTransac
 	}
 
 	// return the first error reported by a validator
-	var err error
-
 	// check if we have stand alone validators specific for this tx version, if so apply them
+	var err error
 	if validators, ok := cs.txVersionMappedValidators[t.Version]; ok {
 		for _, validator := range validators {
 			err = validator(t, ctx)
@@ -75,7 +74,7 @@ func (cs *ConsensusSet) TryTransactionSet(txns []types.Transaction) (modules.Con
 	// manually manage the tx instead of using 'Update', but that has safety
 	// concerns and is more difficult to implement correctly.
 	errSuccess := errors.New("success")
-	err = cs.db.Update(func(tx *bolt.Tx) error {
+	err = cs.db.Update(func(tx kv.Tx) error {
 		diffHolder.Height = blockHeight(tx)
 		blockTime, err := blockTimeStamp(tx, diffHolder.Height)
 		if err != nil {
@@ -90,25 +89,43 @@ func (cs *ConsensusSet) TryTransactionSet(txns []types.Transaction) (modules.Con
 				SequenceID:             uint16(idx),
 				SpentCoinOutputs:       make(map[types.CoinOutputID]types.CoinOutput),
 				SpentBlockStakeOutputs: make(map[types.BlockStakeOutputID]types.BlockStakeOutput),
+
+				SpentCoinOutputHeights:       make(map[types.CoinOutputID]types.BlockHeight),
+				SpentBlockStakeOutputHeights: make(map[types.BlockStakeOutputID]types.BlockHeight),
 			}
 			for _, ci := range txn.CoinInputs {
 				cTxn.SpentCoinOutputs[ci.ParentID], err = getCoinOutput(tx, ci.ParentID)
 				if err != nil {
 					return fmt.Errorf("failed to find coin input %s from txn %s as unspent coin output in the consensus state: %v", ci.ParentID.String(), txn.ID().String(), err)
 				}
+				cTxn.SpentCoinOutputHeights[ci.ParentID], err = getCoinOutputHeight(tx, ci.ParentID)
+				if err != nil {
+					return fmt.Errorf("failed to find the confirmation height of coin input %s from txn %s: %v", ci.ParentID.String(), txn.ID().String(), err)
+				}
 			}
 			for _, bsi := range txn.BlockStakeInputs {
 				cTxn.SpentBlockStakeOutputs[bsi.ParentID], err = getBlockStakeOutput(tx, bsi.ParentID)
 				if err != nil {
 					return fmt.Errorf("failed to find block stake input %s from txn %s as unspent block stake output in the consensus state: %v", bsi.ParentID.String(), txn.ID().String(), err)
 				}
+				cTxn.SpentBlockStakeOutputHeights[bsi.ParentID], err = getBlockStakeOutputHeight(tx, bsi.ParentID)
+				if err != nil {
+					return fmt.Errorf("failed to find the confirmation height of block stake input %s from txn %s: %v", bsi.ParentID.String(), txn.ID().String(), err)
+				}
+			}
+
+			// ensure the transaction version and all of its unlock condition and fulfillment types
+			// are active at the height of the next block, the first block the transaction can be part of
+			err = cs.chainCts.ActivationSchedule.ValidateTransaction(txn, diffHolder.Height+1)
+			if err != nil {
+				return err
 			}
 
 			// a transaction can only be "block creating" in the context of a block,
 			// which we don't have here, so just pass in false for the "isBlockCreatingTx"
 			// argument. In other words, a block creating transaction can never be part
 			// of a transaction pool and must be inserted when the block is actually created
-			err := cs.validTransaction(tx, cTxn, types.TransactionValidationConstants{
+			err = cs.validTransaction(tx, cTxn, types.TransactionValidationConstants{
 				BlockSizeLimit:         cs.chainCts.BlockSizeLimit,
 				ArbitraryDataSizeLimit: cs.chainCts.ArbitraryDataSizeLimit,
 				MinimumMinerFee:        cs.chainCts.MinimumTransactionFee,
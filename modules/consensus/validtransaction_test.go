@@ -1,5 +1,49 @@
 package consensus
 
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+// TestTryTransactionSetActivation probes that TryTransactionSet validates the activation
+// of a transaction at the height of the next block, the first block the transaction can be part of.
+func TestTryTransactionSetActivation(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cst, err := blankConsensusSetTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cst.cs.Close()
+		cst.gateway.Close()
+	}()
+
+	txn := types.Transaction{
+		Version: cst.cs.chainCts.DefaultTransactionVersion,
+		CoinOutputs: []types.CoinOutput{{
+			Value:     types.NewCurrency64(1),
+			Condition: types.NewCondition(types.NewTimeLockCondition(42, &types.NilCondition{})),
+		}},
+	}
+	// the current block is the genesis block, and thus the next block is at height 1
+	cst.cs.chainCts.ActivationSchedule.ConditionTypes = map[types.ConditionType]types.BlockHeight{
+		types.ConditionTypeTimeLock: 2,
+	}
+	_, err = cst.cs.TryTransactionSet([]types.Transaction{txn})
+	if err != types.ErrConditionTypeNotActivated {
+		t.Fatalf("expected %v, got: %v", types.ErrConditionTypeNotActivated, err)
+	}
+	// once activated at the height of the next block, the transaction is refused for other reasons only
+	cst.cs.chainCts.ActivationSchedule.ConditionTypes[types.ConditionTypeTimeLock] = 1
+	_, err = cst.cs.TryTransactionSet([]types.Transaction{txn})
+	if err == types.ErrConditionTypeNotActivated {
+		t.Fatal("expected the condition type to be activated at the height of the next block")
+	}
+}
+
 // TODO:
 // enable and fix
 /*
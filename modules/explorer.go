@@ -64,6 +64,9 @@ type (
 	// DaemonConstants represent the constants in use by the daemon
 	DaemonConstants struct {
 		ChainInfo types.BlockchainInfo `json:"chaininfo"`
+		// ChainID is derived from the ChainInfo and the genesis block ID,
+		// and is committed to by the signatures of TransactionVersionTwo transactions.
+		ChainID types.ChainID `json:"chainid"`
 
 		// ConsensusPlugins are the plugins loaded in the consensus set module of this daemon
 		ConsensusPlugins []string `json:"consensusplugins"`
@@ -93,6 +96,10 @@ type (
 		OneCoin types.Currency `json:"onecoin"`
 
 		DefaultTransactionVersion types.TransactionVersion `json:"deftransactionversion"`
+
+		// UnlockHashEncoding is the format used to represent unlock hashes as strings,
+		// the bech32 encoding uses the AddressPrefix of the ChainInfo as human-readable part.
+		UnlockHashEncoding types.UnlockHashEncoding `json:"unlockhashencoding"`
 	}
 
 	// Explorer tracks the blockchain and provides tools for gathering
@@ -128,6 +135,10 @@ type (
 		// input id.
 		CoinOutput(types.CoinOutputID) (types.CoinOutput, bool)
 
+		// CoinOutputAsset returns the ID of the asset held by the coin output
+		// associated with the input id, the zero ID in case it holds the native coin.
+		CoinOutputAsset(types.CoinOutputID) types.AssetID
+
 		// CoinOutputID returns all of the transaction ids associated with
 		// the provided coin output id.
 		CoinOutputID(types.CoinOutputID) []types.TransactionID
@@ -182,6 +193,7 @@ func NewChainStats(size int) *ChainStats {
 func NewDaemonConstants(info types.BlockchainInfo, constants types.ChainConstants, consensusPlugins []string) DaemonConstants {
 	return DaemonConstants{
 		ChainInfo: info,
+		ChainID:   info.ChainID(constants.GenesisBlockID()),
 
 		ConsensusPlugins: consensusPlugins,
 
@@ -210,5 +222,7 @@ func NewDaemonConstants(info types.BlockchainInfo, constants types.ChainConstant
 		OneCoin: constants.CurrencyUnits.OneCoin,
 
 		DefaultTransactionVersion: constants.DefaultTransactionVersion,
+
+		UnlockHashEncoding: constants.UnlockHashEncoding,
 	}
 }
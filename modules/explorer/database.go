@@ -4,7 +4,7 @@ import (
 	"errors"
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -27,6 +27,9 @@ var (
 	// used to map (single-signature) wallet addresses to all the
 	// multisig addresses they are part of
 	bucketWalletAddressToMultiSigAddressMapping = []byte("WalletAddressToMultiSigAddressMapping")
+	// used to map coin outputs to the asset they hold,
+	// coin outputs holding the native coin are not stored
+	bucketCoinOutputAssets = []byte("CoinOutputAssets")
 
 	errNotExist = errors.New("entry does not exist")
 
@@ -35,7 +38,7 @@ var (
 	internalRecentChange = []byte("RecentChange")
 )
 
-// These functions all return a 'func(*bolt.Tx) error', which, allows them to
+// These functions all return a 'func(kv.Tx) error', which, allows them to
 // be called concisely with the db.View and db.Update functions, e.g.:
 //
 //    var height types.BlockHeight
@@ -44,18 +47,18 @@ var (
 // Instead of:
 //
 //   var height types.BlockHeight
-//   db.View(func(tx *bolt.Tx) error {
+//   db.View(func(tx kv.Tx) error {
 //       idBytes, err := siabin.Marshal(id)
 //       if err != nil { return err }
 //       bytes := tx.Bucket(bucketBlockIDs).Get()
 //       return siabin.Unmarshal(bytes, &height)
 //   })
 
-// dbGetAndDecode returns a 'func(*bolt.Tx) error' that retrieves and decodes
+// dbGetAndDecode returns a 'func(kv.Tx) error' that retrieves and decodes
 // a value from the specified bucket. If the value does not exist,
 // dbGetAndDecode returns errNotExist.
-func dbGetAndDecode(bucket []byte, key, val interface{}) func(*bolt.Tx) error {
-	return func(tx *bolt.Tx) error {
+func dbGetAndDecode(bucket []byte, key, val interface{}) func(kv.Tx) error {
+	return func(tx kv.Tx) error {
 		keyBytes, err := siabin.Marshal(key)
 		if err != nil {
 			return fmt.Errorf("failed to (siabin) marshal key bytes: %v", err)
@@ -68,11 +71,11 @@ func dbGetAndDecode(bucket []byte, key, val interface{}) func(*bolt.Tx) error {
 	}
 }
 
-// dbGetTransactionIDSet returns a 'func(*bolt.Tx) error' that decodes a
+// dbGetTransactionIDSet returns a 'func(kv.Tx) error' that decodes a
 // bucket of transaction IDs into a slice. If the bucket is nil,
 // dbGetTransactionIDSet returns errNotExist.
-func dbGetTransactionIDSet(bucket []byte, key interface{}, ids *[]types.TransactionID) func(*bolt.Tx) error {
-	return func(tx *bolt.Tx) error {
+func dbGetTransactionIDSet(bucket []byte, key interface{}, ids *[]types.TransactionID) func(kv.Tx) error {
+	return func(tx kv.Tx) error {
 		keyBytes, err := siabin.Marshal(key)
 		if err != nil {
 			return fmt.Errorf("failed to (siabin) marshal key bytes: %v", err)
@@ -101,10 +104,10 @@ func dbGetTransactionIDSet(bucket []byte, key interface{}, ids *[]types.Transact
 	}
 }
 
-// dbGetBlockFacts returns a 'func(*bolt.Tx) error' that decodes
+// dbGetBlockFacts returns a 'func(kv.Tx) error' that decodes
 // the block facts for `height` into blockfacts
-func (e *Explorer) dbGetBlockFacts(height types.BlockHeight, bf *blockFacts) func(*bolt.Tx) error {
-	return func(tx *bolt.Tx) error {
+func (e *Explorer) dbGetBlockFacts(height types.BlockHeight, bf *blockFacts) func(kv.Tx) error {
+	return func(tx kv.Tx) error {
 		block, exists := e.cs.BlockAtHeight(height)
 		if !exists {
 			return errors.New("requested block facts for a block that does not exist")
@@ -114,8 +117,8 @@ func (e *Explorer) dbGetBlockFacts(height types.BlockHeight, bf *blockFacts) fun
 }
 
 // dbSetInternal sets the specified key of bucketInternal to the encoded value.
-func dbSetInternal(key []byte, val interface{}) func(*bolt.Tx) error {
-	return func(tx *bolt.Tx) error {
+func dbSetInternal(key []byte, val interface{}) func(kv.Tx) error {
+	return func(tx kv.Tx) error {
 		valBytes, err := siabin.Marshal(val)
 		if err != nil {
 			return fmt.Errorf("failed to (siabin) marshal value: %v", err)
@@ -125,8 +128,8 @@ func dbSetInternal(key []byte, val interface{}) func(*bolt.Tx) error {
 }
 
 // dbGetInternal decodes the specified key of bucketInternal into the supplied pointer.
-func dbGetInternal(key []byte, val interface{}) func(*bolt.Tx) error {
-	return func(tx *bolt.Tx) error {
+func dbGetInternal(key []byte, val interface{}) func(kv.Tx) error {
+	return func(tx kv.Tx) error {
 		return siabin.Unmarshal(tx.Bucket(bucketInternal).Get(key), val)
 	}
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/types"
 )
 
@@ -37,7 +38,8 @@ type (
 	// including various statistics and metrics.
 	Explorer struct {
 		cs             modules.ConsensusSet
-		db             *persist.BoltDatabase
+		db             *persist.Database
+		dbBackend      kv.Backend
 		log            *persist.Logger
 		persistDir     string
 		bcInfo         types.BlockchainInfo
@@ -49,8 +51,15 @@ type (
 )
 
 // New creates the internal data structures, and subscribes to
-// consensus for changes to the blockchain
+// consensus for changes to the blockchain.
+// The database is stored using the default (bolt) storage backend.
 func New(cs modules.ConsensusSet, persistDir string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, verboseLogging bool) (*Explorer, error) {
+	return NewWithBackend(cs, persistDir, bcInfo, chainCts, verboseLogging, kv.BackendBolt)
+}
+
+// NewWithBackend creates a new explorer, the same way as New does,
+// storing its database using the given storage backend.
+func NewWithBackend(cs modules.ConsensusSet, persistDir string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, verboseLogging bool, dbBackend kv.Backend) (*Explorer, error) {
 	// Check that input modules are non-nil
 	if cs == nil {
 		return nil, errNilCS
@@ -61,6 +70,7 @@ func New(cs modules.ConsensusSet, persistDir string, bcInfo types.BlockchainInfo
 	e := &Explorer{
 		cs:             cs,
 		persistDir:     persistDir,
+		dbBackend:      dbBackend,
 		bcInfo:         bcInfo,
 		chainCts:       chainCts,
 		rootTarget:     chainCts.RootTarget(),
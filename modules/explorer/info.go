@@ -4,9 +4,9 @@ import (
 	"errors"
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -43,7 +43,7 @@ func (e *Explorer) BlockFacts(height types.BlockHeight) (modules.BlockFacts, boo
 // at the latest block height in the explorer's consensus set.
 func (e *Explorer) LatestBlockFacts() modules.BlockFacts {
 	var bf blockFacts
-	err := e.db.View(func(tx *bolt.Tx) error {
+	err := e.db.View(func(tx kv.Tx) error {
 		var height types.BlockHeight
 		err := dbGetInternal(internalBlockHeight, &height)(tx)
 		if err != nil {
@@ -87,10 +87,10 @@ func (e *Explorer) UnlockHash(uh types.UnlockHash) []types.TransactionID {
 
 // MultiSigAddresses returns all multisig addresses this wallet address is involved in.
 func (e *Explorer) MultiSigAddresses(uh types.UnlockHash) (uhs []types.UnlockHash) {
-	if uh.Type != types.UnlockTypePubKey {
+	if !uh.Type.IsPubKey() {
 		return nil
 	}
-	err := e.db.View(func(tx *bolt.Tx) error {
+	err := e.db.View(func(tx kv.Tx) error {
 		uhb, err := siabin.Marshal(uh)
 		if err != nil {
 			return fmt.Errorf("failed to siabin marshal uh: %v", err)
@@ -125,6 +125,17 @@ func (e *Explorer) CoinOutput(id types.CoinOutputID) (types.CoinOutput, bool) {
 	return sco, true
 }
 
+// CoinOutputAsset returns the ID of the asset held by the coin output associated with the specified ID,
+// the zero ID in case it holds the native coin.
+func (e *Explorer) CoinOutputAsset(id types.CoinOutputID) types.AssetID {
+	var assetID types.AssetID
+	err := e.db.View(dbGetAndDecode(bucketCoinOutputAssets, id, &assetID))
+	if err != nil {
+		return types.AssetID{}
+	}
+	return assetID
+}
+
 // CoinOutputID returns all of the transactions that contain the specified
 // coin output ID. An empty set indicates that the siacoin output ID does
 // not appear in the blockchain.
@@ -166,7 +177,7 @@ func (e *Explorer) HistoryStats(history types.BlockHeight) (*modules.ChainStats,
 	}
 	// Get the current height
 	var height types.BlockHeight
-	err := e.db.View(func(tx *bolt.Tx) error {
+	err := e.db.View(func(tx kv.Tx) error {
 		return dbGetInternal(internalBlockHeight, &height)(tx)
 	})
 	if err != nil {
@@ -187,7 +198,7 @@ func (e *Explorer) RangeStats(start types.BlockHeight, end types.BlockHeight) (*
 	}
 	// Get the current height
 	var height types.BlockHeight
-	err := e.db.View(func(tx *bolt.Tx) error {
+	err := e.db.View(func(tx kv.Tx) error {
 		return dbGetInternal(internalBlockHeight, &height)(tx)
 	})
 	if err != nil || height < start {
@@ -202,7 +213,7 @@ func (e *Explorer) RangeStats(start types.BlockHeight, end types.BlockHeight) (*
 // getRangeStats fills in some stats from the blockfacts and the actual blocks in a specified range
 func (e *Explorer) getStats(start types.BlockHeight, end types.BlockHeight) (*modules.ChainStats, error) {
 	stats := modules.NewChainStats(int(end-start) + 1)
-	err := e.db.View(func(tx *bolt.Tx) error {
+	err := e.db.View(func(tx kv.Tx) error {
 		for height := start; height <= end; height++ {
 
 			// Load the block from the consensus set first so we have the ID, this saves a DB call to the
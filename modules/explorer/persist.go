@@ -9,7 +9,7 @@ import (
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 var explorerMetadata = persist.Metadata{
@@ -34,7 +34,7 @@ func (e *Explorer) initPersist(verbose bool) error {
 
 	// Open the database
 	dbFilPath := filepath.Join(e.persistDir, "explorer.db")
-	db, err := persist.OpenDatabase(explorerMetadata, dbFilPath)
+	db, err := persist.OpenDatabaseWithBackend(explorerMetadata, dbFilPath, e.dbBackend)
 	if err != nil {
 		if err != persist.ErrBadVersion {
 			return err
@@ -47,7 +47,7 @@ func (e *Explorer) initPersist(verbose bool) error {
 	e.db = db
 
 	// Initialize the database
-	err = e.db.Update(func(tx *bolt.Tx) error {
+	err = e.db.Update(func(tx kv.Tx) error {
 		buckets := [][]byte{
 			bucketBlockFacts,
 			bucketBlockIDs,
@@ -56,6 +56,7 @@ func (e *Explorer) initPersist(verbose bool) error {
 			bucketInternal,
 			bucketCoinOutputIDs,
 			bucketCoinOutputs,
+			bucketCoinOutputAssets,
 			bucketBlockStakeOutputIDs,
 			bucketBlockStakeOutputs,
 			bucketTransactionIDs,
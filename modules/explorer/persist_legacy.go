@@ -8,10 +8,10 @@ import (
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
-func (e *Explorer) convertLegacyDatabase(filePath string) (db *persist.BoltDatabase, err error) {
+func (e *Explorer) convertLegacyDatabase(filePath string) (db *persist.Database, err error) {
 	var legacyExplorerMetadata = persist.Metadata{
 		Header:  "Sia Explorer",
 		Version: "1.0.5",
@@ -25,7 +25,7 @@ func (e *Explorer) convertLegacyDatabase(filePath string) (db *persist.BoltDatab
 		return
 	}
 
-	err = db.Update(func(tx *bolt.Tx) error {
+	err = db.Update(func(tx kv.Tx) error {
 		// delete old outputID->condition mapping bucket
 		tx.DeleteBucket([]byte("parentIDUnlockHashMapping")) // ignore errors though
 
@@ -99,7 +99,7 @@ func (e *Explorer) convertLegacyDatabase(filePath string) (db *persist.BoltDatab
 	})
 	// If a bucket exist error is thrown its because we used to have a software version which ran this migration but did not update
 	// the database metadata
-	if err == nil || err == bolt.ErrBucketExists {
+	if err == nil || err == kv.ErrBucketExists {
 		// set the new metadata, and save it,
 		// such that next time we have the new version stored
 		db.Header, db.Version = explorerMetadata.Header, explorerMetadata.Version
@@ -117,7 +117,7 @@ func (e *Explorer) convertLegacyDatabase(filePath string) (db *persist.BoltDatab
 // convert052Database converts a 0.5.2 explorer database,
 // to a database of the current version as defined by explorerMetadata.
 // It keeps the database open and returns it for further usage.
-func convert052Database(filePath string) (db *persist.BoltDatabase, err error) {
+func convert052Database(filePath string) (db *persist.Database, err error) {
 	var legacyExplorerMetadata = persist.Metadata{
 		Header:  "Sia Explorer",
 		Version: "0.5.2",
@@ -127,7 +127,7 @@ func convert052Database(filePath string) (db *persist.BoltDatabase, err error) {
 		return
 	}
 
-	err = db.Update(func(tx *bolt.Tx) error {
+	err = db.Update(func(tx kv.Tx) error {
 		if bucket := tx.Bucket(bucketCoinOutputs); bucket != nil {
 			if err := updateLegacyCoinOutputBucket(bucket); err != nil {
 				return err
@@ -153,7 +153,7 @@ func convert052Database(filePath string) (db *persist.BoltDatabase, err error) {
 	return
 }
 
-func updateLegacyCoinOutputBucket(bucket *bolt.Bucket) error {
+func updateLegacyCoinOutputBucket(bucket kv.Bucket) error {
 	var (
 		err    error
 		cursor = bucket.Cursor()
@@ -188,7 +188,7 @@ func updateLegacyCoinOutputBucket(bucket *bolt.Bucket) error {
 	return nil
 }
 
-func updateLegacyBlockstakeOutputBucket(bucket *bolt.Bucket) error {
+func updateLegacyBlockstakeOutputBucket(bucket kv.Bucket) error {
 	var (
 		err    error
 		cursor = bucket.Cursor()
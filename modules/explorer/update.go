@@ -3,9 +3,9 @@ package explorer
 import (
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -17,7 +17,7 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 		build.Critical("Explorer.ProcessConsensusChange called with a ConsensusChange that has no AppliedBlocks")
 	}
 
-	err := e.db.Update(func(tx *bolt.Tx) (err error) {
+	err := e.db.Update(func(tx kv.Tx) (err error) {
 		// use exception-style error handling to enable more concise update code
 		defer func() {
 			if r := recover(); r != nil {
@@ -66,10 +66,14 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 						build.Severe(err)
 					}
 				}
+				exData, _ := txn.CommonExtensionData()
 				for k, sco := range txn.CoinOutputs {
 					scoid := txn.CoinOutputID(uint64(k))
 					dbRemoveCoinOutputID(tx, scoid, txid)
 					dbRemoveCoinOutput(tx, scoid)
+					if !exData.CoinOutputAsset(k).IsNative() {
+						dbRemoveCoinOutputAsset(tx, scoid)
+					}
 					unmapUnlockConditionHash(tx, sco.Condition, txid)
 				}
 				for _, sfi := range txn.BlockStakeInputs {
@@ -86,7 +90,6 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 				}
 
 				// remove any common extension data, should the txn have it
-				exData, _ := txn.CommonExtensionData()
 				for _, condition := range exData.UnlockConditions {
 					unmapUnlockConditionHash(tx, condition, txid)
 				}
@@ -107,6 +110,17 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 				continue
 			}
 
+			// special handling for the snapshot block, which is applied instead of
+			// the genesis block for consensus sets bootstrapped from a snapshot
+			snapshotBlock := blockheight == 0 && dbGetAndDecode(bucketBlockFacts, e.genesisBlockID, new(blockFacts))(tx) != nil
+			if snapshotBlock {
+				height, exists := e.cs.BlockHeightOfBlock(block)
+				if !exists {
+					build.Critical("consensus is missing snapshot block", bid)
+				}
+				blockheight = height - 1
+			}
+
 			blockheight++
 			dbAddBlockID(tx, bid, blockheight)
 			dbAddTransactionID(tx, tbid, blockheight) // Miner payouts are a transaction
@@ -136,10 +150,14 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 				txid := txn.ID()
 				dbAddTransactionID(tx, txid, blockheight)
 
+				exData, _ := txn.CommonExtensionData()
 				for j, sco := range txn.CoinOutputs {
 					scoid := txn.CoinOutputID(uint64(j))
 					dbAddCoinOutputID(tx, scoid, txid)
 					dbAddCoinOutput(tx, scoid, sco)
+					if assetID := exData.CoinOutputAsset(j); !assetID.IsNative() {
+						dbAddCoinOutputAsset(tx, scoid, assetID)
+					}
 					mapUnlockConditionHash(tx, sco.Condition, txid)
 				}
 				for _, sci := range txn.CoinInputs {
@@ -168,7 +186,6 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 				}
 
 				// add any common extension data, should the txn have it
-				exData, _ := txn.CommonExtensionData()
 				for _, condition := range exData.UnlockConditions {
 					mapUnlockConditionHash(tx, condition, txid)
 				}
@@ -182,6 +199,8 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 			if tx.Bucket(bucketBlockFacts).Get(blockParentIDBytes) != nil {
 				facts := e.dbCalculateBlockFacts(tx, block)
 				dbAddBlockFacts(tx, facts)
+			} else if snapshotBlock {
+				e.dbAddSnapshotBlock(tx, block, blockheight, target, cc)
 			}
 		}
 
@@ -231,7 +250,7 @@ func (e *Explorer) ProcessConsensusChange(cc modules.ConsensusChange) {
 	}
 }
 
-func (e *Explorer) dbCalculateBlockFacts(tx *bolt.Tx, block types.Block) blockFacts {
+func (e *Explorer) dbCalculateBlockFacts(tx kv.Tx, block types.Block) blockFacts {
 	// get the parent block facts
 	var bf blockFacts
 	err := dbGetAndDecode(bucketBlockFacts, block.ParentID, &bf)(tx)
@@ -305,7 +324,7 @@ func (e *Explorer) dbCalculateBlockFacts(tx *bolt.Tx, block types.Block) blockFa
 }
 
 // Special handling for the genesis block. No other functions are called on it.
-func (e *Explorer) dbAddGenesisBlock(tx *bolt.Tx) {
+func (e *Explorer) dbAddGenesisBlock(tx kv.Tx) {
 	id := e.genesisBlockID
 	dbAddBlockID(tx, id, 0)
 	txid := e.genesisBlock.Transactions[0].ID()
@@ -337,6 +356,28 @@ func (e *Explorer) dbAddGenesisBlock(tx *bolt.Tx) {
 	})
 }
 
+// Special handling for the snapshot block of a consensus set bootstrapped from a snapshot.
+// The outputs unspent at the snapshot height are added, and the block facts start
+// counting from the snapshot block, as the blocks prior to it are unknown.
+func (e *Explorer) dbAddSnapshotBlock(tx kv.Tx, block types.Block, height types.BlockHeight, target types.Target, cc modules.ConsensusChange) {
+	for _, diff := range cc.CoinOutputDiffs {
+		dbAddCoinOutput(tx, diff.ID, diff.CoinOutput)
+	}
+	for _, diff := range cc.BlockStakeOutputDiffs {
+		dbAddBlockStakeOutput(tx, diff.ID, diff.BlockStakeOutput)
+	}
+	dbAddBlockFacts(tx, blockFacts{
+		BlockFacts: modules.BlockFacts{
+			BlockID:    block.ID(),
+			Height:     height,
+			Difficulty: target.Difficulty(e.chainCts.RootDepth),
+			Target:     target,
+			TotalCoins: types.NewCurrency64(0), //TODO rivine
+		},
+		Timestamp: block.Timestamp,
+	})
+}
+
 // helper functions
 func assertNil(err error) {
 	if err != nil {
@@ -348,16 +389,16 @@ func assertSiaMarshal(val interface{}) []byte {
 	assertNil(err)
 	return b
 }
-func mustPut(bucket *bolt.Bucket, key, val interface{}) {
+func mustPut(bucket kv.Bucket, key, val interface{}) {
 	assertNil(bucket.Put(assertSiaMarshal(key), assertSiaMarshal(val)))
 }
-func mustPutSet(bucket *bolt.Bucket, key interface{}) {
+func mustPutSet(bucket kv.Bucket, key interface{}) {
 	assertNil(bucket.Put(assertSiaMarshal(key), nil))
 }
-func mustDelete(bucket *bolt.Bucket, key interface{}) {
+func mustDelete(bucket kv.Bucket, key interface{}) {
 	assertNil(bucket.Delete(assertSiaMarshal(key)))
 }
-func bucketIsEmpty(bucket *bolt.Bucket) bool {
+func bucketIsEmpty(bucket kv.Bucket) bool {
 	k, _ := bucket.Cursor().First()
 	return k == nil
 }
@@ -366,45 +407,53 @@ func bucketIsEmpty(bucket *bolt.Bucket) bool {
 // ProcessConsensusChange.
 
 // Add/Remove block ID
-func dbAddBlockID(tx *bolt.Tx, id types.BlockID, height types.BlockHeight) {
+func dbAddBlockID(tx kv.Tx, id types.BlockID, height types.BlockHeight) {
 	mustPut(tx.Bucket(bucketBlockIDs), id, height)
 }
-func dbRemoveBlockID(tx *bolt.Tx, id types.BlockID) {
+func dbRemoveBlockID(tx kv.Tx, id types.BlockID) {
 	mustDelete(tx.Bucket(bucketBlockIDs), id)
 }
 
 // Add/Remove block facts
-func dbAddBlockFacts(tx *bolt.Tx, facts blockFacts) {
+func dbAddBlockFacts(tx kv.Tx, facts blockFacts) {
 	mustPut(tx.Bucket(bucketBlockFacts), facts.BlockID, facts)
 }
-func dbRemoveBlockFacts(tx *bolt.Tx, id types.BlockID) {
+func dbRemoveBlockFacts(tx kv.Tx, id types.BlockID) {
 	mustDelete(tx.Bucket(bucketBlockFacts), id)
 }
 
 // Add/Remove block target
-func dbAddBlockTarget(tx *bolt.Tx, id types.BlockID, target types.Target) {
+func dbAddBlockTarget(tx kv.Tx, id types.BlockID, target types.Target) {
 	mustPut(tx.Bucket(bucketBlockTargets), id, target)
 }
-func dbRemoveBlockTarget(tx *bolt.Tx, id types.BlockID, target types.Target) {
+func dbRemoveBlockTarget(tx kv.Tx, id types.BlockID, target types.Target) {
 	mustDelete(tx.Bucket(bucketBlockTargets), id)
 }
 
 // Add/Remove siacoin output
-func dbAddCoinOutput(tx *bolt.Tx, id types.CoinOutputID, output types.CoinOutput) {
+func dbAddCoinOutput(tx kv.Tx, id types.CoinOutputID, output types.CoinOutput) {
 	mustPut(tx.Bucket(bucketCoinOutputs), id, output)
 }
-func dbRemoveCoinOutput(tx *bolt.Tx, id types.CoinOutputID) {
+func dbRemoveCoinOutput(tx kv.Tx, id types.CoinOutputID) {
 	mustDelete(tx.Bucket(bucketCoinOutputs), id)
 }
 
+// Add/Remove the asset held by a coin output
+func dbAddCoinOutputAsset(tx kv.Tx, id types.CoinOutputID, assetID types.AssetID) {
+	mustPut(tx.Bucket(bucketCoinOutputAssets), id, assetID)
+}
+func dbRemoveCoinOutputAsset(tx kv.Tx, id types.CoinOutputID) {
+	mustDelete(tx.Bucket(bucketCoinOutputAssets), id)
+}
+
 // Add/Remove txid from siacoin output ID bucket
-func dbAddCoinOutputID(tx *bolt.Tx, id types.CoinOutputID, txid types.TransactionID) {
+func dbAddCoinOutputID(tx kv.Tx, id types.CoinOutputID, txid types.TransactionID) {
 	b, err := tx.Bucket(bucketCoinOutputIDs).CreateBucketIfNotExists(assertSiaMarshal(id))
 	assertNil(err)
 	mustPutSet(b, txid)
 }
 
-func dbRemoveCoinOutputID(tx *bolt.Tx, id types.CoinOutputID, txid types.TransactionID) {
+func dbRemoveCoinOutputID(tx kv.Tx, id types.CoinOutputID, txid types.TransactionID) {
 	bucket := tx.Bucket(bucketCoinOutputIDs).Bucket(assertSiaMarshal(id))
 	mustDelete(bucket, txid)
 	if bucketIsEmpty(bucket) {
@@ -415,21 +464,21 @@ func dbRemoveCoinOutputID(tx *bolt.Tx, id types.CoinOutputID, txid types.Transac
 }
 
 // Add/Remove blockstake output
-func dbAddBlockStakeOutput(tx *bolt.Tx, id types.BlockStakeOutputID, output types.BlockStakeOutput) {
+func dbAddBlockStakeOutput(tx kv.Tx, id types.BlockStakeOutputID, output types.BlockStakeOutput) {
 	mustPut(tx.Bucket(bucketBlockStakeOutputs), id, output)
 }
-func dbRemoveBlockStakeOutput(tx *bolt.Tx, id types.BlockStakeOutputID) {
+func dbRemoveBlockStakeOutput(tx kv.Tx, id types.BlockStakeOutputID) {
 	mustDelete(tx.Bucket(bucketBlockStakeOutputs), id)
 }
 
 // Add/Remove txid from blockstake output ID bucket
-func dbAddBlockStakeOutputID(tx *bolt.Tx, id types.BlockStakeOutputID, txid types.TransactionID) {
+func dbAddBlockStakeOutputID(tx kv.Tx, id types.BlockStakeOutputID, txid types.TransactionID) {
 	b, err := tx.Bucket(bucketBlockStakeOutputIDs).CreateBucketIfNotExists(assertSiaMarshal(id))
 	assertNil(err)
 	mustPutSet(b, txid)
 }
 
-func dbRemoveBlockStakeOutputID(tx *bolt.Tx, id types.BlockStakeOutputID, txid types.TransactionID) {
+func dbRemoveBlockStakeOutputID(tx kv.Tx, id types.BlockStakeOutputID, txid types.TransactionID) {
 	bucket := tx.Bucket(bucketBlockStakeOutputIDs).Bucket(assertSiaMarshal(id))
 	mustDelete(bucket, txid)
 	if bucketIsEmpty(bucket) {
@@ -440,14 +489,14 @@ func dbRemoveBlockStakeOutputID(tx *bolt.Tx, id types.BlockStakeOutputID, txid t
 }
 
 // Add/Remove transaction ID
-func dbAddTransactionID(tx *bolt.Tx, id types.TransactionID, height types.BlockHeight) {
+func dbAddTransactionID(tx kv.Tx, id types.TransactionID, height types.BlockHeight) {
 	mustPut(tx.Bucket(bucketTransactionIDs), id, height)
 }
-func dbRemoveTransactionID(tx *bolt.Tx, id types.TransactionID) {
+func dbRemoveTransactionID(tx kv.Tx, id types.TransactionID) {
 	mustDelete(tx.Bucket(bucketTransactionIDs), id)
 }
 
-func mapParentUnlockConditionHash(tx *bolt.Tx, parentID interface{}, txid types.TransactionID) error {
+func mapParentUnlockConditionHash(tx kv.Tx, parentID interface{}, txid types.TransactionID) error {
 	switch id := parentID.(type) {
 	case types.CoinOutputID:
 		var sco types.CoinOutput
@@ -471,7 +520,7 @@ func mapParentUnlockConditionHash(tx *bolt.Tx, parentID interface{}, txid types.
 
 	return nil
 }
-func unmapParentUnlockConditionHash(tx *bolt.Tx, parentID interface{}, txid types.TransactionID) error {
+func unmapParentUnlockConditionHash(tx kv.Tx, parentID interface{}, txid types.TransactionID) error {
 	switch id := parentID.(type) {
 	case types.CoinOutputID:
 		var sco types.CoinOutput
@@ -494,7 +543,7 @@ func unmapParentUnlockConditionHash(tx *bolt.Tx, parentID interface{}, txid type
 	}
 	return nil
 }
-func mapUnlockConditionHash(tx *bolt.Tx, ucp types.UnlockConditionProxy, txid types.TransactionID) {
+func mapUnlockConditionHash(tx kv.Tx, ucp types.UnlockConditionProxy, txid types.TransactionID) {
 	muh := ucp.UnlockHash()
 	dbAddUnlockHash(tx, muh, txid)
 	if ucp.ConditionType() == types.ConditionTypeNil {
@@ -502,7 +551,7 @@ func mapUnlockConditionHash(tx *bolt.Tx, ucp types.UnlockConditionProxy, txid ty
 	}
 	mapUnlockConditionMultiSigAddress(tx, muh, ucp.Condition, txid)
 }
-func unmapUnlockConditionHash(tx *bolt.Tx, ucp types.UnlockConditionProxy, txid types.TransactionID) {
+func unmapUnlockConditionHash(tx kv.Tx, ucp types.UnlockConditionProxy, txid types.TransactionID) {
 	muh := ucp.UnlockHash()
 	dbRemoveUnlockHash(tx, muh, txid)
 	if ucp.ConditionType() == types.ConditionTypeNil {
@@ -510,25 +559,25 @@ func unmapUnlockConditionHash(tx *bolt.Tx, ucp types.UnlockConditionProxy, txid
 	}
 	unmapUnlockConditionMultiSigAddress(tx, muh, ucp.Condition, txid)
 }
-func mapUnlockConditionMultiSigAddress(tx *bolt.Tx, muh types.UnlockHash, cond types.MarshalableUnlockCondition, txid types.TransactionID) {
+func mapUnlockConditionMultiSigAddress(tx kv.Tx, muh types.UnlockHash, cond types.MarshalableUnlockCondition, txid types.TransactionID) {
 	switch cond.ConditionType() {
-	case types.ConditionTypeTimeLock:
+	case types.ConditionTypeTimeLock, types.ConditionTypeRelativeTimeLock:
 		cg, ok := cond.(types.MarshalableUnlockConditionGetter)
 		if !ok {
-			build.Severe(fmt.Errorf("unexpected Go-type for TimeLockCondition: %T", cond))
+			build.Severe(fmt.Errorf("unexpected Go-type for (relative) TimeLockCondition: %T", cond))
 			return
 		}
 		cond = cg.GetMarshalableUnlockCondition()
 		if cond == nil {
-			build.Severe(fmt.Errorf("unexpected nil-type for Internal condition of TimeLockCondition"))
+			build.Severe(fmt.Errorf("unexpected nil-type for Internal condition of (relative) TimeLockCondition"))
 			return
 		}
 		mapUnlockConditionMultiSigAddress(tx, muh, cond, txid)
 
-	case types.ConditionTypeMultiSignature:
+	case types.ConditionTypeMultiSignature, types.ConditionTypeWeightedMultiSignature:
 		mcond, ok := cond.(types.UnlockHashSliceGetter)
 		if !ok {
-			build.Severe(fmt.Errorf("unexpected Go-type for MultiSignatureCondition: %T", cond))
+			build.Severe(fmt.Errorf("unexpected Go-type for (Weighted) MultiSignatureCondition: %T", cond))
 			return
 		}
 		// map the multisig address to all internal addresses
@@ -537,25 +586,25 @@ func mapUnlockConditionMultiSigAddress(tx *bolt.Tx, muh types.UnlockHash, cond t
 		}
 	}
 }
-func unmapUnlockConditionMultiSigAddress(tx *bolt.Tx, muh types.UnlockHash, cond types.MarshalableUnlockCondition, txid types.TransactionID) {
+func unmapUnlockConditionMultiSigAddress(tx kv.Tx, muh types.UnlockHash, cond types.MarshalableUnlockCondition, txid types.TransactionID) {
 	switch cond.ConditionType() {
-	case types.ConditionTypeTimeLock:
+	case types.ConditionTypeTimeLock, types.ConditionTypeRelativeTimeLock:
 		cg, ok := cond.(types.MarshalableUnlockConditionGetter)
 		if !ok {
-			build.Severe(fmt.Errorf("unexpected Go-type for TimeLockCondition: %T", cond))
+			build.Severe(fmt.Errorf("unexpected Go-type for (relative) TimeLockCondition: %T", cond))
 			return
 		}
 		cond = cg.GetMarshalableUnlockCondition()
 		if cond == nil {
-			build.Severe(fmt.Errorf("unexpected nil-type for Internal condition of TimeLockCondition"))
+			build.Severe(fmt.Errorf("unexpected nil-type for Internal condition of (relative) TimeLockCondition"))
 			return
 		}
 		unmapUnlockConditionMultiSigAddress(tx, muh, cond, txid)
 
-	case types.ConditionTypeMultiSignature:
+	case types.ConditionTypeMultiSignature, types.ConditionTypeWeightedMultiSignature:
 		mcond, ok := cond.(types.UnlockHashSliceGetter)
 		if !ok {
-			build.Severe(fmt.Errorf("unexpected Go-type for MultiSignatureCondition: %T", cond))
+			build.Severe(fmt.Errorf("unexpected Go-type for (Weighted) MultiSignatureCondition: %T", cond))
 			return
 		}
 		// unmap the multisig address to all internal addresses
@@ -566,12 +615,12 @@ func unmapUnlockConditionMultiSigAddress(tx *bolt.Tx, muh types.UnlockHash, cond
 }
 
 // Add/Remove txid from unlock hash bucket
-func dbAddUnlockHash(tx *bolt.Tx, uh types.UnlockHash, txid types.TransactionID) {
+func dbAddUnlockHash(tx kv.Tx, uh types.UnlockHash, txid types.TransactionID) {
 	b, err := tx.Bucket(bucketUnlockHashes).CreateBucketIfNotExists(assertSiaMarshal(uh))
 	assertNil(err)
 	mustPutSet(b, txid)
 }
-func dbRemoveUnlockHash(tx *bolt.Tx, uh types.UnlockHash, txid types.TransactionID) {
+func dbRemoveUnlockHash(tx kv.Tx, uh types.UnlockHash, txid types.TransactionID) {
 	uhb := tx.Bucket(bucketUnlockHashes)
 	muh := assertSiaMarshal(uh)
 	b := uhb.Bucket(muh)
@@ -584,12 +633,12 @@ func dbRemoveUnlockHash(tx *bolt.Tx, uh types.UnlockHash, txid types.Transaction
 }
 
 // add/remove multisig address from wallet address mapping bucket
-func dbAddWalletAddressToMultiSigAddressMapping(tx *bolt.Tx, walletAddress, multiSigAddress types.UnlockHash, txid types.TransactionID) {
+func dbAddWalletAddressToMultiSigAddressMapping(tx kv.Tx, walletAddress, multiSigAddress types.UnlockHash, txid types.TransactionID) {
 	if build.DEBUG {
-		if walletAddress.Type != types.UnlockTypePubKey {
+		if !walletAddress.Type.IsPubKey() {
 			build.Critical(fmt.Errorf("wallet address has wrong type: %d", walletAddress.Type))
 		}
-		if multiSigAddress.Type != types.UnlockTypeMultiSig {
+		if !multiSigAddress.Type.IsMultiSig() {
 			build.Critical(fmt.Errorf("multisig address has wrong type: %d", multiSigAddress.Type))
 		}
 	}
@@ -599,12 +648,12 @@ func dbAddWalletAddressToMultiSigAddressMapping(tx *bolt.Tx, walletAddress, mult
 	assertNil(err)
 	mustPutSet(b, txid)
 }
-func dbRemoveWalletAddressToMultiSigAddressMapping(tx *bolt.Tx, walletAddress, multiSigAddress types.UnlockHash, txid types.TransactionID) {
+func dbRemoveWalletAddressToMultiSigAddressMapping(tx kv.Tx, walletAddress, multiSigAddress types.UnlockHash, txid types.TransactionID) {
 	if build.DEBUG {
-		if walletAddress.Type != types.UnlockTypePubKey {
+		if !walletAddress.Type.IsPubKey() {
 			build.Critical(fmt.Errorf("wallet address has wrong type: %d", walletAddress.Type))
 		}
-		if multiSigAddress.Type != types.UnlockTypeMultiSig {
+		if !multiSigAddress.Type.IsMultiSig() {
 			build.Critical(fmt.Errorf("multisig address has wrong type: %d", multiSigAddress.Type))
 		}
 	}
@@ -42,6 +42,17 @@ const (
 	TransactionPoolMaxRebroadcasts = 4
 )
 
+const (
+	// TransactionSizeEstimate is the estimated (siabin) byte size of a typical transaction,
+	// spending a single coin output to a destination and refund address,
+	// used to compute the miner fee of a transaction from an estimated fee rate.
+	TransactionSizeEstimate = 350
+
+	// DefaultFeeTarget is the default amount of blocks within which a transaction
+	// is targeted to be confirmed, when estimating the miner fee it has to pay.
+	DefaultFeeTarget types.BlockHeight = 6
+)
+
 const (
 	// TransactionPoolDir is the name of the directory that is used to store
 	// the transaction pool's persistent data.
@@ -76,9 +87,18 @@ type TransactionPool interface {
 
 	// TransactionList returns a list of all transactions in the transaction
 	// pool. The transactions are provided in an order that can acceptably be
-	// put into a block.
+	// put into a block, with the highest fee rate transactions first.
 	TransactionList() []types.Transaction
 
+	// FeeEstimation returns the estimated fee rate, per byte, a transaction has to pay
+	// in order to get confirmed within the target amount of blocks, as well as the miner fee
+	// that is to be paid by a transaction of TransactionSizeEstimate bytes, never lower
+	// than the minimum transaction fee. The estimation is based on how long the recently
+	// confirmed transaction sets, and those still in the pool, waited at each fee rate.
+	// These observations are only kept in memory, such that the minimum transaction fee
+	// is estimated until enough transaction sets have been observed since the pool was started.
+	FeeEstimation(target types.BlockHeight) (feeRate, fee types.Currency)
+
 	// Transaction returns the transaction with the given ID from the transaction pool.
 	// If no transaction for that ID is found ErrNotFound is returned.
 	Transaction(id types.TransactionID) (types.Transaction, error)
@@ -12,13 +12,9 @@ import (
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
-// TODO: Add a priority structure that will allow the transaction pool to
-// fill up beyond the size of a single block, without being subject to
-// manipulation.
-
 var (
 	errObjectConflict      = errors.New("transaction set conflicts with an existing transaction set")
 	errFullTransactionPool = errors.New("transaction pool cannot accept more transactions")
@@ -40,10 +36,6 @@ func (tp *TransactionPool) validateTransactionSetComposition(ts []types.Transact
 		return modules.ErrDuplicateTransactionSet
 	}
 
-	if tp.transactionListSize > tp.chainCts.TransactionPool.PoolSizeLimit {
-		return errFullTransactionPool
-	}
-
 	// TODO: There is no DoS prevention mechanism in place to prevent repeated
 	// expensive verifications of invalid transactions that are created on the
 	// fly.
@@ -59,12 +51,21 @@ func (tp *TransactionPool) validateTransactionSetComposition(ts []types.Transact
 	if err != nil {
 		return err
 	}
-	return nil
+	// Validates that the transaction set only uses transaction versions, unlock condition types
+	// and unlock fulfillment types which are activated at the next block height.
+	err = tp.ValidateTransactionSetActivation(ts)
+	if err != nil {
+		return err
+	}
+	// Validates that none of the transactions expire before the next block,
+	// as such transactions can no longer be included in a block.
+	return tp.ValidateTransactionSetExpiry(ts)
 }
 
 // acceptTransactionSet verifies that a transaction set is allowed to be in the
-// transaction pool, and then adds it to the transaction pool.
-func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction) error {
+// transaction pool, and then adds it to the transaction pool,
+// persisting it as part of the given database transaction.
+func (tp *TransactionPool) acceptTransactionSet(tx kv.Tx, ts []types.Transaction) error {
 	tp.log.Debug("Trying to accept transaction set")
 	if len(ts) == 0 {
 		tp.log.Debug("Attempted to accept empty transaction set")
@@ -72,18 +73,12 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction) error {
 	}
 
 	// Remove all transactions that have been confirmed in the transaction set.
-	err := tp.db.Update(func(tx *bolt.Tx) error {
-		oldTS := ts
-		ts = []types.Transaction{}
-		for _, txn := range oldTS {
-			if !tp.transactionConfirmed(tx, txn.ID()) {
-				ts = append(ts, txn)
-			}
+	oldTS := ts
+	ts = []types.Transaction{}
+	for _, txn := range oldTS {
+		if !tp.transactionConfirmed(tx, txn.ID()) {
+			ts = append(ts, txn)
 		}
-		return nil
-	})
-	if err != nil {
-		return err
 	}
 	// If no transactions remain, return a duplicate error.
 	if len(ts) == 0 {
@@ -105,8 +100,34 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction) error {
 		return err
 	}
 
-	// Validate the new set in context of all other sets
-	txns := tp.transactionList()
+	// Replace the sets the new set conflicts with, should it pay a higher fee,
+	// and make room for the new set in case the pool is full,
+	// by evicting the sets with a lower fee rate, including their dependent sets.
+	pts, err := newPoolTransactionSet(setID, ts)
+	if err != nil {
+		return err
+	}
+	pts.Parents = tp.transactionSetParents(ts)
+	replaced, err := tp.transactionSetsToReplace(pts)
+	if err != nil {
+		tp.log.Debug(fmt.Sprintf("Transaction set %v cannot replace the sets it conflicts with: %v", crypto.Hash(setID).String(), err))
+		return err
+	}
+	evicted, err := tp.transactionSetsToEvict(pts, replaced)
+	if err != nil {
+		tp.log.Debug(fmt.Sprintf("Transaction set %v cannot be accepted: %v", crypto.Hash(setID).String(), err))
+		return err
+	}
+
+	// Validate the new set in context of all other sets,
+	// excluding the sets it replaces. The sets are validated in the order
+	// they were added to the pool, which already respects their dependencies.
+	var txns []types.Transaction
+	for _, tSet := range tp.transactionSets {
+		if _, ok := replaced[tSet.ID]; !ok {
+			txns = append(txns, tSet.Transactions...)
+		}
+	}
 	txns = append(txns, ts...)
 
 	tp.log.Debug(fmt.Sprintf("Trying out transaction set %v against current consensus and txpool state", crypto.Hash(setID).String()))
@@ -116,21 +137,30 @@ func (tp *TransactionPool) acceptTransactionSet(ts []types.Transaction) error {
 		return err
 	}
 
-	// Add the transaction set to the pool.
+	// Persist the transaction set, such that it survives a restart of the pool.
+	for id := range evicted {
+		err = tp.deleteTransactionSet(tx, id)
+		if err != nil {
+			return err
+		}
+	}
+	err = tp.putTransactionSet(tx, setID, ts)
+	if err != nil {
+		return err
+	}
+
+	// Evict the replaced sets and the sets with a lower fee rate, should the pool be full,
+	// and add the transaction set to the pool.
+	tp.removeTransactionSets(evicted)
 	tp.transactionSetMapping[setID] = len(tp.transactionSets)
-	tp.transactionSets = append(tp.transactionSets, poolTransactionSet{
-		ID:           setID,
-		Transactions: ts,
-	})
+	tp.transactionSets = append(tp.transactionSets, pts)
+	tp.invalidateFeeRateOrder()
 	tp.log.Println(fmt.Sprintf("Accepted transaction set %v in pool", crypto.Hash(setID).String()))
 	// remember when the transaction was added
 	tp.broadcastCache.add(setID, tp.consensusSet.Height())
 	tp.transactionSetDiffs[setID] = cc
-	tsBytes, err := siabin.Marshal(ts)
-	if err != nil {
-		return fmt.Errorf("failed to (siabin) marshal transaction set: %v", err)
-	}
-	tp.transactionListSize += len(tsBytes)
+	tp.transactionListSize += pts.Size
+	tp.feeEstimator.addTransactionSet(pts)
 	return nil
 }
 
@@ -141,7 +171,9 @@ func (tp *TransactionPool) AcceptTransactionSet(ts []types.Transaction) error {
 	tp.mu.Lock()
 	defer tp.mu.Unlock()
 
-	err := tp.acceptTransactionSet(ts)
+	err := tp.db.Update(func(tx kv.Tx) error {
+		return tp.acceptTransactionSet(tx, ts)
+	})
 	if err != nil {
 		return err
 	}
@@ -1,7 +1,6 @@
 package transactionpool
 
 import (
-	"crypto/rand"
 	"testing"
 
 	"github.com/threefoldtech/rivine/modules"
@@ -11,58 +10,6 @@ import (
 // TestIntegrationAcceptTransactionSet probes the AcceptTransactionSet method
 // of the transaction pool.
 func TestIntegrationAcceptTransactionSet(t *testing.T) {
-	//TODO: fix test
-	// // Create a transaction pool tester.
-	// tpt, err := createTpoolTester(t.Name())
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// defer tpt.Close()
-	//
-	// // Check that the transaction pool is empty.
-	// if len(tpt.tpool.transactionSets) != 0 {
-	// 	t.Error("transaction pool is not empty")
-	// }
-	//
-	// // Create a valid transaction set using the wallet.
-	// txns, err := tpt.wallet.SendSiacoins(types.NewCurrency64(100), types.UnlockHash{})
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// if len(tpt.tpool.transactionSets) != 1 {
-	// 	t.Error("sending coins did not increase the transaction sets by 1")
-	// }
-	//
-	// // Submit the transaction set again to trigger a duplication error.
-	// err = tpt.tpool.AcceptTransactionSet(txns)
-	// if err != modules.ErrDuplicateTransactionSet {
-	// 	t.Error(err)
-	// }
-	//
-	// // Mine a block and check that the transaction pool gets emptied.
-	// block, _ := tpt.miner.FindBlock()
-	// err = tpt.cs.AcceptBlock(block)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// if len(tpt.tpool.TransactionList()) != 0 {
-	// 	t.Error("transaction pool was not emptied after mining a block")
-	// }
-	//
-	// // Try to resubmit the transaction set to verify
-	// err = tpt.tpool.AcceptTransactionSet(txns)
-	// if err == nil {
-	// 	t.Error("transaction set was supposed to be rejected")
-	// }
-}
-
-// TestIntegrationConflictingTransactionSets tries to add two transaction sets
-// to the transaction pool that are each legal individually, but double spend
-// an output.
-func TestIntegrationConflictingTransactionSets(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
 	// Create a transaction pool tester.
 	tpt, err := createTpoolTester(t.Name())
 	if err != nil {
@@ -70,46 +17,52 @@ func TestIntegrationConflictingTransactionSets(t *testing.T) {
 	}
 	defer tpt.Close()
 
-	// Fund a partial transaction.
-	fund := types.NewCurrency64(30e6)
-	txnBuilder := tpt.wallet.StartTransaction()
-	err = txnBuilder.FundCoins(fund, nil, false)
+	// Check that the transaction pool is empty.
+	if len(tpt.tpool.transactionSets) != 0 {
+		t.Error("transaction pool is not empty")
+	}
+
+	// Create a valid transaction set.
+	fund := types.NewCurrency64(30e9)
+	ids, err := tpt.createCoinOutputs(fund)
 	if err != nil {
 		t.Fatal(err)
 	}
-	// wholeTransaction is set to false so that we can use the same signature
-	// to create a double spend.
-	txnSet, err := txnBuilder.Sign(false)
+	txns := []types.Transaction{tpt.spendTransaction(ids[0], fund, tpt.chainCts.MinimumTransactionFee)}
+	err = tpt.tpool.AcceptTransactionSet(txns)
 	if err != nil {
 		t.Fatal(err)
 	}
-	txnSetDoubleSpend := make([]types.Transaction, len(txnSet))
-	copy(txnSetDoubleSpend, txnSet)
+	if len(tpt.tpool.transactionSets) != 1 {
+		t.Error("accepting a transaction set did not increase the transaction sets by 1")
+	}
 
-	// There are now two sets of transactions that are signed and ready to
-	// spend the same output. Have one spend the money in a miner fee, and the
-	// other create a siacoin output.
-	txnIndex := len(txnSet) - 1
-	txnSet[txnIndex].MinerFees = append(txnSet[txnIndex].MinerFees, fund)
-	txnSetDoubleSpend[txnIndex].CoinOutputs = append(txnSetDoubleSpend[txnIndex].CoinOutputs, types.CoinOutput{Value: fund})
+	// Submit the transaction set again to trigger a duplication error.
+	err = tpt.tpool.AcceptTransactionSet(txns)
+	if err != modules.ErrDuplicateTransactionSet {
+		t.Error(err)
+	}
 
-	// Add the first and then the second txn set.
-	err = tpt.tpool.AcceptTransactionSet(txnSet)
+	// Confirm the transaction set in a block and check that the transaction pool gets emptied.
+	err = tpt.cs.addBlock(txns...)
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
-	err = tpt.tpool.AcceptTransactionSet(txnSetDoubleSpend)
+	if len(tpt.tpool.TransactionList()) != 0 {
+		t.Error("transaction pool was not emptied after confirming the transaction set")
+	}
+
+	// Try to resubmit the transaction set to verify
+	err = tpt.tpool.AcceptTransactionSet(txns)
 	if err == nil {
-		t.Error("transaction should not have passed inspection")
+		t.Error("transaction set was supposed to be rejected")
 	}
 }
 
-// TestIntegrationCheckMinerFees probes the checkMinerFees method of the
-// transaction pool.
-func TestIntegrationCheckMinerFees(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
+// TestIntegrationConflictingTransactionSets tries to add two transaction sets
+// to the transaction pool that are each legal individually, but double spend
+// an output.
+func TestIntegrationConflictingTransactionSets(t *testing.T) {
 	// Create a transaction pool tester.
 	tpt, err := createTpoolTester(t.Name())
 	if err != nil {
@@ -117,44 +70,72 @@ func TestIntegrationCheckMinerFees(t *testing.T) {
 	}
 	defer tpt.Close()
 
-	// Fill the transaction pool to the fee limit.
-	for i := 0; i < TransactionPoolSizeForFee/10e3; i++ {
-		arbData := make([]byte, 10e3)
-		copy(arbData, modules.PrefixNonSia[:])
-		_, err = rand.Read(arbData[100:116]) // prevents collisions with other transacitons in the loop.
-		if err != nil {
-			t.Fatal(err)
-		}
-		txn := types.Transaction{
-			Version:       tpt.tpool.chainCts.DefaultTransactionVersion,
-			ArbitraryData: arbData}
-		err := tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
-		if err != nil {
-			t.Fatal(err)
-		}
-	}
-
-	// Add another transaction, this one should fail for having too few fees.
-	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{{}})
-	if err != errLowMinerFees {
-		t.Error(err)
+	fund := types.NewCurrency64(30e9)
+	ids, err := tpt.createCoinOutputs(fund)
+	if err != nil {
+		t.Fatal(err)
 	}
+	// There are now two sets of transactions that are ready to
+	// spend the same output. Have one spend the money in a miner fee, and the
+	// other create a coin output.
+	txnSet := []types.Transaction{{
+		Version:    tpt.chainCts.DefaultTransactionVersion,
+		CoinInputs: []types.CoinInput{{ParentID: ids[0]}},
+		MinerFees:  []types.Currency{fund},
+	}}
+	txnSetDoubleSpend := []types.Transaction{tpt.spendTransaction(ids[0], fund, tpt.chainCts.MinimumTransactionFee)}
 
-	// Add a transaction that has sufficient fees.
-	_, err = tpt.wallet.SendCoins(types.NewCurrency64(100), types.NewCondition(nil), nil)
+	// Add the first and then the second txn set.
+	err = tpt.tpool.AcceptTransactionSet(txnSet)
 	if err != nil {
 		t.Error(err)
 	}
+	err = tpt.tpool.AcceptTransactionSet(txnSetDoubleSpend)
+	if err == nil {
+		t.Error("transaction should not have passed inspection")
+	}
+}
 
-	// TODO: fill the pool up all the way and try again.
+// TestIntegrationCheckMinerFees probes the checkMinerFees method of the
+// transaction pool.
+func TestIntegrationCheckMinerFees(t *testing.T) {
+	//TODO: fix test, the transaction pool does not require miner fees
+	// until it is full, which is covered by TestAcceptTransactionSetFullPool
+	//
+	// // Create a transaction pool tester.
+	// tpt, err := createTpoolTester(t.Name())
+	// if err != nil {
+	// 	t.Fatal(err)
+	// }
+	// defer tpt.Close()
+	//
+	// // Fill the transaction pool to the fee limit.
+	// for i := 0; i < TransactionPoolSizeForFee/10e3; i++ {
+	// 	arbData := make([]byte, 10e3)
+	// 	copy(arbData, modules.PrefixNonSia[:])
+	// 	_, err = rand.Read(arbData[100:116]) // prevents collisions with other transacitons in the loop.
+	// 	if err != nil {
+	// 		t.Fatal(err)
+	// 	}
+	// 	txn := types.Transaction{
+	// 		Version:       tpt.tpool.chainCts.DefaultTransactionVersion,
+	// 		ArbitraryData: arbData}
+	// 	err := tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	// 	if err != nil {
+	// 		t.Fatal(err)
+	// 	}
+	// }
+	//
+	// // Add another transaction, this one should fail for having too few fees.
+	// err = tpt.tpool.AcceptTransactionSet([]types.Transaction{{}})
+	// if err != errLowMinerFees {
+	// 	t.Error(err)
+	// }
 }
 
-// TestTransactionSuperset submits a single transaction to the network,
+// TestIntegrationTransactionSuperset submits a single transaction to the network,
 // followed by a transaction set containing that single transaction.
 func TestIntegrationTransactionSuperset(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
 	// Create a transaction pool tester.
 	tpt, err := createTpoolTester(t.Name())
 	if err != nil {
@@ -162,23 +143,7 @@ func TestIntegrationTransactionSuperset(t *testing.T) {
 	}
 	defer tpt.Close()
 
-	// Fund a partial transaction.
-	fund := types.NewCurrency64(30e6)
-	txnBuilder := tpt.wallet.StartTransaction()
-	err = txnBuilder.FundCoins(fund, nil, false)
-	if err != nil {
-		t.Fatal(err)
-	}
-	txnBuilder.AddMinerFee(fund)
-	// wholeTransaction is set to false so that we can use the same signature
-	// to create a double spend.
-	txnSet, err := txnBuilder.Sign(false)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if len(txnSet) <= 1 {
-		t.Fatal("test is invalid unless the transaction set has two or more transactions")
-	}
+	txnSet := createDependentTransactionSet(t, tpt)
 	// Check that the second transaction is dependent on the first.
 	err = tpt.tpool.AcceptTransactionSet(txnSet[1:])
 	if err == nil {
@@ -186,34 +151,28 @@ func TestIntegrationTransactionSuperset(t *testing.T) {
 	}
 
 	// Submit the first transaction in the set to the transaction pool, and
-	// then the superset.
+	// then the superset, which conflicts with the transaction in the pool,
+	// as it spends the same output, while depending on it as well.
 	err = tpt.tpool.AcceptTransactionSet(txnSet[:1])
 	if err != nil {
 		t.Fatal("first transaction in the transaction set was not valid?")
 	}
 	err = tpt.tpool.AcceptTransactionSet(txnSet)
-	if err != nil {
-		t.Fatal("super setting is not working:", err)
+	if err != errReplacementSpendsReplaced {
+		t.Fatal("superset was not expected to be accepted:", err)
 	}
 
-	// Try resubmitting the individual transaction and the superset, a
-	// duplication error should be returned for each case.
+	// Try resubmitting the individual transaction, a
+	// duplication error should be returned.
 	err = tpt.tpool.AcceptTransactionSet(txnSet[:1])
 	if err != modules.ErrDuplicateTransactionSet {
 		t.Fatal(err)
 	}
-	err = tpt.tpool.AcceptTransactionSet(txnSet)
-	if err != modules.ErrDuplicateTransactionSet {
-		t.Fatal("super setting is not working:", err)
-	}
 }
 
-// TestTransactionSubset submits a transaction set to the network, followed by
-// just a subset, expectint ErrDuplicateTransactionSet as a response.
+// TestIntegrationTransactionSubset submits a transaction set to the network, followed by
+// just a subset, which conflicts with the set in the pool without paying a higher fee rate.
 func TestIntegrationTransactionSubset(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
 	// Create a transaction pool tester.
 	tpt, err := createTpoolTester(t.Name())
 	if err != nil {
@@ -221,23 +180,7 @@ func TestIntegrationTransactionSubset(t *testing.T) {
 	}
 	defer tpt.Close()
 
-	// Fund a partial transaction.
-	fund := types.NewCurrency64(30e6)
-	txnBuilder := tpt.wallet.StartTransaction()
-	err = txnBuilder.FundCoins(fund, nil, false)
-	if err != nil {
-		t.Fatal(err)
-	}
-	txnBuilder.AddMinerFee(fund)
-	// wholeTransaction is set to false so that we can use the same signature
-	// to create a double spend.
-	txnSet, err := txnBuilder.Sign(false)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if len(txnSet) <= 1 {
-		t.Fatal("test is invalid unless the transaction set has two or more transactions")
-	}
+	txnSet := createDependentTransactionSet(t, tpt)
 	// Check that the second transaction is dependent on the first.
 	err = tpt.tpool.AcceptTransactionSet(txnSet[1:])
 	if err == nil {
@@ -250,6 +193,10 @@ func TestIntegrationTransactionSubset(t *testing.T) {
 		t.Fatal("super setting is not working:", err)
 	}
 	err = tpt.tpool.AcceptTransactionSet(txnSet[:1])
+	if err != errReplacementLowFeeRate {
+		t.Fatal("subset was not expected to be accepted:", err)
+	}
+	err = tpt.tpool.AcceptTransactionSet(txnSet)
 	if err != modules.ErrDuplicateTransactionSet {
 		t.Fatal(err)
 	}
@@ -258,9 +205,6 @@ func TestIntegrationTransactionSubset(t *testing.T) {
 // TestIntegrationTransactionChild submits a single transaction to the network,
 // followed by a child transaction.
 func TestIntegrationTransactionChild(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
 	// Create a transaction pool tester.
 	tpt, err := createTpoolTester(t.Name())
 	if err != nil {
@@ -268,23 +212,7 @@ func TestIntegrationTransactionChild(t *testing.T) {
 	}
 	defer tpt.Close()
 
-	// Fund a partial transaction.
-	fund := types.NewCurrency64(30e6)
-	txnBuilder := tpt.wallet.StartTransaction()
-	err = txnBuilder.FundCoins(fund, nil, false)
-	if err != nil {
-		t.Fatal(err)
-	}
-	txnBuilder.AddMinerFee(fund)
-	// wholeTransaction is set to false so that we can use the same signature
-	// to create a double spend.
-	txnSet, err := txnBuilder.Sign(false)
-	if err != nil {
-		t.Fatal(err)
-	}
-	if len(txnSet) <= 1 {
-		t.Fatal("test is invalid unless the transaction set has two or more transactions")
-	}
+	txnSet := createDependentTransactionSet(t, tpt)
 	// Check that the second transaction is dependent on the first.
 	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txnSet[1]})
 	if err == nil {
@@ -300,14 +228,17 @@ func TestIntegrationTransactionChild(t *testing.T) {
 	if err != nil {
 		t.Fatal("child transaction not seen as valid")
 	}
+	if len(tpt.tpool.transactionSets) != 2 {
+		t.Fatalf("expected 2 transaction sets in the pool, got %d", len(tpt.tpool.transactionSets))
+	}
+	if parents := tpt.tpool.transactionSets[1].Parents; len(parents) != 1 || parents[0] != tpt.tpool.transactionSets[0].ID {
+		t.Fatalf("child transaction set has unexpected parents: %v", parents)
+	}
 }
 
 // TestIntegrationNilAccept tries submitting a nil transaction set and a 0-len
 // transaction set to the transaction pool.
 func TestIntegrationNilAccept(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
 	tpt, err := createTpoolTester(t.Name())
 	if err != nil {
 		t.Fatal(err)
@@ -324,6 +255,20 @@ func TestIntegrationNilAccept(t *testing.T) {
 	}
 }
 
+// createDependentTransactionSet creates a transaction set of two transactions,
+// the second transaction spending the output created by the first.
+func createDependentTransactionSet(t *testing.T, tpt *tpoolTester) []types.Transaction {
+	fund := types.NewCurrency64(30e9)
+	ids, err := tpt.createCoinOutputs(fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fee := tpt.chainCts.MinimumTransactionFee
+	parent := tpt.spendTransaction(ids[0], fund, fee)
+	child := tpt.spendTransaction(parent.CoinOutputID(0), fund.Sub(fee), fee)
+	return []types.Transaction{parent, child}
+}
+
 // TestPartialConfirmation checks that the transaction pool correctly accepts a
 // transaction set which has parents that have been accepted by the consensus
 // set but not the whole set has been accepted by the consensus set.
@@ -0,0 +1,254 @@
+package transactionpool
+
+import (
+	"sort"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+)
+
+const (
+	// feeEstimatorHistory is the maximum amount of confirmed transaction sets
+	// remembered by the fee estimator, the oldest confirmations being forgotten first.
+	feeEstimatorHistory = 2000
+
+	// feeEstimatorMinObservations is the minimum amount of transaction sets that have to be
+	// observed paying at least a certain fee rate, before that fee rate can be estimated.
+	feeEstimatorMinObservations = 10
+
+	// feeEstimatorSuccessRate is the minimum percentage of the observed transaction sets,
+	// paying at least the estimated fee rate, which got confirmed within the target amount of blocks.
+	feeEstimatorSuccessRate = 85
+)
+
+type (
+	// feeEstimator estimates the fee rate a transaction has to pay in order to be
+	// confirmed within a target amount of blocks, by tracking how many blocks
+	// the transaction sets at each fee rate waited in the pool before they got confirmed.
+	//
+	// The estimator only keeps its observations in memory, such that it starts without
+	// any observations when the transaction pool is restarted, estimating the minimum
+	// transaction fee until it has once again observed enough transaction sets.
+	feeEstimator struct {
+		// height is the amount of blocks the estimator has seen applied,
+		// only used to compute the amount of blocks a transaction set waited in the pool.
+		height types.BlockHeight
+
+		// unconfirmed contains the transaction sets in the pool, and confirmed
+		// the most recently confirmed transaction sets that were seen in the pool.
+		unconfirmed map[TransactionSetID]unconfirmedFeeObservation
+		confirmed   []confirmedFeeObservation
+
+		// transactions maps the transactions in the pool to the transaction set
+		// they were last seen in, as well as the height at which they were first seen.
+		transactions map[types.TransactionID]unconfirmedTransaction
+	}
+
+	// unconfirmedFeeObservation is a transaction set waiting in the pool since the given height,
+	// of the given size, paying the given fees.
+	unconfirmedFeeObservation struct {
+		Height types.BlockHeight
+		Size   int
+		Fees   types.Currency
+	}
+
+	// unconfirmedTransaction is a transaction waiting in the pool since the given height,
+	// as part of the transaction set with the given ID.
+	unconfirmedTransaction struct {
+		Set    TransactionSetID
+		Height types.BlockHeight
+	}
+
+	// confirmedFeeObservation is a transaction set that got confirmed after waiting the given amount
+	// of blocks in the pool, of the given size, paying the given fees.
+	confirmedFeeObservation struct {
+		Wait types.BlockHeight
+		Size int
+		Fees types.Currency
+	}
+)
+
+// newFeeEstimator creates a new fee estimator, which has not observed any transaction set yet.
+func newFeeEstimator() *feeEstimator {
+	return &feeEstimator{
+		unconfirmed:  make(map[TransactionSetID]unconfirmedFeeObservation),
+		transactions: make(map[types.TransactionID]unconfirmedTransaction),
+	}
+}
+
+// addTransactionSet starts tracking a transaction set accepted by the pool.
+// Transaction sets already tracked keep the height at which they were first seen,
+// as do the transactions which were already seen as part of another transaction set.
+func (fe *feeEstimator) addTransactionSet(pts poolTransactionSet) {
+	if _, ok := fe.unconfirmed[pts.ID]; ok {
+		return
+	}
+	height := fe.height
+	for _, txn := range pts.Transactions {
+		if tracked, ok := fe.transactions[txn.ID()]; ok && tracked.Height < height {
+			height = tracked.Height
+		}
+	}
+	fe.unconfirmed[pts.ID] = unconfirmedFeeObservation{
+		Height: height,
+		Size:   pts.Size,
+		Fees:   pts.Fees,
+	}
+	for _, txn := range pts.Transactions {
+		fe.transactions[txn.ID()] = unconfirmedTransaction{
+			Set:    pts.ID,
+			Height: height,
+		}
+	}
+}
+
+// processConsensusChange records how long the transaction sets confirmed by the
+// applied blocks of the given consensus change waited in the pool.
+// A transaction set is observed as confirmed once the first of its transactions is confirmed.
+func (fe *feeEstimator) processConsensusChange(cc modules.ConsensusChange) {
+	for range cc.RevertedBlocks {
+		if fe.height > 0 {
+			fe.height--
+		}
+	}
+	for _, block := range cc.AppliedBlocks {
+		fe.height++
+		for _, txn := range block.Transactions {
+			id := txn.ID()
+			tracked, ok := fe.transactions[id]
+			if !ok {
+				continue
+			}
+			delete(fe.transactions, id)
+			observation, ok := fe.unconfirmed[tracked.Set]
+			if !ok {
+				// already observed as confirmed
+				continue
+			}
+			delete(fe.unconfirmed, tracked.Set)
+			var wait types.BlockHeight
+			if fe.height > observation.Height {
+				wait = fe.height - observation.Height
+			}
+			fe.confirmed = append(fe.confirmed, confirmedFeeObservation{
+				Wait: wait,
+				Size: observation.Size,
+				Fees: observation.Fees,
+			})
+		}
+	}
+	if n := len(fe.confirmed); n > feeEstimatorHistory {
+		fe.confirmed = append([]confirmedFeeObservation(nil), fe.confirmed[n-feeEstimatorHistory:]...)
+	}
+}
+
+// prune stops tracking the transaction sets, and transactions, which are no longer
+// in the given pool transaction sets.
+func (fe *feeEstimator) prune(tSets []poolTransactionSet) {
+	setIDs := make(map[TransactionSetID]struct{}, len(tSets))
+	txnIDs := make(map[types.TransactionID]struct{}, len(fe.transactions))
+	for _, tSet := range tSets {
+		setIDs[tSet.ID] = struct{}{}
+		for _, txn := range tSet.Transactions {
+			txnIDs[txn.ID()] = struct{}{}
+		}
+	}
+	for id := range fe.unconfirmed {
+		if _, ok := setIDs[id]; !ok {
+			delete(fe.unconfirmed, id)
+		}
+	}
+	for id := range fe.transactions {
+		if _, ok := txnIDs[id]; !ok {
+			delete(fe.transactions, id)
+		}
+	}
+}
+
+// estimate returns the lowest fee rate, as (the fees and size of) the transaction set paying it,
+// for which at least feeEstimatorSuccessRate percent of the observed transaction sets
+// paying that fee rate or higher were confirmed within the target amount of blocks.
+// Transaction sets that are still in the pool count as failed, once they waited the target amount of blocks.
+// False is returned if not enough transaction sets have been observed to estimate the fee rate.
+func (fe *feeEstimator) estimate(target types.BlockHeight) (poolTransactionSet, bool) {
+	type observation struct {
+		poolTransactionSet
+		success bool
+	}
+	observations := make([]observation, 0, len(fe.confirmed)+len(fe.unconfirmed))
+	for _, confirmed := range fe.confirmed {
+		observations = append(observations, observation{
+			poolTransactionSet: poolTransactionSet{Size: confirmed.Size, Fees: confirmed.Fees},
+			success:            confirmed.Wait <= target,
+		})
+	}
+	for _, unconfirmed := range fe.unconfirmed {
+		if fe.height < unconfirmed.Height || fe.height-unconfirmed.Height < target {
+			// it can still be confirmed within the target amount of blocks
+			continue
+		}
+		observations = append(observations, observation{
+			poolTransactionSet: poolTransactionSet{Size: unconfirmed.Size, Fees: unconfirmed.Fees},
+		})
+	}
+	sort.Slice(observations, func(i, j int) bool {
+		return observations[i].cmpFeeRate(observations[j].poolTransactionSet) > 0
+	})
+
+	// walk from the highest to the lowest fee rate,
+	// until the observed transaction sets no longer get confirmed in time
+	var (
+		estimate       poolTransactionSet
+		found          bool
+		total, success int
+	)
+	for i, observation := range observations {
+		total++
+		if observation.success {
+			success++
+		}
+		if i+1 < len(observations) && observations[i+1].cmpFeeRate(observation.poolTransactionSet) == 0 {
+			// judge all observations of the same fee rate together
+			continue
+		}
+		if total < feeEstimatorMinObservations {
+			continue
+		}
+		if success*100 < total*feeEstimatorSuccessRate {
+			break
+		}
+		estimate, found = observation.poolTransactionSet, true
+	}
+	return estimate, found
+}
+
+// FeeEstimation implements modules.TransactionPool.FeeEstimation
+func (tp *TransactionPool) FeeEstimation(target types.BlockHeight) (feeRate, fee types.Currency) {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+
+	if target == 0 {
+		target = 1
+	}
+	estimate, ok := tp.feeEstimator.estimate(target)
+	if !ok {
+		// not enough transaction sets have been observed (since the pool was started),
+		// default to the minimum transaction fee
+		fee = tp.chainCts.MinimumTransactionFee
+		return feeRatePerByte(fee, modules.TransactionSizeEstimate), fee
+	}
+	feeRate = feeRatePerByte(estimate.Fees, estimate.Size)
+	fee = feeRate.Mul64(modules.TransactionSizeEstimate)
+	if fee.Cmp(tp.chainCts.MinimumTransactionFee) < 0 {
+		fee = tp.chainCts.MinimumTransactionFee
+	}
+	return feeRate, fee
+}
+
+// feeRatePerByte returns the given fees divided by the given (byte) size, rounded up.
+func feeRatePerByte(fees types.Currency, size int) types.Currency {
+	if size <= 0 {
+		return fees
+	}
+	return fees.Add(types.NewCurrency64(uint64(size - 1))).Div64(uint64(size))
+}
@@ -0,0 +1,175 @@
+package transactionpool
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// feeEstimatorTester observes unique transaction sets paying a given fee.
+type feeEstimatorTester struct {
+	t       *testing.T
+	fe      *feeEstimator
+	counter uint64
+}
+
+// transactionSet creates a unique transaction set of the given amount of transactions,
+// each paying the given fee, and starts tracking it.
+func (fet *feeEstimatorTester) transactionSet(n int, fee types.Currency) poolTransactionSet {
+	txns := make([]types.Transaction, 0, n)
+	for i := 0; i < n; i++ {
+		fet.counter++
+		data := make([]byte, 8)
+		binary.LittleEndian.PutUint64(data, fet.counter)
+		txns = append(txns, types.Transaction{
+			ArbitraryData: data,
+			MinerFees:     []types.Currency{fee},
+		})
+	}
+	var id TransactionSetID
+	binary.LittleEndian.PutUint64(id[:], fet.counter)
+	pts, err := newPoolTransactionSet(id, txns)
+	if err != nil {
+		fet.t.Fatal(err)
+	}
+	fet.fe.addTransactionSet(pts)
+	return pts
+}
+
+// confirm applies the given amount of blocks, the last one confirming the given transaction sets.
+func (fet *feeEstimatorTester) confirm(blocks int, sets ...poolTransactionSet) {
+	var cc modules.ConsensusChange
+	for i := 0; i < blocks; i++ {
+		cc.AppliedBlocks = append(cc.AppliedBlocks, types.Block{})
+	}
+	for _, pts := range sets {
+		cc.AppliedBlocks[blocks-1].Transactions = append(cc.AppliedBlocks[blocks-1].Transactions, pts.Transactions...)
+	}
+	fet.fe.processConsensusChange(cc)
+}
+
+// observe observes n transaction sets, paying the given fee,
+// which all get confirmed after the given amount of blocks.
+func (fet *feeEstimatorTester) observe(n int, fee types.Currency, wait int) {
+	sets := make([]poolTransactionSet, 0, n)
+	for i := 0; i < n; i++ {
+		sets = append(sets, fet.transactionSet(1, fee))
+	}
+	fet.confirm(wait, sets...)
+}
+
+// TestFeeEstimatorTransactionSetObservation probes that a transaction set
+// is observed only once, no matter how many transactions it contains.
+func TestFeeEstimatorTransactionSetObservation(t *testing.T) {
+	fet := feeEstimatorTester{t: t, fe: newFeeEstimator()}
+	pts := fet.transactionSet(3, types.NewCurrency64(100))
+	// accepting the same set again doesn't track it again
+	fet.fe.addTransactionSet(pts)
+	if n := len(fet.fe.unconfirmed); n != 1 {
+		t.Fatalf("expected 1 unconfirmed transaction set, found %d", n)
+	}
+
+	fet.confirm(2, pts)
+	if n := len(fet.fe.unconfirmed); n != 0 {
+		t.Fatalf("expected no unconfirmed transaction sets, found %d", n)
+	}
+	if n := len(fet.fe.transactions); n != 0 {
+		t.Fatalf("expected no unconfirmed transactions, found %d", n)
+	}
+	if n := len(fet.fe.confirmed); n != 1 {
+		t.Fatalf("expected 1 confirmed transaction set, found %d", n)
+	}
+	observation := fet.fe.confirmed[0]
+	if observation.Wait != 2 || observation.Size != pts.Size || !observation.Fees.Equals(pts.Fees) {
+		t.Fatalf("unexpected observation: %v", observation)
+	}
+
+	// a pruned transaction set is no longer tracked
+	pruned := fet.transactionSet(2, types.NewCurrency64(100))
+	kept := fet.transactionSet(1, types.NewCurrency64(100))
+	fet.fe.prune([]poolTransactionSet{kept})
+	if _, ok := fet.fe.unconfirmed[pruned.ID]; ok {
+		t.Fatal("expected the pruned transaction set to no longer be tracked")
+	}
+	if _, ok := fet.fe.unconfirmed[kept.ID]; !ok {
+		t.Fatal("expected the kept transaction set to still be tracked")
+	}
+	if n := len(fet.fe.transactions); n != 1 {
+		t.Fatalf("expected 1 unconfirmed transaction, found %d", n)
+	}
+}
+
+// TestFeeEstimatorMinObservations probes that the fee estimator
+// falls back to the minimum transaction fee as long as
+// it hasn't observed enough transaction sets.
+func TestFeeEstimatorMinObservations(t *testing.T) {
+	fet := feeEstimatorTester{t: t, fe: newFeeEstimator()}
+	fet.observe(feeEstimatorMinObservations-1, types.NewCurrency64(100), 1)
+	if _, ok := fet.fe.estimate(1); ok {
+		t.Fatal("expected no estimate with too few observations")
+	}
+	fet.observe(1, types.NewCurrency64(100), 1)
+	if _, ok := fet.fe.estimate(1); !ok {
+		t.Fatal("expected an estimate with enough observations")
+	}
+
+	// a new (or restarted) pool estimates the minimum transaction fee
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+	minFee := tpt.chainCts.MinimumTransactionFee
+	feeRate, fee := tpt.tpool.FeeEstimation(modules.DefaultFeeTarget)
+	if !fee.Equals(minFee) {
+		t.Fatalf("expected fee %v, got: %v", minFee, fee)
+	}
+	if expected := feeRatePerByte(minFee, modules.TransactionSizeEstimate); !feeRate.Equals(expected) {
+		t.Fatalf("expected fee rate %v, got: %v", expected, feeRate)
+	}
+}
+
+// TestFeeEstimatorTargets probes that the fee estimator estimates the lowest fee rate
+// at which enough transaction sets got confirmed within the target amount of blocks.
+func TestFeeEstimatorTargets(t *testing.T) {
+	fet := feeEstimatorTester{t: t, fe: newFeeEstimator()}
+	high, medium, low := types.NewCurrency64(300), types.NewCurrency64(200), types.NewCurrency64(100)
+	fet.observe(feeEstimatorMinObservations, high, 1)
+	fet.observe(feeEstimatorMinObservations, medium, 2)
+	fet.observe(feeEstimatorMinObservations, low, 6)
+
+	testCases := []struct {
+		Target types.BlockHeight
+		Fee    types.Currency
+	}{
+		{1, high},
+		{2, medium},
+		{5, medium},
+		{6, low},
+	}
+	for idx, testCase := range testCases {
+		estimate, ok := fet.fe.estimate(testCase.Target)
+		if !ok {
+			t.Errorf("#%d: expected an estimate for target %d", idx, testCase.Target)
+			continue
+		}
+		if !estimate.Fees.Equals(testCase.Fee) {
+			t.Errorf("#%d: expected fee %v for target %d, got: %v", idx, testCase.Fee, testCase.Target, estimate.Fees)
+		}
+	}
+
+	// transaction sets waiting in the pool for longer than the target count as failed
+	for i := 0; i < feeEstimatorMinObservations; i++ {
+		fet.transactionSet(1, high)
+	}
+	fet.confirm(1)
+	if _, ok := fet.fe.estimate(1); ok {
+		t.Fatal("expected no estimate for target 1, while the high fee rate sets wait in the pool")
+	}
+	estimate, ok := fet.fe.estimate(2)
+	if !ok || !estimate.Fees.Equals(medium) {
+		t.Fatalf("expected fee %v for target 2, got: %v (%v)", medium, estimate.Fees, ok)
+	}
+}
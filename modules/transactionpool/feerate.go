@@ -0,0 +1,361 @@
+package transactionpool
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+	"github.com/threefoldtech/rivine/types"
+)
+
+// newPoolTransactionSet creates a new pool transaction set for the given transactions,
+// computing its (siabin) byte size and the total amount of miner fees it pays.
+func newPoolTransactionSet(id TransactionSetID, ts []types.Transaction) (poolTransactionSet, error) {
+	tsBytes, err := siabin.Marshal(ts)
+	if err != nil {
+		return poolTransactionSet{}, fmt.Errorf("failed to (siabin) marshal transaction set: %v", err)
+	}
+	var fees types.Currency
+	for _, txn := range ts {
+		for _, fee := range txn.MinerFees {
+			fees = fees.Add(fee)
+		}
+	}
+	return poolTransactionSet{
+		ID:           id,
+		Transactions: ts,
+		Size:         len(tsBytes),
+		Fees:         fees,
+	}, nil
+}
+
+// cmpFeeRate compares the fee rate of this transaction set with the fee rate of another,
+// returning -1 if it is lower, 0 if equal and 1 if it is higher.
+// The fee rates are compared without rounding them.
+func (pts poolTransactionSet) cmpFeeRate(other poolTransactionSet) int {
+	return pts.Fees.Mul64(uint64(other.Size)).Cmp(other.Fees.Mul64(uint64(pts.Size)))
+}
+
+// transactionSetParents returns the IDs of all transaction sets in the pool
+// which create outputs that are spent by the given transactions.
+func (tp *TransactionPool) transactionSetParents(ts []types.Transaction) []TransactionSetID {
+	coinOutputs := make(map[types.CoinOutputID]TransactionSetID)
+	blockStakeOutputs := make(map[types.BlockStakeOutputID]TransactionSetID)
+	for _, tSet := range tp.transactionSets {
+		for _, txn := range tSet.Transactions {
+			for i := range txn.CoinOutputs {
+				coinOutputs[txn.CoinOutputID(uint64(i))] = tSet.ID
+			}
+			for i := range txn.BlockStakeOutputs {
+				blockStakeOutputs[txn.BlockStakeOutputID(uint64(i))] = tSet.ID
+			}
+		}
+	}
+
+	var parents []TransactionSetID
+	known := make(map[TransactionSetID]struct{})
+	addParent := func(id TransactionSetID) {
+		if _, ok := known[id]; !ok {
+			known[id] = struct{}{}
+			parents = append(parents, id)
+		}
+	}
+	for _, txn := range ts {
+		for _, ci := range txn.CoinInputs {
+			if id, ok := coinOutputs[ci.ParentID]; ok {
+				addParent(id)
+			}
+		}
+		for _, bsi := range txn.BlockStakeInputs {
+			if id, ok := blockStakeOutputs[bsi.ParentID]; ok {
+				addParent(id)
+			}
+		}
+	}
+	return parents
+}
+
+// transactionSetChildren returns, for each transaction set in the pool,
+// the IDs of the transaction sets in the pool that depend on it.
+func (tp *TransactionPool) transactionSetChildren() map[TransactionSetID][]TransactionSetID {
+	children := make(map[TransactionSetID][]TransactionSetID)
+	for _, tSet := range tp.transactionSets {
+		for _, parent := range tSet.Parents {
+			children[parent] = append(children[parent], tSet.ID)
+		}
+	}
+	return children
+}
+
+// transactionSetDescendants adds the given transaction set,
+// as well as all transaction sets in the pool that (indirectly) depend on it,
+// to the given set of IDs.
+func (tp *TransactionPool) transactionSetDescendants(id TransactionSetID, children map[TransactionSetID][]TransactionSetID, ids map[TransactionSetID]struct{}) {
+	if _, ok := ids[id]; ok {
+		return
+	}
+	ids[id] = struct{}{}
+	for _, child := range children[id] {
+		tp.transactionSetDescendants(child, children, ids)
+	}
+}
+
+// transactionSetAncestors adds the IDs of all transaction sets in the pool
+// that the given transaction set (indirectly) depends on, to the given set of IDs.
+func (tp *TransactionPool) transactionSetAncestors(parents []TransactionSetID, ids map[TransactionSetID]struct{}) {
+	for _, parent := range parents {
+		if _, ok := ids[parent]; ok {
+			continue
+		}
+		tSet, ok := tp.transactionSetByID(parent)
+		if !ok {
+			continue
+		}
+		ids[parent] = struct{}{}
+		tp.transactionSetAncestors(tSet.Parents, ids)
+	}
+}
+
+// transactionSetsToEvict returns the IDs of the transaction sets that have to be evicted
+// from the pool in order to make room for the given transaction set,
+// including the given IDs of the transaction sets it replaces.
+// A transaction set is always evicted together with all transaction sets that depend on it,
+// and is therefore ranked as a package with those transaction sets.
+// Only packages with a lower fee rate than the given transaction set are evicted,
+// the lowest fee rate first. Transaction sets that the given transaction set
+// depends on are never evicted. If not enough room can be made, errFullTransactionPool is returned.
+func (tp *TransactionPool) transactionSetsToEvict(pts poolTransactionSet, replaced map[TransactionSetID]struct{}) (map[TransactionSetID]struct{}, error) {
+	evicted := make(map[TransactionSetID]struct{}, len(replaced))
+	required := tp.transactionListSize + pts.Size - tp.chainCts.TransactionPool.PoolSizeLimit
+	for id := range replaced {
+		evicted[id] = struct{}{}
+		tSet, _ := tp.transactionSetByID(id)
+		required -= tSet.Size
+	}
+	if required <= 0 {
+		return evicted, nil
+	}
+
+	ancestors := make(map[TransactionSetID]struct{})
+	tp.transactionSetAncestors(pts.Parents, ancestors)
+	children := tp.transactionSetChildren()
+
+	type candidate struct {
+		poolTransactionSet
+		descendants map[TransactionSetID]struct{}
+	}
+	candidates := make([]candidate, 0, len(tp.transactionSets))
+	for _, tSet := range tp.transactionSets {
+		if _, ok := ancestors[tSet.ID]; ok {
+			continue
+		}
+		descendants := make(map[TransactionSetID]struct{})
+		tp.transactionSetDescendants(tSet.ID, children, descendants)
+		pkg := poolTransactionSet{ID: tSet.ID}
+		for id := range descendants {
+			descendant, _ := tp.transactionSetByID(id)
+			pkg.Size += descendant.Size
+			pkg.Fees = pkg.Fees.Add(descendant.Fees)
+		}
+		candidates = append(candidates, candidate{
+			poolTransactionSet: pkg,
+			descendants:        descendants,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].cmpFeeRate(candidates[j].poolTransactionSet) < 0
+	})
+	for _, candidate := range candidates {
+		if required <= 0 {
+			break
+		}
+		if candidate.cmpFeeRate(pts) >= 0 {
+			// all remaining packages pay at least the same fee rate
+			break
+		}
+		for id := range candidate.descendants {
+			if _, ok := evicted[id]; ok {
+				continue
+			}
+			evicted[id] = struct{}{}
+			tSet, _ := tp.transactionSetByID(id)
+			required -= tSet.Size
+		}
+	}
+	if required > 0 {
+		return nil, errFullTransactionPool
+	}
+	return evicted, nil
+}
+
+// removeTransactionSets removes the transaction sets with the given IDs from the pool.
+func (tp *TransactionPool) removeTransactionSets(ids map[TransactionSetID]struct{}) {
+	if len(ids) == 0 {
+		return
+	}
+	tSets := make([]poolTransactionSet, 0, len(tp.transactionSets))
+	for _, tSet := range tp.transactionSets {
+		if _, ok := ids[tSet.ID]; ok {
+			tp.log.Println(fmt.Sprintf("Evicting transaction set %v from pool", crypto.Hash(tSet.ID).String()))
+			delete(tp.transactionSetDiffs, tSet.ID)
+			tp.broadcastCache.delete(tSet.ID)
+			tp.transactionListSize -= tSet.Size
+			continue
+		}
+		tSets = append(tSets, tSet)
+	}
+	tp.transactionSets = tSets
+	tp.transactionSetMapping = make(map[TransactionSetID]int, len(tSets))
+	for index, tSet := range tSets {
+		tp.transactionSetMapping[tSet.ID] = index
+	}
+	tp.invalidateFeeRateOrder()
+}
+
+// invalidateFeeRateOrder drops the cached fee rate order of the transaction sets,
+// which has to be called every time transaction sets are added to or removed from the pool.
+func (tp *TransactionPool) invalidateFeeRateOrder() {
+	tp.feeRateOrderMu.Lock()
+	tp.feeRateOrder = nil
+	tp.feeRateOrderMu.Unlock()
+}
+
+// transactionSetsByFeeRate returns all transaction sets in the pool,
+// ordered such that they can acceptably be put into a block, the most profitable first.
+// The order is computed only once for as long as the pool doesn't change.
+// The returned slice is shared and should not be modified.
+func (tp *TransactionPool) transactionSetsByFeeRate() []poolTransactionSet {
+	tp.feeRateOrderMu.Lock()
+	defer tp.feeRateOrderMu.Unlock()
+	if tp.feeRateOrder == nil {
+		tp.feeRateOrder = tp.orderTransactionSetsByFeeRate()
+	}
+	return tp.feeRateOrder
+}
+
+// orderTransactionSetsByFeeRate orders all transaction sets in the pool,
+// such that they can acceptably be put into a block, the most profitable first.
+//
+// Transaction sets are ranked as a package, together with all transaction sets
+// in the pool they (indirectly) depend on and which aren't yet ordered.
+// This allows a (child) transaction set paying a high fee to pay for the low fee
+// of the (parent) transaction sets it depends on. The package with the highest
+// fee rate is added first, its transaction sets ordered as they were added to the pool,
+// after which the packages of the transaction sets depending on it are ranked again.
+func (tp *TransactionPool) orderTransactionSetsByFeeRate() []poolTransactionSet {
+	children := tp.transactionSetChildren()
+	ancestors := make(map[TransactionSetID]map[TransactionSetID]struct{}, len(tp.transactionSets))
+	packages := make(map[TransactionSetID]poolTransactionSet, len(tp.transactionSets))
+	queue := make(transactionSetPackageQueue, 0, len(tp.transactionSets))
+	for _, tSet := range tp.transactionSets {
+		ids := make(map[TransactionSetID]struct{})
+		tp.transactionSetAncestors(tSet.Parents, ids)
+		pkg := poolTransactionSet{ID: tSet.ID, Size: tSet.Size, Fees: tSet.Fees}
+		for id := range ids {
+			ancestor, _ := tp.transactionSetByID(id)
+			pkg.Size += ancestor.Size
+			pkg.Fees = pkg.Fees.Add(ancestor.Fees)
+		}
+		ancestors[tSet.ID] = ids
+		packages[tSet.ID] = pkg
+		queue = append(queue, transactionSetPackage{
+			poolTransactionSet: pkg,
+			index:              tp.transactionSetMapping[tSet.ID],
+		})
+	}
+	heap.Init(&queue)
+
+	tSets := make([]poolTransactionSet, 0, len(tp.transactionSets))
+	for queue.Len() > 0 {
+		next := heap.Pop(&queue).(transactionSetPackage)
+		pkg, ok := packages[next.ID]
+		if !ok || pkg.Size != next.Size {
+			// the transaction set is already ordered,
+			// or its package has shrunk since it was queued
+			continue
+		}
+
+		// order the package, its transaction sets in the order they were added to the pool,
+		// which ensures a transaction set is never ordered prior to a set it depends on
+		indices := make([]int, 0, len(ancestors[next.ID])+1)
+		indices = append(indices, next.index)
+		for id := range ancestors[next.ID] {
+			indices = append(indices, tp.transactionSetMapping[id])
+		}
+		sort.Ints(indices)
+		changed := make(map[TransactionSetID]struct{})
+		for _, index := range indices {
+			tSet := tp.transactionSets[index]
+			tSets = append(tSets, tSet)
+			delete(packages, tSet.ID)
+			// remove the ordered transaction set from the packages depending on it
+			descendants := make(map[TransactionSetID]struct{})
+			tp.transactionSetDescendants(tSet.ID, children, descendants)
+			for id := range descendants {
+				dpkg, ok := packages[id]
+				if !ok || id == tSet.ID {
+					continue
+				}
+				delete(ancestors[id], tSet.ID)
+				dpkg.Size -= tSet.Size
+				dpkg.Fees = dpkg.Fees.Sub(tSet.Fees)
+				packages[id] = dpkg
+				changed[id] = struct{}{}
+			}
+		}
+		// rank the packages of the depending transaction sets again
+		for id := range changed {
+			if pkg, ok := packages[id]; ok {
+				heap.Push(&queue, transactionSetPackage{
+					poolTransactionSet: pkg,
+					index:              tp.transactionSetMapping[id],
+				})
+			}
+		}
+	}
+	return tSets
+}
+
+type (
+	// transactionSetPackage is the package of a transaction set, as ranked by fee rate,
+	// defining the total size and fees of the transaction set and its unordered ancestors.
+	transactionSetPackage struct {
+		poolTransactionSet
+		index int
+	}
+
+	// transactionSetPackageQueue is a priority queue of transaction set packages,
+	// popping the package with the highest fee rate first, and the transaction set
+	// that was added to the pool first in case of equal fee rates.
+	transactionSetPackageQueue []transactionSetPackage
+)
+
+// Len implements heap.Interface.Len
+func (q transactionSetPackageQueue) Len() int { return len(q) }
+
+// Less implements heap.Interface.Less
+func (q transactionSetPackageQueue) Less(i, j int) bool {
+	if c := q[i].cmpFeeRate(q[j].poolTransactionSet); c != 0 {
+		return c > 0
+	}
+	return q[i].index < q[j].index
+}
+
+// Swap implements heap.Interface.Swap
+func (q transactionSetPackageQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+// Push implements heap.Interface.Push
+func (q *transactionSetPackageQueue) Push(x interface{}) {
+	*q = append(*q, x.(transactionSetPackage))
+}
+
+// Pop implements heap.Interface.Pop
+func (q *transactionSetPackageQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
@@ -0,0 +1,134 @@
+package transactionpool
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+// transactionSetSize returns the (siabin) byte size of the given transaction set.
+func transactionSetSize(t *testing.T, txns ...types.Transaction) int {
+	pts, err := newPoolTransactionSet(TransactionSetID{}, txns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pts.Size
+}
+
+// TestTransactionSetEviction probes that a full transaction pool evicts
+// the transaction set with the lowest fee rate, together with the sets depending on it,
+// and rejects a transaction set which doesn't pay a high enough fee rate.
+func TestTransactionSetEviction(t *testing.T) {
+	fund := types.NewCurrency64(30e9)
+	chainCts := types.TestnetChainConstants()
+	minFee := chainCts.MinimumTransactionFee
+	tpt, err := createTpoolTesterWithChainConstants(t.Name(), chainCts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	ids, err := tpt.createCoinOutputs(fund, fund, fund, fund, fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := tpt.spendTransaction(ids[0], fund, minFee)
+	child := tpt.spendTransaction(parent.CoinOutputID(0), parent.CoinOutputs[0].Value, minFee.Mul64(2))
+	high := tpt.spendTransaction(ids[1], fund, minFee.Mul64(4))
+	higher := tpt.spendTransaction(ids[2], fund, minFee.Mul64(5))
+	highest := tpt.spendTransaction(ids[3], fund, minFee.Mul64(6))
+	low := tpt.spendTransaction(ids[4], fund, minFee)
+
+	// the pool has only room for the first three sets and half of another
+	tpt.tpool.chainCts.TransactionPool.PoolSizeLimit = transactionSetSize(t, parent) +
+		transactionSetSize(t, child) + transactionSetSize(t, high) + transactionSetSize(t, higher)/2
+	for _, txn := range []types.Transaction{parent, child, high} {
+		err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the parent set has the lowest fee rate, even when ranked together with its child,
+	// and is therefore evicted together with its child
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{higher})
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns := tpt.tpool.TransactionList()
+	if len(txns) != 2 || txns[0].ID() != higher.ID() || txns[1].ID() != high.ID() {
+		t.Fatalf("unexpected transactions after eviction: %v", txns)
+	}
+
+	// room remains for another set
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{highest})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the pool is full, and all sets pay a higher fee rate than the new set
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{low})
+	if err != errFullTransactionPool {
+		t.Fatalf("expected %v, got: %v", errFullTransactionPool, err)
+	}
+	if n := len(tpt.tpool.TransactionList()); n != 3 {
+		t.Fatalf("expected 3 transactions to remain in the pool, found %d", n)
+	}
+}
+
+// TestTransactionListFeeRateOrder probes that the transaction list is ordered by fee rate,
+// ranking a transaction set together with the sets it depends on.
+func TestTransactionListFeeRateOrder(t *testing.T) {
+	fund := types.NewCurrency64(30e9)
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+	minFee := tpt.chainCts.MinimumTransactionFee
+
+	ids, err := tpt.createCoinOutputs(fund, fund, fund, fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := tpt.spendTransaction(ids[0], fund, minFee)
+	child := tpt.spendTransaction(parent.CoinOutputID(0), parent.CoinOutputs[0].Value, minFee.Mul64(10))
+	low := tpt.spendTransaction(ids[1], fund, minFee.Mul64(3))
+	high := tpt.spendTransaction(ids[2], fund, minFee.Mul64(7))
+	for _, txn := range []types.Transaction{low, parent, high, child} {
+		err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checkOrder := func(expected ...types.Transaction) {
+		t.Helper()
+		txns := tpt.tpool.TransactionList()
+		if len(txns) != len(expected) {
+			t.Fatalf("expected %d transactions, found %d", len(expected), len(txns))
+		}
+		for idx, txn := range expected {
+			if txns[idx].ID() != txn.ID() {
+				t.Fatalf("unexpected transaction #%d: %v", idx, txns[idx])
+			}
+		}
+	}
+	// the child pays for its parent, ranking both of them before the low fee set
+	checkOrder(high, parent, child, low)
+
+	// accepting a new set changes the order
+	highest := tpt.spendTransaction(ids[3], fund, minFee.Mul64(20))
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{highest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(highest, high, parent, child, low)
+
+	// confirming a set changes the order
+	err = tpt.cs.addBlock(high)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkOrder(highest, parent, child, low)
+}
@@ -2,14 +2,17 @@ package transactionpool
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 
-	"github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 const (
@@ -25,6 +28,10 @@ var (
 	// been confirmed on the blockchain.
 	bucketConfirmedTransactions = []byte("ConfirmedTransactions")
 
+	// bucketTransactionSets holds every transaction set in the pool,
+	// such that they can be re-admitted when the transaction pool is restarted.
+	bucketTransactionSets = []byte("TransactionSets")
+
 	// errNilConsensusChange is returned if there is no consensus change in the
 	// database.
 	errNilConsensusChange = errors.New("no consensus change found")
@@ -35,7 +42,7 @@ var (
 )
 
 // resetDB deletes all consensus related persistence from the transaction pool.
-func (tp *TransactionPool) resetDB(tx *bolt.Tx) error {
+func (tp *TransactionPool) resetDB(tx kv.Tx) error {
 	err := tx.DeleteBucket(bucketConfirmedTransactions)
 	if err != nil {
 		return err
@@ -64,18 +71,23 @@ func (tp *TransactionPool) initPersist(verbose bool) error {
 	}
 
 	// Open the database file.
-	tp.db, err = persist.OpenDatabase(dbMetadata, filepath.Join(tp.persistDir, dbFilename))
+	tp.db, err = persist.OpenDatabaseWithBackend(dbMetadata, filepath.Join(tp.persistDir, dbFilename), tp.dbBackend)
 	if err != nil {
 		return err
 	}
 
-	// Create the database and get the most recent consensus change.
-	var cc modules.ConsensusChangeID
-	err = tp.db.Update(func(tx *bolt.Tx) error {
+	// Create the database, get the most recent consensus change
+	// and the transaction sets that were in the pool when it was closed.
+	var (
+		cc   modules.ConsensusChangeID
+		sets [][]types.Transaction
+	)
+	err = tp.db.Update(func(tx kv.Tx) error {
 		// Create the database buckets.
 		buckets := [][]byte{
 			bucketRecentConsensusChange,
 			bucketConfirmedTransactions,
+			bucketTransactionSets,
 		}
 		for _, bucket := range buckets {
 			_, err := tx.CreateBucketIfNotExists(bucket)
@@ -84,6 +96,12 @@ func (tp *TransactionPool) initPersist(verbose bool) error {
 			}
 		}
 
+		// Get the persisted transaction sets.
+		sets, err = tp.getTransactionSets(tx)
+		if err != nil {
+			return err
+		}
+
 		// Get the recent consensus change.
 		cc, err = tp.getRecentConsensusChange(tx)
 		if err == errNilConsensusChange {
@@ -100,20 +118,101 @@ func (tp *TransactionPool) initPersist(verbose bool) error {
 	if err == modules.ErrInvalidConsensusChangeID {
 		// Reset and rescan because the consensus set does not recognize the
 		// provided consensus change id.
-		resetErr := tp.db.Update(func(tx *bolt.Tx) error {
+		resetErr := tp.db.Update(func(tx kv.Tx) error {
 			return tp.resetDB(tx)
 		})
 		if resetErr != nil {
 			return resetErr
 		}
-		return tp.consensusSet.ConsensusSetSubscribe(tp, modules.ConsensusChangeBeginning, nil)
+		err = tp.consensusSet.ConsensusSetSubscribe(tp, modules.ConsensusChangeBeginning, nil)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+
+	// Re-admit the persisted transaction sets, now that the pool is synced with the consensus set.
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	return tp.readmitTransactionSets(orderTransactionSets(sets))
+}
+
+// readmitTransactionSets purges the pool, after which the given transaction sets
+// are validated against the current consensus, adding them to the pool once again if they are still valid.
+// The transaction sets that are no longer valid are dropped. All transaction sets
+// are persisted within a single database transaction.
+func (tp *TransactionPool) readmitTransactionSets(sets [][]types.Transaction) error {
+	tp.clearTransactionSets()
+	return tp.db.Update(func(tx kv.Tx) error {
+		err := tp.resetTransactionSets(tx)
+		if err != nil {
+			return err
+		}
+		for _, set := range sets {
+			err = tp.acceptTransactionSet(tx, set)
+			if err == nil {
+				continue
+			}
+			// the transaction set is no longer in the pool,
+			// so remove it from the cache as well
+			tsh, hashErr := crypto.HashObject(set)
+			if hashErr != nil {
+				return hashErr
+			}
+			setID := TransactionSetID(tsh)
+			tp.broadcastCache.delete(setID)
+			tp.log.Println(fmt.Sprintf("[WARN] Dropping transaction set %v, as it is no longer valid: %v", crypto.Hash(setID).String(), err))
+		}
+		return nil
+	})
+}
+
+// orderTransactionSets orders the given transaction sets,
+// such that a transaction set is never ordered prior to a set it depends on.
+func orderTransactionSets(sets [][]types.Transaction) [][]types.Transaction {
+	coinOutputs := make(map[types.CoinOutputID]int)
+	blockStakeOutputs := make(map[types.BlockStakeOutputID]int)
+	for index, set := range sets {
+		for _, txn := range set {
+			for i := range txn.CoinOutputs {
+				coinOutputs[txn.CoinOutputID(uint64(i))] = index
+			}
+			for i := range txn.BlockStakeOutputs {
+				blockStakeOutputs[txn.BlockStakeOutputID(uint64(i))] = index
+			}
+		}
+	}
+
+	ordered := make([][]types.Transaction, 0, len(sets))
+	visited := make(map[int]struct{}, len(sets))
+	var add func(index int)
+	add = func(index int) {
+		if _, ok := visited[index]; ok {
+			return
+		}
+		visited[index] = struct{}{}
+		for _, txn := range sets[index] {
+			for _, ci := range txn.CoinInputs {
+				if parent, ok := coinOutputs[ci.ParentID]; ok {
+					add(parent)
+				}
+			}
+			for _, bsi := range txn.BlockStakeInputs {
+				if parent, ok := blockStakeOutputs[bsi.ParentID]; ok {
+					add(parent)
+				}
+			}
+		}
+		ordered = append(ordered, sets[index])
+	}
+	for index := range sets {
+		add(index)
+	}
+	return ordered
 }
 
 // getRecentConsensusChange returns the most recent consensus change from the
 // database.
-func (tp *TransactionPool) getRecentConsensusChange(tx *bolt.Tx) (cc modules.ConsensusChangeID, err error) {
+func (tp *TransactionPool) getRecentConsensusChange(tx kv.Tx) (cc modules.ConsensusChangeID, err error) {
 	ccBytes := tx.Bucket(bucketRecentConsensusChange).Get(fieldRecentConsensusChange)
 	if ccBytes == nil {
 		return modules.ConsensusChangeID{}, errNilConsensusChange
@@ -124,14 +223,14 @@ func (tp *TransactionPool) getRecentConsensusChange(tx *bolt.Tx) (cc modules.Con
 
 // putRecentConsensusChange updates the most recent consensus change seen by
 // the transaction pool.
-func (tp *TransactionPool) putRecentConsensusChange(tx *bolt.Tx, cc modules.ConsensusChangeID) error {
+func (tp *TransactionPool) putRecentConsensusChange(tx kv.Tx, cc modules.ConsensusChangeID) error {
 	return tx.Bucket(bucketRecentConsensusChange).Put(fieldRecentConsensusChange, cc[:])
 }
 
 // transactionConfirmed returns true if the transaction has been confirmed on
 // the blockchain and false if the transaction has not been confirmed on the
 // blockchain.
-func (tp *TransactionPool) transactionConfirmed(tx *bolt.Tx, id types.TransactionID) bool {
+func (tp *TransactionPool) transactionConfirmed(tx kv.Tx, id types.TransactionID) bool {
 	confirmedBytes := tx.Bucket(bucketConfirmedTransactions).Get(id[:])
 	if confirmedBytes == nil {
 		return false
@@ -140,12 +239,67 @@ func (tp *TransactionPool) transactionConfirmed(tx *bolt.Tx, id types.Transactio
 }
 
 // addTransaction adds a transaction to the list of confirmed transactions.
-func (tp *TransactionPool) addTransaction(tx *bolt.Tx, id types.TransactionID) error {
+func (tp *TransactionPool) addTransaction(tx kv.Tx, id types.TransactionID) error {
 	return tx.Bucket(bucketConfirmedTransactions).Put(id[:], []byte{})
 }
 
 // deleteTransaction deletes a transaction from the list of confirmed
 // transactions.
-func (tp *TransactionPool) deleteTransaction(tx *bolt.Tx, id types.TransactionID) error {
+func (tp *TransactionPool) deleteTransaction(tx kv.Tx, id types.TransactionID) error {
 	return tx.Bucket(bucketConfirmedTransactions).Delete(id[:])
 }
+
+// getTransactionSets returns all transaction sets persisted in the database.
+// Transaction sets that can't be decoded are dropped.
+func (tp *TransactionPool) getTransactionSets(tx kv.Tx) ([][]types.Transaction, error) {
+	var (
+		sets    [][]types.Transaction
+		invalid [][]byte
+	)
+	bucket := tx.Bucket(bucketTransactionSets)
+	err := bucket.ForEach(func(k, v []byte) error {
+		var set []types.Transaction
+		err := siabin.Unmarshal(v, &set)
+		if err != nil {
+			tp.log.Println(fmt.Sprintf("[WARN] Dropping persisted transaction set %x, as it can't be (siabin) unmarshaled: %v", k, err))
+			invalid = append(invalid, append([]byte(nil), k...))
+			return nil
+		}
+		sets = append(sets, set)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range invalid {
+		err = bucket.Delete(k)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
+
+// putTransactionSet adds a transaction set to the persisted transaction sets.
+func (tp *TransactionPool) putTransactionSet(tx kv.Tx, id TransactionSetID, ts []types.Transaction) error {
+	b, err := siabin.Marshal(ts)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketTransactionSets).Put(id[:], b)
+}
+
+// deleteTransactionSet deletes a transaction set from the persisted transaction sets.
+func (tp *TransactionPool) deleteTransactionSet(tx kv.Tx, id TransactionSetID) error {
+	return tx.Bucket(bucketTransactionSets).Delete(id[:])
+}
+
+// resetTransactionSets deletes all persisted transaction sets.
+func (tp *TransactionPool) resetTransactionSets(tx kv.Tx) error {
+	err := tx.DeleteBucket(bucketTransactionSets)
+	if err != nil {
+		return err
+	}
+	_, err = tx.CreateBucket(bucketTransactionSets)
+	return err
+}
@@ -1,86 +1,203 @@
 package transactionpool
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
+	"github.com/threefoldtech/rivine/types"
+)
 
 // TestRescan triggers a rescan in the transaction pool, verifying that the
 // rescan code does not cause deadlocks or crashes.
 func TestRescan(t *testing.T) {
-	//TODO: fix test
-	// if testing.Short() {
-	// 	t.SkipNow()
-	// }
-	//
-	// tpt, err := createTpoolTester(t.Name())
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// defer tpt.Close()
-	//
-	// // Create a valid transaction set using the wallet.
-	// txns, err := tpt.wallet.SendSiacoins(types.NewCurrency64(100), types.UnlockHash{})
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// if len(tpt.tpool.transactionSets) != 1 {
-	// 	t.Error("sending coins did not increase the transaction sets by 1")
-	// }
-	// // Mine the transaction into a block, so that it's in the consensus set.
-	// _, err = tpt.miner.AddBlock()
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	//
-	// // Close the tpool, delete the persistence, then restart the tpool. The
-	// // tpool should still recognize the transaction set as a duplicate.
-	// persistDir := tpt.tpool.persistDir
-	// err = tpt.tpool.Close()
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// err = os.RemoveAll(persistDir)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// err = tpt.tpool.AcceptTransactionSet(txns)
-	// if err != modules.ErrDuplicateTransactionSet {
-	// 	t.Fatal("expecting modules.ErrDuplicateTransactionSet, got:", err)
-	// }
-	//
-	// // Close the tpool, corrupt the database, then restart the tpool. The tpool
-	// // should still recognize the transaction set as a duplicate.
-	// err = tpt.tpool.Close()
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// db, err := persist.OpenDatabase(dbMetadata, filepath.Join(persistDir, dbFilename))
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// err = db.Update(func(tx *bolt.Tx) error {
-	// 	ccBytes := tx.Bucket(bucketRecentConsensusChange).Get(fieldRecentConsensusChange)
-	// 	// copy the bytes due to bolt's mmap.
-	// 	newCCBytes := make([]byte, len(ccBytes))
-	// 	copy(newCCBytes, ccBytes)
-	// 	newCCBytes[0]++
-	// 	return tx.Bucket(bucketRecentConsensusChange).Put(fieldRecentConsensusChange, newCCBytes)
-	// })
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// err = db.Close()
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// tpt.tpool, err = New(tpt.cs, tpt.gateway, persistDir)
-	// if err != nil {
-	// 	t.Fatal(err)
-	// }
-	// err = tpt.tpool.AcceptTransactionSet(txns)
-	// if err != modules.ErrDuplicateTransactionSet {
-	// 	t.Fatal("expecting modules.ErrDuplicateTransactionSet, got:", err)
-	// }
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	// Create a valid transaction set.
+	fund := types.NewCurrency64(30e9)
+	ids, err := tpt.createCoinOutputs(fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txns := []types.Transaction{tpt.spendTransaction(ids[0], fund, tpt.chainCts.MinimumTransactionFee)}
+	err = tpt.tpool.AcceptTransactionSet(txns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tpt.tpool.transactionSets) != 1 {
+		t.Error("accepting a transaction set did not increase the transaction sets by 1")
+	}
+	// Confirm the transaction set in a block, so that it's in the consensus set.
+	err = tpt.cs.addBlock(txns...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Close the tpool, delete the persistence, then restart the tpool. The
+	// tpool should still recognize the transaction set as a duplicate.
+	persistDir := tpt.tpool.persistDir
+	err = tpt.tpool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.RemoveAll(persistDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.AcceptTransactionSet(txns)
+	if err != modules.ErrDuplicateTransactionSet {
+		t.Fatal("expecting modules.ErrDuplicateTransactionSet, got:", err)
+	}
+
+	// Close the tpool, corrupt the database, then restart the tpool. The tpool
+	// should still recognize the transaction set as a duplicate.
+	err = tpt.tpool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := persist.OpenDatabase(dbMetadata, filepath.Join(persistDir, dbFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx kv.Tx) error {
+		ccBytes := tx.Bucket(bucketRecentConsensusChange).Get(fieldRecentConsensusChange)
+		// copy the bytes due to bolt's mmap.
+		newCCBytes := make([]byte, len(ccBytes))
+		copy(newCCBytes, ccBytes)
+		newCCBytes[0]++
+		return tx.Bucket(bucketRecentConsensusChange).Put(fieldRecentConsensusChange, newCCBytes)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.AcceptTransactionSet(txns)
+	if err != modules.ErrDuplicateTransactionSet {
+		t.Fatal("expecting modules.ErrDuplicateTransactionSet, got:", err)
+	}
+}
+
+// TestPersistTransactionSets probes that the transaction sets in the pool are re-admitted
+// when the pool is restarted, in the order of their dependencies,
+// dropping the persisted transaction sets that are invalid or confirmed in the meantime.
+func TestPersistTransactionSets(t *testing.T) {
+	tpt, err := createTpoolTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	fund := types.NewCurrency64(30e9)
+	fee := tpt.chainCts.MinimumTransactionFee
+	ids, err := tpt.createCoinOutputs(fund, fund, fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parent := tpt.spendTransaction(ids[0], fund, fee)
+	child := tpt.spendTransaction(parent.CoinOutputID(0), parent.CoinOutputs[0].Value, fee)
+	confirmed := tpt.spendTransaction(ids[1], fund, fee)
+	independent := tpt.spendTransaction(ids[2], fund, fee)
+	invalid := tpt.spendTransaction(types.CoinOutputID{1}, fund, fee)
+	for _, txn := range []types.Transaction{parent, child, confirmed, independent} {
+		err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// confirm a transaction while the pool is closed
+	err = tpt.tpool.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.cs.addBlock(confirmed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// persist the child prior to its parent, together with an invalid and an undecodable set
+	db, err := persist.OpenDatabase(dbMetadata, filepath.Join(tpt.persistDir, modules.TransactionPoolDir, dbFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx kv.Tx) error {
+		err := tpt.tpool.resetTransactionSets(tx)
+		if err != nil {
+			return err
+		}
+		for i, txn := range []types.Transaction{child, confirmed, invalid, independent, parent} {
+			err = tpt.tpool.putTransactionSet(tx, TransactionSetID{byte(i)}, []types.Transaction{txn})
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketTransactionSets).Put([]byte{0xff}, []byte{1, 2, 3})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkPool := func() {
+		t.Helper()
+		expected := map[types.TransactionID]struct{}{
+			parent.ID():      {},
+			child.ID():       {},
+			independent.ID(): {},
+		}
+		txns := tpt.tpool.TransactionList()
+		if len(txns) != len(expected) {
+			t.Fatalf("expected %d transactions in the pool, found %d", len(expected), len(txns))
+		}
+		for _, txn := range txns {
+			if _, ok := expected[txn.ID()]; !ok {
+				t.Fatalf("unexpected transaction in the pool: %v", txn)
+			}
+		}
+		var persisted int
+		err := tpt.tpool.db.View(func(tx kv.Tx) error {
+			return tx.Bucket(bucketTransactionSets).ForEach(func(_, _ []byte) error {
+				persisted++
+				return nil
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if persisted != len(expected) {
+			t.Fatalf("expected %d persisted transaction sets, found %d", len(expected), persisted)
+		}
+	}
+
+	err = tpt.open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPool()
+	err = tpt.reopen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPool()
 }
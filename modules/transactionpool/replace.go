@@ -0,0 +1,107 @@
+package transactionpool
+
+import (
+	"errors"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+const (
+	// maxReplacedTransactionSets defines the maximum amount of transaction sets
+	// that can be evicted from the pool by a single replacement,
+	// including all transaction sets that depend on the conflicting transaction sets.
+	maxReplacedTransactionSets = 100
+)
+
+var (
+	errReplacementLowFees        = errors.New("replacement transaction set has to pay a higher total fee than the transaction sets it replaces, increased by at least the minimum transaction fee")
+	errReplacementLowFeeRate     = errors.New("replacement transaction set has to pay a higher fee rate than the transaction sets it conflicts with")
+	errReplacementTooManySets    = errors.New("replacement transaction set would evict too many transaction sets from the pool")
+	errReplacementSpendsReplaced = errors.New("replacement transaction set cannot depend on a transaction set it replaces")
+)
+
+// conflictingTransactionSets returns the IDs of all transaction sets in the pool
+// which spend an output that is spent by the given transactions as well.
+func (tp *TransactionPool) conflictingTransactionSets(ts []types.Transaction) []TransactionSetID {
+	coinInputs := make(map[types.CoinOutputID]TransactionSetID)
+	blockStakeInputs := make(map[types.BlockStakeOutputID]TransactionSetID)
+	for _, tSet := range tp.transactionSets {
+		for _, txn := range tSet.Transactions {
+			for _, ci := range txn.CoinInputs {
+				coinInputs[ci.ParentID] = tSet.ID
+			}
+			for _, bsi := range txn.BlockStakeInputs {
+				blockStakeInputs[bsi.ParentID] = tSet.ID
+			}
+		}
+	}
+
+	var conflicts []TransactionSetID
+	known := make(map[TransactionSetID]struct{})
+	addConflict := func(id TransactionSetID) {
+		if _, ok := known[id]; !ok {
+			known[id] = struct{}{}
+			conflicts = append(conflicts, id)
+		}
+	}
+	for _, txn := range ts {
+		for _, ci := range txn.CoinInputs {
+			if id, ok := coinInputs[ci.ParentID]; ok {
+				addConflict(id)
+			}
+		}
+		for _, bsi := range txn.BlockStakeInputs {
+			if id, ok := blockStakeInputs[bsi.ParentID]; ok {
+				addConflict(id)
+			}
+		}
+	}
+	return conflicts
+}
+
+// transactionSetsToReplace returns the IDs of the transaction sets that have to be evicted
+// from the pool in order for the given transaction set to replace the transaction sets
+// it conflicts with, which includes all transaction sets that depend on the conflicting ones.
+// An empty set of IDs is returned if the given transaction set conflicts with no transaction set.
+//
+// A transaction set can only replace the transaction sets it conflicts with, if:
+//
+//   - it pays a strictly higher fee rate than each of the transaction sets it conflicts with;
+//   - it pays a higher total fee than all transaction sets it replaces together,
+//     increased with at least the minimum transaction fee;
+//   - no more than maxReplacedTransactionSets transaction sets are replaced;
+//   - it does not depend on any of the transaction sets it replaces.
+func (tp *TransactionPool) transactionSetsToReplace(pts poolTransactionSet) (map[TransactionSetID]struct{}, error) {
+	replaced := make(map[TransactionSetID]struct{})
+	conflicts := tp.conflictingTransactionSets(pts.Transactions)
+	if len(conflicts) == 0 {
+		return replaced, nil
+	}
+
+	children := tp.transactionSetChildren()
+	for _, id := range conflicts {
+		conflict, _ := tp.transactionSetByID(id)
+		if pts.cmpFeeRate(conflict) <= 0 {
+			return nil, errReplacementLowFeeRate
+		}
+		tp.transactionSetDescendants(id, children, replaced)
+	}
+	if len(replaced) > maxReplacedTransactionSets {
+		return nil, errReplacementTooManySets
+	}
+
+	ancestors := make(map[TransactionSetID]struct{})
+	tp.transactionSetAncestors(pts.Parents, ancestors)
+	var replacedFees types.Currency
+	for id := range replaced {
+		if _, ok := ancestors[id]; ok {
+			return nil, errReplacementSpendsReplaced
+		}
+		tSet, _ := tp.transactionSetByID(id)
+		replacedFees = replacedFees.Add(tSet.Fees)
+	}
+	if pts.Fees.Cmp(replacedFees) <= 0 || pts.Fees.Cmp(replacedFees.Add(tp.chainCts.MinimumTransactionFee)) < 0 {
+		return nil, errReplacementLowFees
+	}
+	return replaced, nil
+}
@@ -29,3 +29,33 @@ func (tp *TransactionPool) ValidateTransactionSetSize(ts []types.Transaction) er
 	}
 	return nil
 }
+
+// ValidateTransactionSetActivation validates that all transactions only use
+// transaction versions, unlock condition types and unlock fulfillment types
+// which are active at the next block height, the first height at which they could be included in a block,
+// as defined by the activation schedule of the chain constants.
+// Transactions that use them prior to their activation are considered non-standard.
+func (tp *TransactionPool) ValidateTransactionSetActivation(ts []types.Transaction) error {
+	height := tp.consensusSet.Height() + 1
+	for _, t := range ts {
+		err := tp.chainCts.ActivationSchedule.ValidateTransaction(t, height)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateTransactionSetExpiry validates that none of the transactions
+// expire prior to the next block height, the first height at which they could be included in a block.
+// Transactions that expire are evicted from the pool as part of the next consensus change.
+func (tp *TransactionPool) ValidateTransactionSetExpiry(ts []types.Transaction) error {
+	height := tp.consensusSet.Height() + 1
+	for _, t := range ts {
+		err := t.ValidateValidUntil(height)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
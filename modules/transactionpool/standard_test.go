@@ -1,9 +1,10 @@
 package transactionpool
 
 import (
-	"crypto/rand"
 	"testing"
 
+	"github.com/NebulousLabs/fastrand"
+
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -11,10 +12,6 @@ import (
 // TestIntegrationLargeTransactions tries to add a large transaction to the
 // transaction pool.
 func TestIntegrationLargeTransactions(t *testing.T) {
-	if testing.Short() {
-		t.SkipNow()
-	}
-
 	tpt, err := createTpoolTester(t.Name())
 	if err != nil {
 		t.Fatal(err)
@@ -22,15 +19,9 @@ func TestIntegrationLargeTransactions(t *testing.T) {
 	defer tpt.Close()
 
 	// Create a large transaction and try to get it accepted.
-	arbData := make([]byte, modules.TransactionSizeLimit)
-	copy(arbData, modules.PrefixNonSia[:])
-	_, err = rand.Read(arbData[100:116]) // prevents collisions with other transacitons in the loop.
-	if err != nil {
-		t.Fatal(err)
-	}
 	txn := types.Transaction{
 		Version:       tpt.tpool.chainCts.DefaultTransactionVersion,
-		ArbitraryData: arbData,
+		ArbitraryData: fastrand.Bytes(tpt.chainCts.TransactionPool.TransactionSizeLimit),
 	}
 	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
 	if err != modules.ErrLargeTransaction {
@@ -39,16 +30,10 @@ func TestIntegrationLargeTransactions(t *testing.T) {
 
 	// Create a large transaction set and try to get it accepted.
 	var tset []types.Transaction
-	for i := 0; i <= modules.TransactionSetSizeLimit/10e3; i++ {
-		arbData := make([]byte, 10e3)
-		copy(arbData, modules.PrefixNonSia[:])
-		_, err = rand.Read(arbData[100:116]) // prevents collisions with other transacitons in the loop.
-		if err != nil {
-			t.Fatal(err)
-		}
+	for i := 0; i <= tpt.chainCts.TransactionPool.TransactionSetSizeLimit/10e3; i++ {
 		txn := types.Transaction{
 			Version:       tpt.tpool.chainCts.DefaultTransactionVersion,
-			ArbitraryData: arbData,
+			ArbitraryData: fastrand.Bytes(10e3), // prevents collisions with other transactions in the loop.
 		}
 		tset = append(tset, txn)
 	}
@@ -57,3 +42,108 @@ func TestIntegrationLargeTransactions(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestTransactionSetExpiry probes that the transaction pool only accepts
+// transactions which can still be included in the next block.
+func TestTransactionSetExpiry(t *testing.T) {
+	chainCts := types.TestnetChainConstants()
+	defer types.RegisterTransactionVersion(types.TransactionVersionThree, nil)
+	types.RegisterTransactionVersion(types.TransactionVersionThree, types.ValidUntilTransactionController{
+		ChainID: types.DefaultBlockchainInfo().ChainID(chainCts.GenesisBlockID()),
+	})
+
+	tpt, err := createTpoolTesterWithChainConstants(t.Name(), chainCts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+	for i := 0; i < 3; i++ {
+		err = tpt.cs.addBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the next block is at height 4
+	testCases := []struct {
+		ValidUntil types.BlockHeight
+		Expired    bool
+	}{
+		{0, false},
+		{3, true},
+		{4, false},
+		{5, false},
+	}
+	for idx, testCase := range testCases {
+		txn := types.Transaction{
+			Version:       types.TransactionVersionThree,
+			ArbitraryData: fastrand.Bytes(16),
+			Extension:     &types.ValidUntilTransactionExtension{ValidUntil: testCase.ValidUntil},
+		}
+		err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+		if testCase.Expired && err == nil {
+			t.Errorf("#%d: expected transaction valid until height %d to be refused", idx, testCase.ValidUntil)
+		} else if !testCase.Expired && err != nil {
+			t.Errorf("#%d: expected transaction valid until height %d to be accepted: %v", idx, testCase.ValidUntil, err)
+		}
+	}
+
+	// an expired transaction is evicted as part of the next consensus change
+	err = tpt.cs.addBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var validUntil []types.BlockHeight
+	for _, txn := range tpt.tpool.TransactionList() {
+		height, _ := txn.ValidUntil()
+		validUntil = append(validUntil, height)
+	}
+	if len(validUntil) != 2 {
+		t.Fatalf("expected 2 transactions to remain in the pool, found transactions valid until: %v", validUntil)
+	}
+	for _, height := range validUntil {
+		if height == 4 {
+			t.Fatal("expected the transaction valid until height 4 to be evicted")
+		}
+	}
+}
+
+// TestTransactionSetActivation probes that the transaction pool accepts transactions
+// using a scheduled condition type, as soon as it is activated at the height of the next block.
+func TestTransactionSetActivation(t *testing.T) {
+	chainCts := types.TestnetChainConstants()
+	chainCts.ActivationSchedule.ConditionTypes = map[types.ConditionType]types.BlockHeight{
+		types.ConditionTypeTimeLock: 3,
+	}
+	tpt, err := createTpoolTesterWithChainConstants(t.Name(), chainCts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tpt.Close()
+
+	txn := types.Transaction{
+		Version: chainCts.DefaultTransactionVersion,
+		CoinOutputs: []types.CoinOutput{{
+			Value:     types.NewCurrency64(1),
+			Condition: types.NewCondition(types.NewTimeLockCondition(42, &types.NilCondition{})),
+		}},
+	}
+	// the next block is at height 2
+	err = tpt.cs.addBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != types.ErrConditionTypeNotActivated {
+		t.Fatalf("expected %v, got: %v", types.ErrConditionTypeNotActivated, err)
+	}
+	// the next block is at height 3, the activation height
+	err = tpt.cs.addBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
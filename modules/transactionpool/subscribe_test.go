@@ -26,10 +26,6 @@ func (ms *mockSubscriber) ReceiveUpdatedUnconfirmedTransactions(txns []types.Tra
 // shortens the list of subscribers to the transaction pool by 1 (doesn't
 // actually check that the mockSubscriber was the one unsubscribed).
 func TestSubscription(t *testing.T) {
-	if testing.Short() {
-		t.Skip()
-	}
-
 	tpt, err := createTpoolTester(t.Name())
 	if err != nil {
 		t.Fatal(err)
@@ -50,7 +46,12 @@ func TestSubscription(t *testing.T) {
 
 	// Create a valid transaction set and check that the mock subscriber's
 	// transaction list is updated.
-	_, err = tpt.wallet.SendCoins(types.NewCurrency64(100), types.UnlockHash{}, nil)
+	fund := types.NewCurrency64(30e9)
+	ids, err := tpt.createCoinOutputs(fund)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tpt.tpool.AcceptTransactionSet([]types.Transaction{tpt.spendTransaction(ids[0], fund, tpt.chainCts.MinimumTransactionFee)})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -59,7 +60,7 @@ func TestSubscription(t *testing.T) {
 	}
 	numTxns := 0
 	for _, txnSet := range tpt.tpool.transactionSets {
-		numTxns += len(txnSet)
+		numTxns += len(txnSet.Transactions)
 	}
 	if len(ms.txns) != numTxns {
 		t.Errorf("mock subscriber should've received %v transactions; received %v instead", numTxns, len(ms.txns))
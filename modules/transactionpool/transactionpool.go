@@ -3,6 +3,7 @@ package transactionpool
 import (
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/NebulousLabs/demotemutex"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
 	"github.com/threefoldtech/rivine/persist"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/types"
 )
 
@@ -34,6 +36,16 @@ type (
 	poolTransactionSet struct {
 		ID           TransactionSetID
 		Transactions []types.Transaction
+
+		// Size is the (siabin) byte size of the transaction set,
+		// and Fees the total amount of miner fees paid by its transactions,
+		// together defining the fee rate of the transaction set.
+		Size int
+		Fees types.Currency
+
+		// Parents contains the IDs of the transaction sets in the pool,
+		// at the time this set was accepted, that create outputs spent by this set.
+		Parents []TransactionSetID
 	}
 
 	// The TransactionPool tracks incoming transactions, accepting them or
@@ -50,6 +62,12 @@ type (
 		transactionSetMapping map[TransactionSetID]int
 		transactionSetDiffs   map[TransactionSetID]modules.ConsensusChange
 		transactionListSize   int
+
+		// feeRateOrder caches the transaction sets ordered by fee rate,
+		// as returned by transactionSetsByFeeRate, until the pool changes.
+		// It is guarded by feeRateOrderMu, as it is computed while only the pool is read-locked.
+		feeRateOrder   []poolTransactionSet
+		feeRateOrderMu sync.Mutex
 		// TODO: Write a consistency check comparing transactionSets,
 		// transactionSetDiffs.
 		//
@@ -65,8 +83,12 @@ type (
 		// broadcastCache keeps track of all transaction sets currently in the pool.
 		broadcastCache transactionCache
 
+		// feeEstimator tracks how long the transactions in the pool wait to be confirmed.
+		feeEstimator *feeEstimator
+
 		// Utilities.
-		db         *persist.BoltDatabase
+		db         *persist.Database
+		dbBackend  kv.Backend
 		mu         demotemutex.DemoteMutex
 		persistDir string
 
@@ -78,7 +100,14 @@ type (
 )
 
 // New creates a transaction pool that is ready to receive transactions.
+// The database is stored using the default (bolt) storage backend.
 func New(cs modules.ConsensusSet, g modules.Gateway, persistDir string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, verbose bool) (*TransactionPool, error) {
+	return NewWithBackend(cs, g, persistDir, bcInfo, chainCts, verbose, kv.BackendBolt)
+}
+
+// NewWithBackend creates a transaction pool, the same way as New does,
+// storing its database using the given storage backend.
+func NewWithBackend(cs modules.ConsensusSet, g modules.Gateway, persistDir string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, verbose bool, dbBackend kv.Backend) (*TransactionPool, error) {
 	// Check that the input modules are non-nil.
 	if cs == nil {
 		return nil, errNilCS
@@ -97,8 +126,10 @@ func New(cs modules.ConsensusSet, g modules.Gateway, persistDir string, bcInfo t
 		transactionSetDiffs:   make(map[TransactionSetID]modules.ConsensusChange),
 
 		broadcastCache: newTransactionCache(),
+		feeEstimator:   newFeeEstimator(),
 
 		persistDir: persistDir,
+		dbBackend:  dbBackend,
 
 		bcInfo:   bcInfo,
 		chainCts: chainCts,
@@ -133,7 +164,9 @@ func (tp *TransactionPool) Close() error {
 
 // TransactionList returns a list of all transactions in the transaction pool.
 // The transactions are provided in an order that can acceptably be put into a
-// block.
+// block, ordered by the fee rate of their transaction set, the most profitable first.
+// A transaction set is ranked together with the transaction sets in the pool it depends on,
+// such that a child transaction can pay for the fee of its parent transaction.
 func (tp *TransactionPool) TransactionList() []types.Transaction {
 	tp.mu.RLock()
 	defer tp.mu.RUnlock()
@@ -141,7 +174,7 @@ func (tp *TransactionPool) TransactionList() []types.Transaction {
 }
 func (tp *TransactionPool) transactionList() []types.Transaction {
 	var txns []types.Transaction
-	for _, tSet := range tp.transactionSets {
+	for _, tSet := range tp.transactionSetsByFeeRate() {
 		txns = append(txns, tSet.Transactions...)
 	}
 	return txns
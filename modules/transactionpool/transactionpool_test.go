@@ -1,104 +1,68 @@
 package transactionpool
 
 import (
-	"crypto/rand"
+	"errors"
 	"path/filepath"
 	"testing"
 
+	"github.com/NebulousLabs/fastrand"
+
 	"github.com/threefoldtech/rivine/build"
-	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
-	"github.com/threefoldtech/rivine/modules/consensus"
 	"github.com/threefoldtech/rivine/modules/gateway"
-	"github.com/threefoldtech/rivine/modules/wallet"
+	"github.com/threefoldtech/rivine/types"
 )
 
 // A tpoolTester is used during testing to initialize a transaction pool and
 // useful helper modules.
 type tpoolTester struct {
-	cs      modules.ConsensusSet
+	cs      *consensusSetStub
 	gateway modules.Gateway
 	tpool   *TransactionPool
-	//	miner     modules.TestMiner
-	wallet    modules.Wallet
-	walletKey crypto.TwofishKey
 
+	chainCts   types.ChainConstants
 	persistDir string
 }
 
 // createTpoolTester returns a ready-to-use tpool tester, with all modules
-// initialized.
+// initialized, using the testnet chain constants.
 func createTpoolTester(name string) (*tpoolTester, error) {
+	return createTpoolTesterWithChainConstants(name, types.TestnetChainConstants())
+}
+
+// createTpoolTesterWithChainConstants returns a ready-to-use tpool tester, with all modules
+// initialized, using the given chain constants.
+func createTpoolTesterWithChainConstants(name string, chainCts types.ChainConstants) (*tpoolTester, error) {
+	bcInfo := types.DefaultBlockchainInfo()
 	// Initialize the modules.
 	testdir := build.TempDir(modules.TransactionPoolDir, name)
-	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
-	if err != nil {
-		return nil, err
-	}
-	cs, err := consensus.New(g, false, filepath.Join(testdir, modules.ConsensusDir))
+	g, err := gateway.New("localhost:0", false, 1, filepath.Join(testdir, modules.GatewayDir), bcInfo, chainCts, nil, false)
 	if err != nil {
 		return nil, err
 	}
-	tp, err := New(cs, g, filepath.Join(testdir, modules.TransactionPoolDir))
+	cs := newConsensusSetStub(chainCts)
+	tp, err := New(cs, g, filepath.Join(testdir, modules.TransactionPoolDir), bcInfo, chainCts, false)
 	if err != nil {
 		return nil, err
 	}
-	w, err := wallet.New(cs, tp, filepath.Join(testdir, modules.WalletDir))
-	if err != nil {
-		return nil, err
-	}
-	var key crypto.TwofishKey
-	_, err = rand.Read(key[:])
-	if err != nil {
-		return nil, err
-	}
-	_, err = w.Encrypt(key)
-	if err != nil {
-		return nil, err
-	}
-	err = w.Unlock(key)
-	if err != nil {
-		return nil, err
-	}
-	// m, err := miner.New(cs, tp, w, filepath.Join(testdir, modules.MinerDir))
-	// if err != nil {
-	// 	return nil, err
-	// }
 
 	// Assemble all of the objects into a tpoolTester
-	tpt := &tpoolTester{
+	return &tpoolTester{
 		cs:      cs,
 		gateway: g,
 		tpool:   tp,
-		//	miner:     m,
-		wallet:    w,
-		walletKey: key,
 
+		chainCts:   chainCts,
 		persistDir: testdir,
-	}
-
-	// Mine blocks until there is money in the wallet.
-	// for i := types.BlockHeight(0); i <= types.MaturityDelay; i++ {
-	// 	b, _ := tpt.miner.FindBlock()
-	// 	err = tpt.cs.AcceptBlock(b)
-	// 	if err != nil {
-	// 		return nil, err
-	// 	}
-	// }
-
-	return tpt, nil
+	}, nil
 }
 
 // Close safely closes the tpoolTester, calling a panic in the event of an
 // error since there isn't a good way to errcheck when deferring a Close.
 func (tpt *tpoolTester) Close() error {
 	errs := []error{
-		tpt.cs.Close(),
-		tpt.gateway.Close(),
 		tpt.tpool.Close(),
-		//		tpt.miner.Close(),
-		// TODO: implement modules.Wallet.Close()
-		// tpt.wallet.Close()
+		tpt.gateway.Close(),
 	}
 	if err := build.JoinErrors(errs, "; "); err != nil {
 		build.Critical(err)
@@ -106,35 +70,248 @@ func (tpt *tpoolTester) Close() error {
 	return nil
 }
 
+// reopen closes the transaction pool and opens it again, using the same persist directory.
+func (tpt *tpoolTester) reopen() error {
+	err := tpt.tpool.Close()
+	if err != nil {
+		return err
+	}
+	return tpt.open()
+}
+
+// open opens a new transaction pool, using the persist directory of the tester.
+// The previous transaction pool has to be closed already.
+func (tpt *tpoolTester) open() (err error) {
+	tpt.tpool, err = New(tpt.cs, tpt.gateway, filepath.Join(tpt.persistDir, modules.TransactionPoolDir), types.DefaultBlockchainInfo(), tpt.chainCts, false)
+	return err
+}
+
+// createCoinOutputs confirms a transaction creating coin outputs of the given values,
+// returning the IDs of the created coin outputs.
+func (tpt *tpoolTester) createCoinOutputs(values ...types.Currency) ([]types.CoinOutputID, error) {
+	txn := types.Transaction{
+		Version:       tpt.chainCts.DefaultTransactionVersion,
+		ArbitraryData: fastrand.Bytes(16), // prevents collisions with other transactions
+	}
+	for _, value := range values {
+		txn.CoinOutputs = append(txn.CoinOutputs, types.CoinOutput{
+			Value:     value,
+			Condition: types.NewCondition(types.NewUnlockHashCondition(types.UnlockHash{Type: types.UnlockTypePubKey})),
+		})
+	}
+	err := tpt.cs.addBlock(txn)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]types.CoinOutputID, 0, len(values))
+	for i := range values {
+		ids = append(ids, txn.CoinOutputID(uint64(i)))
+	}
+	return ids, nil
+}
+
+// spendTransaction creates a transaction spending the coin output with the given ID and value,
+// paying the given miner fee and sending the remaining value to a single coin output.
+func (tpt *tpoolTester) spendTransaction(id types.CoinOutputID, value, fee types.Currency) types.Transaction {
+	return types.Transaction{
+		Version: tpt.chainCts.DefaultTransactionVersion,
+		CoinInputs: []types.CoinInput{{
+			ParentID: id,
+		}},
+		CoinOutputs: []types.CoinOutput{{
+			Value:     value.Sub(fee),
+			Condition: types.NewCondition(types.NewUnlockHashCondition(types.UnlockHash{Type: types.UnlockTypePubKey})),
+		}},
+		MinerFees: []types.Currency{fee},
+	}
+}
+
 // TestIntegrationNewNilInputs tries to trigger a panic with nil inputs.
 func TestIntegrationNewNilInputs(t *testing.T) {
+	bcInfo := types.DefaultBlockchainInfo()
+	chainCts := types.TestnetChainConstants()
 	// Create a gateway and consensus set.
 	testdir := build.TempDir(modules.TransactionPoolDir, t.Name())
-	g, err := gateway.New("localhost:0", false, filepath.Join(testdir, modules.GatewayDir))
-	if err != nil {
-		t.Fatal(err)
-	}
-	cs, err := consensus.New(g, false, filepath.Join(testdir, modules.ConsensusDir))
+	g, err := gateway.New("localhost:0", false, 1, filepath.Join(testdir, modules.GatewayDir), bcInfo, chainCts, nil, false)
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer g.Close()
+	cs := newConsensusSetStub(chainCts)
 	tpDir := filepath.Join(testdir, modules.TransactionPoolDir)
 
 	// Try all combinations of nil inputs.
-	_, err = New(nil, nil, tpDir)
+	_, err = New(nil, nil, tpDir, bcInfo, chainCts, false)
 	if err == nil {
 		t.Error(err)
 	}
-	_, err = New(nil, g, tpDir)
+	_, err = New(nil, g, tpDir, bcInfo, chainCts, false)
 	if err != errNilCS {
 		t.Error(err)
 	}
-	_, err = New(cs, nil, tpDir)
+	_, err = New(cs, nil, tpDir, bcInfo, chainCts, false)
 	if err != errNilGateway {
 		t.Error(err)
 	}
-	_, err = New(cs, g, tpDir)
+	tp, err := New(cs, g, tpDir, bcInfo, chainCts, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tp.Close()
 	if err != nil {
 		t.Error(err)
 	}
 }
+
+var (
+	errStubMissingCoinOutput = errors.New("coin output does not exist or is already spent")
+)
+
+// consensusSetStub is a minimal consensus set, only implementing the methods used by the transaction pool.
+// It tracks the unspent coin outputs of its blocks, such that it validates
+// that the coin inputs of a transaction spend existing outputs, but does not validate anything else.
+type consensusSetStub struct {
+	modules.ConsensusSet
+
+	blocks      []types.Block
+	subscribers []modules.ConsensusSetSubscriber
+}
+
+func newConsensusSetStub(chainCts types.ChainConstants) *consensusSetStub {
+	return &consensusSetStub{
+		blocks: []types.Block{chainCts.GenesisBlock()},
+	}
+}
+
+// addBlock applies a block containing the given transactions.
+func (css *consensusSetStub) addBlock(txns ...types.Transaction) error {
+	_, err := css.TryTransactionSet(txns)
+	if err != nil {
+		return err
+	}
+	block := types.Block{
+		ParentID:     css.CurrentBlock().ID(),
+		Timestamp:    css.CurrentBlock().Timestamp + 1,
+		Transactions: txns,
+	}
+	css.blocks = append(css.blocks, block)
+	for _, subscriber := range css.subscribers {
+		subscriber.ProcessConsensusChange(modules.ConsensusChange{
+			ID:            consensusChangeIDOfBlock(block),
+			AppliedBlocks: []types.Block{block},
+			Synced:        true,
+		})
+	}
+	return nil
+}
+
+// revertBlock reverts the current block.
+func (css *consensusSetStub) revertBlock() {
+	block := css.CurrentBlock()
+	css.blocks = css.blocks[:len(css.blocks)-1]
+	for _, subscriber := range css.subscribers {
+		subscriber.ProcessConsensusChange(modules.ConsensusChange{
+			// identified as the block that is now the current block,
+			// such that a subscriber can resume from it
+			ID:             consensusChangeIDOfBlock(css.CurrentBlock()),
+			RevertedBlocks: []types.Block{block},
+			Synced:         true,
+		})
+	}
+}
+
+// consensusChangeIDOfBlock returns the ID of the consensus change applying or reverting the given block.
+func consensusChangeIDOfBlock(block types.Block) modules.ConsensusChangeID {
+	return modules.ConsensusChangeID(block.ID())
+}
+
+// unspentCoinOutputs returns all coin outputs created and not spent by the blocks of the stub.
+func (css *consensusSetStub) unspentCoinOutputs() map[types.CoinOutputID]types.CoinOutput {
+	outputs := make(map[types.CoinOutputID]types.CoinOutput)
+	for _, block := range css.blocks {
+		for _, txn := range block.Transactions {
+			for _, ci := range txn.CoinInputs {
+				delete(outputs, ci.ParentID)
+			}
+			for i, co := range txn.CoinOutputs {
+				outputs[txn.CoinOutputID(uint64(i))] = co
+			}
+		}
+	}
+	return outputs
+}
+
+// TryTransactionSet implements modules.ConsensusSet.TryTransactionSet
+func (css *consensusSetStub) TryTransactionSet(txns []types.Transaction) (modules.ConsensusChange, error) {
+	outputs := css.unspentCoinOutputs()
+	var cc modules.ConsensusChange
+	for _, txn := range txns {
+		for _, ci := range txn.CoinInputs {
+			co, ok := outputs[ci.ParentID]
+			if !ok {
+				return modules.ConsensusChange{}, errStubMissingCoinOutput
+			}
+			delete(outputs, ci.ParentID)
+			cc.CoinOutputDiffs = append(cc.CoinOutputDiffs, modules.CoinOutputDiff{
+				Direction:  modules.DiffRevert,
+				ID:         ci.ParentID,
+				CoinOutput: co,
+			})
+		}
+		for i, co := range txn.CoinOutputs {
+			id := txn.CoinOutputID(uint64(i))
+			outputs[id] = co
+			cc.CoinOutputDiffs = append(cc.CoinOutputDiffs, modules.CoinOutputDiff{
+				Direction:  modules.DiffApply,
+				ID:         id,
+				CoinOutput: co,
+			})
+		}
+	}
+	return cc, nil
+}
+
+// ConsensusSetSubscribe implements modules.ConsensusSet.ConsensusSetSubscribe
+func (css *consensusSetStub) ConsensusSetSubscribe(subscriber modules.ConsensusSetSubscriber, changeID modules.ConsensusChangeID, _ <-chan struct{}) error {
+	start := 0
+	if changeID != modules.ConsensusChangeBeginning {
+		start = -1
+		for height, block := range css.blocks {
+			if consensusChangeIDOfBlock(block) == changeID {
+				start = height + 1
+				break
+			}
+		}
+		if start == -1 {
+			return modules.ErrInvalidConsensusChangeID
+		}
+	}
+	for _, block := range css.blocks[start:] {
+		subscriber.ProcessConsensusChange(modules.ConsensusChange{
+			ID:            consensusChangeIDOfBlock(block),
+			AppliedBlocks: []types.Block{block},
+		})
+	}
+	css.subscribers = append(css.subscribers, subscriber)
+	return nil
+}
+
+// Unsubscribe implements modules.ConsensusSet.Unsubscribe
+func (css *consensusSetStub) Unsubscribe(subscriber modules.ConsensusSetSubscriber) {
+	for i := range css.subscribers {
+		if css.subscribers[i] == subscriber {
+			css.subscribers = append(css.subscribers[:i], css.subscribers[i+1:]...)
+			return
+		}
+	}
+}
+
+// CurrentBlock implements modules.ConsensusSet.CurrentBlock
+func (css *consensusSetStub) CurrentBlock() types.Block {
+	return css.blocks[len(css.blocks)-1]
+}
+
+// Height implements modules.ConsensusSet.Height
+func (css *consensusSetStub) Height() types.BlockHeight {
+	return types.BlockHeight(len(css.blocks) - 1)
+}
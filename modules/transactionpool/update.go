@@ -3,20 +3,34 @@ package transactionpool
 import (
 	"fmt"
 
-	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/types"
 )
 
-// purge removes all transactions from the transaction pool.
+// purge removes all transactions from the transaction pool,
+// including the persisted transaction sets.
 func (tp *TransactionPool) purge() {
+	tp.clearTransactionSets()
+	err := tp.db.Update(func(tx kv.Tx) error {
+		return tp.resetTransactionSets(tx)
+	})
+	if err != nil {
+		tp.log.Println("[ERROR] Failed to purge the persisted transaction sets:", err)
+	}
+}
+
+// clearTransactionSets removes all transaction sets from the transaction pool,
+// without removing the persisted transaction sets.
+func (tp *TransactionPool) clearTransactionSets() {
 	tp.log.Debug("Purging transactionpool")
 	tp.transactionSets = make([]poolTransactionSet, 0)
 	tp.transactionSetMapping = make(map[TransactionSetID]int)
 	tp.transactionSetDiffs = make(map[TransactionSetID]modules.ConsensusChange)
 	tp.transactionListSize = 0
+	tp.invalidateFeeRateOrder()
 }
 
 // ProcessConsensusChange gets called to inform the transaction pool of changes
@@ -26,7 +40,7 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 
 	tp.log.Debug("Apply consensus update")
 	// Update the database of confirmed transactions.
-	err := tp.db.Update(func(tx *bolt.Tx) error {
+	err := tp.db.Update(func(tx kv.Tx) error {
 		for _, block := range cc.RevertedBlocks {
 			for _, txn := range block.Transactions {
 				err := tp.deleteTransaction(tx, txn.ID())
@@ -49,6 +63,9 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 		build.Severe("update consensus change in tx pool failed", err)
 	}
 
+	// Track how long the confirmed transactions waited in the pool.
+	tp.feeEstimator.processConsensusChange(cc)
+
 	// Remove all transactions confirmed in the block from the cache
 	for _, block := range cc.AppliedBlocks {
 		for _, txn := range block.Transactions {
@@ -110,13 +127,10 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 		unconfirmedSets = append(unconfirmedSets, newTSet)
 	}
 
-	// Purge the transaction pool. Some of the transactions sets may be invalid
-	// after the consensus change.
-	tp.purge()
-
-	// Add all of the unconfirmed transaction sets back to the transaction
-	// pool. The ones that are invalid will throw an error and will not be
-	// re-added.
+	// Purge the transaction pool, and add all of the unconfirmed transaction sets
+	// back to the transaction pool, as some of the transactions sets may be invalid
+	// after the consensus change. The ones that are invalid will throw an error
+	// and will not be re-added. The re-added sets are persisted in a single database transaction.
 	//
 	// Accepting a transaction set requires locking the consensus set (to check
 	// validity). But, ProcessConsensusChange is only called when the consensus
@@ -130,20 +144,12 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// more rules need to be put in place.
 	// Accepting the set again will write the current block height in the
 	// broadcast cache. So we copy the cache, clear it, and override it later
-	for _, set := range unconfirmedSets {
-		if err := tp.acceptTransactionSet(set); err != nil {
-			// the transaction is now invalid and no longer in the pool,
-			// so remove it from the cache as well
-			tsh, err := crypto.HashObject(set)
-			if err != nil {
-				build.Severe("update consensus change in tx pool failed: error while hashing txn set to be accepted", err)
-			}
-			setID := TransactionSetID(tsh)
-			tp.broadcastCache.delete(setID)
-			tp.log.Println(fmt.Sprintf("[WARN] Failed to accept transaction set %v, was previously in pool but not in latest block, and is now invalid", crypto.Hash(setID).String()))
-			continue
-		}
+	err = tp.readmitTransactionSets(unconfirmedSets)
+	if err != nil {
+		tp.log.Println("[ERROR] Failed to persist the re-admitted transaction sets:", err)
 	}
+	// Stop tracking the transactions that are no longer in the pool.
+	tp.feeEstimator.prune(tp.transactionSets)
 
 	// If we are synced, try to broadcast again
 	if cc.Synced {
@@ -152,7 +158,8 @@ func (tp *TransactionPool) ProcessConsensusChange(cc modules.ConsensusChange) {
 			tp.log.Println(fmt.Sprintf("Rebroadcasting transaction %v to peers", crypto.Hash(id).String()))
 			tSet, ok := tp.transactionSetByID(id)
 			if !ok {
-				tp.log.Println(fmt.Sprintf("failed to find transaction set for", crypto.Hash(id).String()))
+				tp.log.Println(fmt.Sprintf("failed to find transaction set for %v", crypto.Hash(id).String()))
+				continue
 			}
 			go tp.gateway.Broadcast("RelayTransactionSet", tSet.Transactions, tp.gateway.Peers())
 		}
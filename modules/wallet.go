@@ -68,6 +68,9 @@ type (
 		WalletAddress  bool             `json:"walletaddress"`
 		RelatedAddress types.UnlockHash `json:"relatedaddress"`
 		Value          types.Currency   `json:"value"`
+		// AssetID is only defined for coin inputs which spend an asset,
+		// rather than the native coin
+		AssetID *types.AssetID `json:"assetid,omitempty"`
 	}
 
 	// A ProcessedOutput is a coin output that appears in a transaction.
@@ -88,6 +91,9 @@ type (
 		WalletAddress  bool             `json:"walletaddress"`
 		RelatedAddress types.UnlockHash `json:"relatedaddress"`
 		Value          types.Currency   `json:"value"`
+		// AssetID is only defined for coin outputs which hold an asset,
+		// rather than the native coin
+		AssetID *types.AssetID `json:"assetid,omitempty"`
 	}
 
 	// A ProcessedTransaction is a transaction that has been processed into
@@ -148,6 +154,11 @@ type (
 		// transaction failed.
 		FundCoins(amount types.Currency, refundAddress *types.UnlockHash, reuseRefundAddress bool) error
 
+		// FundAsset will add coin inputs holding exactly 'amount' of the given asset
+		// to the transaction, similar to FundCoins. Any refund output is added
+		// as a regular coin output, it is up to the caller to define it as holding the asset.
+		FundAsset(assetID types.AssetID, amount types.Currency, refundAddress *types.UnlockHash, reuseRefundAddress bool) error
+
 		// FundBlockStakes will add a siafund input of exactly 'amount' to the
 		// transaction. A parent transaction may be needed to achieve an input
 		// with the correct value. The siafund input will not be signed until
@@ -193,6 +204,11 @@ type (
 		// AddArbitraryData sets the arbitrary data of the transaction.
 		SetArbitraryData(arb []byte)
 
+		// SetSigHashFlags sets the signature hash flags used for all signatures
+		// added by the builder, defining which parts of the transaction are signed.
+		// An error is returned if the transaction version does not support signature hash flags.
+		SetSigHashFlags(flags types.SigHashFlags) error
+
 		// Sign will sign any inputs added by 'FundCoins' or 'FundBlockStakes'
 		// and return a transaction set that contains all parents prepended to
 		// the transaction. If more fields need to be added, a new transaction
@@ -288,6 +304,10 @@ type (
 		// primary seed.
 		NextAddress() (types.UnlockHash, error)
 
+		// NextSecp256k1Address returns a new coin address generated from the
+		// primary seed, linked to a secp256k1 public key.
+		NextSecp256k1Address() (types.UnlockHash, error)
+
 		// CreateBackup will create a backup of the wallet at the provided
 		// filepath. The backup will have all seeds and keys.
 		CreateBackup(string) error
@@ -308,6 +328,12 @@ type (
 		LoadPlainSeed(Seed) error
 	}
 
+	// CoinOutputAssetGetter looks up the ID of the asset held by an unspent coin output,
+	// the zero ID in case it holds the native coin. The assets extension plugin implements it.
+	CoinOutputAssetGetter interface {
+		GetCoinOutputAsset(id types.CoinOutputID) (types.AssetID, error)
+	}
+
 	// Wallet stores and manages siacoins and siafunds. The wallet file is
 	// encrypted using a user-specified password. Common addresses are all
 	// derived from a single address seed.
@@ -343,6 +369,10 @@ type (
 		// refund transactions which are locked as well.
 		ConfirmedLockedBalance() (siacoinBalance types.Currency, blockstakeBalance types.Currency, err error)
 
+		// ConfirmedAssetBalances returns the confirmed balance of each asset owned by the wallet.
+		// Assets are not included in the coin balances returned by the other balance methods.
+		ConfirmedAssetBalances() (map[types.AssetID]types.Currency, error)
+
 		// GetUnspentBlockStakeOutputs returns the blockstake outputs where the beneficiary is an
 		// address this wallet has an unlockhash for.
 		GetUnspentBlockStakeOutputs() ([]types.UnspentBlockStakeOutput, error)
@@ -391,9 +421,10 @@ type (
 		StartTransaction() TransactionBuilder
 
 		// SendCoins is a tool for sending coins from the wallet to anyone who can fulfill the
-		// given condition (can be nil). The transaction is automatically given to the transaction pool, and
-		// are also returned to the caller.
-		SendCoins(amount types.Currency, cond types.UnlockConditionProxy, data []byte) (types.Transaction, error)
+		// given condition (can be nil). If expiresIn is non-zero, the transaction can no longer be included
+		// in a block once that amount of blocks have been created. The transaction is automatically given
+		// to the transaction pool, and are also returned to the caller.
+		SendCoins(amount types.Currency, cond types.UnlockConditionProxy, data []byte, expiresIn types.BlockHeight) (types.Transaction, error)
 
 		// SendBlockStakes is a tool for sending blockstakes from the wallet to anyone who can fulfill the
 		// given condition (can be nil). Sending money usually results in multiple transactions. The
@@ -402,8 +433,23 @@ type (
 		SendBlockStakes(amount types.Currency, cond types.UnlockConditionProxy) (types.Transaction, error)
 
 		// SendOutputs is a tool for sending coins and/or block stakes from the wallet, to one or multiple addreses.
+		// If expiresIn is non-zero, the transaction can no longer be included in a block once that amount of blocks have been created.
+		// If minerFee is zero, the miner fee estimated by the transaction pool (for DefaultFeeTarget blocks) is paid,
+		// otherwise it has to be at least the minimum transaction fee.
 		// The transaction is automatically given to the transaction pool, and is also returned to the caller.
-		SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs []types.BlockStakeOutput, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool) (types.Transaction, error)
+		SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs []types.BlockStakeOutput, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool, expiresIn types.BlockHeight, minerFee types.Currency) (types.Transaction, error)
+
+		// BumpFee replaces the unconfirmed transaction with the given ID, sent by this wallet,
+		// with an identical transaction paying a higher miner fee, paid for by its refund output.
+		// The replacement transaction is automatically given to the transaction pool, and is also returned to the caller.
+		BumpFee(id types.TransactionID) (types.Transaction, error)
+
+		// ChildPaysForParent spends the unconfirmed coin output, owned by this wallet, at the given index
+		// of the unconfirmed transaction with the given ID, using a child transaction paying
+		// the miner fee required for itself and its parent transaction to pay the given fee rate (per byte).
+		// If feeRate is zero, the fee rate estimated by the transaction pool (for DefaultFeeTarget blocks) is paid.
+		// The child transaction is automatically given to the transaction pool, and is also returned to the caller.
+		ChildPaysForParent(id types.TransactionID, outputIndex uint64, feeRate types.Currency) (types.Transaction, error)
 
 		// BlockStakeStats returns the blockstake statistical information of
 		// this wallet of the last 1000 blocks. If the blockcount is less than
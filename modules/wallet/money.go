@@ -6,13 +6,22 @@ import (
 	"strconv"
 
 	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
 
 // various errors returned by the wallet
 var (
 	ErrNilOutputs = errors.New("nil outputs cannot be send")
+
+	errBumpFeeUnknownTransaction     = errors.New("transaction is not an unconfirmed transaction of this wallet")
+	errBumpFeeUnsupportedTransaction = errors.New("only unconfirmed transactions of which all inputs can be signed by this wallet can have their fee bumped")
+	errBumpFeeNoRefundOutput         = errors.New("transaction has no coin output owned by this wallet which can pay for the fee increase")
+	errCPFPInvalidOutput             = errors.New("transaction has no unspent (native) coin output owned by this wallet at the given index")
+	errCPFPLowOutputValue            = errors.New("coin output value is too low to pay for the fee of the child transaction")
+	errLowMinerFee                   = errors.New("miner fee is lower than the minimum transaction fee")
 )
 
 // ConfirmedBalance returns the balance of the wallet according to all of the
@@ -30,13 +39,16 @@ func (w *Wallet) ConfirmedBalance() (coinBalance types.Currency, blockstakeBalan
 	ctx := w.getFulfillableContextForLatestBlock()
 
 	// get all coin and block stake stum
-	for _, sco := range w.coinOutputs {
-		if sco.Condition.Fulfillable(ctx) {
+	for id, sco := range w.coinOutputs {
+		if !w.coinOutputAsset(id).IsNative() {
+			continue // assets are reported separately
+		}
+		if sco.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			coinBalance = coinBalance.Add(sco.Value)
 		}
 	}
-	for _, sfo := range w.blockstakeOutputs {
-		if sfo.Condition.Fulfillable(ctx) {
+	for id, sfo := range w.blockstakeOutputs {
+		if sfo.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			blockstakeBalance = blockstakeBalance.Add(sfo.Value)
 		}
 	}
@@ -58,19 +70,64 @@ func (w *Wallet) ConfirmedLockedBalance() (coinBalance types.Currency, blockstak
 	ctx := w.getFulfillableContextForLatestBlock()
 
 	// get all coin and block stake stum
-	for _, sco := range w.coinOutputs {
-		if !sco.Condition.Fulfillable(ctx) {
+	for id, sco := range w.coinOutputs {
+		if !w.coinOutputAsset(id).IsNative() {
+			continue // assets are reported separately
+		}
+		if !sco.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			coinBalance = coinBalance.Add(sco.Value)
 		}
 	}
-	for _, sfo := range w.blockstakeOutputs {
-		if !sfo.Condition.Fulfillable(ctx) {
+	for id, sfo := range w.blockstakeOutputs {
+		if !sfo.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			blockstakeBalance = blockstakeBalance.Add(sfo.Value)
 		}
 	}
 	return
 }
 
+// ConfirmedAssetBalances returns the balance of each asset owned by the wallet,
+// according to all of the confirmed transactions, excluding the native coin.
+func (w *Wallet) ConfirmedAssetBalances() (map[types.AssetID]types.Currency, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+
+	// prepare fulfillable context
+	ctx := w.getFulfillableContextForLatestBlock()
+
+	balances := make(map[types.AssetID]types.Currency)
+	for id, sco := range w.coinOutputs {
+		assetID := w.coinOutputAsset(id)
+		if assetID.IsNative() {
+			continue
+		}
+		if sco.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
+			balances[assetID] = balances[assetID].Add(sco.Value)
+		}
+	}
+	return balances, nil
+}
+
+// coinOutputAsset returns the ID of the asset held by the given coin output,
+// the zero ID in case it holds the native coin (or is unknown to the wallet).
+// The asset of a coin output applied by a consensus snapshot is looked up
+// using the asset getter of the wallet, if it has one.
+func (w *Wallet) coinOutputAsset(id types.CoinOutputID) types.AssetID {
+	output := w.historicOutputs[types.OutputID(id)]
+	if !output.AssetUnknown || w.assets == nil {
+		return output.AssetID
+	}
+	assetID, err := w.assets.GetCoinOutputAsset(id)
+	if err != nil {
+		w.log.Printf("WARN: failed to look up the asset of coin output %v: %v", id, err)
+	}
+	return assetID
+}
+
 // UnspentBlockStakeOutputs returns the blockstake outputs where the beneficiary is an
 // address this wallet has an unlockhash for.
 func (w *Wallet) UnspentBlockStakeOutputs() (map[types.BlockStakeOutputID]types.BlockStakeOutput, error) {
@@ -88,7 +145,7 @@ func (w *Wallet) UnspentBlockStakeOutputs() (map[types.BlockStakeOutputID]types.
 	outputs := make(map[types.BlockStakeOutputID]types.BlockStakeOutput)
 	for id := range w.blockstakeOutputs {
 		output := w.blockstakeOutputs[id]
-		if output.Condition.Fulfillable(ctx) {
+		if output.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			outputs[id] = output
 		}
 	}
@@ -109,12 +166,12 @@ func (w *Wallet) UnconfirmedBalance() (outgoingCoins types.Currency, incomingCoi
 
 	for _, upt := range w.unconfirmedProcessedTransactions {
 		for _, input := range upt.Inputs {
-			if input.FundType == types.SpecifierCoinInput && input.WalletAddress {
+			if input.FundType == types.SpecifierCoinInput && input.WalletAddress && input.AssetID == nil {
 				outgoingCoins = outgoingCoins.Add(input.Value)
 			}
 		}
 		for _, output := range upt.Outputs {
-			if output.FundType == types.SpecifierCoinOutput && output.WalletAddress {
+			if output.FundType == types.SpecifierCoinOutput && output.WalletAddress && output.AssetID == nil {
 				incomingCoins = incomingCoins.Add(output.Value)
 			}
 		}
@@ -138,6 +195,9 @@ func (w *Wallet) MultiSigWallets() ([]modules.MultiSigWallet, error) {
 	var wallet *modules.MultiSigWallet
 	var exists bool
 	for id, co := range w.multiSigCoinOutputs {
+		if !w.coinOutputAsset(id).IsNative() {
+			continue // assets are not reported as part of multisig wallets
+		}
 		address := co.Condition.UnlockHash()
 		// Check if the wallet exists
 		if wallet, exists = wallets[address]; !exists {
@@ -157,7 +217,7 @@ func (w *Wallet) MultiSigWallets() ([]modules.MultiSigWallet, error) {
 			}
 			wallets[address] = wallet
 		}
-		if !co.Condition.Fulfillable(ctx) {
+		if !co.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			// Add the locked coins if applicable
 			wallet.ConfirmedLockedCoinBalance = wallet.ConfirmedLockedCoinBalance.Add(co.Value)
 		} else {
@@ -188,7 +248,7 @@ func (w *Wallet) MultiSigWallets() ([]modules.MultiSigWallet, error) {
 			}
 			wallets[address] = wallet
 		}
-		if !bso.Condition.Fulfillable(ctx) {
+		if !bso.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			// Add the locked block stakes if applicable
 			wallet.ConfirmedLockedBlockStakeBalance = wallet.ConfirmedLockedBlockStakeBalance.Add(bso.Value)
 		} else {
@@ -202,14 +262,14 @@ func (w *Wallet) MultiSigWallets() ([]modules.MultiSigWallet, error) {
 	// Check unconfrimed transactions
 	for _, upt := range w.unconfirmedProcessedTransactions {
 		for _, input := range upt.Inputs {
-			if wallet, exists = wallets[input.RelatedAddress]; exists && input.FundType == types.SpecifierCoinInput {
+			if wallet, exists = wallets[input.RelatedAddress]; exists && input.FundType == types.SpecifierCoinInput && input.AssetID == nil {
 				wallet.UnconfirmedOutgoingCoins = wallet.UnconfirmedOutgoingCoins.Add(input.Value)
 			} else if exists && input.FundType == types.SpecifierBlockStakeInput {
 				wallet.UnconfirmedOutgoingBlockStakes = wallet.UnconfirmedOutgoingBlockStakes.Add(input.Value)
 			}
 		}
 		for _, output := range upt.Outputs {
-			if wallet, exists = wallets[output.RelatedAddress]; exists && output.FundType == types.SpecifierCoinOutput {
+			if wallet, exists = wallets[output.RelatedAddress]; exists && output.FundType == types.SpecifierCoinOutput && output.AssetID == nil {
 				wallet.UnconfirmedIncomingCoins = wallet.UnconfirmedIncomingCoins.Add(output.Value)
 			} else if exists && output.FundType == types.SpecifierBlockStakeOutput {
 				wallet.UnconfirmedIncomingBlockStakes = wallet.UnconfirmedIncomingBlockStakes.Add(output.Value)
@@ -226,45 +286,64 @@ func (w *Wallet) MultiSigWallets() ([]modules.MultiSigWallet, error) {
 }
 
 // SendCoins creates a transaction sending 'amount' to whoever can fulfill the condition. If data is provided,
-// it is added as arbitrary data to the transaction. The transaction
-// is submitted to the transaction pool and is also returned.
-func (w *Wallet) SendCoins(amount types.Currency, cond types.UnlockConditionProxy, data []byte) (types.Transaction, error) {
+// it is added as arbitrary data to the transaction. If expiresIn is non-zero, the transaction
+// can no longer be included in a block once that amount of blocks have been created. The transaction
+// pays the miner fee estimated by the transaction pool, and is submitted to the transaction pool and is also returned.
+func (w *Wallet) SendCoins(amount types.Currency, cond types.UnlockConditionProxy, data []byte, expiresIn types.BlockHeight) (types.Transaction, error) {
 	return w.SendOutputs([]types.CoinOutput{
 		{
 			Condition: cond,
 			Value:     amount,
 		},
-	}, nil, nil, nil, false)
+	}, nil, nil, nil, false, expiresIn, types.ZeroCurrency)
 }
 
 // SendBlockStakes creates a transaction sending 'amount' to whoever can fulfill the condition. The transaction
-// is submitted to the transaction pool and is also returned.
+// pays the miner fee estimated by the transaction pool, and is submitted to the transaction pool and is also returned.
 func (w *Wallet) SendBlockStakes(amount types.Currency, cond types.UnlockConditionProxy) (types.Transaction, error) {
 	return w.SendOutputs(nil, []types.BlockStakeOutput{
 		{
 			Condition: cond,
 			Value:     amount,
 		},
-	}, nil, nil, false)
+	}, nil, nil, false, 0, types.ZeroCurrency)
 }
 
 // SendOutputs is a tool for sending coins and block stakes from the wallet, to one or multiple addreses.
+// If expiresIn is non-zero, the transaction can no longer be included in a block once that amount of blocks have been created.
+// If minerFee is zero, the transaction pays the miner fee estimated by the transaction pool
+// for it to be confirmed within modules.DefaultFeeTarget blocks,
+// otherwise it has to be at least the minimum transaction fee.
 // The transaction is automatically given to the transaction pool, and is also returned to the caller.
-func (w *Wallet) SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs []types.BlockStakeOutput, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool) (types.Transaction, error) {
+func (w *Wallet) SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs []types.BlockStakeOutput, data []byte, refundAddress *types.UnlockHash, reuseRefundAddress bool, expiresIn types.BlockHeight, minerFee types.Currency) (types.Transaction, error) {
 	if len(coinOutputs) == 0 && len(blockstakeOutputs) == 0 {
 		// at least one coin output OR one block stake output has to be send
 		return types.Transaction{}, ErrNilOutputs
 	}
+	if !minerFee.IsZero() && minerFee.Cmp(w.chainCts.MinimumTransactionFee) < 0 {
+		return types.Transaction{}, errLowMinerFee
+	}
 
 	if err := w.tg.Add(); err != nil {
 		return types.Transaction{}, err
 	}
 	defer w.tg.Done()
 
-	tpoolFee := w.chainCts.MinimumTransactionFee.Mul64(1) // TODO better fee algo
+	tpoolFee := minerFee
+	if tpoolFee.IsZero() {
+		_, tpoolFee = w.tpool.FeeEstimation(modules.DefaultFeeTarget)
+	}
 	totalAmount := types.NewCurrency64(0).Add(tpoolFee)
 	var err error
-	txnBuilder := w.StartTransaction()
+	var txnBuilder modules.TransactionBuilder
+	if expiresIn > 0 {
+		txnBuilder, err = w.startValidUntilTransaction(expiresIn)
+		if err != nil {
+			return types.Transaction{}, err
+		}
+	} else {
+		txnBuilder = w.StartTransaction()
+	}
 	// Make sure to release inputs in case of an error
 	defer func() {
 		if err != nil {
@@ -308,3 +387,349 @@ func (w *Wallet) SendOutputs(coinOutputs []types.CoinOutput, blockstakeOutputs [
 	}
 	return txnSet[0], nil
 }
+
+// BumpFee replaces the unconfirmed transaction with the given ID, sent by this wallet,
+// with an identical transaction which pays a higher miner fee. The fee is increased
+// with the minimum transaction fee, as well as with the fees of the unconfirmed wallet transactions
+// which depend on the transaction, as those are replaced (evicted) together with it.
+// The fee increase is paid for by a coin output of the transaction owned by this wallet,
+// usually the refund output. The replacement transaction is signed again,
+// and is automatically given to the transaction pool, and is also returned to the caller.
+func (w *Wallet) BumpFee(id types.TransactionID) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, err
+	}
+	defer w.tg.Done()
+
+	txnBuilder, err := w.bumpFeeTransactionBuilder(id)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	txnSet, err := txnBuilder.Sign()
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	err = w.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	return txnSet[len(txnSet)-1], nil
+}
+
+// bumpFeeTransactionBuilder creates a transaction builder for the replacement
+// of the unconfirmed wallet transaction with the given ID, paying a higher miner fee.
+// All inputs of the transaction are to be signed (again) by the returned builder.
+func (w *Wallet) bumpFeeTransactionBuilder(id types.TransactionID) (*transactionBuilder, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+
+	// find the unconfirmed transaction, as well as the wallet transactions depending on it
+	var (
+		txn   types.Transaction
+		found bool
+	)
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		if upt.TransactionID == id {
+			txn, found = upt.Transaction, true
+			break
+		}
+	}
+	if !found {
+		return nil, errBumpFeeUnknownTransaction
+	}
+	if txn.Extension != nil {
+		if _, ok := txn.ValidUntil(); !ok {
+			return nil, errBumpFeeUnsupportedTransaction
+		}
+	}
+	fee := w.chainCts.MinimumTransactionFee.Add(w.unconfirmedDescendantFees(txn))
+
+	tb := &transactionBuilder{
+		transaction: types.Transaction{
+			Version:           txn.Version,
+			CoinInputs:        make([]types.CoinInput, 0, len(txn.CoinInputs)),
+			CoinOutputs:       make([]types.CoinOutput, len(txn.CoinOutputs)),
+			BlockStakeInputs:  make([]types.BlockStakeInput, 0, len(txn.BlockStakeInputs)),
+			BlockStakeOutputs: append([]types.BlockStakeOutput(nil), txn.BlockStakeOutputs...),
+			MinerFees:         append([]types.Currency(nil), txn.MinerFees...),
+			ArbitraryData:     append([]byte(nil), txn.ArbitraryData...),
+			Extension:         txn.Extension,
+		},
+		wallet: w,
+	}
+
+	// all inputs have to be owned by the wallet, such that they can be signed again
+	for _, ci := range txn.CoinInputs {
+		co, exists := w.coinOutputs[ci.ParentID]
+		if !exists {
+			co = tb.getCoFromUnconfirmedProcessedTransactions(ci.ParentID)
+		}
+		uh, ff, err := w.bumpFeeFulfillment(co.Condition)
+		if err != nil {
+			return nil, err
+		}
+		tb.coinInputs = append(tb.coinInputs, inputSignContext{
+			InputIndex: len(tb.transaction.CoinInputs),
+			UnlockHash: uh,
+		})
+		tb.transaction.CoinInputs = append(tb.transaction.CoinInputs, types.CoinInput{
+			ParentID:    ci.ParentID,
+			Fulfillment: ff,
+		})
+	}
+	for _, bsi := range txn.BlockStakeInputs {
+		uh, ff, err := w.bumpFeeFulfillment(w.blockstakeOutputs[bsi.ParentID].Condition)
+		if err != nil {
+			return nil, err
+		}
+		tb.blockstakeInputs = append(tb.blockstakeInputs, inputSignContext{
+			InputIndex: len(tb.transaction.BlockStakeInputs),
+			UnlockHash: uh,
+		})
+		tb.transaction.BlockStakeInputs = append(tb.transaction.BlockStakeInputs, types.BlockStakeInput{
+			ParentID:    bsi.ParentID,
+			Fulfillment: ff,
+		})
+	}
+
+	// pay the fee increase using the last (native) coin output owned by the wallet,
+	// which can cover it, being the refund output for transactions created by this wallet
+	exData, _ := txn.CommonExtensionData()
+	refundIndex := -1
+	for i, co := range txn.CoinOutputs {
+		tb.transaction.CoinOutputs[i] = co
+		if co.Condition.ConditionType() != types.ConditionTypeUnlockHash || !exData.CoinOutputAsset(i).IsNative() {
+			continue
+		}
+		if _, exists := w.keys[co.Condition.UnlockHash()]; exists && co.Value.Cmp(fee) > 0 {
+			refundIndex = i
+		}
+	}
+	if refundIndex == -1 {
+		return nil, errBumpFeeNoRefundOutput
+	}
+	tb.transaction.CoinOutputs[refundIndex].Value = txn.CoinOutputs[refundIndex].Value.Sub(fee)
+	if n := len(tb.transaction.MinerFees); n > 0 {
+		tb.transaction.MinerFees[n-1] = tb.transaction.MinerFees[n-1].Add(fee)
+	} else {
+		tb.transaction.MinerFees = append(tb.transaction.MinerFees, fee)
+	}
+	return tb, nil
+}
+
+// ChildPaysForParent creates a child transaction, spending the unconfirmed coin output
+// owned by this wallet, at the given index of the unconfirmed transaction with the given ID,
+// back to this wallet. The child transaction is ranked by the transaction pool
+// as a single package together with the parent transaction,
+// such that it can speed up the confirmation of the parent transaction.
+// The child transaction pays the miner fee required for the package to pay the given fee rate (per byte),
+// and at least the minimum transaction fee. If feeRate is zero, the fee rate estimated
+// by the transaction pool for modules.DefaultFeeTarget blocks is used.
+func (w *Wallet) ChildPaysForParent(id types.TransactionID, outputIndex uint64, feeRate types.Currency) (types.Transaction, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.Transaction{}, err
+	}
+	defer w.tg.Done()
+
+	if feeRate.IsZero() {
+		feeRate, _ = w.tpool.FeeEstimation(modules.DefaultFeeTarget)
+	}
+	txnBuilder, err := w.childPaysForParentTransactionBuilder(id, outputIndex, feeRate)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	// Make sure to release the spent output in case of an error
+	defer func() {
+		if err != nil {
+			txnBuilder.Drop()
+		}
+	}()
+	var txnSet []types.Transaction
+	txnSet, err = txnBuilder.Sign()
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	err = w.tpool.AcceptTransactionSet(txnSet)
+	if err != nil {
+		return types.Transaction{}, err
+	}
+	return txnSet[len(txnSet)-1], nil
+}
+
+// childPaysForParentTransactionBuilder creates a transaction builder for a child transaction
+// spending the given coin output of the unconfirmed wallet transaction with the given ID,
+// paying the fee required for the parent and child transaction to pay the given fee rate.
+func (w *Wallet) childPaysForParentTransactionBuilder(id types.TransactionID, outputIndex uint64, feeRate types.Currency) (*transactionBuilder, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.unlocked {
+		return nil, modules.ErrLockedWallet
+	}
+
+	var (
+		parent types.Transaction
+		found  bool
+	)
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		if upt.TransactionID == id {
+			parent, found = upt.Transaction, true
+			break
+		}
+	}
+	if !found {
+		return nil, errBumpFeeUnknownTransaction
+	}
+
+	// the output has to be a native coin output owned by the wallet, not yet spent
+	if outputIndex >= uint64(len(parent.CoinOutputs)) {
+		return nil, errCPFPInvalidOutput
+	}
+	co := parent.CoinOutputs[outputIndex]
+	exData, _ := parent.CommonExtensionData()
+	if co.Condition.ConditionType() != types.ConditionTypeUnlockHash || !exData.CoinOutputAsset(int(outputIndex)).IsNative() {
+		return nil, errCPFPInvalidOutput
+	}
+	uh := co.Condition.UnlockHash()
+	pk, _, err := w.getKey(uh)
+	if err != nil {
+		return nil, errCPFPInvalidOutput
+	}
+	coid := parent.CoinOutputID(outputIndex)
+	if _, spent := w.spentOutputs[types.OutputID(coid)]; spent {
+		return nil, errCPFPInvalidOutput
+	}
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		for _, ci := range upt.Transaction.CoinInputs {
+			if ci.ParentID == coid {
+				return nil, errCPFPInvalidOutput
+			}
+		}
+	}
+
+	// estimate the size of the child transaction, using the largest possible fee,
+	// such that the parent and child transaction together pay the given fee rate
+	child := types.Transaction{
+		Version: w.defaultTransactionVersion(),
+		CoinInputs: []types.CoinInput{{
+			ParentID: coid,
+			Fulfillment: types.NewFulfillment(&types.SingleSignatureFulfillment{
+				PublicKey: pk,
+				Signature: make(types.ByteSlice, crypto.SignatureSize),
+			}),
+		}},
+		CoinOutputs: []types.CoinOutput{{
+			Value:     co.Value,
+			Condition: types.NewCondition(types.NewUnlockHashCondition(types.UnlockHash{Type: types.UnlockTypePubKey})),
+		}},
+		MinerFees: []types.Currency{co.Value},
+	}
+	fee, err := childPaysForParentFee(parent, child, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if fee.Cmp(w.chainCts.MinimumTransactionFee) < 0 {
+		fee = w.chainCts.MinimumTransactionFee
+	}
+	if co.Value.Cmp(fee) <= 0 {
+		return nil, errCPFPLowOutputValue
+	}
+	refundUnlockHash, err := w.nextPrimarySeedAddress()
+	if err != nil {
+		return nil, err
+	}
+	child.CoinInputs[0].Fulfillment = types.NewFulfillment(types.NewSingleSignatureFulfillment(pk))
+	child.CoinOutputs[0] = types.CoinOutput{
+		Value:     co.Value.Sub(fee),
+		Condition: types.NewCondition(types.NewUnlockHashCondition(refundUnlockHash)),
+	}
+	child.MinerFees[0] = fee
+
+	tb := &transactionBuilder{
+		transaction: child,
+		coinInputs: []inputSignContext{{
+			InputIndex: 0,
+			UnlockHash: uh,
+		}},
+		wallet: w,
+	}
+	w.spentOutputs[types.OutputID(coid)] = w.consensusSetHeight
+	return tb, nil
+}
+
+// childPaysForParentFee returns the miner fee the given child transaction has to pay,
+// for the given parent and child transaction to pay the given fee rate (per byte) as a package,
+// the (siabin) byte size of each transaction computed as a transaction set of its own,
+// the way the transaction pool computes it. Zero is returned if the parent transaction
+// already pays the fee rate for both transactions.
+func childPaysForParentFee(parent, child types.Transaction, feeRate types.Currency) (types.Currency, error) {
+	var size int
+	for _, txn := range []types.Transaction{parent, child} {
+		b, err := siabin.Marshal([]types.Transaction{txn})
+		if err != nil {
+			return types.Currency{}, fmt.Errorf("failed to (siabin) marshal transaction: %v", err)
+		}
+		size += len(b)
+	}
+	var parentFees types.Currency
+	for _, fee := range parent.MinerFees {
+		parentFees = parentFees.Add(fee)
+	}
+	fees := feeRate.Mul64(uint64(size))
+	if fees.Cmp(parentFees) <= 0 {
+		return types.Currency{}, nil
+	}
+	return fees.Sub(parentFees), nil
+}
+
+// bumpFeeFulfillment returns the unlock hash of the wallet key and the (unsigned) fulfillment
+// to be used for an input spending an output with the given condition, as part of a fee bump.
+// Only the conditions that can be funded by the wallet itself are supported.
+func (w *Wallet) bumpFeeFulfillment(condition types.UnlockConditionProxy) (types.UnlockHash, types.UnlockFulfillmentProxy, error) {
+	switch condition.ConditionType() {
+	case types.ConditionTypeUnlockHash, types.ConditionTypeTimeLock, types.ConditionTypeRelativeTimeLock:
+		uh := condition.UnlockHash()
+		pk, _, err := w.getKey(uh)
+		if err != nil {
+			return types.UnlockHash{}, types.UnlockFulfillmentProxy{}, errBumpFeeUnsupportedTransaction
+		}
+		return uh, types.NewFulfillment(types.NewSingleSignatureFulfillment(pk)), nil
+	default:
+		return types.UnlockHash{}, types.UnlockFulfillmentProxy{}, errBumpFeeUnsupportedTransaction
+	}
+}
+
+// unconfirmedDescendantFees returns the total amount of miner fees paid by
+// the unconfirmed wallet transactions that (indirectly) depend on the given transaction.
+func (w *Wallet) unconfirmedDescendantFees(txn types.Transaction) types.Currency {
+	outputs := make(map[types.CoinOutputID]struct{})
+	for i := range txn.CoinOutputs {
+		outputs[txn.CoinOutputID(uint64(i))] = struct{}{}
+	}
+	var fees types.Currency
+	for _, upt := range w.unconfirmedProcessedTransactions {
+		dependent := false
+		for _, ci := range upt.Transaction.CoinInputs {
+			if _, ok := outputs[ci.ParentID]; ok {
+				dependent = true
+				break
+			}
+		}
+		if !dependent {
+			continue
+		}
+		// unconfirmed transactions are ordered such that
+		// a transaction is listed after the transactions it depends on
+		for i := range upt.Transaction.CoinOutputs {
+			outputs[upt.Transaction.CoinOutputID(uint64(i))] = struct{}{}
+		}
+		for _, minerFee := range upt.Transaction.MinerFees {
+			fees = fees.Add(minerFee)
+		}
+	}
+	return fees
+}
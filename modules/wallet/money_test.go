@@ -5,6 +5,7 @@ import (
 
 	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 	"github.com/threefoldtech/rivine/types"
 )
 
@@ -42,7 +43,7 @@ func TestSendCoins(t *testing.T) {
 	}
 
 	// sending coins requires funds to be send
-	_, err = wt.wallet.SendCoins(types.NewCurrency64(5000), types.NewCondition(nil), nil)
+	_, err = wt.wallet.SendCoins(types.NewCurrency64(5000), types.NewCondition(nil), nil, 0)
 	if err != modules.ErrLowBalance {
 		t.Fatal(err)
 	}
@@ -59,7 +60,7 @@ func TestSendCoins(t *testing.T) {
 	// unconfirmed siacoins - incoming unconfirmed coins should equal 5000 +
 	// fee.
 	tpoolFee := wt.wallet.chainCts.MinimumTransactionFee.Mul64(1)
-	_, err = wt.wallet.SendCoins(types.NewCurrency64(5000), types.NewCondition(nil), nil)
+	_, err = wt.wallet.SendCoins(types.NewCurrency64(5000), types.NewCondition(nil), nil, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -94,7 +95,7 @@ func TestIntegrationSendOverUnder(t *testing.T) {
 
 	// Spend too many coins.
 	tooManyCoins := wt.wallet.chainCts.CurrencyUnits.OneCoin.Mul64(1e12)
-	_, err = wt.wallet.SendCoins(tooManyCoins, types.NewCondition(nil), nil)
+	_, err = wt.wallet.SendCoins(tooManyCoins, types.NewCondition(nil), nil, 0)
 	if err != modules.ErrLowBalance {
 		t.Error("low balance err not returned after attempting to send too many coins")
 	}
@@ -111,7 +112,7 @@ func TestIntegrationSendOverUnder(t *testing.T) {
 	}
 
 	// Spend a reasonable amount of coins.
-	_, err = wt.wallet.SendCoins(reasonableCoins, types.NewCondition(nil), nil)
+	_, err = wt.wallet.SendCoins(reasonableCoins, types.NewCondition(nil), nil, 0)
 	if err != nil {
 		t.Error("unexpected error: ", err)
 	}
@@ -132,13 +133,13 @@ func TestIntegrationSpendHalfHalf(t *testing.T) {
 
 	// Spend more than half of the coins twice.
 	halfPlus := wt.wallet.chainCts.CurrencyUnits.OneCoin.Mul64(200e3)
-	_, err = wt.wallet.SendCoins(halfPlus, types.NewCondition(nil), nil)
+	_, err = wt.wallet.SendCoins(halfPlus, types.NewCondition(nil), nil, 0)
 	if err != nil {
 		t.Error("unexpected error: ", err)
 	}
 	_, err = wt.wallet.SendCoins(halfPlus,
 		types.NewCondition(types.NewUnlockHashCondition(types.NewUnlockHash(0, crypto.Hash{1}))),
-		nil)
+		nil, 0)
 	if err != modules.ErrIncompleteTransactions {
 		t.Error("wallet appears to be reusing outputs when building transactions: ", err)
 	}
@@ -157,14 +158,14 @@ func TestIntegrationSpendUnconfirmed(t *testing.T) {
 
 	// Spend the only output.
 	halfPlus := wt.wallet.chainCts.CurrencyUnits.OneCoin.Mul64(200e3)
-	_, err = wt.wallet.SendCoins(halfPlus, types.NewCondition(nil), nil)
+	_, err = wt.wallet.SendCoins(halfPlus, types.NewCondition(nil), nil, 0)
 	if err != nil {
 		t.Error("unexpected error: ", err)
 	}
 	someMore := wt.wallet.chainCts.CurrencyUnits.OneCoin.Mul64(75e3)
 	_, err = wt.wallet.SendCoins(someMore,
 		types.NewCondition(types.NewUnlockHashCondition(types.NewUnlockHash(0, crypto.Hash{1}))),
-		nil)
+		nil, 0)
 	if err != nil {
 		t.Error("wallet appears to be struggling to spend unconfirmed outputs")
 	}
@@ -185,8 +186,257 @@ func TestNilOutputs(t *testing.T) {
 	}
 	defer wt.closeWt()
 
-	_, err = wt.wallet.SendOutputs(nil, nil, []byte("data"), nil, false)
+	_, err = wt.wallet.SendOutputs(nil, nil, []byte("data"), nil, false, 0, types.ZeroCurrency)
 	if err != ErrNilOutputs {
 		t.Fatal("expected ErrNilOutput, but receiver: ", err)
 	}
 }
+
+// TestSendOutputsMinerFee probes the miner fee paid by the SendOutputs method of the wallet,
+// which defaults to the fee estimated by the transaction pool.
+func TestSendOutputsMinerFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	cs := newConsensusSetStub()
+	wt, err := createWalletTesterWithStubCS(t.Name(), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// give wallet some money to spend
+	addr, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	minFee := wt.wallet.chainCts.MinimumTransactionFee
+	cs.addTransactionAsBlock(addr, minFee.Mul64(100))
+
+	outputs := []types.CoinOutput{{
+		Value:     types.NewCurrency64(5000),
+		Condition: types.NewCondition(types.NewUnlockHashCondition(types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{1}))),
+	}}
+	// without any transactions observed, the minimum transaction fee is estimated
+	_, estimatedFee := wt.tpool.FeeEstimation(modules.DefaultFeeTarget)
+	if !estimatedFee.Equals(minFee) {
+		t.Errorf("expected the minimum transaction fee to be estimated, but received: %v", estimatedFee)
+	}
+	for _, testCase := range []struct {
+		MinerFee, ExpectedFee types.Currency
+	}{
+		{types.ZeroCurrency, estimatedFee},
+		{minFee.Mul64(3), minFee.Mul64(3)},
+	} {
+		txn, err := wt.wallet.SendOutputs(outputs, nil, nil, nil, false, 0, testCase.MinerFee)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(txn.MinerFees) != 1 || !txn.MinerFees[0].Equals(testCase.ExpectedFee) {
+			t.Errorf("unexpected miner fees: %v != %v", txn.MinerFees, testCase.ExpectedFee)
+		}
+	}
+	// a fee lower than the minimum transaction fee is refused
+	_, err = wt.wallet.SendOutputs(outputs, nil, nil, nil, false, 0, minFee.Sub(types.NewCurrency64(1)))
+	if err != errLowMinerFee {
+		t.Fatalf("expected %v, got: %v", errLowMinerFee, err)
+	}
+}
+
+// TestBumpFee probes the BumpFee method of the wallet,
+// replacing an unconfirmed transaction in the transaction pool.
+func TestBumpFee(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	cs := newConsensusSetStub()
+	wt, err := createWalletTesterWithStubCS(t.Name(), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// only unconfirmed wallet transactions can have their fee bumped
+	_, err = wt.wallet.BumpFee(types.TransactionID{1})
+	if err != errBumpFeeUnknownTransaction {
+		t.Fatal("expected errBumpFeeUnknownTransaction, but received:", err)
+	}
+
+	// give wallet some money to spend
+	addr, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	minFee := wt.wallet.chainCts.MinimumTransactionFee
+	cs.addTransactionAsBlock(addr, minFee.Mul64(10).Add(types.NewCurrency64(5000)))
+
+	dest := types.NewCondition(types.NewUnlockHashCondition(types.NewUnlockHash(types.UnlockTypePubKey, crypto.Hash{1})))
+	txn, err := wt.wallet.SendCoins(types.NewCurrency64(5000), dest, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := uint64(2); i <= 3; i++ {
+		bumped, err := wt.wallet.BumpFee(txn.ID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bumped.ID() == txn.ID() {
+			t.Fatal("expected the replacement transaction to have a different ID")
+		}
+		if len(bumped.MinerFees) != 1 || !bumped.MinerFees[0].Equals(minFee.Mul64(i)) {
+			t.Errorf("unexpected miner fees of replacement transaction #%d: %v", i, bumped.MinerFees)
+		}
+		if len(bumped.CoinOutputs) != 2 || !bumped.CoinOutputs[0].Value.Equals64(5000) ||
+			!bumped.CoinOutputs[1].Value.Equals(minFee.Mul64(10-i)) {
+			t.Errorf("unexpected coin outputs of replacement transaction #%d: %v", i, bumped.CoinOutputs)
+		}
+		// the replacement transaction is the only transaction in the pool
+		txns := wt.tpool.TransactionList()
+		if len(txns) != 1 || txns[0].ID() != bumped.ID() {
+			t.Fatalf("expected replacement transaction #%d to replace the transaction in the pool: %v", i, txns)
+		}
+		txn = bumped
+	}
+
+	// the same transaction cannot be replaced with an identical fee
+	err = wt.tpool.AcceptTransactionSet([]types.Transaction{txn})
+	if err != modules.ErrDuplicateTransactionSet {
+		t.Error("expected ErrDuplicateTransactionSet, but received:", err)
+	}
+}
+
+// TestChildPaysForParent probes the ChildPaysForParent method of the wallet,
+// spending an unconfirmed coin output using a child transaction.
+func TestChildPaysForParent(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	cs := newConsensusSetStub()
+	wt, err := createWalletTesterWithStubCS(t.Name(), cs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wt.closeWt()
+
+	// give wallet some money to spend
+	addr, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	minFee := wt.wallet.chainCts.MinimumTransactionFee
+	cs.addTransactionAsBlock(addr, minFee.Mul64(100))
+
+	// send some coins to ourselves, creating an unconfirmed parent transaction
+	dest, err := wt.wallet.NextAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := minFee.Mul64(50)
+	parent, err := wt.wallet.SendCoins(value, types.NewCondition(types.NewUnlockHashCondition(dest)), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = wt.wallet.ChildPaysForParent(types.TransactionID{1}, 0, types.Currency{})
+	if err != errBumpFeeUnknownTransaction {
+		t.Fatal("expected errBumpFeeUnknownTransaction, but received:", err)
+	}
+	_, err = wt.wallet.ChildPaysForParent(parent.ID(), uint64(len(parent.CoinOutputs)), types.Currency{})
+	if err != errCPFPInvalidOutput {
+		t.Fatal("expected errCPFPInvalidOutput, but received:", err)
+	}
+	// the output can't pay for a fee rate requiring a fee higher than its value
+	_, err = wt.wallet.ChildPaysForParent(parent.ID(), 0, minFee.Div64(10))
+	if err != errCPFPLowOutputValue {
+		t.Fatal("expected errCPFPLowOutputValue, but received:", err)
+	}
+
+	feeRate := minFee.Div64(100)
+	child, err := wt.wallet.ChildPaysForParent(parent.ID(), 0, feeRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(child.CoinInputs) != 1 || child.CoinInputs[0].ParentID != parent.CoinOutputID(0) {
+		t.Errorf("unexpected coin inputs of child transaction: %v", child.CoinInputs)
+	}
+	if len(child.MinerFees) != 1 || len(child.CoinOutputs) != 1 || !child.CoinOutputs[0].Value.Add(child.MinerFees[0]).Equals(value) {
+		t.Fatalf("unexpected miner fees and coin outputs of child transaction: %v %v", child.MinerFees, child.CoinOutputs)
+	}
+	// the parent and child transaction together pay the fee rate, give or take a few bytes
+	var size int
+	fees := child.MinerFees[0]
+	for _, txn := range []types.Transaction{parent, child} {
+		b, err := siabin.Marshal([]types.Transaction{txn})
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += len(b)
+	}
+	for _, fee := range parent.MinerFees {
+		fees = fees.Add(fee)
+	}
+	if fees.Cmp(feeRate.Mul64(uint64(size))) < 0 || fees.Cmp(feeRate.Mul64(uint64(size+16))) > 0 {
+		t.Errorf("parent and child transaction pay %v for %d bytes, expected a fee rate of %v", fees, size, feeRate)
+	}
+	// the child transaction is ordered after its parent in the pool
+	txns := wt.tpool.TransactionList()
+	if len(txns) != 2 || txns[0].ID() != parent.ID() || txns[1].ID() != child.ID() {
+		t.Fatalf("expected parent and child transaction in the pool: %v", txns)
+	}
+
+	// the same output cannot be spent twice
+	_, err = wt.wallet.ChildPaysForParent(parent.ID(), 0, types.Currency{})
+	if err != errCPFPInvalidOutput {
+		t.Fatal("expected errCPFPInvalidOutput, but received:", err)
+	}
+}
+
+// coinOutputAssetGetterStub implements modules.CoinOutputAssetGetter using a map.
+type coinOutputAssetGetterStub map[types.CoinOutputID]types.AssetID
+
+func (stub coinOutputAssetGetterStub) GetCoinOutputAsset(id types.CoinOutputID) (types.AssetID, error) {
+	return stub[id], nil
+}
+
+// TestCoinOutputAsset probes the asset lookup of the coin outputs,
+// including those applied by a consensus snapshot.
+func TestCoinOutputAsset(t *testing.T) {
+	assetID := types.AssetID{4, 2}
+	w := &Wallet{
+		historicOutputs: map[types.OutputID]historicOutput{
+			{1}: {AssetID: assetID},
+			{2}: {AssetUnknown: true},
+			{3}: {AssetUnknown: true},
+		},
+	}
+	// without an asset getter, the outputs of a snapshot are assumed to hold the native coin
+	if id := w.coinOutputAsset(types.CoinOutputID{1}); id != assetID {
+		t.Errorf("unexpected asset of coin output 1: %v", id)
+	}
+	if id := w.coinOutputAsset(types.CoinOutputID{2}); !id.IsNative() {
+		t.Errorf("unexpected asset of coin output 2: %v", id)
+	}
+
+	// with an asset getter, the asset of the outputs of a snapshot is looked up
+	w.assets = coinOutputAssetGetterStub{
+		types.CoinOutputID{1}: types.AssetID{1},
+		types.CoinOutputID{2}: assetID,
+	}
+	for _, test := range []struct {
+		ID      types.CoinOutputID
+		AssetID types.AssetID
+	}{
+		{types.CoinOutputID{1}, assetID},
+		{types.CoinOutputID{2}, assetID},
+		{types.CoinOutputID{3}, types.AssetID{}},
+		{types.CoinOutputID{4}, types.AssetID{}},
+	} {
+		if id := w.coinOutputAsset(test.ID); id != test.AssetID {
+			t.Errorf("unexpected asset of coin output %v: %v != %v", test.ID, id, test.AssetID)
+		}
+	}
+}
@@ -23,25 +23,25 @@ func (w *Wallet) UnlockedUnspendOutputs() (map[types.CoinOutputID]types.CoinOutp
 
 	// get all coin and block stake stum
 	for id, co := range w.coinOutputs {
-		if co.Condition.Fulfillable(ctx) {
+		if co.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			ucom[id] = co
 		}
 	}
 	// same for multisig
 	for id, co := range w.multiSigCoinOutputs {
-		if co.Condition.Fulfillable(ctx) {
+		if co.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			ucom[id] = co
 		}
 	}
 	// block stakes
 	for id, bso := range w.blockstakeOutputs {
-		if bso.Condition.Fulfillable(ctx) {
+		if bso.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			ubsom[id] = bso
 		}
 	}
 	// block stake multisigs
 	for id, bso := range w.multiSigBlockStakeOutputs {
-		if bso.Condition.Fulfillable(ctx) {
+		if bso.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			ubsom[id] = bso
 		}
 	}
@@ -65,25 +65,25 @@ func (w *Wallet) LockedUnspendOutputs() (map[types.CoinOutputID]types.CoinOutput
 
 	// get all coin and block stake stum
 	for id, co := range w.coinOutputs {
-		if !co.Condition.Fulfillable(ctx) {
+		if !co.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			ucom[id] = co
 		}
 	}
 	// same for multisig
 	for id, co := range w.multiSigCoinOutputs {
-		if !co.Condition.Fulfillable(ctx) {
+		if !co.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			ucom[id] = co
 		}
 	}
 	// block stakes
 	for id, bso := range w.blockstakeOutputs {
-		if !bso.Condition.Fulfillable(ctx) {
+		if !bso.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			ubsom[id] = bso
 		}
 	}
 	// block stake multisigs
 	for id, bso := range w.multiSigBlockStakeOutputs {
-		if !bso.Condition.Fulfillable(ctx) {
+		if !bso.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(id))) {
 			ubsom[id] = bso
 		}
 	}
@@ -45,12 +45,48 @@ func generateSpendableKey(seed modules.Seed, index uint64) (spendableKey, error)
 	}
 	sk, pk := crypto.GenerateKeyPairDeterministic(h)
 	return spendableKey{
-		PublicKey: pk,
-		SecretKey: sk,
+		PublicKey: types.Ed25519PublicKey(pk),
+		SecretKey: types.ByteSlice(sk[:]),
 		Index:     index,
 	}, nil
 }
 
+// generateSecp256k1SpendableKey creates the secp256k1 keys and unlock conditions for seed at a
+// given index. The algorithm specifier is included in the entropy,
+// such that the secret key is unrelated to the Ed25519 secret key of the same index.
+func generateSecp256k1SpendableKey(seed modules.Seed, index uint64) (spendableKey, error) {
+	h, err := crypto.HashAll(seed, types.SignatureAlgoSecp256k1Specifier, index)
+	if err != nil {
+		return spendableKey{}, err
+	}
+	sk, pk := crypto.GenerateSecp256k1KeyPairDeterministic(h)
+	return spendableKey{
+		PublicKey: types.Secp256k1PublicKey(pk),
+		SecretKey: types.ByteSlice(sk[:]),
+		Index:     index,
+	}, nil
+}
+
+// integrateSpendableKeys generates the keys of all supported signature algorithms
+// for seed at a given index, and adds them to the wallet's set of spendable keys.
+func (w *Wallet) integrateSpendableKeys(seed modules.Seed, index uint64) error {
+	for _, generate := range []func(modules.Seed, uint64) (spendableKey, error){
+		generateSpendableKey,
+		generateSecp256k1SpendableKey,
+	} {
+		spendableKey, err := generate(seed, index)
+		if err != nil {
+			return err
+		}
+		uh, err := spendableKey.UnlockHash()
+		if err != nil {
+			return err
+		}
+		w.keys[uh] = spendableKey
+	}
+	return nil
+}
+
 // encryptAndSaveSeedFile encrypts and saves a seed file.
 func (w *Wallet) encryptAndSaveSeedFile(masterKey crypto.TwofishKey, seed modules.Seed) (SeedFile, error) {
 	var uid UniqueID
@@ -136,16 +172,11 @@ func loadPlainSeedFile(sf SeedFile) (modules.Seed, error) {
 // integrateSeed should not be called with the primary seed.
 func (w *Wallet) integrateSeed(seed modules.Seed) error {
 	for i := uint64(0); i < modules.PublicKeysPerSeed; i++ {
-		// Generate the key and check it is new to the wallet.
-		spendableKey, err := generateSpendableKey(seed, i)
+		// Generate the keys and add them to the wallet.
+		err := w.integrateSpendableKeys(seed, i)
 		if err != nil {
 			return err
 		}
-		uh, err := spendableKey.UnlockHash()
-		if err != nil {
-			return err
-		}
-		w.keys[uh] = spendableKey
 	}
 	w.seeds = append(w.seeds, seed)
 	return nil
@@ -227,15 +258,10 @@ func (w *Wallet) createSeed(seed modules.Seed, depth uint64, fs func(modules.See
 	// The wallet preloads keys to prevent confusion for people using the same
 	// seed/wallet file in multiple places.
 	for i := uint64(0); i < depth; i++ {
-		spendableKey, err := generateSpendableKey(seed, i)
+		err := w.integrateSpendableKeys(seed, i)
 		if err != nil {
 			return err
 		}
-		uh, err := spendableKey.UnlockHash()
-		if err != nil {
-			return err
-		}
-		w.keys[uh] = spendableKey
 	}
 	return w.saveSettingsSync()
 }
@@ -263,15 +289,10 @@ func (w *Wallet) initPrimarySeed(sf func(SeedFile) (modules.Seed, error)) error
 	// The wallet preloads keys to prevent confusion when using the same wallet
 	// in multiple places.
 	for i := uint64(0); i < w.persist.PrimarySeedProgress+modules.WalletSeedPreloadDepth; i++ {
-		spendableKey, err := generateSpendableKey(seed, i)
+		err := w.integrateSpendableKeys(seed, i)
 		if err != nil {
 			return err
 		}
-		uh, err := spendableKey.UnlockHash()
-		if err != nil {
-			return err
-		}
-		w.keys[uh] = spendableKey
 	}
 	w.primarySeed = seed
 	w.seeds = append(w.seeds, seed)
@@ -313,25 +334,34 @@ func (w *Wallet) initAuxiliarySeeds(sf func(SeedFile) (modules.Seed, error)) err
 
 // nextPrimarySeedAddress fetches the next address from the primary seed.
 func (w *Wallet) nextPrimarySeedAddress() (types.UnlockHash, error) {
+	return w.nextPrimarySeedAddressFor(generateSpendableKey)
+}
+
+// nextPrimarySeedSecp256k1Address fetches the next secp256k1 address from the primary seed.
+func (w *Wallet) nextPrimarySeedSecp256k1Address() (types.UnlockHash, error) {
+	return w.nextPrimarySeedAddressFor(generateSecp256k1SpendableKey)
+}
+
+func (w *Wallet) nextPrimarySeedAddressFor(generate func(modules.Seed, uint64) (spendableKey, error)) (types.UnlockHash, error) {
 	// Check that the wallet has been unlocked.
 	if !w.unlocked {
 		return types.UnlockHash{}, modules.ErrLockedWallet
 	}
 
-	// Integrate the next key into the wallet, and return the unlock
+	// Integrate the next keys into the wallet, and return the unlock
 	// conditions. Because the wallet preloads keys, the progress used is
 	// 'PrimarySeedProgress+modules.WalletSeedPreloadDepth'.
-	spendableKey, err := generateSpendableKey(w.primarySeed, w.persist.PrimarySeedProgress+modules.WalletSeedPreloadDepth)
+	index := w.persist.PrimarySeedProgress + modules.WalletSeedPreloadDepth
+	err := w.integrateSpendableKeys(w.primarySeed, index)
 	if err != nil {
 		return types.UnlockHash{}, err
 	}
-	uh, err := spendableKey.UnlockHash()
+	w.persist.PrimarySeedProgress++
+	err = w.saveSettingsSync()
 	if err != nil {
 		return types.UnlockHash{}, err
 	}
-	w.keys[uh] = spendableKey
-	w.persist.PrimarySeedProgress++
-	err = w.saveSettingsSync()
+	spendableKey, err := generate(w.primarySeed, index)
 	if err != nil {
 		return types.UnlockHash{}, err
 	}
@@ -370,6 +400,18 @@ func (w *Wallet) NextAddress() (types.UnlockHash, error) {
 	return w.nextPrimarySeedAddress()
 }
 
+// NextSecp256k1Address returns an unlock hash that is ready to receive coins,
+// linked to a public key using the secp256k1 signature algorithm.
+func (w *Wallet) NextSecp256k1Address() (types.UnlockHash, error) {
+	if err := w.tg.Add(); err != nil {
+		return types.UnlockHash{}, err
+	}
+	defer w.tg.Done()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.nextPrimarySeedSecp256k1Address()
+}
+
 // LoadSeed will track all of the addresses generated by the input seed,
 // reclaiming any funds that were lost due to a deleted file or lost encryption
 // key. An error will be returned if the seed has already been integrated with
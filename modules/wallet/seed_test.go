@@ -314,3 +314,55 @@ func TestLoadSeed(t *testing.T) {
 		t.Error("AllSeeds returned the wrong seed")
 	}
 }
+
+// TestGenerateSecp256k1SpendableKey checks that secp256k1 keys are derived deterministically
+// from a seed, and independently from the Ed25519 keys derived for the same index.
+func TestGenerateSecp256k1SpendableKey(t *testing.T) {
+	var seed modules.Seed
+	copy(seed[:], "secp256k1 spendable key seed")
+
+	key, err := generateSecp256k1SpendableKey(seed, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.PublicKey.Algorithm != types.SignatureAlgoSecp256k1 {
+		t.Fatal("unexpected signature algorithm:", key.PublicKey.Algorithm)
+	}
+	if key.Index != 1 {
+		t.Fatal("unexpected index:", key.Index)
+	}
+	var sk crypto.Secp256k1SecretKey
+	copy(sk[:], key.SecretKey)
+	if pk := sk.PublicKey(); !bytes.Equal(pk[:], key.PublicKey.Key) {
+		t.Fatal("public key does not match secret key")
+	}
+	uh, err := key.UnlockHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uh.Type != types.UnlockTypeSecp256k1PubKey {
+		t.Fatal("unexpected unlock type:", uh.Type)
+	}
+
+	key2, err := generateSecp256k1SpendableKey(seed, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key.SecretKey, key2.SecretKey) {
+		t.Fatal("expected the same secret key for the same seed and index")
+	}
+	key2, err = generateSecp256k1SpendableKey(seed, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(key.SecretKey, key2.SecretKey) {
+		t.Fatal("expected a different secret key for a different index")
+	}
+	edKey, err := generateSpendableKey(seed, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(key.SecretKey, edKey.SecretKey[:crypto.Secp256k1SecretKeySize]) {
+		t.Fatal("expected the secp256k1 secret key to be unrelated to the ed25519 secret key")
+	}
+}
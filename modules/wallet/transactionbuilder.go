@@ -17,6 +17,14 @@ var (
 	// already added at least one successful signature to the transaction,
 	// meaning that future calls to Sign will result in an invalid transaction.
 	errBuilderAlreadySigned = errors.New("sign has already been called on this transaction builder, multiple calls can cause issues")
+
+	// errExpiryNotSupported is returned in case a transaction with an expiry height is to be created,
+	// while the transaction version required for it is not registered or not yet active.
+	errExpiryNotSupported = errors.New("transactions with an expiry height are not supported (yet) on this chain")
+
+	// errSigHashFlagsNotSupported is returned in case signature hash flags are set,
+	// while the version of the transaction does not support them.
+	errSigHashFlagsNotSupported = errors.New("signature hash flags are not supported by the transaction version")
 )
 
 // transactionBuilder allows transactions to be manually constructed, including
@@ -32,6 +40,10 @@ type transactionBuilder struct {
 	coinInputs       []inputSignContext
 	blockstakeInputs []inputSignContext
 
+	// sigHashFlags define which parts of the transaction
+	// are signed by the signatures added by this builder
+	sigHashFlags types.SigHashFlags
+
 	wallet *Wallet
 }
 
@@ -44,6 +56,24 @@ type inputSignContext struct {
 // transaction. The coin input will not be signed until 'Sign' is called
 // on the transaction builder.
 func (tb *transactionBuilder) FundCoins(amount types.Currency, refundAddress *types.UnlockHash, reuseRefundAddress bool) error {
+	return tb.fundCoins(types.AssetID{}, amount, refundAddress, reuseRefundAddress)
+}
+
+// FundAsset will add coin inputs, holding exactly 'amount' of the given asset, to the
+// transaction. Any refund output is added as a regular coin output,
+// it is up to the caller to define it as holding the asset,
+// using a transaction version which supports assets.
+// The coin inputs will not be signed until 'Sign' is called on the transaction builder.
+func (tb *transactionBuilder) FundAsset(assetID types.AssetID, amount types.Currency, refundAddress *types.UnlockHash, reuseRefundAddress bool) error {
+	if assetID.IsNative() {
+		return errors.New("cannot fund the native coin as an asset")
+	}
+	return tb.fundCoins(assetID, amount, refundAddress, reuseRefundAddress)
+}
+
+// fundCoins adds coin inputs, holding exactly 'amount' of the given asset (or native coin),
+// to the transaction, adding a refund output if needed.
+func (tb *transactionBuilder) fundCoins(assetID types.AssetID, amount types.Currency, refundAddress *types.UnlockHash, reuseRefundAddress bool) error {
 	tb.wallet.mu.Lock()
 	defer tb.wallet.mu.Unlock()
 
@@ -57,25 +87,35 @@ func (tb *transactionBuilder) FundCoins(amount types.Currency, refundAddress *ty
 	// Collect a value-sorted set of fulfillable coin outputs.
 	var so sortedOutputs
 	for scoid, sco := range tb.wallet.coinOutputs {
-		if !sco.Condition.Fulfillable(ctx) {
+		if tb.wallet.coinOutputAsset(scoid) != assetID {
+			continue
+		}
+		if !sco.Condition.Fulfillable(tb.wallet.getFulfillableContextForOutput(ctx, types.OutputID(scoid))) {
 			continue
 		}
 		so.ids = append(so.ids, scoid)
 		so.outputs = append(so.outputs, sco)
 	}
-	// Add all of the unconfirmed outputs as well.
+	// Add all of the unconfirmed outputs as well,
+	// which can be confirmed in the next block at the earliest.
+	unconfirmedCtx := ctx
+	unconfirmedCtx.ConfirmationHeight = ctx.BlockHeight + 1
 	for _, upt := range tb.wallet.unconfirmedProcessedTransactions {
 		for i, sco := range upt.Transaction.CoinOutputs {
+			scoid := upt.Transaction.CoinOutputID(uint64(i))
+			if tb.wallet.coinOutputAsset(scoid) != assetID {
+				continue
+			}
 			uh := sco.Condition.UnlockHash()
 			// Determine if the output belongs to the wallet.
 			exists, err := tb.wallet.keyExists(uh)
 			if err != nil {
 				return err
 			}
-			if !exists || !sco.Condition.Fulfillable(ctx) {
+			if !exists || !sco.Condition.Fulfillable(unconfirmedCtx) {
 				continue
 			}
-			so.ids = append(so.ids, upt.Transaction.CoinOutputID(uint64(i)))
+			so.ids = append(so.ids, scoid)
 			so.outputs = append(so.outputs, sco)
 		}
 	}
@@ -109,8 +149,8 @@ func (tb *transactionBuilder) FundCoins(amount types.Currency, refundAddress *ty
 		uh := sco.Condition.UnlockHash()
 		var ff types.MarshalableUnlockFulfillment
 		switch sco.Condition.ConditionType() {
-		case types.ConditionTypeUnlockHash, types.ConditionTypeTimeLock:
-			// ConditionTypeTimeLock is fine, as we know it's fulfillable,
+		case types.ConditionTypeUnlockHash, types.ConditionTypeTimeLock, types.ConditionTypeRelativeTimeLock:
+			// ConditionType(Relative)TimeLock is fine, as we know it's fulfillable,
 			// and that can only mean for now that it is using an internal unlockHashCondition or nilCondition
 			pk, _, err := tb.wallet.getKey(uh)
 			if err != nil {
@@ -223,7 +263,7 @@ func (tb *transactionBuilder) FundBlockStakes(amount types.Currency, refundAddre
 	var potentialFund types.Currency
 	var spentSfoids []types.BlockStakeOutputID
 	for sfoid, sfo := range tb.wallet.blockstakeOutputs {
-		if !sfo.Condition.Fulfillable(ctx) {
+		if !sfo.Condition.Fulfillable(tb.wallet.getFulfillableContextForOutput(ctx, types.OutputID(sfoid))) {
 			continue
 		}
 		// Check that this output has not recently been spent by the wallet.
@@ -242,8 +282,8 @@ func (tb *transactionBuilder) FundBlockStakes(amount types.Currency, refundAddre
 		uh := sfo.Condition.UnlockHash()
 		var ff types.MarshalableUnlockFulfillment
 		switch sfo.Condition.ConditionType() {
-		case types.ConditionTypeUnlockHash, types.ConditionTypeTimeLock:
-			// ConditionTypeTimeLock is fine, as we know it's fulfillable,
+		case types.ConditionTypeUnlockHash, types.ConditionTypeTimeLock, types.ConditionTypeRelativeTimeLock:
+			// ConditionType(Relative)TimeLock is fine, as we know it's fulfillable,
 			// and that can only mean for now that it is using an internal unlockHashCondition or nilCondition
 			pk, _, err := tb.wallet.getKey(uh)
 			if err != nil {
@@ -403,6 +443,19 @@ func (tb *transactionBuilder) SetArbitraryData(arb []byte) {
 	tb.transaction.ArbitraryData = arb
 }
 
+// SetSigHashFlags sets the signature hash flags used for all signatures
+// added by this builder, which is only possible for transaction versions that support them.
+func (tb *transactionBuilder) SetSigHashFlags(flags types.SigHashFlags) error {
+	if err := flags.Validate(); err != nil {
+		return err
+	}
+	if flags != types.SigHashAll && !tb.transaction.Version.SupportsSigHashFlags() {
+		return errSigHashFlagsNotSupported
+	}
+	tb.sigHashFlags = flags
+	return nil
+}
+
 // Drop discards all of the outputs in a transaction, returning them to the
 // pool so that other transactions may use them. 'Drop' should only be called
 // if a transaction is both unsigned and will not be used any further.
@@ -425,7 +478,7 @@ func (tb *transactionBuilder) Drop() {
 	tb.parents = nil
 	tb.signed = false
 	tb.transaction = types.Transaction{
-		Version: tb.wallet.chainCts.DefaultTransactionVersion,
+		Version: tb.wallet.defaultTransactionVersion(),
 	}
 
 	tb.newParents = nil
@@ -470,6 +523,8 @@ func (tb *transactionBuilder) Sign() ([]types.Transaction, error) {
 			ExtraObjects: []interface{}{uint64(ctx.InputIndex)},
 			Transaction:  tb.transaction,
 			Key:          sk,
+			SigHashFlags: tb.sigHashFlags,
+			ParentID:     types.OutputID(input.ParentID),
 		})
 		if err != nil {
 			return nil, err
@@ -486,6 +541,8 @@ func (tb *transactionBuilder) Sign() ([]types.Transaction, error) {
 			ExtraObjects: []interface{}{uint64(ctx.InputIndex)},
 			Transaction:  tb.transaction,
 			Key:          sk,
+			SigHashFlags: tb.sigHashFlags,
+			ParentID:     types.OutputID(input.ParentID),
 		})
 		if err != nil {
 			return nil, err
@@ -511,6 +568,8 @@ func (tb *transactionBuilder) SignAllPossible() error {
 		return modules.ErrLockedWallet
 	}
 
+	ctx := tb.wallet.getFulfillableContextForLatestBlock()
+
 	// sign all coin inputs
 	for i := range tb.transaction.CoinInputs {
 		ci := &tb.transaction.CoinInputs[i]
@@ -519,7 +578,7 @@ func (tb *transactionBuilder) SignAllPossible() error {
 			return err
 		}
 
-		if err = tb.signCoinInput(i, ci, uco.Condition.Condition); err != nil {
+		if err = tb.signCoinInput(i, ci, uco.Condition.Condition, tb.wallet.getFulfillableContextForOutput(ctx, types.OutputID(ci.ParentID))); err != nil {
 			return err
 		}
 
@@ -532,7 +591,7 @@ func (tb *transactionBuilder) SignAllPossible() error {
 		if err != nil {
 			return err
 		}
-		if err = tb.signBlockStakeInput(i, bsi, ubso.Condition.Condition); err != nil {
+		if err = tb.signBlockStakeInput(i, bsi, ubso.Condition.Condition, tb.wallet.getFulfillableContextForOutput(ctx, types.OutputID(bsi.ParentID))); err != nil {
 			return err
 		}
 	}
@@ -543,9 +602,9 @@ func (tb *transactionBuilder) SignAllPossible() error {
 			return errors.New("failed to sign extension: nil fulfillment proxy cannot be signed")
 		}
 		if condition.ConditionType() == types.ConditionTypeNil {
-			return tb.signFulfillment(fulfillment, &types.NilCondition{}, extraObjects...)
+			return tb.signFulfillment(fulfillment, &types.NilCondition{}, ctx, types.OutputID{}, extraObjects...)
 		}
-		return tb.signFulfillment(fulfillment, condition.Condition, extraObjects...)
+		return tb.signFulfillment(fulfillment, condition.Condition, ctx, types.OutputID{}, extraObjects...)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to sign extension, using tx-defined logic: %v", err)
@@ -555,16 +614,18 @@ func (tb *transactionBuilder) SignAllPossible() error {
 }
 
 // signCoinInput attempts to sign a coin input with a key from the wallet
-func (tb *transactionBuilder) signCoinInput(idx int, ci *types.CoinInput, cond types.MarshalableUnlockCondition) error {
-	return tb.signFulfillment(&ci.Fulfillment, cond, uint64(idx))
+func (tb *transactionBuilder) signCoinInput(idx int, ci *types.CoinInput, cond types.MarshalableUnlockCondition, ctx types.FulfillableContext) error {
+	return tb.signFulfillment(&ci.Fulfillment, cond, ctx, types.OutputID(ci.ParentID), uint64(idx))
 }
 
 // signBlockStakeInput attempts to sign a blockstake input with a key from the wallet
-func (tb *transactionBuilder) signBlockStakeInput(idx int, bsi *types.BlockStakeInput, cond types.MarshalableUnlockCondition) error {
-	return tb.signFulfillment(&bsi.Fulfillment, cond, uint64(idx))
+func (tb *transactionBuilder) signBlockStakeInput(idx int, bsi *types.BlockStakeInput, cond types.MarshalableUnlockCondition, ctx types.FulfillableContext) error {
+	return tb.signFulfillment(&bsi.Fulfillment, cond, ctx, types.OutputID(bsi.ParentID), uint64(idx))
 }
 
-func (tb *transactionBuilder) signFulfillment(fulfillment *types.UnlockFulfillmentProxy, cond types.MarshalableUnlockCondition, extraObjects ...interface{}) error {
+// signFulfillment signs the given fulfillment using the wallet's keys, the parentID is the ID
+// of the output spent by the input the fulfillment belongs to, and is nil for extension fulfillments.
+func (tb *transactionBuilder) signFulfillment(fulfillment *types.UnlockFulfillmentProxy, cond types.MarshalableUnlockCondition, ctx types.FulfillableContext, parentID types.OutputID, extraObjects ...interface{}) error {
 	var err error
 	switch uh := cond.UnlockHash(); uh.Type {
 	case types.UnlockTypeNil:
@@ -577,13 +638,15 @@ func (tb *transactionBuilder) signFulfillment(fulfillment *types.UnlockFulfillme
 			return err
 		}
 		fallthrough
-	case types.UnlockTypePubKey:
+	case types.UnlockTypePubKey, types.UnlockTypeSecp256k1PubKey:
 		if key, exists := tb.wallet.keys[uh]; exists {
-			fulfillment.Fulfillment = types.NewSingleSignatureFulfillment(types.Ed25519PublicKey(key.PublicKey))
+			fulfillment.Fulfillment = types.NewSingleSignatureFulfillment(key.PublicKey)
 			err := fulfillment.Fulfillment.Sign(types.FulfillmentSignContext{
 				ExtraObjects: extraObjects,
 				Transaction:  tb.transaction,
 				Key:          key.SecretKey,
+				SigHashFlags: tb.sigHashFlags,
+				ParentID:     parentID,
 			})
 			if err != nil {
 				return err
@@ -599,22 +662,31 @@ func (tb *transactionBuilder) signFulfillment(fulfillment *types.UnlockFulfillme
 		if fulfillment.FulfillmentType() == types.FulfillmentTypeNil {
 			fulfillment.Fulfillment = &types.MultiSignatureFulfillment{}
 		}
-		for _, uh := range uhs {
-			if key, exists := tb.wallet.keys[uh]; exists {
-				err := fulfillment.Sign(types.FulfillmentSignContext{
-					ExtraObjects: extraObjects,
-					Transaction:  tb.transaction,
-					Key: types.KeyPair{
-						PublicKey:  types.Ed25519PublicKey(key.PublicKey),
-						PrivateKey: types.ByteSlice(key.SecretKey[:]),
-					},
-				})
-				if err != nil {
-					return err
-				}
-				tb.signed = true
-			}
+		return tb.signMultiSignatureFulfillment(fulfillment, uhs, parentID, extraObjects...)
+
+	case types.UnlockTypeWeightedMultiSig:
+		wms := getWeightedMultiSignatureCondition(cond)
+		if wms == nil {
+			return fmt.Errorf("unexpected condition type %T for weighted multi sig condition", cond)
+		}
+		if fulfillment.FulfillmentType() == types.FulfillmentTypeNil {
+			fulfillment.Fulfillment = &types.WeightedMultiSignatureFulfillment{}
 		}
+		return tb.signMultiSignatureFulfillment(fulfillment, wms.UnlockHashSlice(), parentID, extraObjects...)
+
+	case types.UnlockTypePolicy:
+		policy := getPolicyCondition(cond)
+		if policy == nil {
+			return fmt.Errorf("unexpected condition type %T for policy condition", cond)
+		}
+		if fulfillment.FulfillmentType() == types.FulfillmentTypeNil {
+			fulfillment.Fulfillment = &types.PolicyFulfillment{}
+		}
+		pf, ok := fulfillment.Fulfillment.(*types.PolicyFulfillment)
+		if !ok {
+			return fmt.Errorf("unexpected fulfillment type %T for policy condition", fulfillment.Fulfillment)
+		}
+		return tb.signPolicyFulfillment(pf, policy, ctx, parentID, extraObjects...)
 
 	default:
 		return fmt.Errorf("failed to sign fulfillment: unexpected condition type %T", cond)
@@ -623,6 +695,117 @@ func (tb *transactionBuilder) signFulfillment(fulfillment *types.UnlockFulfillme
 	return nil
 }
 
+// signMultiSignatureFulfillment signs the given (weighted) multisig fulfillment,
+// using the keys of all given unlock hashes which are owned by the wallet.
+func (tb *transactionBuilder) signMultiSignatureFulfillment(fulfillment *types.UnlockFulfillmentProxy, uhs []types.UnlockHash, parentID types.OutputID, extraObjects ...interface{}) error {
+	for _, uh := range uhs {
+		if key, exists := tb.wallet.keys[uh]; exists {
+			err := fulfillment.Sign(types.FulfillmentSignContext{
+				ExtraObjects: extraObjects,
+				Transaction:  tb.transaction,
+				Key: types.KeyPair{
+					PublicKey:  key.PublicKey,
+					PrivateKey: key.SecretKey,
+				},
+				SigHashFlags: tb.sigHashFlags,
+				ParentID:     parentID,
+			})
+			if err != nil {
+				return err
+			}
+			tb.signed = true
+		}
+	}
+	return nil
+}
+
+// signPolicyFulfillment signs all fulfillable branches of the given policy condition,
+// for which the wallet has the required key(s). Once the fully fulfilled branches
+// satisfy the policy, all other (partially signed) branches are dropped,
+// as each branch defined by a policy fulfillment has to be fulfilled.
+func (tb *transactionBuilder) signPolicyFulfillment(pf *types.PolicyFulfillment, policy *types.PolicyCondition, ctx types.FulfillableContext, parentID types.OutputID, extraObjects ...interface{}) error {
+	signed := tb.signed
+	for idx, branch := range policy.Conditions {
+		switch branch.UnlockHash().Type {
+		case types.UnlockTypePubKey, types.UnlockTypeSecp256k1PubKey, types.UnlockTypeMultiSig, types.UnlockTypeWeightedMultiSig, types.UnlockTypePolicy:
+		default:
+			continue // branches that cannot be signed by the wallet are skipped
+		}
+		if !branch.Fulfillable(ctx) {
+			continue
+		}
+		fulfillment, exists := pf.Branch(uint64(idx))
+		if exists && fulfillment.FulfillmentType() == types.FulfillmentTypeSingleSignature {
+			continue // already signed
+		}
+		tb.signed = false
+		err := tb.signFulfillment(&fulfillment, branch.Condition, ctx, parentID, extraObjects...)
+		if err != nil {
+			return fmt.Errorf("failed to sign policy branch #%d: %v", idx, err)
+		}
+		if tb.signed {
+			pf.SetBranch(uint64(idx), fulfillment)
+			signed = true
+		}
+	}
+	tb.signed = signed
+
+	required, err := policy.RequiredBranchCount()
+	if err != nil {
+		return err
+	}
+	fulfillCtx := types.FulfillContext{
+		ExtraObjects:       extraObjects,
+		BlockHeight:        ctx.BlockHeight,
+		BlockTime:          ctx.BlockTime,
+		ConfirmationHeight: ctx.ConfirmationHeight,
+		Transaction:        tb.transaction,
+		ParentID:           parentID,
+	}
+	var fulfilled []types.PolicyBranchFulfillment
+	for _, branch := range pf.Branches {
+		if policy.Conditions[branch.Index].Fulfill(branch.Fulfillment, fulfillCtx) == nil {
+			fulfilled = append(fulfilled, branch)
+		}
+	}
+	if uint64(len(fulfilled)) >= required {
+		pf.Branches = fulfilled
+	}
+	return nil
+}
+
+// getPolicyCondition returns the policy condition of the given condition,
+// unwrapping it if needed, or nil in case it isn't a policy condition.
+func getPolicyCondition(condition types.MarshalableUnlockCondition) *types.PolicyCondition {
+	for condition != nil {
+		switch c := condition.(type) {
+		case *types.PolicyCondition:
+			return c
+		case types.MarshalableUnlockConditionGetter:
+			condition = c.GetMarshalableUnlockCondition()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// getWeightedMultiSignatureCondition returns the weighted multisig condition of the given condition,
+// unwrapping it if needed, or nil in case it isn't a weighted multisig condition.
+func getWeightedMultiSignatureCondition(condition types.MarshalableUnlockCondition) *types.WeightedMultiSignatureCondition {
+	for condition != nil {
+		switch c := condition.(type) {
+		case *types.WeightedMultiSignatureCondition:
+			return c
+		case types.MarshalableUnlockConditionGetter:
+			condition = c.GetMarshalableUnlockCondition()
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
 // ViewTransaction returns a transaction-in-progress along with all of its
 // parents, specified by id. An error is returned if the id is invalid.  Note
 // that ids become invalid for a transaction after 'SignTransaction' has been
@@ -682,9 +865,31 @@ func (w *Wallet) RegisterTransaction(t types.Transaction, parents []types.Transa
 }
 
 // StartTransaction is a convenience function that calls
-// StartTransactionWithVersion with the DefaultTransactionVersion constant.
+// StartTransactionWithVersion with the default transaction version of this wallet.
 func (w *Wallet) StartTransaction() modules.TransactionBuilder {
-	return w.StartTransactionWithVersion(w.chainCts.DefaultTransactionVersion)
+	w.mu.RLock()
+	version := w.defaultTransactionVersion()
+	w.mu.RUnlock()
+	return w.StartTransactionWithVersion(version)
+}
+
+// defaultTransactionVersion returns the transaction version to be used for new transactions.
+// This is the DefaultTransactionVersion constant, unless that is TransactionVersionOne,
+// in which case TransactionVersionTwo is used as soon as it is registered and activated,
+// such that the signatures of the transaction commit to the chain ID.
+//
+// The wallet has to be (read) locked when calling this method.
+func (w *Wallet) defaultTransactionVersion() types.TransactionVersion {
+	if w.chainCts.DefaultTransactionVersion != types.TransactionVersionOne {
+		return w.chainCts.DefaultTransactionVersion
+	}
+	if types.TransactionVersionTwo.IsValidTransactionVersion() != nil {
+		return w.chainCts.DefaultTransactionVersion
+	}
+	if !w.chainCts.ActivationSchedule.TransactionVersionIsActive(types.TransactionVersionTwo, w.consensusSetHeight) {
+		return w.chainCts.DefaultTransactionVersion
+	}
+	return types.TransactionVersionTwo
 }
 
 // StartTransactionWithVersion is a convenience function that calls
@@ -694,3 +899,24 @@ func (w *Wallet) StartTransactionWithVersion(version types.TransactionVersion) m
 		Version: version,
 	}, nil)
 }
+
+// startValidUntilTransaction starts a TransactionVersionThree transaction,
+// which can no longer be included in a block once the given amount of blocks
+// have been created on top of the current block.
+func (w *Wallet) startValidUntilTransaction(expiresIn types.BlockHeight) (modules.TransactionBuilder, error) {
+	if types.TransactionVersionThree.IsValidTransactionVersion() != nil {
+		return nil, errExpiryNotSupported
+	}
+	w.mu.RLock()
+	height := w.consensusSetHeight
+	w.mu.RUnlock()
+	if !w.chainCts.ActivationSchedule.TransactionVersionIsActive(types.TransactionVersionThree, height) {
+		return nil, errExpiryNotSupported
+	}
+	return w.RegisterTransaction(types.Transaction{
+		Version: types.TransactionVersionThree,
+		Extension: &types.ValidUntilTransactionExtension{
+			ValidUntil: height + expiresIn,
+		},
+	}, nil), nil
+}
@@ -448,3 +448,115 @@ func TestConcurrentBuilders(t *testing.T) {
 // 		t.Fatal("did not get the expected ending balance", expected, endingSCConfirmed, startingSCConfirmed)
 // 	}
 // }
+
+// TestDefaultTransactionVersion probes the defaultTransactionVersion method,
+// which should only use the chain ID transaction version once it is registered and activated.
+func TestDefaultTransactionVersion(t *testing.T) {
+	w := &Wallet{chainCts: types.TestnetChainConstants()}
+	w.chainCts.ActivationSchedule.TransactionVersions = map[types.TransactionVersion]types.BlockHeight{
+		types.TransactionVersionTwo: 10,
+	}
+
+	// not registered
+	w.consensusSetHeight = 10
+	if v := w.defaultTransactionVersion(); v != types.TransactionVersionOne {
+		t.Errorf("expected version %d, got %d", types.TransactionVersionOne, v)
+	}
+
+	defer types.RegisterTransactionVersion(types.TransactionVersionTwo, nil)
+	types.RegisterTransactionVersion(types.TransactionVersionTwo, types.ChainIDTransactionController{
+		ChainID: types.DefaultBlockchainInfo().ChainID(w.chainCts.GenesisBlockID()),
+	})
+
+	// registered, but not yet activated
+	w.consensusSetHeight = 9
+	if v := w.defaultTransactionVersion(); v != types.TransactionVersionOne {
+		t.Errorf("expected version %d, got %d", types.TransactionVersionOne, v)
+	}
+	// registered and activated
+	w.consensusSetHeight = 10
+	if v := w.defaultTransactionVersion(); v != types.TransactionVersionTwo {
+		t.Errorf("expected version %d, got %d", types.TransactionVersionTwo, v)
+	}
+	// a custom default transaction version is never upgraded
+	w.chainCts.DefaultTransactionVersion = types.TransactionVersionZero
+	if v := w.defaultTransactionVersion(); v != types.TransactionVersionZero {
+		t.Errorf("expected version %d, got %d", types.TransactionVersionZero, v)
+	}
+
+	// only transactions without fulfillments can be upgraded
+	txn := types.Transaction{
+		CoinInputs: []types.CoinInput{{ParentID: types.CoinOutputID{1}}},
+	}
+	if hasFulfillments(txn) {
+		t.Error("expected transaction to have no fulfillments")
+	}
+	txn.CoinInputs[0].Fulfillment = types.NewFulfillment(&types.MultiSignatureFulfillment{})
+	if !hasFulfillments(txn) {
+		t.Error("expected transaction to have fulfillments")
+	}
+}
+
+// TestStartValidUntilTransaction probes the transaction versioning of transactions with an expiry height.
+func TestStartValidUntilTransaction(t *testing.T) {
+	w := &Wallet{chainCts: types.TestnetChainConstants()}
+	w.chainCts.ActivationSchedule.TransactionVersions = map[types.TransactionVersion]types.BlockHeight{
+		types.TransactionVersionThree: 10,
+	}
+
+	// not registered
+	w.consensusSetHeight = 10
+	if _, err := w.startValidUntilTransaction(5); err != errExpiryNotSupported {
+		t.Errorf("expected error %v, got %v", errExpiryNotSupported, err)
+	}
+
+	defer types.RegisterTransactionVersion(types.TransactionVersionThree, nil)
+	types.RegisterTransactionVersion(types.TransactionVersionThree, types.ValidUntilTransactionController{
+		ChainID: types.DefaultBlockchainInfo().ChainID(w.chainCts.GenesisBlockID()),
+	})
+
+	// registered, but not yet activated
+	w.consensusSetHeight = 9
+	if _, err := w.startValidUntilTransaction(5); err != errExpiryNotSupported {
+		t.Errorf("expected error %v, got %v", errExpiryNotSupported, err)
+	}
+	// registered and activated
+	w.consensusSetHeight = 10
+	tb, err := w.startValidUntilTransaction(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn, _ := tb.View()
+	if txn.Version != types.TransactionVersionThree {
+		t.Errorf("expected version %d, got %d", types.TransactionVersionThree, txn.Version)
+	}
+	if height, ok := txn.ValidUntil(); !ok || height != 15 {
+		t.Errorf("expected transaction to be valid until height 15, got %d (%v)", height, ok)
+	}
+}
+
+// TestSetSigHashFlags probes the SetSigHashFlags method of the transaction builder.
+func TestSetSigHashFlags(t *testing.T) {
+	w := &Wallet{chainCts: types.TestnetChainConstants()}
+
+	tb := w.StartTransactionWithVersion(types.TransactionVersionOne)
+	if err := tb.SetSigHashFlags(types.SigHashAll); err != nil {
+		t.Errorf("expected the default flags to be supported by v1 transactions: %v", err)
+	}
+	if err := tb.SetSigHashFlags(types.SigHashInputSingle); err != errSigHashFlagsNotSupported {
+		t.Errorf("expected error %v, got %v", errSigHashFlagsNotSupported, err)
+	}
+
+	defer types.RegisterTransactionVersion(types.TransactionVersionFour, nil)
+	types.RegisterTransactionVersion(types.TransactionVersionFour, types.SigHashTransactionController{
+		ChainID: types.DefaultBlockchainInfo().ChainID(w.chainCts.GenesisBlockID()),
+	})
+
+	tb = w.StartTransactionWithVersion(types.TransactionVersionFour)
+	if err := tb.SetSigHashFlags(types.SigHashInputSingle | types.SigHashOutputsNone); err != nil {
+		t.Error(err)
+	}
+	if err := tb.SetSigHashFlags(types.SigHashFlags(1 << 7)); err != types.ErrUnknownSigHashFlags {
+		t.Errorf("expected error %v, got %v", types.ErrUnknownSigHashFlags, err)
+	}
+}
@@ -143,7 +143,7 @@ func (w *Wallet) BlockStakeStats() (BCcountLast1000 uint64, BCfeeLast1000 types.
 
 		relevant := false
 		switch uh := bso.Condition.UnlockHash(); uh.Type {
-		case types.UnlockTypePubKey:
+		case types.UnlockTypePubKey, types.UnlockTypeSecp256k1PubKey:
 			_, relevant = w.keys[uh]
 		case types.UnlockTypeNil:
 			relevant = true
@@ -256,10 +256,35 @@ func (w *Wallet) CreateRawTransaction(coids []types.CoinOutputID, bsoids []types
 }
 
 // GreedySign attempts to sign every input in the transaction that can be signed
-// using the keys loaded in this wallet. The transaction is assumed to be valid
+// using the keys loaded in this wallet. The transaction is assumed to be valid.
+//
+// A transaction which uses the DefaultTransactionVersion constant and isn't fulfilled yet,
+// is upgraded to the default transaction version of this wallet prior to signing it.
 func (w *Wallet) GreedySign(txn types.Transaction) (types.Transaction, error) {
+	if txn.Version == w.chainCts.DefaultTransactionVersion && !hasFulfillments(txn) {
+		w.mu.RLock()
+		txn.Version = w.defaultTransactionVersion()
+		w.mu.RUnlock()
+	}
 	txnBuilder := w.RegisterTransaction(txn, nil)
 	err := txnBuilder.SignAllPossible()
 	signedTxn, _ := txnBuilder.View()
 	return signedTxn, err
 }
+
+// hasFulfillments returns true if any of the inputs of the given transaction
+// is already (partially) fulfilled, in which case its version can't be changed,
+// as that would invalidate the signatures created by others.
+func hasFulfillments(txn types.Transaction) bool {
+	for _, ci := range txn.CoinInputs {
+		if ci.Fulfillment.FulfillmentType() != types.FulfillmentTypeNil {
+			return true
+		}
+	}
+	for _, bsi := range txn.BlockStakeInputs {
+		if bsi.Fulfillment.FulfillmentType() != types.FulfillmentTypeNil {
+			return true
+		}
+	}
+	return false
+}
@@ -129,10 +129,10 @@ func (w *Wallet) updateConfirmedSet(cc modules.ConsensusChange) {
 
 func getMultisigConditionProperties(condition types.MarshalableUnlockCondition) ([]types.UnlockHash, uint64) {
 	ct := condition.ConditionType()
-	if ct == types.ConditionTypeTimeLock {
+	if ct == types.ConditionTypeTimeLock || ct == types.ConditionTypeRelativeTimeLock {
 		cg, ok := condition.(types.MarshalableUnlockConditionGetter)
 		if !ok {
-			build.Severe(fmt.Sprintf("unexpected Go-type for TimeLockCondition: %T", condition))
+			build.Severe(fmt.Sprintf("unexpected Go-type for (relative) TimeLockCondition: %T", condition))
 			return nil, 0
 		}
 		return getMultisigConditionProperties(cg.GetMarshalableUnlockCondition())
@@ -152,6 +152,15 @@ func getMultisigConditionProperties(condition types.MarshalableUnlockCondition)
 	}
 }
 
+// assetIDPointer returns a pointer to the given asset ID,
+// or nil in case it identifies the native coin.
+func assetIDPointer(id types.AssetID) *types.AssetID {
+	if id.IsNative() {
+		return nil
+	}
+	return &id
+}
+
 // revertHistory reverts any transaction history that was destroyed by reverted
 // blocks in the consensus change.
 func (w *Wallet) revertHistory(cc modules.ConsensusChange) {
@@ -187,7 +196,33 @@ func (w *Wallet) revertHistory(cc modules.ConsensusChange) {
 // applied blocks.
 func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 	for _, block := range cc.AppliedBlocks {
+		if w.consensusSetHeight == 0 {
+			// a consensus set bootstrapped from a snapshot starts at the snapshot block,
+			// rather than the genesis block, applying all outputs unspent at that height
+			if height, exists := w.cs.BlockHeightOfBlock(block); exists && height > 0 {
+				w.consensusSetHeight = height
+				// the assets held by these coin outputs are looked up once required, see coinOutputAsset
+				for _, diff := range cc.CoinOutputDiffs {
+					w.historicOutputs[types.OutputID(diff.ID)] = historicOutput{
+						UnlockHash:   diff.CoinOutput.Condition.UnlockHash(),
+						Value:        diff.CoinOutput.Value,
+						AssetUnknown: true,
+					}
+				}
+				for _, diff := range cc.BlockStakeOutputDiffs {
+					w.historicOutputs[types.OutputID(diff.ID)] = historicOutput{
+						UnlockHash: diff.BlockStakeOutput.Condition.UnlockHash(),
+						Value:      diff.BlockStakeOutput.Value,
+					}
+				}
+			}
+		}
 		w.consensusSetHeight++
+		blockheight, blockexists := w.cs.BlockHeightOfBlock(block)
+		if !blockexists {
+			build.Critical("Block wherer ubs is used to respent, does not yet exist as processedblock")
+		}
+
 		// Apply the miner payout transaction if applicable.
 		minerPT := modules.ProcessedTransaction{
 			Transaction:           types.Transaction{},
@@ -211,6 +246,8 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 			w.historicOutputs[types.OutputID(block.MinerPayoutID(uint64(i)))] = historicOutput{
 				UnlockHash: mp.UnlockHash,
 				Value:      mp.Value,
+				// miner payouts are only added to the unspent outputs once matured
+				ConfirmationHeight: blockheight + w.chainCts.MaturityDelay,
 			}
 		}
 		if relevant {
@@ -218,11 +255,6 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 			w.processedTransactionMap[minerPT.TransactionID] = &w.processedTransactions[len(w.processedTransactions)-1]
 		}
 
-		blockheight, blockexists := w.cs.BlockHeightOfBlock(block)
-		if !blockexists {
-			build.Critical("Block wherer ubs is used to respent, does not yet exist as processedblock")
-		}
-
 		for ti, txn := range block.Transactions {
 			relevant := false
 			pt := modules.ProcessedTransaction{
@@ -249,8 +281,10 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 					WalletAddress:  exists,
 					RelatedAddress: output.UnlockHash,
 					Value:          output.Value,
+					AssetID:        assetIDPointer(output.AssetID),
 				})
 			}
+			exData, _ := txn.CommonExtensionData()
 			for i, sco := range txn.CoinOutputs {
 				_, exists := w.keys[sco.Condition.UnlockHash()]
 				if exists {
@@ -263,16 +297,20 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 					exists = false
 				}
 				uh := sco.Condition.UnlockHash()
+				assetID := exData.CoinOutputAsset(i)
 				pt.Outputs = append(pt.Outputs, modules.ProcessedOutput{
 					FundType:       types.SpecifierCoinOutput,
 					MaturityHeight: w.consensusSetHeight,
 					WalletAddress:  exists,
 					RelatedAddress: uh,
 					Value:          sco.Value,
+					AssetID:        assetIDPointer(assetID),
 				})
 				w.historicOutputs[types.OutputID(txn.CoinOutputID(uint64(i)))] = historicOutput{
-					UnlockHash: uh,
-					Value:      sco.Value,
+					UnlockHash:         uh,
+					Value:              sco.Value,
+					AssetID:            assetID,
+					ConfirmationHeight: blockheight,
 				}
 			}
 			for _, sfi := range txn.BlockStakeInputs {
@@ -329,8 +367,9 @@ func (w *Wallet) applyHistory(cc modules.ConsensusChange) {
 					}
 				}
 				w.historicOutputs[types.OutputID(bsoid)] = historicOutput{
-					UnlockHash: uh,
-					Value:      sfo.Value,
+					UnlockHash:         uh,
+					Value:              sfo.Value,
+					ConfirmationHeight: blockheight,
 				}
 			}
 			if relevant {
@@ -406,8 +445,10 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(txns []types.Transaction,
 				WalletAddress:  exists,
 				RelatedAddress: output.UnlockHash,
 				Value:          output.Value,
+				AssetID:        assetIDPointer(output.AssetID),
 			})
 		}
+		exData, _ := txn.CommonExtensionData()
 		for i, sco := range txn.CoinOutputs {
 			uh := sco.Condition.UnlockHash()
 			_, exists := w.keys[uh]
@@ -420,16 +461,19 @@ func (w *Wallet) ReceiveUpdatedUnconfirmedTransactions(txns []types.Transaction,
 				// set "exists" to false since the output is not owned by the wallet.
 				exists = false
 			}
+			assetID := exData.CoinOutputAsset(i)
 			pt.Outputs = append(pt.Outputs, modules.ProcessedOutput{
 				FundType:       types.SpecifierCoinOutput,
 				MaturityHeight: types.BlockHeight(math.MaxUint64),
 				WalletAddress:  exists,
 				RelatedAddress: uh,
 				Value:          sco.Value,
+				AssetID:        assetIDPointer(assetID),
 			})
 			w.historicOutputs[types.OutputID(txn.CoinOutputID(uint64(i)))] = historicOutput{
 				UnlockHash: uh,
 				Value:      sco.Value,
+				AssetID:    assetID,
 			}
 		}
 		for _, bsi := range txn.BlockStakeInputs {
@@ -36,9 +36,10 @@ var (
 // matched to the corresponding public keys in the unlock conditions. All
 // addresses that are to be used in 'FundSiacoins' or 'FundSiafunds' in the
 // transaction builder must conform to this form of spendable key.
+// The algorithm of the public key defines how the secret key is to be interpreted.
 type spendableKey struct {
-	PublicKey crypto.PublicKey
-	SecretKey crypto.SecretKey
+	PublicKey types.PublicKey
+	SecretKey types.ByteSlice
 	Index     uint64
 }
 
@@ -48,7 +49,7 @@ func (sk spendableKey) WipeSecret() spendableKey {
 }
 
 func (sk spendableKey) UnlockHash() (types.UnlockHash, error) {
-	return types.NewEd25519PubKeyUnlockHash(sk.PublicKey)
+	return types.NewPubKeyUnlockHash(sk.PublicKey)
 }
 
 // Wallet is an object that tracks balances, creates keys and addresses,
@@ -71,6 +72,9 @@ type Wallet struct {
 	cs                 modules.ConsensusSet
 	tpool              modules.TransactionPool
 	consensusSetHeight types.BlockHeight
+	// assets is optional, and used to look up the asset held by
+	// the coin outputs applied by a consensus snapshot
+	assets modules.CoinOutputAssetGetter
 
 	// The following set of fields are responsible for tracking the confirmed
 	// outputs, and for being able to spend them. The seeds are used to derive
@@ -126,6 +130,15 @@ type Wallet struct {
 type historicOutput struct {
 	UnlockHash types.UnlockHash
 	Value      types.Currency
+	// AssetID is the ID of the asset held by a coin output,
+	// the zero ID in case it holds the native coin
+	AssetID types.AssetID
+	// AssetUnknown is true for the coin outputs applied by a consensus snapshot,
+	// as the transaction which defines their asset is unknown to the wallet
+	AssetUnknown bool
+	// ConfirmationHeight is the height at which the output
+	// was added to the unspent outputs, 0 if unknown or unconfirmed
+	ConfirmationHeight types.BlockHeight
 }
 
 // New creates a new wallet, loading any known addresses from the input file
@@ -133,6 +146,14 @@ type historicOutput struct {
 // not loaded into the wallet during the call to 'new', but rather during the
 // call to 'Unlock'.
 func New(cs modules.ConsensusSet, tpool modules.TransactionPool, persistDir string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, verboseLogging bool) (*Wallet, error) {
+	return NewWithAssets(cs, tpool, persistDir, bcInfo, chainCts, verboseLogging, nil)
+}
+
+// NewWithAssets creates a new wallet just like New, using the given (optional) asset getter
+// to look up the asset held by the coin outputs applied by a consensus snapshot.
+// Chains which support assets and bootstrap their consensus set from a snapshot require it,
+// as the wallet otherwise assumes that all such outputs hold the native coin.
+func NewWithAssets(cs modules.ConsensusSet, tpool modules.TransactionPool, persistDir string, bcInfo types.BlockchainInfo, chainCts types.ChainConstants, verboseLogging bool, assets modules.CoinOutputAssetGetter) (*Wallet, error) {
 	// Check for nil dependencies.
 	if cs == nil {
 		return nil, errNilConsensusSet
@@ -143,8 +164,9 @@ func New(cs modules.ConsensusSet, tpool modules.TransactionPool, persistDir stri
 
 	// Initialize the data structure.
 	w := &Wallet{
-		cs:    cs,
-		tpool: tpool,
+		cs:     cs,
+		tpool:  tpool,
+		assets: assets,
 
 		keys:                      make(map[types.UnlockHash]spendableKey),
 		coinOutputs:               make(map[types.CoinOutputID]types.CoinOutput),
@@ -271,7 +293,7 @@ func (w *Wallet) getKey(address types.UnlockHash) (types.PublicKey, types.ByteSl
 	if !found {
 		return types.PublicKey{}, types.ByteSlice{}, errUnknownAddress
 	}
-	return types.Ed25519PublicKey(sp.PublicKey), types.ByteSlice(sp.SecretKey[:]), nil
+	return sp.PublicKey, sp.SecretKey, nil
 }
 func (w *Wallet) keyExists(address types.UnlockHash) (bool, error) {
 	if !w.unlocked {
@@ -299,7 +321,7 @@ func (w *Wallet) GetUnspentBlockStakeOutputs() (unspent []types.UnspentBlockStak
 
 	// collect all fulfillable block stake outputs
 	for usbsoid, output := range w.blockstakeOutputs {
-		if output.Condition.Fulfillable(ctx) {
+		if output.Condition.Fulfillable(w.getFulfillableContextForOutput(ctx, types.OutputID(usbsoid))) {
 			unspent = append(unspent, w.unspentblockstakeoutputs[usbsoid])
 		}
 	}
@@ -314,3 +336,10 @@ func (w *Wallet) getFulfillableContextForLatestBlock() types.FulfillableContext
 		BlockTime:   block.Timestamp,
 	}
 }
+
+// getFulfillableContextForOutput returns the given (latest block) fulfillable context,
+// completed with the height at which the output, identified by the given ID, was confirmed.
+func (w *Wallet) getFulfillableContextForOutput(ctx types.FulfillableContext, id types.OutputID) types.FulfillableContext {
+	ctx.ConfirmationHeight = w.historicOutputs[id].ConfirmationHeight
+	return ctx
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"errors"
+	"io"
 	"math/big"
 	"path/filepath"
 	"strconv"
@@ -502,6 +503,22 @@ func (css *consensusSetStub) Synced() bool {
 	return true
 }
 
+func (css *consensusSetStub) SyncProgress() modules.ConsensusSyncProgress {
+	return modules.ConsensusSyncProgress{}
+}
+
+func (css *consensusSetStub) PrunedHeight() types.BlockHeight {
+	return 0
+}
+
+func (css *consensusSetStub) RefusedForks() []modules.RefusedFork {
+	return nil
+}
+
+func (css *consensusSetStub) ExportSnapshot(io.Writer) (modules.ConsensusSnapshot, error) {
+	return modules.ConsensusSnapshot{}, errors.New("not implemented")
+}
+
 func (css *consensusSetStub) InCurrentPath(id types.BlockID) bool {
 	for _, b := range css.blocks {
 		if b.ID() == id {
@@ -3,21 +3,20 @@ package persist
 import (
 	"fmt"
 	"sync"
-	"time"
 
-	bolt "github.com/rivine/bbolt"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
-// BoltDatabase is a persist-level wrapper for the bolt database, providing
+// Database is a persist-level wrapper for a key-value database, providing
 // extra information such as a version number.
-type BoltDatabase struct {
+type Database struct {
 	Metadata
-	*bolt.DB
+	kv.DB
 }
 
 // SaveMetadata overwrites the metadata.
-func (db *BoltDatabase) SaveMetadata() error {
-	return db.Update(func(tx *bolt.Tx) error {
+func (db *Database) SaveMetadata() error {
+	return db.Update(func(tx kv.Tx) error {
 		// Check if the database has metadata. If not, create metadata for the
 		// database.
 		bucket := tx.Bucket([]byte("Metadata"))
@@ -34,8 +33,8 @@ func (db *BoltDatabase) SaveMetadata() error {
 
 // checkMetadata confirms that the metadata in the database is
 // correct. If there is no metadata, correct metadata is inserted
-func (db *BoltDatabase) checkMetadata(md Metadata) error {
-	err := db.Update(func(tx *bolt.Tx) error {
+func (db *Database) checkMetadata(md Metadata) error {
+	err := db.Update(func(tx kv.Tx) error {
 		// Check if the database has metadata. If not, create metadata for the
 		// database.
 		bucket := tx.Bucket([]byte("Metadata"))
@@ -59,7 +58,7 @@ func (db *BoltDatabase) checkMetadata(md Metadata) error {
 
 // updateMetadata will set the contents of the metadata bucket to the values
 // in db.Metadata.
-func (db *BoltDatabase) updateMetadata(tx *bolt.Tx) error {
+func (db *Database) updateMetadata(tx kv.Tx) error {
 	bucket, err := tx.CreateBucketIfNotExists([]byte("Metadata"))
 	if err != nil {
 		return err
@@ -76,55 +75,59 @@ func (db *BoltDatabase) updateMetadata(tx *bolt.Tx) error {
 }
 
 // Close closes the database.
-func (db *BoltDatabase) Close() error {
+func (db *Database) Close() error {
 	return db.DB.Close()
 }
 
-// OpenDatabase opens a database and validates its metadata.
-func OpenDatabase(md Metadata, filename string) (*BoltDatabase, error) {
-	// Open the database using a 3 second timeout (without the timeout,
-	// database will potentially hang indefinitely.
-	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 3 * time.Second})
+// OpenDatabase opens a bolt database and validates its metadata.
+func OpenDatabase(md Metadata, filename string) (*Database, error) {
+	return OpenDatabaseWithBackend(md, filename, kv.BackendBolt)
+}
+
+// OpenDatabaseWithBackend opens a database using the given storage backend
+// and validates its metadata.
+func OpenDatabaseWithBackend(md Metadata, filename string, backend kv.Backend) (*Database, error) {
+	db, err := kv.Open(backend, filename)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check the metadata.
-	boltDB := &BoltDatabase{
+	kvDB := &Database{
 		Metadata: md,
 		DB:       db,
 	}
-	err = boltDB.checkMetadata(md)
+	err = kvDB.checkMetadata(md)
 	if err != nil {
 		db.Close()
 		return nil, err
 	}
 
-	return boltDB, nil
+	return kvDB, nil
 }
 
-// LazyBoltBucket is a lazy implementation of the bolt DB,
-// allowing you to only actually get the bucket from bolt db if you need it.
-type LazyBoltBucket struct {
-	createdBucket *bolt.Bucket
+// LazyBucket is a lazy bucket implementation,
+// allowing you to only actually get the bucket from the database if you need it.
+type LazyBucket struct {
+	createdBucket kv.Bucket
 	once          sync.Once
-	getter        func() (*bolt.Bucket, error)
+	getter        func() (kv.Bucket, error)
 }
 
-// NewLazyBoltBucket creates a new lazy BoltDB bucket,
-// see `LazyBoltBucket` for more information.
-func NewLazyBoltBucket(getter func() (*bolt.Bucket, error)) *LazyBoltBucket {
-	return &LazyBoltBucket{
+// NewLazyBucket creates a new lazy bucket,
+// see `LazyBucket` for more information.
+func NewLazyBucket(getter func() (kv.Bucket, error)) *LazyBucket {
+	return &LazyBucket{
 		createdBucket: nil,
 		getter:        getter,
 	}
 }
 
-func (lb *LazyBoltBucket) AsBoltBucket() (*bolt.Bucket, error) {
+func (lb *LazyBucket) AsBucket() (kv.Bucket, error) {
 	return lb.bucket()
 }
 
-func (lb *LazyBoltBucket) Bucket(name []byte) (*bolt.Bucket, error) {
+func (lb *LazyBucket) Bucket(name []byte) (kv.Bucket, error) {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return nil, err
@@ -135,113 +138,64 @@ func (lb *LazyBoltBucket) Bucket(name []byte) (*bolt.Bucket, error) {
 	}
 	return outBucket, nil
 }
-func (lb *LazyBoltBucket) CreateBucket(key []byte) (*bolt.Bucket, error) {
+func (lb *LazyBucket) CreateBucket(key []byte) (kv.Bucket, error) {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return nil, err
 	}
 	return bucket.CreateBucket(key)
 }
-func (lb *LazyBoltBucket) CreateBucketIfNotExists(key []byte) (*bolt.Bucket, error) {
+func (lb *LazyBucket) CreateBucketIfNotExists(key []byte) (kv.Bucket, error) {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return nil, err
 	}
 	return bucket.CreateBucketIfNotExists(key)
 }
-func (lb *LazyBoltBucket) Cursor() (*bolt.Cursor, error) {
+func (lb *LazyBucket) Cursor() (kv.Cursor, error) {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return nil, err
 	}
 	return bucket.Cursor(), nil
 }
-func (lb *LazyBoltBucket) Delete(key []byte) error {
+func (lb *LazyBucket) Delete(key []byte) error {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return err
 	}
 	return bucket.Delete(key)
 }
-func (lb *LazyBoltBucket) DeleteBucket(key []byte) error {
+func (lb *LazyBucket) DeleteBucket(key []byte) error {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return err
 	}
 	return bucket.DeleteBucket(key)
 }
-func (lb *LazyBoltBucket) ForEach(fn func(k, v []byte) error) error {
+func (lb *LazyBucket) ForEach(fn func(k, v []byte) error) error {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return err
 	}
 	return bucket.ForEach(fn)
 }
-func (lb *LazyBoltBucket) Get(key []byte) ([]byte, error) {
+func (lb *LazyBucket) Get(key []byte) ([]byte, error) {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return nil, err
 	}
 	return bucket.Get(key), nil
 }
-func (lb *LazyBoltBucket) NextSequence() (uint64, error) {
-	bucket, err := lb.bucket()
-	if err != nil {
-		return 0, err
-	}
-	return bucket.NextSequence()
-}
-func (lb *LazyBoltBucket) Put(key []byte, value []byte) error {
+func (lb *LazyBucket) Put(key []byte, value []byte) error {
 	bucket, err := lb.bucket()
 	if err != nil {
 		return err
 	}
 	return bucket.Put(key, value)
 }
-func (lb *LazyBoltBucket) Root() (uint64, error) {
-	bucket, err := lb.bucket()
-	if err != nil {
-		return 0, err
-	}
-	return uint64(bucket.Root()), nil
-}
-func (lb *LazyBoltBucket) Sequence() (uint64, error) {
-	bucket, err := lb.bucket()
-	if err != nil {
-		return 0, err
-	}
-	return bucket.Sequence(), nil
-}
-func (lb *LazyBoltBucket) SetSequence(v uint64) error {
-	bucket, err := lb.bucket()
-	if err != nil {
-		return nil
-	}
-	return bucket.SetSequence(v)
-}
-func (lb *LazyBoltBucket) Stats() (bolt.BucketStats, error) {
-	bucket, err := lb.bucket()
-	if err != nil {
-		return bolt.BucketStats{}, err
-	}
-	return bucket.Stats(), nil
-}
-func (lb *LazyBoltBucket) Tx() (*bolt.Tx, error) {
-	bucket, err := lb.bucket()
-	if err != nil {
-		return nil, err
-	}
-	return bucket.Tx(), nil
-}
-func (lb *LazyBoltBucket) Writable() (bool, error) {
-	bucket, err := lb.bucket()
-	if err != nil {
-		return false, err
-	}
-	return bucket.Writable(), nil
-}
 
-func (lb *LazyBoltBucket) bucket() (bucket *bolt.Bucket, err error) {
+func (lb *LazyBucket) bucket() (bucket kv.Bucket, err error) {
 	lb.once.Do(func() {
 		lb.createdBucket, err = lb.getter()
 	})
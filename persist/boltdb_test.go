@@ -10,6 +10,7 @@ import (
 	"github.com/NebulousLabs/fastrand"
 	bolt "github.com/rivine/bbolt"
 	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/persist/kv"
 )
 
 // testInputs and testFilenames are global variables because most tests require
@@ -113,7 +114,7 @@ func TestOpenDatabase(t *testing.T) {
 			continue
 		}
 		// Create buckets in the database.
-		err = db.Update(func(tx *bolt.Tx) error {
+		err = db.Update(func(tx kv.Tx) error {
 			for _, testBucket := range testBuckets {
 				_, err := tx.CreateBucketIfNotExists(testBucket)
 				if err != nil {
@@ -129,7 +130,7 @@ func TestOpenDatabase(t *testing.T) {
 		}
 		// Make sure CreateBucketIfNotExists method handles invalid (nil)
 		// bucket name.
-		err = db.Update(func(tx *bolt.Tx) error {
+		err = db.Update(func(tx kv.Tx) error {
 			_, err := tx.CreateBucketIfNotExists(nil)
 			return err
 		})
@@ -140,7 +141,7 @@ func TestOpenDatabase(t *testing.T) {
 		// Fill each bucket with a random number (0-9, inclusive) of key/value
 		// pairs, where each key is a length-10 random byteslice and each value
 		// is a length-1000 random byteslice.
-		err = db.Update(func(tx *bolt.Tx) error {
+		err = db.Update(func(tx kv.Tx) error {
 			for _, testBucket := range testBuckets {
 				b := tx.Bucket(testBucket)
 				x := fastrand.Intn(10)
@@ -171,7 +172,7 @@ func TestOpenDatabase(t *testing.T) {
 			continue
 		}
 		// Empty every bucket in the database.
-		err = db.Update(func(tx *bolt.Tx) error {
+		err = db.Update(func(tx kv.Tx) error {
 			for _, testBucket := range testBuckets {
 				b := tx.Bucket(testBucket)
 				err := b.ForEach(func(k, v []byte) error {
@@ -262,13 +263,13 @@ func TestErrTxNotWritable(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		boltDB := &BoltDatabase{
+		boltDB := &Database{
 			Metadata: in.md,
-			DB:       db,
+			DB:       kv.WrapBolt(db),
 		}
 		// Should return an error because updateMetadata is being called from
 		// a read-only transaction.
-		err = db.View(boltDB.updateMetadata)
+		err = boltDB.View(boltDB.updateMetadata)
 		if err != bolt.ErrTxNotWritable {
 			t.Errorf("updateMetadata returned wrong error for input %v, filename %v; expected tx not writable, got %v", in.md, dbFilename, err)
 		}
@@ -284,7 +285,7 @@ func TestErrTxNotWritable(t *testing.T) {
 }
 
 // TestErrDatabaseNotOpen tests that checkMetadata returns an error when called
-// on a BoltDatabase that is closed.
+// on a Database that is closed.
 func TestErrDatabaseNotOpen(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
@@ -300,9 +301,9 @@ func TestErrDatabaseNotOpen(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	boltDB := &BoltDatabase{
+	boltDB := &Database{
 		Metadata: md,
-		DB:       db,
+		DB:       kv.WrapBolt(db),
 	}
 	err = boltDB.Close()
 	if err != nil {
@@ -320,7 +321,7 @@ func TestErrDatabaseNotOpen(t *testing.T) {
 }
 
 // TestErrCheckMetadata tests that checkMetadata returns an error when called
-// on a BoltDatabase whose metadata has been changed.
+// on a Database whose metadata has been changed.
 func TestErrCheckMetadata(t *testing.T) {
 	if testing.Short() {
 		t.SkipNow()
@@ -337,11 +338,11 @@ func TestErrCheckMetadata(t *testing.T) {
 		if err != nil {
 			t.Fatal(err)
 		}
-		boltDB := &BoltDatabase{
+		boltDB := &Database{
 			Metadata: in.md,
-			DB:       db,
+			DB:       kv.WrapBolt(db),
 		}
-		err = db.Update(func(tx *bolt.Tx) error {
+		err = boltDB.Update(func(tx kv.Tx) error {
 			bucket, err := tx.CreateBucketIfNotExists([]byte("Metadata"))
 			if err != nil {
 				return err
@@ -378,7 +379,7 @@ func TestErrCheckMetadata(t *testing.T) {
 }
 
 // TestErrIntegratedCheckMetadata checks that checkMetadata returns an error
-// within OpenDatabase when OpenDatabase is called on a BoltDatabase that has
+// within OpenDatabase when OpenDatabase is called on a Database that has
 // already been set up with different metadata.
 func TestErrIntegratedCheckMetadata(t *testing.T) {
 	if testing.Short() {
@@ -0,0 +1,175 @@
+package kv
+
+import (
+	"time"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+type (
+	// boltDB implements DB using a bolt database.
+	boltDB struct {
+		db *bolt.DB
+	}
+
+	// boltTx implements Tx using a bolt transaction.
+	boltTx struct {
+		tx *bolt.Tx
+	}
+
+	// boltBucket implements Bucket using a bolt bucket.
+	boltBucket struct {
+		bucket *bolt.Bucket
+	}
+)
+
+// OpenBolt opens (or creates) a bolt database file.
+func OpenBolt(filename string) (DB, error) {
+	// Open the database using a 3 second timeout (without the timeout,
+	// database will potentially hang indefinitely.
+	db, err := bolt.Open(filename, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return WrapBolt(db), nil
+}
+
+// WrapBolt wraps an opened bolt database, such that it implements DB.
+func WrapBolt(db *bolt.DB) DB {
+	return &boltDB{db: db}
+}
+
+// Bolt returns the underlying bolt database of a DB opened using the bolt backend,
+// or nil in case the DB is backed by a different storage backend.
+func Bolt(db DB) *bolt.DB {
+	if bdb, ok := db.(*boltDB); ok {
+		return bdb.db
+	}
+	return nil
+}
+
+// WrapBoltTx wraps a bolt transaction, such that it implements Tx.
+func WrapBoltTx(tx *bolt.Tx) Tx {
+	return boltTx{tx: tx}
+}
+
+// WrapBoltBucket wraps a bolt bucket, such that it implements Bucket.
+// A nil bucket is returned as a nil Bucket.
+func WrapBoltBucket(bucket *bolt.Bucket) Bucket {
+	if bucket == nil {
+		return nil
+	}
+	return boltBucket{bucket: bucket}
+}
+
+// View implements DB.View
+func (db *boltDB) View(fn func(Tx) error) error {
+	return db.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx: tx})
+	})
+}
+
+// Update implements DB.Update
+func (db *boltDB) Update(fn func(Tx) error) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx: tx})
+	})
+}
+
+// Close implements DB.Close
+func (db *boltDB) Close() error {
+	return db.db.Close()
+}
+
+// Bucket implements Tx.Bucket
+func (tx boltTx) Bucket(name []byte) Bucket {
+	return WrapBoltBucket(tx.tx.Bucket(name))
+}
+
+// CreateBucket implements Tx.CreateBucket
+func (tx boltTx) CreateBucket(name []byte) (Bucket, error) {
+	bucket, err := tx.tx.CreateBucket(name)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{bucket: bucket}, nil
+}
+
+// CreateBucketIfNotExists implements Tx.CreateBucketIfNotExists
+func (tx boltTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	bucket, err := tx.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{bucket: bucket}, nil
+}
+
+// DeleteBucket implements Tx.DeleteBucket
+func (tx boltTx) DeleteBucket(name []byte) error {
+	return tx.tx.DeleteBucket(name)
+}
+
+// ForEach implements Tx.ForEach
+func (tx boltTx) ForEach(fn func(name []byte, b Bucket) error) error {
+	return tx.tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+		return fn(name, boltBucket{bucket: b})
+	})
+}
+
+// Writable implements Tx.Writable
+func (tx boltTx) Writable() bool {
+	return tx.tx.Writable()
+}
+
+// Bucket implements Bucket.Bucket
+func (b boltBucket) Bucket(name []byte) Bucket {
+	return WrapBoltBucket(b.bucket.Bucket(name))
+}
+
+// CreateBucket implements Bucket.CreateBucket
+func (b boltBucket) CreateBucket(key []byte) (Bucket, error) {
+	bucket, err := b.bucket.CreateBucket(key)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{bucket: bucket}, nil
+}
+
+// CreateBucketIfNotExists implements Bucket.CreateBucketIfNotExists
+func (b boltBucket) CreateBucketIfNotExists(key []byte) (Bucket, error) {
+	bucket, err := b.bucket.CreateBucketIfNotExists(key)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{bucket: bucket}, nil
+}
+
+// DeleteBucket implements Bucket.DeleteBucket
+func (b boltBucket) DeleteBucket(key []byte) error {
+	return b.bucket.DeleteBucket(key)
+}
+
+// Get implements Bucket.Get
+func (b boltBucket) Get(key []byte) []byte {
+	return b.bucket.Get(key)
+}
+
+// Put implements Bucket.Put
+func (b boltBucket) Put(key []byte, value []byte) error {
+	return b.bucket.Put(key, value)
+}
+
+// Delete implements Bucket.Delete
+func (b boltBucket) Delete(key []byte) error {
+	return b.bucket.Delete(key)
+}
+
+// ForEach implements Bucket.ForEach
+func (b boltBucket) ForEach(fn func(k, v []byte) error) error {
+	return b.bucket.ForEach(fn)
+}
+
+// Cursor implements Bucket.Cursor
+func (b boltBucket) Cursor() Cursor {
+	return b.bucket.Cursor()
+}
@@ -0,0 +1,171 @@
+// Package kv defines the key-value storage abstraction used by the persistent
+// modules (consensus set, explorer, transaction pool and consensus plugins),
+// such that they can be backed by different embedded databases.
+//
+// The abstraction follows the model of bolt: a database contains (nested) buckets
+// of sorted key-value pairs, which can only be accessed from within a transaction.
+// Read-only transactions are created using DB.View, read-write transactions using DB.Update.
+// A transaction is committed when the given function returns nil,
+// and rolled back as a whole when it returns an error.
+package kv
+
+import (
+	"fmt"
+	"strings"
+
+	bolt "github.com/rivine/bbolt"
+)
+
+// Errors returned by the storage backends. These are equal to the bolt errors,
+// such that errors can be compared independent from the backend used.
+var (
+	ErrDatabaseNotOpen    = bolt.ErrDatabaseNotOpen
+	ErrTxNotWritable      = bolt.ErrTxNotWritable
+	ErrBucketNotFound     = bolt.ErrBucketNotFound
+	ErrBucketExists       = bolt.ErrBucketExists
+	ErrBucketNameRequired = bolt.ErrBucketNameRequired
+	ErrKeyRequired        = bolt.ErrKeyRequired
+	ErrIncompatibleValue  = bolt.ErrIncompatibleValue
+)
+
+type (
+	// DB is a key-value database, storing its data in buckets.
+	DB interface {
+		// View executes a function within the context of a read-only transaction.
+		// Any error returned from the function is returned from View.
+		View(fn func(Tx) error) error
+		// Update executes a function within the context of a read-write transaction.
+		// The transaction is committed if the function returns nil,
+		// and rolled back if it returns an error, which is returned from Update.
+		Update(fn func(Tx) error) error
+		// Close releases all database resources.
+		Close() error
+	}
+
+	// Tx is a read-only or read-write transaction on the database,
+	// giving access to the root buckets of the database.
+	Tx interface {
+		// Bucket returns the root bucket with the given name,
+		// or nil if the bucket does not exist.
+		Bucket(name []byte) Bucket
+		// CreateBucket creates a new root bucket, returning ErrBucketExists
+		// if a bucket with the given name already exists.
+		CreateBucket(name []byte) (Bucket, error)
+		// CreateBucketIfNotExists creates a new root bucket if it doesn't already exist.
+		CreateBucketIfNotExists(name []byte) (Bucket, error)
+		// DeleteBucket deletes a root bucket, returning ErrBucketNotFound
+		// if the bucket does not exist.
+		DeleteBucket(name []byte) error
+		// ForEach executes a function for each root bucket, in sorted order.
+		ForEach(fn func(name []byte, b Bucket) error) error
+		// Writable returns whether the transaction can perform write operations.
+		Writable() bool
+	}
+
+	// Bucket is a collection of sorted key-value pairs and nested buckets.
+	//
+	// Byte slices returned by a bucket are only valid for the lifetime of the transaction,
+	// and should not be modified. Keys and values given to a bucket are
+	// owned by the bucket for the lifetime of the transaction.
+	Bucket interface {
+		// Bucket returns the nested bucket with the given name,
+		// or nil if the bucket does not exist.
+		Bucket(name []byte) Bucket
+		// CreateBucket creates a new nested bucket, returning ErrBucketExists
+		// if a bucket with the given name already exists.
+		CreateBucket(key []byte) (Bucket, error)
+		// CreateBucketIfNotExists creates a new nested bucket if it doesn't already exist.
+		CreateBucketIfNotExists(key []byte) (Bucket, error)
+		// DeleteBucket deletes a nested bucket, returning ErrBucketNotFound
+		// if the bucket does not exist.
+		DeleteBucket(key []byte) error
+
+		// Get returns the value for the given key,
+		// or nil if the key does not exist or refers to a nested bucket.
+		Get(key []byte) []byte
+		// Put sets the value for the given key, overwriting any previous value.
+		Put(key []byte, value []byte) error
+		// Delete removes the given key, which is a no-op if the key does not exist.
+		Delete(key []byte) error
+
+		// ForEach executes a function for each key-value pair in the bucket, in sorted order.
+		// The value is nil for nested buckets. The bucket should not be modified from within
+		// the function.
+		ForEach(fn func(k, v []byte) error) error
+		// Cursor creates a cursor, allowing to iterate over the bucket in sorted order.
+		Cursor() Cursor
+	}
+
+	// Cursor iterates over the key-value pairs of a bucket in sorted order.
+	// All methods return a nil key when the cursor moves past the first or last key.
+	// The value is nil for nested buckets.
+	Cursor interface {
+		// First moves the cursor to the first key of the bucket.
+		First() (key []byte, value []byte)
+		// Last moves the cursor to the last key of the bucket.
+		Last() (key []byte, value []byte)
+		// Next moves the cursor to the next key.
+		Next() (key []byte, value []byte)
+		// Prev moves the cursor to the previous key.
+		Prev() (key []byte, value []byte)
+		// Seek moves the cursor to the given key, or the next key if the given key does not exist.
+		Seek(seek []byte) (key []byte, value []byte)
+	}
+)
+
+// Backend identifies a storage backend.
+type Backend string
+
+const (
+	// BackendBolt stores the data in a bolt database,
+	// a B+tree stored in a single memory-mapped file.
+	// It is the default backend.
+	BackendBolt Backend = "bolt"
+	// BackendLevelDB stores the data in a leveldb database, a log-structured merge-tree
+	// stored as a directory of sorted table files, which are compacted in the background.
+	// Writes are sequential, making it suited for write-heavy nodes.
+	BackendLevelDB Backend = "leveldb"
+)
+
+// Backends lists all available storage backends.
+var Backends = []Backend{BackendBolt, BackendLevelDB}
+
+// ParseBackend parses a storage backend from its string representation.
+// An empty string results in the default (bolt) backend.
+func ParseBackend(str string) (Backend, error) {
+	if str == "" {
+		return BackendBolt, nil
+	}
+	for _, backend := range Backends {
+		if string(backend) == str {
+			return backend, nil
+		}
+	}
+	return "", fmt.Errorf("unknown storage backend %q, expected one of: %s", str, backendList())
+}
+
+// String implements fmt.Stringer.String
+func (backend Backend) String() string {
+	return string(backend)
+}
+
+// Open opens (or creates) the database file using the given storage backend.
+// The leveldb backend uses the given filename as the name of its database directory.
+func Open(backend Backend, filename string) (DB, error) {
+	switch backend {
+	case BackendBolt, "":
+		return OpenBolt(filename)
+	case BackendLevelDB:
+		return OpenLevelDB(filename)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q, expected one of: %s", backend, backendList())
+	}
+}
+
+func backendList() string {
+	strs := make([]string, 0, len(Backends))
+	for _, backend := range Backends {
+		strs = append(strs, string(backend))
+	}
+	return strings.Join(strs, ", ")
+}
@@ -0,0 +1,367 @@
+package kv
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/fastrand"
+	"github.com/threefoldtech/rivine/build"
+)
+
+// openTestDB opens a new database of the given backend in a fresh test directory.
+func openTestDB(t *testing.T, backend Backend) (DB, string) {
+	dir := build.TempDir("kv", t.Name(), string(backend))
+	err := os.RemoveAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(dir, "test.db")
+	db, err := Open(backend, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, filename
+}
+
+// TestBackends probes the behaviour shared by all storage backends.
+func TestBackends(t *testing.T) {
+	for _, backend := range Backends {
+		t.Run(string(backend), func(t *testing.T) {
+			testBackend(t, backend)
+		})
+	}
+}
+
+func testBackend(t *testing.T, backend Backend) {
+	db, filename := openTestDB(t, backend)
+
+	errRollback := errors.New("rollback")
+	err := db.Update(func(tx Tx) error {
+		if tx.Bucket([]byte("missing")) != nil {
+			t.Error("missing bucket should be nil")
+		}
+		if _, err := tx.CreateBucket(nil); err != ErrBucketNameRequired {
+			t.Errorf("expected %v, got: %v", ErrBucketNameRequired, err)
+		}
+		bucket, err := tx.CreateBucket([]byte("a"))
+		if err != nil {
+			return err
+		}
+		if _, err = tx.CreateBucket([]byte("a")); err != ErrBucketExists {
+			t.Errorf("expected %v, got: %v", ErrBucketExists, err)
+		}
+		for i := 9; i >= 0; i-- {
+			err = bucket.Put([]byte{byte(i)}, []byte(fmt.Sprint(i)))
+			if err != nil {
+				return err
+			}
+		}
+		nested, err := bucket.CreateBucketIfNotExists([]byte{5, 5})
+		if err != nil {
+			return err
+		}
+		err = nested.Put([]byte("key"), []byte("value"))
+		if err != nil {
+			return err
+		}
+		if err = bucket.Put([]byte{5, 5}, nil); err != ErrIncompatibleValue {
+			t.Errorf("expected %v, got: %v", ErrIncompatibleValue, err)
+		}
+		// uncommitted keys are visible within the transaction
+		var keys int
+		err = bucket.ForEach(func(k, v []byte) error {
+			keys++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if keys != 11 {
+			t.Errorf("expected 11 uncommitted keys, found %d", keys)
+		}
+		_, err = tx.CreateBucket([]byte("b"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a read-only transaction can be used while a read-write transaction is in progress,
+	// and only sees the committed data
+	err = db.Update(func(tx Tx) error {
+		_, err := tx.CreateBucket([]byte("uncommitted"))
+		if err != nil {
+			return err
+		}
+		return db.View(func(tx Tx) error {
+			if tx.Bucket([]byte("a")) == nil {
+				t.Error("committed bucket not visible")
+			}
+			if tx.Bucket([]byte("uncommitted")) != nil {
+				t.Error("uncommitted bucket visible")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx Tx) error {
+		return tx.DeleteBucket([]byte("uncommitted"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed transaction is rolled back as a whole
+	err = db.Update(func(tx Tx) error {
+		bucket := tx.Bucket([]byte("a"))
+		err := bucket.Put([]byte{1}, []byte("overwritten"))
+		if err != nil {
+			return err
+		}
+		err = bucket.Delete([]byte{2})
+		if err != nil {
+			return err
+		}
+		err = bucket.DeleteBucket([]byte{5, 5})
+		if err != nil {
+			return err
+		}
+		err = tx.DeleteBucket([]byte("b"))
+		if err != nil {
+			return err
+		}
+		_, err = tx.CreateBucket([]byte("c"))
+		if err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if err != errRollback {
+		t.Fatalf("expected %v, got: %v", errRollback, err)
+	}
+
+	check := func(db DB) {
+		err := db.View(func(tx Tx) error {
+			if err := tx.Bucket([]byte("a")).Put([]byte{1}, nil); err != ErrTxNotWritable {
+				t.Errorf("expected %v, got: %v", ErrTxNotWritable, err)
+			}
+			var names []string
+			err := tx.ForEach(func(name []byte, _ Bucket) error {
+				names = append(names, string(name))
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if fmt.Sprint(names) != "[a b]" {
+				t.Errorf("unexpected root buckets: %v", names)
+			}
+			bucket := tx.Bucket([]byte("a"))
+			if v := bucket.Get([]byte{1}); string(v) != "1" {
+				t.Errorf("unexpected value: %q", v)
+			}
+			if v := bucket.Get([]byte{5, 5}); v != nil {
+				t.Errorf("unexpected value for nested bucket: %q", v)
+			}
+			if v := bucket.Bucket([]byte{5, 5}).Get([]byte("key")); string(v) != "value" {
+				t.Errorf("unexpected nested value: %q", v)
+			}
+
+			// iterate in both directions over the sorted keys
+			var keys [][]byte
+			err = bucket.ForEach(func(k, v []byte) error {
+				keys = append(keys, k)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			expected := [][]byte{{0}, {1}, {2}, {3}, {4}, {5}, {5, 5}, {6}, {7}, {8}, {9}}
+			if fmt.Sprint(keys) != fmt.Sprint(expected) {
+				t.Errorf("unexpected keys: %v", keys)
+			}
+			cursor := bucket.Cursor()
+			idx := 0
+			for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+				if !bytes.Equal(k, expected[idx]) {
+					t.Errorf("unexpected key #%d: %v", idx, k)
+				}
+				idx++
+			}
+			idx = len(expected) - 1
+			for k, _ := cursor.Last(); k != nil; k, _ = cursor.Prev() {
+				if !bytes.Equal(k, expected[idx]) {
+					t.Errorf("unexpected key #%d: %v", idx, k)
+				}
+				idx--
+			}
+			if k, v := cursor.Seek([]byte{5, 1}); !bytes.Equal(k, []byte{5, 5}) || v != nil {
+				t.Errorf("unexpected seek result: %v %v", k, v)
+			}
+			if k, _ := cursor.Seek([]byte{10}); k != nil {
+				t.Errorf("unexpected seek result: %v", k)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	check(db)
+
+	// the data remains available after reopening the database
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.View(func(Tx) error { return nil }); err != ErrDatabaseNotOpen {
+		t.Errorf("expected %v, got: %v", ErrDatabaseNotOpen, err)
+	}
+	db, err = Open(backend, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	check(db)
+
+	// keys can be deleted while iterating over them
+	err = db.Update(func(tx Tx) error {
+		bucket := tx.Bucket([]byte("a"))
+		cursor := bucket.Cursor()
+		for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+			if v == nil {
+				continue
+			}
+			err := bucket.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+		var keys [][]byte
+		err := bucket.ForEach(func(k, _ []byte) error {
+			keys = append(keys, k)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(keys) != 1 || !bytes.Equal(keys[0], []byte{5, 5}) {
+			t.Errorf("unexpected keys: %v", keys)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestOpenWrongBackend probes that a database can't be opened using a different backend.
+func TestOpenWrongBackend(t *testing.T) {
+	db, filename := openTestDB(t, BackendBolt)
+	err := db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(BackendLevelDB, filename)
+	if err == nil {
+		t.Fatal("expected a bolt database not to be opened as a leveldb database")
+	}
+
+	db, filename = openTestDB(t, BackendLevelDB)
+	err = db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Open(BackendBolt, filename)
+	if err == nil {
+		t.Fatal("expected a leveldb database not to be opened as a bolt database")
+	}
+}
+
+// TestLevelDBDeleteBucket probes that deleting a bucket from a leveldb database
+// deletes the keys of its nested buckets as well, such that no data is left behind.
+func TestLevelDBDeleteBucket(t *testing.T) {
+	db, _ := openTestDB(t, BackendLevelDB)
+	defer db.Close()
+
+	err := db.Update(func(tx Tx) error {
+		bucket, err := tx.CreateBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 10; i++ {
+			nested, err := bucket.CreateBucket([]byte{byte(i)})
+			if err != nil {
+				return err
+			}
+			for j := 0; j < 10; j++ {
+				err = nested.Put([]byte{byte(j)}, fastrand.Bytes(16))
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// deleting a bucket within the same transaction as its nested buckets are iterated,
+	// makes the deleted bucket unusable
+	err = db.Update(func(tx Tx) error {
+		nested := tx.Bucket([]byte("bucket")).Bucket([]byte{5})
+		err := tx.DeleteBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+		if tx.Bucket([]byte("bucket")) != nil {
+			t.Error("deleted bucket is still visible")
+		}
+		if err = nested.Put([]byte{1}, nil); err != ErrBucketNotFound {
+			t.Errorf("expected %v, got: %v", ErrBucketNotFound, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the bucket sequence should remain
+	var keys int
+	iter := db.(*levelDB).db.NewIterator(nil, nil)
+	for iter.Next() {
+		if !bytes.Equal(iter.Key(), levelDBSequenceKey) {
+			keys++
+		}
+	}
+	iter.Release()
+	if keys != 0 {
+		t.Fatalf("expected all keys to be deleted, %d keys remain", keys)
+	}
+
+	// a new bucket using the same name is empty
+	err = db.Update(func(tx Tx) error {
+		bucket, err := tx.CreateBucket([]byte("bucket"))
+		if err != nil {
+			return err
+		}
+		if bucket.Bucket([]byte{5}) != nil {
+			t.Error("nested bucket of the deleted bucket is visible")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,597 @@
+package kv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/comparer"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/memdb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// The leveldb backend stores the buckets in a single flat keyspace of a leveldb database,
+// a log-structured merge-tree stored as a directory of sorted table files.
+//
+// Each bucket is identified by a unique (never reused) 8-byte ID, the root bucket having ID 0.
+// A key of a bucket is stored prefixed with the ID of that bucket,
+// while its value is prefixed by a single byte indicating whether it is a plain value,
+// or a nested bucket, in which case the ID of the nested bucket follows.
+//
+// Read-only transactions read from a leveldb snapshot, and therefore never block,
+// nor are blocked by, a read-write transaction. A read-write transaction reads from
+// a snapshot as well, buffering its own writes in memory, such that it can read them back.
+// Once committed all writes are applied as a single atomic and synced leveldb batch.
+
+const (
+	levelDBEntryValue byte = iota
+	levelDBEntryBucket
+	// levelDBEntryDeleted is only used in the writes buffered by a read-write transaction
+	levelDBEntryDeleted
+)
+
+// levelDBBucketIDSize is the size of the ID prefixing all keys of a bucket.
+const levelDBBucketIDSize = 8
+
+// levelDBSequenceKey is the key of the last bucket ID allocated.
+// It is shorter than a bucket ID, and can therefore never collide with a bucket key.
+var levelDBSequenceKey = []byte("seq")
+
+type (
+	// levelDB implements DB using a leveldb database.
+	levelDB struct {
+		db *leveldb.DB
+
+		// mu protects db, which is nil once closed,
+		// while writeMu serializes the read-write transactions
+		mu      sync.RWMutex
+		writeMu sync.Mutex
+	}
+
+	// levelDBTx implements Tx using a leveldb snapshot,
+	// and for a read-write transaction an in-memory database buffering its writes.
+	levelDBTx struct {
+		snapshot *leveldb.Snapshot
+		writes   *memdb.DB
+		root     *levelDBBucket
+		// deleted contains the IDs of the buckets deleted within this transaction
+		deleted map[uint64]struct{}
+		// iterators created within this transaction, released when it is closed
+		iterators []iterator.Iterator
+		// err is the first read error of the transaction,
+		// as buckets can't return read errors directly
+		err error
+	}
+
+	// levelDBBucket implements Bucket for the leveldb database.
+	levelDBBucket struct {
+		tx     *levelDBTx
+		id     uint64
+		prefix []byte
+	}
+
+	// levelDBCursor implements Cursor for a bucket of the leveldb database.
+	levelDBCursor struct {
+		bucket *levelDBBucket
+		// snapshotIter and writesIter are created when the cursor is first moved,
+		// writesIter remains nil for a read-only transaction
+		snapshotIter iterator.Iterator
+		writesIter   iterator.Iterator
+		// key is the full key the cursor is positioned at, nil if not positioned
+		key []byte
+	}
+)
+
+// OpenLevelDB opens (or creates) a leveldb database directory.
+func OpenLevelDB(dirname string) (DB, error) {
+	db, err := leveldb.OpenFile(dirname, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &levelDB{db: db}, nil
+}
+
+// View implements DB.View
+func (db *levelDB) View(fn func(Tx) error) error {
+	tx, err := db.begin(false)
+	if err != nil {
+		return err
+	}
+	defer tx.close()
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	return tx.err
+}
+
+// Update implements DB.Update
+func (db *levelDB) Update(fn func(Tx) error) error {
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+
+	tx, err := db.begin(true)
+	if err != nil {
+		return err
+	}
+	// a failed transaction is rolled back by simply discarding its writes
+	err = fn(tx)
+	if err == nil {
+		err = tx.err
+	}
+	if err != nil {
+		tx.close()
+		return err
+	}
+	batch := new(leveldb.Batch)
+	iter := tx.writes.NewIterator(nil)
+	for iter.Next() {
+		if value := iter.Value(); value[0] == levelDBEntryDeleted {
+			batch.Delete(iter.Key())
+		} else {
+			batch.Put(iter.Key(), value)
+		}
+	}
+	iter.Release()
+	tx.close()
+	if batch.Len() == 0 {
+		return nil
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.db == nil {
+		return ErrDatabaseNotOpen
+	}
+	return db.db.Write(batch, &opt.WriteOptions{Sync: true})
+}
+
+// Close implements DB.Close
+func (db *levelDB) Close() error {
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.db == nil {
+		return nil
+	}
+	err := db.db.Close()
+	db.db = nil
+	return err
+}
+
+// begin starts a new transaction, reading from a snapshot of the current state of the database.
+func (db *levelDB) begin(writable bool) (*levelDBTx, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	if db.db == nil {
+		return nil, ErrDatabaseNotOpen
+	}
+	snapshot, err := db.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	tx := &levelDBTx{snapshot: snapshot}
+	if writable {
+		tx.writes = memdb.New(comparer.DefaultComparer, 0)
+		tx.deleted = make(map[uint64]struct{})
+	}
+	tx.root = tx.bucket(0)
+	return tx, nil
+}
+
+// close releases the snapshot and iterators of the transaction.
+func (tx *levelDBTx) close() {
+	for _, iter := range tx.iterators {
+		iter.Release()
+	}
+	tx.iterators = nil
+	tx.snapshot.Release()
+}
+
+// get returns the encoded entry of the given (full) key, or nil if it doesn't exist.
+func (tx *levelDBTx) get(key []byte) []byte {
+	if tx.writes != nil {
+		if value, err := tx.writes.Get(key); err == nil {
+			if value[0] == levelDBEntryDeleted {
+				return nil
+			}
+			return value
+		}
+	}
+	value, err := tx.snapshot.Get(key, nil)
+	if err != nil {
+		if err != leveldb.ErrNotFound {
+			tx.setErr(err)
+		}
+		return nil
+	}
+	return value
+}
+
+// put buffers the encoded entry of the given (full) key.
+func (tx *levelDBTx) put(key, entry []byte) error {
+	return tx.writes.Put(key, entry)
+}
+
+// delete buffers the deletion of the given (full) key.
+func (tx *levelDBTx) delete(key []byte) error {
+	return tx.writes.Put(key, []byte{levelDBEntryDeleted})
+}
+
+func (tx *levelDBTx) setErr(err error) {
+	if tx.err == nil {
+		tx.err = err
+	}
+}
+
+// nextBucketID allocates a new bucket ID.
+func (tx *levelDBTx) nextBucketID() (uint64, error) {
+	var id uint64
+	if value := levelDBEntryToValue(tx.get(levelDBSequenceKey)); len(value) == 8 {
+		id = binary.BigEndian.Uint64(value)
+	}
+	id++
+	entry := make([]byte, 1+8)
+	entry[0] = levelDBEntryValue
+	binary.BigEndian.PutUint64(entry[1:], id)
+	return id, tx.put(levelDBSequenceKey, entry)
+}
+
+func (tx *levelDBTx) bucket(id uint64) *levelDBBucket {
+	prefix := make([]byte, levelDBBucketIDSize)
+	binary.BigEndian.PutUint64(prefix, id)
+	return &levelDBBucket{tx: tx, id: id, prefix: prefix}
+}
+
+// Bucket implements Tx.Bucket
+func (tx *levelDBTx) Bucket(name []byte) Bucket {
+	return tx.root.Bucket(name)
+}
+
+// CreateBucket implements Tx.CreateBucket
+func (tx *levelDBTx) CreateBucket(name []byte) (Bucket, error) {
+	return tx.root.CreateBucket(name)
+}
+
+// CreateBucketIfNotExists implements Tx.CreateBucketIfNotExists
+func (tx *levelDBTx) CreateBucketIfNotExists(name []byte) (Bucket, error) {
+	return tx.root.CreateBucketIfNotExists(name)
+}
+
+// DeleteBucket implements Tx.DeleteBucket
+func (tx *levelDBTx) DeleteBucket(name []byte) error {
+	return tx.root.DeleteBucket(name)
+}
+
+// ForEach implements Tx.ForEach
+func (tx *levelDBTx) ForEach(fn func(name []byte, b Bucket) error) error {
+	c := tx.root.cursor()
+	for k, entry := c.first(); k != nil; k, entry = c.next() {
+		if entry[0] != levelDBEntryBucket {
+			continue
+		}
+		err := fn(k, tx.bucket(binary.BigEndian.Uint64(entry[1:])))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Writable implements Tx.Writable
+func (tx *levelDBTx) Writable() bool {
+	return tx.writes != nil
+}
+
+// key returns the full key of the given key within this bucket.
+func (b *levelDBBucket) key(key []byte) []byte {
+	return append(b.prefix[:len(b.prefix):len(b.prefix)], key...)
+}
+
+// Bucket implements Bucket.Bucket
+func (b *levelDBBucket) Bucket(name []byte) Bucket {
+	entry := b.tx.get(b.key(name))
+	if len(entry) != 1+levelDBBucketIDSize || entry[0] != levelDBEntryBucket {
+		return nil
+	}
+	return b.tx.bucket(binary.BigEndian.Uint64(entry[1:]))
+}
+
+// CreateBucket implements Bucket.CreateBucket
+func (b *levelDBBucket) CreateBucket(key []byte) (Bucket, error) {
+	err := b.checkWritable()
+	if err != nil {
+		return nil, err
+	}
+	if len(key) == 0 {
+		return nil, ErrBucketNameRequired
+	}
+	if entry := b.tx.get(b.key(key)); entry != nil {
+		if entry[0] == levelDBEntryBucket {
+			return nil, ErrBucketExists
+		}
+		return nil, ErrIncompatibleValue
+	}
+	id, err := b.tx.nextBucketID()
+	if err != nil {
+		return nil, err
+	}
+	entry := make([]byte, 1+levelDBBucketIDSize)
+	entry[0] = levelDBEntryBucket
+	binary.BigEndian.PutUint64(entry[1:], id)
+	err = b.tx.put(b.key(key), entry)
+	if err != nil {
+		return nil, err
+	}
+	return b.tx.bucket(id), nil
+}
+
+// CreateBucketIfNotExists implements Bucket.CreateBucketIfNotExists
+func (b *levelDBBucket) CreateBucketIfNotExists(key []byte) (Bucket, error) {
+	if child := b.Bucket(key); child != nil {
+		err := b.checkWritable()
+		if err != nil {
+			return nil, err
+		}
+		return child, nil
+	}
+	return b.CreateBucket(key)
+}
+
+// DeleteBucket implements Bucket.DeleteBucket
+func (b *levelDBBucket) DeleteBucket(key []byte) error {
+	err := b.checkWritable()
+	if err != nil {
+		return err
+	}
+	entry := b.tx.get(b.key(key))
+	if entry == nil {
+		return ErrBucketNotFound
+	}
+	if entry[0] != levelDBEntryBucket {
+		return ErrIncompatibleValue
+	}
+	err = b.tx.bucket(binary.BigEndian.Uint64(entry[1:])).deleteAll()
+	if err != nil {
+		return err
+	}
+	return b.tx.delete(b.key(key))
+}
+
+// deleteAll deletes all keys of the bucket, including its nested buckets,
+// and marks the bucket (and its nested buckets) as deleted.
+func (b *levelDBBucket) deleteAll() error {
+	var keys [][]byte
+	c := b.cursor()
+	for k, entry := c.first(); k != nil; k, entry = c.next() {
+		if entry[0] == levelDBEntryBucket {
+			err := b.tx.bucket(binary.BigEndian.Uint64(entry[1:])).deleteAll()
+			if err != nil {
+				return err
+			}
+		}
+		keys = append(keys, c.key)
+	}
+	for _, key := range keys {
+		err := b.tx.delete(key)
+		if err != nil {
+			return err
+		}
+	}
+	b.tx.deleted[b.id] = struct{}{}
+	return nil
+}
+
+// Get implements Bucket.Get
+func (b *levelDBBucket) Get(key []byte) []byte {
+	return levelDBEntryToValue(b.tx.get(b.key(key)))
+}
+
+// Put implements Bucket.Put
+func (b *levelDBBucket) Put(key []byte, value []byte) error {
+	err := b.checkWritable()
+	if err != nil {
+		return err
+	}
+	if len(key) == 0 {
+		return ErrKeyRequired
+	}
+	if entry := b.tx.get(b.key(key)); entry != nil && entry[0] == levelDBEntryBucket {
+		return ErrIncompatibleValue
+	}
+	entry := make([]byte, 1+len(value))
+	entry[0] = levelDBEntryValue
+	copy(entry[1:], value)
+	return b.tx.put(b.key(key), entry)
+}
+
+// Delete implements Bucket.Delete
+func (b *levelDBBucket) Delete(key []byte) error {
+	err := b.checkWritable()
+	if err != nil {
+		return err
+	}
+	entry := b.tx.get(b.key(key))
+	if entry == nil {
+		return nil
+	}
+	if entry[0] == levelDBEntryBucket {
+		return ErrIncompatibleValue
+	}
+	return b.tx.delete(b.key(key))
+}
+
+// ForEach implements Bucket.ForEach
+func (b *levelDBBucket) ForEach(fn func(k, v []byte) error) error {
+	c := b.cursor()
+	for k, entry := c.first(); k != nil; k, entry = c.next() {
+		err := fn(k, levelDBEntryToValue(entry))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cursor implements Bucket.Cursor
+func (b *levelDBBucket) Cursor() Cursor {
+	return b.cursor()
+}
+
+func (b *levelDBBucket) cursor() *levelDBCursor {
+	return &levelDBCursor{bucket: b}
+}
+
+// checkWritable returns an error if the bucket can't be modified.
+func (b *levelDBBucket) checkWritable() error {
+	if b.tx.writes == nil {
+		return ErrTxNotWritable
+	}
+	if _, ok := b.tx.deleted[b.id]; ok {
+		return ErrBucketNotFound
+	}
+	return nil
+}
+
+// levelDBEntryToValue returns the value of an encoded entry,
+// which is nil for a nested bucket.
+func levelDBEntryToValue(entry []byte) []byte {
+	if len(entry) == 0 || entry[0] != levelDBEntryValue {
+		return nil
+	}
+	return entry[1:]
+}
+
+// First implements Cursor.First
+func (c *levelDBCursor) First() ([]byte, []byte) {
+	k, entry := c.first()
+	return k, levelDBEntryToValue(entry)
+}
+
+// Last implements Cursor.Last
+func (c *levelDBCursor) Last() ([]byte, []byte) {
+	k, entry := c.move(nil, false)
+	return k, levelDBEntryToValue(entry)
+}
+
+// Next implements Cursor.Next
+func (c *levelDBCursor) Next() ([]byte, []byte) {
+	k, entry := c.next()
+	return k, levelDBEntryToValue(entry)
+}
+
+// Prev implements Cursor.Prev
+func (c *levelDBCursor) Prev() ([]byte, []byte) {
+	if c.key == nil {
+		return nil, nil
+	}
+	k, entry := c.move(c.key, false)
+	return k, levelDBEntryToValue(entry)
+}
+
+// Seek implements Cursor.Seek
+func (c *levelDBCursor) Seek(seek []byte) ([]byte, []byte) {
+	k, entry := c.move(c.bucket.key(seek), true)
+	return k, levelDBEntryToValue(entry)
+}
+
+func (c *levelDBCursor) first() ([]byte, []byte) {
+	return c.move(nil, true)
+}
+
+func (c *levelDBCursor) next() ([]byte, []byte) {
+	if c.key == nil {
+		return nil, nil
+	}
+	// the smallest key greater than the current key
+	return c.move(append(c.key[:len(c.key):len(c.key)], 0), true)
+}
+
+// move positions the cursor at the first key greater than or equal to the given full key when moving forward,
+// or the last key less than the given full key when moving backward, and returns the key and encoded entry.
+// A nil key positions the cursor at the first or last key of the bucket.
+//
+// The iterators are positioned again for each move, such that the cursor
+// remains valid while the bucket is modified within the same transaction.
+func (c *levelDBCursor) move(key []byte, forward bool) ([]byte, []byte) {
+	tx := c.bucket.tx
+	if c.snapshotIter == nil {
+		slice := util.BytesPrefix(c.bucket.prefix)
+		c.snapshotIter = tx.snapshot.NewIterator(slice, nil)
+		tx.iterators = append(tx.iterators, c.snapshotIter)
+		if tx.writes != nil {
+			c.writesIter = tx.writes.NewIterator(slice)
+			tx.iterators = append(tx.iterators, c.writesIter)
+		}
+	}
+	snapshotValid := levelDBPositionIterator(c.snapshotIter, key, forward)
+	writesValid := c.writesIter != nil && levelDBPositionIterator(c.writesIter, key, forward)
+	for snapshotValid || writesValid {
+		// pick the next key of both iterators, the buffered writes taking precedence
+		iter := c.snapshotIter
+		if writesValid {
+			cmp := 1
+			if snapshotValid {
+				cmp = bytes.Compare(c.snapshotIter.Key(), c.writesIter.Key())
+				if !forward {
+					cmp = -cmp
+				}
+			}
+			if cmp >= 0 {
+				if cmp == 0 {
+					snapshotValid = levelDBStepIterator(c.snapshotIter, forward)
+				}
+				iter = c.writesIter
+			}
+		}
+		if entry := iter.Value(); len(entry) > 0 && entry[0] != levelDBEntryDeleted {
+			c.key = cloneBytes(iter.Key())
+			return c.key[len(c.bucket.prefix):], cloneBytes(entry)
+		}
+		if iter == c.writesIter {
+			writesValid = levelDBStepIterator(c.writesIter, forward)
+		} else {
+			snapshotValid = levelDBStepIterator(c.snapshotIter, forward)
+		}
+	}
+	for _, iter := range []iterator.Iterator{c.snapshotIter, c.writesIter} {
+		if iter != nil && iter.Error() != nil {
+			tx.setErr(iter.Error())
+		}
+	}
+	c.key = nil
+	return nil, nil
+}
+
+// levelDBPositionIterator positions the iterator at the first key greater than or equal to the given key,
+// when moving forward, or the last key less than the given key, when moving backward.
+func levelDBPositionIterator(iter iterator.Iterator, key []byte, forward bool) bool {
+	if key == nil {
+		if forward {
+			return iter.First()
+		}
+		return iter.Last()
+	}
+	if forward {
+		return iter.Seek(key)
+	}
+	if iter.Seek(key) {
+		return iter.Prev()
+	}
+	return iter.Last()
+}
+
+func levelDBStepIterator(iter iterator.Iterator, forward bool) bool {
+	if forward {
+		return iter.Next()
+	}
+	return iter.Prev()
+}
+
+// cloneBytes returns a copy of b, which is never nil.
+func cloneBytes(b []byte) []byte {
+	return append(make([]byte, 0, len(b)), b...)
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
@@ -63,6 +65,13 @@ type (
 		Height       types.BlockHeight `json:"height"`
 		CurrentBlock types.BlockID     `json:"currentblock"`
 		Target       types.Target      `json:"target"`
+		// SyncProgress is only defined while the consensus set is not yet synced
+		SyncProgress *modules.ConsensusSyncProgress `json:"syncprogress,omitempty"`
+		// PrunedHeight is only defined if blocks prior to it are not available,
+		// as they have been pruned or the consensus set was bootstrapped from a snapshot
+		PrunedHeight types.BlockHeight `json:"prunedheight,omitempty"`
+		// RefusedForks lists the most recent forks the consensus set refused to switch to
+		RefusedForks []modules.RefusedFork `json:"refusedforks,omitempty"`
 	}
 
 	// ConsensusGetTransaction is the object returned by a GET request to
@@ -72,6 +81,16 @@ type (
 		TxShortID types.TransactionShortID `json:"shortid,omitempty"`
 	}
 
+	// ConsensusGetTransactionProof is the object returned by a GET request to
+	// /consensus/proofs/transactions/:id
+	ConsensusGetTransactionProof struct {
+		Transaction types.Transaction            `json:"transaction"`
+		TxShortID   types.TransactionShortID     `json:"shortid"`
+		BlockID     types.BlockID                `json:"blockid"`
+		BlockHeight types.BlockHeight            `json:"blockheight"`
+		Proof       types.TransactionMerkleProof `json:"proof"`
+	}
+
 	// ConsensusGetUnspentCoinOutput is the object returned by a GET request to
 	// /consensus/unspent/coinoutput/:id
 	ConsensusGetUnspentCoinOutput struct {
@@ -83,6 +102,12 @@ type (
 	ConsensusGetUnspentBlockstakeOutput struct {
 		Output types.BlockStakeOutput `json:"output"`
 	}
+
+	// ConsensusPostSnapshot is the object returned by a POST request to
+	// /consensus/snapshot
+	ConsensusPostSnapshot struct {
+		modules.ConsensusSnapshot
+	}
 )
 
 // RegisterConsensusHTTPHandlers registers the default Rivine handlers for all default Rivine Consensus HTTP endpoints.
@@ -96,21 +121,43 @@ func RegisterConsensusHTTPHandlers(router Router, cs modules.ConsensusSet) {
 
 	router.GET("/consensus", NewConsensusRootHandler(cs))
 	router.GET("/consensus/transactions/:id", NewConsensusGetTransactionHandler(cs))
+	router.GET("/consensus/proofs/transactions/:id", NewConsensusGetTransactionProofHandler(cs))
 	router.GET("/consensus/unspent/coinoutputs/:id", NewConsensusGetUnspentCoinOutputHandler(cs))
 	router.GET("/consensus/unspent/blockstakeoutputs/:id", NewConsensusGetUnspentBlockstakeOutputHandler(cs))
 }
 
+// RegisterConsensusSnapshotHTTPHandlers registers the default Rivine handlers for the Consensus snapshot HTTP endpoints.
+// These are optional and registered separately from the default Rivine Consensus HTTP endpoints,
+// as the export of a snapshot writes to the file system of the daemon and requires the API password.
+func RegisterConsensusSnapshotHTTPHandlers(router Router, cs modules.ConsensusSet, requiredPassword string) {
+	if cs == nil {
+		build.Critical("no consensus module given")
+	}
+	if router == nil {
+		build.Critical("no httprouter Router given")
+	}
+
+	router.POST("/consensus/snapshot", RequirePasswordHandler(NewConsensusPostSnapshotHandler(cs), requiredPassword))
+}
+
 // NewConsensusRootHandler creates a handler to handle the API calls to /consensus.
 func NewConsensusRootHandler(cs modules.ConsensusSet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 		cbid := cs.CurrentBlock().ID()
 		currentTarget, _ := cs.ChildTarget(cbid)
-		WriteJSON(w, ConsensusGET{
+		cg := ConsensusGET{
 			Synced:       cs.Synced(),
 			Height:       cs.Height(),
 			CurrentBlock: cbid,
 			Target:       currentTarget,
-		})
+			PrunedHeight: cs.PrunedHeight(),
+			RefusedForks: cs.RefusedForks(),
+		}
+		if !cg.Synced {
+			progress := cs.SyncProgress()
+			cg.SyncProgress = &progress
+		}
+		WriteJSON(w, cg)
 	}
 }
 
@@ -147,6 +194,56 @@ func NewConsensusGetTransactionHandler(cs modules.ConsensusSet) httprouter.Handl
 	}
 }
 
+// NewConsensusGetTransactionProofHandler creates a handler to handle the creation of a merkle proof,
+// proving that a transaction, identified by its long ID, is part of a block within the blockchain.
+func NewConsensusGetTransactionProofHandler(cs modules.ConsensusSet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		var (
+			txID types.TransactionID
+			id   = ps.ByName("id")
+		)
+
+		if len(id) != len(txID)*2 {
+			WriteError(w, Error{ErrInvalidIDLength.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		txn, txShortID, err := GetTransactionByLongID(cs, id)
+		if err != nil {
+			if err == ErrNotFound {
+				WriteError(w, Error{err.Error()}, http.StatusNoContent)
+				return
+			}
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+
+		height := txShortID.BlockHeight()
+		block, found := cs.BlockAtHeight(height)
+		if !found && height < cs.PrunedHeight() {
+			WriteError(w, Error{fmt.Sprintf("block at height %d: %v", height, modules.ErrBlockPruned)}, http.StatusNotFound)
+			return
+		}
+		if !found {
+			WriteError(w, Error{fmt.Sprintf("block at height %d could not be found", height)}, http.StatusInternalServerError)
+			return
+		}
+		proof, err := block.TransactionMerkleProof(int(txShortID.TransactionSequenceIndex()))
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusInternalServerError)
+			return
+		}
+
+		WriteJSON(w, ConsensusGetTransactionProof{
+			Transaction: txn,
+			TxShortID:   txShortID,
+			BlockID:     proof.BlockHeader.ID(),
+			BlockHeight: height,
+			Proof:       proof,
+		})
+	}
+}
+
 // NewConsensusGetUnspentCoinOutputHandler creates a handler to handle lookups of unspent coin outputs.
 func NewConsensusGetUnspentCoinOutputHandler(cs modules.ConsensusSet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
@@ -202,3 +299,35 @@ func NewConsensusGetUnspentBlockstakeOutputHandler(cs modules.ConsensusSet) http
 		WriteJSON(w, ConsensusGetUnspentBlockstakeOutput{Output: output})
 	}
 }
+
+// NewConsensusPostSnapshotHandler creates a handler to handle the export of a consensus snapshot,
+// at the current block height, to an (absolute) destination path on the machine of the daemon.
+func NewConsensusPostSnapshotHandler(cs modules.ConsensusSet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		destination := req.FormValue("destination")
+		// Check that the destination is absolute.
+		if !filepath.IsAbs(destination) {
+			WriteError(w, Error{"error when calling /consensus/snapshot: destination must be an absolute path"}, http.StatusBadRequest)
+			return
+		}
+
+		file, err := os.OpenFile(destination, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			WriteError(w, Error{"error when calling /consensus/snapshot: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		snapshot, err := cs.ExportSnapshot(file)
+		if err == nil {
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(destination)
+			WriteError(w, Error{"error after call to /consensus/snapshot: " + err.Error()}, http.StatusInternalServerError)
+			return
+		}
+		WriteJSON(w, ConsensusPostSnapshot{ConsensusSnapshot: snapshot})
+	}
+}
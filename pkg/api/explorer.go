@@ -54,6 +54,9 @@ type (
 		BlockStakeOutputIDs          []types.BlockStakeOutputID `json:"blockstakeoutputids"`
 		BlockStakeOutputUnlockHashes []types.UnlockHash         `json:"blockstakeunlockhashes"`
 
+		// the assets held by the coin outputs, only defined if at least one coin output holds an asset
+		CoinOutputAssetIDs []types.AssetID `json:"coinoutputassetids,omitempty"`
+
 		Unconfirmed bool `json:"unconfirmed"`
 	}
 
@@ -119,16 +122,23 @@ func buildExplorerTransactionWithMappedCoinOutputs(explorer modules.Explorer, he
 		if !ok {
 			build.Severe("could not find corresponding coin output")
 		}
-		et.CoinInputOutputs = append(et.CoinInputOutputs, ExplorerCoinOutput{
+		eco := ExplorerCoinOutput{
 			CoinOutput: sco,
 			UnlockHash: sco.Condition.UnlockHash(),
-		})
+		}
+		if assetID := explorer.CoinOutputAsset(sci.ParentID); !assetID.IsNative() {
+			eco.AssetID = &assetID
+		}
+		et.CoinInputOutputs = append(et.CoinInputOutputs, eco)
 	}
 
 	for i, co := range txn.CoinOutputs {
 		et.CoinOutputIDs = append(et.CoinOutputIDs, txn.CoinOutputID(uint64(i)))
 		et.CoinOutputUnlockHashes = append(et.CoinOutputUnlockHashes, co.Condition.UnlockHash())
 	}
+	if exData, err := txn.CommonExtensionData(); err == nil && len(exData.CoinOutputAssets) > 0 {
+		et.CoinOutputAssetIDs = exData.CoinOutputAssets
+	}
 
 	// Add the siafund outputs that correspond to each siacoin input.
 	for _, sci := range txn.BlockStakeInputs {
@@ -216,10 +226,12 @@ func BuildTransactionSet(explorer modules.Explorer, txids []types.TransactionID,
 
 type (
 	// ExplorerCoinOutput is the same a regular types.CoinOutput,
-	// but with the addition of the pre-computed UnlockHash of its condition.
+	// but with the addition of the pre-computed UnlockHash of its condition,
+	// and the ID of the asset it holds, should it not hold the native coin.
 	ExplorerCoinOutput struct {
 		types.CoinOutput
 		UnlockHash types.UnlockHash `json:"unlockhash"`
+		AssetID    *types.AssetID   `json:"assetid,omitempty"`
 	}
 
 	// ExplorerBlockStakeOutput is the same a regular types.BlockStakeOutput,
@@ -294,6 +306,10 @@ func NewExplorerBlocksHandler(cs modules.ConsensusSet, explorer modules.Explorer
 
 		// Fetch and return the explorer block.
 		block, exists := cs.BlockAtHeight(height)
+		if !exists && height < cs.PrunedHeight() {
+			WriteError(w, Error{fmt.Sprintf("block at height %d: %v", height, modules.ErrBlockPruned)}, http.StatusNotFound)
+			return
+		}
 		if !exists {
 			WriteError(w, Error{"no block found at input height in call to /explorer/block"}, http.StatusBadRequest)
 			return
@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/modules"
@@ -22,6 +23,15 @@ type (
 	TransactionPoolPOST struct {
 		TransactionID types.TransactionID `json:"transactionid"`
 	}
+
+	// TransactionPoolFeeGET contains the fields returned by a GET call to "/transactionpool/fee".
+	// It contains the estimated fee rate (per byte) and miner fee a transaction has to pay,
+	// in order to get confirmed within the target amount of blocks.
+	TransactionPoolFeeGET struct {
+		Target  types.BlockHeight `json:"target"`
+		FeeRate types.Currency    `json:"feerate"`
+		Fee     types.Currency    `json:"fee"`
+	}
 )
 
 // RegisterTransactionPoolHTTPHandlers registers the default Rivine handlers for all default Rivine TransactionPool HTTP endpoints.
@@ -38,6 +48,7 @@ func RegisterTransactionPoolHTTPHandlers(router Router, cs modules.ConsensusSet,
 	router.GET("/transactionpool/transactions", NewTransactionPoolGetTransactionsHandler(cs, tpool))
 	router.POST("/transactionpool/transactions", RequirePasswordHandler(NewTransactionPoolPostTransactionHandler(tpool), requiredPassword))
 	router.OPTIONS("/transactionpool/transactions", RequirePasswordHandler(NewTransactionPoolOptionsTransactionHandler(), requiredPassword))
+	router.GET("/transactionpool/fee", NewTransactionPoolGetFeeHandler(tpool))
 }
 
 // NewTransactionPoolGetTransactionsHandler creates a handler
@@ -137,6 +148,12 @@ func isUnlockHashInCondition(uh types.UnlockHash, co types.UnlockConditionProxy)
 				return true
 			}
 		}
+	case *types.WeightedMultiSignatureCondition:
+		for _, signatory := range tco.Signatories {
+			if uh == signatory.UnlockHash {
+				return true
+			}
+		}
 	case *types.NilCondition, nil:
 		return true
 	}
@@ -174,3 +191,26 @@ func transactionPoolErrorToHTTPStatus(err error) int {
 	}
 	return http.StatusBadRequest
 }
+
+// NewTransactionPoolGetFeeHandler creates a handler to handle the API call
+// to get the estimated fee a transaction has to pay in order to get confirmed
+// within an (optional) target amount of blocks.
+func NewTransactionPoolGetFeeHandler(tpool modules.TransactionPool) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		target := modules.DefaultFeeTarget
+		if str := req.URL.Query().Get("target"); str != "" {
+			n, err := strconv.ParseUint(str, 10, 64)
+			if err != nil || n == 0 {
+				WriteError(w, Error{"invalid target amount of blocks: " + str}, http.StatusBadRequest)
+				return
+			}
+			target = types.BlockHeight(n)
+		}
+		feeRate, fee := tpool.FeeEstimation(target)
+		WriteJSON(w, TransactionPoolFeeGET{
+			Target:  target,
+			FeeRate: feeRate,
+			Fee:     fee,
+		})
+	}
+}
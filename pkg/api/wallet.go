@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"path/filepath"
 	"strconv"
@@ -29,6 +30,8 @@ type (
 		BlockStakeBalance       types.Currency `json:"blockstakebalance"`
 		LockedBlockStakeBalance types.Currency `json:"lockedblockstakebalance"`
 
+		ConfirmedAssetBalances map[types.AssetID]types.Currency `json:"confirmedassetbalances,omitempty"`
+
 		MultiSigWallets []modules.MultiSigWallet `json:"multisigwallets"`
 	}
 
@@ -73,6 +76,7 @@ type (
 		Condition types.UnlockConditionProxy `json:"condition"`
 		Amount    types.Currency             `json:"amount"`
 		Data      string                     `json:"data,omitempty"`
+		ExpiresIn types.BlockHeight          `json:"expiresin,omitempty"`
 	}
 
 	// WalletTransactionPOSTResponse contains the ID of the transaction
@@ -88,6 +92,8 @@ type (
 		Data                  []byte             `json:"data,omitempty"`
 		RefundAddress         *types.UnlockHash  `json:"refundaddress,omitempty"`
 		GenerateRefundAddress bool               `json:"genrefundaddress,omitempty"`
+		ExpiresIn             types.BlockHeight  `json:"expiresin,omitempty"`
+		MinerFee              types.Currency     `json:"minerfee"`
 	}
 	// WalletCoinsPOSTResp Resp contains the ID of the transaction
 	// that was created as a result of a POST call to /wallet/coins.
@@ -102,6 +108,8 @@ type (
 		Data                  []byte                   `json:"data,omitempty"`
 		RefundAddress         *types.UnlockHash        `json:"refundaddress,omitempty"`
 		GenerateRefundAddress bool                     `json:"genrefundaddress,omitempty"`
+		ExpiresIn             types.BlockHeight        `json:"expiresin,omitempty"`
+		MinerFee              types.Currency           `json:"minerfee"`
 	}
 	// WalletBlockStakesPOSTResp Resp contains the ID of the transaction
 	// that was created as a result of a POST call to /wallet/blockstakes.
@@ -109,6 +117,24 @@ type (
 		TransactionID types.TransactionID `json:"transactionids"`
 	}
 
+	// WalletBumpFeePOSTResp contains the ID of the replacement transaction
+	// that was created as a result of a POST call to /wallet/bumpfee/:id.
+	WalletBumpFeePOSTResp struct {
+		TransactionID types.TransactionID `json:"transactionid"`
+	}
+
+	// WalletCPFPPOST is optionally given by the user, to define the fee rate (per byte)
+	// the parent and child transaction should pay together, as part of a POST call to /wallet/cpfp/:id/:index.
+	// If no fee rate is given, the fee rate estimated by the transaction pool is used.
+	WalletCPFPPOST struct {
+		FeeRate types.Currency `json:"feerate"`
+	}
+	// WalletCPFPPOSTResp contains the ID of the child transaction
+	// that was created as a result of a POST call to /wallet/cpfp/:id/:index.
+	WalletCPFPPOSTResp struct {
+		TransactionID types.TransactionID `json:"transactionid"`
+	}
+
 	// WalletSeedsGET contains the seeds used by the wallet.
 	WalletSeedsGET struct {
 		PrimarySeed        string   `json:"primaryseed"`
@@ -222,6 +248,8 @@ func RegisterWalletHTTPHandlers(router Router, wallet modules.Wallet, requiredPa
 	router.POST("/wallet/transaction", RequirePasswordHandler(NewWalletTransactionCreateHandler(wallet), requiredPassword))
 	router.POST("/wallet/coins", RequirePasswordHandler(NewWalletCoinsHandler(wallet), requiredPassword))
 	router.POST("/wallet/blockstakes", RequirePasswordHandler(NewWalletBlockStakesHandler(wallet), requiredPassword))
+	router.POST("/wallet/bumpfee/:id", RequirePasswordHandler(NewWalletBumpFeeHandler(wallet), requiredPassword))
+	router.POST("/wallet/cpfp/:id/:index", RequirePasswordHandler(NewWalletCPFPHandler(wallet), requiredPassword))
 	router.GET("/wallet/transaction/:id", NewWalletTransactionHandler(wallet))
 	router.GET("/wallet/transactions", NewWalletTransactionsHandler(wallet))
 	router.GET("/wallet/transactions/:addr", NewWalletTransactionsAddrHandler(wallet))
@@ -252,6 +280,11 @@ func NewWalletRootHandler(wallet modules.Wallet) httprouter.Handle {
 			WriteError(w, Error{"error after call to /wallet: " + err.Error()}, walletErrorToHTTPStatus(err))
 			return
 		}
+		assetBalances, err := wallet.ConfirmedAssetBalances()
+		if err != nil {
+			WriteError(w, Error{"error after call to /wallet: " + err.Error()}, walletErrorToHTTPStatus(err))
+			return
+		}
 		multiSigWallets, err := wallet.MultiSigWallets()
 		if err != nil {
 			WriteError(w, Error{"error after call to /wallet: " + err.Error()}, walletErrorToHTTPStatus(err))
@@ -270,6 +303,8 @@ func NewWalletRootHandler(wallet modules.Wallet) httprouter.Handle {
 			BlockStakeBalance:       blockstakeBal,
 			LockedBlockStakeBalance: blockstakeLockBal,
 
+			ConfirmedAssetBalances: assetBalances,
+
 			MultiSigWallets: multiSigWallets,
 		})
 	}
@@ -327,7 +362,27 @@ func NewWalletBlockStakeStatsHandler(wallet modules.Wallet) httprouter.Handle {
 // NewWalletAddressHandler creates a handler to handle API calls to /wallet/address.
 func NewWalletAddressHandler(wallet modules.Wallet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-		unlockHash, err := wallet.NextAddress()
+		var algo types.SignatureAlgoType
+		if str := req.FormValue("algorithm"); str != "" {
+			err := algo.LoadString(str)
+			if err != nil {
+				WriteError(w, Error{"error after call to /wallet/address: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+		var (
+			unlockHash types.UnlockHash
+			err        error
+		)
+		switch algo {
+		case types.SignatureAlgoNil, types.SignatureAlgoEd25519:
+			unlockHash, err = wallet.NextAddress()
+		case types.SignatureAlgoSecp256k1:
+			unlockHash, err = wallet.NextSecp256k1Address()
+		default:
+			WriteError(w, Error{"error after call to /wallet/address: unsupported signature algorithm " + algo.String()}, http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			WriteError(w, Error{"error after call to /wallet/addresses: " + err.Error()}, walletErrorToHTTPStatus(err))
 			return
@@ -544,7 +599,7 @@ func NewWalletTransactionCreateHandler(wallet modules.Wallet) httprouter.Handle
 			return
 		}
 
-		tx, err := wallet.SendCoins(body.Amount, body.Condition, []byte(body.Data))
+		tx, err := wallet.SendCoins(body.Amount, body.Condition, []byte(body.Data), body.ExpiresIn)
 		if err != nil {
 			WriteError(w, Error{"error after call to /wallet/transaction: " + err.Error()}, walletErrorToHTTPStatus(err))
 			return
@@ -563,7 +618,7 @@ func NewWalletCoinsHandler(wallet modules.Wallet) httprouter.Handle {
 			WriteError(w, Error{"error decoding the supplied coin outputs: " + err.Error()}, http.StatusBadRequest)
 			return
 		}
-		tx, err := wallet.SendOutputs(body.CoinOutputs, nil, body.Data, body.RefundAddress, !body.GenerateRefundAddress)
+		tx, err := wallet.SendOutputs(body.CoinOutputs, nil, body.Data, body.RefundAddress, !body.GenerateRefundAddress, body.ExpiresIn, body.MinerFee)
 		if err != nil {
 			WriteError(w, Error{"error after call to /wallet/coins: " + err.Error()}, walletErrorToHTTPStatus(err))
 			return
@@ -582,7 +637,7 @@ func NewWalletBlockStakesHandler(wallet modules.Wallet) httprouter.Handle {
 			WriteError(w, Error{"error decoding the supplied blockstake outputs: " + err.Error()}, http.StatusBadRequest)
 			return
 		}
-		tx, err := wallet.SendOutputs(nil, body.BlockStakeOutputs, body.Data, body.RefundAddress, !body.GenerateRefundAddress)
+		tx, err := wallet.SendOutputs(nil, body.BlockStakeOutputs, body.Data, body.RefundAddress, !body.GenerateRefundAddress, body.ExpiresIn, body.MinerFee)
 		if err != nil {
 			WriteError(w, Error{"error after call to /wallet/blockstakes: " + err.Error()}, walletErrorToHTTPStatus(err))
 			return
@@ -593,6 +648,58 @@ func NewWalletBlockStakesHandler(wallet modules.Wallet) httprouter.Handle {
 	}
 }
 
+// NewWalletBumpFeeHandler creates a handler to handle API calls to /wallet/bumpfee/:id.
+func NewWalletBumpFeeHandler(wallet modules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		// Parse the id from the url.
+		var id types.TransactionID
+		err := id.LoadString(ps.ByName("id"))
+		if err != nil {
+			WriteError(w, Error{"error after call to /wallet/bumpfee/$(id): " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		tx, err := wallet.BumpFee(id)
+		if err != nil {
+			WriteError(w, Error{"error after call to /wallet/bumpfee/$(id): " + err.Error()}, walletErrorToHTTPStatus(err))
+			return
+		}
+		WriteJSON(w, WalletBumpFeePOSTResp{
+			TransactionID: tx.ID(),
+		})
+	}
+}
+
+// NewWalletCPFPHandler creates a handler to handle API calls to /wallet/cpfp/:id/:index.
+func NewWalletCPFPHandler(wallet modules.Wallet) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		// Parse the id and output index from the url.
+		var id types.TransactionID
+		err := id.LoadString(ps.ByName("id"))
+		if err != nil {
+			WriteError(w, Error{"error after call to /wallet/cpfp/$(id)/$(index): " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		index, err := strconv.ParseUint(ps.ByName("index"), 10, 64)
+		if err != nil {
+			WriteError(w, Error{"error after call to /wallet/cpfp/$(id)/$(index): " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		var body WalletCPFPPOST
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil && err != io.EOF {
+			WriteError(w, Error{"error decoding the supplied fee rate: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		tx, err := wallet.ChildPaysForParent(id, index, body.FeeRate)
+		if err != nil {
+			WriteError(w, Error{"error after call to /wallet/cpfp/$(id)/$(index): " + err.Error()}, walletErrorToHTTPStatus(err))
+			return
+		}
+		WriteJSON(w, WalletCPFPPOSTResp{
+			TransactionID: tx.ID(),
+		})
+	}
+}
+
 // NewWalletTransactionHandler creates a handler to handle API calls to /wallet/transaction/:id.
 func NewWalletTransactionHandler(wallet modules.Wallet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
@@ -804,7 +911,8 @@ func NewWalletSignHandler(wallet modules.Wallet) httprouter.Handle {
 }
 
 // NewWalletFundCoinsHandler creates a handler to handle the API calls to /wallet/fund/coins?amount=.
-// While it might be handy for other use cases, it is needed for 3bot registration
+// While it might be handy for other use cases, it is needed for 3bot registration.
+// An optional asset query parameter can be given, in order to fund an asset rather than the native coin.
 func NewWalletFundCoinsHandler(wallet modules.Wallet) httprouter.Handle {
 	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 		q := req.URL.Query()
@@ -845,9 +953,23 @@ func NewWalletFundCoinsHandler(wallet modules.Wallet) httprouter.Handle {
 			}
 		}
 
+		// parse the optional asset ID
+		var assetID types.AssetID
+		if assetStr := q.Get("asset"); assetStr != "" {
+			err = assetID.LoadString(assetStr)
+			if err != nil {
+				WriteError(w, Error{Message: "invalid asset ID given: " + err.Error()}, http.StatusBadRequest)
+				return
+			}
+		}
+
 		// start a transaction and fund the requested amount
 		txbuilder := wallet.StartTransaction()
-		err = txbuilder.FundCoins(amount, refundAddress, !newRefundAddress)
+		if assetID.IsNative() {
+			err = txbuilder.FundCoins(amount, refundAddress, !newRefundAddress)
+		} else {
+			err = txbuilder.FundAsset(assetID, amount, refundAddress, !newRefundAddress)
+		}
 		if err != nil {
 			WriteError(w, Error{Message: "failed to fund the requested coins: " + err.Error()}, http.StatusInternalServerError)
 			return
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/pkg/api"
 	"github.com/threefoldtech/rivine/pkg/cli"
 	"github.com/threefoldtech/rivine/types"
@@ -280,12 +281,18 @@ func createAtomicSwapCmd(client *CommandLineClient) *cobra.Command {
 		time.Hour*24, "the duration of the atomic swap contract, the amount of time the initiator has to collect")
 	participateCmd.Flags().Var(cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.participateCfg.SourceUnlockHash}, "initiator",
 		"optionally define a wallet address (unlockhash) that is to be used for refunding purposes, one will be generated for you if none is given")
+	atomicSwapCmd.participateCfg.HashType = types.HashLockTypeSHA256
+	participateCmd.Flags().Var(cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.participateCfg.HashType}, "hashtype",
+		"the hash function used by the initiator to compute the secret hash, one of: sha256, blake2b, keccak256")
 
 	initiateCmd.Flags().DurationVarP(
 		&atomicSwapCmd.initiateCfg.Duration, "duration", "d",
 		time.Hour*48, "the duration of the atomic swap contract, the amount of time the participant has to collect")
 	initiateCmd.Flags().Var(cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.initiateCfg.SourceUnlockHash}, "initiator",
 		"optionally define a wallet address (unlockhash) that is to be used for refunding purposes, one will be generated for you if none is given")
+	atomicSwapCmd.initiateCfg.HashType = types.HashLockTypeSHA256
+	initiateCmd.Flags().Var(cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.initiateCfg.HashType}, "hashtype",
+		"the hash function used to compute the secret hash, one of: sha256, blake2b, keccak256")
 
 	auditCmd.Flags().Var(
 		cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.auditCfg.HashedSecret}, "secrethash",
@@ -303,6 +310,10 @@ func createAtomicSwapCmd(client *CommandLineClient) *cobra.Command {
 	extractSecretCmd.Flags().Var(
 		cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.extractSecretCfg.HashedSecret}, "secrethash",
 		"optionally validate the secret of the found atomic swap contract condition by comparing its hashed version with this secret hash")
+	atomicSwapCmd.extractSecretCfg.HashType = types.HashLockTypeSHA256
+	extractSecretCmd.Flags().Var(
+		cli.StringLoaderFlag{StringLoader: &atomicSwapCmd.extractSecretCfg.HashType}, "hashtype",
+		"the hash function used to validate the secret using the --secrethash flag, one of: sha256, blake2b, keccak256")
 
 	// return root command
 	return rootCmd
@@ -318,10 +329,12 @@ type atomicSwapCmd struct {
 	participateCfg struct {
 		Duration         time.Duration
 		SourceUnlockHash types.UnlockHash
+		HashType         types.HashLockType
 	}
 	initiateCfg struct {
 		Duration         time.Duration
 		SourceUnlockHash types.UnlockHash
+		HashType         types.HashLockType
 	}
 	auditCfg struct {
 		ReceiverAddress  types.UnlockHash
@@ -331,6 +344,7 @@ type atomicSwapCmd struct {
 	}
 	extractSecretCfg struct {
 		HashedSecret types.AtomicSwapHashedSecret
+		HashType     types.HashLockType
 	}
 }
 
@@ -340,6 +354,7 @@ type (
 	AtomicSwapOutputCreation struct {
 		Coins         types.Currency            `json:"coins"`
 		Contract      types.AtomicSwapCondition `json:"contract"`
+		HashType      types.HashLockType        `json:"hashtype"`
 		ContractID    types.UnlockHash          `json:"contractid"`
 		Secret        *types.AtomicSwapSecret   `json:"secret,omitempty"`
 		OutputID      types.CoinOutputID        `json:"outputid"`
@@ -350,6 +365,7 @@ type (
 	AtomicSwapOutputAudit struct {
 		Coins    types.Currency            `json:"coins"`
 		Contract types.AtomicSwapCondition `json:"contract"`
+		HashType types.HashLockType        `json:"hashtype"`
 	}
 	// AtomicSwapOutputExtractSecret represents the formatted output
 	// of the atomic swap extract secret command
@@ -363,6 +379,123 @@ type (
 	}
 )
 
+// atomicSwapContract defines an atomic swap contract, regardless of the condition used to define it.
+// Contracts using the SHA-256 hash function are defined using an AtomicSwapCondition,
+// while contracts using any other hash function are defined using a PolicyCondition:
+//
+//	or(and(hashlock(<hashtype>, <hashedsecret>), <receiver>), timelock(<timelock>, <sender>))
+type atomicSwapContract struct {
+	types.AtomicSwapCondition
+	HashType types.HashLockType
+}
+
+// Condition returns the unlock condition which defines this contract.
+func (contract atomicSwapContract) Condition() (types.UnlockConditionProxy, error) {
+	switch contract.HashType {
+	case types.HashLockTypeSHA256:
+		condition := contract.AtomicSwapCondition
+		return types.NewCondition(&condition), nil
+	case types.HashLockTypeBlake2b, types.HashLockTypeKeccak256:
+		return types.NewCondition(types.NewPolicyCondition(types.PolicyOperatorOr, 0,
+			types.NewPolicyCondition(types.PolicyOperatorAnd, 0,
+				&types.HashLockCondition{
+					HashType: contract.HashType,
+					Hash:     crypto.Hash(contract.HashedSecret),
+				},
+				types.NewUnlockHashCondition(contract.Receiver)),
+			types.NewTimeLockCondition(uint64(contract.TimeLock), types.NewUnlockHashCondition(contract.Sender)))), nil
+	default:
+		return types.UnlockConditionProxy{}, types.ErrUnknownHashLockType
+	}
+}
+
+// SignFulfillment creates and signs the fulfillment which spends this contract,
+// redeeming it when a secret is given, and refunding it otherwise.
+func (contract atomicSwapContract) SignFulfillment(ctx types.FulfillmentSignContext, pk types.PublicKey, secret types.AtomicSwapSecret) (types.UnlockFulfillmentProxy, error) {
+	if contract.HashType == types.HashLockTypeSHA256 {
+		fulfillment := types.NewFulfillment(&types.AtomicSwapFulfillment{
+			PublicKey: pk,
+			Secret:    secret,
+		})
+		return fulfillment, fulfillment.Sign(ctx)
+	}
+	// a policy fulfillment cannot be signed directly, only its branches can
+	signature := types.NewSingleSignatureFulfillment(pk)
+	err := signature.Sign(ctx)
+	if err != nil {
+		return types.UnlockFulfillmentProxy{}, err
+	}
+	if secret == (types.AtomicSwapSecret{}) {
+		// refund: fulfill the time locked sender branch
+		return types.NewFulfillment(&types.PolicyFulfillment{
+			Branches: []types.PolicyBranchFulfillment{
+				{Index: 1, Fulfillment: types.NewFulfillment(signature)},
+			},
+		}), nil
+	}
+	// redeem: fulfill both the hash lock and the receiver of the first branch
+	return types.NewFulfillment(&types.PolicyFulfillment{
+		Branches: []types.PolicyBranchFulfillment{
+			{Index: 0, Fulfillment: types.NewFulfillment(&types.PolicyFulfillment{
+				Branches: []types.PolicyBranchFulfillment{
+					{Index: 0, Fulfillment: types.NewFulfillment(&types.HashLockFulfillment{Preimage: secret[:]})},
+					{Index: 1, Fulfillment: types.NewFulfillment(signature)},
+				},
+			})},
+		},
+	}), nil
+}
+
+// parseAtomicSwapContract parses the atomic swap contract defined by the given condition,
+// returning an error in case the condition does not define an atomic swap contract.
+func parseAtomicSwapContract(condition types.UnlockConditionProxy) (atomicSwapContract, error) {
+	switch tc := condition.Condition.(type) {
+	case *types.AtomicSwapCondition:
+		return atomicSwapContract{AtomicSwapCondition: *tc, HashType: types.HashLockTypeSHA256}, nil
+	case *types.PolicyCondition:
+		if tc.Operator != types.PolicyOperatorOr || len(tc.Conditions) != 2 {
+			break
+		}
+		claim, ok := tc.Conditions[0].Condition.(*types.PolicyCondition)
+		if !ok || claim.Operator != types.PolicyOperatorAnd || len(claim.Conditions) != 2 {
+			break
+		}
+		hashLock, ok := claim.Conditions[0].Condition.(*types.HashLockCondition)
+		if !ok {
+			break
+		}
+		receiver, ok := claim.Conditions[1].Condition.(*types.UnlockHashCondition)
+		if !ok {
+			break
+		}
+		refund, ok := tc.Conditions[1].Condition.(*types.TimeLockCondition)
+		if !ok || refund.LockTime < types.LockTimeMinTimestampValue {
+			break
+		}
+		sender, ok := refund.Condition.(*types.UnlockHashCondition)
+		if !ok {
+			break
+		}
+		return atomicSwapContract{
+			AtomicSwapCondition: types.AtomicSwapCondition{
+				Sender:       sender.TargetUnlockHash,
+				Receiver:     receiver.TargetUnlockHash,
+				HashedSecret: types.AtomicSwapHashedSecret(hashLock.Hash),
+				TimeLock:     types.Timestamp(refund.LockTime),
+			},
+			HashType: hashLock.HashType,
+		}, nil
+	}
+	return atomicSwapContract{}, fmt.Errorf(
+		"received unexpected condition of type %T, which does not define an atomic swap contract", condition.Condition)
+}
+
+// hashAtomicSwapSecret hashes the given secret using the given hash function.
+func hashAtomicSwapSecret(hashType types.HashLockType, secret types.AtomicSwapSecret) (types.AtomicSwapHashedSecret, error) {
+	h, err := hashType.Sum(secret[:])
+	return types.AtomicSwapHashedSecret(h), err
+}
+
 func (atomicSwapCmd *atomicSwapCmd) participateCmd(participantAddress, amount, hashedSecret string) {
 	// parse hastings
 	hastings := parseCoinArg(atomicSwapCmd.cli.CreateCurrencyConvertor(), amount)
@@ -399,7 +532,8 @@ func (atomicSwapCmd *atomicSwapCmd) participateCmd(participantAddress, amount, h
 	}
 
 	// create the contract
-	atomicSwapCmd.createAtomicSwapContract(hastings, sender, receiver, hash, atomicSwapCmd.participateCfg.Duration)
+	atomicSwapCmd.createAtomicSwapContract(hastings, sender, receiver,
+		atomicSwapCmd.participateCfg.HashType, hash, atomicSwapCmd.participateCfg.Duration)
 }
 
 func (atomicSwapCmd *atomicSwapCmd) initiateCmd(participantAddress, amount string) {
@@ -429,10 +563,10 @@ func (atomicSwapCmd *atomicSwapCmd) initiateCmd(participantAddress, amount strin
 
 	// create the contract
 	atomicSwapCmd.createAtomicSwapContract(hastings, sender, receiver,
-		types.AtomicSwapHashedSecret{}, atomicSwapCmd.initiateCfg.Duration)
+		atomicSwapCmd.initiateCfg.HashType, types.AtomicSwapHashedSecret{}, atomicSwapCmd.initiateCfg.Duration)
 }
 
-func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Currency, sender, receiver types.UnlockHash, hash types.AtomicSwapHashedSecret, duration time.Duration) {
+func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Currency, sender, receiver types.UnlockHash, hashType types.HashLockType, hash types.AtomicSwapHashedSecret, duration time.Duration) {
 	if hastings.Cmp(atomicSwapCmd.cli.Config.MinimumTransactionFee) != 1 {
 		cli.DieWithExitCode(cli.ExitCodeUsage, "an atomic swap contract has to have a coin value higher than the minimum transaction fee of 1")
 	}
@@ -447,22 +581,32 @@ func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Curr
 		if err != nil {
 			cli.Die("failed to crypto-generate secret:", err)
 		}
-		hash = types.NewAtomicSwapHashedSecret(secret)
+		hash, err = hashAtomicSwapSecret(hashType, secret)
+		if err != nil {
+			cli.DieWithExitCode(cli.ExitCodeUsage, "failed to hash secret:", err)
+		}
 	}
 
 	if duration == 0 {
 		cli.DieWithExitCode(cli.ExitCodeUsage, "duration is required and has to be greater than 0")
 	}
 
-	condition := types.AtomicSwapCondition{
-		Sender:       sender,
-		Receiver:     receiver,
-		HashedSecret: hash,
-		TimeLock:     types.OffsetTimestamp(duration),
+	contract := atomicSwapContract{
+		AtomicSwapCondition: types.AtomicSwapCondition{
+			Sender:       sender,
+			Receiver:     receiver,
+			HashedSecret: hash,
+			TimeLock:     types.OffsetTimestamp(duration),
+		},
+		HashType: hashType,
+	}
+	condition, err := contract.Condition()
+	if err != nil {
+		cli.DieWithExitCode(cli.ExitCodeUsage, "failed to create atomic swap contract:", err)
 	}
 	if !atomicSwapCmd.rootCfg.YesToAll {
 		// print contract for review
-		atomicSwapCmd.printContractInfo(os.Stderr, hastings, condition, secret)
+		atomicSwapCmd.printContractInfo(os.Stderr, hastings, contract, secret)
 		// ensure user wants to continue with creating the contract as it is (aka publishing it)
 		if !askYesNoQuestion("Publish atomic swap transaction?") {
 			cli.DieWithExitCode(cli.ExitCodeCancelled, "cancelled atomic swap contract")
@@ -470,7 +614,7 @@ func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Curr
 	}
 	// publish contract
 	body, err := json.Marshal(api.WalletTransactionPOST{
-		Condition: types.NewCondition(&condition),
+		Condition: condition,
 		Amount:    hastings,
 	})
 	if err != nil {
@@ -498,8 +642,9 @@ func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Curr
 		// if encoding type is JSON, simply print all information as JSON
 		output := AtomicSwapOutputCreation{
 			Coins:         hastings,
-			Contract:      condition,
-			ContractID:    condition.UnlockHash(),
+			Contract:      contract.AtomicSwapCondition,
+			HashType:      contract.HashType,
+			ContractID:    unlockHash,
 			OutputID:      response.Transaction.CoinOutputID(uint64(coinOutputIndex)),
 			TransactionID: response.Transaction.ID(),
 		}
@@ -519,7 +664,7 @@ func (atomicSwapCmd *atomicSwapCmd) createAtomicSwapContract(hastings types.Curr
 	fmt.Println("")
 	fmt.Println("Contract Info:")
 	fmt.Println("")
-	atomicSwapCmd.printContractInfo(os.Stdout, hastings, condition, secret)
+	atomicSwapCmd.printContractInfo(os.Stdout, hastings, contract, secret)
 }
 
 func (atomicSwapCmd *atomicSwapCmd) auditCmd(cmd *cobra.Command, args []string) {
@@ -638,18 +783,18 @@ const (
 func (atomicSwapCmd *atomicSwapCmd) auditAtomicSwapContract(co types.CoinOutput, source auditSource) {
 	currencyConverter := atomicSwapCmd.cli.CreateCurrencyConvertor()
 
-	condition, ok := co.Condition.Condition.(*types.AtomicSwapCondition)
-	if !ok {
-		cli.Die(fmt.Sprintf(
-			"received unexpected condition of type %T, while type *types.AtomicSwapCondition was expected in order to be able to audit",
-			co.Condition.Condition))
+	contract, err := parseAtomicSwapContract(co.Condition)
+	if err != nil {
+		cli.Die("failed to audit atomic swap contract:", err)
 	}
+	condition := &contract.AtomicSwapCondition
 	durationLeft := time.Unix(int64(condition.TimeLock), 0).Sub(computeTimeNow())
 
 	if atomicSwapCmd.rootCfg.EncodingType == cli.EncodingTypeJSON {
 		json.NewEncoder(os.Stdout).Encode(AtomicSwapOutputAudit{
 			Coins:    co.Value,
 			Contract: *condition,
+			HashType: contract.HashType,
 		})
 	} else {
 		fmt.Printf(`Atomic Swap Contract (condition) found:
@@ -658,12 +803,12 @@ Contract value: %s
 
 Receiver's address: %s
 Sender's (contract creator) address: %s
-Secret Hash: %s
-TimeLock: %[5]d (%[5]s)
+Secret Hash: %s (%s)
+TimeLock: %[6]d (%[6]s)
 TimeLock reached in: %s
 
 `, currencyConverter.ToCoinStringWithUnit(co.Value), condition.Receiver,
-			condition.Sender, condition.HashedSecret, condition.TimeLock, durationLeft)
+			condition.Sender, condition.HashedSecret, contract.HashType, condition.TimeLock, durationLeft)
 	}
 
 	var invalidContract bool
@@ -777,19 +922,15 @@ func (atomicSwapCmd *atomicSwapCmd) extractSecretCmd(cmd *cobra.Command, args []
 			if outputIDGiven && ci.ParentID != outputID {
 				continue
 			}
-			if ft := ci.Fulfillment.FulfillmentType(); ft != types.FulfillmentTypeAtomicSwap {
+			var ok bool
+			secret, ok = extractAtomicSwapSecret(ci.Fulfillment)
+			if !ok {
 				if outputIDGiven && ci.ParentID == outputID {
 					cli.Die(fmt.Sprintf(
-						"received unexpected fulfillment type of type %d (%T)", ft, ci.Fulfillment.Fulfillment))
+						"received unexpected fulfillment type of type %d (%T)", ci.Fulfillment.FulfillmentType(), ci.Fulfillment.Fulfillment))
 				}
 				continue
 			}
-			getter, ok := ci.Fulfillment.Fulfillment.(atomicSwapSecretGetter)
-			if !ok {
-				cli.Die(fmt.Sprintf(
-					"received unexpected fulfillment type of type %T", ci.Fulfillment.Fulfillment))
-			}
-			secret = getter.AtomicSwapSecret()
 			goto secretCheck
 		}
 	}
@@ -811,19 +952,15 @@ func (atomicSwapCmd *atomicSwapCmd) extractSecretCmd(cmd *cobra.Command, args []
 		if outputIDGiven && ci.ParentID != outputID {
 			continue
 		}
-		if ft := ci.Fulfillment.FulfillmentType(); ft != types.FulfillmentTypeAtomicSwap {
+		var ok bool
+		secret, ok = extractAtomicSwapSecret(ci.Fulfillment)
+		if !ok {
 			if outputIDGiven && ci.ParentID == outputID {
 				cli.Die(fmt.Sprintf(
-					"received unexpected fulfillment type of type %d (%T)", ft, ci.Fulfillment.Fulfillment))
+					"received unexpected fulfillment type of type %d (%T)", ci.Fulfillment.FulfillmentType(), ci.Fulfillment.Fulfillment))
 			}
 			continue
 		}
-		getter, ok := ci.Fulfillment.Fulfillment.(atomicSwapSecretGetter)
-		if !ok {
-			cli.Die(fmt.Sprintf(
-				"received unexpected fulfillment type of type %T", ci.Fulfillment.Fulfillment))
-		}
-		secret = getter.AtomicSwapSecret()
 		break
 	}
 
@@ -833,7 +970,10 @@ secretCheck:
 			"failed to find a matching atomic swap contract fulfillment in transaction with LongID: ", txnID)
 	}
 	if atomicSwapCmd.extractSecretCfg.HashedSecret != (types.AtomicSwapHashedSecret{}) {
-		hs := types.NewAtomicSwapHashedSecret(secret)
+		hs, err := hashAtomicSwapSecret(atomicSwapCmd.extractSecretCfg.HashType, secret)
+		if err != nil {
+			cli.DieWithExitCode(cli.ExitCodeUsage, "failed to hash extracted secret:", err)
+		}
 		if hs != atomicSwapCmd.extractSecretCfg.HashedSecret {
 			cli.DieWithExitCode(AuditContractExitCodeInvalidContract,
 				fmt.Sprintf("found secret %s does not match expected and given secret hash %s",
@@ -858,6 +998,31 @@ type atomicSwapSecretGetter interface {
 	AtomicSwapSecret() types.AtomicSwapSecret
 }
 
+// extractAtomicSwapSecret extracts the secret from the given fulfillment,
+// which is either an atomic swap fulfillment, or a policy fulfillment
+// which fulfills a hash lock (as part of one of its branches).
+func extractAtomicSwapSecret(fulfillment types.UnlockFulfillmentProxy) (types.AtomicSwapSecret, bool) {
+	switch tf := fulfillment.Fulfillment.(type) {
+	case atomicSwapSecretGetter:
+		secret := tf.AtomicSwapSecret()
+		return secret, secret != (types.AtomicSwapSecret{})
+	case *types.HashLockFulfillment:
+		var secret types.AtomicSwapSecret
+		if len(tf.Preimage) != len(secret) {
+			return types.AtomicSwapSecret{}, false
+		}
+		copy(secret[:], tf.Preimage)
+		return secret, true
+	case *types.PolicyFulfillment:
+		for _, branch := range tf.Branches {
+			if secret, ok := extractAtomicSwapSecret(branch.Fulfillment); ok {
+				return secret, true
+			}
+		}
+	}
+	return types.AtomicSwapSecret{}, false
+}
+
 // redeem outputid secret
 func (atomicSwapCmd *atomicSwapCmd) redeemCmd(outputIDStr, secretStr string) {
 	var (
@@ -923,15 +1088,11 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 	}
 
 	// step 2: get correct spendable key from wallet
-	if ct := unspentCoinOutputResp.Output.Condition.ConditionType(); ct != types.ConditionTypeAtomicSwap {
-		cli.Die("only atomic swap conditions are supported, while referenced output is of type: ", ct)
-	}
-	condition, ok := unspentCoinOutputResp.Output.Condition.Condition.(*types.AtomicSwapCondition)
-	if !ok {
-		cli.Die(fmt.Sprintf(
-			"received unexpected condition of type %T, while type *types.AtomicSwapCondition was expected",
-			unspentCoinOutputResp.Output.Condition.Condition))
+	contract, err := parseAtomicSwapContract(unspentCoinOutputResp.Output.Condition)
+	if err != nil {
+		cli.Die("only atomic swap contracts are supported:", err)
 	}
+	condition := &contract.AtomicSwapCondition
 	var ourUH types.UnlockHash
 	if isSender {
 		ourUH = condition.Sender
@@ -955,7 +1116,7 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 	// step 3: confirm contract details with user, before continuing
 	// print contract for review
 	if !atomicSwapCmd.rootCfg.YesToAll {
-		atomicSwapCmd.printContractInfo(os.Stderr, unspentCoinOutputResp.Output.Value, *condition, secret)
+		atomicSwapCmd.printContractInfo(os.Stderr, unspentCoinOutputResp.Output.Value, contract, secret)
 		// ensure user wants to continue with redeeming the contract!
 		if !askYesNoQuestion("Publish atomic swap " + keyWord + " transaction?") {
 			cli.DieWithExitCode(cli.ExitCodeCancelled, "atomic swap "+keyWord+" transaction cancelled")
@@ -967,10 +1128,6 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 		CoinInputs: []types.CoinInput{
 			{
 				ParentID: outputID,
-				Fulfillment: types.NewFulfillment(&types.AtomicSwapFulfillment{
-					PublicKey: pk,
-					Secret:    secret,
-				}),
 			},
 		},
 		CoinOutputs: []types.CoinOutput{
@@ -983,11 +1140,11 @@ func (atomicSwapCmd *atomicSwapCmd) spendAtomicSwapContract(outputID types.CoinO
 	}
 
 	// step 5: sign transaction's only input
-	err = txn.CoinInputs[0].Fulfillment.Sign(types.FulfillmentSignContext{
+	txn.CoinInputs[0].Fulfillment, err = contract.SignFulfillment(types.FulfillmentSignContext{
 		ExtraObjects: []interface{}{uint64(0)},
 		Transaction:  txn,
 		Key:          sk,
-	})
+	}, pk, secret)
 	if err != nil {
 		cli.Die("failed to "+keyWord+" atomic swap's locked coins, couldn't sign transaction:", err)
 	}
@@ -1062,7 +1219,7 @@ func (atomicSwapCmd *atomicSwapCmd) commitTxn(txn types.Transaction) (types.Tran
 	return resp.TransactionID, err
 }
 
-func (atomicSwapCmd *atomicSwapCmd) printContractInfo(w io.Writer, hastings types.Currency, condition types.AtomicSwapCondition, secret types.AtomicSwapSecret) {
+func (atomicSwapCmd *atomicSwapCmd) printContractInfo(w io.Writer, hastings types.Currency, contract atomicSwapContract, secret types.AtomicSwapSecret) {
 	currencyConvertor := atomicSwapCmd.cli.CreateCurrencyConvertor()
 
 	var amountStr string
@@ -1077,19 +1234,23 @@ Contract value: %s`, currencyConvertor.ToCoinStringWithUnit(hastings))
 Secret: %s`, secret)
 	}
 
+	condition, err := contract.Condition()
+	if err != nil {
+		cli.Die("invalid atomic swap contract:", err)
+	}
 	cuh := condition.UnlockHash()
 
 	fmt.Fprintf(w, `Contract address: %s%s
 Receiver's address: %s
 Sender's (contract creator) address: %s
 
-SecretHash: %s%s
+SecretHash: %s (%s)%s
 
-TimeLock: %[7]d (%[7]s)
+TimeLock: %[8]d (%[8]s)
 TimeLock reached in: %s
-`, cuh, amountStr, condition.Receiver, condition.Sender, condition.HashedSecret,
-		secretStr, condition.TimeLock,
-		time.Until(time.Unix(int64(condition.TimeLock), 0)))
+`, cuh, amountStr, contract.Receiver, contract.Sender, contract.HashedSecret,
+		contract.HashType, secretStr, contract.TimeLock,
+		time.Until(time.Unix(int64(contract.TimeLock), 0)))
 }
 
 func askYesNoQuestion(str string) bool {
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/types"
+)
+
+func TestAtomicSwapContractCondition(t *testing.T) {
+	secret := types.AtomicSwapSecret{1, 2, 3}
+	for _, hashType := range []types.HashLockType{types.HashLockTypeSHA256, types.HashLockTypeBlake2b, types.HashLockTypeKeccak256} {
+		hashedSecret, err := hashAtomicSwapSecret(hashType, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contract := atomicSwapContract{
+			AtomicSwapCondition: types.AtomicSwapCondition{
+				Sender:       newTestPolicyUnlockHash(t),
+				Receiver:     newTestPolicyUnlockHash(t),
+				HashedSecret: hashedSecret,
+				TimeLock:     1700000000,
+			},
+			HashType: hashType,
+		}
+		condition, err := contract.Condition()
+		if err != nil {
+			t.Errorf("%s: failed to create contract condition: %v", hashType, err)
+			continue
+		}
+		if hashType == types.HashLockTypeSHA256 && condition.ConditionType() != types.ConditionTypeAtomicSwap {
+			t.Errorf("%s: unexpected condition type: %d", hashType, condition.ConditionType())
+		}
+		if err = condition.IsStandardCondition(types.ValidationContext{}); err != nil {
+			t.Errorf("%s: expected contract condition to be standard: %v", hashType, err)
+		}
+		parsed, err := parseAtomicSwapContract(condition)
+		if err != nil {
+			t.Errorf("%s: failed to parse contract condition: %v", hashType, err)
+			continue
+		}
+		if parsed != contract {
+			t.Errorf("%s: unexpected parsed contract: %v != %v", hashType, parsed, contract)
+		}
+	}
+
+	if _, err := (atomicSwapContract{}).Condition(); err == nil {
+		t.Error("expected an error for a contract without hash type")
+	}
+	if _, err := parseAtomicSwapContract(types.NewCondition(types.NewUnlockHashCondition(newTestPolicyUnlockHash(t)))); err == nil {
+		t.Error("expected an error when parsing an unlock hash condition as a contract")
+	}
+}
+
+func TestAtomicSwapContractSignFulfillment(t *testing.T) {
+	senderSK, senderPK := crypto.GenerateKeyPair()
+	receiverSK, receiverPK := crypto.GenerateKeyPair()
+	sender, receiver := types.Ed25519PublicKey(senderPK), types.Ed25519PublicKey(receiverPK)
+	senderUH, err := types.NewPubKeyUnlockHash(sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiverUH, err := types.NewPubKeyUnlockHash(receiver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := types.AtomicSwapSecret{4, 2}
+	txn := types.Transaction{Version: types.TransactionVersionOne, ArbitraryData: []byte("atomicswap")}
+
+	for _, hashType := range []types.HashLockType{types.HashLockTypeSHA256, types.HashLockTypeBlake2b, types.HashLockTypeKeccak256} {
+		hashedSecret, err := hashAtomicSwapSecret(hashType, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		contract := atomicSwapContract{
+			AtomicSwapCondition: types.AtomicSwapCondition{
+				Sender:       senderUH,
+				Receiver:     receiverUH,
+				HashedSecret: hashedSecret,
+				TimeLock:     1700000000,
+			},
+			HashType: hashType,
+		}
+		condition, err := contract.Condition()
+		if err != nil {
+			t.Fatal(err)
+		}
+		signCtx := types.FulfillmentSignContext{ExtraObjects: []interface{}{uint64(0)}, Transaction: txn}
+
+		// redeem, prior to the time lock
+		signCtx.Key = receiverSK
+		fulfillment, err := contract.SignFulfillment(signCtx, receiver, secret)
+		if err != nil {
+			t.Errorf("%s: failed to sign redeem fulfillment: %v", hashType, err)
+			continue
+		}
+		err = condition.Fulfill(fulfillment, types.FulfillContext{
+			ExtraObjects: []interface{}{uint64(0)},
+			BlockTime:    1600000000,
+			Transaction:  txn,
+		})
+		if err != nil {
+			t.Errorf("%s: expected redeem fulfillment to fulfill the contract: %v", hashType, err)
+		}
+		if extracted, ok := extractAtomicSwapSecret(fulfillment); !ok || extracted != secret {
+			t.Errorf("%s: failed to extract secret from redeem fulfillment: %v", hashType, extracted)
+		}
+
+		// refund, after the time lock
+		signCtx.Key = senderSK
+		fulfillment, err = contract.SignFulfillment(signCtx, sender, types.AtomicSwapSecret{})
+		if err != nil {
+			t.Errorf("%s: failed to sign refund fulfillment: %v", hashType, err)
+			continue
+		}
+		refundCtx := types.FulfillContext{
+			ExtraObjects: []interface{}{uint64(0)},
+			BlockTime:    1800000000,
+			Transaction:  txn,
+		}
+		if err = condition.Fulfill(fulfillment, refundCtx); err != nil {
+			t.Errorf("%s: expected refund fulfillment to fulfill the contract: %v", hashType, err)
+		}
+		refundCtx.BlockTime = 1600000000
+		if err = condition.Fulfill(fulfillment, refundCtx); err == nil {
+			t.Errorf("%s: expected refund fulfillment to fail prior to the time lock", hashType)
+		}
+		if _, ok := extractAtomicSwapSecret(fulfillment); ok {
+			t.Errorf("%s: expected no secret to be extracted from a refund fulfillment", hashType)
+		}
+	}
+}
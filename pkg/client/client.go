@@ -23,6 +23,7 @@ func ConfigFromDaemonConstants(constants modules.DaemonConstants) Config {
 		ChainName:    constants.ChainInfo.Name,
 		NetworkName:  constants.ChainInfo.NetworkName,
 		ChainVersion: constants.ChainInfo.ChainVersion,
+		ChainID:      constants.ChainID,
 		CurrencyUnits: types.CurrencyUnits{
 			OneCoin: constants.OneCoin,
 		},
@@ -31,6 +32,8 @@ func ConfigFromDaemonConstants(constants modules.DaemonConstants) Config {
 		DefaultTransactionVersion: constants.DefaultTransactionVersion,
 		BlockFrequencyInSeconds:   int64(constants.BlockFrequency),
 		GenesisBlockTimestamp:     constants.GenesisTimestamp,
+		AddressPrefix:             constants.ChainInfo.AddressPrefix,
+		UnlockHashEncoding:        constants.UnlockHashEncoding,
 	}
 }
 
@@ -39,6 +42,7 @@ type Config struct {
 	ChainName    string
 	NetworkName  string
 	ChainVersion build.ProtocolVersion
+	ChainID      types.ChainID
 
 	CurrencyUnits             types.CurrencyUnits
 	CurrencyCoinUnit          string
@@ -49,6 +53,11 @@ type Config struct {
 	// but only in order to estimate progress with the syncing of your consensus.
 	BlockFrequencyInSeconds int64
 	GenesisBlockTimestamp   types.Timestamp
+
+	// AddressPrefix and UnlockHashEncoding define how unlock hashes (addresses)
+	// are printed by the client, and which bech32 addresses it accepts.
+	AddressPrefix      string
+	UnlockHashEncoding types.UnlockHashEncoding
 }
 
 // Wrap wraps a generic command with a check that the command has been
@@ -123,6 +132,7 @@ type OptionalCommandLineClientCommands struct {
 	GatewayCmd    *cobra.Command
 	ExploreCmd    *cobra.Command
 	MergeCmd      *cobra.Command
+	ConditionCmd  *cobra.Command
 }
 
 // NewCommandLineClient creates a new CLI client, which can be run as it is,
@@ -202,6 +212,9 @@ func NewCommandLineClient(address, name, userAgent string, opts *OptionalCommand
 
 		client.MergeCmd = createMergeCmd(client)
 		client.RootCmd.AddCommand(client.MergeCmd)
+
+		client.ConditionCmd = createConditionCmd(client)
+		client.RootCmd.AddCommand(client.ConditionCmd)
 	} else {
 		if opts.WalletCmd == nil {
 			client.WalletCmd = createWalletCmd(client)
@@ -237,6 +250,13 @@ func NewCommandLineClient(address, name, userAgent string, opts *OptionalCommand
 			client.MergeCmd = opts.MergeCmd
 		}
 		client.RootCmd.AddCommand(client.MergeCmd)
+
+		if opts.ConditionCmd == nil {
+			client.ConditionCmd = createConditionCmd(client)
+		} else {
+			client.ConditionCmd = opts.ConditionCmd
+		}
+		client.RootCmd.AddCommand(client.ConditionCmd)
 	}
 
 	// parse flags
@@ -268,6 +288,7 @@ type CommandLineClient struct {
 	GatewayCmd    *cobra.Command
 	ExploreCmd    *cobra.Command
 	MergeCmd      *cobra.Command
+	ConditionCmd  *cobra.Command
 }
 
 // preRunE checks that all preConditions match
@@ -294,6 +315,23 @@ func (cli *CommandLineClient) preRunE(*cobra.Command, []string) error {
 	if cli.Config == nil {
 		return errors.New("cannot run command line client: no config is defined")
 	}
+	err = types.SetUnlockHashEncoding(cli.Config.UnlockHashEncoding, cli.Config.AddressPrefix)
+	if err != nil {
+		return fmt.Errorf("invalid unlock hash encoding config: %v", err)
+	}
+	if cli.Config.ChainID != (types.ChainID{}) {
+		// register the chain ID, valid-until and signature hash flags transaction versions,
+		// such that transactions of those versions can be decoded and signed
+		types.RegisterTransactionVersion(types.TransactionVersionTwo, types.ChainIDTransactionController{
+			ChainID: cli.Config.ChainID,
+		})
+		types.RegisterTransactionVersion(types.TransactionVersionThree, types.ValidUntilTransactionController{
+			ChainID: cli.Config.ChainID,
+		})
+		types.RegisterTransactionVersion(types.TransactionVersionFour, types.SigHashTransactionController{
+			ChainID: cli.Config.ChainID,
+		})
+	}
 	return nil
 }
 
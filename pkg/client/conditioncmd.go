@@ -0,0 +1,91 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/threefoldtech/rivine/pkg/cli"
+)
+
+func createConditionCmd(*CommandLineClient) *cobra.Command {
+	conditionCmd := new(conditionCmd)
+
+	// create root condition command and all subs
+	var (
+		rootCmd = &cobra.Command{
+			Use:   "condition",
+			Short: "Compile and explain unlock conditions",
+			// Run field is not set, as the condition command itself is not a valid command.
+			// A subcommand must be provided.
+		}
+		compileCmd = &cobra.Command{
+			Use:   "compile <policy>",
+			Short: "Compile a policy into a JSON-encoded unlock condition",
+			Long: `Compile a policy, written in the textual policy language, into a JSON-encoded unlock condition.
+A policy is either an address, nil, or one of the following functions,
+where each <condition> is a policy itself:
+
+  timelock(<height|timestamp>, <condition>)
+  relativetimelock(<blocks>, <condition>)
+  multisig(<minsigs>, <address>, <address>...)
+  weightedmultisig(<minweight>, <address>:<weight>, <address>:<weight>...)
+  atomicswap(<sender>, <receiver>, <hashedsecret>, <timestamp>)
+  hashlock(<sha256|blake2b|keccak256>, <hash>)
+  and(<condition>, <condition>...)
+  or(<condition>, <condition>...)
+  threshold(<k>, <condition>, <condition>...)
+
+Example: timelock(1700000000, multisig(2, addrA, addrB, addrC))
+
+A policy can be used anywhere a <rawCondition> is accepted.
+`,
+			Args: cobra.MinimumNArgs(1),
+			Run:  conditionCmd.compileCmd,
+		}
+		explainCmd = &cobra.Command{
+			Use:   "explain <rawCondition>",
+			Short: "Explain a JSON-encoded unlock condition as a policy",
+			Long: `Explain a JSON-encoded unlock condition, by decompiling it
+into the textual policy language, followed by the unlock hash of the condition.
+See the compile command for more information about the policy language.
+`,
+			Args: cobra.MinimumNArgs(1),
+			Run:  conditionCmd.explainCmd,
+		}
+	)
+	rootCmd.AddCommand(compileCmd, explainCmd)
+
+	// return root command
+	return rootCmd
+}
+
+type conditionCmd struct{}
+
+func (conditionCmd *conditionCmd) compileCmd(cmd *cobra.Command, args []string) {
+	condition, err := CompileCondition(strings.Join(args, " "))
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die("failed to compile policy:", err)
+	}
+	err = json.NewEncoder(os.Stdout).Encode(condition)
+	if err != nil {
+		cli.Die("failed to JSON-encode compiled condition:", err)
+	}
+}
+
+func (conditionCmd *conditionCmd) explainCmd(cmd *cobra.Command, args []string) {
+	condition, err := ParseConditionString(strings.Join(args, " "))
+	if err != nil {
+		cmd.UsageFunc()(cmd)
+		cli.Die("failed to parse condition:", err)
+	}
+	policy, err := ExplainCondition(condition)
+	if err != nil {
+		cli.Die("failed to explain condition:", err)
+	}
+	fmt.Println(policy)
+	fmt.Println("unlock hash:", condition.UnlockHash().String())
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -32,8 +34,27 @@ func createConsensusCmd(client *CommandLineClient) (*consensusCmd, *cobra.Comman
 			Long:  "Get an existing transaction from the blockchain, using its given shortID or longID.",
 			Run:   Wrap(consensusCmd.transactionCmd),
 		}
+		proofCmd = &cobra.Command{
+			Use:   "proof <longID>",
+			Short: "Get and verify a merkle proof for an existing transaction",
+			Long: `Get a merkle proof for an existing transaction from the blockchain, using its given longID,
+and verify that the transaction is part of the block identified by the returned block header.`,
+			Run: Wrap(consensusCmd.proofCmd),
+		}
+		snapshotCmd = &cobra.Command{
+			Use:   "snapshot <destination>",
+			Short: "Export a snapshot of the consensus state",
+			Long: `Export a snapshot of the consensus state at the current block height to the given (absolute)
+destination path, on the machine of the daemon. The printed checksum can be used as a trusted snapshot,
+such that new nodes can bootstrap their consensus set using the --bootstrap-snapshot daemon flag.
+No trusted snapshots are defined by default, the printed height and checksum have to be
+hard-coded by the chain's operators in the TrustedSnapshots of the network config.
+The checksum depends on the consensus plugins of the daemon, as their state is part of the snapshot,
+snapshots are only reproducible by daemons which run the same set of plugins.`,
+			Run: Wrap(consensusCmd.snapshotCmd),
+		}
 	)
-	rootCmd.AddCommand(transactionCmd)
+	rootCmd.AddCommand(transactionCmd, proofCmd, snapshotCmd)
 
 	// create flags
 	transactionCmd.Flags().Var(
@@ -65,6 +86,17 @@ Block:  %v
 Height: %v
 Target: %v
 `, YesNo(cg.Synced), cg.CurrentBlock, cg.Height, cg.Target)
+	} else if cg.SyncProgress != nil && cg.SyncProgress.PeerHeight > 0 {
+		progress := float64(cg.Height) / float64(cg.SyncProgress.PeerHeight) * 100
+		if progress > 99 {
+			progress = 99
+		}
+		fmt.Printf(`Synced: %v
+Height: %v
+Peer Height: %v
+Download Peers: %v
+Progress: %.2f%%
+`, YesNo(cg.Synced), cg.Height, cg.SyncProgress.PeerHeight, cg.SyncProgress.DownloadPeers, progress)
 	} else {
 		estimatedHeight := consensusCmd.estimatedHeightAt(time.Now())
 		estimatedProgress := float64(cg.Height) / float64(estimatedHeight) * 100
@@ -135,3 +167,46 @@ func (consensusCmd *consensusCmd) transactionCmd(id string) {
 		cli.Die("failed to encode transaction:", err, "; ID:", id)
 	}
 }
+
+// proofCmd is the handler for the command `rivinec consensus proof`.
+// Fetches the merkle proof for the transaction found for the given id,
+// and verifies that it proves the transaction is part of the returned block.
+func (consensusCmd *consensusCmd) proofCmd(id string) {
+	var resp api.ConsensusGetTransactionProof
+	err := consensusCmd.cli.GetWithResponse("/consensus/proofs/transactions/"+id, &resp)
+	if err != nil {
+		cli.Die("failed to get transaction proof:", err, "; ID:", id)
+	}
+	if blockID := resp.Proof.BlockHeader.ID(); blockID != resp.BlockID {
+		cli.Die(fmt.Sprintf("invalid transaction proof: block header identifies block %s instead of block %s", blockID.String(), resp.BlockID.String()))
+	}
+	err = types.VerifyTransactionMerkleProof(resp.Transaction, resp.Proof)
+	if err != nil {
+		cli.Die("invalid transaction proof:", err, "; ID:", id)
+	}
+	fmt.Printf(`Transaction %s is included in block %s
+Block height: %d
+Short ID:     %d
+`, id, resp.BlockID.String(), resp.BlockHeight, resp.TxShortID)
+}
+
+// snapshotCmd is the handler for the command `rivinec consensus snapshot`.
+// Exports a snapshot of the consensus state to the given destination on the machine of the daemon.
+func (consensusCmd *consensusCmd) snapshotCmd(destination string) {
+	destination, err := filepath.Abs(destination)
+	if err != nil {
+		cli.Die("invalid destination:", err)
+	}
+	values := url.Values{}
+	values.Set("destination", destination)
+	var resp api.ConsensusPostSnapshot
+	err = consensusCmd.cli.PostWithResponse("/consensus/snapshot", values.Encode(), &resp)
+	if err != nil {
+		cli.Die("failed to export consensus snapshot:", err)
+	}
+	fmt.Printf(`Exported consensus snapshot to %s
+Block height: %d
+Block ID:     %s
+Checksum:     %s
+`, destination, resp.BlockHeight, resp.BlockID.String(), resp.Checksum.String())
+}
@@ -0,0 +1,487 @@
+package client
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+// The policy language is a small textual language,
+// which can be used to describe an unlock condition,
+// rather than having to write the JSON encoding of that condition by hand.
+//
+// A policy is either an address (unlock hash), the keyword nil,
+// or one of the following functions, where each <condition> is a policy itself:
+//
+//	nil
+//	<address>
+//	timelock(<height|timestamp>, <condition>)
+//	relativetimelock(<blocks>, <condition>)
+//	multisig(<minsigs>, <address>, <address>...)
+//	weightedmultisig(<minweight>, <address>:<weight>, <address>:<weight>...)
+//	atomicswap(<sender>, <receiver>, <hashedsecret>, <timestamp>)
+//	hashlock(<sha256|blake2b|keccak256>, <hash>)
+//	and(<condition>, <condition>...)
+//	or(<condition>, <condition>...)
+//	threshold(<k>, <condition>, <condition>...)
+//
+// Whitespace is ignored, and function names are case insensitive.
+// Example: timelock(1700000000, multisig(2, addrA, addrB, addrC))
+
+// policy function names
+const (
+	policyNil              = "nil"
+	policyTimeLock         = "timelock"
+	policyRelativeTimeLock = "relativetimelock"
+	policyMultiSig         = "multisig"
+	policyWeightedMultiSig = "weightedmultisig"
+	policyAtomicSwap       = "atomicswap"
+	policyHashLock         = "hashlock"
+)
+
+// CompileCondition compiles a policy, written in the textual policy language,
+// into an unlock condition. The compiled condition is not validated
+// for standardness, this happens only once it is used as part of a transaction.
+func CompileCondition(policy string) (types.UnlockConditionProxy, error) {
+	tokens, err := tokenizePolicy(policy)
+	if err != nil {
+		return types.UnlockConditionProxy{}, err
+	}
+	parser := policyParser{tokens: tokens}
+	node, err := parser.parseNode()
+	if err != nil {
+		return types.UnlockConditionProxy{}, err
+	}
+	if tok := parser.peek(); tok.kind != policyTokenEOF {
+		return types.UnlockConditionProxy{}, fmt.Errorf("unexpected %s at position %d, expected end of policy", tok, tok.pos)
+	}
+	return node.compileCondition()
+}
+
+// ExplainCondition decompiles an unlock condition into
+// its textual representation in the policy language,
+// such that it can be compiled again using CompileCondition.
+func ExplainCondition(condition types.UnlockConditionProxy) (string, error) {
+	switch c := condition.Condition.(type) {
+	case nil, *types.NilCondition:
+		return policyNil, nil
+	case *types.UnlockHashCondition:
+		return c.TargetUnlockHash.String(), nil
+	case *types.TimeLockCondition:
+		inner, err := ExplainCondition(types.NewCondition(c.Condition))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%d, %s)", policyTimeLock, c.LockTime, inner), nil
+	case *types.RelativeTimeLockCondition:
+		inner, err := ExplainCondition(types.NewCondition(c.Condition))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s(%d, %s)", policyRelativeTimeLock, c.LockDuration, inner), nil
+	case *types.MultiSignatureCondition:
+		args := []string{strconv.FormatUint(c.MinimumSignatureCount, 10)}
+		for _, uh := range c.UnlockHashes {
+			args = append(args, uh.String())
+		}
+		return policyMultiSig + "(" + strings.Join(args, ", ") + ")", nil
+	case *types.WeightedMultiSignatureCondition:
+		args := []string{strconv.FormatUint(c.MinimumWeight, 10)}
+		for _, signatory := range c.Signatories {
+			args = append(args, fmt.Sprintf("%s:%d", signatory.UnlockHash.String(), signatory.Weight))
+		}
+		return policyWeightedMultiSig + "(" + strings.Join(args, ", ") + ")", nil
+	case *types.AtomicSwapCondition:
+		return fmt.Sprintf("%s(%s, %s, %s, %d)", policyAtomicSwap,
+			c.Sender.String(), c.Receiver.String(), c.HashedSecret.String(), c.TimeLock), nil
+	case *types.HashLockCondition:
+		hashType := c.HashType.String()
+		if hashType == "" {
+			return "", types.ErrUnknownHashLockType
+		}
+		return fmt.Sprintf("%s(%s, %s)", policyHashLock, hashType, c.Hash.String()), nil
+	case *types.PolicyCondition:
+		var args []string
+		if c.Operator == types.PolicyOperatorThreshold {
+			args = append(args, strconv.FormatUint(c.Threshold, 10))
+		}
+		for _, sub := range c.Conditions {
+			str, err := ExplainCondition(sub)
+			if err != nil {
+				return "", err
+			}
+			args = append(args, str)
+		}
+		op := c.Operator.String()
+		if op == "" {
+			return "", types.ErrUnknownPolicyOperator
+		}
+		return op + "(" + strings.Join(args, ", ") + ")", nil
+	default:
+		return "", fmt.Errorf("condition of type %d cannot be expressed in the policy language", condition.ConditionType())
+	}
+}
+
+// ParseConditionString parses a condition given as an address (unlock hash),
+// a JSON-encoded unlock condition or a policy written in the textual policy language.
+func ParseConditionString(str string) (types.UnlockConditionProxy, error) {
+	// try to parse it as an unlock hash
+	var uh types.UnlockHash
+	err := uh.LoadString(str)
+	if err == nil {
+		return types.NewCondition(types.NewUnlockHashCondition(uh)), nil
+	}
+
+	// try to parse it as a JSON-encoded unlock condition
+	str = strings.TrimSpace(str)
+	if strings.HasPrefix(str, "{") {
+		var condition types.UnlockConditionProxy
+		err = condition.UnmarshalJSON([]byte(str))
+		if err != nil {
+			return types.UnlockConditionProxy{}, fmt.Errorf("invalid JSON-encoded condition: %v", err)
+		}
+		return condition, nil
+	}
+
+	// try to parse it as a policy
+	condition, err := CompileCondition(str)
+	if err != nil {
+		return types.UnlockConditionProxy{}, fmt.Errorf(
+			"condition has to be an UnlockHash, JSON-encoded UnlockCondition or policy, %q is neither: %v", str, err)
+	}
+	return condition, nil
+}
+
+type policyTokenKind uint8
+
+const (
+	policyTokenEOF policyTokenKind = iota
+	policyTokenWord
+	policyTokenOpen
+	policyTokenClose
+	policyTokenComma
+)
+
+type policyToken struct {
+	kind  policyTokenKind
+	value string
+	pos   int
+}
+
+func (tok policyToken) String() string {
+	switch tok.kind {
+	case policyTokenEOF:
+		return "end of policy"
+	case policyTokenWord:
+		return fmt.Sprintf("%q", tok.value)
+	default:
+		return fmt.Sprintf("'%s'", tok.value)
+	}
+}
+
+func tokenizePolicy(policy string) ([]policyToken, error) {
+	var tokens []policyToken
+	for pos := 0; pos < len(policy); {
+		c := policy[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '(':
+			tokens = append(tokens, policyToken{kind: policyTokenOpen, value: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, policyToken{kind: policyTokenClose, value: ")", pos: pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, policyToken{kind: policyTokenComma, value: ",", pos: pos})
+			pos++
+		case isPolicyWordChar(c):
+			start := pos
+			for pos < len(policy) && isPolicyWordChar(policy[pos]) {
+				pos++
+			}
+			tokens = append(tokens, policyToken{kind: policyTokenWord, value: policy[start:pos], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, pos)
+		}
+	}
+	tokens = append(tokens, policyToken{kind: policyTokenEOF, pos: len(policy)})
+	return tokens, nil
+}
+
+func isPolicyWordChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == ':'
+}
+
+type policyParser struct {
+	tokens []policyToken
+	index  int
+}
+
+func (p *policyParser) peek() policyToken {
+	return p.tokens[p.index]
+}
+
+func (p *policyParser) next() policyToken {
+	tok := p.tokens[p.index]
+	if tok.kind != policyTokenEOF {
+		p.index++
+	}
+	return tok
+}
+
+// policyNode is a single word of a policy, optionally called as a function.
+type policyNode struct {
+	name string
+	pos  int
+	call bool
+	args []policyNode
+}
+
+func (p *policyParser) parseNode() (policyNode, error) {
+	tok := p.next()
+	if tok.kind != policyTokenWord {
+		return policyNode{}, fmt.Errorf("unexpected %s at position %d, expected a word", tok, tok.pos)
+	}
+	node := policyNode{name: tok.value, pos: tok.pos}
+	if p.peek().kind != policyTokenOpen {
+		return node, nil
+	}
+	p.next()
+	node.call = true
+	if p.peek().kind == policyTokenClose {
+		p.next()
+		return node, nil
+	}
+	for {
+		arg, err := p.parseNode()
+		if err != nil {
+			return policyNode{}, err
+		}
+		node.args = append(node.args, arg)
+		tok = p.next()
+		switch tok.kind {
+		case policyTokenComma:
+			continue
+		case policyTokenClose:
+			return node, nil
+		default:
+			return policyNode{}, fmt.Errorf("unexpected %s at position %d, expected ',' or ')'", tok, tok.pos)
+		}
+	}
+}
+
+func (node policyNode) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d: %s", node.name, node.pos, fmt.Sprintf(format, args...))
+}
+
+func (node policyNode) compileCondition() (types.UnlockConditionProxy, error) {
+	name := strings.ToLower(node.name)
+	if !node.call {
+		if name == policyNil {
+			return types.NewCondition(&types.NilCondition{}), nil
+		}
+		uh, err := node.compileUnlockHash()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		return types.NewCondition(types.NewUnlockHashCondition(uh)), nil
+	}
+
+	switch name {
+	case policyNil:
+		if len(node.args) != 0 {
+			return types.UnlockConditionProxy{}, node.errorf("expected no arguments")
+		}
+		return types.NewCondition(&types.NilCondition{}), nil
+
+	case policyTimeLock, policyRelativeTimeLock:
+		if len(node.args) != 2 {
+			return types.UnlockConditionProxy{}, node.errorf("expected 2 arguments, a lock time and a condition")
+		}
+		lockTime, err := node.args[0].compileUint64()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		if lockTime == 0 {
+			return types.UnlockConditionProxy{}, node.errorf("lock time cannot be zero")
+		}
+		inner, err := node.args[1].compileCondition()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		if name == policyTimeLock {
+			return types.NewCondition(types.NewTimeLockCondition(lockTime, inner.Condition)), nil
+		}
+		return types.NewCondition(types.NewRelativeTimeLockCondition(types.BlockHeight(lockTime), inner.Condition)), nil
+
+	case policyMultiSig:
+		if len(node.args) < 2 {
+			return types.UnlockConditionProxy{}, node.errorf("expected a minimum signature count and at least one address")
+		}
+		minSigs, err := node.args[0].compileUint64()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		uhs := make(types.UnlockHashSlice, 0, len(node.args)-1)
+		for _, arg := range node.args[1:] {
+			uh, err := arg.compileUnlockHash()
+			if err != nil {
+				return types.UnlockConditionProxy{}, err
+			}
+			uhs = append(uhs, uh)
+		}
+		return types.NewCondition(types.NewMultiSignatureCondition(uhs, minSigs)), nil
+
+	case policyWeightedMultiSig:
+		if len(node.args) < 2 {
+			return types.UnlockConditionProxy{}, node.errorf("expected a minimum weight and at least one weighted address")
+		}
+		minWeight, err := node.args[0].compileUint64()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		signatories := make([]types.WeightedUnlockHash, 0, len(node.args)-1)
+		for _, arg := range node.args[1:] {
+			signatory, err := arg.compileWeightedUnlockHash()
+			if err != nil {
+				return types.UnlockConditionProxy{}, err
+			}
+			signatories = append(signatories, signatory)
+		}
+		return types.NewCondition(types.NewWeightedMultiSignatureCondition(signatories, minWeight)), nil
+
+	case policyAtomicSwap:
+		if len(node.args) != 4 {
+			return types.UnlockConditionProxy{}, node.errorf("expected 4 arguments, a sender, receiver, hashed secret and timestamp")
+		}
+		var (
+			condition types.AtomicSwapCondition
+			err       error
+		)
+		condition.Sender, err = node.args[0].compileUnlockHash()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		condition.Receiver, err = node.args[1].compileUnlockHash()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		if err = node.args[2].expectWord(); err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		err = condition.HashedSecret.LoadString(node.args[2].name)
+		if err != nil {
+			return types.UnlockConditionProxy{}, node.args[2].errorf("invalid hashed secret: %v", err)
+		}
+		timeLock, err := node.args[3].compileUint64()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		condition.TimeLock = types.Timestamp(timeLock)
+		return types.NewCondition(&condition), nil
+
+	case policyHashLock:
+		if len(node.args) != 2 {
+			return types.UnlockConditionProxy{}, node.errorf("expected 2 arguments, a hash function and a hash")
+		}
+		var condition types.HashLockCondition
+		for _, arg := range node.args {
+			if err := arg.expectWord(); err != nil {
+				return types.UnlockConditionProxy{}, err
+			}
+		}
+		err := condition.HashType.LoadString(strings.ToLower(node.args[0].name))
+		if err != nil {
+			return types.UnlockConditionProxy{}, node.args[0].errorf("invalid hash function: %v", err)
+		}
+		err = condition.Hash.LoadString(node.args[1].name)
+		if err != nil {
+			return types.UnlockConditionProxy{}, node.args[1].errorf("invalid hash: %v", err)
+		}
+		return types.NewCondition(&condition), nil
+	}
+
+	var op types.PolicyOperator
+	if err := op.LoadString(name); err != nil {
+		return types.UnlockConditionProxy{}, node.errorf("unknown policy function")
+	}
+	args := node.args
+	condition := &types.PolicyCondition{Operator: op}
+	if op == types.PolicyOperatorThreshold {
+		if len(args) == 0 {
+			return types.UnlockConditionProxy{}, node.errorf("expected a threshold and at least one condition")
+		}
+		var err error
+		condition.Threshold, err = args[0].compileUint64()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return types.UnlockConditionProxy{}, node.errorf("expected at least one condition")
+	}
+	for _, arg := range args {
+		sub, err := arg.compileCondition()
+		if err != nil {
+			return types.UnlockConditionProxy{}, err
+		}
+		condition.Conditions = append(condition.Conditions, sub)
+	}
+	return types.NewCondition(condition), nil
+}
+
+func (node policyNode) expectWord() error {
+	if node.call {
+		return node.errorf("unexpected function call")
+	}
+	return nil
+}
+
+func (node policyNode) compileUint64() (uint64, error) {
+	if err := node.expectWord(); err != nil {
+		return 0, err
+	}
+	x, err := strconv.ParseUint(node.name, 10, 64)
+	if err != nil {
+		return 0, node.errorf("expected an unsigned integer")
+	}
+	return x, nil
+}
+
+func (node policyNode) compileUnlockHash() (types.UnlockHash, error) {
+	if err := node.expectWord(); err != nil {
+		return types.UnlockHash{}, err
+	}
+	var uh types.UnlockHash
+	err := uh.LoadString(node.name)
+	if err != nil {
+		return types.UnlockHash{}, node.errorf("invalid address: %v", err)
+	}
+	if uh.Type == types.UnlockTypeNil {
+		return types.UnlockHash{}, node.errorf("invalid address: nil unlock hash")
+	}
+	return uh, nil
+}
+
+func (node policyNode) compileWeightedUnlockHash() (types.WeightedUnlockHash, error) {
+	if err := node.expectWord(); err != nil {
+		return types.WeightedUnlockHash{}, err
+	}
+	idx := strings.LastIndexByte(node.name, ':')
+	if idx < 0 {
+		return types.WeightedUnlockHash{}, node.errorf("expected a weighted address of the form <address>:<weight>")
+	}
+	uh, err := policyNode{name: node.name[:idx], pos: node.pos}.compileUnlockHash()
+	if err != nil {
+		return types.WeightedUnlockHash{}, err
+	}
+	weight, err := policyNode{name: node.name[idx+1:], pos: node.pos + idx + 1}.compileUint64()
+	if err != nil {
+		return types.WeightedUnlockHash{}, err
+	}
+	return types.WeightedUnlockHash{UnlockHash: uh, Weight: weight}, nil
+}
@@ -0,0 +1,210 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/types"
+)
+
+func newTestPolicyUnlockHash(t *testing.T) types.UnlockHash {
+	_, pk := crypto.GenerateKeyPair()
+	uh, err := types.NewEd25519PubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uh
+}
+
+func TestCompileAndExplainCondition(t *testing.T) {
+	uhA, uhB, uhC := newTestPolicyUnlockHash(t), newTestPolicyUnlockHash(t), newTestPolicyUnlockHash(t)
+	a, b, c := uhA.String(), uhB.String(), uhC.String()
+	hashedSecret := types.NewAtomicSwapHashedSecret(types.AtomicSwapSecret{1, 2, 3})
+	hashLock, err := types.NewHashLockCondition(types.HashLockTypeKeccak256, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		Policy    string
+		Condition types.UnlockConditionProxy
+	}{
+		{"nil", types.NewCondition(&types.NilCondition{})},
+		{a, types.NewCondition(types.NewUnlockHashCondition(uhA))},
+		{
+			"timelock(1700000000, multisig(2, " + a + ", " + b + ", " + c + "))",
+			types.NewCondition(types.NewTimeLockCondition(1700000000,
+				types.NewMultiSignatureCondition(types.UnlockHashSlice{uhA, uhB, uhC}, 2))),
+		},
+		{
+			"relativetimelock(144, " + a + ")",
+			types.NewCondition(types.NewRelativeTimeLockCondition(144, types.NewUnlockHashCondition(uhA))),
+		},
+		{
+			"timelock(42, nil)",
+			types.NewCondition(types.NewTimeLockCondition(42, &types.NilCondition{})),
+		},
+		{
+			"weightedmultisig(3, " + a + ":2, " + b + ":1)",
+			types.NewCondition(types.NewWeightedMultiSignatureCondition([]types.WeightedUnlockHash{
+				{UnlockHash: uhA, Weight: 2},
+				{UnlockHash: uhB, Weight: 1},
+			}, 3)),
+		},
+		{
+			"atomicswap(" + a + ", " + b + ", " + hashedSecret.String() + ", 1700000000)",
+			types.NewCondition(&types.AtomicSwapCondition{
+				Sender:       uhA,
+				Receiver:     uhB,
+				HashedSecret: hashedSecret,
+				TimeLock:     1700000000,
+			}),
+		},
+		{
+			"hashlock(keccak256, " + hashLock.Hash.String() + ")",
+			types.NewCondition(hashLock),
+		},
+		{
+			"and(timelock(1700000000, hashlock(keccak256, " + hashLock.Hash.String() + ")), multisig(2, " + a + ", " + b + "))",
+			types.NewCondition(types.NewPolicyCondition(types.PolicyOperatorAnd, 0,
+				types.NewTimeLockCondition(1700000000, hashLock),
+				types.NewMultiSignatureCondition(types.UnlockHashSlice{uhA, uhB}, 2))),
+		},
+		{
+			"or(" + a + ", and(" + b + ", timelock(1700000000, " + c + ")))",
+			types.NewCondition(types.NewPolicyCondition(types.PolicyOperatorOr, 0,
+				types.NewUnlockHashCondition(uhA),
+				types.NewPolicyCondition(types.PolicyOperatorAnd, 0,
+					types.NewUnlockHashCondition(uhB),
+					types.NewTimeLockCondition(1700000000, types.NewUnlockHashCondition(uhC))))),
+		},
+		{
+			"threshold(2, " + a + ", " + b + ", " + c + ")",
+			types.NewCondition(types.NewPolicyCondition(types.PolicyOperatorThreshold, 2,
+				types.NewUnlockHashCondition(uhA),
+				types.NewUnlockHashCondition(uhB),
+				types.NewUnlockHashCondition(uhC))),
+		},
+	}
+	for idx, testCase := range testCases {
+		condition, err := CompileCondition(testCase.Policy)
+		if err != nil {
+			t.Errorf("test case #%d: failed to compile %q: %v", idx, testCase.Policy, err)
+			continue
+		}
+		if !condition.Equal(testCase.Condition) {
+			t.Errorf("test case #%d: unexpected condition compiled from %q: %v", idx, testCase.Policy, condition)
+		}
+		policy, err := ExplainCondition(testCase.Condition)
+		if err != nil {
+			t.Errorf("test case #%d: failed to explain condition: %v", idx, err)
+			continue
+		}
+		if policy != testCase.Policy {
+			t.Errorf("test case #%d: unexpected policy: %q != %q", idx, policy, testCase.Policy)
+		}
+	}
+}
+
+func TestCompileConditionSyntax(t *testing.T) {
+	uh := newTestPolicyUnlockHash(t)
+	expected := types.NewCondition(types.NewTimeLockCondition(42, types.NewMultiSignatureCondition(types.UnlockHashSlice{uh, uh}, 1)))
+	for idx, policy := range []string{
+		"timelock(42,multisig(1," + uh.String() + "," + uh.String() + "))",
+		"  TimeLock ( 42 ,\n\tMultiSig( 1, " + uh.String() + ", " + uh.String() + " ) ) ",
+	} {
+		condition, err := CompileCondition(policy)
+		if err != nil {
+			t.Errorf("test case #%d: failed to compile %q: %v", idx, policy, err)
+		} else if !condition.Equal(expected) {
+			t.Errorf("test case #%d: unexpected condition compiled from %q: %v", idx, policy, condition)
+		}
+	}
+}
+
+func TestCompileConditionErrors(t *testing.T) {
+	a := newTestPolicyUnlockHash(t).String()
+	testCases := []string{
+		"",
+		"foo",
+		"foo(" + a + ")",
+		"(" + a + ")",
+		a + ")",
+		"and(" + a,
+		"and(" + a + ",)",
+		"and(" + a + " " + a + ")",
+		"and()",
+		"threshold(" + a + ")",
+		"threshold(1)",
+		"nil(" + a + ")",
+		"timelock(0, " + a + ")",
+		"timelock(" + a + ", 42)",
+		"timelock(42)",
+		"timelock(-1, " + a + ")",
+		"multisig(1)",
+		"multisig(1, nil)",
+		"multisig(1, and(" + a + "))",
+		"weightedmultisig(1, " + a + ")",
+		"weightedmultisig(1, " + a + ":x)",
+		"atomicswap(" + a + ", " + a + ", 42, 1700000000)",
+		"hashlock(sha3, " + crypto.Hash{1}.String() + ")",
+		"hashlock(sha256, 42)",
+		"hashlock(sha256)",
+		"hashlock(sha256, " + a + ")",
+		"or(" + a + ") " + a,
+		"or(" + a + "); " + a,
+	}
+	for idx, policy := range testCases {
+		if condition, err := CompileCondition(policy); err == nil {
+			t.Errorf("test case #%d: expected %q to not compile, but it compiled to: %v", idx, policy, condition)
+		}
+	}
+}
+
+func TestParseConditionString(t *testing.T) {
+	uh := newTestPolicyUnlockHash(t)
+	expected := types.NewCondition(types.NewTimeLockCondition(42, types.NewUnlockHashCondition(uh)))
+	b, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an address is parsed as an unlock hash condition
+	condition, err := ParseConditionString(uh.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(types.NewCondition(types.NewUnlockHashCondition(uh))) {
+		t.Errorf("unexpected condition parsed from address: %v", condition)
+	}
+	// both JSON-encoded conditions and policies are accepted as well
+	for _, str := range []string{string(b), "timelock(42, " + uh.String() + ")"} {
+		condition, err = ParseConditionString(str)
+		if err != nil {
+			t.Errorf("failed to parse condition %q: %v", str, err)
+		} else if !condition.Equal(expected) {
+			t.Errorf("unexpected condition parsed from %q: %v", str, condition)
+		}
+	}
+	// invalid input is refused
+	for _, str := range []string{"foo", `{"type":1,"data":{}`, "timelock(42)"} {
+		if _, err = ParseConditionString(str); err == nil {
+			t.Errorf("expected condition %q to be refused", str)
+		}
+	}
+}
+
+// testUnknownCondition is a condition unknown to the policy language.
+type testUnknownCondition struct {
+	*types.NilCondition
+}
+
+func TestExplainConditionErrors(t *testing.T) {
+	if _, err := ExplainCondition(types.NewCondition(testUnknownCondition{&types.NilCondition{}})); err == nil {
+		t.Error("expected an error when explaining an unknown condition")
+	}
+	if policy, err := ExplainCondition(types.UnlockConditionProxy{}); err != nil || policy != "nil" {
+		t.Errorf("unexpected explanation of a nil condition: %q (%v)", policy, err)
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"encoding/json"
+	"fmt"
 
 	rivineapi "github.com/threefoldtech/rivine/pkg/api"
 	"github.com/threefoldtech/rivine/types"
@@ -37,3 +38,14 @@ func (tpool *TransactionPoolClient) AddTransactiom(t types.Transaction) (types.T
 	}
 	return resp.TransactionID, nil
 }
+
+// FeeEstimation returns the fee rate (per byte) and miner fee, as estimated by the transaction pool,
+// a transaction has to pay in order to get confirmed within the target amount of blocks.
+func (tpool *TransactionPoolClient) FeeEstimation(target types.BlockHeight) (feeRate, fee types.Currency, err error) {
+	var resp rivineapi.TransactionPoolFeeGET
+	err = tpool.bc.HTTP().GetWithResponse(fmt.Sprintf("/transactionpool/fee?target=%d", target), &resp)
+	if err != nil {
+		return types.Currency{}, types.Currency{}, err
+	}
+	return resp.FeeRate, resp.Fee, nil
+}
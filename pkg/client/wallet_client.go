@@ -51,6 +51,24 @@ func (wallet *WalletClient) FundCoins(amount types.Currency, refundAddress *type
 	return result.CoinInputs, result.RefundCoinOutput, nil
 }
 
+// FundAsset collects coin inputs owned by this daemon's wallet,
+// that hold sufficient of the given asset to fund the given amount,
+// optionally returning a refund coin output (of the asset) as well.
+func (wallet *WalletClient) FundAsset(assetID types.AssetID, amount types.Currency, refundAddress *types.UnlockHash, newRefundAddress bool) ([]types.CoinInput, *types.CoinOutput, error) {
+	var result api.WalletFundCoins
+	r := fmt.Sprintf("/wallet/fund/coins?amount=%s&asset=%s", amount.String(), assetID.String())
+	if refundAddress != nil {
+		r += "&refund=" + refundAddress.String()
+	} else {
+		r += fmt.Sprintf("&refund=%t", newRefundAddress)
+	}
+	err := wallet.bc.HTTP().GetWithResponse(r, &result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get fund asset %s: %v", assetID.String(), err)
+	}
+	return result.CoinInputs, result.RefundCoinOutput, nil
+}
+
 // GreedySignTx signs the given transactions greedy,
 // meaning that all fulfillments that can be signed, will be signed.
 func (wallet *WalletClient) GreedySignTx(t *types.Transaction) error {
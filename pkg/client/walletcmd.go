@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"sort"
 	"strconv"
 	"text/tabwriter"
 
@@ -74,6 +75,25 @@ func createWalletCmd(cli *CommandLineClient) *WalletCommand {
 	by any of the keys in the wallet.`,
 			Run: Wrap(walletCmd.signTxCmd),
 		}
+		bumpFeeCmd = &cobra.Command{
+			Use:   "bumpfee <txid>",
+			Short: "Bump the fee of an unconfirmed transaction",
+			Long: `Replace an unconfirmed transaction, sent by this wallet, with an identical transaction
+	which pays a higher miner fee, paid for by its refund output. The fee is increased
+	with the Minimum Miner Fee, as well as with the fees of the unconfirmed wallet transactions which depend on it.`,
+			Run: Wrap(walletCmd.bumpFeeCmd),
+		}
+		cpfpCmd = &cobra.Command{
+			Use:   "cpfp <txid> <outputindex>",
+			Short: "Speed up an unconfirmed transaction using a child transaction",
+			Long: `Spend the unconfirmed coin output, owned by this wallet, at the given index of an unconfirmed transaction,
+	back to this wallet, using a child transaction which pays the miner fee required for the parent and child transaction
+	to pay the fee rate estimated by the transaction pool, or the custom fee rate (per byte) if defined,
+	with a minimum of the Minimum Miner Fee.
+	The transaction pool ranks the parent and child transaction by fee rate as a single package,
+	such that the child pays for the confirmation of the parent transaction.`,
+			Run: Wrap(walletCmd.cpfpCmd),
+		}
 		seedsCmd = &cobra.Command{
 			Use:   "seeds",
 			Short: "Retrieve information about your seeds",
@@ -134,7 +154,9 @@ func createWalletCmd(cli *CommandLineClient) *WalletCommand {
 	Amounts have to be given expressed in the OneCoin unit, and without the unit of currency.
 	Decimals are possible and have to be defined using the decimal point.
 	
-	The Minimum Miner Fee will be added on top of the total given amount automatically.
+	The miner fee, as estimated by the transaction pool for the transaction to be confirmed
+	within the --fee-target amount of blocks, will be added on top of the total given amount automatically,
+	unless a custom miner fee is defined using the --fee flag.
 	`,
 			Run: walletCmd.sendCoinsCmd,
 		}
@@ -149,7 +171,9 @@ func createWalletCmd(cli *CommandLineClient) *WalletCommand {
 	Amounts have to be given expressed in the OneCoin unit, and without the unit of currency.
 	Decimals are possible and have to be defined using the decimal point.
 	
-	The Minimum Miner Fee will be added on top of the total given amount automatically.
+	The miner fee, as estimated by the transaction pool for the transaction to be confirmed
+	within the --fee-target amount of blocks, will be paid using the coins of the wallet automatically,
+	unless a custom miner fee is defined using the --fee flag.
 	`,
 			Run: walletCmd.sendBlockStakesCmd,
 		}
@@ -201,6 +225,14 @@ func createWalletCmd(cli *CommandLineClient) *WalletCommand {
 			Args: cobra.MinimumNArgs(3),
 			Run:  walletCmd.createMultisigAddressesCmd,
 		}
+		createWeightedMultisigAddressCmd = &cobra.Command{
+			Use:   "weightedmultisigaddress <minweight> <address1> <weight1> <address2> <weight2> [<address> <weight>]...",
+			Short: "Create a weighted multisig address",
+			Long: `Create a weighted multisig address from the given address and weight pairs,
+	which requires signatures of addresses with a combined weight of at least <minweight> to unlock`,
+			Args: cobra.MinimumNArgs(5),
+			Run:  walletCmd.createWeightedMultisigAddressCmd,
+		}
 		createCoinTxCmd = &cobra.Command{
 			Use:   "cointransaction <parentID>... <dest>|<rawCondition> <amount> [<dest>|<rawCondition> <amount>]...",
 			Short: "Create a new coin transaction",
@@ -247,7 +279,9 @@ func createWalletCmd(cli *CommandLineClient) *WalletCommand {
 		blockStakeStatCmd,
 		listCmd,
 		createCmd,
-		signTxCmd)
+		signTxCmd,
+		bumpFeeCmd,
+		cpfpCmd)
 
 	sendCmd.AddCommand(
 		sendCoinsCmd,
@@ -262,6 +296,7 @@ func createWalletCmd(cli *CommandLineClient) *WalletCommand {
 
 	createCmd.AddCommand(
 		createMultisigAddressesCmd,
+		createWeightedMultisigAddressCmd,
 		createCoinTxCmd,
 		createBlockStakeTxCmd)
 
@@ -295,6 +330,15 @@ func createWalletCmd(cli *CommandLineClient) *WalletCommand {
 	sendCoinsCmd.Flags().BoolVar(
 		&walletCmd.sendCoinsCfg.RefundAddressNew,
 		"refund-address-new", false, "generate a new refund address if a refund needs to happen")
+	sendCoinsCmd.Flags().Uint64Var(
+		&walletCmd.sendCoinsCfg.ExpiresIn,
+		"expires-in", 0, "optional amount of blocks after which the transaction can no longer be included in a block")
+	sendCoinsCmd.Flags().StringVar(
+		&walletCmd.sendCoinsCfg.MinerFee,
+		"fee", "", "define a custom miner fee, instead of the fee estimated by the transaction pool")
+	sendCoinsCmd.Flags().Uint64Var(
+		&walletCmd.sendCoinsCfg.FeeTarget,
+		"fee-target", uint64(modules.DefaultFeeTarget), "amount of blocks within which the transaction should be confirmed, used to estimate the miner fee")
 
 	// other custom send blockstkars flags
 	sendBlockStakesCmd.Flags().StringVar(
@@ -303,6 +347,29 @@ func createWalletCmd(cli *CommandLineClient) *WalletCommand {
 	sendBlockStakesCmd.Flags().BoolVar(
 		&walletCmd.sendBlockStakesCfg.RefundAddressNew,
 		"refund-address-new", false, "generate a new refund address if a refund needs to happen")
+	sendBlockStakesCmd.Flags().Uint64Var(
+		&walletCmd.sendBlockStakesCfg.ExpiresIn,
+		"expires-in", 0, "optional amount of blocks after which the transaction can no longer be included in a block")
+	sendBlockStakesCmd.Flags().StringVar(
+		&walletCmd.sendBlockStakesCfg.MinerFee,
+		"fee", "", "define a custom miner fee, instead of the fee estimated by the transaction pool")
+	sendBlockStakesCmd.Flags().Uint64Var(
+		&walletCmd.sendBlockStakesCfg.FeeTarget,
+		"fee-target", uint64(modules.DefaultFeeTarget), "amount of blocks within which the transaction should be confirmed, used to estimate the miner fee")
+
+	// cpfp cmd flags
+	cpfpCmd.Flags().StringVar(
+		&walletCmd.cpfpCfg.FeeRate,
+		"fee-rate", "", "define a custom fee rate (per byte), instead of the fee rate estimated by the transaction pool")
+	cpfpCmd.Flags().Uint64Var(
+		&walletCmd.cpfpCfg.FeeTarget,
+		"fee-target", uint64(modules.DefaultFeeTarget), "amount of blocks within which the transactions should be confirmed, used to estimate the fee rate")
+
+	// address cmd flags
+	addressCmd.Flags().StringVar(
+		&walletCmd.walletAddressCfg.Algorithm, "algorithm", types.SignatureAlgoEd25519.String(),
+		fmt.Sprintf("signature algorithm of the new address, one of: %s, %s",
+			types.SignatureAlgoEd25519, types.SignatureAlgoSecp256k1))
 
 	// all addresses cmd flags
 	addressesCmd.Flags().BoolVarP(
@@ -336,11 +403,21 @@ type walletCmd struct {
 		Data             []byte
 		RefundAddress    string
 		RefundAddressNew bool
+		ExpiresIn        uint64
+		MinerFee         string
+		FeeTarget        uint64
 	}
 	sendBlockStakesCfg struct {
 		Data             []byte
 		RefundAddress    string
 		RefundAddressNew bool
+		ExpiresIn        uint64
+		MinerFee         string
+		FeeTarget        uint64
+	}
+	cpfpCfg struct {
+		FeeRate   string
+		FeeTarget uint64
 	}
 	walletInitCfg struct {
 		Plain bool
@@ -353,6 +430,9 @@ type walletCmd struct {
 		Plain bool
 		Seed  string
 	}
+	walletAddressCfg struct {
+		Algorithm string
+	}
 	walletAddressesCfg struct {
 		ShowIndices bool
 	}
@@ -361,8 +441,13 @@ type walletCmd struct {
 // addressCmd fetches a new address from the wallet that will be able to
 // receive coins.
 func (walletCmd *walletCmd) addressCmd() {
+	var algo types.SignatureAlgoType
+	err := algo.LoadString(walletCmd.walletAddressCfg.Algorithm)
+	if err != nil {
+		clipkg.DieWithError("Invalid signature algorithm:", err)
+	}
 	addr := new(api.WalletAddressGET)
-	err := walletCmd.cli.GetWithResponse("/wallet/address", addr)
+	err = walletCmd.cli.GetWithResponse("/wallet/address?algorithm="+algo.String(), addr)
 	if err != nil {
 		clipkg.DieWithError("Could not generate new address:", err)
 	}
@@ -556,9 +641,16 @@ func (walletCmd *walletCmd) sendCoinsCmd(cmd *cobra.Command, args []string) {
 		clipkg.Die(err)
 	}
 
+	minerFee, err := walletCmd.minerFee(walletCmd.sendCoinsCfg.MinerFee, walletCmd.sendCoinsCfg.FeeTarget, currencyConvertor)
+	if err != nil {
+		clipkg.DieWithError("invalid miner fee", err)
+	}
+
 	body := api.WalletCoinsPOST{
 		CoinOutputs: make([]types.CoinOutput, len(pairs)),
 		Data:        []byte(walletCmd.sendCoinsCfg.Data),
+		ExpiresIn:   types.BlockHeight(walletCmd.sendCoinsCfg.ExpiresIn),
+		MinerFee:    minerFee,
 	}
 	for i, pair := range pairs {
 		body.CoinOutputs[i] = types.CoinOutput{
@@ -589,6 +681,7 @@ func (walletCmd *walletCmd) sendCoinsCmd(cmd *cobra.Command, args []string) {
 		clipkg.DieWithError("Could not send coins:", err)
 	}
 	fmt.Println("Succesfully sent coins as transaction " + resp.TransactionID.String())
+	fmt.Println("Paid a miner fee of " + currencyConvertor.ToCoinStringWithUnit(minerFee))
 	for _, co := range body.CoinOutputs {
 		fmt.Printf("Sent %s to %s (using ConditionType %d)\n",
 			currencyConvertor.ToCoinStringWithUnit(co.Value), co.Condition.UnlockHash(),
@@ -604,9 +697,17 @@ func (walletCmd *walletCmd) sendBlockStakesCmd(cmd *cobra.Command, args []string
 		clipkg.Die(err)
 	}
 
+	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
+	minerFee, err := walletCmd.minerFee(walletCmd.sendBlockStakesCfg.MinerFee, walletCmd.sendBlockStakesCfg.FeeTarget, currencyConvertor)
+	if err != nil {
+		clipkg.DieWithError("invalid miner fee", err)
+	}
+
 	body := api.WalletBlockStakesPOST{
 		BlockStakeOutputs: make([]types.BlockStakeOutput, len(pairs)),
 		Data:              []byte(walletCmd.sendBlockStakesCfg.Data),
+		ExpiresIn:         types.BlockHeight(walletCmd.sendBlockStakesCfg.ExpiresIn),
+		MinerFee:          minerFee,
 	}
 	for i, pair := range pairs {
 		body.BlockStakeOutputs[i] = types.BlockStakeOutput{
@@ -637,6 +738,7 @@ func (walletCmd *walletCmd) sendBlockStakesCmd(cmd *cobra.Command, args []string
 		clipkg.DieWithError("Could not send block stakes:", err)
 	}
 	fmt.Println("Succesfully sent blockstakes as transaction " + resp.TransactionID.String())
+	fmt.Println("Paid a miner fee of " + currencyConvertor.ToCoinStringWithUnit(minerFee))
 	for _, bo := range body.BlockStakeOutputs {
 		fmt.Printf("Sent %s BS to %s (using ConditionType %d)\n",
 			bo.Value, bo.Condition.UnlockHash(), bo.Condition.ConditionType())
@@ -676,20 +778,10 @@ func parsePairedOutputs(args []string, parseCurrency parseCurrencyString) (pairs
 			return
 		}
 
-		// try to parse it as an unlock hash
-		var uh types.UnlockHash
-		err = uh.LoadString(args[i])
-		if err == nil {
-			// parsing as an unlock hash was succesfull, store the pair and continue to the next pair
-			pair.Condition = types.NewCondition(types.NewUnlockHashCondition(uh))
-			pairs = append(pairs, pair)
-			continue
-		}
-
-		// try to parse it as a JSON-encoded unlock condition
-		err = pair.Condition.UnmarshalJSON([]byte(args[i]))
+		// parse it as an unlock hash, JSON-encoded unlock condition or policy
+		pair.Condition, err = ParseConditionString(args[i])
 		if err != nil {
-			err = fmt.Errorf("condition has to be UnlockHash or JSON-encoded UnlockCondition, output #%d's was neither", i/2)
+			err = fmt.Errorf("failed to parse condition for output #%d: %v", i/2, err)
 			return
 		}
 		pairs = append(pairs, pair)
@@ -768,6 +860,22 @@ BlockStakes:         %v BS
 		fmt.Printf("Locked BlockStakes:  %v BS\n", status.LockedBlockStakeBalance)
 	}
 
+	if len(status.ConfirmedAssetBalances) > 0 {
+		// print the assets sorted by ID, such that the output is deterministic
+		assetIDs := make([]types.AssetID, 0, len(status.ConfirmedAssetBalances))
+		for assetID := range status.ConfirmedAssetBalances {
+			assetIDs = append(assetIDs, assetID)
+		}
+		sort.Slice(assetIDs, func(i, j int) bool {
+			return bytes.Compare(assetIDs[i][:], assetIDs[j][:]) < 0
+		})
+		fmt.Println()
+		fmt.Println("Assets:")
+		for _, assetID := range assetIDs {
+			fmt.Printf("  %s: %v\n", assetID.String(), status.ConfirmedAssetBalances[assetID])
+		}
+	}
+
 	if len(status.MultiSigWallets) > 0 {
 		fmt.Println()
 		fmt.Println("Multisig Wallets:")
@@ -856,7 +964,7 @@ func (walletCmd *walletCmd) listTransactionsCmd() {
 				rootWalletOwned = true
 				outgoingBlockStakes = outgoingBlockStakes.Add(input.Value)
 			}
-			if input.RelatedAddress.Type == types.UnlockTypeMultiSig {
+			if input.RelatedAddress.Type.IsMultiSig() {
 				relatedMultiSigUnlockHashes = append(relatedMultiSigUnlockHashes, input.RelatedAddress)
 			}
 		}
@@ -877,7 +985,7 @@ func (walletCmd *walletCmd) listTransactionsCmd() {
 				rootWalletOwned = true
 				incomingBlockStakes = incomingBlockStakes.Add(output.Value)
 			}
-			if output.RelatedAddress.Type == types.UnlockTypeMultiSig {
+			if output.RelatedAddress.Type.IsMultiSig() {
 				relatedMultiSigUnlockHashes = append(relatedMultiSigUnlockHashes, output.RelatedAddress)
 			}
 		}
@@ -993,6 +1101,84 @@ func (walletCmd *walletCmd) sendTxCmd(txnjson string) {
 	fmt.Println("Transaction published, transaction id:", resp.TransactionID)
 }
 
+// minerFee parses the given miner fee, or fetches the miner fee estimated by the
+// transaction pool for the given target amount of blocks if no miner fee is given.
+func (walletCmd *walletCmd) minerFee(fee string, target uint64, currencyConvertor CurrencyConvertor) (types.Currency, error) {
+	if fee != "" {
+		return currencyConvertor.ParseCoinString(fee)
+	}
+	if target == 0 {
+		return types.Currency{}, errors.New("fee target has to be at least one block")
+	}
+	var resp api.TransactionPoolFeeGET
+	err := walletCmd.cli.GetWithResponse(fmt.Sprintf("/transactionpool/fee?target=%d", target), &resp)
+	if err != nil {
+		return types.Currency{}, fmt.Errorf("failed to get the estimated miner fee: %v", err)
+	}
+	return resp.Fee, nil
+}
+
+// feeRate parses the given fee rate, or fetches the fee rate estimated by the
+// transaction pool for the given target amount of blocks if no fee rate is given.
+func (walletCmd *walletCmd) feeRate(rate string, target uint64, currencyConvertor CurrencyConvertor) (types.Currency, error) {
+	if rate != "" {
+		return currencyConvertor.ParseCoinString(rate)
+	}
+	if target == 0 {
+		return types.Currency{}, errors.New("fee target has to be at least one block")
+	}
+	var resp api.TransactionPoolFeeGET
+	err := walletCmd.cli.GetWithResponse(fmt.Sprintf("/transactionpool/fee?target=%d", target), &resp)
+	if err != nil {
+		return types.Currency{}, fmt.Errorf("failed to get the estimated fee rate: %v", err)
+	}
+	return resp.FeeRate, nil
+}
+
+// bumpFeeCmd replaces an unconfirmed transaction of the wallet
+// with an identical transaction paying a higher miner fee
+func (walletCmd *walletCmd) bumpFeeCmd(txid string) {
+	var id types.TransactionID
+	err := id.LoadString(txid)
+	if err != nil {
+		clipkg.DieWithError("invalid transaction ID specified", err)
+	}
+	var resp api.WalletBumpFeePOSTResp
+	err = walletCmd.cli.PostWithResponse("/wallet/bumpfee/"+id.String(), "", &resp)
+	if err != nil {
+		clipkg.DieWithError("Could not bump the fee of the transaction:", err)
+	}
+	fmt.Println("Succesfully replaced transaction " + id.String() + " as transaction " + resp.TransactionID.String())
+}
+
+// cpfpCmd spends an unconfirmed coin output of the wallet using a child transaction,
+// paying the fee for both the child and its parent transaction
+func (walletCmd *walletCmd) cpfpCmd(txid, outputindex string) {
+	var id types.TransactionID
+	err := id.LoadString(txid)
+	if err != nil {
+		clipkg.DieWithError("invalid transaction ID specified", err)
+	}
+	index, err := strconv.ParseUint(outputindex, 10, 64)
+	if err != nil {
+		clipkg.DieWithError("invalid output index specified", err)
+	}
+	feeRate, err := walletCmd.feeRate(walletCmd.cpfpCfg.FeeRate, walletCmd.cpfpCfg.FeeTarget, walletCmd.cli.CreateCurrencyConvertor())
+	if err != nil {
+		clipkg.DieWithError("invalid fee rate", err)
+	}
+	bytes, err := json.Marshal(&api.WalletCPFPPOST{FeeRate: feeRate})
+	if err != nil {
+		clipkg.Die("Failed to JSON Marshal the input body:", err)
+	}
+	var resp api.WalletCPFPPOSTResp
+	err = walletCmd.cli.PostWithResponse(fmt.Sprintf("/wallet/cpfp/%s/%d", id.String(), index), string(bytes), &resp)
+	if err != nil {
+		clipkg.DieWithError("Could not create the child transaction:", err)
+	}
+	fmt.Println("Succesfully created child transaction " + resp.TransactionID.String() + " for transaction " + id.String())
+}
+
 func (walletCmd *walletCmd) listUnlockedCmd(_ *cobra.Command, args []string) {
 	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
 
@@ -1171,6 +1357,41 @@ func (walletCmd *walletCmd) createMultisigAddressesCmd(cmd *cobra.Command, args
 	fmt.Println("Multisig address:", multiSigCond.UnlockHash())
 }
 
+func (walletCmd *walletCmd) createWeightedMultisigAddressCmd(cmd *cobra.Command, args []string) {
+	minWeight, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		clipkg.Die(err)
+	}
+	if len(args[1:])%2 != 0 {
+		cmd.UsageFunc()(cmd)
+		clipkg.Die("Invalid arguments. Arguments must be of the form <minweight> <address> <weight> [<address> <weight>]...")
+	}
+
+	var signatories []types.WeightedUnlockHash
+	for i := 1; i < len(args); i += 2 {
+		var signatory types.WeightedUnlockHash
+		err = signatory.UnlockHash.LoadString(args[i])
+		if err != nil {
+			clipkg.Die("Failed to load unlock hash:", err)
+		}
+		signatory.Weight, err = strconv.ParseUint(args[i+1], 10, 64)
+		if err != nil {
+			clipkg.Die("Failed to parse weight:", err)
+		}
+		signatories = append(signatories, signatory)
+	}
+
+	condition := &types.WeightedMultiSignatureCondition{
+		Signatories:   signatories,
+		MinimumWeight: minWeight,
+	}
+	err = condition.IsStandardCondition(types.ValidationContext{})
+	if err != nil {
+		clipkg.Die("Invalid weighted multisig condition:", err)
+	}
+	fmt.Println("Weighted multisig address:", condition.UnlockHash())
+}
+
 func (walletCmd *walletCmd) createCoinTxCmd(cmd *cobra.Command, args []string) {
 	currencyConvertor := walletCmd.cli.CreateCurrencyConvertor()
 
@@ -92,6 +92,16 @@ func TestParsePairedOutputs(t *testing.T) {
 				}))),
 			}},
 		}, // no error, more explicit version of first non-error example, combined with a timelock
+		{
+			[]string{`{"type":5,"data":{"lockduration":720,"condition":{"type":1,"data":{"unlockhash":"01746677df456546d93729066dd88514e2009930f3eebac3c93d43c88a108f8f9aa9e7c6f58893"}}}}`, "42"},
+			[]outputPair{{
+				Value: types.NewCurrency64(42000000000),
+				Condition: types.NewCondition(types.NewRelativeTimeLockCondition(720, types.NewUnlockHashCondition(types.UnlockHash{
+					Type: types.UnlockTypePubKey,
+					Hash: hs("746677df456546d93729066dd88514e2009930f3eebac3c93d43c88a108f8f9a"),
+				}))),
+			}},
+		}, // no error, more explicit version of first non-error example, combined with a relative timelock
 		{
 			[]string{
 				`01ad4f73417476f8b8350298681dd0fa8640baa53a91915417b1dd8103d118b543c992e6fba1c4`, "1000",
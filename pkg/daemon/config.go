@@ -7,7 +7,9 @@ import (
 
 	"github.com/spf13/pflag"
 
+	"github.com/threefoldtech/rivine/crypto"
 	"github.com/threefoldtech/rivine/modules"
+	"github.com/threefoldtech/rivine/persist/kv"
 	"github.com/threefoldtech/rivine/pkg/cli"
 	"github.com/threefoldtech/rivine/types"
 )
@@ -64,14 +66,52 @@ type (
 		// DebugConsensusDB is an optional filepath in which json encoded
 		// consensus database stats will be saved
 		DebugConsensusDB string
+
+		// BootstrapSnapshot is an optional filepath of a consensus snapshot,
+		// used to bootstrap a fresh consensus set, instead of replaying the entire chain.
+		// The checksum of the snapshot has to be one of the TrustedSnapshots of the NetworkConfig,
+		// Rivine ships no trusted snapshots, these have to be supplied by the chain implementing Rivine.
+		BootstrapSnapshot string
+
+		// PruneDepth enables the pruned mode of the consensus set when non-zero,
+		// keeping only the full data of the given amount of most recent blocks.
+		// It is also the maximum depth of a reorg the consensus set can handle.
+		PruneDepth uint64
+
+		// MaxReorgDepth is the maximum amount of blocks a single reorg can revert,
+		// forks which would revert more blocks are refused. 0 allows reorgs of any depth.
+		MaxReorgDepth uint64
+
+		// IndexSpentOutputs enables the spent outputs consensus plugin,
+		// indexing for each spent coin- and blockstake output the transaction that spent it.
+		IndexSpentOutputs bool
+
+		// DatabaseBackend is the storage backend used for the databases
+		// of the consensus set, transaction pool and explorer modules.
+		DatabaseBackend string
 	}
 
 	// NetworkConfig are variables for a particular chain. Currently, these are genesis constants and bootstrap peers
 	NetworkConfig struct {
-		// Blockchain Constants for this network
+		// Blockchain Constants for this network,
+		// including the ActivationSchedule which defines from which block height
+		// new transaction versions, unlock condition types and unlock fulfillment types are active.
+		// The transaction versions which commit to the ID of the chain (2, 3 and 4)
+		// are only known once registered using RegisterChainTransactionVersions,
+		// which the daemon has to call prior to creating its modules.
 		Constants types.ChainConstants
 		// BootstrapPeers for this network
 		BootstrapPeers []modules.NetAddress
+		// TrustedSnapshots are the hard-coded checksums of the consensus snapshots,
+		// mapped by block height, which can be used to bootstrap a consensus set for this network.
+		// None are defined by default, a snapshot can only be imported once its checksum
+		// has been exported by a trusted node and added to this map by the chain's operator.
+		// The checksum depends on the plugins registered by the exporting node (e.g. IndexSpentOutputs),
+		// so snapshots should be exported by a node running the plugins of the chain's default daemon.
+		TrustedSnapshots map[types.BlockHeight]crypto.Hash
+		// Checkpoints are the hard-coded IDs of the blocks required at the mapped block heights,
+		// blocks and forks which conflict with these checkpoints are refused
+		Checkpoints map[types.BlockHeight]types.BlockID
 	}
 )
 
@@ -98,6 +138,15 @@ func DefaultConfig() Config {
 		BootstrapPeers: nil,
 
 		DebugConsensusDB: "",
+
+		BootstrapSnapshot: "",
+
+		PruneDepth:    0,
+		MaxReorgDepth: 0,
+
+		IndexSpentOutputs: false,
+
+		DatabaseBackend: kv.BackendBolt.String(),
 	}
 }
 
@@ -118,6 +167,11 @@ func (cfg *Config) RegisterAsFlags(flagSet *pflag.FlagSet) {
 	flagSet.BoolVarP(&cfg.AllowAPIBind, "disable-api-security", "", cfg.AllowAPIBind, fmt.Sprintf("allow the daemon of %s to listen on a non-localhost address (DANGEROUS)", cfg.BlockchainInfo.Name))
 	flagSet.StringVarP(&cfg.BlockchainInfo.NetworkName, "network", "n", cfg.BlockchainInfo.NetworkName, "the name of the network to which the daemon connects")
 	flagSet.StringVar(&cfg.DebugConsensusDB, "consensus-db-stats", cfg.DebugConsensusDB, "file path in which json encoded database stats will be saved")
+	flagSet.StringVar(&cfg.BootstrapSnapshot, "bootstrap-snapshot", cfg.BootstrapSnapshot, "file path of a consensus snapshot to bootstrap a fresh consensus set from, its checksum has to be hard-coded as a trusted snapshot of the network (none are by default)")
+	flagSet.BoolVar(&cfg.IndexSpentOutputs, "index-spent-outputs", cfg.IndexSpentOutputs, "index the transactions which spent coin and blockstake outputs, exposed via the /consensus/spent endpoints")
+	flagSet.Uint64Var(&cfg.MaxReorgDepth, "max-reorg-depth", cfg.MaxReorgDepth, "refuse forks which would revert more than the given amount of blocks (0 allows reorgs of any depth)")
+	flagSet.Uint64Var(&cfg.PruneDepth, "prune-depth", cfg.PruneDepth, "enable the pruned mode, only keeping the full data of the given amount of most recent blocks (0 disables pruning)")
+	flagSet.StringVar(&cfg.DatabaseBackend, "db-backend", cfg.DatabaseBackend, fmt.Sprintf("storage backend used for the consensus, transaction pool and explorer databases, one of: %v", kv.Backends))
 
 	cli.NetAddressArrayFlagVar(flagSet, &cfg.BootstrapPeers, "bootstrap-peers",
 		"overwrite the bootstrap peers to use, instead of using the default bootstrap peers")
@@ -165,6 +219,24 @@ func processNetAddr(addr string) string {
 	return addr
 }
 
+// RegisterChainTransactionVersions registers the transaction versions which commit
+// to the ID of the chain: the chain ID, valid-until and signature hash flags transaction versions.
+// The chain ID is computed from the given blockchain info and the genesis block of this network config,
+// and is returned such that it can be shared with the clients of the daemon.
+func (cfg NetworkConfig) RegisterChainTransactionVersions(bcInfo types.BlockchainInfo) types.ChainID {
+	chainID := bcInfo.ChainID(cfg.Constants.GenesisBlockID())
+	types.RegisterTransactionVersion(types.TransactionVersionTwo, types.ChainIDTransactionController{
+		ChainID: chainID,
+	})
+	types.RegisterTransactionVersion(types.TransactionVersionThree, types.ValidUntilTransactionController{
+		ChainID: chainID,
+	})
+	types.RegisterTransactionVersion(types.TransactionVersionFour, types.SigHashTransactionController{
+		ChainID: chainID,
+	})
+	return chainID
+}
+
 // DefaultNetworkConfig returns the default network config based on a given network name.
 func DefaultNetworkConfig(networkName string) (NetworkConfig, error) {
 	if networkName == "" {
@@ -0,0 +1,45 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/types"
+)
+
+func TestRegisterChainTransactionVersions(t *testing.T) {
+	networkCfg, err := DefaultNetworkConfig("devnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcInfo := types.DefaultBlockchainInfo()
+	defer func() {
+		for _, v := range []types.TransactionVersion{types.TransactionVersionTwo, types.TransactionVersionThree, types.TransactionVersionFour} {
+			types.RegisterTransactionVersion(v, nil)
+		}
+	}()
+	chainID := networkCfg.RegisterChainTransactionVersions(bcInfo)
+	if expected := bcInfo.ChainID(networkCfg.Constants.GenesisBlockID()); chainID != expected {
+		t.Fatal("unexpected chain ID:", chainID, "!=", expected)
+	}
+
+	// the registered transaction versions should be known,
+	// and version 2 should commit to the returned chain ID
+	for _, v := range []types.TransactionVersion{types.TransactionVersionTwo, types.TransactionVersionThree, types.TransactionVersionFour} {
+		_, err := types.Transaction{Version: v}.SignatureHash()
+		if err != nil {
+			t.Errorf("failed to compute the signature hash of a v%d transaction: %v", v, err)
+		}
+	}
+	txn := types.Transaction{Version: types.TransactionVersionTwo}
+	expectedHash, err := types.ChainIDTransactionController{ChainID: chainID}.SignatureHash(txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := txn.SignatureHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != expectedHash {
+		t.Error("unexpected signature hash of a v2 transaction:", hash, "!=", expectedHash)
+	}
+}
@@ -0,0 +1,178 @@
+// Package bech32 implements the bech32 encoding as specified in BIP-0173,
+// a checksummed base32 encoding using a human-readable prefix.
+//
+// Contrary to the BIP-0173 specification, the data given to Encode
+// and returned by Decode are regular (8-bit) bytes, the conversion
+// from and to 5-bit groups is done by this package.
+package bech32
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	charset   = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+	separator = '1'
+
+	checksumSize = 6
+	// MaxLength is the maximum length of a bech32 string,
+	// including the human-readable part, separator and checksum.
+	MaxLength = 90
+)
+
+var gen = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// errors returned by this package
+var (
+	ErrMixedCase       = errors.New("bech32: string contains both lower and upper case characters")
+	ErrInvalidLength   = errors.New("bech32: invalid string length")
+	ErrNoSeparator     = errors.New("bech32: string does not contain a separator")
+	ErrInvalidChecksum = errors.New("bech32: invalid checksum")
+	ErrInvalidPadding  = errors.New("bech32: invalid padding")
+)
+
+// Encode encodes the given data as a bech32 string,
+// using the given human-readable part as prefix.
+func Encode(hrp string, data []byte) (string, error) {
+	if err := ValidateHRP(hrp); err != nil {
+		return "", err
+	}
+	hrp = strings.ToLower(hrp)
+	values := convertBits(data, 8, 5, true)
+	if len(hrp)+1+len(values)+checksumSize > MaxLength {
+		return "", ErrInvalidLength
+	}
+	values = append(values, createChecksum(hrp, values)...)
+	var sb strings.Builder
+	sb.Grow(len(hrp) + 1 + len(values))
+	sb.WriteString(hrp)
+	sb.WriteByte(separator)
+	for _, v := range values {
+		sb.WriteByte(charset[v])
+	}
+	return sb.String(), nil
+}
+
+// Decode decodes a bech32 string, returning the (lower case) human-readable part
+// and the decoded data. An error is returned if the string is invalid or fails the checksum.
+func Decode(str string) (string, []byte, error) {
+	if len(str) < 1+1+checksumSize || len(str) > MaxLength {
+		return "", nil, ErrInvalidLength
+	}
+	lower := strings.ToLower(str)
+	if lower != str && strings.ToUpper(str) != str {
+		return "", nil, ErrMixedCase
+	}
+	str = lower
+	pos := strings.LastIndexByte(str, separator)
+	if pos < 0 {
+		return "", nil, ErrNoSeparator
+	}
+	if pos < 1 || pos+1+checksumSize > len(str) {
+		return "", nil, ErrInvalidLength
+	}
+	hrp := str[:pos]
+	if err := ValidateHRP(hrp); err != nil {
+		return "", nil, err
+	}
+	values := make([]byte, 0, len(str)-pos-1)
+	for i := pos + 1; i < len(str); i++ {
+		v := strings.IndexByte(charset, str[i])
+		if v < 0 {
+			return "", nil, fmt.Errorf("bech32: invalid data character %q", str[i])
+		}
+		values = append(values, byte(v))
+	}
+	if polymod(append(expandHRP(hrp), values...)) != 1 {
+		return "", nil, ErrInvalidChecksum
+	}
+	values = values[:len(values)-checksumSize]
+	data := convertBits(values, 5, 8, false)
+	if data == nil {
+		return "", nil, ErrInvalidPadding
+	}
+	return hrp, data, nil
+}
+
+// ValidateHRP validates the given human-readable part,
+// which has to consist out of at least one US-ASCII character in the range [33, 126],
+// and cannot be of mixed case.
+func ValidateHRP(hrp string) error {
+	if len(hrp) == 0 {
+		return errors.New("bech32: human-readable part cannot be empty")
+	}
+	for i := 0; i < len(hrp); i++ {
+		if hrp[i] < 33 || hrp[i] > 126 {
+			return fmt.Errorf("bech32: invalid human-readable character %q", hrp[i])
+		}
+	}
+	if strings.ToLower(hrp) != hrp && strings.ToUpper(hrp) != hrp {
+		return ErrMixedCase
+	}
+	return nil
+}
+
+func polymod(values []byte) uint32 {
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func expandHRP(hrp string) []byte {
+	values := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	return values
+}
+
+func createChecksum(hrp string, values []byte) []byte {
+	mod := polymod(append(append(expandHRP(hrp), values...), make([]byte, checksumSize)...)) ^ 1
+	checksum := make([]byte, checksumSize)
+	for i := range checksum {
+		checksum[i] = byte(mod>>uint(5*(5-i))) & 31
+	}
+	return checksum
+}
+
+// convertBits regroups the given bits, from groups of fromBits to groups of toBits.
+// When converting back to 8-bit groups (pad is false),
+// nil is returned in case the padding is invalid.
+func convertBits(data []byte, fromBits, toBits uint, pad bool) []byte {
+	var (
+		acc  uint32
+		bits uint
+		ret  = make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+		maxv = uint32(1)<<toBits - 1
+	)
+	for _, v := range data {
+		acc = acc<<fromBits | uint32(v)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			ret = append(ret, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			ret = append(ret, byte(acc<<(toBits-bits)&maxv))
+		}
+	} else if bits >= fromBits || acc<<(toBits-bits)&maxv != 0 {
+		return nil
+	}
+	return ret
+}
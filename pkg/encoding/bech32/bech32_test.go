@@ -0,0 +1,106 @@
+package bech32
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// test vectors taken from BIP-0173
+func TestDecodeValidChecksums(t *testing.T) {
+	testCases := []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"11qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqc8247j",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	}
+	for idx, testCase := range testCases {
+		hrp, _, err := Decode(testCase)
+		if err != nil {
+			t.Errorf("test case #%d (%s) failed to decode: %v", idx, testCase, err)
+			continue
+		}
+		if expected := strings.ToLower(testCase[:strings.LastIndexByte(testCase, '1')]); hrp != expected {
+			t.Errorf("test case #%d (%s) has unexpected hrp: %s != %s", idx, testCase, hrp, expected)
+		}
+	}
+}
+
+// test vectors taken from BIP-0173
+func TestDecodeInvalidStrings(t *testing.T) {
+	testCases := []string{
+		"\x201nwldj5",           // hrp character out of range
+		"\x7f1axkwrx",           // hrp character out of range
+		"\x801eym55h",           // hrp character out of range
+		"pzry9x0s0muk",          // no separator character
+		"1pzry9x0s0muk",         // empty hrp
+		"x1b4n0q5v",             // invalid data character
+		"li1dgmt3",              // too short checksum
+		"de1lg7wt\xff",          // invalid character in checksum
+		"A1G7SGD8",              // checksum calculated with uppercase form of hrp
+		"10a06t8",               // empty hrp
+		"1qzzfhee",              // empty hrp
+		"a12UEL5L",              // mixed case
+		"a12uel5m",              // invalid checksum
+		strings.Repeat("a", 91), // too long
+		"an84characterslonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1569pvx", // too long
+	}
+	for idx, testCase := range testCases {
+		if _, _, err := Decode(testCase); err == nil {
+			t.Errorf("test case #%d (%q) decoded while it was expected to be invalid", idx, testCase)
+		}
+	}
+}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	testCases := []struct {
+		HRP  string
+		Data []byte
+	}{
+		{"a", nil},
+		{"riv", []byte{1}},
+		{"riv", []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{"triv", bytes.Repeat([]byte{0xff}, 33)},
+		{"driv", bytes.Repeat([]byte{0}, 40)},
+	}
+	for idx, testCase := range testCases {
+		str, err := Encode(testCase.HRP, testCase.Data)
+		if err != nil {
+			t.Errorf("test case #%d failed to encode: %v", idx, err)
+			continue
+		}
+		if !strings.HasPrefix(str, testCase.HRP+"1") {
+			t.Errorf("test case #%d has an unexpected prefix: %s", idx, str)
+		}
+		hrp, data, err := Decode(str)
+		if err != nil {
+			t.Errorf("test case #%d failed to decode %s: %v", idx, str, err)
+			continue
+		}
+		if hrp != testCase.HRP {
+			t.Errorf("test case #%d has unexpected hrp: %s != %s", idx, hrp, testCase.HRP)
+		}
+		if !bytes.Equal(data, testCase.Data) {
+			t.Errorf("test case #%d has unexpected data: %v != %v", idx, data, testCase.Data)
+		}
+		// the upper case variant is valid as well
+		if _, data, err = Decode(strings.ToUpper(str)); err != nil || !bytes.Equal(data, testCase.Data) {
+			t.Errorf("test case #%d failed to decode upper case %s: %v", idx, strings.ToUpper(str), err)
+		}
+	}
+}
+
+func TestEncodeInvalidInput(t *testing.T) {
+	if _, err := Encode("", []byte{1}); err == nil {
+		t.Error("expected an error for an empty hrp")
+	}
+	if _, err := Encode("Riv", []byte{1}); err == nil {
+		t.Error("expected an error for a mixed case hrp")
+	}
+	if _, err := Encode("riv", make([]byte, 64)); err == nil {
+		t.Error("expected an error for data exceeding the maximum length")
+	}
+}
@@ -0,0 +1,175 @@
+package types
+
+import (
+	"errors"
+)
+
+var (
+	// ErrTransactionVersionNotActivated is returned in case a transaction
+	// uses a transaction version which isn't activated yet at the block height it is validated at.
+	ErrTransactionVersionNotActivated = errors.New("transaction version is not yet activated at this block height")
+	// ErrConditionTypeNotActivated is returned in case a transaction
+	// uses an unlock condition type which isn't activated yet at the block height it is validated at.
+	ErrConditionTypeNotActivated = errors.New("unlock condition type is not yet activated at this block height")
+	// ErrFulfillmentTypeNotActivated is returned in case a transaction
+	// uses an unlock fulfillment type which isn't activated yet at the block height it is validated at.
+	ErrFulfillmentTypeNotActivated = errors.New("unlock fulfillment type is not yet activated at this block height")
+	// ErrSignatureAlgorithmNotActivated is returned in case a transaction
+	// uses a public key of a signature algorithm which isn't activated yet at the block height it is validated at.
+	ErrSignatureAlgorithmNotActivated = errors.New("signature algorithm is not yet activated at this block height")
+)
+
+// ActivationSchedule defines from which block height onwards
+// a transaction version, unlock condition type, unlock fulfillment type or signature algorithm is active.
+// Prior to that height these versions and types are considered non-standard,
+// and are rejected by the consensus and transaction pool.
+//
+// Versions and types that aren't part of the schedule are active since the genesis block.
+// This allows a live blockchain to ship new versions and types in its binaries,
+// long before they are actually used, see doc/ProtocolUpgrade.md for more information.
+type ActivationSchedule struct {
+	// TransactionVersions maps a transaction version to its activation block height.
+	TransactionVersions map[TransactionVersion]BlockHeight
+	// ConditionTypes maps an unlock condition type to its activation block height.
+	ConditionTypes map[ConditionType]BlockHeight
+	// FulfillmentTypes maps an unlock fulfillment type to its activation block height.
+	FulfillmentTypes map[FulfillmentType]BlockHeight
+	// SignatureAlgorithms maps a signature algorithm to its activation block height.
+	SignatureAlgorithms map[SignatureAlgoType]BlockHeight
+}
+
+// TransactionVersionIsActive returns true if the given transaction version is active at the given block height.
+func (as ActivationSchedule) TransactionVersionIsActive(version TransactionVersion, height BlockHeight) bool {
+	activationHeight, ok := as.TransactionVersions[version]
+	return !ok || height >= activationHeight
+}
+
+// ConditionTypeIsActive returns true if the given unlock condition type is active at the given block height.
+func (as ActivationSchedule) ConditionTypeIsActive(ct ConditionType, height BlockHeight) bool {
+	activationHeight, ok := as.ConditionTypes[ct]
+	return !ok || height >= activationHeight
+}
+
+// FulfillmentTypeIsActive returns true if the given unlock fulfillment type is active at the given block height.
+func (as ActivationSchedule) FulfillmentTypeIsActive(ft FulfillmentType, height BlockHeight) bool {
+	activationHeight, ok := as.FulfillmentTypes[ft]
+	return !ok || height >= activationHeight
+}
+
+// SignatureAlgorithmIsActive returns true if the given signature algorithm is active at the given block height.
+func (as ActivationSchedule) SignatureAlgorithmIsActive(algo SignatureAlgoType, height BlockHeight) bool {
+	activationHeight, ok := as.SignatureAlgorithms[algo]
+	return !ok || height >= activationHeight
+}
+
+// ValidateTransaction validates that the transaction version,
+// as well as all unlock condition and unlock fulfillment types
+// and the signature algorithms of all public keys used by the given transaction,
+// are active at the given block height.
+func (as ActivationSchedule) ValidateTransaction(t Transaction, height BlockHeight) error {
+	if !as.TransactionVersionIsActive(t.Version, height) {
+		return ErrTransactionVersionNotActivated
+	}
+	var err error
+	for _, ci := range t.CoinInputs {
+		err = as.validateFulfillment(ci.Fulfillment, height)
+		if err != nil {
+			return err
+		}
+	}
+	for _, co := range t.CoinOutputs {
+		err = as.validateCondition(co.Condition.Condition, height)
+		if err != nil {
+			return err
+		}
+	}
+	for _, bsi := range t.BlockStakeInputs {
+		err = as.validateFulfillment(bsi.Fulfillment, height)
+		if err != nil {
+			return err
+		}
+	}
+	for _, bso := range t.BlockStakeOutputs {
+		err = as.validateCondition(bso.Condition.Condition, height)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateCondition validates the type of the given condition,
+// as well as the types of any conditions it wraps.
+func (as ActivationSchedule) validateCondition(condition MarshalableUnlockCondition, height BlockHeight) error {
+	if condition == nil {
+		condition = &NilCondition{}
+	}
+	if !as.ConditionTypeIsActive(condition.ConditionType(), height) {
+		return ErrConditionTypeNotActivated
+	}
+	switch tc := condition.(type) {
+	case *TimeLockCondition:
+		return as.validateCondition(tc.Condition, height)
+	case *RelativeTimeLockCondition:
+		return as.validateCondition(tc.Condition, height)
+	case *PolicyCondition:
+		for _, sc := range tc.Conditions {
+			err := as.validateCondition(sc.Condition, height)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (as ActivationSchedule) validateFulfillment(fulfillment UnlockFulfillmentProxy, height BlockHeight) error {
+	if !as.FulfillmentTypeIsActive(fulfillment.FulfillmentType(), height) {
+		return ErrFulfillmentTypeNotActivated
+	}
+	switch tf := fulfillment.Fulfillment.(type) {
+	case *SingleSignatureFulfillment:
+		return as.validatePublicKey(tf.PublicKey, height)
+	case *MultiSignatureFulfillment:
+		for _, pair := range tf.Pairs {
+			err := as.validatePublicKey(pair.PublicKey, height)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case *WeightedMultiSignatureFulfillment:
+		for _, pair := range tf.Pairs {
+			err := as.validatePublicKey(pair.PublicKey, height)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case *AtomicSwapFulfillment:
+		return as.validatePublicKey(tf.PublicKey, height)
+	case *LegacyAtomicSwapFulfillment:
+		return as.validatePublicKey(tf.PublicKey, height)
+	case *anyAtomicSwapFulfillment:
+		return as.validateFulfillment(UnlockFulfillmentProxy{Fulfillment: tf.atomicSwapFulfillment}, height)
+	case *PolicyFulfillment:
+		for _, branch := range tf.Branches {
+			err := as.validateFulfillment(branch.Fulfillment, height)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (as ActivationSchedule) validatePublicKey(pk PublicKey, height BlockHeight) error {
+	if !as.SignatureAlgorithmIsActive(pk.Algorithm, height) {
+		return ErrSignatureAlgorithmNotActivated
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestActivationScheduleUndefined(t *testing.T) {
+	var as ActivationSchedule
+	if !as.TransactionVersionIsActive(TransactionVersionOne, 0) {
+		t.Error("undefined transaction versions should be active from the genesis block")
+	}
+	if !as.ConditionTypeIsActive(ConditionTypeMultiSignature, 0) {
+		t.Error("undefined condition types should be active from the genesis block")
+	}
+	if !as.FulfillmentTypeIsActive(FulfillmentTypeMultiSignature, 0) {
+		t.Error("undefined fulfillment types should be active from the genesis block")
+	}
+	if !as.SignatureAlgorithmIsActive(SignatureAlgoSecp256k1, 0) {
+		t.Error("undefined signature algorithms should be active from the genesis block")
+	}
+	err := as.ValidateTransaction(Transaction{
+		Version: TransactionVersionOne,
+		CoinOutputs: []CoinOutput{
+			{Value: NewCurrency64(1), Condition: NewCondition(NewTimeLockCondition(42, &NilCondition{}))},
+		},
+	}, 0)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestActivationScheduleValidateTransaction(t *testing.T) {
+	as := ActivationSchedule{
+		TransactionVersions: map[TransactionVersion]BlockHeight{
+			TransactionVersion(2): 10,
+		},
+		ConditionTypes: map[ConditionType]BlockHeight{
+			ConditionTypeMultiSignature: 20,
+		},
+		FulfillmentTypes: map[FulfillmentType]BlockHeight{
+			FulfillmentTypeMultiSignature: 30,
+		},
+		SignatureAlgorithms: map[SignatureAlgoType]BlockHeight{
+			SignatureAlgoSecp256k1: 40,
+		},
+	}
+	multiSigCondition := NewCondition(NewMultiSignatureCondition(UnlockHashSlice{
+		unlockHashFromHex("015a080a9259b9d4aaa550e2156f49b1a79a64c7ea463d810d4493e8242e6791584fbdac553e6f"),
+	}, 1))
+	timeLockedMultiSigCondition := NewCondition(NewTimeLockCondition(42, multiSigCondition.Condition))
+	multiSigFulfillment := NewFulfillment(&MultiSignatureFulfillment{})
+	secp256k1PublicKey := PublicKey{Algorithm: SignatureAlgoSecp256k1, Key: make(ByteSlice, 33)}
+	secp256k1Fulfillment := NewFulfillment(NewSingleSignatureFulfillment(secp256k1PublicKey))
+	secp256k1MultiSigFulfillment := NewFulfillment(&MultiSignatureFulfillment{
+		Pairs: []PublicKeySignaturePair{{PublicKey: secp256k1PublicKey}},
+	})
+
+	testCases := []struct {
+		Transaction Transaction
+		Height      BlockHeight
+		Error       error
+	}{
+		{Transaction{Version: TransactionVersionOne}, 0, nil},
+		{Transaction{Version: TransactionVersion(2)}, 9, ErrTransactionVersionNotActivated},
+		{Transaction{Version: TransactionVersion(2)}, 10, nil},
+		{Transaction{
+			Version:     TransactionVersionOne,
+			CoinOutputs: []CoinOutput{{Value: NewCurrency64(1), Condition: multiSigCondition}},
+		}, 19, ErrConditionTypeNotActivated},
+		{Transaction{
+			Version:           TransactionVersionOne,
+			BlockStakeOutputs: []BlockStakeOutput{{Value: NewCurrency64(1), Condition: timeLockedMultiSigCondition}},
+		}, 19, ErrConditionTypeNotActivated},
+		{Transaction{
+			Version:           TransactionVersionOne,
+			BlockStakeOutputs: []BlockStakeOutput{{Value: NewCurrency64(1), Condition: timeLockedMultiSigCondition}},
+		}, 20, nil},
+		{Transaction{
+			Version:    TransactionVersionOne,
+			CoinInputs: []CoinInput{{Fulfillment: multiSigFulfillment}},
+		}, 29, ErrFulfillmentTypeNotActivated},
+		{Transaction{
+			Version:          TransactionVersionOne,
+			BlockStakeInputs: []BlockStakeInput{{Fulfillment: multiSigFulfillment}},
+		}, 30, nil},
+		{Transaction{
+			Version:    TransactionVersionOne,
+			CoinInputs: []CoinInput{{Fulfillment: secp256k1Fulfillment}},
+		}, 39, ErrSignatureAlgorithmNotActivated},
+		{Transaction{
+			Version:          TransactionVersionOne,
+			BlockStakeInputs: []BlockStakeInput{{Fulfillment: secp256k1MultiSigFulfillment}},
+		}, 39, ErrSignatureAlgorithmNotActivated},
+		{Transaction{
+			Version:    TransactionVersionOne,
+			CoinInputs: []CoinInput{{Fulfillment: secp256k1Fulfillment}},
+		}, 40, nil},
+	}
+	for idx, testCase := range testCases {
+		err := as.ValidateTransaction(testCase.Transaction, testCase.Height)
+		if err != testCase.Error {
+			t.Errorf("unexpected result for test case #%d: %v != %v", idx, err, testCase.Error)
+		}
+	}
+}
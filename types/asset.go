@@ -0,0 +1,48 @@
+package types
+
+import (
+	"github.com/threefoldtech/rivine/crypto"
+)
+
+// AssetID uniquely identifies an asset, a token that lives next to the native coin of a chain,
+// using coin outputs just like the native coin does. Which coin outputs hold an asset
+// is defined by the transaction that creates them, see CommonTransactionExtensionData.
+//
+// The zero AssetID identifies the native coin of the chain.
+type AssetID crypto.Hash
+
+// IsNative returns true if the ID identifies the native coin of the chain,
+// rather than an asset.
+func (id AssetID) IsNative() bool {
+	return id == AssetID{}
+}
+
+// String prints the asset ID in hex.
+func (id AssetID) String() string {
+	return crypto.Hash(id).String()
+}
+
+// LoadString loads the given asset ID from a hex string.
+func (id *AssetID) LoadString(str string) error {
+	return (*crypto.Hash)(id).LoadString(str)
+}
+
+// MarshalText marshals the asset ID as a hex string,
+// such that it can be used as a JSON value as well as a JSON map key.
+func (id AssetID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText decodes the hex string of the asset ID.
+func (id *AssetID) UnmarshalText(b []byte) error {
+	return id.LoadString(string(b))
+}
+
+// CoinOutputAsset returns the ID of the asset held by the coin output at the given index,
+// the zero AssetID (the native coin) in case no asset is defined for that coin output.
+func (ced CommonTransactionExtensionData) CoinOutputAsset(index int) AssetID {
+	if index < 0 || index >= len(ced.CoinOutputAssets) {
+		return AssetID{}
+	}
+	return ced.CoinOutputAssets[index]
+}
@@ -0,0 +1,70 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAssetIDStringAndJSON(t *testing.T) {
+	id := AssetID{1, 2, 3}
+	if id.IsNative() {
+		t.Fatal("expected a non-zero asset ID to not be native")
+	}
+	if !(AssetID{}).IsNative() {
+		t.Fatal("expected the zero asset ID to be native")
+	}
+
+	var loaded AssetID
+	err := loaded.LoadString(id.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded != id {
+		t.Fatalf("unexpected asset ID: %s != %s", loaded.String(), id.String())
+	}
+	if err = loaded.LoadString("foo"); err == nil {
+		t.Fatal("expected an error for an invalid asset ID string")
+	}
+
+	// asset IDs can be used as JSON values as well as JSON map keys
+	balances := map[AssetID]Currency{id: NewCurrency64(42)}
+	b, err := json.Marshal(balances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"` + id.String() + `":"42"}`; string(b) != expected {
+		t.Fatalf("unexpected JSON: %s != %s", string(b), expected)
+	}
+	var decodedBalances map[AssetID]Currency
+	err = json.Unmarshal(b, &decodedBalances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance := decodedBalances[id]; !balance.Equals64(42) {
+		t.Fatalf("unexpected balance: %s", balance.String())
+	}
+}
+
+func TestCommonTransactionExtensionDataCoinOutputAsset(t *testing.T) {
+	id := AssetID{4, 2}
+	data := CommonTransactionExtensionData{
+		CoinOutputAssets: []AssetID{{}, id},
+	}
+	testCases := []struct {
+		Index   int
+		AssetID AssetID
+	}{
+		{-1, AssetID{}},
+		{0, AssetID{}},
+		{1, id},
+		{2, AssetID{}},
+	}
+	for _, testCase := range testCases {
+		if assetID := data.CoinOutputAsset(testCase.Index); assetID != testCase.AssetID {
+			t.Errorf("#%d: unexpected asset ID: %s != %s", testCase.Index, assetID.String(), testCase.AssetID.String())
+		}
+	}
+	if assetID := (CommonTransactionExtensionData{}).CoinOutputAsset(0); !assetID.IsNative() {
+		t.Errorf("expected the native coin for data without asset IDs, got %s", assetID.String())
+	}
+}
@@ -1,9 +1,20 @@
 package types
 
 import (
+	"fmt"
+
 	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
 )
 
+// SpecifierChainID is used as a prefix when computing the ID of a chain.
+var SpecifierChainID = Specifier{'c', 'h', 'a', 'i', 'n', ' ', 'i', 'd'}
+
+// ChainID uniquely identifies a blockchain network,
+// and is committed to by the signatures of TransactionVersionTwo transactions,
+// such that these cannot be replayed on any other chain.
+type ChainID crypto.Hash
+
 // BlockchainInfo contains information about a blockchain.
 type BlockchainInfo struct {
 	Name            string
@@ -11,6 +22,9 @@ type BlockchainInfo struct {
 	CoinUnit        string
 	ChainVersion    build.ProtocolVersion
 	ProtocolVersion build.ProtocolVersion
+	// AddressPrefix is the human-readable prefix
+	// of bech32-encoded unlock hashes (addresses) of this chain.
+	AddressPrefix string
 }
 
 // DefaultNetworkName returns a sane default network name,
@@ -42,5 +56,43 @@ func DefaultBlockchainInfo() BlockchainInfo {
 		CoinUnit:        "ROC",
 		ChainVersion:    build.Version,
 		ProtocolVersion: build.Version,
+		AddressPrefix:   "riv",
 	}
 }
+
+// ChainID computes the ID of the blockchain network,
+// identified by its name, network name and the ID of its genesis block.
+// The (chain and protocol) versions are not part of the chain ID,
+// as these are expected to change over the lifetime of a chain.
+func (bi BlockchainInfo) ChainID(genesisID BlockID) ChainID {
+	hash, err := crypto.HashAll(
+		SpecifierChainID,
+		bi.Name,
+		bi.NetworkName,
+		genesisID,
+	)
+	if err != nil {
+		build.Severe("failed to crypto hash the blockchain info and genesis block ID as a chain ID", err)
+	}
+	return ChainID(hash)
+}
+
+// String prints the chain id in hex.
+func (cid ChainID) String() string {
+	return fmt.Sprintf("%x", cid[:])
+}
+
+// LoadString loads the chain id from a hex string.
+func (cid *ChainID) LoadString(str string) error {
+	return (*crypto.Hash)(cid).LoadString(str)
+}
+
+// MarshalJSON marshals a chain id as a hex string.
+func (cid ChainID) MarshalJSON() ([]byte, error) {
+	return crypto.Hash(cid).MarshalJSON()
+}
+
+// UnmarshalJSON decodes the json hex string of the chain id.
+func (cid *ChainID) UnmarshalJSON(b []byte) error {
+	return (*crypto.Hash)(cid).UnmarshalJSON(b)
+}
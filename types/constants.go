@@ -89,8 +89,19 @@ type ChainConstants struct {
 	// for all to be created transactions. It does not impact how transactions are validated or understood.
 	DefaultTransactionVersion TransactionVersion
 
+	// ActivationSchedule defines the block heights from which
+	// transaction versions, unlock condition types and unlock fulfillment types are active.
+	// Versions and types not defined in this schedule are active from the genesis block onwards.
+	ActivationSchedule ActivationSchedule
+
 	CurrencyUnits CurrencyUnits
 
+	// UnlockHashEncoding defines the format used to represent
+	// unlock hashes (addresses) as strings, by the API and CLI client.
+	// The bech32 encoding uses the AddressPrefix of the BlockchainInfo as human-readable part.
+	// Both formats are always accepted as input.
+	UnlockHashEncoding UnlockHashEncoding
+
 	TransactionPool TransactionPoolConstants
 }
 
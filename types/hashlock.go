@@ -0,0 +1,253 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// HashLockType defines the hash function used by a HashLockCondition,
+// in order to compute the hash of the pre-image revealed by its fulfillment.
+type HashLockType uint8
+
+const (
+	// HashLockTypeNil identifies a nil HashLockType value,
+	// it is not a valid hash function.
+	HashLockTypeNil HashLockType = iota
+	// HashLockTypeSHA256 identifies the SHA-256 hash function,
+	// the same hash function as used by the AtomicSwapCondition.
+	HashLockTypeSHA256
+	// HashLockTypeBlake2b identifies the BLAKE2b-256 hash function,
+	// the same hash function as used for all other hashes within Rivine.
+	HashLockTypeBlake2b
+	// HashLockTypeKeccak256 identifies the (legacy) Keccak-256 hash function,
+	// as used by Ethereum. Note that it differs from the standardized SHA3-256.
+	HashLockTypeKeccak256
+)
+
+// The limits which apply to a standard HashLockFulfillment.
+const (
+	// HashLockMaxPreimageSize defines the maximum size
+	// of the pre-image revealed by a standard hash lock fulfillment.
+	HashLockMaxPreimageSize = 256
+)
+
+// Errors returned by hash lock conditions and fulfillments.
+var (
+	// ErrUnknownHashLockType is returned for a hash lock condition with an unknown hash function.
+	ErrUnknownHashLockType = errors.New("unknown hash lock type")
+	// ErrInvalidPreImage is returned as the result of a failed fulfillment,
+	// in case the hash of the fulfillment-defined pre-image does not match
+	// the condition-defined hash.
+	ErrInvalidPreImage = errors.New("invalid pre-image")
+)
+
+type (
+	// HashLockCondition implements the ConditionTypeHashLock ConditionType.
+	// See ConditionTypeHashLock for more information.
+	HashLockCondition struct {
+		// HashType defines the hash function used to compute the hash of the pre-image.
+		HashType HashLockType `json:"hashtype"`
+		// Hash defines the hash of the pre-image that has to be revealed.
+		Hash crypto.Hash `json:"hash"`
+	}
+
+	// HashLockFulfillment implements the FulfillmentTypeHashLock FulfillmentType.
+	// See FulfillmentTypeHashLock for more information.
+	HashLockFulfillment struct {
+		// Preimage defines the pre-image of the hash defined by the condition.
+		Preimage ByteSlice `json:"preimage"`
+	}
+)
+
+var (
+	_ MarshalableUnlockCondition   = (*HashLockCondition)(nil)
+	_ MarshalableUnlockFulfillment = (*HashLockFulfillment)(nil)
+)
+
+// String returns the hash lock type as a string.
+func (t HashLockType) String() string {
+	switch t {
+	case HashLockTypeSHA256:
+		return "sha256"
+	case HashLockTypeBlake2b:
+		return "blake2b"
+	case HashLockTypeKeccak256:
+		return "keccak256"
+	default:
+		return ""
+	}
+}
+
+// LoadString loads the hash lock type from its string representation.
+func (t *HashLockType) LoadString(str string) error {
+	switch str {
+	case "sha256":
+		*t = HashLockTypeSHA256
+	case "blake2b":
+		*t = HashLockTypeBlake2b
+	case "keccak256":
+		*t = HashLockTypeKeccak256
+	default:
+		return fmt.Errorf("unknown HashLockType string: %s", str)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON
+func (t HashLockType) MarshalJSON() ([]byte, error) {
+	str := t.String()
+	if str == "" {
+		return nil, ErrUnknownHashLockType
+	}
+	return json.Marshal(str)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON
+func (t *HashLockType) UnmarshalJSON(b []byte) error {
+	var str string
+	err := json.Unmarshal(b, &str)
+	if err != nil {
+		return err
+	}
+	return t.LoadString(str)
+}
+
+// Sum computes the hash of the given pre-image,
+// using the hash function identified by this hash lock type.
+func (t HashLockType) Sum(preimage []byte) (crypto.Hash, error) {
+	switch t {
+	case HashLockTypeSHA256:
+		return crypto.Hash(sha256.Sum256(preimage)), nil
+	case HashLockTypeBlake2b:
+		return crypto.Hash(blake2b.Sum256(preimage)), nil
+	case HashLockTypeKeccak256:
+		var h crypto.Hash
+		hasher := sha3.NewLegacyKeccak256()
+		hasher.Write(preimage)
+		hasher.Sum(h[:0])
+		return h, nil
+	default:
+		return crypto.Hash{}, ErrUnknownHashLockType
+	}
+}
+
+// NewHashLockCondition creates a new hash lock condition,
+// computing the hash of the given pre-image using the given hash function.
+func NewHashLockCondition(hashType HashLockType, preimage []byte) (*HashLockCondition, error) {
+	h, err := hashType.Sum(preimage)
+	if err != nil {
+		return nil, err
+	}
+	return &HashLockCondition{HashType: hashType, Hash: h}, nil
+}
+
+// Fulfill implements UnlockCondition.Fulfill
+//
+// The hash of the pre-image, defined by the given fulfillment,
+// has to match the hash defined by this condition.
+func (hl *HashLockCondition) Fulfill(fulfillment UnlockFulfillment, ctx FulfillContext) error {
+	hf, ok := fulfillment.(*HashLockFulfillment)
+	if !ok {
+		return ErrUnexpectedUnlockFulfillment
+	}
+	h, err := hl.HashType.Sum(hf.Preimage)
+	if err != nil {
+		return err
+	}
+	if h != hl.Hash {
+		return ErrInvalidPreImage
+	}
+	return nil
+}
+
+// ConditionType implements UnlockCondition.ConditionType
+func (hl *HashLockCondition) ConditionType() ConditionType { return ConditionTypeHashLock }
+
+// IsStandardCondition implements UnlockCondition.IsStandardCondition
+func (hl *HashLockCondition) IsStandardCondition(ValidationContext) error {
+	if hl.HashType.String() == "" {
+		return ErrUnknownHashLockType
+	}
+	if hl.Hash == (crypto.Hash{}) {
+		return errors.New("nil hash not allowed")
+	}
+	return nil
+}
+
+// UnlockHash implements UnlockCondition.UnlockHash
+func (hl *HashLockCondition) UnlockHash() UnlockHash {
+	cb, _ := hl.Marshal(siabin.MarshalAll)
+	h, _ := crypto.HashObject(cb)
+	return NewUnlockHash(UnlockTypeHashLock, h)
+}
+
+// Equal implements UnlockCondition.Equal
+func (hl *HashLockCondition) Equal(c UnlockCondition) bool {
+	ohl, ok := c.(*HashLockCondition)
+	if !ok {
+		return false
+	}
+	return hl.HashType == ohl.HashType && hl.Hash == ohl.Hash
+}
+
+// Fulfillable implements UnlockCondition.Fulfillable
+func (hl *HashLockCondition) Fulfillable(FulfillableContext) bool { return true }
+
+// Marshal implements MarshalableUnlockCondition.Marshal
+func (hl *HashLockCondition) Marshal(f MarshalFunc) ([]byte, error) {
+	return f(hl.HashType, hl.Hash)
+}
+
+// Unmarshal implements MarshalableUnlockCondition.Unmarshal
+func (hl *HashLockCondition) Unmarshal(b []byte, f UnmarshalFunc) error {
+	return f(b, &hl.HashType, &hl.Hash)
+}
+
+// Sign implements UnlockFulfillment.Sign
+//
+// A hash lock fulfillment only reveals a pre-image,
+// and thus has nothing to sign.
+func (hf *HashLockFulfillment) Sign(FulfillmentSignContext) error {
+	return nil
+}
+
+// FulfillmentType implements UnlockFulfillment.FulfillmentType
+func (hf *HashLockFulfillment) FulfillmentType() FulfillmentType { return FulfillmentTypeHashLock }
+
+// IsStandardFulfillment implements UnlockFulfillment.IsStandardFulfillment
+func (hf *HashLockFulfillment) IsStandardFulfillment(ValidationContext) error {
+	if len(hf.Preimage) == 0 {
+		return errors.New("nil pre-image not allowed")
+	}
+	if len(hf.Preimage) > HashLockMaxPreimageSize {
+		return fmt.Errorf("pre-image is %d bytes, while at most %d bytes are allowed", len(hf.Preimage), HashLockMaxPreimageSize)
+	}
+	return nil
+}
+
+// Equal implements UnlockFulfillment.Equal
+func (hf *HashLockFulfillment) Equal(f UnlockFulfillment) bool {
+	ohf, ok := f.(*HashLockFulfillment)
+	if !ok {
+		return false
+	}
+	return string(hf.Preimage) == string(ohf.Preimage)
+}
+
+// Marshal implements MarshalableUnlockFulfillment.Marshal
+func (hf *HashLockFulfillment) Marshal(f MarshalFunc) ([]byte, error) {
+	return f(hf.Preimage)
+}
+
+// Unmarshal implements MarshalableUnlockFulfillment.Unmarshal
+func (hf *HashLockFulfillment) Unmarshal(b []byte, f UnmarshalFunc) error {
+	return f(b, &hf.Preimage)
+}
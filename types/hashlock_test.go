@@ -0,0 +1,334 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+)
+
+func newTestHashLockCondition(t *testing.T, hashType HashLockType, preimage string) *HashLockCondition {
+	condition, err := NewHashLockCondition(hashType, []byte(preimage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return condition
+}
+
+func TestHashLockTypeSum(t *testing.T) {
+	testCases := []struct {
+		HashType HashLockType
+		Expected string
+	}{
+		{HashLockTypeSHA256, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{HashLockTypeBlake2b, "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319"},
+		{HashLockTypeKeccak256, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	}
+	for idx, testCase := range testCases {
+		h, err := testCase.HashType.Sum([]byte("abc"))
+		if err != nil {
+			t.Errorf("test case #%d (%s): %v", idx, testCase.HashType, err)
+			continue
+		}
+		if h.String() != testCase.Expected {
+			t.Errorf("test case #%d (%s): unexpected hash: %s != %s", idx, testCase.HashType, h.String(), testCase.Expected)
+		}
+	}
+	if _, err := HashLockTypeNil.Sum([]byte("abc")); err != ErrUnknownHashLockType {
+		t.Errorf("unexpected error for nil hash lock type: %v", err)
+	}
+}
+
+func TestHashLockTypeString(t *testing.T) {
+	for _, hashType := range []HashLockType{HashLockTypeSHA256, HashLockTypeBlake2b, HashLockTypeKeccak256} {
+		var other HashLockType
+		err := other.LoadString(hashType.String())
+		if err != nil {
+			t.Errorf("failed to load %s: %v", hashType, err)
+		} else if other != hashType {
+			t.Errorf("unexpected hash lock type: %d != %d", other, hashType)
+		}
+	}
+	var hashType HashLockType
+	if err := hashType.LoadString("sha3"); err == nil {
+		t.Error("expected an error for an unknown hash lock type")
+	}
+	if _, err := json.Marshal(HashLockTypeNil); err == nil {
+		t.Error("expected an error when JSON-encoding a nil hash lock type")
+	}
+}
+
+func TestHashLockEncoding(t *testing.T) {
+	condition := NewCondition(newTestHashLockCondition(t, HashLockTypeKeccak256, "secret"))
+	fulfillment := NewFulfillment(&HashLockFulfillment{Preimage: []byte("secret")})
+
+	// JSON
+	b, err := json.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(b, []byte(`"hashtype":"keccak256"`)) {
+		t.Errorf("unexpected JSON-encoded hash lock condition: %s", b)
+	}
+	var jsonCondition UnlockConditionProxy
+	err = json.Unmarshal(b, &jsonCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(jsonCondition) {
+		t.Errorf("JSON: %v != %v", condition, jsonCondition)
+	}
+	b, err = json.Marshal(fulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jsonFulfillment UnlockFulfillmentProxy
+	err = json.Unmarshal(b, &jsonFulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfillment.Equal(jsonFulfillment) {
+		t.Errorf("JSON: %v != %v", fulfillment, jsonFulfillment)
+	}
+
+	// rivine binary
+	b, err = rivbin.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rivineCondition UnlockConditionProxy
+	err = rivbin.Unmarshal(b, &rivineCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(rivineCondition) {
+		t.Errorf("rivbin: %v != %v", condition, rivineCondition)
+	}
+	b, err = rivbin.Marshal(fulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rivineFulfillment UnlockFulfillmentProxy
+	err = rivbin.Unmarshal(b, &rivineFulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfillment.Equal(rivineFulfillment) {
+		t.Errorf("rivbin: %v != %v", fulfillment, rivineFulfillment)
+	}
+
+	// sia binary
+	b, err = siabin.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var siaCondition UnlockConditionProxy
+	err = siabin.Unmarshal(b, &siaCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(siaCondition) {
+		t.Errorf("siabin: %v != %v", condition, siaCondition)
+	}
+}
+
+func TestHashLockConditionUnlockHash(t *testing.T) {
+	sha := newTestHashLockCondition(t, HashLockTypeSHA256, "secret")
+	keccak := newTestHashLockCondition(t, HashLockTypeKeccak256, "secret")
+	if uh := sha.UnlockHash(); uh.Type != UnlockTypeHashLock {
+		t.Errorf("unexpected unlock type: %d", uh.Type)
+	}
+	if sha.UnlockHash().Cmp(newTestHashLockCondition(t, HashLockTypeSHA256, "secret").UnlockHash()) != 0 {
+		t.Error("expected equal hash lock conditions to have the same unlock hash")
+	}
+	if sha.UnlockHash().Cmp(keccak.UnlockHash()) == 0 {
+		t.Error("expected hash lock conditions using different hash functions to have different unlock hashes")
+	}
+	// the hash type is part of the unlock hash, even if the hash is the same
+	other := *sha
+	other.HashType = HashLockTypeBlake2b
+	if sha.UnlockHash().Cmp(other.UnlockHash()) == 0 {
+		t.Error("expected the hash type to be part of the unlock hash")
+	}
+}
+
+func TestHashLockConditionFulfill(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	txn := Transaction{
+		Version:       TransactionVersionOne,
+		ArbitraryData: []byte("hashlock"),
+	}
+	ctx := FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockHeight:  100,
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+	}
+	preimage := &HashLockFulfillment{Preimage: []byte("secret")}
+
+	for _, hashType := range []HashLockType{HashLockTypeSHA256, HashLockTypeBlake2b, HashLockTypeKeccak256} {
+		condition := newTestHashLockCondition(t, hashType, "secret")
+		if err := condition.Fulfill(preimage, ctx); err != nil {
+			t.Errorf("%s: expected the pre-image to fulfill the condition: %v", hashType, err)
+		}
+		if err := condition.Fulfill(&HashLockFulfillment{Preimage: []byte("Secret")}, ctx); err != ErrInvalidPreImage {
+			t.Errorf("%s: unexpected error for an invalid pre-image: %v", hashType, err)
+		}
+		if err := condition.Fulfill(k1.sign(t, txn).Fulfillment, ctx); err != ErrUnexpectedUnlockFulfillment {
+			t.Errorf("%s: unexpected error for a signature fulfillment: %v", hashType, err)
+		}
+	}
+
+	// a hash lock can be time locked
+	condition := newTestHashLockCondition(t, HashLockTypeBlake2b, "secret")
+	if err := NewTimeLockCondition(42, condition).Fulfill(preimage, ctx); err != nil {
+		t.Errorf("expected a time locked hash lock to be fulfilled: %v", err)
+	}
+	if err := NewTimeLockCondition(142, condition).Fulfill(preimage, ctx); err == nil {
+		t.Error("expected a hash lock to be locked until the time lock is reached")
+	}
+	if err := NewRelativeTimeLockCondition(10, condition).Fulfill(preimage, FulfillContext{
+		BlockHeight:        100,
+		ConfirmationHeight: 90,
+		Transaction:        txn,
+	}); err != nil {
+		t.Errorf("expected a relative time locked hash lock to be fulfilled: %v", err)
+	}
+
+	// a hash lock can be combined with a multisig condition
+	policy := NewPolicyCondition(PolicyOperatorAnd, 0,
+		condition,
+		&MultiSignatureCondition{UnlockHashes: UnlockHashSlice{k1.uh, k2.uh}, MinimumSignatureCount: 2},
+	)
+	ms := &MultiSignatureFulfillment{}
+	k1.signMultiSig(t, txn, ms)
+	k2.signMultiSig(t, txn, ms)
+	err := policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{
+			{Index: 0, Fulfillment: NewFulfillment(preimage)},
+			{Index: 1, Fulfillment: NewFulfillment(ms)},
+		},
+	}, ctx)
+	if err != nil {
+		t.Errorf("expected the hash lock and multisig to fulfill the policy: %v", err)
+	}
+	err = policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{{Index: 1, Fulfillment: NewFulfillment(ms)}},
+	}, ctx)
+	if err == nil {
+		t.Error("expected the policy to require the pre-image")
+	}
+}
+
+func TestHashLockIsStandard(t *testing.T) {
+	ctx := ValidationContext{}
+	valid := newTestHashLockCondition(t, HashLockTypeKeccak256, "secret")
+	if err := valid.IsStandardCondition(ctx); err != nil {
+		t.Errorf("expected hash lock condition to be standard: %v", err)
+	}
+	if err := NewTimeLockCondition(42, valid).IsStandardCondition(ctx); err != nil {
+		t.Errorf("expected time locked hash lock condition to be standard: %v", err)
+	}
+	for idx, condition := range []*HashLockCondition{
+		{HashType: HashLockTypeNil, Hash: valid.Hash},
+		{HashType: HashLockType(42), Hash: valid.Hash},
+		{HashType: HashLockTypeSHA256},
+	} {
+		if err := condition.IsStandardCondition(ctx); err == nil {
+			t.Errorf("condition #%d: expected hash lock condition to be non-standard", idx)
+		}
+	}
+
+	for idx, fulfillment := range []*HashLockFulfillment{
+		{Preimage: []byte{1}},
+		{Preimage: make([]byte, HashLockMaxPreimageSize)},
+	} {
+		if err := fulfillment.IsStandardFulfillment(ctx); err != nil {
+			t.Errorf("fulfillment #%d: expected hash lock fulfillment to be standard: %v", idx, err)
+		}
+	}
+	for idx, fulfillment := range []*HashLockFulfillment{
+		{},
+		{Preimage: make([]byte, HashLockMaxPreimageSize+1)},
+	} {
+		if err := fulfillment.IsStandardFulfillment(ctx); err == nil {
+			t.Errorf("fulfillment #%d: expected hash lock fulfillment to be non-standard", idx)
+		}
+	}
+}
+
+// TestHashLockMultiSignaturePolicy probes that a hash lock cannot be one of
+// the signers of a multisig condition, and that a policy condition is to be used instead.
+func TestHashLockMultiSignaturePolicy(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	hashLock := newTestHashLockCondition(t, HashLockTypeSHA256, "secret")
+	validationCtx := ValidationContext{Confirmed: true}
+
+	// multisig conditions only accept public key unlock hashes
+	for idx, condition := range []MarshalableUnlockCondition{
+		&MultiSignatureCondition{
+			UnlockHashes:          UnlockHashSlice{k1.uh, hashLock.UnlockHash()},
+			MinimumSignatureCount: 2,
+		},
+		&WeightedMultiSignatureCondition{
+			Signatories:   []WeightedUnlockHash{{UnlockHash: k1.uh, Weight: 1}, {UnlockHash: hashLock.UnlockHash(), Weight: 1}},
+			MinimumWeight: 2,
+		},
+	} {
+		if err := condition.IsStandardCondition(validationCtx); err == nil {
+			t.Errorf("#%d: expected multisig condition with a hash lock signer to be non-standard", idx)
+		}
+	}
+
+	// AND(hash lock, 2-of-2 multisig)
+	policy := NewPolicyCondition(PolicyOperatorAnd, 0,
+		hashLock,
+		&MultiSignatureCondition{UnlockHashes: UnlockHashSlice{k1.uh, k2.uh}, MinimumSignatureCount: 2},
+	)
+	if err := policy.IsStandardCondition(validationCtx); err != nil {
+		t.Fatalf("expected hash locked multisig policy to be standard: %v", err)
+	}
+	txn := Transaction{
+		Version:       TransactionVersionOne,
+		ArbitraryData: []byte("hashlock"),
+	}
+	ctx := FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockHeight:  100,
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+	}
+	ms := &MultiSignatureFulfillment{}
+	k1.signMultiSig(t, txn, ms)
+	k2.signMultiSig(t, txn, ms)
+	for idx, tc := range []struct {
+		preimage string
+		valid    bool
+	}{
+		{"secret", true},
+		{"guess", false},
+	} {
+		err := policy.Fulfill(&PolicyFulfillment{
+			Branches: []PolicyBranchFulfillment{
+				{Index: 0, Fulfillment: NewFulfillment(&HashLockFulfillment{Preimage: []byte(tc.preimage)})},
+				{Index: 1, Fulfillment: NewFulfillment(ms)},
+			},
+		}, ctx)
+		if tc.valid && err != nil {
+			t.Errorf("#%d: expected policy to be fulfilled: %v", idx, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("#%d: expected policy fulfillment to fail", idx)
+		}
+	}
+	// the pre-image alone does not suffice
+	err := policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{
+			{Index: 0, Fulfillment: NewFulfillment(&HashLockFulfillment{Preimage: []byte("secret")})},
+		},
+	}, ctx)
+	if err == nil {
+		t.Error("expected policy fulfillment without signatures to fail")
+	}
+}
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+)
+
+var (
+	// ErrInvalidMerkleProof is returned in case a merkle proof does not prove
+	// that the given transaction is part of the block, identified by the given block header.
+	ErrInvalidMerkleProof = errors.New("invalid merkle proof: transaction is not part of the block")
+)
+
+// TransactionMerkleProof is a merkle proof which can be used to prove
+// that a transaction is included in the block identified by the given block header,
+// without requiring the full block, as described in specs/spv.md.
+type TransactionMerkleProof struct {
+	// BlockHeader of the block the transaction is part of,
+	// the block ID can be computed from it.
+	BlockHeader BlockHeader `json:"blockheader"`
+	// LeafIndex is the index of the transaction within the leaves of the merkle tree,
+	// which are composed of the miner payouts followed by the transactions of the block.
+	LeafIndex uint64 `json:"leafindex"`
+	// LeafCount is the total amount of leaves of the merkle tree.
+	LeafCount uint64 `json:"leafcount"`
+	// HashSet is the merkle path from the transaction leaf up to the merkle root of the block.
+	HashSet []crypto.Hash `json:"hashset"`
+}
+
+// TransactionMerkleProof creates a merkle proof for the transaction found
+// at the given index (sequence ID) within the block.
+func (b Block) TransactionMerkleProof(index int) (TransactionMerkleProof, error) {
+	if index < 0 || index >= len(b.Transactions) {
+		return TransactionMerkleProof{}, fmt.Errorf(
+			"cannot create merkle proof: transaction index %d is out of range for block %s with %d transactions",
+			index, b.ID().String(), len(b.Transactions))
+	}
+
+	tree := crypto.NewTree()
+	err := tree.SetIndex(uint64(len(b.MinerPayouts) + index))
+	if err != nil {
+		return TransactionMerkleProof{}, err
+	}
+	for _, payout := range b.MinerPayouts {
+		err = tree.PushObject(payout)
+		if err != nil {
+			return TransactionMerkleProof{}, err
+		}
+	}
+	for _, txn := range b.Transactions {
+		err = tree.PushObject(txn)
+		if err != nil {
+			return TransactionMerkleProof{}, err
+		}
+	}
+
+	root, proofSet, proofIndex, numLeaves := tree.Prove()
+	proof := TransactionMerkleProof{
+		BlockHeader: BlockHeader{
+			ParentID:   b.ParentID,
+			POBSOutput: b.POBSOutput,
+			Timestamp:  b.Timestamp,
+		},
+		LeafIndex: proofIndex,
+		LeafCount: numLeaves,
+		HashSet:   make([]crypto.Hash, len(proofSet)-1),
+	}
+	copy(proof.BlockHeader.MerkleRoot[:], root)
+	for i, h := range proofSet[1:] {
+		copy(proof.HashSet[i][:], h)
+	}
+	return proof, nil
+}
+
+// VerifyTransactionMerkleProof verifies that the given transaction is part of the block
+// identified by the block header of the given merkle proof, returning ErrInvalidMerkleProof if it isn't.
+// It is up to the caller to verify that the block header (ID) is part of the blockchain it trusts.
+func VerifyTransactionMerkleProof(txn Transaction, proof TransactionMerkleProof) error {
+	if proof.LeafIndex >= proof.LeafCount {
+		return ErrInvalidMerkleProof
+	}
+	base, err := siabin.Marshal(txn)
+	if err != nil {
+		return fmt.Errorf("failed to (siabin) marshal transaction: %v", err)
+	}
+	if !crypto.VerifySegment(base, proof.HashSet, proof.LeafCount, proof.LeafIndex, proof.BlockHeader.MerkleRoot) {
+		return ErrInvalidMerkleProof
+	}
+	return nil
+}
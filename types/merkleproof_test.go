@@ -0,0 +1,51 @@
+package types
+
+import (
+	"testing"
+)
+
+func TestTransactionMerkleProof(t *testing.T) {
+	var b Block
+	b.ParentID[1] = 1
+	b.Timestamp = 3
+	b.MinerPayouts = []MinerPayout{{Value: NewCurrency64(4)}, {Value: NewCurrency64(2)}}
+	for i := 0; i < 5; i++ {
+		b.Transactions = append(b.Transactions, Transaction{
+			Version:       TestnetChainConstants().DefaultTransactionVersion,
+			ArbitraryData: []byte{byte(i)},
+		})
+	}
+
+	for index, txn := range b.Transactions {
+		proof, err := b.TransactionMerkleProof(index)
+		if err != nil {
+			t.Fatal(index, err)
+		}
+		if proof.BlockHeader.ID() != b.ID() {
+			t.Fatal(index, "block header of proof does not identify the block")
+		}
+		err = VerifyTransactionMerkleProof(txn, proof)
+		if err != nil {
+			t.Error(index, err)
+		}
+
+		// a proof for one transaction shouldn't prove another transaction
+		otherTxn := b.Transactions[(index+1)%len(b.Transactions)]
+		err = VerifyTransactionMerkleProof(otherTxn, proof)
+		if err != ErrInvalidMerkleProof {
+			t.Error(index, "unexpected error:", err)
+		}
+
+		// a modified merkle path should no longer prove the transaction
+		proof.HashSet[0][0]++
+		err = VerifyTransactionMerkleProof(txn, proof)
+		if err != ErrInvalidMerkleProof {
+			t.Error(index, "unexpected error:", err)
+		}
+	}
+
+	_, err := b.TransactionMerkleProof(len(b.Transactions))
+	if err == nil {
+		t.Error("expected an error for an out of range transaction index")
+	}
+}
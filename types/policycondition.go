@@ -0,0 +1,432 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+)
+
+// PolicyOperator defines the operator of a PolicyCondition,
+// defining how many of its (sub) conditions have to be fulfilled.
+type PolicyOperator uint8
+
+const (
+	// PolicyOperatorNil identifies a nil PolicyOperator value,
+	// it is not a valid operator.
+	PolicyOperatorNil PolicyOperator = iota
+	// PolicyOperatorAnd requires all sub conditions to be fulfilled.
+	PolicyOperatorAnd
+	// PolicyOperatorOr requires at least one sub condition to be fulfilled.
+	PolicyOperatorOr
+	// PolicyOperatorThreshold requires at least the threshold (k)
+	// amount of sub conditions (n) to be fulfilled.
+	PolicyOperatorThreshold
+)
+
+// The limits which apply to a standard PolicyCondition (and PolicyFulfillment).
+const (
+	// PolicyConditionMaxDepth defines the maximum amount of levels
+	// of (nested) policy conditions, the root policy condition included.
+	PolicyConditionMaxDepth = 4
+	// PolicyConditionMaxBranches defines the maximum amount of
+	// sub conditions a single policy condition can have.
+	PolicyConditionMaxBranches = 16
+	// PolicyConditionMaxSize defines the maximum size
+	// of a (rivine) binary encoded policy condition, nested conditions included.
+	PolicyConditionMaxSize = 4096
+)
+
+// Errors returned by policy conditions and fulfillments.
+var (
+	// ErrUnsatisfiedPolicy is returned when a policy condition is fulfilled
+	// using a fulfillment which doesn't define enough (fulfilled) branches.
+	ErrUnsatisfiedPolicy = errors.New("not enough policy branches fulfilled")
+	// ErrUnknownPolicyOperator is returned for a policy condition with an unknown operator.
+	ErrUnknownPolicyOperator = errors.New("unknown policy operator")
+	// ErrPolicyFulfillmentSign is returned when a policy fulfillment is signed directly,
+	// as it can only be signed per branch, using the sub condition of that branch.
+	ErrPolicyFulfillmentSign = errors.New("policy fulfillment can only be signed per branch")
+)
+
+type (
+	// PolicyCondition implements the ConditionTypePolicy ConditionType.
+	// See ConditionTypePolicy for more information.
+	PolicyCondition struct {
+		// Operator defines how many of the sub conditions have to be fulfilled.
+		Operator PolicyOperator `json:"operator"`
+		// Threshold defines the amount of sub conditions which have to be fulfilled,
+		// only used (and required) for the PolicyOperatorThreshold operator.
+		Threshold uint64 `json:"threshold,omitempty"`
+		// Conditions defines the sub conditions, the branches of this policy.
+		Conditions []UnlockConditionProxy `json:"conditions"`
+	}
+
+	// PolicyFulfillment implements the FulfillmentTypePolicy FulfillmentType.
+	// See FulfillmentTypePolicy for more information.
+	PolicyFulfillment struct {
+		// Branches defines the fulfilled branches,
+		// ordered by index in ascending order.
+		Branches []PolicyBranchFulfillment `json:"branches"`
+	}
+
+	// PolicyBranchFulfillment fulfills a single branch of a policy condition.
+	PolicyBranchFulfillment struct {
+		// Index of the sub condition fulfilled by this branch.
+		Index uint64 `json:"index"`
+		// Fulfillment of the sub condition identified by the index.
+		Fulfillment UnlockFulfillmentProxy `json:"fulfillment"`
+	}
+)
+
+var (
+	_ MarshalableUnlockCondition   = (*PolicyCondition)(nil)
+	_ MarshalableUnlockFulfillment = (*PolicyFulfillment)(nil)
+)
+
+// String returns the policy operator as a string.
+func (op PolicyOperator) String() string {
+	switch op {
+	case PolicyOperatorAnd:
+		return "and"
+	case PolicyOperatorOr:
+		return "or"
+	case PolicyOperatorThreshold:
+		return "threshold"
+	default:
+		return ""
+	}
+}
+
+// LoadString loads the policy operator from its string representation.
+func (op *PolicyOperator) LoadString(str string) error {
+	switch str {
+	case "and":
+		*op = PolicyOperatorAnd
+	case "or":
+		*op = PolicyOperatorOr
+	case "threshold":
+		*op = PolicyOperatorThreshold
+	default:
+		return fmt.Errorf("unknown PolicyOperator string: %s", str)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON
+func (op PolicyOperator) MarshalJSON() ([]byte, error) {
+	str := op.String()
+	if str == "" {
+		return nil, ErrUnknownPolicyOperator
+	}
+	return json.Marshal(str)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON
+func (op *PolicyOperator) UnmarshalJSON(b []byte) error {
+	var str string
+	err := json.Unmarshal(b, &str)
+	if err != nil {
+		return err
+	}
+	return op.LoadString(str)
+}
+
+// NewPolicyCondition creates a new PolicyCondition,
+// the threshold is only used for the PolicyOperatorThreshold operator.
+func NewPolicyCondition(op PolicyOperator, threshold uint64, conditions ...MarshalableUnlockCondition) *PolicyCondition {
+	p := &PolicyCondition{
+		Operator:   op,
+		Conditions: make([]UnlockConditionProxy, 0, len(conditions)),
+	}
+	if op == PolicyOperatorThreshold {
+		p.Threshold = threshold
+	}
+	for _, condition := range conditions {
+		p.Conditions = append(p.Conditions, NewCondition(condition))
+	}
+	return p
+}
+
+// RequiredBranchCount returns the minimum amount of branches
+// which have to be fulfilled in order to fulfill this policy condition.
+func (p *PolicyCondition) RequiredBranchCount() (uint64, error) {
+	switch p.Operator {
+	case PolicyOperatorAnd:
+		return uint64(len(p.Conditions)), nil
+	case PolicyOperatorOr:
+		return 1, nil
+	case PolicyOperatorThreshold:
+		return p.Threshold, nil
+	default:
+		return 0, ErrUnknownPolicyOperator
+	}
+}
+
+// Fulfill implements UnlockCondition.Fulfill
+//
+// Each branch defined by the given PolicyFulfillment has to fulfill
+// the sub condition it identifies, and enough branches have to be given
+// in order to satisfy the operator of this policy condition.
+func (p *PolicyCondition) Fulfill(fulfillment UnlockFulfillment, ctx FulfillContext) error {
+	pf, ok := fulfillment.(*PolicyFulfillment)
+	if !ok {
+		return ErrUnexpectedUnlockFulfillment
+	}
+	required, err := p.RequiredBranchCount()
+	if err != nil {
+		return err
+	}
+	if len(pf.Branches) == 0 || uint64(len(pf.Branches)) < required {
+		return ErrUnsatisfiedPolicy
+	}
+	for idx, branch := range pf.Branches {
+		if branch.Index >= uint64(len(p.Conditions)) {
+			return fmt.Errorf("policy branch #%d is out of range", branch.Index)
+		}
+		if idx > 0 && branch.Index <= pf.Branches[idx-1].Index {
+			return fmt.Errorf("policy branch #%d is not ordered in ascending order", branch.Index)
+		}
+		err = p.Conditions[branch.Index].Fulfill(branch.Fulfillment, ctx)
+		if err != nil {
+			return fmt.Errorf("policy branch #%d cannot be fulfilled: %v", branch.Index, err)
+		}
+	}
+	return nil
+}
+
+// ConditionType implements UnlockCondition.ConditionType
+func (p *PolicyCondition) ConditionType() ConditionType { return ConditionTypePolicy }
+
+// IsStandardCondition implements UnlockCondition.IsStandardCondition
+func (p *PolicyCondition) IsStandardCondition(ctx ValidationContext) error {
+	switch p.Operator {
+	case PolicyOperatorAnd, PolicyOperatorOr:
+		if p.Threshold != 0 {
+			return fmt.Errorf("threshold cannot be defined for the %s policy operator", p.Operator)
+		}
+	case PolicyOperatorThreshold:
+		if p.Threshold == 0 || p.Threshold > uint64(len(p.Conditions)) {
+			return fmt.Errorf("threshold has to be within the range [1, %d]", len(p.Conditions))
+		}
+	default:
+		return ErrUnknownPolicyOperator
+	}
+	if len(p.Conditions) < 2 {
+		return errors.New("at least two sub conditions have to be defined")
+	}
+	if len(p.Conditions) > PolicyConditionMaxBranches {
+		return fmt.Errorf("at most %d sub conditions can be defined", PolicyConditionMaxBranches)
+	}
+	if depth := p.depth(); depth > PolicyConditionMaxDepth {
+		return fmt.Errorf("policy conditions can be nested at most %d levels deep, not %d", PolicyConditionMaxDepth, depth)
+	}
+	b, err := p.Marshal(rivbin.MarshalAll)
+	if err != nil {
+		return err
+	}
+	if len(b) > PolicyConditionMaxSize {
+		return fmt.Errorf("policy condition is %d bytes, while at most %d bytes are allowed", len(b), PolicyConditionMaxSize)
+	}
+	for idx, condition := range p.Conditions {
+		if condition.ConditionType() == ConditionTypeNil {
+			return fmt.Errorf("sub condition #%d cannot be a nil condition", idx)
+		}
+		err = condition.IsStandardCondition(ctx)
+		if err != nil {
+			return fmt.Errorf("sub condition #%d is not standard: %v", idx, err)
+		}
+	}
+	return nil
+}
+
+// depth returns the amount of levels of (nested) policy conditions,
+// this policy condition included.
+func (p *PolicyCondition) depth() int {
+	var depth int
+	for _, condition := range p.Conditions {
+		if sp, ok := condition.Condition.(*PolicyCondition); ok {
+			if d := sp.depth(); d > depth {
+				depth = d
+			}
+		}
+	}
+	return depth + 1
+}
+
+// UnlockHash implements UnlockCondition.UnlockHash
+//
+// UnlockHash calculates the root hash of a Merkle tree of the
+// PolicyCondition object. The first leaf of this tree is formed by the operator
+// and threshold, followed by one leaf for each (binary encoded) sub condition,
+// in the order they are defined. Contrary to the MultiSignatureCondition,
+// the sub conditions are not sorted, as the fulfillment identifies a branch by its index.
+func (p *PolicyCondition) UnlockHash() UnlockHash {
+	var buf bytes.Buffer
+	e := encoder(&buf)
+	tree := crypto.NewTree()
+	e.Write([]byte{byte(p.Operator)})
+	e.WriteUint64(p.Threshold)
+	tree.Push(buf.Bytes())
+	buf.Reset()
+	for _, condition := range p.Conditions {
+		// Hardcoded at SiaEncoding
+		condition.MarshalSia(&buf)
+		tree.Push(buf.Bytes())
+		buf.Reset()
+	}
+	return NewUnlockHash(UnlockTypePolicy, tree.Root())
+}
+
+// Equal implements UnlockCondition.Equal
+func (p *PolicyCondition) Equal(c UnlockCondition) bool {
+	op, ok := c.(*PolicyCondition)
+	if !ok {
+		return false
+	}
+	if p.Operator != op.Operator || p.Threshold != op.Threshold || len(p.Conditions) != len(op.Conditions) {
+		return false
+	}
+	for idx, condition := range p.Conditions {
+		if !condition.Equal(op.Conditions[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fulfillable implements UnlockCondition.Fulfillable
+//
+// A policy condition is fulfillable if the required amount of
+// sub conditions are fulfillable.
+func (p *PolicyCondition) Fulfillable(ctx FulfillableContext) bool {
+	required, err := p.RequiredBranchCount()
+	if err != nil || required == 0 {
+		return false
+	}
+	var fulfillable uint64
+	for _, condition := range p.Conditions {
+		if condition.Fulfillable(ctx) {
+			fulfillable++
+		}
+	}
+	return fulfillable >= required
+}
+
+// Marshal implements MarshalableUnlockCondition.Marshal
+func (p *PolicyCondition) Marshal(f MarshalFunc) ([]byte, error) {
+	return f(p.Operator, p.Threshold, p.Conditions)
+}
+
+// Unmarshal implements MarshalableUnlockCondition.Unmarshal
+func (p *PolicyCondition) Unmarshal(b []byte, f UnmarshalFunc) error {
+	return f(b, &p.Operator, &p.Threshold, &p.Conditions)
+}
+
+// Branch returns the fulfillment of the branch with the given index,
+// and false in case that branch isn't fulfilled (yet).
+func (pf *PolicyFulfillment) Branch(index uint64) (UnlockFulfillmentProxy, bool) {
+	for _, branch := range pf.Branches {
+		if branch.Index == index {
+			return branch.Fulfillment, true
+		}
+	}
+	return UnlockFulfillmentProxy{}, false
+}
+
+// SetBranch defines the fulfillment of the branch with the given index,
+// replacing the existing fulfillment of that branch if it exists,
+// while keeping the branches ordered by index.
+func (pf *PolicyFulfillment) SetBranch(index uint64, fulfillment UnlockFulfillmentProxy) {
+	for idx, branch := range pf.Branches {
+		if branch.Index == index {
+			pf.Branches[idx].Fulfillment = fulfillment
+			return
+		}
+		if branch.Index > index {
+			pf.Branches = append(pf.Branches, PolicyBranchFulfillment{})
+			copy(pf.Branches[idx+1:], pf.Branches[idx:])
+			pf.Branches[idx] = PolicyBranchFulfillment{Index: index, Fulfillment: fulfillment}
+			return
+		}
+	}
+	pf.Branches = append(pf.Branches, PolicyBranchFulfillment{Index: index, Fulfillment: fulfillment})
+}
+
+// Sign implements UnlockFulfillment.Sign
+//
+// A policy fulfillment cannot be signed directly,
+// instead the fulfillment of each branch has to be signed.
+func (pf *PolicyFulfillment) Sign(FulfillmentSignContext) error {
+	return ErrPolicyFulfillmentSign
+}
+
+// Equal implements UnlockFulfillment.Equal
+func (pf *PolicyFulfillment) Equal(f UnlockFulfillment) bool {
+	opf, ok := f.(*PolicyFulfillment)
+	if !ok {
+		return false
+	}
+	if len(pf.Branches) != len(opf.Branches) {
+		return false
+	}
+	for idx, branch := range pf.Branches {
+		if branch.Index != opf.Branches[idx].Index || !branch.Fulfillment.Equal(opf.Branches[idx].Fulfillment) {
+			return false
+		}
+	}
+	return true
+}
+
+// FulfillmentType implements UnlockFulfillment.FulfillmentType
+func (pf *PolicyFulfillment) FulfillmentType() FulfillmentType { return FulfillmentTypePolicy }
+
+// IsStandardFulfillment implements UnlockFulfillment.IsStandardFulfillment
+func (pf *PolicyFulfillment) IsStandardFulfillment(ctx ValidationContext) error {
+	if len(pf.Branches) == 0 {
+		return errors.New("at least one branch has to be fulfilled")
+	}
+	if len(pf.Branches) > PolicyConditionMaxBranches {
+		return fmt.Errorf("at most %d branches can be fulfilled", PolicyConditionMaxBranches)
+	}
+	if depth := pf.depth(); depth > PolicyConditionMaxDepth {
+		return fmt.Errorf("policy fulfillments can be nested at most %d levels deep, not %d", PolicyConditionMaxDepth, depth)
+	}
+	for idx, branch := range pf.Branches {
+		if idx > 0 && branch.Index <= pf.Branches[idx-1].Index {
+			return fmt.Errorf("policy branch #%d is not ordered in ascending order", branch.Index)
+		}
+		err := branch.Fulfillment.IsStandardFulfillment(ctx)
+		if err != nil {
+			return fmt.Errorf("policy branch #%d is not standard: %v", branch.Index, err)
+		}
+	}
+	return nil
+}
+
+// depth returns the amount of levels of (nested) policy fulfillments,
+// this policy fulfillment included.
+func (pf *PolicyFulfillment) depth() int {
+	var depth int
+	for _, branch := range pf.Branches {
+		if spf, ok := branch.Fulfillment.Fulfillment.(*PolicyFulfillment); ok {
+			if d := spf.depth(); d > depth {
+				depth = d
+			}
+		}
+	}
+	return depth + 1
+}
+
+// Marshal implements MarshalableUnlockFulfillment.Marshal
+func (pf *PolicyFulfillment) Marshal(f MarshalFunc) ([]byte, error) {
+	return f(pf.Branches)
+}
+
+// Unmarshal implements MarshalableUnlockFulfillment.Unmarshal
+func (pf *PolicyFulfillment) Unmarshal(b []byte, f UnmarshalFunc) error {
+	return f(b, &pf.Branches)
+}
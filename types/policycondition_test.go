@@ -0,0 +1,450 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+)
+
+type policyTestKey struct {
+	sk crypto.SecretKey
+	pk PublicKey
+	uh UnlockHash
+}
+
+func newPolicyTestKey(t *testing.T) policyTestKey {
+	sk, rpk := crypto.GenerateKeyPair()
+	pk := Ed25519PublicKey(rpk)
+	uh, err := NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return policyTestKey{sk: sk, pk: pk, uh: uh}
+}
+
+func (k policyTestKey) condition() *UnlockHashCondition {
+	return NewUnlockHashCondition(k.uh)
+}
+
+func (k policyTestKey) sign(t *testing.T, txn Transaction) UnlockFulfillmentProxy {
+	f := NewSingleSignatureFulfillment(k.pk)
+	err := f.Sign(FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  txn,
+		Key:          k.sk,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewFulfillment(f)
+}
+
+func (k policyTestKey) signMultiSig(t *testing.T, txn Transaction, f *MultiSignatureFulfillment) {
+	err := f.Sign(FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  txn,
+		Key: KeyPair{
+			PublicKey:  k.pk,
+			PrivateKey: ByteSlice(k.sk[:]),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPolicyConditionEncoding(t *testing.T) {
+	k1, k2, k3 := newPolicyTestKey(t), newPolicyTestKey(t), newPolicyTestKey(t)
+	condition := NewCondition(NewPolicyCondition(PolicyOperatorOr, 0,
+		&MultiSignatureCondition{UnlockHashes: UnlockHashSlice{k1.uh, k2.uh, k3.uh}, MinimumSignatureCount: 2},
+		NewTimeLockCondition(42, k1.condition()),
+		NewPolicyCondition(PolicyOperatorThreshold, 1, k2.condition(), k3.condition()),
+	))
+	fulfillment := NewFulfillment(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{
+			{Index: 1, Fulfillment: NewFulfillment(NewSingleSignatureFulfillment(k1.pk))},
+			{Index: 2, Fulfillment: NewFulfillment(&PolicyFulfillment{
+				Branches: []PolicyBranchFulfillment{
+					{Index: 0, Fulfillment: NewFulfillment(NewSingleSignatureFulfillment(k2.pk))},
+				},
+			})},
+		},
+	})
+
+	// JSON
+	b, err := json.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jsonCondition UnlockConditionProxy
+	err = json.Unmarshal(b, &jsonCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(jsonCondition) {
+		t.Errorf("JSON: %v != %v", condition, jsonCondition)
+	}
+	b, err = json.Marshal(fulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jsonFulfillment UnlockFulfillmentProxy
+	err = json.Unmarshal(b, &jsonFulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfillment.Equal(jsonFulfillment) {
+		t.Errorf("JSON: %v != %v", fulfillment, jsonFulfillment)
+	}
+
+	// the operator is JSON-encoded as a string
+	b, err = json.Marshal(NewPolicyCondition(PolicyOperatorAnd, 0, k1.condition(), k2.condition()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rawCondition struct {
+		Operator  string `json:"operator"`
+		Threshold uint64 `json:"threshold"`
+	}
+	err = json.Unmarshal(b, &rawCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rawCondition.Operator != "and" || rawCondition.Threshold != 0 {
+		t.Errorf("unexpected JSON encoding of operator and threshold: %s", string(b))
+	}
+	err = json.Unmarshal([]byte(`{"type":6,"data":{"operator":"xor","conditions":[]}}`), &jsonCondition)
+	if err == nil {
+		t.Error("expected an unknown operator to fail to decode")
+	}
+
+	// rivine binary
+	b, err = rivbin.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rivineCondition UnlockConditionProxy
+	err = rivbin.Unmarshal(b, &rivineCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(rivineCondition) {
+		t.Errorf("rivbin: %v != %v", condition, rivineCondition)
+	}
+	b, err = rivbin.Marshal(fulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rivineFulfillment UnlockFulfillmentProxy
+	err = rivbin.Unmarshal(b, &rivineFulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfillment.Equal(rivineFulfillment) {
+		t.Errorf("rivbin: %v != %v", fulfillment, rivineFulfillment)
+	}
+
+	// sia binary
+	b, err = siabin.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var siaCondition UnlockConditionProxy
+	err = siabin.Unmarshal(b, &siaCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(siaCondition) {
+		t.Errorf("siabin: %v != %v", condition, siaCondition)
+	}
+}
+
+func TestPolicyConditionUnlockHash(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	and := NewPolicyCondition(PolicyOperatorAnd, 0, k1.condition(), k2.condition())
+	uh := and.UnlockHash()
+	if uh.Type != UnlockTypePolicy {
+		t.Fatalf("unexpected unlock hash type: %d", uh.Type)
+	}
+	for idx, other := range []*PolicyCondition{
+		NewPolicyCondition(PolicyOperatorOr, 0, k1.condition(), k2.condition()),
+		NewPolicyCondition(PolicyOperatorThreshold, 2, k1.condition(), k2.condition()),
+		NewPolicyCondition(PolicyOperatorAnd, 0, k2.condition(), k1.condition()),
+		NewPolicyCondition(PolicyOperatorAnd, 0, k1.condition(), NewTimeLockCondition(42, k2.condition())),
+	} {
+		if other.UnlockHash().Cmp(uh) == 0 {
+			t.Errorf("#%d: expected a different unlock hash for %v", idx, other)
+		}
+	}
+	if NewPolicyCondition(PolicyOperatorAnd, 0, k1.condition(), k2.condition()).UnlockHash().Cmp(uh) != 0 {
+		t.Error("expected the same unlock hash for an equal policy condition")
+	}
+}
+
+func TestPolicyConditionFulfill(t *testing.T) {
+	k1, k2, k3 := newPolicyTestKey(t), newPolicyTestKey(t), newPolicyTestKey(t)
+	txn := Transaction{
+		Version:       TransactionVersionOne,
+		ArbitraryData: []byte("policy"),
+	}
+	ctx := FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockHeight:  100,
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+	}
+
+	// OR(2-of-3 multisig, AND(k1 after height 42, k3))
+	policy := NewPolicyCondition(PolicyOperatorOr, 0,
+		&MultiSignatureCondition{UnlockHashes: UnlockHashSlice{k1.uh, k2.uh, k3.uh}, MinimumSignatureCount: 2},
+		NewPolicyCondition(PolicyOperatorAnd, 0,
+			NewTimeLockCondition(42, k1.condition()),
+			k3.condition(),
+		),
+	)
+	if !policy.Fulfillable(FulfillableContext{BlockHeight: ctx.BlockHeight, BlockTime: ctx.BlockTime}) {
+		t.Fatal("expected policy to be fulfillable")
+	}
+
+	ms := &MultiSignatureFulfillment{}
+	k1.signMultiSig(t, txn, ms)
+	err := policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{{Index: 0, Fulfillment: NewFulfillment(ms)}},
+	}, ctx)
+	if err == nil {
+		t.Error("expected a multisig branch with one signature to fail")
+	}
+	k2.signMultiSig(t, txn, ms)
+	err = policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{{Index: 0, Fulfillment: NewFulfillment(ms)}},
+	}, ctx)
+	if err != nil {
+		t.Errorf("expected multisig branch to fulfill the policy: %v", err)
+	}
+
+	and := &PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{
+			{Index: 0, Fulfillment: k1.sign(t, txn)},
+			{Index: 1, Fulfillment: k3.sign(t, txn)},
+		},
+	}
+	err = policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{{Index: 1, Fulfillment: NewFulfillment(and)}},
+	}, ctx)
+	if err != nil {
+		t.Errorf("expected nested AND branch to fulfill the policy: %v", err)
+	}
+	// the time lock of the nested AND branch is not yet reached
+	lockedCtx := ctx
+	lockedCtx.BlockHeight = 41
+	err = policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{{Index: 1, Fulfillment: NewFulfillment(and)}},
+	}, lockedCtx)
+	if err == nil {
+		t.Error("expected a time locked branch to fail")
+	}
+	// all branches of an AND policy have to be fulfilled
+	err = policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{{Index: 1, Fulfillment: NewFulfillment(&PolicyFulfillment{
+			Branches: and.Branches[:1],
+		})}},
+	}, ctx)
+	if err == nil {
+		t.Error("expected a partially fulfilled AND branch to fail")
+	}
+	// all named branches have to be fulfilled, even if not required
+	err = policy.Fulfill(&PolicyFulfillment{
+		Branches: []PolicyBranchFulfillment{
+			{Index: 0, Fulfillment: k1.sign(t, txn)},
+			{Index: 1, Fulfillment: NewFulfillment(and)},
+		},
+	}, ctx)
+	if err == nil {
+		t.Error("expected an unfulfilled branch to fail")
+	}
+
+	// 2-of-3 threshold
+	threshold := NewPolicyCondition(PolicyOperatorThreshold, 2, k1.condition(), k2.condition(), k3.condition())
+	for idx, tc := range []struct {
+		branches []PolicyBranchFulfillment
+		valid    bool
+	}{
+		{nil, false},
+		{[]PolicyBranchFulfillment{{Index: 0, Fulfillment: k1.sign(t, txn)}}, false},
+		{[]PolicyBranchFulfillment{{Index: 0, Fulfillment: k1.sign(t, txn)}, {Index: 2, Fulfillment: k3.sign(t, txn)}}, true},
+		{[]PolicyBranchFulfillment{{Index: 1, Fulfillment: k2.sign(t, txn)}, {Index: 2, Fulfillment: k3.sign(t, txn)}}, true},
+		{[]PolicyBranchFulfillment{{Index: 0, Fulfillment: k1.sign(t, txn)}, {Index: 1, Fulfillment: k2.sign(t, txn)}, {Index: 2, Fulfillment: k3.sign(t, txn)}}, true},
+		// wrong key for the branch
+		{[]PolicyBranchFulfillment{{Index: 0, Fulfillment: k2.sign(t, txn)}, {Index: 2, Fulfillment: k3.sign(t, txn)}}, false},
+		// duplicate branch
+		{[]PolicyBranchFulfillment{{Index: 2, Fulfillment: k3.sign(t, txn)}, {Index: 2, Fulfillment: k3.sign(t, txn)}}, false},
+		// unordered branches
+		{[]PolicyBranchFulfillment{{Index: 2, Fulfillment: k3.sign(t, txn)}, {Index: 0, Fulfillment: k1.sign(t, txn)}}, false},
+		// out of range branch
+		{[]PolicyBranchFulfillment{{Index: 0, Fulfillment: k1.sign(t, txn)}, {Index: 3, Fulfillment: k3.sign(t, txn)}}, false},
+	} {
+		err = threshold.Fulfill(&PolicyFulfillment{Branches: tc.branches}, ctx)
+		if tc.valid && err != nil {
+			t.Errorf("#%d: expected threshold to be fulfilled: %v", idx, err)
+		} else if !tc.valid && err == nil {
+			t.Errorf("#%d: expected threshold fulfillment to fail", idx)
+		}
+	}
+
+	// only a policy fulfillment can fulfill a policy condition
+	err = threshold.Fulfill(k1.sign(t, txn).Fulfillment, ctx)
+	if err != ErrUnexpectedUnlockFulfillment {
+		t.Errorf("expected %v, got %v", ErrUnexpectedUnlockFulfillment, err)
+	}
+}
+
+func TestPolicyConditionFulfillable(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	ctx := FulfillableContext{BlockHeight: 10, BlockTime: CurrentTimestamp()}
+	locked := NewTimeLockCondition(42, k1.condition())
+	for idx, tc := range []struct {
+		condition   *PolicyCondition
+		fulfillable bool
+	}{
+		{NewPolicyCondition(PolicyOperatorAnd, 0, k1.condition(), k2.condition()), true},
+		{NewPolicyCondition(PolicyOperatorAnd, 0, locked, k2.condition()), false},
+		{NewPolicyCondition(PolicyOperatorOr, 0, locked, k2.condition()), true},
+		{NewPolicyCondition(PolicyOperatorOr, 0, locked, locked), false},
+		{NewPolicyCondition(PolicyOperatorThreshold, 1, locked, k2.condition()), true},
+		{NewPolicyCondition(PolicyOperatorThreshold, 2, locked, k2.condition()), false},
+		{NewPolicyCondition(PolicyOperatorThreshold, 0, k1.condition(), k2.condition()), false},
+		{NewPolicyCondition(PolicyOperatorNil, 0, k1.condition(), k2.condition()), false},
+	} {
+		if fulfillable := tc.condition.Fulfillable(ctx); fulfillable != tc.fulfillable {
+			t.Errorf("#%d: expected fulfillable to be %v, not %v", idx, tc.fulfillable, fulfillable)
+		}
+	}
+}
+
+func TestPolicyConditionIsStandard(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	ctx := ValidationContext{
+		Confirmed:   true,
+		BlockHeight: 0,
+	}
+	nested := func(depth int) *PolicyCondition {
+		p := NewPolicyCondition(PolicyOperatorAnd, 0, k1.condition(), k2.condition())
+		for i := 1; i < depth; i++ {
+			p = NewPolicyCondition(PolicyOperatorOr, 0, p, k2.condition())
+		}
+		return p
+	}
+	tooManyBranches := NewPolicyCondition(PolicyOperatorOr, 0)
+	for i := 0; i <= PolicyConditionMaxBranches; i++ {
+		tooManyBranches.Conditions = append(tooManyBranches.Conditions, NewCondition(k1.condition()))
+	}
+	tooLarge := NewPolicyCondition(PolicyOperatorOr, 0)
+	for i := 0; i < PolicyConditionMaxBranches/2; i++ {
+		tooLarge.Conditions = append(tooLarge.Conditions, NewCondition(&PolicyCondition{
+			Operator:   PolicyOperatorOr,
+			Conditions: tooManyBranches.Conditions[:PolicyConditionMaxBranches],
+		}))
+	}
+
+	for idx, tc := range []struct {
+		condition *PolicyCondition
+		standard  bool
+	}{
+		{NewPolicyCondition(PolicyOperatorAnd, 0, k1.condition(), k2.condition()), true},
+		{NewPolicyCondition(PolicyOperatorOr, 0, k1.condition(), NewTimeLockCondition(42, k2.condition())), true},
+		{NewPolicyCondition(PolicyOperatorThreshold, 2, k1.condition(), k2.condition()), true},
+		{NewPolicyCondition(PolicyOperatorOr, 0,
+			&MultiSignatureCondition{UnlockHashes: UnlockHashSlice{k1.uh, k2.uh}, MinimumSignatureCount: 2}, k1.condition()), true},
+		{nested(PolicyConditionMaxDepth), true},
+		// invalid operator and threshold
+		{NewPolicyCondition(PolicyOperatorNil, 0, k1.condition(), k2.condition()), false},
+		{&PolicyCondition{Operator: PolicyOperatorAnd, Threshold: 1, Conditions: []UnlockConditionProxy{
+			NewCondition(k1.condition()), NewCondition(k2.condition())}}, false},
+		{NewPolicyCondition(PolicyOperatorThreshold, 0, k1.condition(), k2.condition()), false},
+		{NewPolicyCondition(PolicyOperatorThreshold, 3, k1.condition(), k2.condition()), false},
+		// invalid sub conditions
+		{NewPolicyCondition(PolicyOperatorOr, 0, k1.condition()), false},
+		{NewPolicyCondition(PolicyOperatorOr, 0, k1.condition(), &NilCondition{}), false},
+		{NewPolicyCondition(PolicyOperatorOr, 0, k1.condition(), &TimeLockCondition{Condition: k2.condition()}), false},
+		// limits
+		{nested(PolicyConditionMaxDepth + 1), false},
+		{tooManyBranches, false},
+		{tooLarge, false},
+	} {
+		err := tc.condition.IsStandardCondition(ctx)
+		if tc.standard && err != nil {
+			t.Errorf("#%d: expected condition to be standard: %v", idx, err)
+		} else if !tc.standard && err == nil {
+			t.Errorf("#%d: expected condition to be non-standard", idx)
+		}
+	}
+}
+
+func TestPolicyFulfillmentIsStandard(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	ctx := ValidationContext{
+		Confirmed:   true,
+		BlockHeight: 0,
+	}
+	signature := make(ByteSlice, crypto.SignatureSize)
+	single := func(k policyTestKey) UnlockFulfillmentProxy {
+		return NewFulfillment(&SingleSignatureFulfillment{PublicKey: k.pk, Signature: signature})
+	}
+	nested := func(depth int) *PolicyFulfillment {
+		pf := &PolicyFulfillment{Branches: []PolicyBranchFulfillment{{Index: 0, Fulfillment: single(k1)}}}
+		for i := 1; i < depth; i++ {
+			pf = &PolicyFulfillment{Branches: []PolicyBranchFulfillment{{Index: 0, Fulfillment: NewFulfillment(pf)}}}
+		}
+		return pf
+	}
+	for idx, tc := range []struct {
+		fulfillment *PolicyFulfillment
+		standard    bool
+	}{
+		{&PolicyFulfillment{Branches: []PolicyBranchFulfillment{{Index: 0, Fulfillment: single(k1)}}}, true},
+		{&PolicyFulfillment{Branches: []PolicyBranchFulfillment{{Index: 0, Fulfillment: single(k1)}, {Index: 3, Fulfillment: single(k2)}}}, true},
+		{nested(PolicyConditionMaxDepth), true},
+		{&PolicyFulfillment{}, false},
+		{&PolicyFulfillment{Branches: []PolicyBranchFulfillment{{Index: 1, Fulfillment: single(k1)}, {Index: 0, Fulfillment: single(k2)}}}, false},
+		{&PolicyFulfillment{Branches: []PolicyBranchFulfillment{{Index: 0, Fulfillment: NewFulfillment(&SingleSignatureFulfillment{PublicKey: k1.pk})}}}, false},
+		{nested(PolicyConditionMaxDepth + 1), false},
+	} {
+		err := tc.fulfillment.IsStandardFulfillment(ctx)
+		if tc.standard && err != nil {
+			t.Errorf("#%d: expected fulfillment to be standard: %v", idx, err)
+		} else if !tc.standard && err == nil {
+			t.Errorf("#%d: expected fulfillment to be non-standard", idx)
+		}
+	}
+}
+
+func TestPolicyFulfillmentSetBranch(t *testing.T) {
+	k1, k2, k3 := newPolicyTestKey(t), newPolicyTestKey(t), newPolicyTestKey(t)
+	var pf PolicyFulfillment
+	pf.SetBranch(2, NewFulfillment(NewSingleSignatureFulfillment(k3.pk)))
+	pf.SetBranch(0, NewFulfillment(NewSingleSignatureFulfillment(k1.pk)))
+	pf.SetBranch(1, NewFulfillment(NewSingleSignatureFulfillment(k1.pk)))
+	pf.SetBranch(1, NewFulfillment(NewSingleSignatureFulfillment(k2.pk)))
+	if len(pf.Branches) != 3 {
+		t.Fatalf("expected 3 branches, not %d", len(pf.Branches))
+	}
+	for idx, k := range []policyTestKey{k1, k2, k3} {
+		if pf.Branches[idx].Index != uint64(idx) {
+			t.Errorf("expected branch #%d at position %d, not #%d", idx, idx, pf.Branches[idx].Index)
+		}
+		f, ok := pf.Branch(uint64(idx))
+		if !ok {
+			t.Errorf("expected branch #%d to exist", idx)
+			continue
+		}
+		if !f.Equal(NewSingleSignatureFulfillment(k.pk)) {
+			t.Errorf("unexpected fulfillment for branch #%d: %v", idx, f)
+		}
+	}
+	if _, ok := pf.Branch(3); ok {
+		t.Error("expected branch #3 not to exist")
+	}
+}
@@ -0,0 +1,204 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+)
+
+// SigHashFlags defines which parts of a transaction are signed by a signature.
+// The zero value (SigHashAll) signs the entire transaction.
+//
+// Signature hash flags are only supported by transaction versions whose controller
+// implements the TransactionSigHashFlagsHasher interface, such as TransactionVersionFour.
+type SigHashFlags uint8
+
+const (
+	// SigHashAll signs all inputs and all outputs (including the miner fees) of a transaction.
+	SigHashAll SigHashFlags = 0
+	// SigHashInputSingle signs only the input the signature belongs to,
+	// instead of all inputs, allowing other inputs to be added after signing.
+	// Of the inputs, only the ID of the output spent by the signed input is hashed,
+	// its index is signed as the first of the extra objects, as given by the consensus
+	// (and wallet) for every input. Inputs can therefore only be appended after signing,
+	// inserting or reordering inputs invalidates the signature.
+	SigHashInputSingle SigHashFlags = 1 << 0
+	// SigHashOutputsNone signs none of the coin outputs, block stake outputs and miner fees,
+	// instead of all of them, allowing these to be defined by another party.
+	SigHashOutputsNone SigHashFlags = 1 << 1
+
+	// sigHashFlagsMask masks all known signature hash flags
+	sigHashFlagsMask = SigHashInputSingle | SigHashOutputsNone
+)
+
+// signature hash flags errors
+var (
+	// ErrSigHashFlagsNotSupported is returned when signature hash flags are used
+	// for a transaction of a version which does not support them.
+	ErrSigHashFlagsNotSupported = errors.New("signature hash flags are not supported by the transaction version")
+	// ErrUnknownSigHashFlags is returned when signature hash flags contain unknown flags.
+	ErrUnknownSigHashFlags = errors.New("unknown signature hash flags")
+	// ErrSigHashInputRequired is returned when the SigHashInputSingle flag is used,
+	// while the ID of the output spent by the signed input is unknown.
+	ErrSigHashInputRequired = errors.New("the SigHashInputSingle flag requires the parent ID of the signed input")
+)
+
+// Validate returns an error in case unknown flags are defined.
+func (flags SigHashFlags) Validate() error {
+	if flags&^sigHashFlagsMask != 0 {
+		return ErrUnknownSigHashFlags
+	}
+	return nil
+}
+
+// String returns the flags as a human-readable string.
+func (flags SigHashFlags) String() string {
+	if flags == SigHashAll {
+		return "all"
+	}
+	var parts []string
+	if flags&SigHashInputSingle != 0 {
+		parts = append(parts, "inputsingle")
+	}
+	if flags&SigHashOutputsNone != 0 {
+		parts = append(parts, "outputsnone")
+	}
+	if unknown := flags &^ sigHashFlagsMask; unknown != 0 {
+		parts = append(parts, fmt.Sprintf("0x%02x", uint8(unknown)))
+	}
+	return strings.Join(parts, "|")
+}
+
+// LoadString loads the flags from a human-readable string,
+// as produced by the String method.
+func (flags *SigHashFlags) LoadString(str string) error {
+	var result SigHashFlags
+	for _, part := range strings.Split(str, "|") {
+		switch strings.TrimSpace(part) {
+		case "all", "":
+		case "inputsingle":
+			result |= SigHashInputSingle
+		case "outputsnone":
+			result |= SigHashOutputsNone
+		default:
+			return fmt.Errorf("unknown signature hash flag %q", part)
+		}
+	}
+	*flags = result
+	return nil
+}
+
+// SupportsSigHashFlags returns true if the transaction version is registered,
+// using a controller which supports signature hash flags.
+func (v TransactionVersion) SupportsSigHashFlags() bool {
+	_, ok := _RegisteredTransactionVersions[v].(TransactionSigHashFlagsHasher)
+	return ok
+}
+
+// signatureHashWithFlags computes the hash to be signed (or verified) for the given flags,
+// returning as well the suffix to be appended to the signature, which is the flags byte
+// for transaction versions that support signature hash flags, and nil otherwise.
+func signatureHashWithFlags(t Transaction, flags SigHashFlags, parentID OutputID, extraObjects []interface{}) (crypto.Hash, []byte, error) {
+	hasher, ok := _RegisteredTransactionVersions[t.Version].(TransactionSigHashFlagsHasher)
+	if !ok {
+		if flags != SigHashAll {
+			return crypto.Hash{}, nil, ErrSigHashFlagsNotSupported
+		}
+		hash, err := t.SignatureHash(extraObjects...)
+		return hash, nil, err
+	}
+	err := flags.Validate()
+	if err != nil {
+		return crypto.Hash{}, nil, err
+	}
+	if flags&SigHashInputSingle != 0 && parentID == (OutputID{}) {
+		return crypto.Hash{}, nil, ErrSigHashInputRequired
+	}
+	hash, err := hasher.SignatureHashWithFlags(t, flags, parentID, extraObjects...)
+	if err != nil {
+		return crypto.Hash{}, nil, err
+	}
+	return hash, []byte{byte(flags)}, nil
+}
+
+// splitSignatureHashFlags splits the signature hash flags from the given signature,
+// in case the transaction version supports signature hash flags, in which case
+// the signature is expected to be one byte longer than the given size.
+func splitSignatureHashFlags(t Transaction, sig []byte, size int) ([]byte, SigHashFlags, error) {
+	if !t.Version.SupportsSigHashFlags() {
+		if len(sig) != size {
+			return nil, SigHashAll, crypto.ErrInvalidSignature
+		}
+		return sig, SigHashAll, nil
+	}
+	if len(sig) != size+1 {
+		return nil, SigHashAll, crypto.ErrInvalidSignature
+	}
+	return sig[:size], SigHashFlags(sig[size]), nil
+}
+
+// SigHashTransactionController is the transaction controller used for TransactionVersionFour.
+// It encodes transactions exactly like the DefaultTransactionController,
+// and commits to the ID of the chain in the signature hash, just like the ChainIDTransactionController.
+// On top of that each signature can define, using signature hash flags,
+// whether it signs all inputs or only the input it belongs to,
+// and whether it signs all outputs (including the miner fees) or none.
+//
+// As the chain ID is only known at runtime, this controller is not registered by default,
+// and has to be registered by the daemon (and client) of a chain which wishes to use it.
+type SigHashTransactionController struct {
+	DefaultTransactionController
+	ChainID ChainID
+}
+
+// SignatureHash implements TransactionSignatureHasher.SignatureHash,
+// returning the signature hash for signatures which sign the entire transaction.
+func (shtc SigHashTransactionController) SignatureHash(t Transaction, extraObjects ...interface{}) (crypto.Hash, error) {
+	return shtc.SignatureHashWithFlags(t, SigHashAll, OutputID{}, extraObjects...)
+}
+
+// SignatureHashWithFlags implements TransactionSigHashFlagsHasher.SignatureHashWithFlags
+func (shtc SigHashTransactionController) SignatureHashWithFlags(t Transaction, flags SigHashFlags, parentID OutputID, extraObjects ...interface{}) (crypto.Hash, error) {
+	h := crypto.NewHash()
+	enc := siabin.NewEncoder(h)
+
+	enc.EncodeAll(t.Version, shtc.ChainID, flags)
+	if len(extraObjects) > 0 {
+		// the extra objects start with the index of the signed input
+		enc.EncodeAll(extraObjects...)
+	}
+	if flags&SigHashInputSingle != 0 {
+		enc.Encode(parentID)
+	} else {
+		enc.Encode(len(t.CoinInputs))
+		for _, ci := range t.CoinInputs {
+			enc.Encode(ci.ParentID)
+		}
+		enc.Encode(len(t.BlockStakeInputs))
+		for _, bsi := range t.BlockStakeInputs {
+			enc.Encode(bsi.ParentID)
+		}
+	}
+	if flags&SigHashOutputsNone == 0 {
+		enc.EncodeAll(
+			t.CoinOutputs,
+			t.BlockStakeOutputs,
+			t.MinerFees,
+		)
+	}
+	enc.Encode(t.ArbitraryData)
+
+	var hash crypto.Hash
+	h.Sum(hash[:0])
+	return hash, nil
+}
+
+// ensures at compile time that the SigHash Transaction Controller implement all desired interfaces
+var (
+	_ TransactionController         = SigHashTransactionController{}
+	_ TransactionSignatureHasher    = SigHashTransactionController{}
+	_ TransactionSigHashFlagsHasher = SigHashTransactionController{}
+)
@@ -0,0 +1,229 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+)
+
+func TestSigHashFlagsString(t *testing.T) {
+	testCases := []struct {
+		Flags  SigHashFlags
+		String string
+	}{
+		{SigHashAll, "all"},
+		{SigHashInputSingle, "inputsingle"},
+		{SigHashOutputsNone, "outputsnone"},
+		{SigHashInputSingle | SigHashOutputsNone, "inputsingle|outputsnone"},
+	}
+	for idx, testCase := range testCases {
+		if str := testCase.Flags.String(); str != testCase.String {
+			t.Errorf("#%d: unexpected string: %s != %s", idx, str, testCase.String)
+		}
+		var flags SigHashFlags
+		err := flags.LoadString(testCase.String)
+		if err != nil {
+			t.Errorf("#%d: failed to load %s: %v", idx, testCase.String, err)
+		} else if flags != testCase.Flags {
+			t.Errorf("#%d: unexpected flags: %d != %d", idx, flags, testCase.Flags)
+		}
+	}
+	var flags SigHashFlags
+	if err := flags.LoadString("foo"); err == nil {
+		t.Error("expected an error for an unknown flag")
+	}
+	if err := SigHashFlags(1 << 7).Validate(); err != ErrUnknownSigHashFlags {
+		t.Errorf("expected %v, got %v", ErrUnknownSigHashFlags, err)
+	}
+}
+
+func TestSigHashFlagsSigning(t *testing.T) {
+	defer RegisterTransactionVersion(TransactionVersionFour, nil)
+	RegisterTransactionVersion(TransactionVersionFour, SigHashTransactionController{ChainID: ChainID{1}})
+
+	sk, rpk := crypto.GenerateKeyPair()
+	pk := Ed25519PublicKey(rpk)
+	uh, err := NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	condition := NewUnlockHashCondition(uh)
+	newTransaction := func() Transaction {
+		return Transaction{
+			Version:       TransactionVersionFour,
+			CoinInputs:    []CoinInput{{ParentID: CoinOutputID{4, 2}}},
+			CoinOutputs:   []CoinOutput{{Value: NewCurrency64(42), Condition: NewCondition(nil)}},
+			MinerFees:     []Currency{NewCurrency64(1)},
+			ArbitraryData: []byte("sighash"),
+		}
+	}
+	sign := func(flags SigHashFlags) *SingleSignatureFulfillment {
+		fulfillment := NewSingleSignatureFulfillment(pk)
+		err := fulfillment.Sign(FulfillmentSignContext{
+			ExtraObjects: []interface{}{uint64(0)},
+			Transaction:  newTransaction(),
+			Key:          sk,
+			SigHashFlags: flags,
+			ParentID:     OutputID{4, 2},
+		})
+		if err != nil {
+			t.Fatal(flags, err)
+		}
+		if len(fulfillment.Signature) != crypto.SignatureSize+1 {
+			t.Fatalf("%s: unexpected signature length: %d", flags, len(fulfillment.Signature))
+		}
+		if SigHashFlags(fulfillment.Signature[crypto.SignatureSize]) != flags {
+			t.Fatalf("%s: unexpected flags byte: %d", flags, fulfillment.Signature[crypto.SignatureSize])
+		}
+		return fulfillment
+	}
+	fulfill := func(fulfillment *SingleSignatureFulfillment, txn Transaction) error {
+		return condition.Fulfill(fulfillment, FulfillContext{
+			ExtraObjects: []interface{}{uint64(0)},
+			BlockTime:    CurrentTimestamp(),
+			Transaction:  txn,
+			ParentID:     OutputID{4, 2},
+		})
+	}
+
+	addInput := func(txn *Transaction) {
+		txn.CoinInputs = append(txn.CoinInputs, CoinInput{ParentID: CoinOutputID{1}})
+	}
+	changeOutputs := func(txn *Transaction) {
+		txn.CoinOutputs = append(txn.CoinOutputs, CoinOutput{Value: NewCurrency64(1), Condition: NewCondition(nil)})
+		txn.MinerFees = []Currency{NewCurrency64(2)}
+	}
+	changeData := func(txn *Transaction) {
+		txn.ArbitraryData = []byte("other")
+	}
+
+	testCases := []struct {
+		Flags  SigHashFlags
+		Modify func(*Transaction)
+		Valid  bool
+	}{
+		{SigHashAll, func(*Transaction) {}, true},
+		{SigHashAll, addInput, false},
+		{SigHashAll, changeOutputs, false},
+		{SigHashInputSingle, addInput, true},
+		{SigHashInputSingle, changeOutputs, false},
+		{SigHashOutputsNone, addInput, false},
+		{SigHashOutputsNone, changeOutputs, true},
+		{SigHashInputSingle | SigHashOutputsNone, func(txn *Transaction) { addInput(txn); changeOutputs(txn) }, true},
+		{SigHashInputSingle | SigHashOutputsNone, changeData, false},
+	}
+	for idx, testCase := range testCases {
+		fulfillment := sign(testCase.Flags)
+		txn := newTransaction()
+		testCase.Modify(&txn)
+		err := fulfill(fulfillment, txn)
+		if testCase.Valid && err != nil {
+			t.Errorf("#%d: expected %s signature to be valid: %v", idx, testCase.Flags, err)
+		} else if !testCase.Valid && err == nil {
+			t.Errorf("#%d: expected %s signature to be invalid", idx, testCase.Flags)
+		}
+	}
+
+	// the flags are signed as well
+	fulfillment := sign(SigHashInputSingle)
+	fulfillment.Signature[crypto.SignatureSize] = byte(SigHashInputSingle | SigHashOutputsNone)
+	if err = fulfill(fulfillment, newTransaction()); err == nil {
+		t.Error("expected signature with modified flags to be invalid")
+	}
+	// unknown flags are invalid
+	fulfillment.Signature[crypto.SignatureSize] = 1 << 7
+	if err = fulfill(fulfillment, newTransaction()); err != ErrUnknownSigHashFlags {
+		t.Errorf("expected %v, got %v", ErrUnknownSigHashFlags, err)
+	}
+	// a signature without flags is invalid
+	fulfillment = sign(SigHashAll)
+	fulfillment.Signature = fulfillment.Signature[:crypto.SignatureSize]
+	if err = fulfill(fulfillment, newTransaction()); err == nil {
+		t.Error("expected signature without flags to be invalid")
+	}
+
+	// an input-only signature commits to the ID of the spent output
+	fulfillment = sign(SigHashInputSingle)
+	err = condition.Fulfill(fulfillment, FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  newTransaction(),
+		ParentID:     OutputID{1},
+	})
+	if err == nil {
+		t.Error("expected input-only signature to be invalid for another parent ID")
+	}
+	// and to the index of the signed input, such that inputs cannot be reordered
+	fulfillment = sign(SigHashInputSingle)
+	txn := newTransaction()
+	txn.CoinInputs = append([]CoinInput{{ParentID: CoinOutputID{1}}}, txn.CoinInputs...)
+	err = condition.Fulfill(fulfillment, FulfillContext{
+		ExtraObjects: []interface{}{uint64(1)},
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+		ParentID:     OutputID{4, 2},
+	})
+	if err == nil {
+		t.Error("expected input-only signature to be invalid for another input index")
+	}
+	// and cannot be created without it
+	err = NewSingleSignatureFulfillment(pk).Sign(FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  newTransaction(),
+		Key:          sk,
+		SigHashFlags: SigHashInputSingle,
+	})
+	if err != ErrSigHashInputRequired {
+		t.Errorf("expected %v, got %v", ErrSigHashInputRequired, err)
+	}
+}
+
+func TestSigHashFlagsNotSupported(t *testing.T) {
+	sk, rpk := crypto.GenerateKeyPair()
+	pk := Ed25519PublicKey(rpk)
+	txn := Transaction{
+		Version:    TransactionVersionOne,
+		CoinInputs: []CoinInput{{ParentID: CoinOutputID{4, 2}}},
+		MinerFees:  []Currency{NewCurrency64(1)},
+	}
+	if txn.Version.SupportsSigHashFlags() {
+		t.Fatal("expected v1 transactions to not support signature hash flags")
+	}
+	fulfillment := NewSingleSignatureFulfillment(pk)
+	err := fulfillment.Sign(FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  txn,
+		Key:          sk,
+		SigHashFlags: SigHashInputSingle,
+		ParentID:     OutputID{4, 2},
+	})
+	if err != ErrSigHashFlagsNotSupported {
+		t.Fatalf("expected %v, got %v", ErrSigHashFlagsNotSupported, err)
+	}
+
+	// a v1 signature does not carry any flags
+	err = fulfillment.Sign(FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  txn,
+		Key:          sk,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fulfillment.Signature) != crypto.SignatureSize {
+		t.Fatalf("unexpected signature length: %d", len(fulfillment.Signature))
+	}
+	uh, err := NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fulfillment.Signature = append(fulfillment.Signature, 0)
+	err = NewUnlockHashCondition(uh).Fulfill(fulfillment, FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+	})
+	if err == nil {
+		t.Fatal("expected a v1 signature with a flags byte to be invalid")
+	}
+}
@@ -27,15 +27,21 @@ const (
 	// SignatureAlgoNil identifies a nil SignatureAlgoType value.
 	SignatureAlgoNil SignatureAlgoType = iota
 	// SignatureAlgoEd25519 identifies the Ed25519 signature Algorithm,
-	// the default (and only) algorithm supported by this chain.
+	// the default algorithm supported by this chain.
 	SignatureAlgoEd25519
+	// SignatureAlgoSecp256k1 identifies the ECDSA signature Algorithm,
+	// using the secp256k1 curve, as used by Bitcoin and Ethereum.
+	// Public keys are compressed, and signatures are the concatenation
+	// of the R and S values, where only the lower S value is valid.
+	SignatureAlgoSecp256k1
 )
 
 // These Specifiers enumerate the string versions of the types of signatures that are recognized
 // by this implementation. see Consensus.md for more details.
 var (
-	SignatureAlgoNilSpecifier     = Specifier{}
-	SignatureAlgoEd25519Specifier = Specifier{'e', 'd', '2', '5', '5', '1', '9'}
+	SignatureAlgoNilSpecifier       = Specifier{}
+	SignatureAlgoEd25519Specifier   = Specifier{'e', 'd', '2', '5', '5', '1', '9'}
+	SignatureAlgoSecp256k1Specifier = Specifier{'s', 'e', 'c', 'p', '2', '5', '6', 'k', '1'}
 )
 
 func (sat SignatureAlgoType) String() string {
@@ -48,6 +54,8 @@ func (sat SignatureAlgoType) Specifier() Specifier {
 	switch sat {
 	case SignatureAlgoEd25519:
 		return SignatureAlgoEd25519Specifier
+	case SignatureAlgoSecp256k1:
+		return SignatureAlgoSecp256k1Specifier
 	default:
 		return SignatureAlgoNilSpecifier
 	}
@@ -58,6 +66,8 @@ func (sat *SignatureAlgoType) LoadString(str string) error {
 	switch str {
 	case SignatureAlgoEd25519Specifier.String():
 		*sat = SignatureAlgoEd25519
+	case SignatureAlgoSecp256k1Specifier.String():
+		*sat = SignatureAlgoSecp256k1
 	case SignatureAlgoNilSpecifier.String():
 		*sat = SignatureAlgoNil
 	default:
@@ -71,6 +81,8 @@ func (sat *SignatureAlgoType) LoadSpecifier(specifier Specifier) error {
 	switch specifier {
 	case SignatureAlgoEd25519Specifier:
 		*sat = SignatureAlgoEd25519
+	case SignatureAlgoSecp256k1Specifier:
+		*sat = SignatureAlgoSecp256k1
 	case SignatureAlgoNilSpecifier:
 		*sat = SignatureAlgoNil
 	default:
@@ -89,6 +101,9 @@ var (
 	//ErrPublicKeyOveruse          = errors.New("public key was used multiple times while signing transaction")
 	//ErrSortedUniqueViolation     = errors.New("sorted unique violation")
 	ErrUnlockHashWrongLen = errors.New("marshalled unlock hash is the wrong length")
+	// ErrUnlockHashWrongPrefix is returned when loading a bech32 unlock hash
+	// which uses the address prefix of another chain.
+	ErrUnlockHashWrongPrefix = errors.New("provided unlock hash has the address prefix of another chain")
 )
 
 type (
@@ -116,6 +131,23 @@ func Ed25519PublicKey(pk crypto.PublicKey) PublicKey {
 	}
 }
 
+// Secp256k1PublicKey returns pk as a PublicKey, denoting its algorithm as
+// Secp256k1.
+func Secp256k1PublicKey(pk crypto.Secp256k1PublicKey) PublicKey {
+	return PublicKey{
+		Algorithm: SignatureAlgoSecp256k1,
+		Key:       pk[:],
+	}
+}
+
+// UnlockType returns the type of the unlock hash derived from this public key.
+func (pk PublicKey) UnlockType() UnlockType {
+	if pk.Algorithm == SignatureAlgoSecp256k1 {
+		return UnlockTypeSecp256k1PubKey
+	}
+	return UnlockTypePubKey
+}
+
 // SignatureHash returns the hash of all fields in a transaction,
 // relevant to a Tx sig.
 func (t Transaction) SignatureHash(extraObjects ...interface{}) (crypto.Hash, error) {
@@ -128,11 +160,19 @@ func (t Transaction) SignatureHash(extraObjects ...interface{}) (crypto.Hash, er
 		// use it here to sign the input with it
 		return hasher.SignatureHash(t, extraObjects...)
 	}
+	return defaultSignatureHash(t, nil, extraObjects...), nil
+}
 
+// defaultSignatureHash computes the default signature hash of a transaction,
+// committing to the given (version-specific) objects right after the version, should any be defined.
+func defaultSignatureHash(t Transaction, versionObjects []interface{}, extraObjects ...interface{}) crypto.Hash {
 	h := crypto.NewHash()
 	enc := siabin.NewEncoder(h)
 
 	enc.Encode(t.Version)
+	if len(versionObjects) > 0 {
+		enc.EncodeAll(versionObjects...)
+	}
 	if len(extraObjects) > 0 {
 		enc.EncodeAll(extraObjects...)
 	}
@@ -153,7 +193,7 @@ func (t Transaction) SignatureHash(extraObjects ...interface{}) (crypto.Hash, er
 
 	var hash crypto.Hash
 	h.Sum(hash[:0])
-	return hash, nil
+	return hash
 }
 
 func legacyUnlockHashCondition(uc UnlockCondition) UnlockHash {
@@ -246,6 +286,8 @@ func (pk *PublicKey) UnmarshalRivine(r io.Reader) error {
 	switch pk.Algorithm {
 	case SignatureAlgoEd25519:
 		pk.Key = make(ByteSlice, crypto.PublicKeySize)
+	case SignatureAlgoSecp256k1:
+		pk.Key = make(ByteSlice, crypto.Secp256k1PublicKeySize)
 	case SignatureAlgoNil:
 		pk.Key = nil
 	default:
@@ -7,7 +7,9 @@ import (
 	"testing"
 
 	"github.com/NebulousLabs/fastrand"
+	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
 )
 
 // TestEd25519PublicKey tests the Ed25519PublicKey function.
@@ -36,6 +38,91 @@ func TestSigHash(t *testing.T) {
 	txn.SignatureHash(0)
 }
 
+// TestChainIDSigHash ensures that the signature hash of
+// a TransactionVersionTwo transaction commits to the chain ID.
+func TestChainIDSigHash(t *testing.T) {
+	info := DefaultBlockchainInfo()
+	cts := TestnetChainConstants()
+	chainID := info.ChainID(cts.GenesisBlockID())
+	if chainID == (ChainID{}) {
+		t.Fatal("expected a non-nil chain ID")
+	}
+	otherInfo := info
+	otherInfo.NetworkName = "othernet"
+	otherChainID := otherInfo.ChainID(cts.GenesisBlockID())
+	if otherChainID == chainID {
+		t.Fatal("expected a different chain ID for a different network")
+	}
+	if info.ChainID(BlockID{1}) == chainID {
+		t.Fatal("expected a different chain ID for a different genesis block")
+	}
+	otherInfo = info
+	otherInfo.ChainVersion = build.NewVersion(42, 0, 0, 0)
+	if otherInfo.ChainID(cts.GenesisBlockID()) != chainID {
+		t.Fatal("expected the chain version to not be part of the chain ID")
+	}
+
+	defer RegisterTransactionVersion(TransactionVersionTwo, nil)
+	RegisterTransactionVersion(TransactionVersionTwo, ChainIDTransactionController{ChainID: chainID})
+
+	sk, rpk := crypto.GenerateKeyPair()
+	pk := Ed25519PublicKey(rpk)
+	uh, err := NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := Transaction{
+		Version:       TransactionVersionTwo,
+		CoinInputs:    []CoinInput{{ParentID: CoinOutputID{4, 2}}},
+		MinerFees:     []Currency{NewCurrency64(1)},
+		ArbitraryData: []byte("chain id"),
+	}
+	fulfillment := NewSingleSignatureFulfillment(pk)
+	err = fulfillment.Sign(FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  txn,
+		Key:          sk,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockHeight:  0,
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+	}
+	err = NewUnlockHashCondition(uh).Fulfill(fulfillment, ctx)
+	if err != nil {
+		t.Fatal("expected fulfillment to be valid for the chain it was signed for:", err)
+	}
+
+	// the same transaction is not valid on a sibling chain
+	RegisterTransactionVersion(TransactionVersionTwo, ChainIDTransactionController{ChainID: otherChainID})
+	err = NewUnlockHashCondition(uh).Fulfill(fulfillment, ctx)
+	if err == nil {
+		t.Fatal("expected fulfillment to be invalid on another chain")
+	}
+
+	// the chain ID is the only difference with the default signature hash
+	v1Txn := txn
+	v1Txn.Version = TransactionVersionOne
+	v1Hash, err := v1Txn.SignatureHash(uint64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2Hash, err := txn.SignatureHash(uint64(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v1Hash == v2Hash || v1Hash != defaultSignatureHash(v1Txn, nil, uint64(0)) {
+		t.Fatal("unexpected signature hashes")
+	}
+	if v2Hash != defaultSignatureHash(txn, []interface{}{otherChainID}, uint64(0)) {
+		t.Fatal("expected the signature hash to commit to the registered chain ID")
+	}
+}
+
 // TestSortedUnique probes the sortedUnique function.
 func TestSortedUnique(t *testing.T) {
 	su := []uint64{3, 5, 6, 8, 12}
@@ -139,3 +226,138 @@ func TestPublicKeyString(t *testing.T) {
 		t.Error("got wrong value for spk.String():", spk.String())
 	}
 }
+
+// TestSecp256k1PublicKey tests the Secp256k1PublicKey function,
+// as well as the encoding and unlock hash of such public keys.
+func TestSecp256k1PublicKey(t *testing.T) {
+	_, pk := crypto.GenerateSecp256k1KeyPair()
+	spk := Secp256k1PublicKey(pk)
+	if spk.Algorithm != SignatureAlgoSecp256k1 {
+		t.Error("spk created key with wrong algorithm specifier:", spk.Algorithm)
+	}
+	if !bytes.Equal(spk.Key, pk[:]) {
+		t.Error("Secp256k1PublicKey created key with wrong data")
+	}
+
+	uh, err := NewPubKeyUnlockHash(spk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uh.Type != UnlockTypeSecp256k1PubKey {
+		t.Error("unexpected unlock type:", uh.Type)
+	}
+	var luh UnlockHash
+	err = luh.LoadString(uh.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if luh.Cmp(uh) != 0 {
+		t.Error("unexpected unlock hash:", luh, "!=", uh)
+	}
+
+	var str PublicKey
+	err = str.LoadString(spk.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(str, spk) {
+		t.Error("unexpected public key:", str.String(), "!=", spk.String())
+	}
+	b, err := rivbin.Marshal(spk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 1+crypto.Secp256k1PublicKeySize {
+		t.Error("unexpected rivine-encoded public key length:", len(b))
+	}
+	var rpk PublicKey
+	err = rivbin.Unmarshal(b, &rpk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rpk, spk) {
+		t.Error("unexpected public key:", rpk.String(), "!=", spk.String())
+	}
+}
+
+// TestValidSecp256k1FulFill ensures that secp256k1 keys
+// can be used to sign and fulfill the conditions which require a signature.
+func TestValidSecp256k1FulFill(t *testing.T) {
+	sk, rpk := crypto.GenerateSecp256k1KeyPair()
+	pk := Secp256k1PublicKey(rpk)
+	uh, err := NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edSK, edRPK := crypto.GenerateKeyPair()
+	edPK := Ed25519PublicKey(edRPK)
+	edUH, err := NewPubKeyUnlockHash(edPK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherUH := unlockHashFromHex("01437c56286c76dec14e87f5da5e5a436651006e6cd46bee5865c9060ba178f7296ed843b70a57")
+
+	testValidSignAndFulfill(t, 0, []signAndFulfillInput{
+		{ // unlock hash -> single signature
+			NewUnlockHashCondition(uh),
+			func() MarshalableUnlockFulfillment {
+				return NewSingleSignatureFulfillment(pk)
+			},
+			sk,
+		},
+		{ // atomic swap -> atomic swap (refund)
+			&AtomicSwapCondition{
+				Sender:       uh,
+				Receiver:     otherUH,
+				HashedSecret: NewAtomicSwapHashedSecret(AtomicSwapSecret{4, 2}),
+				TimeLock:     42, // we are waaaaay beyond this
+			},
+			func() MarshalableUnlockFulfillment {
+				return &AtomicSwapFulfillment{PublicKey: pk}
+			},
+			sk,
+		},
+		{ // atomic swap -> atomic swap (claim)
+			&AtomicSwapCondition{
+				Sender:       otherUH,
+				Receiver:     uh,
+				HashedSecret: NewAtomicSwapHashedSecret(AtomicSwapSecret{4, 2}),
+				TimeLock:     CurrentTimestamp() + 123456,
+			},
+			func() MarshalableUnlockFulfillment {
+				return &AtomicSwapFulfillment{PublicKey: pk, Secret: AtomicSwapSecret{4, 2}}
+			},
+			sk,
+		},
+	})
+
+	// multisig fulfillments are signed by multiple keys, mixing signature algorithms here
+	txn := Transaction{Version: TransactionVersionOne}
+	condition := NewMultiSignatureCondition(UnlockHashSlice{uh, edUH}, 2)
+	fulfillment := &MultiSignatureFulfillment{}
+	for _, kp := range []KeyPair{
+		{PublicKey: pk, PrivateKey: ByteSlice(sk[:])},
+		{PublicKey: edPK, PrivateKey: ByteSlice(edSK[:])},
+	} {
+		err = fulfillment.Sign(FulfillmentSignContext{
+			ExtraObjects: []interface{}{uint64(0)},
+			Transaction:  txn,
+			Key:          kp,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = fulfillment.IsStandardFulfillment(ValidationContext{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = condition.Fulfill(fulfillment, FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
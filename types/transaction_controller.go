@@ -75,6 +75,17 @@ type (
 		SignatureHash(t Transaction, extraObjects ...interface{}) (crypto.Hash, error)
 	}
 
+	// TransactionSigHashFlagsHasher defines the interface a transaction controller
+	// can optionally implement, in order to support signature hash flags,
+	// allowing signatures to only commit to part of the transaction.
+	// Signatures of transactions of such a version carry their flags as a single byte appended to the signature.
+	TransactionSigHashFlagsHasher interface {
+		// SignatureHashWithFlags returns the hash signed by a signature with the given flags,
+		// the parentID is the ID of the output spent by the input that is signed,
+		// and only has to be defined when the SigHashInputSingle flag is used.
+		SignatureHashWithFlags(t Transaction, flags SigHashFlags, parentID OutputID, extraObjects ...interface{}) (crypto.Hash, error)
+	}
+
 	// TransactionIDEncoder is an optional interface a transaction controller
 	// can implement, in order to use a different binary encoding for ID-generation purposes,
 	// instead of using the default binary encoding logic for that transaction (version).
@@ -173,6 +184,19 @@ type (
 	// and is also by default the controller for the default transaction version 0x01.
 	DefaultTransactionController struct{}
 
+	// ChainIDTransactionController is the transaction controller used for TransactionVersionTwo.
+	// It encodes transactions exactly like the DefaultTransactionController,
+	// but commits to the ID of the chain in the signature hash,
+	// such that a transaction signed for one chain can't be replayed on another chain.
+	//
+	// As the chain ID is only known at runtime, this controller is not registered by default,
+	// and has to be registered by the daemon (and client) of a chain which wishes to use it.
+	// Daemons can do so using the RegisterChainTransactionVersions method of their daemon.NetworkConfig.
+	ChainIDTransactionController struct {
+		DefaultTransactionController
+		ChainID ChainID
+	}
+
 	// LegacyTransactionController is a legacy transaction controller,
 	// which used to be the default when Rivine launched.
 	// It should however not be used any longer, and only exists,
@@ -215,6 +239,11 @@ func (dtc DefaultTransactionController) JSONDecodeTransactionData(b []byte) (td
 	return
 }
 
+// SignatureHash implements TransactionSignatureHasher.SignatureHash
+func (ctc ChainIDTransactionController) SignatureHash(t Transaction, extraObjects ...interface{}) (crypto.Hash, error) {
+	return defaultSignatureHash(t, []interface{}{ctc.ChainID}, extraObjects...), nil
+}
+
 // EncodeTransactionData implements TransactionController.EncodeTransactionData
 func (ltc LegacyTransactionController) EncodeTransactionData(w io.Writer, td TransactionData) error {
 	// turn the transaction data into the legacy format first
@@ -308,6 +337,9 @@ func (ltc LegacyTransactionController) EncodeTransactionIDInput(w io.Writer, td
 // ensures at compile time that the Transaction Controller implement all desired interfaces
 var (
 	_ TransactionController = DisabledTransactionController{}
+
+	_ TransactionController      = ChainIDTransactionController{}
+	_ TransactionSignatureHasher = ChainIDTransactionController{}
 )
 
 // DisabledTransactionController is used for transaction versions that are disabled but still need to be JSON decodable.
@@ -42,6 +42,19 @@ const (
 	// TransactionVersionOne defines the new (default) transaction version,
 	// which deprecates and is based upon TransactionVersionZero.
 	TransactionVersionOne
+	// TransactionVersionTwo defines the transaction version which is identical to
+	// TransactionVersionOne, except that its signature hash commits to the chain ID as well,
+	// protecting it from being replayed on sibling chains which share the same address format.
+	// See ChainIDTransactionController for more information.
+	TransactionVersionTwo
+	// TransactionVersionThree defines the transaction version which is identical to
+	// TransactionVersionTwo, except that it can optionally define the last block height
+	// at which it can be included in a block. See ValidUntilTransactionController for more information.
+	TransactionVersionThree
+	// TransactionVersionFour defines the transaction version which is identical to
+	// TransactionVersionTwo, except that each signature defines which parts of the transaction it signs,
+	// using signature hash flags. See SigHashTransactionController for more information.
+	TransactionVersionFour
 )
 
 type (
@@ -264,6 +277,9 @@ func (t Transaction) CustomMinerPayouts() ([]MinerPayout, error) {
 // Tx Extension data as a single struct.
 type CommonTransactionExtensionData struct {
 	UnlockConditions []UnlockConditionProxy
+	// CoinOutputAssets defines for each coin output the ID of the asset it holds,
+	// nil in case all coin outputs hold the native coin.
+	CoinOutputAssets []AssetID
 }
 
 // CommonExtensionData returns the common-understood Extension data.
@@ -217,6 +217,13 @@ type (
 		// (Private) key to be used for signing, what type it is or whether it is defined at all
 		// is of no importance, as long as the fulfillment supports its (none) definition.
 		Key interface{}
+		// SigHashFlags defines which parts of the transaction are signed,
+		// by default (SigHashAll) the entire transaction is signed.
+		// Only transaction versions which support signature hash flags accept non-default flags.
+		SigHashFlags SigHashFlags
+		// ParentID is the ID of the output spent by the input that is signed,
+		// required only when signing using the SigHashInputSingle flag.
+		ParentID OutputID
 	}
 
 	// FulfillContext is given as part of the fulfill call of an UnlockCondition,
@@ -230,8 +237,14 @@ type (
 		// BlockTime defines the time of the currently last registered block,
 		// the transaction belonged to.
 		BlockTime Timestamp
+		// ConfirmationHeight defines the height of the block in which
+		// the (parent) output, owning the condition, was confirmed.
+		ConfirmationHeight BlockHeight
 		// (Parent) transaction the fulfillment belongs to.
 		Transaction Transaction
+		// ParentID is the ID of the output spent by the input that is fulfilled,
+		// required only to validate signatures created using the SigHashInputSingle flag.
+		ParentID OutputID
 	}
 
 	// FulfillableContext is given as part of the fulfillable call of an UnlockCondition,
@@ -242,6 +255,9 @@ type (
 		// BlockTime defines the time of the currently last registered block,
 		// the transaction belonged to.
 		BlockTime Timestamp
+		// ConfirmationHeight defines the height of the block in which
+		// the (parent) output, owning the condition, was confirmed.
+		ConfirmationHeight BlockHeight
 	}
 
 	// FundValidationContext is used for coin- and block stake- validators,
@@ -305,6 +321,8 @@ const (
 	// NilCondition,
 	// UnlockHashCondition (0x01 unlock hash type is the only standard one at the moment, others aren't allowed),
 	// MultiSignatureCondition,
+	// WeightedMultiSignatureCondition,
+	// HashLockCondition,
 	// ]
 	ConditionTypeTimeLock
 
@@ -320,6 +338,49 @@ const (
 	//
 	// Implemented by the MultiSignatureCondition type
 	ConditionTypeMultiSignature
+
+	// ConditionTypeRelativeTimeLock defines an unlock condition
+	// which locks another condition for an amount of blocks,
+	// relative to the height of the block in which the output,
+	// using this condition, is confirmed.
+	// The internal condition has to be one of: [
+	// NilCondition,
+	// UnlockHashCondition (0x01 unlock hash type is the only standard one at the moment, others aren't allowed),
+	// MultiSignatureCondition,
+	// WeightedMultiSignatureCondition,
+	// HashLockCondition,
+	// ]
+	//
+	// Implemented by the RelativeTimeLockCondition type.
+	ConditionTypeRelativeTimeLock
+
+	// ConditionTypePolicy defines an unlock condition which composes
+	// multiple (sub) conditions, using an AND, OR or threshold (k-of-n) operator.
+	// Policy conditions can be nested, up to PolicyConditionMaxDepth levels deep.
+	// It can be fulfilled only by a PolicyFulfillment.
+	//
+	// Implemented by the PolicyCondition type.
+	ConditionTypePolicy
+
+	// ConditionTypeWeightedMultiSignature defines an unlock condition which
+	// can only be unlocked by multiple signatures, similar to ConditionTypeMultiSignature.
+	// Rather than requiring a minimum amount of signatures, each signatory has a weight,
+	// and the combined weight of the signatories which sign has to reach a minimum weight.
+	// It can be fulfilled only by a WeightedMultiSignatureFulfillment.
+	//
+	// Implemented by the WeightedMultiSignatureCondition type.
+	ConditionTypeWeightedMultiSignature
+
+	// ConditionTypeHashLock defines an unlock condition which can be unlocked
+	// by anyone who reveals the pre-image of the hash it defines. Contrary to the
+	// AtomicSwapCondition, the hash function is selectable, the pre-image can be of any
+	// (standard) length, and no signature is required. It can be fulfilled only by a HashLockFulfillment.
+	//
+	// As the pre-image becomes public as soon as it is broadcasted,
+	// it is usually composed with a signature requirement using a PolicyCondition.
+	//
+	// Implemented by the HashLockCondition type.
+	ConditionTypeHashLock
 )
 
 // The following enumeration defines the different possible and standard
@@ -362,6 +423,22 @@ const (
 	//
 	// Implemented by the MultiSignatureFulfillment type
 	FulfillmentTypeMultiSignature
+	// FulfillmentTypePolicy defines the policy fulfillment, and is defined by
+	// the (sub) fulfillments of the branches of the PolicyCondition it fulfills,
+	// each branch identified by the index of the sub condition it fulfills.
+	//
+	// Implemented by the PolicyFulfillment type
+	FulfillmentTypePolicy
+	// FulfillmentTypeWeightedMultiSignature defines the weighted multisig fulfillment,
+	// defined, just like the multisig fulfillment, by public key and signature pairs.
+	//
+	// Implemented by the WeightedMultiSignatureFulfillment type
+	FulfillmentTypeWeightedMultiSignature
+	// FulfillmentTypeHashLock defines the hash lock fulfillment,
+	// and is defined by the pre-image of the hash defined by the HashLockCondition it fulfills.
+	//
+	// Implemented by the HashLockFulfillment type
+	FulfillmentTypeHashLock
 )
 
 // Constants that are used as part of AtomicSwap Conditions/Fulfillments.
@@ -471,6 +548,12 @@ var (
 		ConditionTypeAtomicSwap:     func() MarshalableUnlockCondition { return &AtomicSwapCondition{} },
 		ConditionTypeTimeLock:       func() MarshalableUnlockCondition { return &TimeLockCondition{} },
 		ConditionTypeMultiSignature: func() MarshalableUnlockCondition { return &MultiSignatureCondition{} },
+
+		ConditionTypeRelativeTimeLock: func() MarshalableUnlockCondition { return &RelativeTimeLockCondition{} },
+		ConditionTypePolicy:           func() MarshalableUnlockCondition { return &PolicyCondition{} },
+
+		ConditionTypeWeightedMultiSignature: func() MarshalableUnlockCondition { return &WeightedMultiSignatureCondition{} },
+		ConditionTypeHashLock:               func() MarshalableUnlockCondition { return &HashLockCondition{} },
 	}
 	// Manipulated by the RegisterUnlockFulfillmentType function,
 	// and used by the UnlockFulfillmentProxy.
@@ -479,6 +562,10 @@ var (
 		FulfillmentTypeSingleSignature: func() MarshalableUnlockFulfillment { return &SingleSignatureFulfillment{} },
 		FulfillmentTypeAtomicSwap:      func() MarshalableUnlockFulfillment { return &anyAtomicSwapFulfillment{} },
 		FulfillmentTypeMultiSignature:  func() MarshalableUnlockFulfillment { return &MultiSignatureFulfillment{} },
+		FulfillmentTypePolicy:          func() MarshalableUnlockFulfillment { return &PolicyFulfillment{} },
+
+		FulfillmentTypeWeightedMultiSignature: func() MarshalableUnlockFulfillment { return &WeightedMultiSignatureFulfillment{} },
+		FulfillmentTypeHashLock:               func() MarshalableUnlockFulfillment { return &HashLockFulfillment{} },
 	}
 )
 
@@ -561,6 +648,20 @@ type (
 		Condition MarshalableUnlockCondition
 	}
 
+	// RelativeTimeLockCondition defines an unlock condition which requires an amount of blocks
+	// to be created on top of the block which confirmed the (parent) output using this condition,
+	// on top of some other defined condition, which both have to be fulfilled
+	// in order to unlock/spend/use the unspend output as an input.
+	RelativeTimeLockCondition struct {
+		// LockDuration defines the amount of blocks, relative to the height
+		// of the block which confirmed the (parent) output, after which the output can be spent.
+		LockDuration BlockHeight
+		// Condition defines the condition which has to be fulfilled
+		// on top of the LockDuration condition defined by this condition.
+		// See ConditionTypeRelativeTimeLock in order to know which conditions are supported.
+		Condition MarshalableUnlockCondition
+	}
+
 	// MultiSignatureCondition implements the ConditionTypeMultiSignature ConditionType.
 	// See ConditionTypeMultiSignature for more information.
 	MultiSignatureCondition struct {
@@ -625,7 +726,7 @@ func (n *NilCondition) Fulfill(fulfillment UnlockFulfillment, ctx FulfillContext
 	switch tf := fulfillment.(type) {
 	case *SingleSignatureFulfillment:
 		return verifyHashUsingPublicKey(tf.PublicKey,
-			ctx.Transaction, tf.Signature, ctx.ExtraObjects)
+			ctx, tf.Signature, ctx.ExtraObjects)
 	default:
 		return ErrUnexpectedUnlockFulfillment
 	}
@@ -700,8 +801,8 @@ func NewUnlockHashCondition(uh UnlockHash) *UnlockHashCondition {
 func (uh *UnlockHashCondition) Fulfill(fulfillment UnlockFulfillment, ctx FulfillContext) error {
 	switch tf := fulfillment.(type) {
 	case *SingleSignatureFulfillment:
-		// only UnlockTypePubKey is supported when fulfilling using a SingleSignatureFulfillment
-		if uh.TargetUnlockHash.Type != UnlockTypePubKey {
+		// only public key unlock types are supported when fulfilling using a SingleSignatureFulfillment
+		if !uh.TargetUnlockHash.Type.IsPubKey() {
 			return ErrUnexpectedUnlockType
 		}
 
@@ -712,7 +813,7 @@ func (uh *UnlockHashCondition) Fulfill(fulfillment UnlockFulfillment, ctx Fulfil
 		if euh != uh.TargetUnlockHash {
 			return errors.New("single signature fulfillment provides wrong public key")
 		}
-		return verifyHashUsingPublicKey(tf.PublicKey, ctx.Transaction, tf.Signature, ctx.ExtraObjects)
+		return verifyHashUsingPublicKey(tf.PublicKey, ctx, tf.Signature, ctx.ExtraObjects)
 
 	case *LegacyAtomicSwapFulfillment:
 		// only UnlockTypeAtomicSwap is supported when fulfilling using a LegacyAtomicSwapFulfillment
@@ -749,7 +850,7 @@ func (uh *UnlockHashCondition) Fulfill(fulfillment UnlockFulfillment, ctx Fulfil
 
 			// verify signature
 			err := verifyHashUsingPublicKey(
-				tf.PublicKey, ctx.Transaction, tf.Signature,
+				tf.PublicKey, ctx, tf.Signature,
 				mergeExtraObjects(ctx.ExtraObjects, tf.PublicKey, tf.Secret))
 			if err != nil {
 				return err
@@ -773,7 +874,7 @@ func (uh *UnlockHashCondition) Fulfill(fulfillment UnlockFulfillment, ctx Fulfil
 		// after the deadline (timelock),
 		// only the original sender can reclaim the unspend output
 		return verifyHashUsingPublicKey(
-			tf.PublicKey, ctx.Transaction, tf.Signature,
+			tf.PublicKey, ctx, tf.Signature,
 			mergeExtraObjects(ctx.ExtraObjects, tf.PublicKey))
 
 	case *anyAtomicSwapFulfillment:
@@ -789,7 +890,7 @@ func (uh *UnlockHashCondition) ConditionType() ConditionType { return ConditionT
 
 // IsStandardCondition implements UnlockCondition.IsStandardCondition
 func (uh *UnlockHashCondition) IsStandardCondition(ValidationContext) error {
-	if uh.TargetUnlockHash.Type != UnlockTypePubKey && uh.TargetUnlockHash.Type != UnlockTypeAtomicSwap {
+	if !uh.TargetUnlockHash.Type.IsPubKey() && uh.TargetUnlockHash.Type != UnlockTypeAtomicSwap {
 		return fmt.Errorf("unsupported unlock type '%d' by unlock hash condition", uh.TargetUnlockHash.Type)
 	}
 	if uh.TargetUnlockHash.Hash == (crypto.Hash{}) {
@@ -838,7 +939,7 @@ func (ss *SingleSignatureFulfillment) Sign(ctx FulfillmentSignContext) (err erro
 		return ErrFulfillmentDoubleSign
 	}
 
-	ss.Signature, err = signHashUsingPublicKey(ss.PublicKey, ctx.Transaction, ctx.Key, ctx.ExtraObjects)
+	ss.Signature, err = signHashUsingPublicKey(ss.PublicKey, ctx, ctx.Key, ctx.ExtraObjects)
 	return
 }
 
@@ -888,15 +989,10 @@ func (as *AtomicSwapCondition) Fulfill(fulfillment UnlockFulfillment, ctx Fulfil
 		//     a valid signature for the initiator's address is provided.
 
 		// create the unlockHash for the given public Ke
-		pkb, err := siabin.Marshal(tf.PublicKey)
-		if err != nil {
-			return err
-		}
-		pkh, err := crypto.HashObject(pkb)
+		unlockHash, err := NewPubKeyUnlockHash(tf.PublicKey)
 		if err != nil {
 			return err
 		}
-		unlockHash := NewUnlockHash(UnlockTypePubKey, pkh)
 
 		// if secret is given, we'll assume that the participator (receiver) wants to claim
 		if tf.Secret != (AtomicSwapSecret{}) {
@@ -913,7 +1009,7 @@ func (as *AtomicSwapCondition) Fulfill(fulfillment UnlockFulfillment, ctx Fulfil
 
 			// verify signature
 			return verifyHashUsingPublicKey(
-				tf.PublicKey, ctx.Transaction, tf.Signature,
+				tf.PublicKey, ctx, tf.Signature,
 				mergeExtraObjects(ctx.ExtraObjects, tf.PublicKey, tf.Secret))
 		}
 
@@ -930,7 +1026,7 @@ func (as *AtomicSwapCondition) Fulfill(fulfillment UnlockFulfillment, ctx Fulfil
 		}
 		// verify the signature is indeed done by
 		return verifyHashUsingPublicKey(
-			tf.PublicKey, ctx.Transaction, tf.Signature,
+			tf.PublicKey, ctx, tf.Signature,
 			mergeExtraObjects(ctx.ExtraObjects, tf.PublicKey))
 
 	case *LegacyAtomicSwapFulfillment:
@@ -970,10 +1066,10 @@ func (as *AtomicSwapCondition) ConditionType() ConditionType { return ConditionT
 
 // IsStandardCondition implements UnlockCondition.IsStandardCondition
 func (as *AtomicSwapCondition) IsStandardCondition(ValidationContext) error {
-	if as.Sender.Type != UnlockTypePubKey {
+	if !as.Sender.Type.IsPubKey() {
 		return fmt.Errorf("unsupported unlock hash sender type: %d", as.Sender.Type)
 	}
-	if as.Receiver.Type != UnlockTypePubKey {
+	if !as.Receiver.Type.IsPubKey() {
 		return fmt.Errorf("unsupported unlock hash receiver type: %d", as.Receiver.Type)
 	}
 	if as.Sender.Hash == (crypto.Hash{}) || as.Receiver.Hash == (crypto.Hash{}) {
@@ -1060,7 +1156,7 @@ func (as *AtomicSwapFulfillment) Sign(ctx FulfillmentSignContext) error {
 		// sign as claimer
 		var err error
 		as.Signature, err = signHashUsingPublicKey(
-			as.PublicKey, ctx.Transaction, ctx.Key,
+			as.PublicKey, ctx, ctx.Key,
 			mergeExtraObjects(ctx.ExtraObjects, as.PublicKey, as.Secret))
 		return err
 	}
@@ -1068,7 +1164,7 @@ func (as *AtomicSwapFulfillment) Sign(ctx FulfillmentSignContext) error {
 	// sign as refunder
 	var err error
 	as.Signature, err = signHashUsingPublicKey(
-		as.PublicKey, ctx.Transaction, ctx.Key,
+		as.PublicKey, ctx, ctx.Key,
 		mergeExtraObjects(ctx.ExtraObjects, as.PublicKey))
 	return err
 }
@@ -1123,7 +1219,7 @@ func (as *LegacyAtomicSwapFulfillment) Sign(ctx FulfillmentSignContext) error {
 		// sign as claimer
 		var err error
 		as.Signature, err = signHashUsingPublicKey(
-			as.PublicKey, ctx.Transaction, ctx.Key,
+			as.PublicKey, ctx, ctx.Key,
 			mergeExtraObjects(ctx.ExtraObjects, as.PublicKey, as.Secret))
 		return err
 	}
@@ -1131,7 +1227,7 @@ func (as *LegacyAtomicSwapFulfillment) Sign(ctx FulfillmentSignContext) error {
 	// sign as refunder
 	var err error
 	as.Signature, err = signHashUsingPublicKey(
-		as.PublicKey, ctx.Transaction, ctx.Key,
+		as.PublicKey, ctx, ctx.Key,
 		mergeExtraObjects(ctx.ExtraObjects, as.PublicKey))
 	return err
 }
@@ -1143,7 +1239,7 @@ func (as *LegacyAtomicSwapFulfillment) FulfillmentType() FulfillmentType {
 
 // IsStandardFulfillment implements UnlockFulfillment.IsStandardFulfillment
 func (as *LegacyAtomicSwapFulfillment) IsStandardFulfillment(ValidationContext) error {
-	if as.Sender.Type != UnlockTypePubKey || as.Receiver.Type != UnlockTypePubKey {
+	if !as.Sender.Type.IsPubKey() || !as.Receiver.Type.IsPubKey() {
 		return errors.New("unsupported unlock hash type")
 	}
 	if as.Sender.Hash == (crypto.Hash{}) || as.Receiver.Hash == (crypto.Hash{}) {
@@ -1209,6 +1305,7 @@ var (
 	_ MarshalableUnlockCondition = (*UnlockHashCondition)(nil)
 	_ MarshalableUnlockCondition = (*AtomicSwapCondition)(nil)
 	_ MarshalableUnlockCondition = (*MultiSignatureCondition)(nil)
+	_ MarshalableUnlockCondition = (*RelativeTimeLockCondition)(nil)
 
 	_ MarshalableUnlockFulfillment = (*NilFulfillment)(nil)
 	_ MarshalableUnlockFulfillment = (*SingleSignatureFulfillment)(nil)
@@ -1410,6 +1507,10 @@ func (tl *TimeLockCondition) Fulfill(fulfillment UnlockFulfillment, ctx FulfillC
 		return tl.Condition.Fulfill(tf, ctx)
 	case *MultiSignatureFulfillment:
 		return tl.Condition.Fulfill(tf, ctx)
+	case *WeightedMultiSignatureFulfillment:
+		return tl.Condition.Fulfill(tf, ctx)
+	case *HashLockFulfillment:
+		return tl.Condition.Fulfill(tf, ctx)
 	default:
 		return ErrUnexpectedUnlockFulfillment
 	}
@@ -1423,18 +1524,26 @@ func (tl *TimeLockCondition) IsStandardCondition(ctx ValidationContext) error {
 	if tl.LockTime == 0 {
 		return errors.New("lock time has to be defined")
 	}
-	switch ct := tl.Condition.ConditionType(); ct {
+	return isStandardTimeLockedCondition(tl.Condition, ctx)
+}
+
+// isStandardTimeLockedCondition returns if the given condition,
+// locked by a (relative) time lock condition, is standard.
+// Hash locks are only accepted here, the multisig conditions only accept public key unlock hashes,
+// a hash lock combined with multiple signatures is to be defined using a PolicyCondition instead.
+func isStandardTimeLockedCondition(condition MarshalableUnlockCondition, ctx ValidationContext) error {
+	switch ct := condition.ConditionType(); ct {
 	case ConditionTypeUnlockHash:
-		uh := tl.Condition.UnlockHash()
+		uh := condition.UnlockHash()
 		if uh.Hash == (crypto.Hash{}) {
 			return errors.New("nil crypto hash cannot be used as unlock hash")
 		}
-		if uh.Type != UnlockTypePubKey {
+		if !uh.Type.IsPubKey() {
 			return errors.New("non-standard unlock hash type")
 		}
 		return nil
-	case ConditionTypeMultiSignature:
-		return tl.Condition.IsStandardCondition(ctx)
+	case ConditionTypeMultiSignature, ConditionTypeWeightedMultiSignature, ConditionTypeHashLock:
+		return condition.IsStandardCondition(ctx)
 	case ConditionTypeNil:
 		return nil
 	default:
@@ -1564,8 +1673,8 @@ func NewMultiSignatureCondition(uhs UnlockHashSlice, minsigs uint64) *MultiSigna
 	}
 	if build.DEBUG {
 		for _, uh := range uhs {
-			if uh.Type != UnlockTypePubKey {
-				build.Critical("Unlock hashes used in multisig condition must have the UnlockTypePubKey type")
+			if !uh.Type.IsPubKey() {
+				build.Critical("Unlock hashes used in multisig condition must have a public key unlock type")
 			}
 		}
 
@@ -1608,7 +1717,7 @@ func (ms *MultiSignatureCondition) Fulfill(fulfillment UnlockFulfillment, ctx Fu
 	// Finally verify all the signatures
 	for _, pks := range tf.Pairs {
 		if err := verifyHashUsingPublicKey(
-			pks.PublicKey, ctx.Transaction, pks.Signature,
+			pks.PublicKey, ctx, pks.Signature,
 			mergeExtraObjects(ctx.ExtraObjects, pks.PublicKey),
 		); err != nil {
 			return err
@@ -1633,7 +1742,7 @@ func (ms *MultiSignatureCondition) IsStandardCondition(ValidationContext) error
 		return errors.New("The minimum amount of signatures can't be higher than the amount of unlockhashes")
 	}
 	for idx, uh := range ms.UnlockHashes {
-		if uh.Type != UnlockTypePubKey {
+		if !uh.Type.IsPubKey() {
 			return fmt.Errorf("unsupported unlock hash #%d type: %d", idx, uh.Type)
 		}
 	}
@@ -1802,7 +1911,7 @@ func (ms *MultiSignatureFulfillment) Sign(ctx FulfillmentSignContext) (err error
 	}
 
 	signature, err := signHashUsingPublicKey(
-		keypair.PublicKey, ctx.Transaction, keypair.PrivateKey,
+		keypair.PublicKey, ctx, keypair.PrivateKey,
 		mergeExtraObjects(ctx.ExtraObjects, keypair.PublicKey))
 	if err != nil {
 		return
@@ -1823,6 +1932,165 @@ func (ms *MultiSignatureFulfillment) Unmarshal(b []byte, f UnmarshalFunc) error
 	return f(b, &ms.Pairs)
 }
 
+// NewRelativeTimeLockCondition creates a new RelativeTimeLockCondition.
+// If no MarshalableUnlockCondition is given, the NilCondition is assumed.
+func NewRelativeTimeLockCondition(lockDuration BlockHeight, condition MarshalableUnlockCondition) *RelativeTimeLockCondition {
+	if lockDuration == 0 {
+		build.Severe("lock duration is required")
+	}
+	if condition == nil {
+		condition = &NilCondition{}
+	}
+	return &RelativeTimeLockCondition{
+		LockDuration: lockDuration,
+		Condition:    condition,
+	}
+}
+
+// Fulfill implements UnlockFulfillment.Fulfill
+//
+// The relative time lock is validated against the confirmation height
+// of the parent output, as defined by the given context.
+func (rtl *RelativeTimeLockCondition) Fulfill(fulfillment UnlockFulfillment, ctx FulfillContext) error {
+	if !rtl.Fulfillable(FulfillableContext{BlockHeight: ctx.BlockHeight, BlockTime: ctx.BlockTime, ConfirmationHeight: ctx.ConfirmationHeight}) {
+		return errors.New("relative time lock has not yet been reached")
+	}
+
+	// relative time lock hash been reached,
+	// delegate the actual fulfillment to the given fulfillment, if supported
+	switch tf := fulfillment.(type) {
+	case *SingleSignatureFulfillment:
+		return rtl.Condition.Fulfill(tf, ctx)
+	case *MultiSignatureFulfillment:
+		return rtl.Condition.Fulfill(tf, ctx)
+	case *WeightedMultiSignatureFulfillment:
+		return rtl.Condition.Fulfill(tf, ctx)
+	case *HashLockFulfillment:
+		return rtl.Condition.Fulfill(tf, ctx)
+	default:
+		return ErrUnexpectedUnlockFulfillment
+	}
+}
+
+// ConditionType implements UnlockCondition.ConditionType
+func (rtl *RelativeTimeLockCondition) ConditionType() ConditionType {
+	return ConditionTypeRelativeTimeLock
+}
+
+// IsStandardCondition implements UnlockCondition.IsStandardCondition
+func (rtl *RelativeTimeLockCondition) IsStandardCondition(ctx ValidationContext) error {
+	if rtl.LockDuration == 0 {
+		return errors.New("lock duration has to be defined")
+	}
+	return isStandardTimeLockedCondition(rtl.Condition, ctx)
+}
+
+// UnlockHash implements UnlockCondition.UnlockHash
+func (rtl *RelativeTimeLockCondition) UnlockHash() UnlockHash {
+	return rtl.Condition.UnlockHash()
+}
+
+// GetMarshalableUnlockCondition implements MarshalableUnlockConditionGetter.GetMarshalableUnlockCondition
+func (rtl *RelativeTimeLockCondition) GetMarshalableUnlockCondition() MarshalableUnlockCondition {
+	return rtl.Condition
+}
+
+// Equal implements UnlockCondition.Equal
+func (rtl *RelativeTimeLockCondition) Equal(c UnlockCondition) bool {
+	ortl, ok := c.(*RelativeTimeLockCondition)
+	if !ok {
+		return false
+	}
+	return rtl.LockDuration == ortl.LockDuration && rtl.Condition.Equal(ortl.Condition)
+}
+
+// Fulfillable implements UnlockCondition.Fulfillable
+//
+// The condition is fulfillable once LockDuration blocks have been created
+// on top of the block which confirmed the parent output.
+func (rtl *RelativeTimeLockCondition) Fulfillable(ctx FulfillableContext) bool {
+	return ctx.BlockHeight >= ctx.ConfirmationHeight &&
+		ctx.BlockHeight-ctx.ConfirmationHeight >= rtl.LockDuration
+}
+
+// Marshal implements MarshalableUnlockCondition.Marshal
+func (rtl *RelativeTimeLockCondition) Marshal(f MarshalFunc) ([]byte, error) {
+	cb, err := rtl.Condition.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	b, err := f(rtl.LockDuration, rtl.Condition.ConditionType())
+	if err != nil {
+		return nil, err
+	}
+	return append(b, cb...), nil
+}
+
+// Unmarshal implements MarshalableUnlockCondition.Unmarshal
+func (rtl *RelativeTimeLockCondition) Unmarshal(b []byte, f UnmarshalFunc) error {
+	if len(b) < 9 {
+		// at least 9 bytes are required (lock duration (8) + condition type (1)),
+		// as to enforce we can decode the relative time lock condition's properties,
+		// whether or not the internal condition requires bytes is of no concern of us.
+		return io.ErrUnexpectedEOF
+	}
+	// unmarshal the lock duration
+	err := f(b[:8], &rtl.LockDuration)
+	if err != nil {
+		return err
+	}
+	// interpret the condition type, and continue decoding based on that,
+	// by getting the correct constructor from the registration mapping
+	var ct ConditionType
+	err = f(b[8:9], &ct)
+	if err != nil {
+		return err
+	}
+	cc, ok := _RegisteredUnlockConditionTypes[ct]
+	if !ok {
+		return ErrUnknownConditionType
+	}
+	// known condition type, create and decode it
+	rtl.Condition = cc()
+	return rtl.Condition.Unmarshal(b[9:], f)
+}
+
+type jsonRelativeTimeLockCondition struct {
+	LockDuration BlockHeight          `json:"lockduration"`
+	Condition    UnlockConditionProxy `json:"condition"`
+}
+
+// MarshalJSON implements json.Marshaler.MarshalJSON
+//
+// This function is required, as to ensure
+// the underlying properties are properly serialized,
+// including the type of the internal condition.
+func (rtl *RelativeTimeLockCondition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonRelativeTimeLockCondition{
+		LockDuration: rtl.LockDuration,
+		Condition:    UnlockConditionProxy{Condition: rtl.Condition},
+	})
+}
+
+// UnmarshalJSON implements json.Unmarshaler.UnmarshalJSON
+//
+// This function is required, as to be able to unmarshal
+// the internal condition based on the encoded condition type.
+func (rtl *RelativeTimeLockCondition) UnmarshalJSON(b []byte) error {
+	var jrtl jsonRelativeTimeLockCondition
+	err := json.Unmarshal(b, &jrtl)
+	if err != nil {
+		return err
+	}
+	rtl.LockDuration = jrtl.LockDuration
+	if jrtl.Condition.Condition == nil {
+		rtl.Condition = &NilCondition{}
+	} else {
+		rtl.Condition = jrtl.Condition.Condition
+	}
+	return nil
+}
+
 // MarshalSia implements siabin.SiaMarshaler.MarshalSia
 //
 // Marshals this ConditionType as a single byte.
@@ -2372,13 +2640,23 @@ var (
 // strictSignatureCheck is used as part of the IsStandardFulfillment
 // check of any Fulfillment which has a signature as part of its body.
 // It ensures that the given public key and signature are a valid pair.
+// The signature can optionally be followed by a signature hash flags byte,
+// whether or not that is allowed depends on the transaction version, and is checked on verification.
 func strictSignatureCheck(pk PublicKey, signature ByteSlice) error {
 	switch pk.Algorithm {
 	case SignatureAlgoEd25519:
 		if len(pk.Key) != crypto.PublicKeySize {
 			return errors.New("invalid public key size in transaction")
 		}
-		if len(signature) != crypto.SignatureSize {
+		if len(signature) != crypto.SignatureSize && len(signature) != crypto.SignatureSize+1 {
+			return errors.New("invalid signature size in transaction")
+		}
+		return nil
+	case SignatureAlgoSecp256k1:
+		if len(pk.Key) != crypto.Secp256k1PublicKeySize {
+			return errors.New("invalid public key size in transaction")
+		}
+		if len(signature) != crypto.Secp256k1SignatureSize && len(signature) != crypto.Secp256k1SignatureSize+1 {
 			return errors.New("invalid signature size in transaction")
 		}
 		return nil
@@ -2399,11 +2677,13 @@ func mergeExtraObjects(extraObjects []interface{}, fulfillmentDefinedObjects ...
 }
 
 // signHashUsingPublicKey produces a signature,
-// for a given input, which is located within the given (parent) transaction,
+// for a given input, which is located within the (parent) transaction of the given context,
 // using the given (optional private) key, and using any extra objects (on top of the normal properties).
 // The public key is to be given, as based on that the function can figure out what algorithm to use,
 // and this also allows the function to know how to interpret the given (private) key.
-func signHashUsingPublicKey(pk PublicKey, tx Transaction, key interface{}, extraObjects []interface{}) ([]byte, error) {
+// For transaction versions which support signature hash flags, the flags of the context
+// are appended to the produced signature.
+func signHashUsingPublicKey(pk PublicKey, ctx FulfillmentSignContext, key interface{}, extraObjects []interface{}) ([]byte, error) {
 	switch pk.Algorithm {
 	case SignatureAlgoEd25519:
 		// decode the ed-secretKey
@@ -2427,12 +2707,44 @@ func signHashUsingPublicKey(pk PublicKey, tx Transaction, key interface{}, extra
 		if edSK.IsNil() {
 			return nil, crypto.ErrSecretNilKey
 		}
-		sigHash, err := tx.SignatureHash(extraObjects...)
+		sigHash, suffix, err := signatureHashWithFlags(ctx.Transaction, ctx.SigHashFlags, ctx.ParentID, extraObjects)
 		if err != nil {
 			return nil, err
 		}
 		sig := crypto.SignHash(sigHash, edSK)
-		return sig[:], nil
+		return append(sig[:], suffix...), nil
+
+	case SignatureAlgoSecp256k1:
+		// decode the secp256k1 secret key
+		var secpSK crypto.Secp256k1SecretKey
+		switch k := key.(type) {
+		case crypto.Secp256k1SecretKey:
+			secpSK = k
+		case ByteSlice:
+			if len(k) != crypto.Secp256k1SecretKeySize {
+				return nil, errors.New("invalid secret key size")
+			}
+			copy(secpSK[:], k)
+		case []byte:
+			if len(k) != crypto.Secp256k1SecretKeySize {
+				return nil, errors.New("invalid secret key size")
+			}
+			copy(secpSK[:], k)
+		default:
+			return nil, fmt.Errorf("%T is an unknown secret key type", key)
+		}
+		if secpSK.IsNil() {
+			return nil, crypto.ErrSecretNilKey
+		}
+		sigHash, suffix, err := signatureHashWithFlags(ctx.Transaction, ctx.SigHashFlags, ctx.ParentID, extraObjects)
+		if err != nil {
+			return nil, err
+		}
+		sig, err := crypto.SignHashSecp256k1(sigHash, secpSK)
+		if err != nil {
+			return nil, err
+		}
+		return append(sig[:], suffix...), nil
 
 	default:
 		return nil, ErrUnknownSignAlgorithmType
@@ -2442,32 +2754,61 @@ func signHashUsingPublicKey(pk PublicKey, tx Transaction, key interface{}, extra
 // verifyHashUsingPublicKey verfies the given signature.
 // It does so by:
 //
-// 1. producing the hash used to create the signature,
-//    using the given (parent) transaction and any extra Objects to include
-//    together with the normal transaction properties;
-// 2. using the algorithm type of the given public key,
+// 1. splitting the signature hash flags from the signature,
+//    should the version of the (parent) transaction support them;
+// 2. producing the hash used to create the signature,
+//    using the (parent) transaction of the given context, the signature hash flags
+//    and any extra Objects to include together with the normal transaction properties;
+// 3. using the algorithm type of the given public key,
 //    as to figure out what signature algorithm is used,
 //    and thus being able to know how to verify the given signature;
-func verifyHashUsingPublicKey(pk PublicKey, tx Transaction, sig []byte, extraObjects []interface{}) (err error) {
+func verifyHashUsingPublicKey(pk PublicKey, ctx FulfillContext, sig []byte, extraObjects []interface{}) (err error) {
 	switch pk.Algorithm {
 	case SignatureAlgoEd25519:
 		// Decode the public key and signature.
 		var (
 			edPK  crypto.PublicKey
 			edSig crypto.Signature
+			flags SigHashFlags
 		)
 		copy(edPK[:], pk.Key)
+		sig, flags, err = splitSignatureHashFlags(ctx.Transaction, sig, crypto.SignatureSize)
+		if err != nil {
+			return err
+		}
 		copy(edSig[:], sig)
 		if edPK.IsNil() {
 			return crypto.ErrPublicNilKey
 		}
 		cryptoSig := crypto.Signature(edSig)
 		var sigHash crypto.Hash
-		sigHash, err = tx.SignatureHash(extraObjects...)
+		sigHash, _, err = signatureHashWithFlags(ctx.Transaction, flags, ctx.ParentID, extraObjects)
 		if err == nil {
 			err = crypto.VerifyHash(sigHash, edPK, cryptoSig)
 		}
 
+	case SignatureAlgoSecp256k1:
+		// Decode the public key and signature.
+		var (
+			secpPK  crypto.Secp256k1PublicKey
+			secpSig crypto.Secp256k1Signature
+			flags   SigHashFlags
+		)
+		copy(secpPK[:], pk.Key)
+		sig, flags, err = splitSignatureHashFlags(ctx.Transaction, sig, crypto.Secp256k1SignatureSize)
+		if err != nil {
+			return err
+		}
+		copy(secpSig[:], sig)
+		if secpPK.IsNil() {
+			return crypto.ErrPublicNilKey
+		}
+		var sigHash crypto.Hash
+		sigHash, _, err = signatureHashWithFlags(ctx.Transaction, flags, ctx.ParentID, extraObjects)
+		if err == nil {
+			err = crypto.VerifyHashSecp256k1(sigHash, secpPK, secpSig)
+		}
+
 	default:
 		err = ErrUnknownSignAlgorithmType
 	}
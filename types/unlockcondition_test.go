@@ -27,6 +27,9 @@ func TestUnlockConditionSiaEncoding(t *testing.T) {
 		`032a00000000000000111111111111111101016363636363636363636363636363636363636363636363636363636363636363`, // using (pubKey) unlock hash condition
 		// MultiSig condition
 		`0452000000000000000200000000000000020000000000000001e89843e4b8231a01ba18b254d530110364432aafab8206bea72e5a20eaa55f7001a6a6c5584b2bfbd08738996cd7930831f958b9a5ed1595525236e861c1a0dc35`,
+		// relative time lock condition
+		`050900000000000000d00200000000000000`, // using nil condition
+		`052a00000000000000d00200000000000001016363636363636363636363636363636363636363636363636363636363636363`, // using (pubKey) unlock hash condition
 	}
 	for idx, testCase := range testCases {
 		b, err := hex.DecodeString(testCase)
@@ -69,6 +72,9 @@ func TestUnlockConditionRivineEncoding(t *testing.T) {
 		`0354111111111111111101016363636363636363636363636363636363636363636363636363636363636363`, // using (pubKey) unlock hash condition
 		// MultiSig condition
 		`049602000000000000000401e89843e4b8231a01ba18b254d530110364432aafab8206bea72e5a20eaa55f7001a6a6c5584b2bfbd08738996cd7930831f958b9a5ed1595525236e861c1a0dc35`,
+		// relative time lock condition
+		`0512d00200000000000000`, // using nil condition
+		`0554d00200000000000001016363636363636363636363636363636363636363636363636363636363636363`, // using (pubKey) unlock hash condition
 	}
 	for idx, testCase := range testCases {
 		b, err := hex.DecodeString(testCase)
@@ -293,6 +299,26 @@ func TestUnlockConditionJSONEncoding(t *testing.T) {
 			}
 		}
 	}
+}`, ``}, // using unlock hash condition
+		// relative time lock condition
+		{`{
+	"type": 5,
+	"data": {
+		"lockduration": 720,
+		"condition": {}
+	}
+}`, ``}, // using nil condition
+		{`{
+	"type": 5,
+	"data": {
+		"lockduration": 720,
+		"condition": {
+			"type": 1,
+			"data": {
+				"unlockhash": "0101234567890123456789012345678901012345678901234567890123456789018a50e31447b8"
+			}
+		}
+	}
 }`, ``}, // using unlock hash condition
 		// MultiSig condition
 		{
@@ -2401,6 +2427,64 @@ func TestIsStandardCondition(t *testing.T) {
 				},
 			}, "no atomic swap condition can be used as the internal condition of a time lock condition",
 		},
+		// relative time lock condition
+		{
+			&RelativeTimeLockCondition{
+				LockDuration: 720,
+				Condition:    &NilCondition{},
+			}, "",
+		},
+		{
+			&RelativeTimeLockCondition{
+				Condition: &NilCondition{},
+			}, "lock duration has to be defined",
+		},
+		{
+			&RelativeTimeLockCondition{
+				LockDuration: 1,
+				Condition: &UnlockHashCondition{
+					TargetUnlockHash: unlockHashFromHex("015fe50b9c596d8717e5e7ba79d5a7c9c8b82b1427a04d5c0771268197c90e99dccbcdf0ba9c90"),
+				},
+			}, "",
+		},
+		{
+			&RelativeTimeLockCondition{
+				LockDuration: 1,
+				Condition: &UnlockHashCondition{
+					TargetUnlockHash: unlockHashFromHex("02a24c97c80eeac111aa4bcbb0ac8ffc364fa9b22da10d3054778d2332f68b365e5e5af8e71541"),
+				},
+			}, "non-standard unlock hash type",
+		},
+		{
+			&RelativeTimeLockCondition{
+				LockDuration: 1,
+				Condition: &MultiSignatureCondition{
+					MinimumSignatureCount: 2,
+					UnlockHashes: UnlockHashSlice{
+						unlockHashFromHex("015fe50b9c596d8717e5e7ba79d5a7c9c8b82b1427a04d5c0771268197c90e99dccbcdf0ba9c90"),
+						unlockHashFromHex("01fc8714235d549f890f35e52d745b9eeeee34926f96c4b9ef1689832f338d9349b453898f7e51"),
+					},
+				},
+			}, "",
+		},
+		{
+			&RelativeTimeLockCondition{
+				LockDuration: 1,
+				Condition: &TimeLockCondition{
+					LockTime:  4,
+					Condition: &NilCondition{},
+				},
+			}, "no time lock condition can be used as the internal condition of a relative time lock condition",
+		},
+		{
+			&RelativeTimeLockCondition{
+				LockDuration: 1,
+				Condition: &RelativeTimeLockCondition{
+					LockDuration: 4,
+					Condition:    &NilCondition{},
+				},
+			}, "no relative time lock condition can be used as the internal condition of another relative time lock condition",
+		},
 		{
 			&MultiSignatureCondition{},
 			"amount of required signatures must be greater than one",
@@ -3218,6 +3302,31 @@ func TestUnlockConditionFulfillable(t *testing.T) {
 			FulfillableContext{},
 			true,
 		},
+		{
+			&RelativeTimeLockCondition{},
+			FulfillableContext{},
+			true,
+		},
+		{
+			&RelativeTimeLockCondition{LockDuration: 10},
+			FulfillableContext{BlockHeight: 109, ConfirmationHeight: 100},
+			false,
+		},
+		{
+			&RelativeTimeLockCondition{LockDuration: 10},
+			FulfillableContext{BlockHeight: 110, ConfirmationHeight: 100},
+			true,
+		},
+		{
+			&RelativeTimeLockCondition{LockDuration: 10},
+			FulfillableContext{BlockHeight: 500, BlockTime: 1, ConfirmationHeight: 100},
+			true,
+		},
+		{
+			&RelativeTimeLockCondition{LockDuration: 10},
+			FulfillableContext{BlockHeight: 5, ConfirmationHeight: 100},
+			false,
+		},
 	}
 	for idx, testCase := range testCases {
 		fulfillable := testCase.Condition.Fulfillable(testCase.Context)
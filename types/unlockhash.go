@@ -3,11 +3,14 @@ package types
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/threefoldtech/rivine/build"
 	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/bech32"
 	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
 	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
 )
@@ -22,8 +25,100 @@ const (
 	// brings the total size of the address to 38 bytes, leaving 2 bytes for
 	// potential version additions in the future.
 	UnlockHashChecksumSize = 6
+
+	// MaxAddressPrefixLength is the maximum length of the
+	// human-readable prefix used for bech32-encoded unlock hashes.
+	MaxAddressPrefixLength = 16
+)
+
+// UnlockHashEncoding defines the format used to
+// represent unlock hashes (addresses) as strings.
+type UnlockHashEncoding uint8
+
+const (
+	// UnlockHashEncodingHex encodes an unlock hash as a hex string,
+	// including a partial checksum. It is the default encoding.
+	UnlockHashEncodingHex UnlockHashEncoding = iota
+	// UnlockHashEncodingBech32 encodes an unlock hash as a bech32 string,
+	// using the address prefix of the chain as human-readable part,
+	// such that addresses of different chains are easy to tell apart.
+	UnlockHashEncodingBech32
+)
+
+var (
+	unlockHashEncoding      = UnlockHashEncodingHex
+	unlockHashAddressPrefix string
 )
 
+// SetUnlockHashEncoding defines the encoding used by UnlockHash.String (and thus also its JSON encoding),
+// as well as the address prefix used for bech32-encoded unlock hashes.
+// Bech32-encoded unlock hashes are accepted by UnlockHash.LoadString no matter the encoding set,
+// given they have the same prefix. If no prefix is set, bech32 unlock hashes of any prefix are accepted.
+//
+// This function is not thread-safe, and is expected to be called
+// only once at startup, prior to any unlock hash being (un)marshaled.
+func SetUnlockHashEncoding(encoding UnlockHashEncoding, addressPrefix string) error {
+	switch encoding {
+	case UnlockHashEncodingHex:
+	case UnlockHashEncodingBech32:
+		if addressPrefix == "" {
+			return errors.New("bech32 unlock hash encoding requires an address prefix")
+		}
+	default:
+		return fmt.Errorf("unknown unlock hash encoding %d", encoding)
+	}
+	if addressPrefix != "" {
+		if len(addressPrefix) > MaxAddressPrefixLength {
+			return fmt.Errorf("address prefix %q exceeds the maximum length of %d characters", addressPrefix, MaxAddressPrefixLength)
+		}
+		if strings.ToLower(addressPrefix) != addressPrefix {
+			return fmt.Errorf("address prefix %q has to be lower case", addressPrefix)
+		}
+		err := bech32.ValidateHRP(addressPrefix)
+		if err != nil {
+			return fmt.Errorf("invalid address prefix %q: %v", addressPrefix, err)
+		}
+	}
+	unlockHashEncoding = encoding
+	unlockHashAddressPrefix = addressPrefix
+	return nil
+}
+
+// String returns the name of the unlock hash encoding.
+func (e UnlockHashEncoding) String() string {
+	switch e {
+	case UnlockHashEncodingHex:
+		return "hex"
+	case UnlockHashEncodingBech32:
+		return "bech32"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(e))
+	}
+}
+
+// LoadString loads an unlock hash encoding from its name.
+func (e *UnlockHashEncoding) LoadString(str string) error {
+	switch str {
+	case "hex":
+		*e = UnlockHashEncodingHex
+	case "bech32":
+		*e = UnlockHashEncodingBech32
+	default:
+		return fmt.Errorf("unknown unlock hash encoding %q", str)
+	}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.MarshalText
+func (e UnlockHashEncoding) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.UnmarshalText
+func (e *UnlockHashEncoding) UnmarshalText(text []byte) error {
+	return e.LoadString(string(text))
+}
+
 type (
 	// UnlockType defines the type of
 	// an unlock condition-fulfillment pair.
@@ -64,6 +159,27 @@ const (
 	// be spent after at least the specified amount of identities have agreed,
 	// by means of providing their signature.
 	UnlockTypeMultiSig
+
+	// UnlockTypePolicy provides a condition which composes multiple
+	// (sub) conditions, using an AND, OR or threshold (k-of-n) operator.
+	// The output can only be spent once the required amount of
+	// sub conditions are fulfilled.
+	UnlockTypePolicy
+
+	// UnlockTypeSecp256k1PubKey provides the same unlock type as UnlockTypePubKey,
+	// for public keys using the secp256k1 signature algorithm instead.
+	UnlockTypeSecp256k1PubKey
+
+	// UnlockTypeWeightedMultiSig provides a condition in which the receiving party
+	// consists of multiple signatories, each with their own signature weight,
+	// where the combined weight of the signatories which sign
+	// has to reach a minimum weight in order to spend the output.
+	UnlockTypeWeightedMultiSig
+
+	// UnlockTypeHashLock provides a condition in which the output
+	// can be spent by anyone who reveals the pre-image of a hash,
+	// computed using the hash function defined by the condition.
+	UnlockTypeHashLock
 )
 
 var (
@@ -85,8 +201,15 @@ func NewEd25519PubKeyUnlockHash(pk crypto.PublicKey) (UnlockHash, error) {
 	return NewPubKeyUnlockHash(Ed25519PublicKey(pk))
 }
 
-// NewPubKeyUnlockHash creates a new unlock hash of type UnlockTypePubKey,
-// using a given Sia-standard Public key.
+// NewSecp256k1PubKeyUnlockHash creates a new unlock hash of type UnlockTypeSecp256k1PubKey,
+// using a given public key which is assumed to be used in combination with the secp256k1 algorithm.
+func NewSecp256k1PubKeyUnlockHash(pk crypto.Secp256k1PublicKey) (UnlockHash, error) {
+	return NewPubKeyUnlockHash(Secp256k1PublicKey(pk))
+}
+
+// NewPubKeyUnlockHash creates a new unlock hash using a given Sia-standard Public key.
+// The unlock hash is of type UnlockTypeSecp256k1PubKey for secp256k1 public keys,
+// and of type UnlockTypePubKey otherwise.
 func NewPubKeyUnlockHash(pk PublicKey) (UnlockHash, error) {
 	pkb, err := siabin.Marshal(pk)
 	if err != nil {
@@ -97,11 +220,23 @@ func NewPubKeyUnlockHash(pk PublicKey) (UnlockHash, error) {
 		return UnlockHash{}, err
 	}
 	return UnlockHash{
-		Type: UnlockTypePubKey,
+		Type: pk.UnlockType(),
 		Hash: h,
 	}, nil
 }
 
+// IsMultiSig returns true if the unlock type identifies an unlock hash
+// derived from a (weighted) multisig condition.
+func (t UnlockType) IsMultiSig() bool {
+	return t == UnlockTypeMultiSig || t == UnlockTypeWeightedMultiSig
+}
+
+// IsPubKey returns true if the unlock type identifies an unlock hash
+// derived from a single public key, no matter the signature algorithm of that key.
+func (t UnlockType) IsPubKey() bool {
+	return t == UnlockTypePubKey || t == UnlockTypeSecp256k1PubKey
+}
+
 // NewUnlockHash creates a new unlock hash
 func NewUnlockHash(t UnlockType, h crypto.Hash) UnlockHash {
 	return UnlockHash{
@@ -213,20 +348,37 @@ func (uh *UnlockHash) UnmarshalJSON(b []byte) error {
 	return uh.LoadString(str)
 }
 
-// String returns the hex representation of the unlock hash as a string - this
-// includes a checksum.
+// String returns the string representation of the unlock hash,
+// using the encoding defined by SetUnlockHashEncoding.
+// By default this is the hex representation, which includes a checksum.
 func (uh UnlockHash) String() string {
 	if uh.Type == 0 {
 		return "" // nil unlock hash
 	}
+	if unlockHashEncoding == UnlockHashEncodingBech32 {
+		return uh.Bech32String(unlockHashAddressPrefix)
+	}
 
 	uhChecksum, _ := crypto.HashAll(uh.Type, uh.Hash)
 	return fmt.Sprintf("%02x%x%x",
 		uh.Type, uh.Hash[:], uhChecksum[:UnlockHashChecksumSize])
 }
 
+// Bech32String returns the bech32 representation of the unlock hash as a string,
+// using the given address prefix as its human-readable part.
+func (uh UnlockHash) Bech32String(addressPrefix string) string {
+	if uh.Type == 0 {
+		return "" // nil unlock hash
+	}
+	str, err := bech32.Encode(addressPrefix, append([]byte{byte(uh.Type)}, uh.Hash[:]...))
+	if err != nil {
+		build.Critical(fmt.Sprintf("failed to bech32-encode unlock hash using prefix %q: %v", addressPrefix, err))
+	}
+	return str
+}
+
 // LoadString loads a hex representation (including checksum)
-// of an unlock hash into an unlock hash object.
+// or a bech32 representation of an unlock hash into an unlock hash object.
 // An error is returned if the string is invalid or
 // fails the checksum.
 func (uh *UnlockHash) LoadString(strUH string) error {
@@ -235,6 +387,9 @@ func (uh *UnlockHash) LoadString(strUH string) error {
 		*uh = NilUnlockHash
 		return nil
 	}
+	if isBech32UnlockHashString(strUH) {
+		return uh.loadBech32String(strUH)
+	}
 
 	// Check the length of strUH.
 	// total length is 39, 1 byte for the (unlock) type,
@@ -294,6 +449,42 @@ func (uh *UnlockHash) LoadString(strUH string) error {
 	return nil
 }
 
+// isBech32UnlockHashString returns true if the given string contains the bech32 separator
+// and is not a hex string, and thus is expected to be a bech32-encoded unlock hash.
+func isBech32UnlockHashString(str string) bool {
+	if !strings.ContainsRune(str, '1') {
+		return false
+	}
+	for _, r := range str {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') && (r < 'A' || r > 'F') {
+			return true
+		}
+	}
+	return false
+}
+
+func (uh *UnlockHash) loadBech32String(strUH string) error {
+	prefix, data, err := bech32.Decode(strUH)
+	if err != nil {
+		return err
+	}
+	if unlockHashAddressPrefix != "" && prefix != unlockHashAddressPrefix {
+		return ErrUnlockHashWrongPrefix
+	}
+	if len(data) != 1+crypto.HashSize {
+		return ErrUnlockHashWrongLen
+	}
+	ut := UnlockType(data[0])
+	var unlockHash crypto.Hash
+	copy(unlockHash[:], data[1:])
+	if ut == UnlockTypeNil && unlockHash != NilUnlockHash.Hash {
+		return errors.New("unexpected crypto hash for UnlockTypeNil: " + NilUnlockHash.Hash.String())
+	}
+	uh.Type = ut
+	uh.Hash = unlockHash
+	return nil
+}
+
 // Len implements the Len method of sort.Interface.
 func (uhs UnlockHashSlice) Len() int {
 	return len(uhs)
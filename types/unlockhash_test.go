@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/threefoldtech/rivine/crypto"
@@ -381,3 +382,100 @@ func TestUnlockHashLoadNilUnlockHashJSONStringErrors(t *testing.T) {
 		t.Fatal("no error received, while unmarshalling nil unlock hash with invalid hash")
 	}
 }
+
+func TestUnlockHashBech32Encoding(t *testing.T) {
+	_, pk := crypto.GenerateKeyPair()
+	uh, err := NewEd25519PubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hexStr := uh.String()
+
+	err = SetUnlockHashEncoding(UnlockHashEncodingBech32, "riv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetUnlockHashEncoding(UnlockHashEncodingHex, "")
+
+	// the bech32 encoding is used for the string and JSON representation
+	str := uh.String()
+	if !strings.HasPrefix(str, "riv1") {
+		t.Fatalf("unexpected bech32 unlock hash: %s", str)
+	}
+	if str != uh.Bech32String("riv") {
+		t.Fatalf("unexpected bech32 unlock hash: %s != %s", str, uh.Bech32String("riv"))
+	}
+	b, err := json.Marshal(uh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `"`+str+`"` {
+		t.Fatalf("unexpected JSON unlock hash: %s", string(b))
+	}
+	if NilUnlockHash.String() != "" {
+		t.Fatalf("unexpected nil unlock hash string: %s", NilUnlockHash.String())
+	}
+
+	// both the bech32 and hex representations can be loaded
+	for _, s := range []string{str, strings.ToUpper(str), hexStr} {
+		var loadedUH UnlockHash
+		err = loadedUH.LoadString(s)
+		if err != nil {
+			t.Errorf("failed to load unlock hash %s: %v", s, err)
+		} else if loadedUH != uh {
+			t.Errorf("unexpected unlock hash loaded from %s: %v != %v", s, loadedUH, uh)
+		}
+	}
+	var loadedUH UnlockHash
+	if err = json.Unmarshal(b, &loadedUH); err != nil || loadedUH != uh {
+		t.Errorf("failed to unmarshal JSON unlock hash %s: %v", string(b), err)
+	}
+
+	// bech32 unlock hashes of other chains are refused
+	err = loadedUH.LoadString(uh.Bech32String("other"))
+	if err != ErrUnlockHashWrongPrefix {
+		t.Errorf("expected ErrUnlockHashWrongPrefix, got: %v", err)
+	}
+	// as are corrupted ones
+	corrupted := []byte(str)
+	if corrupted[10] == 'q' {
+		corrupted[10] = 'p'
+	} else {
+		corrupted[10] = 'q'
+	}
+	if err = loadedUH.LoadString(string(corrupted)); err == nil {
+		t.Errorf("expected an error when loading corrupted unlock hash %s", string(corrupted))
+	}
+
+	// the hex encoding remains the default
+	err = SetUnlockHashEncoding(UnlockHashEncodingHex, "riv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uh.String() != hexStr {
+		t.Fatalf("unexpected hex unlock hash: %s != %s", uh.String(), hexStr)
+	}
+	if err = loadedUH.LoadString(str); err != nil || loadedUH != uh {
+		t.Errorf("failed to load bech32 unlock hash %s using hex encoding: %v", str, err)
+	}
+}
+
+func TestSetUnlockHashEncodingErrors(t *testing.T) {
+	defer SetUnlockHashEncoding(UnlockHashEncodingHex, "")
+	testCases := []struct {
+		Encoding UnlockHashEncoding
+		Prefix   string
+	}{
+		{UnlockHashEncodingBech32, ""},
+		{UnlockHashEncodingBech32, "Riv"},
+		{UnlockHashEncodingBech32, "riv riv"},
+		{UnlockHashEncodingBech32, strings.Repeat("r", MaxAddressPrefixLength+1)},
+		{UnlockHashEncodingHex, "RIV"},
+		{UnlockHashEncoding(42), "riv"},
+	}
+	for idx, testCase := range testCases {
+		if err := SetUnlockHashEncoding(testCase.Encoding, testCase.Prefix); err == nil {
+			t.Errorf("test case #%d: expected an error for encoding %v and prefix %q", idx, testCase.Encoding, testCase.Prefix)
+		}
+	}
+}
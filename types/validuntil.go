@@ -0,0 +1,159 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+)
+
+type (
+	// ValidUntilTransactionController is the transaction controller used for TransactionVersionThree.
+	// It encodes transactions like the DefaultTransactionController,
+	// with the addition of an optional block height, which is the last block height
+	// at which the transaction can still be included in a block.
+	// Just like the ChainIDTransactionController it commits to the ID of the chain in the signature hash,
+	// as well as to the defined block height, such that neither can be changed once signed.
+	//
+	// As the chain ID is only known at runtime, this controller is not registered by default,
+	// and has to be registered by the daemon (and client) of a chain which wishes to use it.
+	ValidUntilTransactionController struct {
+		ChainID ChainID
+	}
+
+	// ValidUntilTransactionExtension defines the extension data of a TransactionVersionThree transaction.
+	ValidUntilTransactionExtension struct {
+		// ValidUntil is the last block height at which the transaction can be included in a block.
+		// The zero value means that the transaction does not expire.
+		ValidUntil BlockHeight
+	}
+
+	// validUntilTransactionData is the JSON format of a TransactionVersionThree transaction's data
+	validUntilTransactionData struct {
+		CoinInputs        []CoinInput        `json:"coininputs"`
+		CoinOutputs       []CoinOutput       `json:"coinoutputs,omitempty"`
+		BlockStakeInputs  []BlockStakeInput  `json:"blockstakeinputs,omitempty"`
+		BlockStakeOutputs []BlockStakeOutput `json:"blockstakeoutputs,omitempty"`
+		MinerFees         []Currency         `json:"minerfees"`
+		ArbitraryData     []byte             `json:"arbitrarydata,omitempty"`
+		ValidUntil        BlockHeight        `json:"validuntil,omitempty"`
+	}
+)
+
+var (
+	// ErrTransactionExpired is returned when a transaction
+	// is validated at a block height past its expiry height.
+	ErrTransactionExpired = errors.New("transaction has expired")
+)
+
+// ValidUntil returns the last block height at which the transaction
+// can be included in a block, and true if the transaction expires at all.
+func (t Transaction) ValidUntil() (BlockHeight, bool) {
+	height := validUntilFromExtension(t.Extension)
+	return height, height != 0
+}
+
+// ValidateValidUntil validates that the given transaction has not yet expired at the given block height.
+func (t Transaction) ValidateValidUntil(height BlockHeight) error {
+	if validUntil, ok := t.ValidUntil(); ok && height > validUntil {
+		return fmt.Errorf("%v: valid until block height %d, current height is %d", ErrTransactionExpired, validUntil, height)
+	}
+	return nil
+}
+
+// validUntilFromExtension returns the expiry height defined in the given extension,
+// should it be a ValidUntilTransactionExtension, or 0 otherwise.
+func validUntilFromExtension(extension interface{}) BlockHeight {
+	switch ext := extension.(type) {
+	case *ValidUntilTransactionExtension:
+		if ext != nil {
+			return ext.ValidUntil
+		}
+	case ValidUntilTransactionExtension:
+		return ext.ValidUntil
+	}
+	return 0
+}
+
+// validUntilExtension returns the extension to attach to transaction data for the given height,
+// nil in case no expiry height is defined.
+func validUntilExtension(height BlockHeight) interface{} {
+	if height == 0 {
+		return nil
+	}
+	return &ValidUntilTransactionExtension{ValidUntil: height}
+}
+
+// EncodeTransactionData implements TransactionController.EncodeTransactionData
+func (vutc ValidUntilTransactionController) EncodeTransactionData(w io.Writer, td TransactionData) error {
+	// encode to a byte slice first, including the expiry height
+	b, err := siabin.MarshalAll(td, validUntilFromExtension(td.Extension))
+	if err != nil {
+		return fmt.Errorf("failed to (siabin) marshal valid-until transaction data: %v", err)
+	}
+	// copy those bytes together with its prefixed length, as the final encoding
+	return siabin.NewEncoder(w).Encode(b)
+}
+
+// DecodeTransactionData implements TransactionController.DecodeTransactionData
+func (vutc ValidUntilTransactionController) DecodeTransactionData(r io.Reader) (td TransactionData, err error) {
+	// decode it as a byte slice first
+	var b []byte
+	err = siabin.NewDecoder(r).Decode(&b)
+	if err != nil {
+		return
+	}
+	// decode the transaction data and expiry height
+	var height BlockHeight
+	err = siabin.UnmarshalAll(b, &td, &height)
+	if err != nil {
+		return
+	}
+	td.Extension = validUntilExtension(height)
+	return
+}
+
+// JSONEncodeTransactionData implements TransactionController.JSONEncodeTransactionData
+func (vutc ValidUntilTransactionController) JSONEncodeTransactionData(td TransactionData) ([]byte, error) {
+	return json.Marshal(validUntilTransactionData{
+		CoinInputs:        td.CoinInputs,
+		CoinOutputs:       td.CoinOutputs,
+		BlockStakeInputs:  td.BlockStakeInputs,
+		BlockStakeOutputs: td.BlockStakeOutputs,
+		MinerFees:         td.MinerFees,
+		ArbitraryData:     td.ArbitraryData,
+		ValidUntil:        validUntilFromExtension(td.Extension),
+	})
+}
+
+// JSONDecodeTransactionData implements TransactionController.JSONDecodeTransactionData
+func (vutc ValidUntilTransactionController) JSONDecodeTransactionData(b []byte) (TransactionData, error) {
+	var vutd validUntilTransactionData
+	err := json.Unmarshal(b, &vutd)
+	if err != nil {
+		return TransactionData{}, err
+	}
+	return TransactionData{
+		CoinInputs:        vutd.CoinInputs,
+		CoinOutputs:       vutd.CoinOutputs,
+		BlockStakeInputs:  vutd.BlockStakeInputs,
+		BlockStakeOutputs: vutd.BlockStakeOutputs,
+		MinerFees:         vutd.MinerFees,
+		ArbitraryData:     vutd.ArbitraryData,
+		Extension:         validUntilExtension(vutd.ValidUntil),
+	}, nil
+}
+
+// SignatureHash implements TransactionSignatureHasher.SignatureHash
+func (vutc ValidUntilTransactionController) SignatureHash(t Transaction, extraObjects ...interface{}) (crypto.Hash, error) {
+	return defaultSignatureHash(t, []interface{}{vutc.ChainID, validUntilFromExtension(t.Extension)}, extraObjects...), nil
+}
+
+// ensures at compile time that the ValidUntil Transaction Controller implement all desired interfaces
+var (
+	_ TransactionController      = ValidUntilTransactionController{}
+	_ TransactionSignatureHasher = ValidUntilTransactionController{}
+)
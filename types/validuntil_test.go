@@ -0,0 +1,164 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+)
+
+func TestValidUntilTransactionEncoding(t *testing.T) {
+	defer RegisterTransactionVersion(TransactionVersionThree, nil)
+	RegisterTransactionVersion(TransactionVersionThree, ValidUntilTransactionController{ChainID: ChainID{4, 2}})
+
+	for _, validUntil := range []BlockHeight{0, 1, 42, 1 << 40} {
+		txn := Transaction{
+			Version:       TransactionVersionThree,
+			CoinInputs:    []CoinInput{{ParentID: CoinOutputID{1}}},
+			CoinOutputs:   []CoinOutput{{Value: NewCurrency64(42), Condition: NewCondition(nil)}},
+			MinerFees:     []Currency{NewCurrency64(1)},
+			ArbitraryData: []byte("valid until"),
+			Extension:     validUntilExtension(validUntil),
+		}
+
+		b, err := siabin.Marshal(txn)
+		if err != nil {
+			t.Fatal(validUntil, err)
+		}
+		var decodedTxn Transaction
+		err = siabin.Unmarshal(b, &decodedTxn)
+		if err != nil {
+			t.Fatal(validUntil, err)
+		}
+		if height, _ := decodedTxn.ValidUntil(); height != validUntil {
+			t.Errorf("binary: unexpected valid until height: %d != %d", height, validUntil)
+		}
+		if decodedTxn.ID() != txn.ID() {
+			t.Errorf("binary: unexpected transaction ID: %s != %s", decodedTxn.ID(), txn.ID())
+		}
+
+		b, err = json.Marshal(txn)
+		if err != nil {
+			t.Fatal(validUntil, err)
+		}
+		decodedTxn = Transaction{}
+		err = json.Unmarshal(b, &decodedTxn)
+		if err != nil {
+			t.Fatal(validUntil, err)
+		}
+		if height, _ := decodedTxn.ValidUntil(); height != validUntil {
+			t.Errorf("json: unexpected valid until height: %d != %d", height, validUntil)
+		}
+		if decodedTxn.ID() != txn.ID() {
+			t.Errorf("json: unexpected transaction ID: %s != %s", decodedTxn.ID(), txn.ID())
+		}
+	}
+}
+
+func TestValidUntilTransactionJSON(t *testing.T) {
+	defer RegisterTransactionVersion(TransactionVersionThree, nil)
+	RegisterTransactionVersion(TransactionVersionThree, ValidUntilTransactionController{})
+
+	const input = `{"version":3,"data":{"coininputs":null,"minerfees":["1"],"validuntil":42}}`
+	var txn Transaction
+	err := json.Unmarshal([]byte(input), &txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	height, ok := txn.ValidUntil()
+	if !ok || height != 42 {
+		t.Fatalf("unexpected valid until height: %d (%v)", height, ok)
+	}
+	b, err := json.Marshal(txn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != input {
+		t.Fatalf("unexpected JSON output: %s != %s", string(b), input)
+	}
+}
+
+func TestValidUntilSigHash(t *testing.T) {
+	defer RegisterTransactionVersion(TransactionVersionThree, nil)
+	RegisterTransactionVersion(TransactionVersionThree, ValidUntilTransactionController{ChainID: ChainID{1}})
+
+	sk, rpk := crypto.GenerateKeyPair()
+	pk := Ed25519PublicKey(rpk)
+	uh, err := NewPubKeyUnlockHash(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txn := Transaction{
+		Version:    TransactionVersionThree,
+		CoinInputs: []CoinInput{{ParentID: CoinOutputID{4, 2}}},
+		MinerFees:  []Currency{NewCurrency64(1)},
+		Extension:  &ValidUntilTransactionExtension{ValidUntil: 10},
+	}
+	fulfillment := NewSingleSignatureFulfillment(pk)
+	err = fulfillment.Sign(FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  txn,
+		Key:          sk,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+	}
+	err = NewUnlockHashCondition(uh).Fulfill(fulfillment, ctx)
+	if err != nil {
+		t.Fatal("expected fulfillment to be valid:", err)
+	}
+
+	// the expiry height cannot be changed once signed
+	ctx.Transaction.Extension = &ValidUntilTransactionExtension{ValidUntil: 11}
+	err = NewUnlockHashCondition(uh).Fulfill(fulfillment, ctx)
+	if err == nil {
+		t.Fatal("expected fulfillment to be invalid for a different valid until height")
+	}
+	ctx.Transaction.Extension = nil
+	err = NewUnlockHashCondition(uh).Fulfill(fulfillment, ctx)
+	if err == nil {
+		t.Fatal("expected fulfillment to be invalid for an undefined valid until height")
+	}
+
+	// the transaction is not valid on another chain
+	ctx.Transaction = txn
+	RegisterTransactionVersion(TransactionVersionThree, ValidUntilTransactionController{ChainID: ChainID{2}})
+	err = NewUnlockHashCondition(uh).Fulfill(fulfillment, ctx)
+	if err == nil {
+		t.Fatal("expected fulfillment to be invalid for another chain")
+	}
+}
+
+func TestValidateValidUntil(t *testing.T) {
+	testCases := []struct {
+		Extension interface{}
+		Height    BlockHeight
+		Valid     bool
+	}{
+		{nil, 0, true},
+		{nil, 1 << 60, true},
+		{&ValidUntilTransactionExtension{}, 1 << 60, true},
+		{&ValidUntilTransactionExtension{ValidUntil: 10}, 0, true},
+		{&ValidUntilTransactionExtension{ValidUntil: 10}, 10, true},
+		{&ValidUntilTransactionExtension{ValidUntil: 10}, 11, false},
+		{ValidUntilTransactionExtension{ValidUntil: 10}, 11, false},
+	}
+	for idx, testCase := range testCases {
+		txn := Transaction{
+			Version:   TransactionVersionThree,
+			Extension: testCase.Extension,
+		}
+		err := txn.ValidateValidUntil(testCase.Height)
+		if testCase.Valid && err != nil {
+			t.Errorf("#%d: expected transaction to be valid at height %d: %v", idx, testCase.Height, err)
+		} else if !testCase.Valid && err == nil {
+			t.Errorf("#%d: expected transaction to be expired at height %d", idx, testCase.Height)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/threefoldtech/rivine/build"
+	"github.com/threefoldtech/rivine/crypto"
+)
+
+// The limits which apply to a standard WeightedMultiSignatureCondition (and WeightedMultiSignatureFulfillment).
+const (
+	// WeightedMultiSignatureMaxSignatories defines the maximum amount of
+	// signatories a single weighted multisig condition can have.
+	WeightedMultiSignatureMaxSignatories = 16
+	// WeightedMultiSignatureMaxWeight defines the maximum weight
+	// a single signatory of a weighted multisig condition can have.
+	WeightedMultiSignatureMaxWeight = 100
+)
+
+// ErrInsufficientSignatureWeight is returned when a weighted multisig condition
+// is fulfilled by signatories whose combined weight is lower than the required minimum weight.
+var ErrInsufficientSignatureWeight = errors.New("not enough signature weight")
+
+type (
+	// WeightedMultiSignatureCondition implements the ConditionTypeWeightedMultiSignature ConditionType.
+	// See ConditionTypeWeightedMultiSignature for more information.
+	WeightedMultiSignatureCondition struct {
+		// Signatories defines the unlock hashes which are allowed to sign, each with its own weight.
+		Signatories []WeightedUnlockHash `json:"signatories"`
+		// MinimumWeight defines the minimum combined weight of the signatories
+		// which have to sign in order to fulfill this condition.
+		MinimumWeight uint64 `json:"minimumweight"`
+	}
+
+	// WeightedUnlockHash is an unlock hash, allowed to sign
+	// a WeightedMultiSignatureCondition, combined with the weight of its signature.
+	WeightedUnlockHash struct {
+		UnlockHash UnlockHash `json:"unlockhash"`
+		Weight     uint64     `json:"weight"`
+	}
+
+	// WeightedMultiSignatureFulfillment implements the FulfillmentTypeWeightedMultiSignature FulfillmentType.
+	// See FulfillmentTypeWeightedMultiSignature for more information.
+	WeightedMultiSignatureFulfillment struct {
+		Pairs []PublicKeySignaturePair `json:"pairs"`
+	}
+)
+
+var (
+	_ MarshalableUnlockCondition   = (*WeightedMultiSignatureCondition)(nil)
+	_ MarshalableUnlockFulfillment = (*WeightedMultiSignatureFulfillment)(nil)
+
+	_ UnlockHashSliceGetter = (*WeightedMultiSignatureCondition)(nil)
+)
+
+// NewWeightedMultiSignatureCondition creates a new weighted multisig unlock condition,
+// using the given weighted unlockhashes as a representation of the identities
+// who can unlock the output, and the minimum weight their combined signatures need to have.
+func NewWeightedMultiSignatureCondition(signatories []WeightedUnlockHash, minWeight uint64) *WeightedMultiSignatureCondition {
+	if minWeight == 0 {
+		build.Severe("Weighted MultiSig outputs must require a minimum signature weight to unlock")
+	}
+	if len(signatories) == 0 {
+		build.Severe("Weighted MultiSig outputs must specify at least a single address which can sign it as an input")
+	}
+	wms := &WeightedMultiSignatureCondition{Signatories: signatories, MinimumWeight: minWeight}
+	if wms.TotalWeight() < minWeight {
+		build.Severe("You can't create a weighted multisig which requires more signature weight to spent then there is weight assigned to the addresses which can sign")
+	}
+	if build.DEBUG {
+		for _, signatory := range signatories {
+			if !signatory.UnlockHash.Type.IsPubKey() {
+				build.Critical("Unlock hashes used in weighted multisig condition must have a public key unlock type")
+			}
+		}
+	}
+	return wms
+}
+
+// TotalWeight returns the combined weight of all signatories.
+func (wms *WeightedMultiSignatureCondition) TotalWeight() uint64 {
+	var total uint64
+	for _, signatory := range wms.Signatories {
+		total = addSignatureWeight(total, signatory.Weight)
+	}
+	return total
+}
+
+// addSignatureWeight adds two weights, capping the result
+// at the maximum uint64 value in case of an overflow.
+func addSignatureWeight(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+// Fulfill implements UnlockCondition.Fulfill
+//
+// Each public key of the given fulfillment has to match a distinct signatory,
+// and the combined weight of those signatories has to reach the minimum weight.
+func (wms *WeightedMultiSignatureCondition) Fulfill(fulfillment UnlockFulfillment, ctx FulfillContext) error {
+	tf, ok := fulfillment.(*WeightedMultiSignatureFulfillment)
+	if !ok {
+		return ErrUnexpectedUnlockFulfillment
+	}
+	if len(tf.Pairs) == 0 {
+		return ErrInsufficientSignatures
+	}
+
+	// Check if all the unlock keypairs have an associated signatory,
+	// and compute the combined weight of those signatories
+	used := make([]bool, len(wms.Signatories))
+	var weight uint64
+	for _, kp := range tf.Pairs {
+		uh, err := NewPubKeyUnlockHash(kp.PublicKey)
+		if err != nil {
+			return err
+		}
+		found := false
+		for i, signatory := range wms.Signatories {
+			if !used[i] && signatory.UnlockHash.Cmp(uh) == 0 {
+				used[i], found = true, true
+				weight = addSignatureWeight(weight, signatory.Weight)
+				break
+			}
+		}
+		if !found {
+			return ErrUnauthorizedPubKey
+		}
+	}
+	if weight < wms.MinimumWeight {
+		return ErrInsufficientSignatureWeight
+	}
+
+	// Finally verify all the signatures
+	for _, pks := range tf.Pairs {
+		if err := verifyHashUsingPublicKey(
+			pks.PublicKey, ctx, pks.Signature,
+			mergeExtraObjects(ctx.ExtraObjects, pks.PublicKey),
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ConditionType implements UnlockCondition.ConditionType
+func (wms *WeightedMultiSignatureCondition) ConditionType() ConditionType {
+	return ConditionTypeWeightedMultiSignature
+}
+
+// IsStandardCondition implements UnlockCondition.IsStandardCondition
+func (wms *WeightedMultiSignatureCondition) IsStandardCondition(ValidationContext) error {
+	if wms.MinimumWeight == 0 {
+		return errors.New("a minimum signature weight must be specified")
+	}
+	if len(wms.Signatories) < 2 {
+		return errors.New("at least two signatories must be provided")
+	}
+	if len(wms.Signatories) > WeightedMultiSignatureMaxSignatories {
+		return fmt.Errorf("at most %d signatories can be provided", WeightedMultiSignatureMaxSignatories)
+	}
+	for idx, signatory := range wms.Signatories {
+		if !signatory.UnlockHash.Type.IsPubKey() {
+			return fmt.Errorf("unsupported unlock hash #%d type: %d", idx, signatory.UnlockHash.Type)
+		}
+		if signatory.Weight == 0 || signatory.Weight > WeightedMultiSignatureMaxWeight {
+			return fmt.Errorf("weight of signatory #%d has to be within the range [1, %d]", idx, WeightedMultiSignatureMaxWeight)
+		}
+		for _, other := range wms.Signatories[:idx] {
+			if other.UnlockHash.Cmp(signatory.UnlockHash) == 0 {
+				return fmt.Errorf("signatory #%d is defined multiple times", idx)
+			}
+		}
+	}
+	if wms.MinimumWeight > wms.TotalWeight() {
+		return errors.New("the minimum signature weight can't be higher than the combined weight of all signatories")
+	}
+	return nil
+}
+
+// UnlockHash implements UnlockCondition.UnlockHash
+//
+// UnlockHash calculates the root hash of a Merkle tree of the
+// WeightedMultiSignatureCondition object, computed the same way as the one of the
+// MultiSignatureCondition, except that the leaf of each (sorted) signatory
+// is formed by the unlock hash followed by the weight of that signatory.
+func (wms *WeightedMultiSignatureCondition) UnlockHash() UnlockHash {
+	// Copy the signatories to a new slice and sort it,
+	// so the same unlockhash is produced for the same set
+	// of signatories, regardless of their ordering
+	signatories := make([]WeightedUnlockHash, len(wms.Signatories))
+	copy(signatories, wms.Signatories)
+	sort.Slice(signatories, func(i, j int) bool {
+		if c := signatories[i].UnlockHash.Cmp(signatories[j].UnlockHash); c != 0 {
+			return c < 0
+		}
+		return signatories[i].Weight < signatories[j].Weight
+	})
+
+	// compute the hash
+	var buf bytes.Buffer
+	e := encoder(&buf)
+	tree := crypto.NewTree()
+	e.WriteUint64(uint64(len(signatories)))
+	tree.Push(buf.Bytes())
+	buf.Reset()
+	for _, signatory := range signatories {
+		signatory.UnlockHash.MarshalSia(e)
+		e.WriteUint64(signatory.Weight)
+		tree.Push(buf.Bytes())
+		buf.Reset()
+	}
+	e.WriteUint64(wms.MinimumWeight)
+	tree.Push(buf.Bytes())
+	return NewUnlockHash(UnlockTypeWeightedMultiSig, tree.Root())
+}
+
+// UnlockHashSlice implements UnlockHashSliceGetter.UnlockHashSlice
+func (wms *WeightedMultiSignatureCondition) UnlockHashSlice() []UnlockHash {
+	uhs := make([]UnlockHash, 0, len(wms.Signatories))
+	for _, signatory := range wms.Signatories {
+		uhs = append(uhs, signatory.UnlockHash)
+	}
+	return uhs
+}
+
+// Equal implements UnlockCondition.Equal
+func (wms *WeightedMultiSignatureCondition) Equal(c UnlockCondition) bool {
+	owms, ok := c.(*WeightedMultiSignatureCondition)
+	if !ok {
+		return false
+	}
+	if wms.MinimumWeight != owms.MinimumWeight || len(wms.Signatories) != len(owms.Signatories) {
+		return false
+	}
+
+	// Check and make sure that all signatories match,
+	// regardless of the ordering
+	others := make([]WeightedUnlockHash, len(owms.Signatories))
+	copy(others, owms.Signatories)
+	for _, signatory := range wms.Signatories {
+		for i, other := range others {
+			if signatory.Weight == other.Weight && signatory.UnlockHash.Cmp(other.UnlockHash) == 0 {
+				others = append(others[:i], others[i+1:]...)
+				break
+			}
+		}
+	}
+	return len(others) == 0
+}
+
+// Fulfillable implements UnlockCondition.Fulfillable
+func (wms *WeightedMultiSignatureCondition) Fulfillable(FulfillableContext) bool {
+	return true
+}
+
+// Marshal implements MarshalableUnlockCondition.Marshal
+func (wms *WeightedMultiSignatureCondition) Marshal(f MarshalFunc) ([]byte, error) {
+	return f(wms.MinimumWeight, wms.Signatories)
+}
+
+// Unmarshal implements MarshalableUnlockCondition.Unmarshal
+func (wms *WeightedMultiSignatureCondition) Unmarshal(b []byte, f UnmarshalFunc) error {
+	return f(b, &wms.MinimumWeight, &wms.Signatories)
+}
+
+// FulfillmentType implements UnlockFulfillment.FulfillmentType
+func (wms *WeightedMultiSignatureFulfillment) FulfillmentType() FulfillmentType {
+	return FulfillmentTypeWeightedMultiSignature
+}
+
+// IsStandardFulfillment implements UnlockFulfillment.IsStandardFulfillment
+func (wms *WeightedMultiSignatureFulfillment) IsStandardFulfillment(ValidationContext) error {
+	if len(wms.Pairs) == 0 {
+		return errors.New("at least one pair must be provided")
+	}
+	if len(wms.Pairs) > WeightedMultiSignatureMaxSignatories {
+		return fmt.Errorf("at most %d pairs can be provided", WeightedMultiSignatureMaxSignatories)
+	}
+	for idx, pair := range wms.Pairs {
+		err := strictSignatureCheck(pair.PublicKey, pair.Signature)
+		if err != nil {
+			return err
+		}
+		for _, other := range wms.Pairs[:idx] {
+			if other.PublicKey.Algorithm == pair.PublicKey.Algorithm && bytes.Equal(other.PublicKey.Key, pair.PublicKey.Key) {
+				return fmt.Errorf("public key of pair #%d is used multiple times", idx)
+			}
+		}
+	}
+	return nil
+}
+
+// Equal implements UnlockFulfillment.Equal
+func (wms *WeightedMultiSignatureFulfillment) Equal(f UnlockFulfillment) bool {
+	owms, ok := f.(*WeightedMultiSignatureFulfillment)
+	if !ok {
+		return false
+	}
+	return (&MultiSignatureFulfillment{Pairs: wms.Pairs}).Equal(&MultiSignatureFulfillment{Pairs: owms.Pairs})
+}
+
+// Sign implements UnlockFulfillment.Sign
+func (wms *WeightedMultiSignatureFulfillment) Sign(ctx FulfillmentSignContext) error {
+	keypair, ok := ctx.Key.(KeyPair)
+	if !ok {
+		return errors.New("Invalid keypair to sign this input")
+	}
+
+	signature, err := signHashUsingPublicKey(
+		keypair.PublicKey, ctx, keypair.PrivateKey,
+		mergeExtraObjects(ctx.ExtraObjects, keypair.PublicKey))
+	if err != nil {
+		return err
+	}
+
+	// Only modify the fulfillment in case the signature was created successfully
+	wms.Pairs = append(wms.Pairs, PublicKeySignaturePair{PublicKey: keypair.PublicKey, Signature: signature})
+	return nil
+}
+
+// Marshal implements MarshalableUnlockFulfillment.Marshal
+func (wms *WeightedMultiSignatureFulfillment) Marshal(f MarshalFunc) ([]byte, error) {
+	return f(wms.Pairs)
+}
+
+// Unmarshal implements MarshalableUnlockFulfillment.Unmarshal
+func (wms *WeightedMultiSignatureFulfillment) Unmarshal(b []byte, f UnmarshalFunc) error {
+	return f(b, &wms.Pairs)
+}
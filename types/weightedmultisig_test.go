@@ -0,0 +1,328 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/threefoldtech/rivine/crypto"
+	"github.com/threefoldtech/rivine/pkg/encoding/rivbin"
+	"github.com/threefoldtech/rivine/pkg/encoding/siabin"
+)
+
+func (k policyTestKey) signWeightedMultiSig(t *testing.T, txn Transaction, f *WeightedMultiSignatureFulfillment) {
+	err := f.Sign(FulfillmentSignContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		Transaction:  txn,
+		Key: KeyPair{
+			PublicKey:  k.pk,
+			PrivateKey: ByteSlice(k.sk[:]),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWeightedMultiSignatureEncoding(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	condition := NewCondition(NewWeightedMultiSignatureCondition([]WeightedUnlockHash{
+		{UnlockHash: k1.uh, Weight: 2},
+		{UnlockHash: k2.uh, Weight: 1},
+	}, 2))
+	wf := &WeightedMultiSignatureFulfillment{}
+	k1.signWeightedMultiSig(t, Transaction{Version: TransactionVersionOne}, wf)
+	fulfillment := NewFulfillment(wf)
+
+	// JSON
+	b, err := json.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jsonCondition UnlockConditionProxy
+	err = json.Unmarshal(b, &jsonCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(jsonCondition) {
+		t.Errorf("JSON: %v != %v", condition, jsonCondition)
+	}
+	b, err = json.Marshal(fulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jsonFulfillment UnlockFulfillmentProxy
+	err = json.Unmarshal(b, &jsonFulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfillment.Equal(jsonFulfillment) {
+		t.Errorf("JSON: %v != %v", fulfillment, jsonFulfillment)
+	}
+
+	// rivine binary
+	b, err = rivbin.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rivineCondition UnlockConditionProxy
+	err = rivbin.Unmarshal(b, &rivineCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(rivineCondition) {
+		t.Errorf("rivbin: %v != %v", condition, rivineCondition)
+	}
+	b, err = rivbin.Marshal(fulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rivineFulfillment UnlockFulfillmentProxy
+	err = rivbin.Unmarshal(b, &rivineFulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfillment.Equal(rivineFulfillment) {
+		t.Errorf("rivbin: %v != %v", fulfillment, rivineFulfillment)
+	}
+
+	// sia binary
+	b, err = siabin.Marshal(condition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var siaCondition UnlockConditionProxy
+	err = siabin.Unmarshal(b, &siaCondition)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !condition.Equal(siaCondition) {
+		t.Errorf("siabin: %v != %v", condition, siaCondition)
+	}
+	b, err = siabin.Marshal(fulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var siaFulfillment UnlockFulfillmentProxy
+	err = siabin.Unmarshal(b, &siaFulfillment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fulfillment.Equal(siaFulfillment) {
+		t.Errorf("siabin: %v != %v", fulfillment, siaFulfillment)
+	}
+}
+
+func TestWeightedMultiSignatureUnlockHash(t *testing.T) {
+	k1, k2, k3 := newPolicyTestKey(t), newPolicyTestKey(t), newPolicyTestKey(t)
+	a := NewWeightedMultiSignatureCondition([]WeightedUnlockHash{
+		{UnlockHash: k1.uh, Weight: 2},
+		{UnlockHash: k2.uh, Weight: 1},
+		{UnlockHash: k3.uh, Weight: 1},
+	}, 3)
+	b := NewWeightedMultiSignatureCondition([]WeightedUnlockHash{
+		{UnlockHash: k3.uh, Weight: 1},
+		{UnlockHash: k1.uh, Weight: 2},
+		{UnlockHash: k2.uh, Weight: 1},
+	}, 3)
+	if a.UnlockHash().Type != UnlockTypeWeightedMultiSig {
+		t.Errorf("unexpected unlock hash type: %d", a.UnlockHash().Type)
+	}
+	// the ordering of the signatories does not matter
+	if a.UnlockHash().Cmp(b.UnlockHash()) != 0 {
+		t.Error("expected the same unlock hash regardless of signatory ordering")
+	}
+	if !a.Equal(b) {
+		t.Error("expected conditions to be equal regardless of signatory ordering")
+	}
+
+	// both the weights and the minimum weight are committed to
+	for idx, c := range []*WeightedMultiSignatureCondition{
+		NewWeightedMultiSignatureCondition([]WeightedUnlockHash{
+			{UnlockHash: k1.uh, Weight: 1},
+			{UnlockHash: k2.uh, Weight: 2},
+			{UnlockHash: k3.uh, Weight: 1},
+		}, 3),
+		NewWeightedMultiSignatureCondition([]WeightedUnlockHash{
+			{UnlockHash: k1.uh, Weight: 2},
+			{UnlockHash: k2.uh, Weight: 1},
+			{UnlockHash: k3.uh, Weight: 1},
+		}, 2),
+		NewWeightedMultiSignatureCondition([]WeightedUnlockHash{
+			{UnlockHash: k1.uh, Weight: 2},
+			{UnlockHash: k2.uh, Weight: 1},
+		}, 3),
+	} {
+		if a.UnlockHash().Cmp(c.UnlockHash()) == 0 {
+			t.Errorf("#%d: expected a different unlock hash", idx)
+		}
+		if a.Equal(c) {
+			t.Errorf("#%d: expected conditions to differ", idx)
+		}
+	}
+
+	// a weighted multisig condition never equals a regular multisig condition
+	ms := &MultiSignatureCondition{UnlockHashes: UnlockHashSlice{k1.uh, k2.uh, k3.uh}, MinimumSignatureCount: 3}
+	if a.UnlockHash().Cmp(ms.UnlockHash()) == 0 || a.Equal(ms) {
+		t.Error("expected weighted and regular multisig conditions to differ")
+	}
+}
+
+func TestWeightedMultiSignatureFulfill(t *testing.T) {
+	cfo, m1, m2, outsider := newPolicyTestKey(t), newPolicyTestKey(t), newPolicyTestKey(t), newPolicyTestKey(t)
+	txn := Transaction{
+		Version:       TransactionVersionOne,
+		ArbitraryData: []byte("weighted"),
+	}
+	ctx := FulfillContext{
+		ExtraObjects: []interface{}{uint64(0)},
+		BlockHeight:  100,
+		BlockTime:    CurrentTimestamp(),
+		Transaction:  txn,
+	}
+
+	// the CFO has a weight of 2, the other members a weight of 1,
+	// and a combined weight of 4 is required
+	condition := NewWeightedMultiSignatureCondition([]WeightedUnlockHash{
+		{UnlockHash: cfo.uh, Weight: 2},
+		{UnlockHash: m1.uh, Weight: 1},
+		{UnlockHash: m2.uh, Weight: 1},
+	}, 4)
+	sign := func(keys ...policyTestKey) *WeightedMultiSignatureFulfillment {
+		f := &WeightedMultiSignatureFulfillment{}
+		for _, k := range keys {
+			k.signWeightedMultiSig(t, txn, f)
+		}
+		return f
+	}
+
+	for idx, tc := range []struct {
+		fulfillment *WeightedMultiSignatureFulfillment
+		err         error
+	}{
+		{sign(cfo, m1, m2), nil},
+		{sign(m2, cfo, m1), nil},
+		{sign(cfo, m1), ErrInsufficientSignatureWeight},
+		{sign(m1, m2), ErrInsufficientSignatureWeight},
+		{sign(), ErrInsufficientSignatures},
+		// a key which isn't a signatory
+		{sign(cfo, m1, outsider), ErrUnauthorizedPubKey},
+		// a key can't count twice
+		{sign(cfo, cfo), ErrUnauthorizedPubKey},
+	} {
+		err := condition.Fulfill(tc.fulfillment, ctx)
+		if err != tc.err {
+			t.Errorf("#%d: expected %v, got %v", idx, tc.err, err)
+		}
+	}
+
+	// signatures are verified
+	f := sign(cfo, m1, m2)
+	f.Pairs[2].Signature = f.Pairs[1].Signature
+	if err := condition.Fulfill(f, ctx); err == nil {
+		t.Error("expected an invalid signature to fail")
+	}
+
+	// only a weighted multisig fulfillment can fulfill a weighted multisig condition
+	ms := &MultiSignatureFulfillment{}
+	cfo.signMultiSig(t, txn, ms)
+	m1.signMultiSig(t, txn, ms)
+	m2.signMultiSig(t, txn, ms)
+	if err := condition.Fulfill(ms, ctx); err != ErrUnexpectedUnlockFulfillment {
+		t.Errorf("expected %v, got %v", ErrUnexpectedUnlockFulfillment, err)
+	}
+
+	// a weighted multisig condition can be time locked
+	locked := NewTimeLockCondition(42, condition)
+	if err := locked.IsStandardCondition(ValidationContext{Confirmed: true}); err != nil {
+		t.Errorf("expected time locked condition to be standard: %v", err)
+	}
+	if err := locked.Fulfill(sign(cfo, m1, m2), ctx); err != nil {
+		t.Errorf("expected time locked condition to be fulfilled: %v", err)
+	}
+	lockedCtx := ctx
+	lockedCtx.BlockHeight = 41
+	if err := locked.Fulfill(sign(cfo, m1, m2), lockedCtx); err == nil {
+		t.Error("expected a time locked condition to fail")
+	}
+}
+
+func TestWeightedMultiSignatureConditionIsStandard(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	ctx := ValidationContext{
+		Confirmed:   true,
+		BlockHeight: 0,
+	}
+	tooMany := &WeightedMultiSignatureCondition{MinimumWeight: 1}
+	for i := 0; i <= WeightedMultiSignatureMaxSignatories; i++ {
+		tooMany.Signatories = append(tooMany.Signatories, WeightedUnlockHash{UnlockHash: newPolicyTestKey(t).uh, Weight: 1})
+	}
+	maxSignatories := &WeightedMultiSignatureCondition{
+		Signatories:   tooMany.Signatories[:WeightedMultiSignatureMaxSignatories],
+		MinimumWeight: WeightedMultiSignatureMaxSignatories,
+	}
+	_, secpPK := crypto.GenerateSecp256k1KeyPair()
+	secpUH, err := NewSecp256k1PubKeyUnlockHash(secpPK)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for idx, tc := range []struct {
+		condition *WeightedMultiSignatureCondition
+		standard  bool
+	}{
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 1}, {k2.uh, 1}}, MinimumWeight: 1}, true},
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 2}, {k2.uh, 1}}, MinimumWeight: 3}, true},
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, WeightedMultiSignatureMaxWeight}, {secpUH, 1}}, MinimumWeight: 1}, true},
+		{maxSignatories, true},
+		// invalid minimum weight
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 1}, {k2.uh, 1}}}, false},
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 1}, {k2.uh, 1}}, MinimumWeight: 3}, false},
+		// invalid signatories
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 1}}, MinimumWeight: 1}, false},
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 1}, {k1.uh, 1}}, MinimumWeight: 1}, false},
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 1}, {NewUnlockHash(UnlockTypeMultiSig, crypto.Hash{1}), 1}}, MinimumWeight: 1}, false},
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 1}, {k2.uh, 0}}, MinimumWeight: 1}, false},
+		{&WeightedMultiSignatureCondition{Signatories: []WeightedUnlockHash{{k1.uh, 1}, {k2.uh, WeightedMultiSignatureMaxWeight + 1}}, MinimumWeight: 1}, false},
+		// limits
+		{tooMany, false},
+	} {
+		err := tc.condition.IsStandardCondition(ctx)
+		if tc.standard && err != nil {
+			t.Errorf("#%d: expected condition to be standard: %v", idx, err)
+		} else if !tc.standard && err == nil {
+			t.Errorf("#%d: expected condition to be non-standard", idx)
+		}
+	}
+}
+
+func TestWeightedMultiSignatureFulfillmentIsStandard(t *testing.T) {
+	k1, k2 := newPolicyTestKey(t), newPolicyTestKey(t)
+	ctx := ValidationContext{
+		Confirmed:   true,
+		BlockHeight: 0,
+	}
+	signature := make(ByteSlice, crypto.SignatureSize)
+	tooMany := &WeightedMultiSignatureFulfillment{}
+	for i := 0; i <= WeightedMultiSignatureMaxSignatories; i++ {
+		tooMany.Pairs = append(tooMany.Pairs, PublicKeySignaturePair{PublicKey: newPolicyTestKey(t).pk, Signature: signature})
+	}
+	for idx, tc := range []struct {
+		fulfillment *WeightedMultiSignatureFulfillment
+		standard    bool
+	}{
+		{&WeightedMultiSignatureFulfillment{Pairs: []PublicKeySignaturePair{{k1.pk, signature}}}, true},
+		{&WeightedMultiSignatureFulfillment{Pairs: []PublicKeySignaturePair{{k1.pk, signature}, {k2.pk, signature}}}, true},
+		{&WeightedMultiSignatureFulfillment{Pairs: tooMany.Pairs[:WeightedMultiSignatureMaxSignatories]}, true},
+		{&WeightedMultiSignatureFulfillment{}, false},
+		{&WeightedMultiSignatureFulfillment{Pairs: []PublicKeySignaturePair{{k1.pk, nil}}}, false},
+		{&WeightedMultiSignatureFulfillment{Pairs: []PublicKeySignaturePair{{k1.pk, signature}, {k1.pk, signature}}}, false},
+		{tooMany, false},
+	} {
+		err := tc.fulfillment.IsStandardFulfillment(ctx)
+		if tc.standard && err != nil {
+			t.Errorf("#%d: expected fulfillment to be standard: %v", idx, err)
+		} else if !tc.standard && err == nil {
+			t.Errorf("#%d: expected fulfillment to be non-standard", idx)
+		}
+	}
+}